@@ -38,6 +38,9 @@ const (
 	KeySubscriptionNotificationSent = "sub_reminder"
 	KeyNewsbox                      = "newsbox"
 	KeyInviteCode                   = "invite"
+	KeyLastDataExport               = "last_data_export"
+	KeyWakapiMigrationCheckpoint    = "wakapi_migration_checkpoint"
+	KeyGoalReached                  = "goal_reached" // date the user last reached their daily goal
 
 	SessionKeyDefault = "default"
 
@@ -46,6 +49,7 @@ const (
 
 	ErrUnauthorized        = "401 unauthorized"
 	ErrBadRequest          = "400 bad request"
+	ErrForbidden           = "403 forbidden"
 	ErrNotFound            = "404 not found"
 	ErrInternalServerError = "500 internal server error"
 )
@@ -62,13 +66,24 @@ const (
 )
 
 const (
-	MailProviderSmtp = "smtp"
+	MailProviderSmtp     = "smtp"
+	MailProviderFile     = "file"
+	MailProviderSendmail = "sendmail"
+	MailProviderHttp     = "http"
 )
 
 var emailProviders = []string{
 	MailProviderSmtp,
+	MailProviderFile,
+	MailProviderSendmail,
+	MailProviderHttp,
 }
 
+const (
+	MailFileFormatEml     = "eml"
+	MailFileFormatMaildir = "maildir"
+)
+
 // first wakatime commit was on this day ;-) so no real heartbeats should exist before
 // https://github.com/wakatime/legacy-python-cli/commit/3da94756aa1903c1cca5035803e3f704e818c086
 const heartbeatsMinDate = "2013-07-06"
@@ -120,6 +135,7 @@ type securityConfig struct {
 	SignupMaxRate             string                     `yaml:"signup_max_rate" default:"5/1h" env:"WAKAPI_SIGNUP_MAX_RATE"`
 	LoginMaxRate              string                     `yaml:"login_max_rate" default:"10/1m" env:"WAKAPI_LOGIN_MAX_RATE"`
 	PasswordResetMaxRate      string                     `yaml:"password_reset_max_rate" default:"5/1h" env:"WAKAPI_PASSWORD_RESET_MAX_RATE"`
+	AllowPrivateNetworks      bool                       `yaml:"allow_private_networks" default:"false" env:"WAKAPI_ALLOW_PRIVATE_NETWORKS"` // whether webhooks, notification channels, relay targets and wakapi migrations may target loopback or private addresses
 	SecureCookie              *securecookie.SecureCookie `yaml:"-"`
 	SessionKey                []byte                     `yaml:"-"`
 	trustReverseProxyIpParsed []net.IP
@@ -172,10 +188,13 @@ type sentryConfig struct {
 }
 
 type mailConfig struct {
-	Enabled  bool           `env:"WAKAPI_MAIL_ENABLED" default:"true"`
-	Provider string         `env:"WAKAPI_MAIL_PROVIDER" default:"smtp"`
-	Smtp     SMTPMailConfig `yaml:"smtp"`
-	Sender   string         `env:"WAKAPI_MAIL_SENDER" yaml:"sender"`
+	Enabled  bool               `env:"WAKAPI_MAIL_ENABLED" default:"true"`
+	Provider string             `env:"WAKAPI_MAIL_PROVIDER" default:"smtp"`
+	Smtp     SMTPMailConfig     `yaml:"smtp"`
+	File     FileMailConfig     `yaml:"file"`
+	Sendmail SendmailMailConfig `yaml:"sendmail"`
+	Http     HttpMailConfig     `yaml:"http"`
+	Sender   string             `env:"WAKAPI_MAIL_SENDER" yaml:"sender"`
 }
 
 type SMTPMailConfig struct {
@@ -186,6 +205,39 @@ type SMTPMailConfig struct {
 	TLS      bool   `env:"WAKAPI_MAIL_SMTP_TLS"`
 }
 
+// FileMailConfig configures writing mails to a local directory instead of sending them, either as plain .eml files or as a maildir
+type FileMailConfig struct {
+	Path   string `env:"WAKAPI_MAIL_FILE_PATH" default:"mails"`
+	Format string `env:"WAKAPI_MAIL_FILE_FORMAT" default:"eml"`
+}
+
+// SendmailMailConfig configures handing mails to the local mta via a sendmail-compatible binary
+type SendmailMailConfig struct {
+	Path string `env:"WAKAPI_MAIL_SENDMAIL_PATH" default:"/usr/sbin/sendmail"`
+	Args string `env:"WAKAPI_MAIL_SENDMAIL_ARGS" default:"-t -i"` // space-separated
+}
+
+// HttpMailConfig configures posting mails as json to a transactional mail api or a custom webhook
+type HttpMailConfig struct {
+	Url           string `env:"WAKAPI_MAIL_HTTP_URL"`
+	Authorization string `env:"WAKAPI_MAIL_HTTP_AUTHORIZATION"` // full value of the authorization header, e.g. "Bearer <token>"
+}
+
+type oidcConfig struct {
+	Enabled              bool   `yaml:"enabled" default:"false" env:"WAKAPI_OIDC_ENABLED"`
+	DisplayName          string `yaml:"display_name" default:"Single Sign-On" env:"WAKAPI_OIDC_DISPLAY_NAME"`
+	Issuer               string `yaml:"issuer" env:"WAKAPI_OIDC_ISSUER"`
+	ClientId             string `yaml:"client_id" env:"WAKAPI_OIDC_CLIENT_ID"`
+	ClientSecret         string `yaml:"client_secret" env:"WAKAPI_OIDC_CLIENT_SECRET"`
+	Scopes               string `yaml:"scopes" default:"openid,profile,email" env:"WAKAPI_OIDC_SCOPES"` // comma-separated list of scopes
+	UsernameClaim        string `yaml:"username_claim" default:"preferred_username" env:"WAKAPI_OIDC_USERNAME_CLAIM"`
+	GroupsClaim          string `yaml:"groups_claim" default:"groups" env:"WAKAPI_OIDC_GROUPS_CLAIM"`
+	AdminGroup           string `yaml:"admin_group" default:"" env:"WAKAPI_OIDC_ADMIN_GROUP"` // members of this group are admins, leave blank to not manage admin rights via oidc
+	AllowSignup          bool   `yaml:"allow_signup" default:"true" env:"WAKAPI_OIDC_ALLOW_SIGNUP"`
+	LinkByEmail          bool   `yaml:"link_by_email" default:"false" env:"WAKAPI_OIDC_LINK_BY_EMAIL"` // link existing accounts with matching, verified e-mail address
+	DisablePasswordLogin bool   `yaml:"disable_password_login" default:"false" env:"WAKAPI_OIDC_DISABLE_PASSWORD_LOGIN"`
+}
+
 type Config struct {
 	Env            string `default:"dev" env:"ENVIRONMENT"`
 	Version        string `yaml:"-"`
@@ -200,6 +252,7 @@ type Config struct {
 	Subscriptions  subscriptionsConfig
 	Sentry         sentryConfig
 	Mail           mailConfig
+	Oidc           oidcConfig
 }
 
 func (c *Config) CreateCookie(name, value string) *http.Cookie {
@@ -398,6 +451,25 @@ func (c *serverConfig) GetPublicUrl() string {
 	return strings.TrimSuffix(c.PublicUrl, "/")
 }
 
+func (c *oidcConfig) GetScopes() []string {
+	scopes := make([]string, 0)
+	for _, s := range strings.Split(c.Scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func (c *oidcConfig) GetCallbackUrl(publicUrl string) string {
+	return fmt.Sprintf("%s/login/oidc/callback", strings.TrimSuffix(publicUrl, "/"))
+}
+
+// IsPasswordLoginEnabled returns false if users are supposed to sign in via single sign-on exclusively
+func (c *oidcConfig) IsPasswordLoginEnabled() bool {
+	return !c.Enabled || !c.DisablePasswordLogin
+}
+
 func (c *SMTPMailConfig) ConnStr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
@@ -520,12 +592,21 @@ func Load(configFlag string, version string) *Config {
 	if config.Mail.Provider != "" && utils.FindString(config.Mail.Provider, emailProviders, "") == "" {
 		logbuch.Fatal("unknown mail provider '%s'", config.Mail.Provider)
 	}
+	if config.Mail.Enabled && config.Mail.Provider == MailProviderFile && config.Mail.File.Format != MailFileFormatEml && config.Mail.File.Format != MailFileFormatMaildir {
+		logbuch.Fatal("unknown mail file format '%s'", config.Mail.File.Format)
+	}
+	if config.Mail.Enabled && config.Mail.Provider == MailProviderHttp && config.Mail.Http.Url == "" {
+		logbuch.Fatal("http mail provider requires url to be set")
+	}
 	if _, err := time.ParseDuration(config.App.HeartbeatMaxAge); err != nil {
 		logbuch.Fatal("invalid duration set for heartbeat_max_age")
 	}
 	if config.Security.TrustedHeaderAuth && len(config.Security.trustReverseProxyIpParsed) == 0 {
 		config.Security.TrustedHeaderAuth = false
 	}
+	if config.Oidc.Enabled && (config.Oidc.Issuer == "" || config.Oidc.ClientId == "" || config.Oidc.UsernameClaim == "") {
+		logbuch.Fatal("oidc requires issuer, client_id and username_claim to be set")
+	}
 	if d, err := time.Parse(config.App.DateFormat, config.App.DateFormat); err != nil || !d.Equal(time.Date(2006, time.January, 2, 0, 0, 0, 0, d.Location())) {
 		logbuch.Fatal("invalid date format '%s'", config.App.DateFormat)
 	}
@@ -576,6 +657,7 @@ func Empty() *Config {
 		Subscriptions: subscriptionsConfig{},
 		Sentry:        sentryConfig{},
 		Mail:          mailConfig{},
+		Oidc:          oidcConfig{},
 	}
 }
 
@@ -17,6 +17,10 @@ const (
 	EventProjectLabelCreate = "project_label.create"
 	EventProjectLabelDelete = "project_label.delete"
 	EventWakatimeFailure    = "wakatime.failure"
+	EventSummaryCreate      = "summary.create"
+	EventImportFinish       = "import.finish"
+	EventGoalReached        = "goal.reached"
+	EventRelayTargetDisable = "relay_target.disable"
 	FieldPayload            = "payload"
 	FieldUser               = "user"
 	FieldUserId             = "user.id"
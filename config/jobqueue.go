@@ -17,6 +17,8 @@ const (
 	QueueMails        = "wakapi.mail"
 	QueueImports      = "wakapi.imports"
 	QueueHousekeeping = "wakapi.housekeeping"
+	QueueRelay        = "wakapi.relay"
+	QueueWebhooks     = "wakapi.webhooks"
 )
 
 type JobQueueMetrics struct {
@@ -36,6 +38,8 @@ func StartJobs() {
 	InitQueue(QueueMails, 1)
 	InitQueue(QueueImports, 1)
 	InitQueue(QueueHousekeeping, utils.HalfCPUs())
+	InitQueue(QueueRelay, 1)
+	InitQueue(QueueWebhooks, 1)
 }
 
 func InitQueue(name string, workers int) error {
@@ -1,14 +1,21 @@
 package config
 
 const (
-	IndexTemplate         = "index.tpl.html"
-	LoginTemplate         = "login.tpl.html"
-	ImprintTemplate       = "imprint.tpl.html"
-	SignupTemplate        = "signup.tpl.html"
-	SetPasswordTemplate   = "set-password.tpl.html"
-	ResetPasswordTemplate = "reset-password.tpl.html"
-	SettingsTemplate      = "settings.tpl.html"
-	SummaryTemplate       = "summary.tpl.html"
-	LeaderboardTemplate   = "leaderboard.tpl.html"
-	ProjectsTemplate      = "projects.tpl.html"
+	IndexTemplate               = "index.tpl.html"
+	LoginTemplate               = "login.tpl.html"
+	LoginTotpTemplate           = "login-totp.tpl.html"
+	ImprintTemplate             = "imprint.tpl.html"
+	SignupTemplate              = "signup.tpl.html"
+	SetPasswordTemplate         = "set-password.tpl.html"
+	ResetPasswordTemplate       = "reset-password.tpl.html"
+	SettingsTemplate            = "settings.tpl.html"
+	SummaryTemplate             = "summary.tpl.html"
+	LeaderboardTemplate         = "leaderboard.tpl.html"
+	ProjectsTemplate            = "projects.tpl.html"
+	AdminTemplate               = "admin.tpl.html"
+	TeamsTemplate               = "teams.tpl.html"
+	TeamTemplate                = "team.tpl.html"
+	PrivateLeaderboardsTemplate = "private-leaderboards.tpl.html"
+	CsvImportTemplate           = "csv-import.tpl.html"
+	BillingTemplate             = "billing.tpl.html"
 )
@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/muety/wakapi/models"
+)
+
+const (
+	icsTimeFormat    = "20060102T150405Z"
+	icsMaxLineLength = 75 // in octets, excluding the line break
+)
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// WriteCalendar writes the given work sessions as events of an icalendar (rfc 5545) document
+func WriteCalendar(w io.Writer, name, userId string, sessions models.WorkSessions) error {
+	var sb strings.Builder
+	writeLine := func(name, value string) {
+		sb.WriteString(foldIcsLine(name + ":" + value))
+		sb.WriteString("\r\n")
+	}
+
+	now := time.Now().UTC().Format(icsTimeFormat)
+
+	writeLine("BEGIN", "VCALENDAR")
+	writeLine("VERSION", "2.0")
+	writeLine("PRODID", "-//Wakapi//Work Sessions//EN")
+	writeLine("CALSCALE", "GREGORIAN")
+	writeLine("METHOD", "PUBLISH")
+	writeLine("X-WR-CALNAME", icsEscaper.Replace(name))
+
+	for _, s := range sessions {
+		description := fmt.Sprintf("Coding time: %s", FmtWakatimeDuration(s.Duration))
+		if s.Branch != "" {
+			description += "\nBranch: " + s.Branch
+		}
+		if len(s.Projects) > 1 {
+			description += "\nProjects: " + strings.Join(s.Projects, ", ")
+		}
+
+		writeLine("BEGIN", "VEVENT")
+		writeLine("UID", fmt.Sprintf("%x@wakapi", sha1.Sum([]byte(fmt.Sprintf("%s-%d", userId, s.Start.Unix())))))
+		writeLine("DTSTAMP", now)
+		writeLine("DTSTART", s.Start.UTC().Format(icsTimeFormat))
+		writeLine("DTEND", s.End.UTC().Format(icsTimeFormat))
+		writeLine("SUMMARY", icsEscaper.Replace(s.Description()))
+		writeLine("DESCRIPTION", icsEscaper.Replace(description))
+		writeLine("TRANSP", "TRANSPARENT")
+		writeLine("END", "VEVENT")
+	}
+
+	writeLine("END", "VCALENDAR")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// foldIcsLine splits a content line into multiple ones of at most 75 octets each, without breaking multibyte characters
+func foldIcsLine(line string) string {
+	if len(line) <= icsMaxLineLength {
+		return line
+	}
+
+	var sb strings.Builder
+	limit := icsMaxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsMaxLineLength - 1 // continuation lines start with a space
+	}
+	sb.WriteString(line)
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCalendar(t *testing.T) {
+	sessions := models.WorkSessions{
+		{
+			Start:    time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC),
+			End:      time.Date(2025, 3, 10, 10, 30, 0, 0, time.UTC),
+			Duration: 80 * time.Minute,
+			Project:  "wakapi",
+			Branch:   "fix;things,now",
+			Projects: []string{"wakapi", "anchr"},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteCalendar(&buf, "Wakapi", "user1", sessions))
+	result := strings.ReplaceAll(buf.String(), "\r\n ", "") // unfold
+
+	assert.True(t, strings.HasPrefix(result, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(result, "END:VCALENDAR\r\n"))
+	assert.Contains(t, result, "DTSTART:20250310T090000Z\r\n")
+	assert.Contains(t, result, "DTEND:20250310T103000Z\r\n")
+	assert.Contains(t, result, `SUMMARY:wakapi (fix\;things\,now)`+"\r\n")
+	assert.Contains(t, result, `\nProjects: wakapi\, anchr`)
+	assert.Equal(t, 1, strings.Count(result, "BEGIN:VEVENT"))
+}
+
+func TestFoldIcsLine(t *testing.T) {
+	assert.Equal(t, "SUMMARY:short", foldIcsLine("SUMMARY:short"))
+
+	line := "SUMMARY:" + strings.Repeat("ü", 60)
+	folded := foldIcsLine(line)
+	parts := strings.Split(folded, "\r\n")
+
+	assert.Greater(t, len(parts), 1)
+	for i, p := range parts {
+		assert.LessOrEqual(t, len(p), 75)
+		if i > 0 {
+			assert.True(t, strings.HasPrefix(p, " "))
+		}
+	}
+	assert.Equal(t, line, strings.ReplaceAll(folded, "\r\n ", ""))
+}
@@ -0,0 +1,76 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
+// RowWriter writes exported rows (see services.ExportService) in a tabular or line-based format
+type RowWriter interface {
+	WriteRow([]interface{}) error
+	Flush() error
+}
+
+// NewRowWriter returns a writer for the given export format. For csv, the header row is written right away.
+func NewRowWriter(w io.Writer, format string, columns []string) (RowWriter, error) {
+	switch format {
+	case models.ExportFormatCsv:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvRowWriter{writer: writer}, nil
+	case models.ExportFormatNdjson:
+		return &ndjsonRowWriter{encoder: json.NewEncoder(w), columns: columns}, nil
+	}
+	return nil, fmt.Errorf("unsupported format '%s'", format)
+}
+
+type csvRowWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, v := range row {
+		switch value := v.(type) {
+		case time.Time:
+			record[i] = value.Format(time.RFC3339)
+		case float64:
+			record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		case nil:
+			record[i] = ""
+		default:
+			record[i] = fmt.Sprint(value)
+		}
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonRowWriter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (n *ndjsonRowWriter) WriteRow(row []interface{}) error {
+	object := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		object[n.columns[i]] = v
+	}
+	return n.encoder.Encode(object)
+}
+
+func (n *ndjsonRowWriter) Flush() error {
+	return nil
+}
@@ -8,17 +8,18 @@ import (
 	"net/http"
 )
 
-func ExtractCookieAuth(r *http.Request, config *config.Config) (username *string, err error) {
+// ExtractCookieAuth returns the session token contained in the (signed) authentication cookie
+func ExtractCookieAuth(r *http.Request, config *config.Config) (sessionToken *string, err error) {
 	cookie, err := r.Cookie(models.AuthCookieKey)
 	if err != nil {
 		return nil, errors.New("missing authentication")
 	}
 
-	if err := config.Security.SecureCookie.Decode(models.AuthCookieKey, cookie.Value, &username); err != nil {
+	if err := config.Security.SecureCookie.Decode(models.AuthCookieKey, cookie.Value, &sessionToken); err != nil {
 		return nil, errors.New("cookie is invalid")
 	}
 
-	return username, nil
+	return sessionToken, nil
 }
 
 func RespondJSON(w http.ResponseWriter, r *http.Request, status int, object interface{}) {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/muety/wakapi/models"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -69,6 +70,14 @@ func ParseSummaryFilters(r *http.Request) *models.Filters {
 	if q := r.URL.Query().Get("entity"); q != "" {
 		filters.With(models.SummaryBranch, q)
 	}
+	if q := r.URL.Query().Get("ticket"); q != "" {
+		filters.With(models.SummaryTicket, q)
+	}
+	if q := r.URL.Query().Get("label_depth"); q != "" {
+		if depth, err := strconv.Atoi(q); err == nil {
+			filters.WithLabelDepth(depth)
+		}
+	}
 	return filters
 }
 
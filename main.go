@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/migrations"
+	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/routes"
 	"github.com/muety/wakapi/routes/api"
@@ -35,7 +37,10 @@ import (
 	wtV1Routes "github.com/muety/wakapi/routes/compat/wakatime/v1"
 	"github.com/muety/wakapi/routes/relay"
 	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/services/document"
+	"github.com/muety/wakapi/services/imports"
 	"github.com/muety/wakapi/services/mail"
+	"github.com/muety/wakapi/services/notification"
 	"github.com/muety/wakapi/static/docs"
 	fsutils "github.com/muety/wakapi/utils/fs"
 
@@ -58,35 +63,65 @@ var (
 )
 
 var (
-	aliasRepository           repositories.IAliasRepository
-	heartbeatRepository       repositories.IHeartbeatRepository
-	userRepository            repositories.IUserRepository
-	languageMappingRepository repositories.ILanguageMappingRepository
-	projectLabelRepository    repositories.IProjectLabelRepository
-	summaryRepository         repositories.ISummaryRepository
-	leaderboardRepository     *repositories.LeaderboardRepository
-	keyValueRepository        repositories.IKeyValueRepository
-	diagnosticsRepository     repositories.IDiagnosticsRepository
-	metricsRepository         *repositories.MetricsRepository
+	aliasRepository                  repositories.IAliasRepository
+	heartbeatRepository              repositories.IHeartbeatRepository
+	userRepository                   repositories.IUserRepository
+	languageMappingRepository        repositories.ILanguageMappingRepository
+	defaultLanguageMappingRepository repositories.IDefaultLanguageMappingRepository
+	projectLabelRepository           repositories.IProjectLabelRepository
+	labelColorRepository             repositories.ILabelColorRepository
+	billingRateRepository            repositories.IBillingRateRepository
+	sessionRepository                repositories.ISessionRepository
+	teamRepository                   repositories.ITeamRepository
+	summaryRepository                repositories.ISummaryRepository
+	leaderboardRepository            *repositories.LeaderboardRepository
+	privateLeaderboardRepository     repositories.IPrivateLeaderboardRepository
+	keyValueRepository               repositories.IKeyValueRepository
+	diagnosticsRepository            repositories.IDiagnosticsRepository
+	metricsRepository                *repositories.MetricsRepository
+	importJobRepository              repositories.IImportJobRepository
+	relayTargetRepository            repositories.IRelayTargetRepository
+	relayOutboxRepository            repositories.IRelayOutboxRepository
+	webhookRepository                repositories.IWebhookRepository
+	webhookDeliveryRepository        repositories.IWebhookDeliveryRepository
+	notificationChannelRepository    repositories.INotificationChannelRepository
+	reportSubscriptionRepository     repositories.IReportSubscriptionRepository
 )
 
 var (
-	aliasService           services.IAliasService
-	heartbeatService       services.IHeartbeatService
-	userService            services.IUserService
-	languageMappingService services.ILanguageMappingService
-	projectLabelService    services.IProjectLabelService
-	durationService        services.IDurationService
-	summaryService         services.ISummaryService
-	leaderboardService     services.ILeaderboardService
-	aggregationService     services.IAggregationService
-	mailService            services.IMailService
-	keyValueService        services.IKeyValueService
-	reportService          services.IReportService
-	activityService        services.IActivityService
-	diagnosticsService     services.IDiagnosticsService
-	housekeepingService    services.IHousekeepingService
-	miscService            services.IMiscService
+	aliasService              services.IAliasService
+	heartbeatService          services.IHeartbeatService
+	userService               services.IUserService
+	languageMappingService    services.ILanguageMappingService
+	projectLabelService       services.IProjectLabelService
+	durationService           services.IDurationService
+	summaryService            services.ISummaryService
+	leaderboardService        services.ILeaderboardService
+	privateLeaderboardService services.IPrivateLeaderboardService
+	aggregationService        services.IAggregationService
+	mailService               services.IMailService
+	keyValueService           services.IKeyValueService
+	reportService             services.IReportService
+	reportRenderer            services.IReportRenderer
+	billingService            services.IBillingService
+	activityService           services.IActivityService
+	diagnosticsService        services.IDiagnosticsService
+	housekeepingService       services.IHousekeepingService
+	miscService               services.IMiscService
+	oidcService               services.IOidcService
+	totpService               services.ITotpService
+	sessionService            services.ISessionService
+	adminService              services.IAdminService
+	teamService               services.ITeamService
+	dataExportService         services.IDataExportService
+	importService             services.IImportService
+	exportService             services.IExportService
+	wakapiMigrationService    services.IWakapiMigrationService
+	relayTargetService        services.IRelayTargetService
+	relayService              services.IRelayService
+	webhookService            services.IWebhookService
+	goalService               services.IGoalService
+	notificationService       services.INotificationService
 )
 
 // TODO: Refactor entire project to be structured after business domains
@@ -113,6 +148,8 @@ var (
 func main() {
 	var versionFlag = flag.Bool("version", false, "print version")
 	var configFlag = flag.String("config", conf.DefaultConfigPath, "config file location")
+	var importFileFlag = flag.String("import-wakatime-file", "", "import heartbeats from a wakatime json export file (optionally gzipped) and exit, requires -import-user")
+	var importUserFlag = flag.String("import-user", "", "id of the user to import data for")
 	flag.Parse()
 
 	if *versionFlag {
@@ -173,32 +210,68 @@ func main() {
 	heartbeatRepository = repositories.NewHeartbeatRepository(db)
 	userRepository = repositories.NewUserRepository(db)
 	languageMappingRepository = repositories.NewLanguageMappingRepository(db)
+	defaultLanguageMappingRepository = repositories.NewDefaultLanguageMappingRepository(db)
 	projectLabelRepository = repositories.NewProjectLabelRepository(db)
+	labelColorRepository = repositories.NewLabelColorRepository(db)
+	billingRateRepository = repositories.NewBillingRateRepository(db)
+	sessionRepository = repositories.NewSessionRepository(db)
+	teamRepository = repositories.NewTeamRepository(db)
 	summaryRepository = repositories.NewSummaryRepository(db)
 	leaderboardRepository = repositories.NewLeaderboardRepository(db)
+	privateLeaderboardRepository = repositories.NewPrivateLeaderboardRepository(db)
 	keyValueRepository = repositories.NewKeyValueRepository(db)
 	diagnosticsRepository = repositories.NewDiagnosticsRepository(db)
 	metricsRepository = repositories.NewMetricsRepository(db)
+	importJobRepository = repositories.NewImportJobRepository(db)
+	relayTargetRepository = repositories.NewRelayTargetRepository(db)
+	relayOutboxRepository = repositories.NewRelayOutboxRepository(db)
+	webhookRepository = repositories.NewWebhookRepository(db)
+	webhookDeliveryRepository = repositories.NewWebhookDeliveryRepository(db)
+	notificationChannelRepository = repositories.NewNotificationChannelRepository(db)
+	reportSubscriptionRepository = repositories.NewReportSubscriptionRepository(db)
 
 	// Services
 	mailService = mail.NewMailService()
+	notificationService = notification.NewNotificationService(notificationChannelRepository)
 	aliasService = services.NewAliasService(aliasRepository)
-	userService = services.NewUserService(mailService, userRepository)
-	languageMappingService = services.NewLanguageMappingService(languageMappingRepository)
-	projectLabelService = services.NewProjectLabelService(projectLabelRepository)
+	sessionService = services.NewSessionService(sessionRepository)
+	userService = services.NewUserService(mailService, notificationService, sessionService, userRepository)
+	languageMappingService = services.NewLanguageMappingService(languageMappingRepository, defaultLanguageMappingRepository)
+	projectLabelService = services.NewProjectLabelService(projectLabelRepository, labelColorRepository)
 	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService)
 	durationService = services.NewDurationService(heartbeatService)
 	summaryService = services.NewSummaryService(summaryRepository, durationService, aliasService, projectLabelService)
 	aggregationService = services.NewAggregationService(userService, summaryService, heartbeatService)
 	keyValueService = services.NewKeyValueService(keyValueRepository)
-	reportService = services.NewReportService(summaryService, userService, mailService)
+	reportService = services.NewReportService(reportSubscriptionRepository, summaryService, userService, mailService, notificationService)
+	reportRenderer = document.NewReportRenderer()
+	billingService = services.NewBillingService(billingRateRepository, summaryService, projectLabelService)
 	activityService = services.NewActivityService(summaryService)
 	diagnosticsService = services.NewDiagnosticsService(diagnosticsRepository)
 	housekeepingService = services.NewHousekeepingService(userService, heartbeatService, summaryService)
-	miscService = services.NewMiscService(userService, heartbeatService, summaryService, keyValueService, mailService)
+	miscService = services.NewMiscService(userService, heartbeatService, summaryService, keyValueService, mailService, notificationService)
+	oidcService = services.NewOidcService(userService)
+	totpService = services.NewTotpService(userService)
+	teamService = services.NewTeamService(teamRepository, userService, summaryService)
+	relayTargetService = services.NewRelayTargetService(relayTargetRepository)
+	relayService = services.NewRelayService(relayOutboxRepository, userService, relayTargetService)
+	webhookService = services.NewWebhookService(webhookRepository, webhookDeliveryRepository)
+	goalService = services.NewGoalService(userService, summaryService, keyValueService)
 
 	if config.App.LeaderboardEnabled {
 		leaderboardService = services.NewLeaderboardService(leaderboardRepository, summaryService, userService)
+		privateLeaderboardService = services.NewPrivateLeaderboardService(privateLeaderboardRepository, leaderboardService)
+	}
+
+	dataExportService = services.NewDataExportService(userService, heartbeatService, summaryService, aliasService, projectLabelService, languageMappingService, leaderboardService, diagnosticsService, keyValueService, mailService)
+	exportService = services.NewExportService(heartbeatService, durationService, summaryService, aliasService, projectLabelService)
+	importService = services.NewImportService(importJobRepository, userService, heartbeatService, summaryService, aggregationService, keyValueService, mailService, notificationService)
+	wakapiMigrationService = services.NewWakapiMigrationService(importService, summaryService, aliasService, projectLabelService, languageMappingService, keyValueService)
+	adminService = services.NewAdminService(userService, heartbeatService, keyValueService, mailService, sessionService, relayTargetService, relayService, importService)
+
+	if *importFileFlag != "" {
+		runFileImport(*importFileFlag, *importUserFlag)
+		return
 	}
 
 	// Schedule background tasks
@@ -207,16 +280,26 @@ func main() {
 	go reportService.Schedule()
 	go housekeepingService.Schedule()
 	go miscService.Schedule()
+	go sessionService.Schedule()
+	go dataExportService.Schedule()
+	go relayService.Schedule()
+	go webhookService.Schedule()
+	go goalService.Schedule()
 
 	if config.App.LeaderboardEnabled {
 		go leaderboardService.Schedule()
+		go privateLeaderboardService.Schedule()
 	}
 
+	// Continue imports interrupted by the last shutdown
+	importService.ResumeInterrupted()
+	wakapiMigrationService.ResumeInterrupted()
+
 	routes.Init()
 
 	// API Handlers
 	healthApiHandler := api.NewHealthApiHandler(db)
-	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService)
+	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService, relayService, relayTargetService, projectLabelService)
 	summaryApiHandler := api.NewSummaryApiHandler(userService, summaryService)
 	metricsHandler := api.NewMetricsHandler(userService, summaryService, heartbeatService, leaderboardService, keyValueService, metricsRepository)
 	diagnosticsHandler := api.NewDiagnosticsApiHandler(userService, diagnosticsService)
@@ -224,6 +307,9 @@ func main() {
 	activityHandler := api.NewActivityApiHandler(userService, activityService)
 	badgeHandler := api.NewBadgeHandler(userService, summaryService)
 	captchaHandler := api.NewCaptchaHandler()
+	adminApiHandler := api.NewAdminApiHandler(userService, adminService)
+	teamApiHandler := api.NewTeamApiHandler(userService, teamService)
+	exportApiHandler := api.NewExportApiHandler(userService, heartbeatService, aliasService, projectLabelService, languageMappingService, exportService, reportService, reportRenderer)
 
 	// Compat Handlers
 	wakatimeV1StatusBarHandler := wtV1Routes.NewStatusBarHandler(userService, summaryService)
@@ -234,17 +320,23 @@ func main() {
 	wakatimeV1ProjectsHandler := wtV1Routes.NewProjectsHandler(userService, heartbeatService)
 	wakatimeV1HeartbeatsHandler := wtV1Routes.NewHeartbeatHandler(userService, heartbeatService)
 	wakatimeV1LeadersHandler := wtV1Routes.NewLeadersHandler(userService, leaderboardService)
+	wakatimeV1PrivateLeaderboardsHandler := condition.TernaryOperator[bool, routes.Handler](config.App.LeaderboardEnabled, wtV1Routes.NewPrivateLeaderboardsHandler(userService, privateLeaderboardService), routes.NewNoopHandler())
 	shieldV1BadgeHandler := shieldsV1Routes.NewBadgeHandler(summaryService, userService)
 
 	// MVC Handlers
-	summaryHandler := routes.NewSummaryHandler(summaryService, userService, keyValueService)
-	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, keyValueService, mailService)
+	summaryHandler := routes.NewSummaryHandler(summaryService, userService, keyValueService, projectLabelService, reportService, reportRenderer)
+	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, keyValueService, mailService, totpService, sessionService, dataExportService, importService, wakapiMigrationService, relayTargetService, relayService, webhookService, notificationService, reportService)
 	subscriptionHandler := routes.NewSubscriptionHandler(userService, mailService, keyValueService)
 	projectsHandler := routes.NewProjectsHandler(userService, heartbeatService)
+	adminHandler := routes.NewAdminHandler(userService, adminService)
+	teamsHandler := routes.NewTeamsHandler(userService, teamService)
+	billingHandler := routes.NewBillingHandler(userService, heartbeatService, projectLabelService, billingService)
+	dataExportHandler := routes.NewDataExportHandler(dataExportService)
+	csvImportHandler := routes.NewCsvImportHandler(userService, importService)
 	homeHandler := routes.NewHomeHandler(userService, keyValueService)
-	loginHandler := routes.NewLoginHandler(userService, mailService, keyValueService)
+	loginHandler := routes.NewLoginHandler(userService, mailService, keyValueService, oidcService, totpService, sessionService)
 	imprintHandler := routes.NewImprintHandler(keyValueService)
-	leaderboardHandler := condition.TernaryOperator[bool, routes.Handler](config.App.LeaderboardEnabled, routes.NewLeaderboardHandler(userService, leaderboardService), routes.NewNoopHandler())
+	leaderboardHandler := condition.TernaryOperator[bool, routes.Handler](config.App.LeaderboardEnabled, routes.NewLeaderboardHandler(userService, leaderboardService, privateLeaderboardService), routes.NewNoopHandler())
 
 	// Other Handlers
 	relayHandler := relay.NewRelayHandler()
@@ -286,6 +378,11 @@ func main() {
 	leaderboardHandler.RegisterRoutes(rootRouter)
 	projectsHandler.RegisterRoutes(rootRouter)
 	settingsHandler.RegisterRoutes(rootRouter)
+	adminHandler.RegisterRoutes(rootRouter)
+	teamsHandler.RegisterRoutes(rootRouter)
+	billingHandler.RegisterRoutes(rootRouter)
+	dataExportHandler.RegisterRoutes(rootRouter)
+	csvImportHandler.RegisterRoutes(rootRouter)
 	subscriptionHandler.RegisterRoutes(rootRouter)
 	relayHandler.RegisterRoutes(rootRouter)
 
@@ -306,8 +403,12 @@ func main() {
 	wakatimeV1ProjectsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1HeartbeatsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1LeadersHandler.RegisterRoutes(apiRouter)
+	wakatimeV1PrivateLeaderboardsHandler.RegisterRoutes(apiRouter)
 	shieldV1BadgeHandler.RegisterRoutes(apiRouter)
 	captchaHandler.RegisterRoutes(apiRouter)
+	adminApiHandler.RegisterRoutes(apiRouter)
+	teamApiHandler.RegisterRoutes(apiRouter)
+	exportApiHandler.RegisterRoutes(apiRouter)
 
 	// Static Routes
 	// https://github.com/golang/go/issues/43431
@@ -337,6 +438,35 @@ func main() {
 	listen(router)
 }
 
+// runFileImport imports a wakatime export file from disk for the given user, blocking until done
+func runFileImport(path, userId string) {
+	user, err := userService.GetUserById(userId)
+	if err != nil {
+		logbuch.Fatal("user '%s' not found", userId)
+	}
+
+	if path, err = filepath.Abs(path); err != nil {
+		logbuch.Fatal("invalid path '%s' - %v", path, err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		logbuch.Fatal("failed to open '%s' - %v", path, err)
+	}
+
+	// tracked as a job, so that an interrupted import is resumed by the server on its next start
+	job, err := importService.CreateJob(models.NewImportJob(user, models.ImportSourceWakatimeFile).WithParams(&models.ImportJobParams{FilePath: path}))
+	if err != nil {
+		logbuch.Fatal("failed to create import job - %v", err)
+	}
+
+	result, err := importService.RunJob(job, imports.NewWakatimeFileImporter(file, user.WakatimeApiKey), nil)
+	if err != nil {
+		logbuch.Fatal("import failed - %v", err)
+	}
+	logbuch.Info("imported %d heartbeats for user '%s' in %v (%d processed, %d duplicates)", result.Imported, user.ID, result.Duration, result.Processed, result.Duplicates)
+}
+
 func listen(handler http.Handler) {
 	var s4, s6, sSocket *http.Server
 
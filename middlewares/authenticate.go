@@ -21,7 +21,8 @@ const (
 )
 
 var (
-	errEmptyKey = fmt.Errorf("the api_key is empty")
+	errEmptyKey        = fmt.Errorf("the api_key is empty")
+	errAccountDisabled = errors.New("account is disabled")
 )
 
 type AuthenticateMiddleware struct {
@@ -74,6 +75,9 @@ func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	if err != nil && m.config.Security.TrustedHeaderAuth {
 		user, err = m.tryGetUserByTrustedHeader(r)
 	}
+	if err == nil && user != nil && user.IsDisabled {
+		err = errAccountDisabled
+	}
 
 	if err != nil || user == nil {
 		if m.isOptional(r.URL.Path) {
@@ -152,18 +156,16 @@ func (m *AuthenticateMiddleware) tryGetUserByTrustedHeader(r *http.Request) (*mo
 }
 
 func (m *AuthenticateMiddleware) tryGetUserByCookie(r *http.Request) (*models.User, error) {
-	username, err := helpers.ExtractCookieAuth(r, m.config)
+	sessionToken, err := helpers.ExtractCookieAuth(r, m.config)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := m.userSrvc.GetUserById(*username)
+	// cookie only references a server-side session, which might have been revoked or expired in the meantime
+	user, err := m.userSrvc.GetUserBySession(*sessionToken)
 	if err != nil {
 		return nil, err
 	}
 
-	// no need to check password here, as securecookie decoding will fail anyway,
-	// if cookie is not properly signed
-
 	return user, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/muety/wakapi/config"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -179,3 +180,28 @@ func TestAuthenticateMiddleware_tryGetUserByTrustedHeader_Success(t *testing.T)
 }
 
 // TODO: somehow test cookie auth function
+
+func TestAuthenticateMiddleware_ServeHTTP_AccountDisabled(t *testing.T) {
+	config.Set(config.Empty())
+
+	testApiKey := "z5uig69cn9ut93n"
+	testUser := &models.User{ID: "johndoe", ApiKey: testApiKey, IsDisabled: true}
+
+	params := url.Values{}
+	params.Add("api_key", testApiKey)
+	mockRequest := httptest.NewRequest(http.MethodGet, "/?"+params.Encode(), nil)
+	recorder := httptest.NewRecorder()
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", testApiKey).Return(testUser, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	var called bool
+	sut.ServeHTTP(recorder, mockRequest, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
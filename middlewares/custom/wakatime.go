@@ -2,41 +2,36 @@ package relay
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/emvi/logbuch"
-	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
 	"github.com/patrickmn/go-cache"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
 )
 
-const maxFailuresPerDay = 100
-
-// WakatimeRelayMiddleware is a middleware to conditionally relay heartbeats to Wakatime (and other compatible services)
+// WakatimeRelayMiddleware is a middleware to conditionally relay heartbeats to Wakatime (and other compatible services).
+// Besides the user's primary WakaTime connection, heartbeats are mirrored to every enabled relay target, whose project and label filters match.
+// Heartbeats are not sent right away, but put to a persistent outbox, from which they are delivered by the relay service.
 type WakatimeRelayMiddleware struct {
-	httpClient   *http.Client
-	hashCache    *cache.Cache
-	failureCache *cache.Cache
-	eventBus     *hub.Hub
+	hashCache        *cache.Cache
+	relaySrvc        services.IRelayService
+	relayTargetSrvc  services.IRelayTargetService
+	projectLabelSrvc services.IProjectLabelService
 }
 
-func NewWakatimeRelayMiddleware() *WakatimeRelayMiddleware {
+func NewWakatimeRelayMiddleware(relayService services.IRelayService, relayTargetService services.IRelayTargetService, projectLabelService services.IProjectLabelService) *WakatimeRelayMiddleware {
 	return &WakatimeRelayMiddleware{
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		hashCache:    cache.New(10*time.Minute, 10*time.Minute),
-		failureCache: cache.New(24*time.Hour, 1*time.Hour),
-		eventBus:     config.EventBus(),
+		hashCache:        cache.New(10*time.Minute, 10*time.Minute),
+		relaySrvc:        relayService,
+		relayTargetSrvc:  relayTargetService,
+		projectLabelSrvc: projectLabelService,
 	}
 }
 
@@ -57,19 +52,25 @@ func (m *WakatimeRelayMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	}
 
 	user := middlewares.GetPrincipal(r)
-	if user == nil || user.WakatimeApiKey == "" {
+	if user == nil {
 		return
 	}
 
-	err := m.filterByCache(r)
+	targets, err := m.relayTargetSrvc.GetEnabledByUser(user.ID)
 	if err != nil {
-		logbuch.Warn("%v", err)
+		logbuch.Error("failed to fetch relay targets for user %s - %v", user.ID, err)
+		targets = []*models.RelayTarget{}
+	}
+
+	if user.WakatimeApiKey == "" && len(targets) == 0 {
 		return
 	}
 
-	body, _ := ioutil.ReadAll(r.Body)
-	r.Body.Close()
-	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	heartbeats, rawData, err := m.filterByCache(r)
+	if err != nil {
+		logbuch.Warn("%v", err)
+		return
+	}
 
 	// prevent cycles
 	downstreamInstanceId := ownInstanceId
@@ -77,76 +78,71 @@ func (m *WakatimeRelayMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		downstreamInstanceId = originInstanceId
 	}
 
-	headers := http.Header{
-		"X-Machine-Name": r.Header.Values("X-Machine-Name"),
-		"Content-Type":   r.Header.Values("Content-Type"),
-		"Accept":         r.Header.Values("Accept"),
-		"User-Agent":     r.Header.Values("User-Agent"),
-		"X-Origin": []string{
-			fmt.Sprintf("wakapi v%s", config.Get().Version),
-		},
-		"X-Origin-Instance": []string{downstreamInstanceId},
-		"Authorization": []string{
-			fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(user.WakatimeApiKey))),
-		},
-	}
-
-	url := user.WakaTimeURL(config.WakatimeApiUrl) + config.WakatimeApiHeartbeatsBulkUrl
-
-	go m.send(
-		http.MethodPost,
-		url,
-		bytes.NewReader(body),
-		headers,
-		user,
-	)
-}
-
-func (m *WakatimeRelayMiddleware) send(method, url string, body io.Reader, headers http.Header, forUser *models.User) {
-	request, err := http.NewRequest(method, url, body)
-	if err != nil {
-		logbuch.Warn("error constructing relayed request - %v", err)
-		return
+	newItem := func(targetId uint, i int) (*models.RelayOutboxItem, error) {
+		payload, err := json.Marshal(rawData[i])
+		if err != nil {
+			return nil, err
+		}
+		return &models.RelayOutboxItem{
+			UserID:         user.ID,
+			TargetID:       targetId,
+			Hash:           heartbeats[i].Hash,
+			Payload:        string(payload),
+			MachineName:    r.Header.Get("X-Machine-Name"),
+			UserAgent:      r.Header.Get("User-Agent"),
+			OriginInstance: downstreamInstanceId,
+		}, nil
+	}
+
+	items := make([]*models.RelayOutboxItem, 0, len(heartbeats)*(len(targets)+1))
+
+	if user.WakatimeApiKey != "" {
+		for i := range heartbeats {
+			item, err := newItem(0, i)
+			if err != nil {
+				logbuch.Warn("failed to encode relayed heartbeat - %v", err)
+				return
+			}
+			items = append(items, item)
+		}
 	}
 
-	for k, v := range headers {
-		for _, h := range v {
-			request.Header.Set(k, h)
+	if len(targets) > 0 {
+		projectLabels, err := m.projectLabelSrvc.GetByUserGrouped(user.ID)
+		if err != nil {
+			// never risk relaying heartbeats, that would have been excluded by a label
+			logbuch.Error("failed to fetch project labels for user %s - %v", user.ID, err)
+			targets = []*models.RelayTarget{}
 		}
-	}
 
-	response, err := m.httpClient.Do(request)
-	if err != nil {
-		logbuch.Warn("error executing relayed request - %v", err)
-		return
+		for _, target := range targets {
+			for i, hb := range heartbeats {
+				if !target.Matches(hb.Project, labelsOf(projectLabels[hb.Project])) {
+					continue
+				}
+				item, err := newItem(target.ID, i)
+				if err != nil {
+					logbuch.Warn("failed to encode relayed heartbeat - %v", err)
+					return
+				}
+				items = append(items, item)
+			}
+		}
 	}
 
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		logbuch.Warn("failed to relay request for user %s, got status %d", forUser.ID, response.StatusCode)
-
-		// TODO: use leaky bucket instead of expiring cache?
-		if _, found := m.failureCache.Get(forUser.ID); !found {
-			m.failureCache.SetDefault(forUser.ID, 0)
-		}
-		if n, _ := m.failureCache.IncrementInt(forUser.ID, 1); n == maxFailuresPerDay {
-			m.eventBus.Publish(hub.Message{
-				Name:   config.EventWakatimeFailure,
-				Fields: map[string]interface{}{config.FieldUser: forUser, config.FieldPayload: n},
-			})
-		} else if n%10 == 0 {
-			logbuch.Warn("%d / %d failed wakatime heartbeat relaying attempts for user %s within last 24 hours", n, maxFailuresPerDay, forUser.ID)
-		}
+	if err := m.relaySrvc.Enqueue(items); err != nil {
+		config.Log().Request(r).Error("failed to enqueue %d heartbeats for relaying for user %s - %v", len(items), user.ID, err)
 	}
 }
 
-// filterByCache takes an HTTP request, tries to parse the body contents as heartbeats, checks against a local cache for whether a heartbeat has already been relayed before according to its hash and in-place filters these from the request's raw json body.
+// filterByCache takes an HTTP request, tries to parse the body contents as heartbeats, checks against a local cache for whether a heartbeat has already been relayed before according to its hash and returns only the new ones, alongside their raw json representation.
 // This method operates on the raw body data (interface{}), because serialization of models.Heartbeat is not necessarily identical to what the CLI has actually sent.
 // Purpose of this mechanism is mainly to prevent cyclic relays / loops.
-// Caution: this method does in-place changes to the request.
-func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
+// The request's body is left untouched for the subsequent handler.
+func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) ([]*models.Heartbeat, []interface{}, error) {
 	heartbeats, err := routeutils.ParseHeartbeats(r)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	body, _ := ioutil.ReadAll(r.Body)
@@ -155,9 +151,10 @@ func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 
 	var rawData interface{}
 	if err := json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(body))).Decode(&rawData); err != nil {
-		return err
+		return nil, nil, err
 	}
 
+	newHeartbeats := make([]*models.Heartbeat, 0, len(heartbeats))
 	newData := make([]interface{}, 0, len(heartbeats))
 
 	process := func(heartbeat *models.Heartbeat, rawData interface{}) {
@@ -165,6 +162,7 @@ func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 		// we didn't see this particular heartbeat before
 		if _, found := m.hashCache.Get(heartbeat.Hash); !found {
 			m.hashCache.SetDefault(heartbeat.Hash, true)
+			newHeartbeats = append(newHeartbeats, heartbeat)
 			newData = append(newData, rawData)
 		}
 	}
@@ -178,7 +176,7 @@ func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 	}
 
 	if len(newData) == 0 {
-		return errors.New("no new heartbeats to relay")
+		return nil, nil, errors.New("no new heartbeats to relay")
 	}
 
 	if len(newData) != len(heartbeats) {
@@ -186,11 +184,13 @@ func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 		logbuch.Warn("only relaying %d of %d heartbeats for user %s", len(newData), len(heartbeats), user.ID)
 	}
 
-	buf := bytes.Buffer{}
-	if err := json.NewEncoder(&buf).Encode(newData); err != nil {
-		return err
-	}
-	r.Body = ioutil.NopCloser(&buf)
+	return newHeartbeats, newData, nil
+}
 
-	return nil
+func labelsOf(projectLabels []*models.ProjectLabel) []string {
+	labels := make([]string, len(projectLabels))
+	for i, l := range projectLabels {
+		labels[i] = l.Label
+	}
+	return labels
 }
@@ -49,12 +49,12 @@ func (lg *LoggingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		r.URL.String(),
 		duration,
 		ww.BytesWritten(),
-		readUserIP(r),
+		ReadUserIP(r),
 		readUserID(r),
 	)
 }
 
-func readUserIP(r *http.Request) string {
+func ReadUserIP(r *http.Request) string {
 	ip := r.Header.Get("X-Real-Ip")
 	if ip == "" {
 		ip = r.Header.Get("X-Forwarded-For")
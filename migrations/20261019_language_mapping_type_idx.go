@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+// language mappings used to be unique per user and pattern, which doesn't allow for the same pattern to be used
+// with different types (e.g. "Dockerfile" as both file name and path prefix)
+// -> drop index and let it be auto-created again including the type column
+
+func init() {
+	const name = "20261019-language_mapping_type_idx"
+	const idxName = "idx_language_mapping_composite"
+
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if !db.Migrator().HasTable(&models.KeyStringValue{}) || hasRun(name, db) {
+				return nil
+			}
+
+			if db.Migrator().HasIndex(&models.LanguageMapping{}, idxName) {
+				logbuch.Info("running migration '%s'", name)
+				if err := db.Migrator().DropIndex(&models.LanguageMapping{}, idxName); err != nil {
+					logbuch.Warn("failed to drop %s", idxName)
+				}
+			}
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPreMigration(f)
+}
@@ -0,0 +1,19 @@
+package mocks
+
+import (
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/stretchr/testify/mock"
+)
+
+type AggregationServiceMock struct {
+	mock.Mock
+}
+
+func (m *AggregationServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *AggregationServiceMock) AggregateSummaries(set datastructure.Set[string]) error {
+	args := m.Called(set)
+	return args.Error(0)
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type BillingRateRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *BillingRateRepositoryMock) GetById(u uint) (*models.BillingRate, error) {
+	args := m.Called(u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BillingRate), args.Error(1)
+}
+
+func (m *BillingRateRepositoryMock) GetByUser(s string) ([]*models.BillingRate, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.BillingRate), args.Error(1)
+}
+
+func (m *BillingRateRepositoryMock) Insert(rate *models.BillingRate) (*models.BillingRate, error) {
+	args := m.Called(rate)
+	return args.Get(0).(*models.BillingRate), args.Error(1)
+}
+
+func (m *BillingRateRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type DiagnosticsServiceMock struct {
+	mock.Mock
+}
+
+func (m *DiagnosticsServiceMock) Create(diagnostics *models.Diagnostics) (*models.Diagnostics, error) {
+	args := m.Called(diagnostics)
+	return args.Get(0).(*models.Diagnostics), args.Error(1)
+}
+
+func (m *DiagnosticsServiceMock) GetByUser(s string) ([]*models.Diagnostics, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Diagnostics), args.Error(1)
+}
@@ -28,12 +28,27 @@ func (m *HeartbeatServiceMock) Count(a bool) (int64, error) {
 
 func (m *HeartbeatServiceMock) CountByUser(user *models.User) (int64, error) {
 	args := m.Called(user)
-	return args.Get(0).(int64), args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) CountByUserUncached(user *models.User) (int64, error) {
+	args := m.Called(user)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (m *HeartbeatServiceMock) CountByUsers(users []*models.User) ([]*models.CountByUser, error) {
 	args := m.Called(users)
-	return args.Get(0).([]*models.CountByUser), args.Error(0)
+	return args.Get(0).([]*models.CountByUser), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) StreamAllByUser(user *models.User, batchSize int, callback func([]*models.Heartbeat) error) error {
+	args := m.Called(user, batchSize, callback)
+	return args.Error(0)
+}
+
+func (m *HeartbeatServiceMock) GetPageByUser(user *models.User, afterId uint64, from, to time.Time, limit int) ([]*models.Heartbeat, error) {
+	args := m.Called(user, afterId, from, to, limit)
+	return args.Get(0).([]*models.Heartbeat), args.Error(1)
 }
 
 func (m *HeartbeatServiceMock) GetAllWithin(time time.Time, time2 time.Time, user *models.User) ([]*models.Heartbeat, error) {
@@ -51,6 +66,11 @@ func (m *HeartbeatServiceMock) GetFirstByUsers() ([]*models.TimeByUser, error) {
 	return args.Get(0).([]*models.TimeByUser), args.Error(1)
 }
 
+func (m *HeartbeatServiceMock) GetLatestTimesByUsers(users []*models.User) ([]*models.TimeByUser, error) {
+	args := m.Called(users)
+	return args.Get(0).([]*models.TimeByUser), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetLatestByUser(user *models.User) (*models.Heartbeat, error) {
 	args := m.Called(user)
 	return args.Get(0).(*models.Heartbeat), args.Error(1)
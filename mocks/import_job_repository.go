@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ImportJobRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ImportJobRepositoryMock) GetById(id uint) (*models.ImportJob, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) GetByUser(s string, i int) ([]*models.ImportJob, error) {
+	args := m.Called(s, i)
+	return args.Get(0).([]*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) GetLatestByUsers(users []*models.User) ([]*models.ImportJob, error) {
+	args := m.Called(users)
+	return args.Get(0).([]*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) GetByState(s string) ([]*models.ImportJob, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) Insert(job *models.ImportJob) (*models.ImportJob, error) {
+	args := m.Called(job)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) Update(job *models.ImportJob) (*models.ImportJob, error) {
+	args := m.Called(job)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportJob), args.Error(1)
+}
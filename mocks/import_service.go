@@ -0,0 +1,68 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/stretchr/testify/mock"
+)
+
+type ImportServiceMock struct {
+	mock.Mock
+}
+
+func (m *ImportServiceMock) CheckRateLimit(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *ImportServiceMock) GetJobsByUser(user *models.User) ([]*models.ImportJob, error) {
+	args := m.Called(user)
+	return args.Get(0).([]*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportServiceMock) GetLatestJobsByUsers(users []*models.User) ([]*models.ImportJob, error) {
+	args := m.Called(users)
+	return args.Get(0).([]*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportServiceMock) GetRunningJobs(source string) ([]*models.ImportJob, error) {
+	args := m.Called(source)
+	return args.Get(0).([]*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportServiceMock) CreateJob(job *models.ImportJob) (*models.ImportJob, error) {
+	args := m.Called(job)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportServiceMock) UpdateJob(job *models.ImportJob) (*models.ImportJob, error) {
+	args := m.Called(job)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportJob), args.Error(1)
+}
+
+func (m *ImportServiceMock) FailJob(job *models.ImportJob, err error) {
+	m.Called(job, err)
+}
+
+func (m *ImportServiceMock) Submit(job *models.ImportJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *ImportServiceMock) RunJob(job *models.ImportJob, importer imports.DataImporter, checkpoint func(*models.Heartbeat)) (*models.ImportResult, error) {
+	args := m.Called(job, importer, checkpoint)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportResult), args.Error(1)
+}
+
+func (m *ImportServiceMock) ResumeInterrupted() {
+	m.Called()
+}
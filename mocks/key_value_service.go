@@ -19,6 +19,11 @@ func (m *KeyValueServiceMock) MustGetString(s string) *models.KeyStringValue {
 	return args.Get(0).(*models.KeyStringValue)
 }
 
+func (m *KeyValueServiceMock) GetStrings(s []string) ([]*models.KeyStringValue, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.KeyStringValue), args.Error(1)
+}
+
 func (m *KeyValueServiceMock) GetByPrefix(s string) ([]*models.KeyStringValue, error) {
 	args := m.Called(s)
 	return args.Get(0).([]*models.KeyStringValue), args.Error(1)
@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LanguageMappingServiceMock struct {
+	mock.Mock
+}
+
+func (m *LanguageMappingServiceMock) GetById(u uint) (*models.LanguageMapping, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) GetByUser(s string) ([]*models.LanguageMapping, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) ResolveByUser(s string) ([]*models.LanguageMapping, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) Create(mapping *models.LanguageMapping) (*models.LanguageMapping, error) {
+	args := m.Called(mapping)
+	return args.Get(0).(*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) Delete(mapping *models.LanguageMapping) error {
+	args := m.Called(mapping)
+	return args.Error(0)
+}
+
+func (m *LanguageMappingServiceMock) GetDefaults() ([]*models.DefaultLanguageMapping, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.DefaultLanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) ResolveDefaults() ([]*models.LanguageMapping, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) CreateDefault(mapping *models.DefaultLanguageMapping) (*models.DefaultLanguageMapping, error) {
+	args := m.Called(mapping)
+	return args.Get(0).(*models.DefaultLanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) DeleteDefault(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -0,0 +1,80 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/mock"
+)
+
+type LeaderboardServiceMock struct {
+	mock.Mock
+}
+
+func (m *LeaderboardServiceMock) GetDefaultScope() *models.IntervalKey {
+	args := m.Called()
+	return args.Get(0).(*models.IntervalKey)
+}
+
+func (m *LeaderboardServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *LeaderboardServiceMock) ComputeLeaderboard(users []*models.User, key *models.IntervalKey, by []uint8) error {
+	args := m.Called(users, key, by)
+	return args.Error(0)
+}
+
+func (m *LeaderboardServiceMock) ComputePrivateLeaderboard(leaderboard *models.PrivateLeaderboard, users []*models.User) error {
+	args := m.Called(leaderboard, users)
+	return args.Error(0)
+}
+
+func (m *LeaderboardServiceMock) ExistsAnyByUser(s string) (bool, error) {
+	args := m.Called(s)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GetAllByUser(s string) ([]*models.LeaderboardItem, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.LeaderboardItem), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) CountUsers(b bool) (int64, error) {
+	args := m.Called(b)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GetByInterval(key *models.IntervalKey, params *utils.PageParams, b bool) (models.Leaderboard, error) {
+	args := m.Called(key, params, b)
+	return args.Get(0).(models.Leaderboard), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GetByIntervalAndUser(key *models.IntervalKey, s string, b bool) (models.Leaderboard, error) {
+	args := m.Called(key, s, b)
+	return args.Get(0).(models.Leaderboard), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GetAggregatedByInterval(key *models.IntervalKey, by *uint8, params *utils.PageParams, b bool) (models.Leaderboard, error) {
+	args := m.Called(key, by, params, b)
+	return args.Get(0).(models.Leaderboard), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GetAggregatedByIntervalAndUser(key *models.IntervalKey, s string, by *uint8, b bool) (models.Leaderboard, error) {
+	args := m.Called(key, s, by, b)
+	return args.Get(0).(models.Leaderboard), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GetPrivateLeaderboard(leaderboard *models.PrivateLeaderboard, by *uint8, b bool) (models.Leaderboard, error) {
+	args := m.Called(leaderboard, by, b)
+	return args.Get(0).(models.Leaderboard), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GenerateByUser(user *models.User, key *models.IntervalKey) (*models.LeaderboardItem, error) {
+	args := m.Called(user, key)
+	return args.Get(0).(*models.LeaderboardItem), args.Error(1)
+}
+
+func (m *LeaderboardServiceMock) GenerateAggregatedByUser(user *models.User, key *models.IntervalKey, by uint8) ([]*models.LeaderboardItem, error) {
+	args := m.Called(user, key, by)
+	return args.Get(0).([]*models.LeaderboardItem), args.Error(1)
+}
@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type MailServiceMock struct {
+	mock.Mock
+}
+
+func (m *MailServiceMock) SendPasswordReset(u *models.User, s string) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendWakatimeFailureNotification(u *models.User, i int) error {
+	args := m.Called(u, i)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendImportNotification(u *models.User, d time.Duration, i int) error {
+	args := m.Called(u, d, i)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendImportFailureNotification(u *models.User, i int, e []string) error {
+	args := m.Called(u, i, e)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendReport(u *models.User, r *models.Report) error {
+	args := m.Called(u, r)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendSubscriptionNotification(u *models.User, b bool) error {
+	args := m.Called(u, b)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendDataExport(u *models.User, s string, t time.Time) error {
+	args := m.Called(u, s, t)
+	return args.Error(0)
+}
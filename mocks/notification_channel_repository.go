@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationChannelRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *NotificationChannelRepositoryMock) GetById(u uint) (*models.NotificationChannel, error) {
+	args := m.Called(u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationChannelRepositoryMock) GetByUser(s string) ([]*models.NotificationChannel, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationChannelRepositoryMock) Insert(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	args := m.Called(channel)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationChannelRepositoryMock) Update(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	args := m.Called(channel)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationChannelRepositoryMock) RecordResult(u uint, s string) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
+
+func (m *NotificationChannelRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -0,0 +1,79 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type NotificationServiceMock struct {
+	mock.Mock
+}
+
+func (m *NotificationServiceMock) GetById(u uint) (*models.NotificationChannel, error) {
+	args := m.Called(u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationServiceMock) GetByUser(s string) ([]*models.NotificationChannel, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationServiceMock) Create(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	args := m.Called(channel)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationServiceMock) Update(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	args := m.Called(channel)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationServiceMock) Delete(channel *models.NotificationChannel) error {
+	args := m.Called(channel)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) HasChannels(u *models.User, s string) bool {
+	args := m.Called(u, s)
+	return args.Bool(0)
+}
+
+func (m *NotificationServiceMock) SendTest(channel *models.NotificationChannel) error {
+	args := m.Called(channel)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendWakatimeFailureNotification(u *models.User, i int) error {
+	args := m.Called(u, i)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendRelayTargetFailureNotification(u *models.User, t *models.RelayTarget) error {
+	args := m.Called(u, t)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendImportNotification(u *models.User, d time.Duration, i int) error {
+	args := m.Called(u, d, i)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendImportFailureNotification(u *models.User, i int, e []string) error {
+	args := m.Called(u, i, e)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendReport(u *models.User, r *models.Report) error {
+	args := m.Called(u, r)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendSubscriptionNotification(u *models.User, b bool) error {
+	args := m.Called(u, b)
+	return args.Error(0)
+}
@@ -0,0 +1,83 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type PrivateLeaderboardRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *PrivateLeaderboardRepositoryMock) GetAll() ([]*models.PrivateLeaderboard, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.PrivateLeaderboard), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) GetById(id uint) (*models.PrivateLeaderboard, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PrivateLeaderboard), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) GetByInviteCode(s string) (*models.PrivateLeaderboard, error) {
+	args := m.Called(s)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PrivateLeaderboard), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) GetByMember(s string) ([]*models.PrivateLeaderboard, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.PrivateLeaderboard), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) GetMembers(id uint) ([]*models.PrivateLeaderboardMember, error) {
+	args := m.Called(id)
+	return args.Get(0).([]*models.PrivateLeaderboardMember), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) GetMember(id uint, s string) (*models.PrivateLeaderboardMember, error) {
+	args := m.Called(id, s)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PrivateLeaderboardMember), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) Insert(leaderboard *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error) {
+	args := m.Called(leaderboard)
+	if args.Get(0) == nil {
+		return leaderboard, args.Error(1)
+	}
+	return args.Get(0).(*models.PrivateLeaderboard), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) Update(leaderboard *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error) {
+	args := m.Called(leaderboard)
+	if args.Get(0) == nil {
+		return leaderboard, args.Error(1)
+	}
+	return args.Get(0).(*models.PrivateLeaderboard), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) InsertMember(member *models.PrivateLeaderboardMember) (*models.PrivateLeaderboardMember, error) {
+	args := m.Called(member)
+	if args.Get(0) == nil {
+		return member, args.Error(1)
+	}
+	return args.Get(0).(*models.PrivateLeaderboardMember), args.Error(1)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) DeleteMember(id uint, s string) error {
+	args := m.Called(id, s)
+	return args.Error(0)
+}
+
+func (m *PrivateLeaderboardRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -29,6 +29,11 @@ func (p *ProjectLabelServiceMock) GetByUserGroupedInverted(s string) (map[string
 	return args.Get(0).(map[string][]*models.ProjectLabel), args.Error(1)
 }
 
+func (p *ProjectLabelServiceMock) GetColorsByUser(s string) (map[string]string, error) {
+	args := p.Called(s)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
 func (p *ProjectLabelServiceMock) Create(l *models.ProjectLabel) (*models.ProjectLabel, error) {
 	args := p.Called(l)
 	return args.Get(0).(*models.ProjectLabel), args.Error(1)
@@ -38,3 +43,13 @@ func (p *ProjectLabelServiceMock) Delete(l *models.ProjectLabel) error {
 	args := p.Called(l)
 	return args.Error(0)
 }
+
+func (p *ProjectLabelServiceMock) SetColor(c *models.LabelColor) (*models.LabelColor, error) {
+	args := p.Called(c)
+	return args.Get(0).(*models.LabelColor), args.Error(1)
+}
+
+func (p *ProjectLabelServiceMock) DeleteColor(s1, s2 string) error {
+	args := p.Called(s1, s2)
+	return args.Error(0)
+}
@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type RelayOutboxRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RelayOutboxRepositoryMock) InsertBatch(items []*models.RelayOutboxItem) error {
+	args := m.Called(items)
+	return args.Error(0)
+}
+
+func (m *RelayOutboxRepositoryMock) GetDue(t time.Time, i int) ([]*models.RelayOutboxItem, error) {
+	args := m.Called(t, i)
+	return args.Get(0).([]*models.RelayOutboxItem), args.Error(1)
+}
+
+func (m *RelayOutboxRepositoryMock) GetBacklog(s string) (*models.RelayBacklog, error) {
+	args := m.Called(s)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayBacklog), args.Error(1)
+}
+
+func (m *RelayOutboxRepositoryMock) CountUndeliveredByUsers(users []*models.User) ([]*models.CountByUser, error) {
+	args := m.Called(users)
+	return args.Get(0).([]*models.CountByUser), args.Error(1)
+}
+
+func (m *RelayOutboxRepositoryMock) MarkDelivered(ids []uint64, t time.Time) error {
+	args := m.Called(ids, t)
+	return args.Error(0)
+}
+
+func (m *RelayOutboxRepositoryMock) UpdateAttempts(items []*models.RelayOutboxItem) error {
+	args := m.Called(items)
+	return args.Error(0)
+}
+
+func (m *RelayOutboxRepositoryMock) Postpone(ids []uint64, t time.Time) error {
+	args := m.Called(ids, t)
+	return args.Error(0)
+}
+
+func (m *RelayOutboxRepositoryMock) DeleteByIds(ids []uint64) error {
+	args := m.Called(ids)
+	return args.Error(0)
+}
+
+func (m *RelayOutboxRepositoryMock) DeleteDeliveredBefore(t time.Time) (int64, error) {
+	args := m.Called(t)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RelayServiceMock struct {
+	mock.Mock
+}
+
+func (m *RelayServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *RelayServiceMock) Enqueue(items []*models.RelayOutboxItem) error {
+	args := m.Called(items)
+	return args.Error(0)
+}
+
+func (m *RelayServiceMock) GetBacklog(user *models.User) (*models.RelayBacklog, error) {
+	args := m.Called(user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayBacklog), args.Error(1)
+}
+
+func (m *RelayServiceMock) CountBacklogByUsers(users []*models.User) ([]*models.CountByUser, error) {
+	args := m.Called(users)
+	return args.Get(0).([]*models.CountByUser), args.Error(1)
+}
+
+func (m *RelayServiceMock) ProcessOutbox() {
+	m.Called()
+}
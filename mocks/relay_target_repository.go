@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RelayTargetRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RelayTargetRepositoryMock) GetById(id uint) (*models.RelayTarget, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) GetByUser(s string) ([]*models.RelayTarget, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) GetByUsers(users []*models.User) ([]*models.RelayTarget, error) {
+	args := m.Called(users)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) Insert(target *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(target)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) Update(target *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(target)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) RecordSuccess(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *RelayTargetRepositoryMock) RecordFailure(id uint, s string) (int, error) {
+	args := m.Called(id, s)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
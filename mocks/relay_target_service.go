@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RelayTargetServiceMock struct {
+	mock.Mock
+}
+
+func (m *RelayTargetServiceMock) GetById(id uint) (*models.RelayTarget, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetServiceMock) GetByUser(s string) ([]*models.RelayTarget, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetServiceMock) GetByUsers(users []*models.User) ([]*models.RelayTarget, error) {
+	args := m.Called(users)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetServiceMock) GetEnabledByUser(s string) ([]*models.RelayTarget, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetServiceMock) Create(target *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(target)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetServiceMock) Update(target *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(target)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetServiceMock) Delete(target *models.RelayTarget) error {
+	args := m.Called(target)
+	return args.Error(0)
+}
+
+func (m *RelayTargetServiceMock) RecordSuccess(target *models.RelayTarget) error {
+	args := m.Called(target)
+	return args.Error(0)
+}
+
+func (m *RelayTargetServiceMock) RecordFailure(target *models.RelayTarget, s string) error {
+	args := m.Called(target, s)
+	return args.Error(0)
+}
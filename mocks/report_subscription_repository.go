@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type ReportSubscriptionRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ReportSubscriptionRepositoryMock) GetById(u uint) (*models.ReportSubscription, error) {
+	args := m.Called(u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) GetByUser(s string) ([]*models.ReportSubscription, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) GetDue(t time.Time) ([]*models.ReportSubscription, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) Insert(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	args := m.Called(subscription)
+	return args.Get(0).(*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) Update(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	args := m.Called(subscription)
+	return args.Get(0).(*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type SessionRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *SessionRepositoryMock) GetById(s string) (*models.Session, error) {
+	args := m.Called(s)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) GetByUser(s string) ([]*models.Session, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) Insert(session *models.Session) (*models.Session, error) {
+	args := m.Called(session)
+	if args.Get(0) == nil {
+		return session, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) Touch(session *models.Session, t time.Time) error {
+	args := m.Called(session, t)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) Delete(s string) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) DeleteByUser(s string, exceptIds ...string) error {
+	args := m.Called(s, exceptIds)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) DeleteBefore(t time.Time) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionServiceMock struct {
+	mock.Mock
+}
+
+func (m *SessionServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *SessionServiceMock) Create(user *models.User, s string, s2 string) (string, *models.Session, error) {
+	args := m.Called(user, s, s2)
+	return args.String(0), args.Get(1).(*models.Session), args.Error(2)
+}
+
+func (m *SessionServiceMock) GetByToken(s string) (*models.Session, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *SessionServiceMock) GetByUser(user *models.User) ([]*models.Session, error) {
+	args := m.Called(user)
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *SessionServiceMock) Delete(user *models.User, s string) error {
+	args := m.Called(user, s)
+	return args.Error(0)
+}
+
+func (m *SessionServiceMock) DeleteByToken(s string) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+func (m *SessionServiceMock) DeleteByUser(user *models.User, exceptIds ...string) error {
+	args := m.Called(user, exceptIds)
+	return args.Error(0)
+}
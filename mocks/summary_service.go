@@ -26,6 +26,11 @@ func (m *SummaryServiceMock) Summarize(t time.Time, t2 time.Time, u *models.User
 	return args.Get(0).(*models.Summary), args.Error(1)
 }
 
+func (m *SummaryServiceMock) GetPersisted(t time.Time, t2 time.Time, u *models.User) ([]*models.Summary, error) {
+	args := m.Called(t, t2, u)
+	return args.Get(0).([]*models.Summary), args.Error(1)
+}
+
 func (m *SummaryServiceMock) GetLatestByUser() ([]*models.TimeByUser, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.TimeByUser), args.Error(1)
@@ -0,0 +1,78 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type TeamRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *TeamRepositoryMock) GetById(id uint) (*models.Team, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Team), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) GetMemberships(s string) ([]*models.TeamMember, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) GetMembers(id uint) ([]*models.TeamMember, error) {
+	args := m.Called(id)
+	return args.Get(0).([]*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) GetMember(id uint, s string) (*models.TeamMember, error) {
+	args := m.Called(id, s)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) Insert(team *models.Team) (*models.Team, error) {
+	args := m.Called(team)
+	if args.Get(0) == nil {
+		return team, args.Error(1)
+	}
+	return args.Get(0).(*models.Team), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) Update(team *models.Team) (*models.Team, error) {
+	args := m.Called(team)
+	if args.Get(0) == nil {
+		return team, args.Error(1)
+	}
+	return args.Get(0).(*models.Team), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) InsertMember(member *models.TeamMember) (*models.TeamMember, error) {
+	args := m.Called(member)
+	if args.Get(0) == nil {
+		return member, args.Error(1)
+	}
+	return args.Get(0).(*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) UpdateMember(member *models.TeamMember) (*models.TeamMember, error) {
+	args := m.Called(member)
+	if args.Get(0) == nil {
+		return member, args.Error(1)
+	}
+	return args.Get(0).(*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) DeleteMember(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *TeamRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -19,11 +20,26 @@ func (m *UserServiceMock) GetUserByKey(s string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) GetUserBySession(s string) (*models.User, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) GetUserByEmail(s string) (*models.User, error) {
 	args := m.Called(s)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) GetUserByOidcSubject(s string) (*models.User, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) GetUserByCalendarToken(s string) (*models.User, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) GetUserByResetToken(s string) (*models.User, error) {
 	args := m.Called(s)
 	return args.Get(0).(*models.User), args.Error(1)
@@ -69,6 +85,11 @@ func (m *UserServiceMock) GetActive(b bool) ([]*models.User, error) {
 	return args.Get(0).([]*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) Search(q string, pageParams *utils.PageParams) ([]*models.User, error) {
+	args := m.Called(q, pageParams)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) Count() (int64, error) {
 	args := m.Called()
 	return int64(args.Int(0)), args.Error(1)
@@ -84,11 +105,21 @@ func (m *UserServiceMock) Update(user *models.User) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) SetAdmin(user *models.User, isAdmin bool) (*models.User, error) {
+	args := m.Called(user, isAdmin)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) Delete(user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
+func (m *UserServiceMock) ResetCalendarToken(user *models.User) (*models.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) ResetApiKey(user *models.User) (*models.User, error) {
 	args := m.Called(user)
 	return args.Get(0).(*models.User), args.Error(1)
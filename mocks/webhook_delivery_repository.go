@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type WebhookDeliveryRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *WebhookDeliveryRepositoryMock) InsertBatch(deliveries []*models.WebhookDelivery) error {
+	args := m.Called(deliveries)
+	return args.Error(0)
+}
+
+func (m *WebhookDeliveryRepositoryMock) GetDue(t time.Time, i int) ([]*models.WebhookDelivery, error) {
+	args := m.Called(t, i)
+	return args.Get(0).([]*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *WebhookDeliveryRepositoryMock) GetByWebhook(u uint, i int) ([]*models.WebhookDelivery, error) {
+	args := m.Called(u, i)
+	return args.Get(0).([]*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *WebhookDeliveryRepositoryMock) Update(delivery *models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *WebhookDeliveryRepositoryMock) Postpone(ids []uint64, t time.Time) error {
+	args := m.Called(ids, t)
+	return args.Error(0)
+}
+
+func (m *WebhookDeliveryRepositoryMock) DeleteFinishedBefore(t time.Time) (int64, error) {
+	args := m.Called(t)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type WebhookRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *WebhookRepositoryMock) GetById(u uint) (*models.Webhook, error) {
+	args := m.Called(u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) GetByUser(s string) ([]*models.Webhook, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) GetEnabled() ([]*models.Webhook, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) Insert(webhook *models.Webhook) (*models.Webhook, error) {
+	args := m.Called(webhook)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) Update(webhook *models.Webhook) (*models.Webhook, error) {
+	args := m.Called(webhook)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
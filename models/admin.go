@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// AdminUserInfo is an administrative view on a user account, as shown in the admin console and returned by the admin api
+type AdminUserInfo struct {
+	ID                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	IsAdmin               bool       `json:"is_admin"`
+	IsDisabled            bool       `json:"is_disabled"`
+	HasTotp               bool       `json:"has_totp"`
+	HasOidc               bool       `json:"has_oidc"`
+	CreatedAt             time.Time  `json:"created_at"`
+	LastLoggedInAt        time.Time  `json:"last_logged_in_at"`
+	LastHeartbeatAt       *time.Time `json:"last_heartbeat_at"`
+	Heartbeats            int64      `json:"heartbeats"`
+	RelayEnabled          bool       `json:"relay_enabled"`
+	RelayWakatime         bool       `json:"relay_wakatime"` // whether heartbeats are relayed through the legacy wakatime integration
+	RelayUrl              string     `json:"relay_url,omitempty"`
+	RelayTargets          int        `json:"relay_targets"` // number of enabled relay targets
+	RelayBacklog          int64      `json:"relay_backlog"` // number of heartbeats not (yet) delivered to any relay target
+	LastImportAt          *time.Time `json:"last_import_at"`
+	LastImportSuccessAt   *time.Time `json:"last_import_success_at"`
+	LastImportState       string     `json:"last_import_state,omitempty"`
+	HasActiveSubscription bool       `json:"has_active_subscription"`
+}
+
+type AdminPasswordResetResult struct {
+	Link     string `json:"link"`
+	MailSent bool   `json:"mail_sent"`
+}
+
+func NewAdminUserInfo(user *User) *AdminUserInfo {
+	return &AdminUserInfo{
+		ID:                    user.ID,
+		Email:                 user.Email,
+		IsAdmin:               user.IsAdmin,
+		IsDisabled:            user.IsDisabled,
+		HasTotp:               user.HasTotp(),
+		HasOidc:               user.OidcSubject != "",
+		CreatedAt:             user.CreatedAt.T(),
+		LastLoggedInAt:        user.LastLoggedInAt.T(),
+		RelayEnabled:          user.WakatimeApiKey != "",
+		RelayWakatime:         user.WakatimeApiKey != "",
+		RelayUrl:              user.WakatimeApiUrl,
+		HasActiveSubscription: user.HasActiveSubscription(),
+	}
+}
+
+// WithRelayTargets considers the user's relay targets in addition to the legacy wakatime integration
+func (i *AdminUserInfo) WithRelayTargets(targets []*RelayTarget, backlog int64) *AdminUserInfo {
+	for _, t := range targets {
+		if t.Enabled {
+			i.RelayTargets++
+		}
+	}
+	i.RelayEnabled = i.RelayWakatime || i.RelayTargets > 0
+	i.RelayBacklog = backlog
+	return i
+}
+
+// WithImportJob considers the user's latest import job in addition to the timestamps kept for legacy imports
+func (i *AdminUserInfo) WithImportJob(job *ImportJob) *AdminUserInfo {
+	if job == nil {
+		return i
+	}
+	i.LastImportState = job.State
+	i.LastImportAt = latestTime(i.LastImportAt, job.CreatedAt.T())
+	if job.State == ImportJobCompleted && job.FinishedAt != nil {
+		i.LastImportSuccessAt = latestTime(i.LastImportSuccessAt, job.FinishedAt.T())
+	}
+	return i
+}
+
+func latestTime(t1 *time.Time, t2 time.Time) *time.Time {
+	if t1 != nil && t1.After(t2) {
+		return t1
+	}
+	return &t2
+}
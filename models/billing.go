@@ -0,0 +1,227 @@
+package models
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	BillingTargetProject = "project"
+	BillingTargetLabel   = "label"
+)
+
+const (
+	BillingRoundingUp      = "up"
+	BillingRoundingDown    = "down"
+	BillingRoundingNearest = "nearest"
+)
+
+var currencyRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+var billingExportColumns = []string{"project", "target_type", "target", "billable", "currency", "hourly_rate", "valid_from", "days", "hours", "billed_hours", "amount"}
+
+// BillingRate is an hourly rate for either a single project or a label, applying to all projects labeled with it or any
+// label nested below it. Rates are versioned: instead of being edited, a new rate for the same target is added, which
+// takes effect at its ValidFrom date, so that amounts for earlier periods remain unchanged.
+type BillingRate struct {
+	ID              uint       `json:"id" gorm:"primary_key"`
+	User            *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID          string     `json:"-" gorm:"not null; index:idx_billing_rate_user"`
+	TargetType      string     `json:"target_type" gorm:"type:varchar(16)"`
+	TargetKey       string     `json:"target" gorm:"type:varchar(255)"`
+	HourlyRate      float64    `json:"hourly_rate"`
+	Currency        string     `json:"currency" gorm:"type:varchar(3)"`
+	Billable        bool       `json:"billable" gorm:"default:true; type:bool"`
+	RoundingMinutes int        `json:"rounding_minutes" gorm:"default:0"`                   // increment to round every day's time per project to, none if zero
+	RoundingMode    string     `json:"rounding_mode" gorm:"type:varchar(16); default:'up'"` // one of up, down or nearest
+	ValidFrom       CustomTime `json:"valid_from" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt       CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type BillingRates []*BillingRate
+
+// BillingReport holds the billable amounts of a user within a given range, one line per project and applicable rate
+type BillingReport struct {
+	From   time.Time
+	To     time.Time
+	Lines  []*BillingLine
+	Totals []*BillingTotal // per currency, only including billable lines
+}
+
+type BillingLine struct {
+	Project        string
+	Rate           *BillingRate // nil if no rate applies to the project
+	Days           int
+	Duration       time.Duration
+	BilledDuration time.Duration // after rounding
+	Amount         float64
+}
+
+type BillingTotal struct {
+	Currency       string
+	BilledDuration time.Duration
+	Amount         float64
+}
+
+// BillingExportColumns returns the columns of a billing report export, in their order
+func BillingExportColumns() []string {
+	return billingExportColumns
+}
+
+func (r *BillingRate) IsValid() bool {
+	if r.TargetType != BillingTargetProject && r.TargetType != BillingTargetLabel {
+		return false
+	}
+	if r.TargetType == BillingTargetLabel && !ValidateLabel(r.TargetKey) {
+		return false
+	}
+	if r.TargetKey == "" || len(r.TargetKey) > 255 {
+		return false
+	}
+	if r.RoundingMode != BillingRoundingUp && r.RoundingMode != BillingRoundingDown && r.RoundingMode != BillingRoundingNearest {
+		return false
+	}
+	return r.HourlyRate >= 0 && !math.IsInf(r.HourlyRate, 0) && !math.IsNaN(r.HourlyRate) &&
+		currencyRegex.MatchString(r.Currency) &&
+		r.RoundingMinutes >= 0 && r.RoundingMinutes <= 24*60 &&
+		!r.ValidFrom.T().IsZero()
+}
+
+// IsUpcoming tells whether the rate only takes effect in the future, i.e. hasn't applied to any period, yet
+func (r *BillingRate) IsUpcoming() bool {
+	return r.ValidFrom.T().After(time.Now())
+}
+
+// Round rounds the given duration to the rate's increment, according to its rounding mode
+func (r *BillingRate) Round(d time.Duration) time.Duration {
+	if r.RoundingMinutes <= 0 || d <= 0 {
+		return d
+	}
+	increment := time.Duration(r.RoundingMinutes) * time.Minute
+	switch r.RoundingMode {
+	case BillingRoundingDown:
+		return d.Truncate(increment)
+	case BillingRoundingNearest:
+		return d.Round(increment)
+	default:
+		if rounded := d.Truncate(increment); rounded < d {
+			return rounded + increment
+		}
+		return d
+	}
+}
+
+// Amount returns the amount to bill for the given (already rounded) duration, or zero for non-billable rates
+func (r *BillingRate) Amount(d time.Duration) float64 {
+	if !r.Billable {
+		return 0
+	}
+	return math.Round(d.Hours()*r.HourlyRate*100) / 100
+}
+
+// Resolve returns the rate applying to the given project on the given day, if any. A rate for the project itself takes
+// precedence over rates for any of its labels, among which the most specific one wins, i.e. one for "client/acme" over one
+// for "client". Of multiple versions of a rate, the one most recently valid at the given day applies.
+func (rates BillingRates) Resolve(project string, labels []string, day time.Time) *BillingRate {
+	if rate := rates.effective(BillingTargetProject, project, day); rate != nil {
+		return rate
+	}
+
+	var result *BillingRate
+	var resultDepth int
+	for _, label := range labels {
+		for _, ancestor := range LabelAncestors(label) {
+			depth := LabelDepth(ancestor)
+			if depth <= resultDepth {
+				continue
+			}
+			if rate := rates.effective(BillingTargetLabel, ancestor, day); rate != nil {
+				result, resultDepth = rate, depth
+			}
+		}
+	}
+	return result
+}
+
+// Versions returns all rates grouped by their target, with the most recent version first
+func (rates BillingRates) Versions() []BillingRates {
+	sorted := make(BillingRates, len(rates))
+	copy(sorted, rates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].TargetType != sorted[j].TargetType {
+			return sorted[i].TargetType > sorted[j].TargetType // projects first
+		}
+		if sorted[i].TargetKey != sorted[j].TargetKey {
+			return sorted[i].TargetKey < sorted[j].TargetKey
+		}
+		return sorted[i].isNewerThan(sorted[j])
+	})
+
+	groups := make([]BillingRates, 0)
+	for i, rate := range sorted {
+		if i == 0 || rate.TargetType != sorted[i-1].TargetType || rate.TargetKey != sorted[i-1].TargetKey {
+			groups = append(groups, BillingRates{})
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], rate)
+	}
+	return groups
+}
+
+func (rates BillingRates) effective(targetType, targetKey string, day time.Time) *BillingRate {
+	var result *BillingRate
+	for _, rate := range rates {
+		if rate.TargetType != targetType || rate.TargetKey != targetKey || rate.ValidFrom.T().After(day) {
+			continue
+		}
+		if result == nil || rate.isNewerThan(result) {
+			result = rate
+		}
+	}
+	return result
+}
+
+func (r *BillingRate) isNewerThan(other *BillingRate) bool {
+	if !r.ValidFrom.T().Equal(other.ValidFrom.T()) {
+		return r.ValidFrom.T().After(other.ValidFrom.T())
+	}
+	return r.ID > other.ID
+}
+
+func (l *BillingLine) IsBillable() bool {
+	return l.Rate != nil && l.Rate.Billable
+}
+
+func (l *BillingLine) ExportValue(column string) interface{} {
+	switch column {
+	case "project":
+		return l.Project
+	case "billable":
+		return l.IsBillable()
+	case "days":
+		return l.Days
+	case "hours":
+		return math.Round(l.Duration.Hours()*100) / 100
+	case "billed_hours":
+		return math.Round(l.BilledDuration.Hours()*100) / 100
+	case "amount":
+		return l.Amount
+	}
+	if l.Rate == nil {
+		return nil
+	}
+	switch column {
+	case "target_type":
+		return l.Rate.TargetType
+	case "target":
+		return l.Rate.TargetKey
+	case "currency":
+		return l.Rate.Currency
+	case "hourly_rate":
+		return l.Rate.HourlyRate
+	case "valid_from":
+		return l.Rate.ValidFrom.T().Format(time.DateOnly)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBillingRate_IsValid(t *testing.T) {
+	rate := &BillingRate{TargetType: BillingTargetLabel, TargetKey: "client/acme", HourlyRate: 80, Currency: "EUR", RoundingMode: BillingRoundingUp, ValidFrom: CustomTime(time.Now())}
+	assert.True(t, rate.IsValid())
+
+	rate.TargetKey = "client//acme"
+	assert.False(t, rate.IsValid())
+
+	rate.TargetKey = "client/acme"
+	rate.Currency = "euro"
+	assert.False(t, rate.IsValid())
+
+	rate.Currency = "EUR"
+	rate.HourlyRate = -1
+	assert.False(t, rate.IsValid())
+
+	rate.HourlyRate = 80
+	rate.ValidFrom = CustomTime{}
+	assert.False(t, rate.IsValid())
+}
+
+func TestBillingRate_Round(t *testing.T) {
+	rate := &BillingRate{RoundingMinutes: 15, RoundingMode: BillingRoundingUp}
+	assert.Equal(t, 15*time.Minute, rate.Round(1*time.Minute))
+	assert.Equal(t, 30*time.Minute, rate.Round(30*time.Minute))
+	assert.Equal(t, 45*time.Minute, rate.Round(31*time.Minute))
+	assert.Equal(t, time.Duration(0), rate.Round(0))
+
+	rate.RoundingMode = BillingRoundingDown
+	assert.Equal(t, 30*time.Minute, rate.Round(44*time.Minute))
+
+	rate.RoundingMode = BillingRoundingNearest
+	assert.Equal(t, 30*time.Minute, rate.Round(37*time.Minute))
+	assert.Equal(t, 45*time.Minute, rate.Round(38*time.Minute))
+
+	rate.RoundingMinutes = 0
+	assert.Equal(t, 37*time.Minute, rate.Round(37*time.Minute))
+}
+
+func TestBillingRate_Amount(t *testing.T) {
+	rate := &BillingRate{HourlyRate: 90, Billable: true}
+	assert.Equal(t, 67.5, rate.Amount(45*time.Minute))
+
+	rate.Billable = false
+	assert.Zero(t, rate.Amount(45*time.Minute))
+}
+
+func TestBillingRates_Resolve(t *testing.T) {
+	jan, feb, mar := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	rates := BillingRates{
+		{ID: 1, TargetType: BillingTargetLabel, TargetKey: "client", ValidFrom: CustomTime(jan)},
+		{ID: 2, TargetType: BillingTargetLabel, TargetKey: "client/acme", ValidFrom: CustomTime(jan)},
+		{ID: 3, TargetType: BillingTargetLabel, TargetKey: "client/acme", ValidFrom: CustomTime(mar)},
+		{ID: 4, TargetType: BillingTargetProject, TargetKey: "wakapi", ValidFrom: CustomTime(feb)},
+	}
+
+	assert.Equal(t, uint(2), rates.Resolve("anchr", []string{"client/acme/backend"}, feb).ID)
+	assert.Equal(t, uint(3), rates.Resolve("anchr", []string{"client/acme/backend"}, mar).ID)
+	assert.Equal(t, uint(1), rates.Resolve("anchr", []string{"client/other", "private"}, feb).ID)
+	assert.Equal(t, uint(2), rates.Resolve("wakapi", []string{"client/acme"}, jan).ID) // project rate not valid yet
+	assert.Equal(t, uint(4), rates.Resolve("wakapi", []string{"client/acme"}, mar).ID)
+	assert.Nil(t, rates.Resolve("anchr", []string{"private"}, mar))
+	assert.Nil(t, rates.Resolve("anchr", []string{"client"}, jan.Add(-1*time.Hour)))
+}
+
+func TestBillingRates_Versions(t *testing.T) {
+	jan, feb := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	rates := BillingRates{
+		{ID: 1, TargetType: BillingTargetLabel, TargetKey: "client", ValidFrom: CustomTime(jan)},
+		{ID: 2, TargetType: BillingTargetProject, TargetKey: "wakapi", ValidFrom: CustomTime(jan)},
+		{ID: 3, TargetType: BillingTargetLabel, TargetKey: "client", ValidFrom: CustomTime(feb)},
+	}
+
+	versions := rates.Versions()
+	assert.Len(t, versions, 2)
+	assert.Equal(t, "wakapi", versions[0][0].TargetKey)
+	assert.Equal(t, []uint{3, 1}, []uint{versions[1][0].ID, versions[1][1].ID})
+}
@@ -0,0 +1,23 @@
+package v1
+
+import "time"
+
+// partially compatible with https://wakatime.com/developers#private_leaderboards
+
+type PrivateLeaderboardsViewModel struct {
+	Data       []*PrivateLeaderboardEntry `json:"data"`
+	Total      int                        `json:"total"`
+	TotalPages int                        `json:"total_pages"`
+}
+
+type PrivateLeaderboardEntry struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Code         string    `json:"code,omitempty"` // only visible to the owner
+	Language     string    `json:"language,omitempty"`
+	TimeRange    string    `json:"time_range"`
+	MembersCount int       `json:"members_count"`
+	CanEdit      bool      `json:"can_edit"`
+	CanDelete    bool      `json:"can_delete"`
+	CreatedAt    time.Time `json:"created_at"`
+}
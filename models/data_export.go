@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// DataExportProfile is a user's account data as included in a data export, i.e. everything except credentials and other secrets
+type DataExportProfile struct {
+	ID                     string      `json:"id"`
+	Email                  string      `json:"email"`
+	Location               string      `json:"location"`
+	CreatedAt              CustomTime  `json:"created_at"`
+	LastLoggedInAt         CustomTime  `json:"last_logged_in_at"`
+	ShareDataMaxDays       int         `json:"share_data_max_days"`
+	ShareEditors           bool        `json:"share_editors"`
+	ShareLanguages         bool        `json:"share_languages"`
+	ShareProjects          bool        `json:"share_projects"`
+	ShareOSs               bool        `json:"share_oss"`
+	ShareMachines          bool        `json:"share_machines"`
+	ShareLabels            bool        `json:"share_labels"`
+	IsAdmin                bool        `json:"is_admin"`
+	ReportsWeekly          bool        `json:"reports_weekly"`
+	PublicLeaderboard      bool        `json:"public_leaderboard"`
+	ExcludeUnknownProjects bool        `json:"exclude_unknown_projects"`
+	TicketPattern          string      `json:"ticket_pattern"`
+	DailyGoalMinutes       int         `json:"daily_goal_minutes"`
+	WakatimeApiUrl         string      `json:"wakatime_api_url"`
+	HasWakatimeApiKey      bool        `json:"has_wakatime_api_key"`
+	OidcSubject            string      `json:"oidc_subject"`
+	TotpEnabled            bool        `json:"totp_enabled"`
+	InvitedBy              string      `json:"invited_by"`
+	SubscribedUntil        *CustomTime `json:"subscribed_until"`
+}
+
+// DataExportToken is what download links of data exports carry (signed and encrypted)
+type DataExportToken struct {
+	UserID    string    `json:"user_id"`
+	FileName  string    `json:"file_name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewDataExportProfile(user *User) *DataExportProfile {
+	return &DataExportProfile{
+		ID:                     user.ID,
+		Email:                  user.Email,
+		Location:               user.Location,
+		CreatedAt:              user.CreatedAt,
+		LastLoggedInAt:         user.LastLoggedInAt,
+		ShareDataMaxDays:       user.ShareDataMaxDays,
+		ShareEditors:           user.ShareEditors,
+		ShareLanguages:         user.ShareLanguages,
+		ShareProjects:          user.ShareProjects,
+		ShareOSs:               user.ShareOSs,
+		ShareMachines:          user.ShareMachines,
+		ShareLabels:            user.ShareLabels,
+		IsAdmin:                user.IsAdmin,
+		ReportsWeekly:          user.ReportsWeekly,
+		PublicLeaderboard:      user.PublicLeaderboard,
+		ExcludeUnknownProjects: user.ExcludeUnknownProjects,
+		TicketPattern:          user.TicketPattern,
+		DailyGoalMinutes:       user.DailyGoalMinutes,
+		WakatimeApiUrl:         user.WakatimeApiUrl,
+		HasWakatimeApiKey:      user.WakatimeApiKey != "",
+		OidcSubject:            user.OidcSubject,
+		TotpEnabled:            user.TotpEnabled,
+		InvitedBy:              user.InvitedBy,
+		SubscribedUntil:        user.SubscribedUntil,
+	}
+}
+
+func (t *DataExportToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// HeartbeatsExportPage is a chunk of a user's heartbeats as served by the native export api, ordered by id
+type HeartbeatsExportPage struct {
+	Data    []*Heartbeat `json:"data"`
+	Cursor  uint64       `json:"cursor"` // id of the page's last heartbeat, to be passed as 'after' for fetching the next page
+	HasMore bool         `json:"has_more"`
+}
+
+// SettingsExport holds those of a user's settings, which affect how their data is presented, e.g. for migrating to another instance
+type SettingsExport struct {
+	Aliases          []*Alias           `json:"aliases"`
+	ProjectLabels    []*ProjectLabel    `json:"project_labels"`
+	LabelColors      map[string]string  `json:"label_colors"`
+	LanguageMappings []*LanguageMapping `json:"language_mappings"`
+}
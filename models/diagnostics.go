@@ -1,11 +1,13 @@
 package models
 
 type Diagnostics struct {
-	ID           uint   `gorm:"primary_key"`
-	Platform     string `json:"platform"`
-	Architecture string `json:"architecture"`
-	Plugin       string `json:"plugin"`
-	CliVersion   string `json:"cli_version"`
-	Logs         string `json:"logs" gorm:"type:text"`
-	StackTrace   string `json:"stacktrace" gorm:"type:text"`
+	ID           uint    `gorm:"primary_key"`
+	Platform     string  `json:"platform"`
+	Architecture string  `json:"architecture"`
+	Plugin       string  `json:"plugin"`
+	CliVersion   string  `json:"cli_version"`
+	Logs         string  `json:"logs" gorm:"type:text"`
+	StackTrace   string  `json:"stacktrace" gorm:"type:text"`
+	Submitter    *User   `json:"-" gorm:"foreignKey:SubmittedBy; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	SubmittedBy  *string `json:"-" gorm:"index:idx_diagnostics_submitter"` // only set if submitted with a valid api key, in order to include it in data exports
 }
@@ -19,6 +19,7 @@ type Duration struct {
 	Machine         string        `json:"machine"`
 	Branch          string        `json:"branch"`
 	Entity          string        `json:"Entity"`
+	Ticket          string        `json:"ticket"`
 	NumHeartbeats   int           `json:"-" hash:"ignore"`
 	GroupHash       string        `json:"-" hash:"ignore"`
 	excludeEntity   bool          `json:"-" hash:"ignore"`
@@ -60,6 +61,11 @@ func (d *Duration) WithEntityIgnored() *Duration {
 	return d
 }
 
+func (d *Duration) WithTicket(ticket string) *Duration {
+	d.Ticket = ticket
+	return d
+}
+
 func (d *Duration) Hashed() *Duration {
 	hash, err := hashstructure.Hash(d, hashstructure.FormatV2, nil)
 	if err != nil {
@@ -85,6 +91,8 @@ func (d *Duration) GetKey(t uint8) (key string) {
 		key = d.Branch
 	case SummaryEntity:
 		key = d.Entity
+	case SummaryTicket:
+		key = d.Ticket
 	}
 
 	if key == "" {
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ExportFormatCsv    = "csv"
+	ExportFormatNdjson = "ndjson"
+)
+
+const (
+	ExportHeartbeats = "heartbeats"
+	ExportDurations  = "durations"
+	ExportSummaries  = "summaries"
+	ExportWorklogs   = "worklogs"
+)
+
+var exportColumns = map[string][]string{
+	ExportHeartbeats: {"time", "entity", "type", "category", "project", "branch", "language", "is_write", "editor", "operating_system", "machine", "user_agent"},
+	ExportDurations:  {"time", "duration", "project", "branch", "language", "editor", "operating_system", "machine", "ticket"},
+	ExportSummaries:  {"date", "type", "key", "total"},
+	ExportWorklogs:   {"issue_key", "date", "started", "time_spent_seconds", "time_spent", "comment"}, // as understood by common jira worklog importers
+}
+
+// ExportParams describes which data to include in a heartbeat, duration, summary or worklog export
+type ExportParams struct {
+	From    time.Time
+	To      time.Time
+	Filters *Filters
+	Columns []string
+	Raw     bool // if true, aliases are not resolved
+}
+
+// ExportColumns returns all columns available for the given kind of export, in their default order
+func ExportColumns(kind string) []string {
+	return exportColumns[kind]
+}
+
+func IsValidExportFormat(format string) bool {
+	return format == ExportFormatCsv || format == ExportFormatNdjson
+}
+
+// WithColumns selects the given comma-separated columns or all columns of the given kind of export if empty
+func (p *ExportParams) WithColumns(kind, columns string) (*ExportParams, error) {
+	available := ExportColumns(kind)
+	if strings.TrimSpace(columns) == "" {
+		p.Columns = available
+		return p, nil
+	}
+
+	p.Columns = []string{}
+	for _, c := range strings.Split(columns, ",") {
+		c = strings.TrimSpace(c)
+		if !slices.Contains(available, c) {
+			return nil, fmt.Errorf("unknown column '%s', available columns are: %s", c, strings.Join(available, ", "))
+		}
+		p.Columns = append(p.Columns, c)
+	}
+	return p, nil
+}
+
+func (h *Heartbeat) ExportValue(column string) interface{} {
+	switch column {
+	case "time":
+		return h.Time.T()
+	case "entity":
+		return h.Entity
+	case "type":
+		return h.Type
+	case "category":
+		return h.Category
+	case "project":
+		return h.Project
+	case "branch":
+		return h.Branch
+	case "language":
+		return h.Language
+	case "is_write":
+		return h.IsWrite
+	case "editor":
+		return h.Editor
+	case "operating_system":
+		return h.OperatingSystem
+	case "machine":
+		return h.Machine
+	case "user_agent":
+		return h.UserAgent
+	}
+	return nil
+}
+
+func (d *Duration) ExportValue(column string) interface{} {
+	switch column {
+	case "time":
+		return d.Time.T()
+	case "duration":
+		return d.Duration.Seconds()
+	case "project":
+		return d.Project
+	case "branch":
+		return d.Branch
+	case "language":
+		return d.Language
+	case "editor":
+		return d.Editor
+	case "operating_system":
+		return d.OperatingSystem
+	case "machine":
+		return d.Machine
+	case "ticket":
+		return d.Ticket
+	}
+	return nil
+}
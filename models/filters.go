@@ -15,7 +15,9 @@ type Filters struct {
 	Label              OrFilter
 	Branch             OrFilter
 	Entity             OrFilter
+	Ticket             OrFilter
 	SelectFilteredOnly bool // flag indicating to drop all Entity types from a summary except the single one filtered by
+	LabelDepth         int  // depth at which to roll up hierarchical labels (e.g. 1 to sum up "client/acme" and "client/other" as "client"), 0 for no roll-up
 }
 
 type OrFilter []string
@@ -60,6 +62,13 @@ func (f *Filters) WithSelectFilteredOnly() *Filters {
 	return f
 }
 
+func (f *Filters) WithLabelDepth(depth int) *Filters {
+	if depth >= 0 {
+		f.LabelDepth = depth
+	}
+	return f
+}
+
 func (f *Filters) WithMultiple(entity uint8, keys []string) *Filters {
 	switch entity {
 	case SummaryProject:
@@ -78,6 +87,8 @@ func (f *Filters) WithMultiple(entity uint8, keys []string) *Filters {
 		f.Branch = append(f.Branch, keys...)
 	case SummaryEntity:
 		f.Entity = append(f.Entity, keys...)
+	case SummaryTicket:
+		f.Ticket = append(f.Ticket, keys...)
 	}
 	return f
 }
@@ -99,6 +110,8 @@ func (f *Filters) One() (bool, uint8, OrFilter) {
 		return true, SummaryBranch, f.Branch
 	} else if f.Entity != nil && f.Entity.Exists() {
 		return true, SummaryEntity, f.Entity
+	} else if f.Ticket != nil && f.Ticket.Exists() {
+		return true, SummaryTicket, f.Ticket
 	}
 	return false, 0, OrFilter{}
 }
@@ -117,7 +130,7 @@ func (f *Filters) IsEmpty() bool {
 
 func (f *Filters) Count() int {
 	var count int
-	for i := SummaryProject; i <= SummaryEntity; i++ {
+	for i := SummaryProject; i <= SummaryTicket; i++ {
 		count += f.CountByType(i)
 	}
 	return count
@@ -125,7 +138,7 @@ func (f *Filters) Count() int {
 
 func (f *Filters) CountDistinctTypes() int {
 	var count int
-	for i := SummaryProject; i <= SummaryEntity; i++ {
+	for i := SummaryProject; i <= SummaryTicket; i++ {
 		if f.CountByType(i) > 0 {
 			count += f.CountByType(i)
 		}
@@ -139,7 +152,7 @@ func (f *Filters) CountByType(entity uint8) int {
 
 func (f *Filters) EntityCount() int {
 	var count int
-	for i := SummaryProject; i <= SummaryEntity; i++ {
+	for i := SummaryProject; i <= SummaryTicket; i++ {
 		if c := f.CountByType(i); c > 0 {
 			count++
 		}
@@ -165,6 +178,8 @@ func (f *Filters) ResolveType(entityId uint8) *OrFilter {
 		return &f.Branch
 	case SummaryEntity:
 		return &f.Entity
+	case SummaryTicket:
+		return &f.Ticket
 	default:
 		return &OrFilter{}
 	}
@@ -191,7 +206,8 @@ func (f *Filters) MatchDuration(d *Duration) bool {
 		(f.OS == nil || f.OS.MatchAny(d.OperatingSystem)) &&
 		(f.Language == nil || f.Language.MatchAny(d.Language)) &&
 		(f.Editor == nil || f.Editor.MatchAny(d.Editor)) &&
-		(f.Machine == nil || f.Machine.MatchAny(d.Machine))
+		(f.Machine == nil || f.Machine.MatchAny(d.Machine)) &&
+		(f.Ticket == nil || f.Ticket.MatchAny(d.Ticket))
 }
 
 // WithAliases adds OR-conditions for every alias of a Filter key as additional Filter keys
@@ -2,7 +2,6 @@ package models
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/duke-git/lancet/v2/strutil"
@@ -57,13 +56,17 @@ func (h *Heartbeat) Sanitize() *Heartbeat {
 	return h
 }
 
-func (h *Heartbeat) Augment(languageMappings map[string]string) {
-	maxPrec := -1 // precision / mapping complexity -> more concrete ones shall take precedence
-	for ending, value := range languageMappings {
-		if ok, prec := strings.HasSuffix(h.Entity, "."+ending), strings.Count(ending, "."); ok && prec > maxPrec {
-			h.Language = value
-			maxPrec = prec
-		}
+// Augment overrides the heartbeat's language according to the best matching of the given mappings.
+// Default mappings are only considered as a fallback, i.e. if the heartbeat has no language, yet.
+func (h *Heartbeat) Augment(languageMappings []*LanguageMapping, defaultMappings []*LanguageMapping) {
+	if language, ok := matchLanguage(h.Entity, languageMappings); ok {
+		h.Language = language
+	}
+	if h.Language != "" {
+		return
+	}
+	if language, ok := matchLanguage(h.Entity, defaultMappings); ok {
+		h.Language = language
 	}
 }
 
@@ -134,5 +137,19 @@ func GetEntityColumn(t uint8) string {
 		"machine",
 		"label",
 		"branch",
+		"entity",
+		"ticket",
 	}[t]
 }
+
+func matchLanguage(entity string, mappings []*LanguageMapping) (string, bool) {
+	var language string
+	maxPrec := -1 // precision / mapping complexity -> more concrete ones shall take precedence
+	for _, m := range mappings {
+		if ok, prec := m.Match(entity); ok && prec > maxPrec {
+			language = m.Language
+			maxPrec = prec
+		}
+	}
+	return language, maxPrec >= 0
+}
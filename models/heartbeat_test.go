@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -25,11 +26,11 @@ func TestHeartbeat_Valid_MissingUser(t *testing.T) {
 }
 
 func TestHeartbeat_Augment(t *testing.T) {
-	testMappings := map[string]string{
-		"py":        "Python3",
-		"foo":       "Foo Script",
-		"php":       "PHP 8",
-		"blade.php": "Blade",
+	testMappings := []*LanguageMapping{
+		{Extension: "py", Language: "Python3"},
+		{Extension: "foo", Language: "Foo Script"},
+		{Extension: "php", Language: "PHP 8"},
+		{Extension: "blade.php", Language: "Blade"},
 	}
 
 	sut1, sut2, sut3 := &Heartbeat{
@@ -43,15 +44,53 @@ func TestHeartbeat_Augment(t *testing.T) {
 		Language: "PHP",
 	}
 
-	sut1.Augment(testMappings)
-	sut2.Augment(testMappings)
-	sut3.Augment(testMappings)
+	sut1.Augment(testMappings, nil)
+	sut2.Augment(testMappings, nil)
+	sut3.Augment(testMappings, nil)
 
 	assert.Equal(t, "Python3", sut1.Language)
 	assert.Equal(t, "Blade", sut2.Language)
 	assert.Equal(t, "PHP 8", sut3.Language)
 }
 
+func TestHeartbeat_Augment_Patterns(t *testing.T) {
+	testMappings := []*LanguageMapping{
+		{Extension: "php", Type: LanguageMappingTypeExtension, Language: "PHP 8"},
+		{Extension: "*.blade.php", Type: LanguageMappingTypeGlob, Language: "Blade"},
+		{Extension: "Jenkinsfile", Type: LanguageMappingTypeFilename, Language: "Groovy"},
+		{Extension: "ci/*.conf", Type: LanguageMappingTypeGlob, Language: "CI Config"},
+		{Extension: "/home/me/scripts/", Type: LanguageMappingTypePrefix, Language: "Bash"},
+	}
+
+	testDefaultMappings := []*LanguageMapping{
+		{Extension: "Dockerfile", Type: LanguageMappingTypeFilename, Language: "Docker"},
+		{Extension: "Makefile", Type: LanguageMappingTypeFilename, Language: "Makefile"},
+	}
+
+	tests := []struct {
+		entity   string
+		language string
+		expected string
+	}{
+		{"/home/me/dev/views/index.blade.php", "PHP", "Blade"},
+		{"/home/me/dev/index.php", "", "PHP 8"},
+		{"/home/me/dev/Jenkinsfile", "", "Groovy"},
+		{"C:\\dev\\Jenkinsfile", "", "Groovy"},
+		{"/home/me/dev/ci/deploy.conf", "", "CI Config"},
+		{"/home/me/dev/deploy.conf", "", ""},
+		{"/home/me/scripts/backup", "", "Bash"},
+		{"/home/me/dev/Dockerfile", "", "Docker"},
+		{"/home/me/dev/Dockerfile", "Dockerfile", "Dockerfile"}, // default mappings don't override
+		{"/home/me/dev/Makefile.bak", "", ""},
+	}
+
+	for _, tt := range tests {
+		sut := &Heartbeat{Entity: tt.entity, Language: tt.language}
+		sut.Augment(testMappings, testDefaultMappings)
+		assert.Equal(t, tt.expected, sut.Language, tt.entity)
+	}
+}
+
 func TestHeartbeat_GetKey(t *testing.T) {
 	sut := &Heartbeat{
 		Project: "wakapi",
@@ -103,3 +142,22 @@ func TestHeartbeat_Hashed_NoCollision(t *testing.T) {
 		hashes[sut.Hash] = true
 	}
 }
+
+func TestHeartbeat_JsonRoundTrip(t *testing.T) {
+	sut := &Heartbeat{
+		Entity:  "~/dev/wakapi",
+		Project: "wakapi",
+		Time:    CustomTime(time.Date(2023, 1, 15, 19, 25, 32, 500*1e6, time.UTC)),
+	}
+
+	data, err := json.Marshal(sut)
+	assert.Nil(t, err)
+
+	var result Heartbeat
+	assert.Nil(t, json.Unmarshal(data, &result))
+	assert.Equal(t, sut.Time.T().UnixMilli(), result.Time.T().UnixMilli())
+	assert.Equal(t, sut.Entity, result.Entity)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"time": 1673810732.5}`), &result))
+	assert.Equal(t, int64(1673810732500), result.Time.T().UnixMilli())
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// ImportResult summarizes a finished heartbeat import
+type ImportResult struct {
+	// Processed is the number of heartbeats yielded by the importer
+	Processed int
+	// Duplicates is the number of heartbeats skipped, because they were yielded more than once or had already existed before
+	Duplicates int
+	// Imported is the number of heartbeats actually added, i.e. excluding those that had already existed before
+	Imported int
+	Duration time.Duration
+}
+
+// WakapiMigrationCheckpoint marks how far the migration of a user's heartbeats from another wakapi instance got
+type WakapiMigrationCheckpoint struct {
+	BaseUrl   string    `json:"base_url"`
+	Cursor    uint64    `json:"cursor"` // id of the last heartbeat persisted, as known to the source instance
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WakapiMigrationResult summarizes a finished migration from another wakapi instance, including a comparison of the
+// total coding time on both ends
+type WakapiMigrationResult struct {
+	*ImportResult
+	SourceTotal time.Duration
+	TargetTotal time.Duration
+}
+
+// Verified tells whether all coding time of the source instance is present locally, allowing for a small deviation
+// caused by different heartbeat timeouts or rounding
+func (r *WakapiMigrationResult) Verified() bool {
+	return float64(r.TargetTotal) >= float64(r.SourceTotal)*0.99
+}
+
+// CsvImportMapping assigns csv columns (by their header name) to heartbeat fields. Only time and either entity or project
+// are required, all other columns are optional.
+type CsvImportMapping struct {
+	Time     string         `json:"time"`
+	Entity   string         `json:"entity"`
+	Project  string         `json:"project"`
+	Language string         `json:"language"`
+	Branch   string         `json:"branch"`
+	Editor   string         `json:"editor"`
+	Machine  string         `json:"machine"`
+	Duration string         `json:"duration"`
+	Location *time.Location `json:"-"`
+}
+
+func (m *CsvImportMapping) IsValid() bool {
+	return m.Time != "" && (m.Entity != "" || m.Project != "") && m.Location != nil
+}
+
+// CsvImportRecord is a single, mapped csv row
+type CsvImportRecord struct {
+	Line     int
+	Time     time.Time
+	Entity   string
+	Project  string
+	Language string
+	Branch   string
+	Editor   string
+	Machine  string
+	Duration time.Duration
+}
+
+type CsvImportRowError struct {
+	Line  int
+	Error string
+}
+
+type CsvImportPreview struct {
+	Records []*CsvImportRecord
+	Errors  []*CsvImportRowError
+}
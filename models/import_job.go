@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ImportSourceWakatime      = "wakatime"
+	ImportSourceWakatimeFile  = "wakatime_file"
+	ImportSourceActivityWatch = "activitywatch"
+	ImportSourceCsv           = "csv"
+	ImportSourceWakapi        = "wakapi"
+)
+
+const (
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+const importJobMaxErrors = 1000 // e.g. one per rejected csv row
+
+// ImportJob keeps track of a heartbeat import, from which source and for which range data is imported, how far it
+// got and what went wrong. Jobs still running when wakapi is shut down are resumed on the next start.
+type ImportJob struct {
+	ID           uint        `json:"id" gorm:"primary_key"`
+	User         *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID       string      `json:"-" gorm:"not null; index:idx_import_job_user"`
+	Source       string      `json:"source" gorm:"type:varchar(32)"`
+	State        string      `json:"state" gorm:"type:varchar(16); index:idx_import_job_state"`
+	From         *CustomTime `json:"from" gorm:"column:from_time" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // nil to import everything available
+	To           *CustomTime `json:"to" gorm:"column:to_time" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Processed    int         `json:"processed"`
+	Total        int         `json:"total"` // only known once completed, as most sources can't tell in advance
+	Imported     int         `json:"imported"`
+	Duplicates   int         `json:"duplicates"`
+	Errors       string      `json:"-" gorm:"type:text"` // newline-separated
+	Params       string      `json:"-" gorm:"type:text"` // json-encoded ImportJobParams
+	CheckpointAt *CustomTime `json:"checkpoint_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt    CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	FinishedAt   *CustomTime `json:"finished_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// ImportJobParams holds whatever is needed to (re-)create a job's importer, e.g. when resuming it after a restart
+type ImportJobParams struct {
+	FilePath    string            `json:"file_path,omitempty"`
+	DeleteFile  bool              `json:"delete_file,omitempty"` // whether the file was uploaded for this import only and is to be removed once done
+	ForceLegacy bool              `json:"force_legacy,omitempty"`
+	Buckets     []string          `json:"buckets,omitempty"`
+	CsvMapping  *CsvImportMapping `json:"csv_mapping,omitempty"`
+	Timezone    string            `json:"timezone,omitempty"`
+	BaseUrl     string            `json:"base_url,omitempty"`
+	ApiKey      string            `json:"api_key,omitempty"` // removed once the job is finished
+}
+
+func NewImportJob(user *User, source string) *ImportJob {
+	return &ImportJob{
+		User:      user,
+		UserID:    user.ID,
+		Source:    source,
+		State:     ImportJobRunning,
+		CreatedAt: CustomTime(time.Now()),
+	}
+}
+
+func (j *ImportJob) WithRange(from, to time.Time) *ImportJob {
+	j.From, j.To = (*CustomTime)(&from), (*CustomTime)(&to)
+	return j
+}
+
+func (j *ImportJob) WithParams(params *ImportJobParams) *ImportJob {
+	data, _ := json.Marshal(params)
+	j.Params = string(data)
+	return j
+}
+
+func (j *ImportJob) GetParams() *ImportJobParams {
+	var params ImportJobParams
+	json.Unmarshal([]byte(j.Params), &params)
+	return &params
+}
+
+func (j *ImportJob) AddError(message string) {
+	message = strings.ReplaceAll(message, "\n", " ")
+	errors := j.ErrorList()
+	if len(errors) >= importJobMaxErrors || slices.Contains(errors, message) {
+		return // errors are reported again when resuming a job
+	}
+	j.Errors = strings.Join(append(errors, message), "\n")
+}
+
+func (j *ImportJob) ErrorList() []string {
+	if j.Errors == "" {
+		return []string{}
+	}
+	return strings.Split(j.Errors, "\n")
+}
+
+func (j *ImportJob) IsRunning() bool {
+	return j.State == ImportJobRunning
+}
+
+// IsReplayable tells whether the job's source yields the very same heartbeats in the same order every time, so that
+// an interrupted job can skip over those already processed before
+func (j *ImportJob) IsReplayable() bool {
+	return j.Source == ImportSourceWakatimeFile || j.Source == ImportSourceActivityWatch || j.Source == ImportSourceCsv
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportJob_AddError(t *testing.T) {
+	sut := NewImportJob(&User{ID: "foo"}, ImportSourceCsv)
+	assert.Empty(t, sut.ErrorList())
+
+	sut.AddError("Line 3: invalid time")
+	sut.AddError("Line 3: invalid time") // e.g. reported again after resuming
+	sut.AddError("Line 7: missing\nproject")
+	assert.Equal(t, []string{"Line 3: invalid time", "Line 7: missing project"}, sut.ErrorList())
+
+	for i := 0; i < 2*importJobMaxErrors; i++ {
+		sut.AddError(fmt.Sprintf("Line %d: invalid time", i+10))
+	}
+	assert.Len(t, sut.ErrorList(), importJobMaxErrors)
+}
+
+func TestImportJob_Params(t *testing.T) {
+	sut := NewImportJob(&User{ID: "foo"}, ImportSourceCsv).WithParams(&ImportJobParams{
+		FilePath:   "/tmp/upload.csv",
+		DeleteFile: true,
+		CsvMapping: &CsvImportMapping{Time: "date", Project: "project"},
+		Timezone:   "Europe/Berlin",
+	})
+
+	params := sut.GetParams()
+	assert.Equal(t, "/tmp/upload.csv", params.FilePath)
+	assert.True(t, params.DeleteFile)
+	assert.Equal(t, "date", params.CsvMapping.Time)
+	assert.Equal(t, "Europe/Berlin", params.Timezone)
+	assert.True(t, sut.IsRunning())
+	assert.True(t, sut.IsReplayable())
+	assert.False(t, NewImportJob(&User{ID: "foo"}, ImportSourceWakatime).IsReplayable())
+}
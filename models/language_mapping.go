@@ -1,15 +1,71 @@
 package models
 
+import (
+	"path"
+	"strings"
+)
+
+const (
+	LanguageMappingTypeExtension = "extension" // file name ends in ".<pattern>", e.g. "blade.php"
+	LanguageMappingTypeFilename  = "filename"  // file name equals pattern, e.g. "Dockerfile"
+	LanguageMappingTypeGlob      = "glob"      // file name (or trailing path segments, if pattern contains slashes) matches glob, e.g. "Jenkinsfile.*" or "ci/*.conf"
+	LanguageMappingTypePrefix    = "prefix"    // full path starts with pattern, e.g. "/home/me/scripts/"
+)
+
+var languageMappingTypes = []string{
+	LanguageMappingTypeExtension,
+	LanguageMappingTypeFilename,
+	LanguageMappingTypeGlob,
+	LanguageMappingTypePrefix,
+}
+
 type LanguageMapping struct {
+	ID     uint   `json:"id" gorm:"primary_key"`
+	User   *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID string `json:"-" gorm:"not null; index:idx_language_mapping_user; uniqueIndex:idx_language_mapping_composite"`
+	// for historic reasons, the pattern column is still called "extension"
+	Extension string `json:"extension" gorm:"uniqueIndex:idx_language_mapping_composite; type:varchar(255)"`
+	Type      string `json:"type" gorm:"uniqueIndex:idx_language_mapping_composite; type:varchar(16); default:extension"`
+	Language  string `json:"language" gorm:"type:varchar(64)"`
+}
+
+// DefaultLanguageMapping is an instance-wide, admin-managed mapping, which is only applied to heartbeats that came without any language
+type DefaultLanguageMapping struct {
 	ID        uint   `json:"id" gorm:"primary_key"`
-	User      *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	UserID    string `json:"-" gorm:"not null; index:idx_language_mapping_user; uniqueIndex:idx_language_mapping_composite"`
-	Extension string `json:"extension" gorm:"uniqueIndex:idx_language_mapping_composite; type:varchar(16)"`
+	Extension string `json:"extension" gorm:"uniqueIndex:idx_default_language_mapping_pattern; type:varchar(255)"`
+	Type      string `json:"type" gorm:"uniqueIndex:idx_default_language_mapping_pattern; type:varchar(16); default:extension"`
 	Language  string `json:"language" gorm:"type:varchar(64)"`
 }
 
 func (m *LanguageMapping) IsValid() bool {
-	return m.validateLanguage() && m.validateExtension()
+	return m.validateLanguage() && m.validateExtension() && m.validateType()
+}
+
+func (m *LanguageMapping) GetType() string {
+	if m.Type == "" {
+		return LanguageMappingTypeExtension
+	}
+	return m.Type
+}
+
+// Match checks whether the given entity (file path) is matched by this mapping and, if so, returns the mapping's precedence.
+// More concrete mappings yield a higher precedence, i.e. exact file names > globs > path prefixes > file extensions.
+func (m *LanguageMapping) Match(entity string) (bool, int) {
+	entity = strings.ReplaceAll(entity, "\\", "/")
+	filename := path.Base(entity)
+
+	switch m.GetType() {
+	case LanguageMappingTypeFilename:
+		return filename == m.Extension, 3000
+	case LanguageMappingTypeGlob:
+		ok, _ := path.Match(m.Extension, trailingPathSegments(entity, m.Extension))
+		return ok, 2000 + len(strings.Trim(m.Extension, "*?"))
+	case LanguageMappingTypePrefix:
+		prefix := strings.ReplaceAll(m.Extension, "\\", "/") // patterns might be specified with windows path separators, too
+		return strings.HasPrefix(entity, prefix), 1000 + len(prefix)
+	default:
+		return strings.HasSuffix(filename, "."+m.Extension), strings.Count(m.Extension, ".")
+	}
 }
 
 func (m *LanguageMapping) validateLanguage() bool {
@@ -17,5 +73,48 @@ func (m *LanguageMapping) validateLanguage() bool {
 }
 
 func (m *LanguageMapping) validateExtension() bool {
+	if m.GetType() == LanguageMappingTypeGlob {
+		if _, err := path.Match(m.Extension, ""); err != nil {
+			return false
+		}
+	}
 	return len(m.Extension) >= 1
 }
+
+func (m *LanguageMapping) validateType() bool {
+	for _, t := range languageMappingTypes {
+		if m.GetType() == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *DefaultLanguageMapping) IsValid() bool {
+	return m.AsLanguageMapping().IsValid()
+}
+
+func (m *DefaultLanguageMapping) AsLanguageMapping() *LanguageMapping {
+	return &LanguageMapping{
+		Extension: m.Extension,
+		Type:      m.Type,
+		Language:  m.Language,
+	}
+}
+
+func IsValidLanguageMappingType(t string) bool {
+	return (&LanguageMapping{Type: t}).validateType()
+}
+
+// trailingPathSegments returns as many trailing segments of the given path as the pattern consists of, or the entire path for absolute patterns
+func trailingPathSegments(entity, pattern string) string {
+	if strings.HasPrefix(pattern, "/") {
+		return entity
+	}
+	segments := strings.Split(entity, "/")
+	n := strings.Count(pattern, "/") + 1
+	if n > len(segments) {
+		n = len(segments)
+	}
+	return strings.Join(segments[len(segments)-n:], "/")
+}
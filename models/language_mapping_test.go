@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguageMapping_IsValid(t *testing.T) {
+	assert.True(t, (&LanguageMapping{Extension: "py", Language: "Python"}).IsValid())
+	assert.True(t, (&LanguageMapping{Extension: "Dockerfile", Type: LanguageMappingTypeFilename, Language: "Docker"}).IsValid())
+	assert.True(t, (&LanguageMapping{Extension: "*.blade.php", Type: LanguageMappingTypeGlob, Language: "Blade"}).IsValid())
+	assert.False(t, (&LanguageMapping{Extension: "[a-", Type: LanguageMappingTypeGlob, Language: "Foo"}).IsValid())
+	assert.False(t, (&LanguageMapping{Extension: "py", Type: "regex", Language: "Python"}).IsValid())
+	assert.False(t, (&LanguageMapping{Extension: "", Language: "Python"}).IsValid())
+	assert.False(t, (&LanguageMapping{Extension: "py", Language: ""}).IsValid())
+	assert.True(t, (&DefaultLanguageMapping{Extension: "Makefile", Type: LanguageMappingTypeFilename, Language: "Makefile"}).IsValid())
+}
+
+func TestLanguageMapping_Match_Precedence(t *testing.T) {
+	entity := "/home/me/dev/Dockerfile.prod"
+
+	okExt, precExt := (&LanguageMapping{Extension: "prod"}).Match(entity)
+	okPrefix, precPrefix := (&LanguageMapping{Extension: "/home/me/", Type: LanguageMappingTypePrefix}).Match(entity)
+	okGlob, precGlob := (&LanguageMapping{Extension: "Dockerfile.*", Type: LanguageMappingTypeGlob}).Match(entity)
+	okName, precName := (&LanguageMapping{Extension: "Dockerfile.prod", Type: LanguageMappingTypeFilename}).Match(entity)
+
+	assert.True(t, okExt && okPrefix && okGlob && okName)
+	assert.Less(t, precExt, precPrefix)
+	assert.Less(t, precPrefix, precGlob)
+	assert.Less(t, precGlob, precName)
+}
+
+func TestLanguageMapping_Match_WindowsPrefix(t *testing.T) {
+	mapping := &LanguageMapping{Extension: `C:\Users\me\scripts\`, Type: LanguageMappingTypePrefix}
+
+	ok, _ := mapping.Match(`C:\Users\me\scripts\deploy`)
+	assert.True(t, ok)
+	ok, _ = mapping.Match("C:/Users/me/scripts/deploy")
+	assert.True(t, ok)
+	ok, _ = mapping.Match(`C:\Users\me\other\deploy`)
+	assert.False(t, ok)
+}
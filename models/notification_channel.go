@@ -0,0 +1,141 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	NotificationChannelSlack      = "slack"
+	NotificationChannelDiscord    = "discord"
+	NotificationChannelMatrix     = "matrix"
+	NotificationChannelMattermost = "mattermost"
+	NotificationChannelNtfy       = "ntfy"
+)
+
+const (
+	NotificationKindReport          = "report"
+	NotificationKindImport          = "import"
+	NotificationKindRelayFailure    = "relay_failure"
+	NotificationKindSubscription    = "subscription"
+	NotificationKindTest            = "test"
+	notificationChannelMaxNameLen   = 255
+	notificationChannelMaxRoomIdLen = 255
+)
+
+var NotificationChannelTypes = []string{
+	NotificationChannelSlack,
+	NotificationChannelDiscord,
+	NotificationChannelMatrix,
+	NotificationChannelMattermost,
+	NotificationChannelNtfy,
+}
+
+// NotificationKinds are the kinds of notifications, which can be routed to a channel
+var NotificationKinds = []string{
+	NotificationKindReport,
+	NotificationKindImport,
+	NotificationKindRelayFailure,
+	NotificationKindSubscription,
+}
+
+// NotificationChannel is a chat integration, which a user's notifications of the given (comma-separated) kinds are posted to.
+// For slack, discord, mattermost and ntfy, the url is the incoming webhook (or topic) url. For matrix, it is the homeserver's base url,
+// complemented by a room id and an access token. Ntfy optionally accepts an access token as well.
+type NotificationChannel struct {
+	ID         uint        `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"-" gorm:"not null; index:idx_notification_channel_user"`
+	Name       string      `json:"name" gorm:"type:varchar(255)"`
+	Type       string      `json:"type" gorm:"type:varchar(16)"`
+	Url        string      `json:"-" gorm:"not null"`
+	RoomId     string      `json:"room_id" gorm:"type:varchar(255)"`
+	Token      string      `json:"-"`
+	Kinds      string      `json:"kinds"`
+	Enabled    bool        `json:"enabled" gorm:"default:true; type:bool"`
+	LastError  string      `json:"last_error"`
+	LastSentAt *CustomTime `json:"last_sent_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt  CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// Notification is a short, chat-friendly message, which is rendered by every channel type in its own flavor of markup
+type Notification struct {
+	Kind  string
+	Title string
+	Lines []string
+	Link  string
+}
+
+func (c *NotificationChannel) IsValid() bool {
+	u, err := url.Parse(c.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if c.UserID == "" || len(c.Name) > notificationChannelMaxNameLen || len(c.RoomId) > notificationChannelMaxRoomIdLen || !IsNotificationChannelType(c.Type) {
+		return false
+	}
+	if c.Type == NotificationChannelMatrix && (c.RoomId == "" || c.Token == "") {
+		return false
+	}
+	if len(c.KindList()) == 0 {
+		return false
+	}
+	for _, k := range c.KindList() {
+		if !IsNotificationKind(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// DisplayName returns the channel's name or its type and host, if no name was given
+func (c *NotificationChannel) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if u, err := url.Parse(c.Url); err == nil && u.Host != "" {
+		return c.Type + " (" + u.Host + ")"
+	}
+	return c.Type
+}
+
+func (c *NotificationChannel) KindList() []string {
+	result := make([]string, 0)
+	for _, s := range strings.Split(c.Kinds, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// Subscribes returns whether notifications of the given kind are to be posted to this channel. Test notifications always are.
+func (c *NotificationChannel) Subscribes(kind string) bool {
+	if kind == NotificationKindTest {
+		return true
+	}
+	for _, k := range c.KindList() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func IsNotificationChannelType(channelType string) bool {
+	for _, t := range NotificationChannelTypes {
+		if t == channelType {
+			return true
+		}
+	}
+	return false
+}
+
+func IsNotificationKind(kind string) bool {
+	for _, k := range NotificationKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationChannel_IsValid(t *testing.T) {
+	channel := &NotificationChannel{UserID: "user1", Type: NotificationChannelSlack, Url: "https://hooks.slack.com/services/T0/B0/X", Kinds: "report, import"}
+	assert.True(t, channel.IsValid())
+
+	channel.Type = "irc"
+	assert.False(t, channel.IsValid())
+
+	channel.Type = NotificationChannelMatrix
+	assert.False(t, channel.IsValid())
+	channel.RoomId, channel.Token = "!abc:matrix.org", "token"
+	assert.True(t, channel.IsValid())
+
+	channel.Kinds = "report,test"
+	assert.False(t, channel.IsValid())
+
+	channel.Kinds = ""
+	assert.False(t, channel.IsValid())
+}
+
+func TestNotificationChannel_Subscribes(t *testing.T) {
+	channel := &NotificationChannel{Kinds: "report,relay_failure"}
+	assert.True(t, channel.Subscribes(NotificationKindReport))
+	assert.True(t, channel.Subscribes(NotificationKindRelayFailure))
+	assert.True(t, channel.Subscribes(NotificationKindTest))
+	assert.False(t, channel.Subscribes(NotificationKindImport))
+}
@@ -0,0 +1,64 @@
+package models
+
+import "github.com/duke-git/lancet/v2/slice"
+
+// OidcIdentity is the information about a user as obtained from an openid connect provider's id token
+type OidcIdentity struct {
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// OidcIdentityFromClaims extracts an identity from the given id token claims, using the given claims to derive username and groups from
+func OidcIdentityFromClaims(claims map[string]interface{}, usernameClaim, groupsClaim string) *OidcIdentity {
+	identity := &OidcIdentity{
+		Subject:  claimString(claims, "sub"),
+		Username: claimString(claims, usernameClaim),
+		Email:    claimString(claims, "email"),
+		Groups:   claimStrings(claims, groupsClaim),
+	}
+
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = v
+	case string:
+		identity.EmailVerified = v == "true"
+	}
+
+	return identity
+}
+
+func (i *OidcIdentity) IsMemberOf(group string) bool {
+	return group != "" && slice.Contain[string](i.Groups, group)
+}
+
+func (i *OidcIdentity) IsValid() bool {
+	return i.Subject != "" && ValidateUsername(i.Username)
+}
+
+func claimString(claims map[string]interface{}, key string) string {
+	if v, ok := claims[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func claimStrings(claims map[string]interface{}, key string) []string {
+	switch v := claims[key].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return []string{}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOidcIdentityFromClaims(t *testing.T) {
+	claims := map[string]interface{}{
+		"sub":            "some-subject",
+		"email":          "john@example.org",
+		"email_verified": "true",
+		"nickname":       "johndoe",
+		"roles":          []interface{}{"developers", "admins", 42},
+	}
+
+	sut := OidcIdentityFromClaims(claims, "nickname", "roles")
+	assert.True(t, sut.IsValid())
+	assert.Equal(t, "some-subject", sut.Subject)
+	assert.Equal(t, "johndoe", sut.Username)
+	assert.True(t, sut.EmailVerified)
+	assert.Equal(t, []string{"developers", "admins"}, sut.Groups)
+	assert.True(t, sut.IsMemberOf("admins"))
+	assert.False(t, sut.IsMemberOf(""))
+
+	sut = OidcIdentityFromClaims(claims, "preferred_username", "groups")
+	assert.False(t, sut.IsValid())
+	assert.Empty(t, sut.Groups)
+}
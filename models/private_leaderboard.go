@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+)
+
+// PrivateLeaderboardScopes are the intervals a private leaderboard can be ranked by
+var PrivateLeaderboardScopes = []*IntervalKey{
+	IntervalPast7Days,
+	IntervalPast14Days,
+	IntervalPast30Days,
+	IntervalThisWeek,
+	IntervalThisMonth,
+	IntervalThisYear,
+}
+
+// PrivateLeaderboard is a user-created leaderboard, which is only visible to its members. Other than the public
+// leaderboard, it doesn't require members to opt in to the public one.
+type PrivateLeaderboard struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	Name       string     `json:"name" gorm:"not null; type:varchar(255)"`
+	Owner      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	OwnerID    string     `json:"owner_id" gorm:"not null; index:idx_private_leaderboard_owner"`
+	InviteCode string     `json:"invite_code" gorm:"not null; type:varchar(32); uniqueIndex:idx_private_leaderboard_code"`
+	Interval   string     `json:"interval" gorm:"not null; size:32"`
+	Language   string     `json:"language" gorm:"type:varchar(255)"` // optional, to only rank coding time in that language
+	CreatedAt  CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type PrivateLeaderboardMember struct {
+	ID            uint                `json:"-" gorm:"primary_key"`
+	Leaderboard   *PrivateLeaderboard `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	LeaderboardID uint                `json:"leaderboard_id" gorm:"not null; uniqueIndex:idx_private_leaderboard_member_composite"`
+	User          *User               `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID        string              `json:"user_id" gorm:"not null; index:idx_private_leaderboard_member_user; uniqueIndex:idx_private_leaderboard_member_composite"`
+	CreatedAt     CustomTime          `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (l *PrivateLeaderboard) IsValid() bool {
+	name := strings.TrimSpace(l.Name)
+	return name != "" && len(name) <= 255 && l.OwnerID != "" && l.InviteCode != "" && l.Scope() != nil
+}
+
+// Scope resolves the leaderboard's interval or returns nil, if invalid
+func (l *PrivateLeaderboard) Scope() *IntervalKey {
+	for _, scope := range PrivateLeaderboardScopes {
+		if scope.HasAlias(l.Interval) {
+			return scope
+		}
+	}
+	return nil
+}
+
+func (l *PrivateLeaderboard) HasLanguageFocus() bool {
+	return l.Language != ""
+}
@@ -1,5 +1,14 @@
 package models
 
+import (
+	"regexp"
+	"strings"
+)
+
+const LabelSeparator = "/"
+
+var labelColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
 // ProjectLabelReverseResolver returns all projects for a given label
 type ProjectLabelReverseResolver func(l string) []string
 
@@ -8,9 +17,80 @@ type ProjectLabel struct {
 	User       *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	UserID     string `json:"-" gorm:"not null; index:idx_project_label_user"`
 	ProjectKey string `json:"project"`
-	Label      string `json:"label" gorm:"type:varchar(64)"`
+	Label      string `json:"label" gorm:"type:varchar(255)"`
+}
+
+// LabelColor holds a user-chosen display color for a label at any level of the label hierarchy (e.g. "client" or "client/acme")
+type LabelColor struct {
+	ID     uint   `json:"id" gorm:"primary_key"`
+	User   *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID string `json:"-" gorm:"not null; index:idx_label_color_user; uniqueIndex:idx_label_color_composite"`
+	Label  string `json:"label" gorm:"type:varchar(255); uniqueIndex:idx_label_color_composite"`
+	Color  string `json:"color" gorm:"type:varchar(7)"`
 }
 
 func (l *ProjectLabel) IsValid() bool {
-	return l.ProjectKey != "" && l.Label != ""
+	return l.ProjectKey != "" && ValidateLabel(l.Label)
+}
+
+func (c *LabelColor) IsValid() bool {
+	return ValidateLabel(c.Label) && labelColorRegex.MatchString(c.Color)
+}
+
+// ValidateLabel checks that a label is non-empty and doesn't contain empty path segments (e.g. "client//backend")
+func ValidateLabel(label string) bool {
+	if label == "" || len(label) > 255 {
+		return false
+	}
+	for _, part := range strings.Split(label, LabelSeparator) {
+		if strings.TrimSpace(part) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeLabel trims whitespace around every segment of a hierarchical label as well as leading or trailing separators
+func NormalizeLabel(label string) string {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(label), LabelSeparator), LabelSeparator)
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return strings.Join(parts, LabelSeparator)
+}
+
+// LabelDepth returns the number of levels of a hierarchical label, e.g. 3 for "client/acme/backend"
+func LabelDepth(label string) int {
+	if label == "" {
+		return 0
+	}
+	return strings.Count(label, LabelSeparator) + 1
+}
+
+// TruncateLabel cuts a hierarchical label down to the given depth, e.g. "client/acme/backend" becomes "client/acme" for depth 2.
+// A depth of zero or less leaves the label untouched.
+func TruncateLabel(label string, depth int) string {
+	if depth <= 0 {
+		return label
+	}
+	parts := strings.Split(label, LabelSeparator)
+	if len(parts) <= depth {
+		return label
+	}
+	return strings.Join(parts[:depth], LabelSeparator)
+}
+
+// LabelAncestors returns all labels the given one rolls up into, including itself, e.g. ["client", "client/acme", "client/acme/backend"]
+func LabelAncestors(label string) []string {
+	parts := strings.Split(label, LabelSeparator)
+	ancestors := make([]string, len(parts))
+	for i := range parts {
+		ancestors[i] = strings.Join(parts[:i+1], LabelSeparator)
+	}
+	return ancestors
+}
+
+// IsLabelDescendant returns true if label equals the given ancestor or is nested below it
+func IsLabelDescendant(label, ancestor string) bool {
+	return label == ancestor || strings.HasPrefix(label, ancestor+LabelSeparator)
 }
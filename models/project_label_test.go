@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectLabel_IsValid(t *testing.T) {
+	assert.True(t, (&ProjectLabel{ProjectKey: "wakapi", Label: "client/acme/backend"}).IsValid())
+	assert.False(t, (&ProjectLabel{ProjectKey: "wakapi", Label: "client//backend"}).IsValid())
+	assert.False(t, (&ProjectLabel{ProjectKey: "wakapi", Label: ""}).IsValid())
+	assert.False(t, (&ProjectLabel{ProjectKey: "", Label: "client"}).IsValid())
+}
+
+func TestLabelColor_IsValid(t *testing.T) {
+	assert.True(t, (&LabelColor{Label: "client", Color: "#1a2B3c"}).IsValid())
+	assert.False(t, (&LabelColor{Label: "client", Color: "red"}).IsValid())
+	assert.False(t, (&LabelColor{Label: "", Color: "#1a2b3c"}).IsValid())
+}
+
+func TestNormalizeLabel(t *testing.T) {
+	assert.Equal(t, "client/acme/backend", NormalizeLabel(" /client / acme/backend/ "))
+	assert.Equal(t, "private", NormalizeLabel("private"))
+}
+
+func TestTruncateLabel(t *testing.T) {
+	assert.Equal(t, "client", TruncateLabel("client/acme/backend", 1))
+	assert.Equal(t, "client/acme", TruncateLabel("client/acme/backend", 2))
+	assert.Equal(t, "client/acme/backend", TruncateLabel("client/acme/backend", 3))
+	assert.Equal(t, "client/acme/backend", TruncateLabel("client/acme/backend", 5))
+	assert.Equal(t, "client/acme/backend", TruncateLabel("client/acme/backend", 0))
+}
+
+func TestLabelAncestors(t *testing.T) {
+	assert.Equal(t, []string{"client", "client/acme", "client/acme/backend"}, LabelAncestors("client/acme/backend"))
+	assert.Equal(t, []string{"private"}, LabelAncestors("private"))
+	assert.Equal(t, 3, LabelDepth("client/acme/backend"))
+	assert.Equal(t, 0, LabelDepth(""))
+}
+
+func TestIsLabelDescendant(t *testing.T) {
+	assert.True(t, IsLabelDescendant("client/acme", "client"))
+	assert.True(t, IsLabelDescendant("client", "client"))
+	assert.False(t, IsLabelDescendant("clients", "client"))
+	assert.False(t, IsLabelDescendant("client", "client/acme"))
+}
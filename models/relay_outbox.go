@@ -0,0 +1,84 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// RelayOutboxMaxAttempts is the number of delivery attempts after which an outbox item is given up on
+	RelayOutboxMaxAttempts = 12
+	relayOutboxBaseBackoff = 30 * time.Second
+	relayOutboxMaxBackoff  = 12 * time.Hour
+)
+
+// RelayOutboxItem is a single heartbeat waiting to be relayed, either to the user's WakaTime connection (target id 0) or to one of their relay targets.
+// The payload is kept as the raw json originally sent by the client, so that it is relayed unaltered.
+type RelayOutboxItem struct {
+	ID             uint64      `json:"id" gorm:"primary_key"`
+	User           *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID         string      `json:"-" gorm:"not null; index:idx_relay_outbox_user; uniqueIndex:idx_relay_outbox_composite"`
+	TargetID       uint        `json:"target_id" gorm:"not null; default:0; uniqueIndex:idx_relay_outbox_composite"`
+	Hash           string      `json:"-" gorm:"type:varchar(17); uniqueIndex:idx_relay_outbox_composite"`
+	Payload        string      `json:"-" gorm:"not null"`
+	MachineName    string      `json:"-"`
+	UserAgent      string      `json:"-"`
+	OriginInstance string      `json:"-"`
+	Attempts       int         `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  CustomTime  `json:"next_attempt_at" gorm:"index:idx_relay_outbox_next" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastError      string      `json:"last_error"`
+	DeliveredAt    *CustomTime `json:"delivered_at" gorm:"index:idx_relay_outbox_delivered" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt      CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// RelayBacklog summarizes a user's undelivered outbox items
+type RelayBacklog struct {
+	Pending         int64
+	Failed          int64
+	OldestPendingAt *time.Time
+}
+
+func (i *RelayOutboxItem) IsPrimary() bool {
+	return i.TargetID == 0
+}
+
+// BatchKey identifies the items, which can be relayed together in a single bulk request
+func (i *RelayOutboxItem) BatchKey() string {
+	return fmt.Sprintf("%s|%d|%s|%s|%s", i.UserID, i.TargetID, i.MachineName, i.UserAgent, i.OriginInstance)
+}
+
+// IsExhausted returns true if the item won't be retried anymore
+func (i *RelayOutboxItem) IsExhausted() bool {
+	return i.Attempts >= RelayOutboxMaxAttempts
+}
+
+// ScheduleRetry records a failed delivery attempt and defers the next one by an exponentially growing interval
+func (i *RelayOutboxItem) ScheduleRetry(reason string, now time.Time) {
+	i.Attempts++
+	i.LastError = reason
+	i.NextAttemptAt = CustomTime(now.Add(RelayOutboxBackoff(i.Attempts)))
+}
+
+// RelayOutboxBackoff returns the delay before the next delivery attempt after the given number of failed ones
+func RelayOutboxBackoff(attempts int) time.Duration {
+	return exponentialBackoff(attempts, relayOutboxBaseBackoff, relayOutboxMaxBackoff)
+}
+
+func (b *RelayBacklog) IsEmpty() bool {
+	return b == nil || (b.Pending == 0 && b.Failed == 0)
+}
+
+// exponentialBackoff doubles the base delay with every failed attempt, up to the given maximum
+func exponentialBackoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+	backoff := base
+	for n := 1; n < attempts && backoff < max; n++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayOutboxBackoff(t *testing.T) {
+	assert.Equal(t, time.Duration(0), RelayOutboxBackoff(0))
+	assert.Equal(t, 30*time.Second, RelayOutboxBackoff(1))
+	assert.Equal(t, 60*time.Second, RelayOutboxBackoff(2))
+	assert.Equal(t, 8*time.Minute, RelayOutboxBackoff(5))
+	assert.Equal(t, 12*time.Hour, RelayOutboxBackoff(RelayOutboxMaxAttempts))
+}
+
+func TestRelayOutboxItem_ScheduleRetry(t *testing.T) {
+	now := time.Now()
+	item := &RelayOutboxItem{}
+	for i := 0; i < RelayOutboxMaxAttempts; i++ {
+		assert.False(t, item.IsExhausted())
+		item.ScheduleRetry("got status 500", now)
+	}
+	assert.True(t, item.IsExhausted())
+	assert.Equal(t, "got status 500", item.LastError)
+	assert.Equal(t, now.Add(12*time.Hour), item.NextAttemptAt.T())
+}
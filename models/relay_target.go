@@ -0,0 +1,108 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	RelayFilterNone    = ""
+	RelayFilterInclude = "include"
+	RelayFilterExclude = "exclude"
+)
+
+// RelayTarget is an additional WakaTime-compatible backend (e.g. WakaTime itself or another Wakapi instance), which a user's heartbeats are mirrored to.
+// Projects and labels are comma-separated lists, which heartbeats are matched against according to the filter mode.
+type RelayTarget struct {
+	ID                  uint        `json:"id" gorm:"primary_key"`
+	User                *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID              string      `json:"-" gorm:"not null; index:idx_relay_target_user"`
+	Name                string      `json:"name" gorm:"type:varchar(255)"`
+	ApiUrl              string      `json:"api_url" gorm:"not null"`
+	ApiKey              string      `json:"-" gorm:"not null"`
+	Enabled             bool        `json:"enabled" gorm:"default:true; type:bool"`
+	FilterMode          string      `json:"filter_mode" gorm:"size:16"`
+	Projects            string      `json:"projects"`
+	Labels              string      `json:"labels"`
+	SuccessCount        int         `json:"success_count" gorm:"default:0"`
+	FailureCount        int         `json:"failure_count" gorm:"default:0"`
+	ConsecutiveFailures int         `json:"consecutive_failures" gorm:"default:0"`
+	LastError           string      `json:"last_error"`
+	LastSuccessAt       *CustomTime `json:"last_success_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastFailureAt       *CustomTime `json:"last_failure_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt           CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (t *RelayTarget) IsValid() bool {
+	u, err := url.Parse(t.ApiUrl)
+	return err == nil &&
+		(u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		t.ApiKey != "" && t.UserID != "" && len(t.Name) <= 255 &&
+		(t.FilterMode == RelayFilterNone || t.FilterMode == RelayFilterInclude || t.FilterMode == RelayFilterExclude)
+}
+
+// BaseUrl returns the target's api url without trailing slash
+func (t *RelayTarget) BaseUrl() string {
+	return strings.TrimSuffix(t.ApiUrl, "/")
+}
+
+// DisplayName returns the target's name or its host, if no name was given
+func (t *RelayTarget) DisplayName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if u, err := url.Parse(t.ApiUrl); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return t.ApiUrl
+}
+
+func (t *RelayTarget) ProjectList() []string {
+	return splitRelayFilterList(t.Projects)
+}
+
+func (t *RelayTarget) LabelList() []string {
+	return splitRelayFilterList(t.Labels)
+}
+
+// Matches decides whether a heartbeat of the given project, carrying the given (project) labels, should be relayed to this target.
+// A label filter also matches all of the label's descendants.
+func (t *RelayTarget) Matches(project string, labels []string) bool {
+	if t.FilterMode == RelayFilterNone {
+		return true
+	}
+
+	matched := false
+	for _, p := range t.ProjectList() {
+		if p == project {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+	outer:
+		for _, filterLabel := range t.LabelList() {
+			for _, l := range labels {
+				if IsLabelDescendant(l, filterLabel) {
+					matched = true
+					break outer
+				}
+			}
+		}
+	}
+
+	if t.FilterMode == RelayFilterInclude {
+		return matched
+	}
+	return !matched
+}
+
+func splitRelayFilterList(list string) []string {
+	result := make([]string, 0)
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayTarget_IsValid(t *testing.T) {
+	assert.True(t, (&RelayTarget{UserID: "user1", ApiUrl: "https://wakapi.example.org/api/compat/wakatime/v1", ApiKey: "key"}).IsValid())
+	assert.True(t, (&RelayTarget{UserID: "user1", ApiUrl: "https://api.wakatime.com/api/v1", ApiKey: "key", FilterMode: RelayFilterExclude}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", ApiUrl: "ftp://example.org", ApiKey: "key"}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", ApiUrl: "https://api.wakatime.com/api/v1", ApiKey: ""}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", ApiUrl: "https://api.wakatime.com/api/v1", ApiKey: "key", FilterMode: "foo"}).IsValid())
+}
+
+func TestRelayTarget_Matches(t *testing.T) {
+	all := &RelayTarget{}
+	assert.True(t, all.Matches("wakapi", nil))
+
+	include := &RelayTarget{FilterMode: RelayFilterInclude, Projects: " wakapi, anchr ", Labels: "oss"}
+	assert.True(t, include.Matches("wakapi", nil))
+	assert.True(t, include.Matches("other", []string{"private", "oss/go"}))
+	assert.False(t, include.Matches("other", []string{"ossified"}))
+	assert.False(t, include.Matches("acme-backend", []string{"client/acme"}))
+
+	exclude := &RelayTarget{FilterMode: RelayFilterExclude, Labels: "client"}
+	assert.False(t, exclude.Matches("acme-backend", []string{"client/acme"}))
+	assert.True(t, exclude.Matches("wakapi", []string{"oss"}))
+	assert.True(t, exclude.Matches("wakapi", nil))
+}
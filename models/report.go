@@ -1,11 +1,88 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ReportFormatHtml = "html"
+	ReportFormatPdf  = "pdf"
+)
 
 type Report struct {
 	From           time.Time
 	To             time.Time
 	User           *User
+	Period         string   // one of ReportPeriods, weekly for legacy reports
+	Sections       []string // sections to render, all if empty
+	Projects       []string // projects the report is restricted to, if any
+	Filters        *Filters // filters applied to a report document, if any
 	Summary        *Summary
+	Previous       *Summary // summary of the preceding period of same length, only if comparison was requested
 	DailySummaries []*Summary
+	ProjectDetails []*ReportProjectDetail // per-project breakdown by entity (e.g. file), only if requested for a report document
+}
+
+// ReportProjectDetail is a single project's coding time broken down by entity and branch
+type ReportProjectDetail struct {
+	Project  string
+	Total    time.Duration
+	Entities SummaryItems
+	Branches SummaryItems
+}
+
+// Includes returns whether the given section is to be rendered
+func (r *Report) Includes(section string) bool {
+	if len(r.Sections) == 0 {
+		return section != ReportSectionComparison || r.Previous != nil
+	}
+	for _, s := range r.Sections {
+		if s == section {
+			return section != ReportSectionComparison || r.Previous != nil
+		}
+	}
+	return false
+}
+
+// Change returns the relative change of total coding time compared to the previous period in percent
+func (r *Report) Change() int {
+	if r.Previous == nil || r.Summary == nil || r.Previous.TotalTime() == 0 {
+		return 0
+	}
+	return int((float64(r.Summary.TotalTime())/float64(r.Previous.TotalTime()) - 1) * 100)
+}
+
+// SummaryInterval returns the key of the summary interval corresponding to the report's period
+func (r *Report) SummaryInterval() string {
+	switch r.Period {
+	case ReportPeriodDaily:
+		return "yesterday"
+	case ReportPeriodMonthly:
+		return "last_month"
+	default:
+		return "last_7_days"
+	}
+}
+
+// FilterList returns the report's filters in a human-readable form, e.g. "project: wakapi, anchr"
+func (r *Report) FilterList() []string {
+	result := make([]string, 0)
+	if r.Filters == nil {
+		return result
+	}
+	for _, t := range SummaryTypes() {
+		values := *r.Filters.ResolveType(t)
+		if !values.Exists() {
+			continue
+		}
+		name := strings.ReplaceAll(GetEntityColumn(t), "_", " ")
+		result = append(result, fmt.Sprintf("%s: %s", name, strings.Join(values, ", ")))
+	}
+	return result
+}
+
+func IsValidReportFormat(format string) bool {
+	return format == ReportFormatHtml || format == ReportFormatPdf
 }
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+)
+
+const (
+	ReportPeriodDaily   = "daily"
+	ReportPeriodWeekly  = "weekly"
+	ReportPeriodMonthly = "monthly"
+)
+
+const (
+	ReportSectionProjects         = "projects"
+	ReportSectionLanguages        = "languages"
+	ReportSectionEditors          = "editors"
+	ReportSectionOperatingSystems = "operating_systems"
+	ReportSectionMachines         = "machines"
+	ReportSectionLabels           = "labels"
+	ReportSectionDays             = "days"
+	ReportSectionComparison       = "comparison"
+)
+
+var ReportPeriods = []string{
+	ReportPeriodDaily,
+	ReportPeriodWeekly,
+	ReportPeriodMonthly,
+}
+
+// ReportSections are the parts a report can be composed of
+var ReportSections = []string{
+	ReportSectionProjects,
+	ReportSectionLanguages,
+	ReportSectionEditors,
+	ReportSectionOperatingSystems,
+	ReportSectionMachines,
+	ReportSectionLabels,
+	ReportSectionDays,
+	ReportSectionComparison,
+}
+
+// ReportSubscription is a user's subscription to a recurring report (digest), which is delivered at the given hour in the user's time zone
+// on every day (daily), on the given weekday (weekly) or on the first day of every month (monthly). A report covers the full days
+// of the past period up until the day it is delivered, optionally restricted to the given (comma-separated) projects.
+type ReportSubscription struct {
+	ID         uint        `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"-" gorm:"not null; index:idx_report_subscription_user"`
+	Period     string      `json:"period" gorm:"type:varchar(16)"`
+	Weekday    int         `json:"weekday"`
+	Hour       int         `json:"hour"`
+	Sections   string      `json:"sections"`
+	Projects   string      `json:"projects"`
+	Enabled    bool        `json:"enabled" gorm:"default:true; type:bool"`
+	NextRunAt  CustomTime  `json:"next_run_at" gorm:"index:idx_report_subscription_next" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastSentAt *CustomTime `json:"last_sent_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt  CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (s *ReportSubscription) IsValid() bool {
+	if s.UserID == "" || !IsReportPeriod(s.Period) || s.Hour < 0 || s.Hour > 23 || s.Weekday < 0 || s.Weekday > 6 {
+		return false
+	}
+	if len(s.SectionList()) == 0 {
+		return false
+	}
+	for _, section := range s.SectionList() {
+		if !IsReportSection(section) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *ReportSubscription) SectionList() []string {
+	return splitCsv(s.Sections)
+}
+
+func (s *ReportSubscription) ProjectList() []string {
+	return splitCsv(s.Projects)
+}
+
+// Range returns the period covered by a report delivered at the given time, i.e. the full days (in the user's time zone) of the past day, week or month
+func (s *ReportSubscription) Range(at time.Time, tz *time.Location) (time.Time, time.Time) {
+	to := datetime.BeginOfDay(at.In(tz))
+	switch s.Period {
+	case ReportPeriodDaily:
+		return to.AddDate(0, 0, -1), to
+	case ReportPeriodMonthly:
+		to = datetime.BeginOfMonth(to)
+		return to.AddDate(0, -1, 0), to
+	default:
+		return to.AddDate(0, 0, -7), to
+	}
+}
+
+// NextRun returns the first delivery time strictly after the given time
+func (s *ReportSubscription) NextRun(after time.Time, tz *time.Location) time.Time {
+	after = after.In(tz)
+	candidate := time.Date(after.Year(), after.Month(), after.Day(), s.Hour, 0, 0, 0, tz)
+	for !candidate.After(after) || !s.isDeliveryDay(candidate) {
+		candidate = time.Date(candidate.Year(), candidate.Month(), candidate.Day()+1, s.Hour, 0, 0, 0, tz)
+	}
+	return candidate
+}
+
+func (s *ReportSubscription) isDeliveryDay(t time.Time) bool {
+	switch s.Period {
+	case ReportPeriodWeekly:
+		return int(t.Weekday()) == s.Weekday
+	case ReportPeriodMonthly:
+		return t.Day() == 1
+	default:
+		return true
+	}
+}
+
+func (s *ReportSubscription) Includes(section string) bool {
+	for _, sec := range s.SectionList() {
+		if sec == section {
+			return true
+		}
+	}
+	return false
+}
+
+// WeekdayName returns the name of the day weekly reports are delivered on
+func (s *ReportSubscription) WeekdayName() string {
+	return time.Weekday(s.Weekday).String()
+}
+
+func IsReportPeriod(period string) bool {
+	for _, p := range ReportPeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+func IsReportSection(section string) bool {
+	for _, s := range ReportSections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+func splitCsv(csv string) []string {
+	result := make([]string, 0)
+	for _, s := range strings.Split(csv, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
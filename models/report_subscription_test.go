@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportSubscription_IsValid(t *testing.T) {
+	sub := &ReportSubscription{UserID: "user1", Period: ReportPeriodWeekly, Weekday: 5, Hour: 18, Sections: "projects, comparison"}
+	assert.True(t, sub.IsValid())
+
+	sub.Period = "yearly"
+	assert.False(t, sub.IsValid())
+
+	sub.Period, sub.Hour = ReportPeriodDaily, 24
+	assert.False(t, sub.IsValid())
+
+	sub.Hour, sub.Sections = 8, "projects,branches"
+	assert.False(t, sub.IsValid())
+
+	sub.Sections = ""
+	assert.False(t, sub.IsValid())
+}
+
+func TestReportSubscription_NextRun(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	now := time.Date(2023, 3, 24, 17, 30, 0, 0, tz) // friday
+
+	daily := &ReportSubscription{Period: ReportPeriodDaily, Hour: 8}
+	assert.Equal(t, time.Date(2023, 3, 25, 8, 0, 0, 0, tz), daily.NextRun(now, tz))
+
+	weekly := &ReportSubscription{Period: ReportPeriodWeekly, Weekday: int(time.Friday), Hour: 18}
+	assert.Equal(t, time.Date(2023, 3, 24, 18, 0, 0, 0, tz), weekly.NextRun(now, tz))
+	assert.Equal(t, time.Date(2023, 3, 31, 18, 0, 0, 0, tz), weekly.NextRun(weekly.NextRun(now, tz), tz))
+
+	monthly := &ReportSubscription{Period: ReportPeriodMonthly, Hour: 9}
+	assert.Equal(t, time.Date(2023, 4, 1, 9, 0, 0, 0, tz), monthly.NextRun(now, tz))
+
+	// time zone of the input doesn't matter
+	assert.Equal(t, time.Date(2023, 3, 25, 8, 0, 0, 0, tz).Unix(), daily.NextRun(now.UTC(), tz).Unix())
+}
+
+func TestReportSubscription_Range(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	at := time.Date(2023, 4, 1, 9, 0, 0, 0, tz)
+
+	from, to := (&ReportSubscription{Period: ReportPeriodDaily}).Range(at, tz)
+	assert.Equal(t, time.Date(2023, 3, 31, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2023, 4, 1, 0, 0, 0, 0, tz), to)
+
+	from, to = (&ReportSubscription{Period: ReportPeriodWeekly}).Range(at, tz)
+	assert.Equal(t, time.Date(2023, 3, 25, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2023, 4, 1, 0, 0, 0, 0, tz), to)
+
+	from, to = (&ReportSubscription{Period: ReportPeriodMonthly}).Range(at.AddDate(0, 0, 1), tz)
+	assert.Equal(t, time.Date(2023, 3, 1, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2023, 4, 1, 0, 0, 0, 0, tz), to)
+}
+
+func TestReport_Includes(t *testing.T) {
+	report := &Report{}
+	assert.True(t, report.Includes(ReportSectionProjects))
+	assert.False(t, report.Includes(ReportSectionComparison))
+
+	report.Sections = []string{ReportSectionLanguages, ReportSectionComparison}
+	assert.False(t, report.Includes(ReportSectionProjects))
+	assert.True(t, report.Includes(ReportSectionLanguages))
+	assert.False(t, report.Includes(ReportSectionComparison))
+
+	report.Previous = &Summary{}
+	assert.True(t, report.Includes(ReportSectionComparison))
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Session is a server-side record of a web login. The cookie only carries a random token, while the database only
+// stores its hash, such that leaked database contents can't be used to hijack sessions.
+type Session struct {
+	ID         string     `gorm:"primary_key; type:varchar(64)"`
+	User       *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string     `gorm:"not null; index:idx_session_user"`
+	UserAgent  string     `gorm:"type:varchar(255)"`
+	Ip         string     `gorm:"type:varchar(64)"`
+	CreatedAt  CustomTime `gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastSeenAt CustomTime `gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func HashSessionToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *Session) IsExpired(maxAge time.Duration) bool {
+	return s.CreatedAt.T().Add(maxAge).Before(time.Now())
+}
+
+// Device returns a short, human-readable description of the browser and operating system the session was created from
+func (s *Session) Device() string {
+	var browser, os string
+
+	ua := s.UserAgent
+	switch {
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+
+	switch {
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	case ua != "":
+		return ua
+	default:
+		return "Unknown device"
+	}
+}
@@ -18,6 +18,8 @@ const (
 	UserKey               = "user"
 	ImprintKey            = "imprint"
 	AuthCookieKey         = "wakapi_auth"
+	OidcStateCookieKey    = "wakapi_oidc_state"
+	TotpPendingCookieKey  = "wakapi_totp_pending"
 	PersistentIntervalKey = "wakapi_summary_interval"
 )
 
@@ -64,7 +66,13 @@ func (j *CustomTime) UnmarshalJSON(b []byte) error {
 	s := strings.Trim(string(b), "\"")
 	ts, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return err
+		// also accept the format produced by MarshalJSON, e.g. when reading data exported by another wakapi instance
+		t, err2 := time.Parse(time.RFC3339Nano, s)
+		if err2 != nil {
+			return err
+		}
+		*j = CustomTime(t)
+		return nil
 	}
 	t := time.Unix(0, int64(ts*1e9)) // ms to ns
 	*j = CustomTime(t)
@@ -20,6 +20,7 @@ const (
 	SummaryLabel    uint8 = 5
 	SummaryBranch   uint8 = 6
 	SummaryEntity   uint8 = 7
+	SummaryTicket   uint8 = 8
 )
 
 const UnknownSummaryKey = "unknown"
@@ -48,6 +49,7 @@ type Summary struct {
 	Labels           SummaryItems `json:"labels" gorm:"-"`   // labels are not persisted, but calculated at runtime, i.e. when summary is retrieved
 	Branches         SummaryItems `json:"branches" gorm:"-"` // branches are not persisted, but calculated at runtime in case a project Filter is applied
 	Entities         SummaryItems `json:"entities" gorm:"-"` // entities are not persisted, but calculated at runtime in case a project Filter is applied
+	Tickets          SummaryItems `json:"tickets" gorm:"-"`  // tickets are extracted from branches using the user's ticket pattern, persisted only if one is configured
 	NumHeartbeats    int          `json:"-"`
 }
 
@@ -76,7 +78,7 @@ type SummaryParams struct {
 }
 
 func SummaryTypes() []uint8 {
-	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryLabel, SummaryBranch, SummaryEntity}
+	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryLabel, SummaryBranch, SummaryEntity, SummaryTicket}
 }
 
 func NativeSummaryTypes() []uint8 {
@@ -97,6 +99,7 @@ func NewEmptySummary() *Summary {
 		Labels:           SummaryItems{},
 		Branches:         SummaryItems{},
 		Entities:         SummaryItems{},
+		Tickets:          SummaryItems{},
 	}
 }
 
@@ -109,6 +112,7 @@ func (s *Summary) Sorted() *Summary {
 	sort.Sort(sort.Reverse(s.Labels))
 	sort.Sort(sort.Reverse(s.Branches))
 	sort.Sort(sort.Reverse(s.Entities))
+	sort.Sort(sort.Reverse(s.Tickets))
 	return s
 }
 
@@ -126,6 +130,7 @@ func (s *Summary) MappedItems() map[uint8]*SummaryItems {
 		SummaryLabel:    &s.Labels,
 		SummaryBranch:   &s.Branches,
 		SummaryEntity:   &s.Entities,
+		SummaryTicket:   &s.Tickets,
 	}
 }
 
@@ -147,6 +152,8 @@ func (s *Summary) GetByType(summaryType uint8) *SummaryItems {
 		return &s.Branches
 	case SummaryEntity:
 		return &s.Entities
+	case SummaryTicket:
+		return &s.Tickets
 	}
 	return nil
 }
@@ -177,6 +184,9 @@ func (s *Summary) SetByType(summaryType uint8, items *SummaryItems) {
 	case SummaryEntity:
 		s.Entities = *items
 		break
+	case SummaryTicket:
+		s.Tickets = *items
+		break
 	}
 }
 
@@ -397,7 +407,7 @@ func (s *Summary) WithResolvedAliases(resolve AliasResolver) *Summary {
 	s.Machines = processAliases(s.Machines)
 	s.Labels = processAliases(s.Labels)
 	s.Branches = processAliases(s.Branches)
-	// no aliases for entities / files
+	// no aliases for entities / files and tickets
 
 	return s
 }
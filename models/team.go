@@ -0,0 +1,103 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleMember = "member"
+)
+
+type Team struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	Name      string     `json:"name" gorm:"not null; type:varchar(255)"`
+	CreatedAt CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// TeamMember links a user to a team. Invited users only become actual members (and have their data included in the
+// team's statistics) once they accepted the invitation.
+type TeamMember struct {
+	ID        uint       `json:"-" gorm:"primary_key"`
+	Team      *Team      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	TeamID    uint       `json:"team_id" gorm:"not null; uniqueIndex:idx_team_member_composite"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"user_id" gorm:"not null; index:idx_team_member_user; uniqueIndex:idx_team_member_composite"`
+	Role      string     `json:"role" gorm:"type:varchar(16)"`
+	Accepted  bool       `json:"accepted" gorm:"default:false; type:bool"`
+	InvitedBy string     `json:"invited_by" gorm:"type:varchar(255)"`
+	CreatedAt CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// TeamSummary is the aggregate of all (accepted) team members' summaries within a given interval, restricted to
+// what every member chose to share
+type TeamSummary struct {
+	Team     *Team                `json:"team"`
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Summary  *Summary             `json:"summary"`
+	Members  []*TeamMemberSummary `json:"members"`
+	Filtered bool                 `json:"filtered"`
+}
+
+type TeamMemberSummary struct {
+	UserID string `json:"user_id"`
+	// Total is the member's overall coding time within the part of the interval they share data for, omitted if they don't share any
+	Total time.Duration `json:"total,omitempty" swaggertype:"primitive,integer"`
+	// Private is true if the member doesn't share any data at all (see User.ShareDataMaxDays)
+	Private bool `json:"private"`
+	// Shared lists the summary types a member exposes to the team, the summary only includes those
+	Shared  []uint8  `json:"shared"`
+	Summary *Summary `json:"summary"`
+	// Excluded is true if team-scoped filters target a type the member doesn't share, in which case the member is left out entirely
+	Excluded bool `json:"excluded"`
+}
+
+func (t *Team) IsValid() bool {
+	name := strings.TrimSpace(t.Name)
+	return name != "" && len(name) <= 255
+}
+
+func (m *TeamMember) IsOwner() bool {
+	return m.Role == TeamRoleOwner
+}
+
+func (m *TeamMember) IsValid() bool {
+	return m.TeamID != 0 && m.UserID != "" && (m.Role == TeamRoleOwner || m.Role == TeamRoleMember)
+}
+
+func (s *TeamSummary) TotalTime() time.Duration {
+	var total time.Duration
+	for _, m := range s.Members {
+		if !m.Excluded && !m.Private {
+			total += m.Total
+		}
+	}
+	return total
+}
+
+func (s *TeamMemberSummary) Shares(summaryType uint8) bool {
+	for _, t := range s.Shared {
+		if t == summaryType {
+			return true
+		}
+	}
+	return false
+}
+
+// SharedTeamSummaryTypes returns the summary types a user exposes to their teams, based on the user's public sharing settings
+func SharedTeamSummaryTypes(user *User) map[uint8]bool {
+	shared := user.ShareDataMaxDays != 0
+	return map[uint8]bool{
+		SummaryProject:  shared && user.ShareProjects,
+		SummaryBranch:   shared && user.ShareProjects,
+		SummaryEntity:   shared && user.ShareProjects,
+		SummaryTicket:   shared && user.ShareProjects,
+		SummaryLanguage: shared && user.ShareLanguages,
+		SummaryEditor:   shared && user.ShareEditors,
+		SummaryOS:       shared && user.ShareOSs,
+		SummaryMachine:  shared && user.ShareMachines,
+		SummaryLabel:    shared && user.ShareLabels,
+	}
+}
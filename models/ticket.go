@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTicketPattern   = `[A-Z][A-Z0-9]+-[0-9]+` // jira-style issue keys, e.g. "PROJ-1234"
+	MaxTicketPatternLength = 255
+)
+
+// TicketExtractor extracts ticket or issue keys from branch names, e.g. "PROJ-1234" from "feature/PROJ-1234-some-text",
+// and, optionally, from file paths for activity without a branch. If the pattern contains a capturing group, the key is
+// taken from the first one, otherwise the entire match is used.
+type TicketExtractor struct {
+	pattern    *regexp.Regexp
+	fromEntity bool
+}
+
+// Worklog is the time spent on a single ticket on a single day, as imported into issue trackers' time tracking
+type Worklog struct {
+	Ticket   string
+	Started  time.Time     // start of the first activity on that day
+	Duration time.Duration // actual coding time, excluding breaks
+	Projects []string
+}
+
+func NewTicketExtractor(pattern string, fromEntity bool) (*TicketExtractor, error) {
+	if len(pattern) > MaxTicketPatternLength {
+		return nil, fmt.Errorf("pattern must not be longer than %d characters", MaxTicketPatternLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &TicketExtractor{pattern: re, fromEntity: fromEntity}, nil
+}
+
+func ValidateTicketPattern(pattern string) bool {
+	_, err := NewTicketExtractor(pattern, false)
+	return err == nil
+}
+
+// Extract returns the ticket key found in the given branch or, if enabled and the branch doesn't contain any, in the
+// given entity, or an empty string if there is none. Safe to call on a nil extractor.
+func (e *TicketExtractor) Extract(branch, entity string) string {
+	if e == nil {
+		return ""
+	}
+	if ticket := e.match(branch); ticket != "" || !e.fromEntity {
+		return ticket
+	}
+	return e.match(entity)
+}
+
+func (e *TicketExtractor) match(s string) string {
+	if s == "" {
+		return ""
+	}
+	m := e.pattern.FindStringSubmatch(s)
+	if len(m) == 0 {
+		return ""
+	}
+	if len(m) > 1 {
+		return m[1]
+	}
+	return m[0]
+}
+
+func (w *Worklog) ExportValue(column string, user *User) interface{} {
+	switch column {
+	case "issue_key":
+		return w.Ticket
+	case "date":
+		return w.Started.In(user.TZ()).Format(time.DateOnly)
+	case "started":
+		return w.Started.In(user.TZ()).Format(time.RFC3339)
+	case "time_spent_seconds":
+		return int(w.Duration.Round(time.Second).Seconds())
+	case "time_spent":
+		return FormatWorklogDuration(w.Duration)
+	case "comment":
+		return fmt.Sprintf("Coding activity in %s", strings.Join(w.Projects, ", "))
+	}
+	return nil
+}
+
+// FormatWorklogDuration formats a duration the way jira expects time spent to be entered, e.g. "1h 30m", rounded to minutes
+func FormatWorklogDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	switch {
+	case minutes < 1:
+		return "1m"
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	default:
+		return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTicketExtractor_Extract(t *testing.T) {
+	sut, err := NewTicketExtractor(DefaultTicketPattern, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "PROJ-1234", sut.Extract("feature/PROJ-1234-some-text", ""))
+	assert.Equal(t, "", sut.Extract("master", "/home/bob/dev/PROJ-99/main.go"))
+	assert.Equal(t, "", sut.Extract("", ""))
+
+	sut, _ = NewTicketExtractor(DefaultTicketPattern, true)
+	assert.Equal(t, "PROJ-1234", sut.Extract("feature/PROJ-1234-some-text", "/home/bob/dev/PROJ-99/main.go"))
+	assert.Equal(t, "PROJ-99", sut.Extract("master", "/home/bob/dev/PROJ-99/main.go"))
+
+	// key is taken from first group, if any
+	sut, _ = NewTicketExtractor(`(?:issue|gh)-(\d+)`, false)
+	assert.Equal(t, "42", sut.Extract("bugfix/gh-42-crash", ""))
+
+	var nilExtractor *TicketExtractor
+	assert.Equal(t, "", nilExtractor.Extract("feature/PROJ-1234", ""))
+}
+
+func TestValidateTicketPattern(t *testing.T) {
+	assert.True(t, ValidateTicketPattern(DefaultTicketPattern))
+	assert.False(t, ValidateTicketPattern(`[A-Z+`))
+	assert.False(t, ValidateTicketPattern(string(make([]byte, MaxTicketPatternLength+1))))
+}
+
+func TestUser_TicketExtractor(t *testing.T) {
+	assert.Nil(t, (&User{}).TicketExtractor())
+	assert.Nil(t, (&User{TicketPattern: `(`}).TicketExtractor())
+	assert.NotNil(t, (&User{TicketPattern: DefaultTicketPattern}).TicketExtractor())
+}
+
+func TestFormatWorklogDuration(t *testing.T) {
+	assert.Equal(t, "1m", FormatWorklogDuration(10*time.Second))
+	assert.Equal(t, "45m", FormatWorklogDuration(45*time.Minute))
+	assert.Equal(t, "2h", FormatWorklogDuration(2*time.Hour))
+	assert.Equal(t, "1h 30m", FormatWorklogDuration(89*time.Minute+40*time.Second))
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 )
 
+const MaxDailyGoalMinutes = 24 * 60
+
 func init() {
 	mailRegex = regexp.MustCompile(MailPattern)
 }
@@ -31,6 +33,7 @@ type User struct {
 	ShareMachines          bool        `json:"-" gorm:"default:false; type:bool"`
 	ShareLabels            bool        `json:"-" gorm:"default:false; type:bool"`
 	IsAdmin                bool        `json:"-" gorm:"default:false; type:bool"`
+	IsDisabled             bool        `json:"-" gorm:"default:false; type:bool"` // disabled accounts can neither log in nor use the api
 	HasData                bool        `json:"-" gorm:"default:false; type:bool"`
 	WakatimeApiKey         string      `json:"-"` // for relay middleware and imports
 	WakatimeApiUrl         string      `json:"-"` // for relay middleware and imports
@@ -42,6 +45,16 @@ type User struct {
 	StripeCustomerId       string      `json:"-"`
 	InvitedBy              string      `json:"-"`
 	ExcludeUnknownProjects bool        `json:"-"`
+	OidcSubject            string      `json:"-" gorm:"index:idx_user_oidc_subject; size:255"` // subject identifier at the configured openid connect provider, if linked
+	TotpSecret             string      `json:"-"`
+	TotpEnabled            bool        `json:"-" gorm:"default:false; type:bool"`
+	TotpRecoveryCodes      string      `json:"-"`                                               // comma-separated list of hashed, single-use recovery codes
+	TotpLastStep           int64       `json:"-"`                                               // time step of the last accepted one-time password, to prevent replays
+	SessionGapMinutes      int         `json:"-" gorm:"default:15"`                             // maximum break within a work session
+	CalendarToken          string      `json:"-" gorm:"index:idx_user_calendar_token; size:64"` // secret for subscribing to work sessions as icalendar feed, disabled if empty
+	TicketPattern          string      `json:"-" gorm:"size:255"`                               // regular expression to extract ticket keys from branch names, disabled if empty
+	TicketFromEntity       bool        `json:"-" gorm:"default:false; type:bool"`               // whether to also extract ticket keys from file paths if the branch doesn't contain any
+	DailyGoalMinutes       int         `json:"-" gorm:"default:0"`                              // daily coding time goal, disabled if zero
 }
 
 type Login struct {
@@ -109,6 +122,26 @@ func (u *User) TZ() *time.Location {
 	return tz
 }
 
+// SessionGap returns the maximum break between two activities to still be considered part of the same work session
+func (u *User) SessionGap() time.Duration {
+	if u.SessionGapMinutes <= 0 {
+		return DefaultSessionGapMinutes * time.Minute
+	}
+	return time.Duration(u.SessionGapMinutes) * time.Minute
+}
+
+// TicketExtractor returns an extractor for the user's ticket pattern or nil if none (or an invalid one) is configured
+func (u *User) TicketExtractor() *TicketExtractor {
+	if u.TicketPattern == "" {
+		return nil
+	}
+	extractor, err := NewTicketExtractor(u.TicketPattern, u.TicketFromEntity)
+	if err != nil {
+		return nil
+	}
+	return extractor
+}
+
 // TZOffset returns the time difference between the user's current time zone and UTC
 // TODO: is this actually working??
 func (u *User) TZOffset() time.Duration {
@@ -170,6 +203,11 @@ func (u *User) MinDataAge() time.Time {
 	return time.Now().AddDate(0, -retentionMonths, 0)
 }
 
+// HasTotp returns true if the user has set up two-factor authentication, i.e. is required to enter a one-time password on login
+func (u *User) HasTotp() bool {
+	return u.TotpEnabled && u.TotpSecret != ""
+}
+
 func (u *User) AnyDataShared() bool {
 	return u.ShareDataMaxDays != 0 && (u.ShareEditors || u.ShareLanguages || u.ShareProjects || u.ShareOSs || u.ShareMachines || u.ShareLabels)
 }
@@ -0,0 +1,25 @@
+package view
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+type AdminViewModel struct {
+	SharedLoggedInViewModel
+	Users         []*models.AdminUserInfo
+	Query         string
+	PageParams    *utils.PageParams
+	PasswordReset *models.AdminPasswordResetResult
+	ResetUserId   string
+}
+
+func (s *AdminViewModel) WithSuccess(m string) *AdminViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *AdminViewModel) WithError(m string) *AdminViewModel {
+	s.SetError(m)
+	return s
+}
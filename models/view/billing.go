@@ -0,0 +1,64 @@
+package view
+
+import (
+	"github.com/muety/wakapi/models"
+	"net/url"
+	"time"
+)
+
+type BillingViewModel struct {
+	SharedLoggedInViewModel
+	Rates    []models.BillingRates // grouped by target, most recent version first
+	Report   *models.BillingReport
+	Labels   []string // all of the user's labels, for suggestions
+	Projects []string // all of the user's projects, for suggestions
+	Query    url.Values
+	Today    time.Time
+}
+
+func (s *BillingViewModel) WithSuccess(m string) *BillingViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *BillingViewModel) WithError(m string) *BillingViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *BillingViewModel) Intervals() []*models.IntervalKey {
+	return []*models.IntervalKey{
+		models.IntervalThisWeek,
+		models.IntervalLastWeek,
+		models.IntervalThisMonth,
+		models.IntervalLastMonth,
+		models.IntervalPast30Days,
+		models.IntervalThisYear,
+	}
+}
+
+func (s *BillingViewModel) RoundingModes() []string {
+	return []string{models.BillingRoundingUp, models.BillingRoundingNearest, models.BillingRoundingDown}
+}
+
+// IntervalLink returns the billing page's query string for the given interval, retaining the currently applied filters
+func (s *BillingViewModel) IntervalLink(interval *models.IntervalKey) string {
+	q := s.copyQuery()
+	q.Del("from")
+	q.Del("to")
+	q.Set("interval", (*interval)[0])
+	return "?" + q.Encode()
+}
+
+// RawQuery returns the current query string, e.g. to request an export of the currently displayed amounts
+func (s *BillingViewModel) RawQuery() string {
+	return s.Query.Encode()
+}
+
+func (s *BillingViewModel) copyQuery() url.Values {
+	q := url.Values{}
+	for k, v := range s.Query {
+		q[k] = v
+	}
+	return q
+}
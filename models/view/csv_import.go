@@ -0,0 +1,34 @@
+package view
+
+import "github.com/muety/wakapi/models"
+
+// CsvImportFields lists the heartbeat fields csv columns can be mapped to, in display order
+var CsvImportFields = []string{"time", "entity", "project", "language", "branch", "editor", "machine", "duration"}
+
+type CsvImportViewModel struct {
+	SharedLoggedInViewModel
+	Token    string
+	FileName string
+	Header   []string
+	Mapping  map[string]string // field -> column
+	Timezone string
+	Preview  *models.CsvImportPreview
+}
+
+func (s *CsvImportViewModel) Fields() []string {
+	return CsvImportFields
+}
+
+func (s *CsvImportViewModel) IsMapped(field, column string) bool {
+	return s.Mapping[field] == column
+}
+
+func (s *CsvImportViewModel) WithSuccess(m string) *CsvImportViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *CsvImportViewModel) WithError(m string) *CsvImportViewModel {
+	s.SetError(m)
+	return s
+}
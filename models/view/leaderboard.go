@@ -1,6 +1,7 @@
 package view
 
 import (
+	"fmt"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/utils"
 	"time"
@@ -15,6 +16,9 @@ type LeaderboardViewModel struct {
 	UserLanguages map[string][]string
 	IntervalLabel string
 	PageParams    *utils.PageParams
+	// only set when viewing a private leaderboard
+	PrivateLeaderboard *models.PrivateLeaderboard
+	Members            []*models.PrivateLeaderboardMember
 }
 
 func (s *LeaderboardViewModel) WithSuccess(m string) *LeaderboardViewModel {
@@ -27,6 +31,22 @@ func (s *LeaderboardViewModel) WithError(m string) *LeaderboardViewModel {
 	return s
 }
 
+// BaseLink is the (relative) link of the leaderboard currently viewed, i.e. either the public or a private one
+func (s *LeaderboardViewModel) BaseLink() string {
+	if s.PrivateLeaderboard != nil {
+		return fmt.Sprintf("leaderboard/private/%d", s.PrivateLeaderboard.ID)
+	}
+	return "leaderboard"
+}
+
+func (s *LeaderboardViewModel) IsOwner() bool {
+	return s.PrivateLeaderboard != nil && s.User != nil && s.PrivateLeaderboard.OwnerID == s.User.ID
+}
+
+func (s *LeaderboardViewModel) Scopes() []PrivateLeaderboardScopeOption {
+	return privateLeaderboardScopeOptions()
+}
+
 func (s *LeaderboardViewModel) ColorModifier(item *models.LeaderboardItemRanked, principal *models.User) string {
 	if principal != nil && item.UserID == principal.ID {
 		return "self"
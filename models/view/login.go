@@ -2,10 +2,13 @@ package view
 
 type LoginViewModel struct {
 	SharedViewModel
-	TotalUsers  int
-	AllowSignup bool
-	CaptchaId   string
-	InviteCode  string
+	TotalUsers    int
+	AllowSignup   bool
+	CaptchaId     string
+	InviteCode    string
+	OidcEnabled   bool
+	OidcName      string
+	PasswordLogin bool
 }
 
 type SetPasswordViewModel struct {
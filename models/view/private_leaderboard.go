@@ -0,0 +1,41 @@
+package view
+
+import (
+	"github.com/muety/wakapi/models"
+)
+
+type PrivateLeaderboardsViewModel struct {
+	SharedLoggedInViewModel
+	Leaderboards []*models.PrivateLeaderboard
+}
+
+type PrivateLeaderboardScopeOption struct {
+	Key   string
+	Label string
+}
+
+func (s *PrivateLeaderboardsViewModel) WithSuccess(m string) *PrivateLeaderboardsViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *PrivateLeaderboardsViewModel) WithError(m string) *PrivateLeaderboardsViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *PrivateLeaderboardsViewModel) IsOwner(leaderboard *models.PrivateLeaderboard) bool {
+	return s.User != nil && leaderboard.OwnerID == s.User.ID
+}
+
+func (s *PrivateLeaderboardsViewModel) Scopes() []PrivateLeaderboardScopeOption {
+	return privateLeaderboardScopeOptions()
+}
+
+func privateLeaderboardScopeOptions() []PrivateLeaderboardScopeOption {
+	options := make([]PrivateLeaderboardScopeOption, len(models.PrivateLeaderboardScopes))
+	for i, scope := range models.PrivateLeaderboardScopes {
+		options[i] = PrivateLeaderboardScopeOption{Key: (*scope)[0], Label: scope.GetHumanReadable()}
+	}
+	return options
+}
@@ -2,20 +2,42 @@ package view
 
 import (
 	"github.com/muety/wakapi/models"
+	"html/template"
 	"time"
 )
 
 type SettingsViewModel struct {
 	SharedLoggedInViewModel
-	LanguageMappings    []*models.LanguageMapping
-	Aliases             []*SettingsVMCombinedAlias
-	Labels              []*SettingsVMCombinedLabel
-	Projects            []string
-	SubscriptionPrice   string
-	DataRetentionMonths int
-	UserFirstData       time.Time
-	SupportContact      string
-	InviteLink          string
+	LanguageMappings         []*models.LanguageMapping
+	DefaultMappings          []*models.DefaultLanguageMapping
+	Aliases                  []*SettingsVMCombinedAlias
+	Labels                   []*SettingsVMCombinedLabel
+	Projects                 []string
+	SubscriptionPrice        string
+	DataRetentionMonths      int
+	UserFirstData            time.Time
+	SupportContact           string
+	InviteLink               string
+	TotpSecret               string
+	TotpQrCode               template.URL
+	TotpRecoveryCodes        []string
+	Sessions                 []*models.Session
+	CurrentSessionId         string
+	ImportJobs               []*models.ImportJob
+	RelayTargets             []*models.RelayTarget
+	RelayBacklog             *models.RelayBacklog
+	Webhooks                 []*SettingsVMWebhook
+	WebhookEvents            []string
+	NotificationChannels     []*models.NotificationChannel
+	NotificationChannelTypes []string
+	NotificationKinds        []string
+	ReportSubscriptions      []*models.ReportSubscription
+	ReportPeriods            []string
+	ReportSections           []string
+	CalendarUrl              string
+	MaxSessionGapMinutes     int
+	MaxDailyGoalMinutes      int
+	DefaultTicketPattern     string
 }
 
 type SettingsVMCombinedAlias struct {
@@ -24,8 +46,14 @@ type SettingsVMCombinedAlias struct {
 	Values []string
 }
 
+type SettingsVMWebhook struct {
+	*models.Webhook
+	Deliveries []*models.WebhookDelivery
+}
+
 type SettingsVMCombinedLabel struct {
 	Key    string
+	Color  string
 	Values []string
 }
 
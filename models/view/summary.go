@@ -14,6 +14,9 @@ type SummaryViewModel struct {
 	EditorColors        map[string]string
 	LanguageColors      map[string]string
 	OSColors            map[string]string
+	LabelColors         map[string]string
+	LabelDepth          int
+	LabelDepthOptions   []int
 	RawQuery            string
 	UserFirstData       time.Time
 	DataRetentionMonths int
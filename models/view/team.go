@@ -0,0 +1,126 @@
+package view
+
+import (
+	"github.com/muety/wakapi/models"
+	"net/url"
+)
+
+const teamDashboardTopItems = 10
+
+type TeamsViewModel struct {
+	SharedLoggedInViewModel
+	Memberships []*models.TeamMember
+	Invitations []*models.TeamMember
+}
+
+type TeamViewModel struct {
+	SharedLoggedInViewModel
+	Team        *models.Team
+	Membership  *models.TeamMember
+	Members     []*models.TeamMember
+	TeamSummary *models.TeamSummary
+	Query       url.Values
+}
+
+func (s *TeamsViewModel) WithSuccess(m string) *TeamsViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *TeamsViewModel) WithError(m string) *TeamsViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *TeamViewModel) WithSuccess(m string) *TeamViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *TeamViewModel) WithError(m string) *TeamViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *TeamViewModel) IsOwner() bool {
+	return s.Membership != nil && s.Membership.IsOwner()
+}
+
+func (s *TeamViewModel) Intervals() []*models.IntervalKey {
+	return []*models.IntervalKey{
+		models.IntervalToday,
+		models.IntervalThisWeek,
+		models.IntervalThisMonth,
+		models.IntervalThisYear,
+		models.IntervalPast7Days,
+		models.IntervalPast30Days,
+		models.IntervalPast12Months,
+		models.IntervalAny,
+	}
+}
+
+func (s *TeamViewModel) DashboardTypes() []uint8 {
+	return []uint8{models.SummaryProject, models.SummaryLanguage, models.SummaryEditor, models.SummaryOS, models.SummaryMachine, models.SummaryLabel}
+}
+
+func (s *TeamViewModel) TopItems(summaryType uint8) models.SummaryItems {
+	if s.TeamSummary == nil {
+		return models.SummaryItems{}
+	}
+	items := *s.TeamSummary.Summary.GetByType(summaryType)
+	if len(items) > teamDashboardTopItems {
+		return items[:teamDashboardTopItems]
+	}
+	return items
+}
+
+func (s *TeamViewModel) Filters() []models.FilterElement {
+	filters := make([]models.FilterElement, 0)
+	if s.TeamSummary == nil || !s.TeamSummary.Filtered {
+		return filters
+	}
+	for _, t := range models.SummaryTypes() {
+		if values := s.Query[filterParam(t)]; len(values) > 0 && values[0] != "" {
+			filters = append(filters, models.FilterElement{Entity: t, Filter: values})
+		}
+	}
+	return filters
+}
+
+// IntervalLink returns the dashboard's query string for the given interval, retaining the currently applied filters
+func (s *TeamViewModel) IntervalLink(interval *models.IntervalKey) string {
+	q := s.copyQuery()
+	q.Del("from")
+	q.Del("to")
+	q.Set("interval", (*interval)[0])
+	return "?" + q.Encode()
+}
+
+// FilterLink returns the dashboard's query string with an additional filter for the given entity
+func (s *TeamViewModel) FilterLink(summaryType uint8, key string) string {
+	q := s.copyQuery()
+	q.Set(filterParam(summaryType), key)
+	return "?" + q.Encode()
+}
+
+func (s *TeamViewModel) UnfilterLink(summaryType uint8) string {
+	q := s.copyQuery()
+	q.Del(filterParam(summaryType))
+	return "?" + q.Encode()
+}
+
+func (s *TeamViewModel) copyQuery() url.Values {
+	q := url.Values{}
+	for k, v := range s.Query {
+		q[k] = v
+	}
+	return q
+}
+
+// filterParam maps a summary type to its corresponding query parameter (see helpers.ParseSummaryFilters)
+func filterParam(summaryType uint8) string {
+	if summaryType == models.SummaryEntity {
+		return "entity"
+	}
+	return models.GetEntityColumn(summaryType)
+}
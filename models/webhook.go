@@ -0,0 +1,173 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/muety/wakapi/config"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// WebhookDeliveryMaxAttempts is the number of delivery attempts after which a webhook delivery is given up on
+	WebhookDeliveryMaxAttempts = 8
+	webhookDeliveryBaseBackoff = 1 * time.Minute
+	webhookDeliveryMaxBackoff  = 2 * time.Hour
+)
+
+// WebhookEvents are the events webhooks can be subscribed to
+var WebhookEvents = []string{
+	config.EventHeartbeatCreate,
+	config.EventUserUpdate,
+	config.EventWakatimeFailure,
+	config.EventSummaryCreate,
+	config.EventImportFinish,
+	config.EventGoalReached,
+}
+
+// Webhook is an http endpoint, which is notified about the given (comma-separated) events of its owner.
+// Global webhooks can only be registered by admins and receive the events of all users.
+type Webhook struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_webhook_user"`
+	Name      string     `json:"name" gorm:"type:varchar(255)"`
+	Url       string     `json:"url" gorm:"not null"`
+	Secret    string     `json:"-" gorm:"not null"`
+	Events    string     `json:"events"`
+	Enabled   bool       `json:"enabled" gorm:"default:true; type:bool"`
+	IsGlobal  bool       `json:"is_global" gorm:"default:false; type:bool"`
+	CreatedAt CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// WebhookDelivery is a single event to be sent to a webhook. Deliveries are kept for a while after they succeeded or were given up on, to serve as a delivery log.
+type WebhookDelivery struct {
+	ID            uint64      `json:"id" gorm:"primary_key"`
+	Webhook       *Webhook    `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	WebhookID     uint        `json:"webhook_id" gorm:"not null; index:idx_webhook_delivery_webhook"`
+	EventId       string      `json:"event_id" gorm:"type:varchar(36)"`
+	Event         string      `json:"event" gorm:"type:varchar(64)"`
+	Payload       string      `json:"-" gorm:"type:text"`
+	Attempts      int         `json:"attempts" gorm:"default:0"`
+	NextAttemptAt CustomTime  `json:"next_attempt_at" gorm:"index:idx_webhook_delivery_next" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	StatusCode    int         `json:"status_code"`
+	LastError     string      `json:"last_error"`
+	DeliveredAt   *CustomTime `json:"delivered_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt     CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// WebhookPayload is the json body sent to webhooks
+type WebhookPayload struct {
+	Id        string      `json:"id"`
+	Event     string      `json:"event"`
+	UserId    string      `json:"user_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func (w *Webhook) IsValid() bool {
+	u, err := url.Parse(w.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if w.UserID == "" || w.Secret == "" || len(w.Name) > 255 || len(w.EventList()) == 0 {
+		return false
+	}
+	for _, e := range w.EventList() {
+		if !IsWebhookEvent(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// DisplayName returns the webhook's name or its host, if no name was given
+func (w *Webhook) DisplayName() string {
+	if w.Name != "" {
+		return w.Name
+	}
+	if u, err := url.Parse(w.Url); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return w.Url
+}
+
+func (w *Webhook) EventList() []string {
+	result := make([]string, 0)
+	for _, s := range strings.Split(w.Events, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches decides whether the given event, caused by the given user, is to be sent to this webhook
+func (w *Webhook) Matches(event, userId string) bool {
+	return w.Enabled && (w.IsGlobal || w.UserID == userId) && w.Subscribes(event)
+}
+
+// Sign computes the hex-encoded hmac-sha256 signature of the given request body and unix timestamp, separated by a dot
+func (w *Webhook) Sign(body []byte, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(fmt.Sprintf("%d.", timestamp)))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *WebhookDelivery) IsDelivered() bool {
+	return d.DeliveredAt != nil
+}
+
+// IsExhausted returns true if the delivery won't be retried anymore
+func (d *WebhookDelivery) IsExhausted() bool {
+	return !d.IsDelivered() && d.Attempts >= WebhookDeliveryMaxAttempts
+}
+
+// State returns "delivered", "failed" (given up on) or "pending"
+func (d *WebhookDelivery) State() string {
+	if d.IsDelivered() {
+		return "delivered"
+	}
+	if d.IsExhausted() {
+		return "failed"
+	}
+	return "pending"
+}
+
+// ScheduleRetry records a failed delivery attempt and defers the next one by an exponentially growing interval
+func (d *WebhookDelivery) ScheduleRetry(statusCode int, reason string, now time.Time) {
+	d.Attempts++
+	d.StatusCode = statusCode
+	d.LastError = reason
+	d.NextAttemptAt = CustomTime(now.Add(exponentialBackoff(d.Attempts, webhookDeliveryBaseBackoff, webhookDeliveryMaxBackoff)))
+}
+
+func (d *WebhookDelivery) MarkDelivered(statusCode int, now time.Time) {
+	at := CustomTime(now)
+	d.Attempts++
+	d.StatusCode = statusCode
+	d.LastError = ""
+	d.DeliveredAt = &at
+}
+
+func IsWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
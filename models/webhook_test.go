@@ -0,0 +1,67 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_IsValid(t *testing.T) {
+	webhook := &Webhook{UserID: "user1", Url: "https://example.org/hook", Secret: "secret", Events: "heartbeat.create, summary.create"}
+	assert.True(t, webhook.IsValid())
+
+	webhook.Url = "ftp://example.org"
+	assert.False(t, webhook.IsValid())
+
+	webhook.Url = "https://example.org/hook"
+	webhook.Events = "heartbeat.create,user.delete"
+	assert.False(t, webhook.IsValid())
+
+	webhook.Events = ""
+	assert.False(t, webhook.IsValid())
+}
+
+func TestWebhook_Matches(t *testing.T) {
+	own := &Webhook{UserID: "user1", Enabled: true, Events: config.EventSummaryCreate}
+	global := &Webhook{UserID: "admin", Enabled: true, IsGlobal: true, Events: config.EventSummaryCreate + "," + config.EventUserUpdate}
+
+	assert.True(t, own.Matches(config.EventSummaryCreate, "user1"))
+	assert.False(t, own.Matches(config.EventSummaryCreate, "user2"))
+	assert.False(t, own.Matches(config.EventUserUpdate, "user1"))
+	assert.True(t, global.Matches(config.EventUserUpdate, "user2"))
+
+	global.Enabled = false
+	assert.False(t, global.Matches(config.EventUserUpdate, "user2"))
+}
+
+func TestWebhook_Sign(t *testing.T) {
+	webhook := &Webhook{Secret: "secret"}
+	body := []byte(`{"event":"user.update"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000.{"event":"user.update"}`))
+
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), webhook.Sign(body, 1700000000))
+	assert.NotEqual(t, webhook.Sign(body, 1700000000), webhook.Sign(body, 1700000001))
+}
+
+func TestWebhookDelivery_ScheduleRetry(t *testing.T) {
+	now := time.Now()
+	delivery := &WebhookDelivery{}
+	for i := 0; i < WebhookDeliveryMaxAttempts; i++ {
+		assert.Equal(t, "pending", delivery.State())
+		delivery.ScheduleRetry(502, "got status 502", now)
+	}
+	assert.Equal(t, "failed", delivery.State())
+	assert.Equal(t, 502, delivery.StatusCode)
+	assert.Equal(t, now.Add(2*time.Hour), delivery.NextAttemptAt.T())
+
+	delivery.MarkDelivered(200, now)
+	assert.Equal(t, "delivered", delivery.State())
+	assert.Empty(t, delivery.LastError)
+}
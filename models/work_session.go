@@ -0,0 +1,163 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	WorkSessionFormatCsv   = "csv"
+	WorkSessionFormatToggl = "toggl" // csv as understood by toggl track's and clockify's importers
+	WorkSessionFormatIcs   = "ics"
+)
+
+const (
+	DefaultSessionGapMinutes = 15
+	MaxSessionGapMinutes     = 240
+)
+
+var (
+	workSessionColumns      = []string{"date", "start", "end", "duration", "hours", "project", "branch", "projects"}
+	workSessionTogglColumns = []string{"Email", "Project", "Description", "Start date", "Start time", "End date", "End time", "Duration"}
+)
+
+// WorkSession is a period of contiguous coding activity, i.e. without any break longer than the user's session gap,
+// attributed to the project most time was spent on
+type WorkSession struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration" swaggertype:"primitive,integer"` // actual coding time within the session, excluding short breaks
+	Project  string        `json:"project"`
+	Branch   string        `json:"branch"`   // branch most time was spent on within the dominant project
+	Projects []string      `json:"projects"` // all projects worked on, by time spent
+}
+
+type WorkSessions []*WorkSession
+
+// NewWorkSessions groups the given durations into sessions, starting a new one whenever there is no activity for more than maxGap
+func NewWorkSessions(durations Durations, maxGap time.Duration) WorkSessions {
+	sorted := make(Durations, len(durations))
+	copy(sorted, durations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.T().Before(sorted[j].Time.T())
+	})
+
+	sessions := make(WorkSessions, 0)
+	var current Durations
+	var end time.Time
+
+	for _, d := range sorted {
+		if len(current) > 0 && d.Time.T().Sub(end) > maxGap {
+			sessions = append(sessions, newWorkSession(current, end))
+			current = nil
+		}
+		if dEnd := d.Time.T().Add(d.Duration); len(current) == 0 || dEnd.After(end) {
+			end = dEnd
+		}
+		current = append(current, d)
+	}
+	if len(current) > 0 {
+		sessions = append(sessions, newWorkSession(current, end))
+	}
+
+	return sessions
+}
+
+func newWorkSession(durations Durations, end time.Time) *WorkSession {
+	session := &WorkSession{Start: durations[0].Time.T(), End: end}
+
+	projects := make(map[string]time.Duration)
+	branches := make(map[string]map[string]time.Duration)
+	for _, d := range durations {
+		session.Duration += d.Duration
+		projects[d.Project] += d.Duration
+		if _, ok := branches[d.Project]; !ok {
+			branches[d.Project] = make(map[string]time.Duration)
+		}
+		if d.Branch != "" {
+			branches[d.Project][d.Branch] += d.Duration
+		}
+	}
+
+	session.Projects = sortedByTime(projects)
+	session.Project = session.Projects[0]
+	if b := sortedByTime(branches[session.Project]); len(b) > 0 {
+		session.Branch = b[0]
+	}
+	return session
+}
+
+// sortedByTime returns the keys of the given map, descending by their values and alphabetically for equal values
+func sortedByTime(m map[string]time.Duration) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func IsValidWorkSessionFormat(format string) bool {
+	return slices.Contains([]string{WorkSessionFormatCsv, WorkSessionFormatToggl, WorkSessionFormatIcs}, format)
+}
+
+// WorkSessionColumns returns the columns of a work session export in the given (tabular) format
+func WorkSessionColumns(format string) []string {
+	if format == WorkSessionFormatToggl {
+		return workSessionTogglColumns
+	}
+	return workSessionColumns
+}
+
+// Description returns a short, human-readable description of the session, e.g. for calendar events or time tracking entries
+func (s *WorkSession) Description() string {
+	if s.Branch != "" {
+		return fmt.Sprintf("%s (%s)", s.Project, s.Branch)
+	}
+	return s.Project
+}
+
+// ExportValue returns the session's value for the given column of a csv or toggl export, with times in the user's time zone
+func (s *WorkSession) ExportValue(column string, user *User) interface{} {
+	start, end := s.Start.In(user.TZ()), s.End.In(user.TZ())
+	switch column {
+	case "date", "Start date":
+		return start.Format(time.DateOnly)
+	case "start":
+		return start.Format(time.RFC3339)
+	case "end":
+		return end.Format(time.RFC3339)
+	case "duration":
+		return s.Duration.Seconds()
+	case "hours":
+		return fmt.Sprintf("%.2f", s.Duration.Hours())
+	case "project", "Project":
+		return s.Project
+	case "branch":
+		return s.Branch
+	case "projects":
+		return strings.Join(s.Projects, ", ")
+	case "Email":
+		return user.Email
+	case "Description":
+		return s.Description()
+	case "Start time":
+		return start.Format(time.TimeOnly)
+	case "End date":
+		return end.Format(time.DateOnly)
+	case "End time":
+		return end.Format(time.TimeOnly)
+	case "Duration":
+		total := int(s.Duration.Round(time.Second).Seconds())
+		return fmt.Sprintf("%02d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return nil
+}
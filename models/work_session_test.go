@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWorkSessions(t *testing.T) {
+	t0 := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	durations := Durations{
+		// out of order on purpose
+		{Time: CustomTime(t0.Add(30 * time.Minute)), Duration: 20 * time.Minute, Project: "wakapi", Branch: "master"},
+		{Time: CustomTime(t0), Duration: 10 * time.Minute, Project: "anchr", Branch: "dev"},
+		{Time: CustomTime(t0.Add(15 * time.Minute)), Duration: 10 * time.Minute, Project: "wakapi", Branch: "feature"},
+		// 60 min break
+		{Time: CustomTime(t0.Add(110 * time.Minute)), Duration: 5 * time.Minute, Project: "anchr"},
+	}
+
+	sessions := NewWorkSessions(durations, 15*time.Minute)
+
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, t0, sessions[0].Start)
+	assert.Equal(t, t0.Add(50*time.Minute), sessions[0].End)
+	assert.Equal(t, 40*time.Minute, sessions[0].Duration)
+	assert.Equal(t, "wakapi", sessions[0].Project)
+	assert.Equal(t, "master", sessions[0].Branch)
+	assert.Equal(t, []string{"wakapi", "anchr"}, sessions[0].Projects)
+	assert.Equal(t, "wakapi (master)", sessions[0].Description())
+
+	assert.Equal(t, t0.Add(110*time.Minute), sessions[1].Start)
+	assert.Equal(t, "anchr", sessions[1].Project)
+	assert.Empty(t, sessions[1].Branch)
+	assert.Equal(t, "anchr", sessions[1].Description())
+
+	assert.Len(t, NewWorkSessions(durations, 90*time.Minute), 1)
+	assert.Empty(t, NewWorkSessions(Durations{}, 15*time.Minute))
+}
+
+func TestWorkSession_ExportValue(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	user := &User{ID: "user1", Email: "user1@example.org", Location: tz.String()}
+	session := &WorkSession{
+		Start:    time.Date(2025, 3, 10, 22, 30, 0, 0, time.UTC),
+		End:      time.Date(2025, 3, 11, 0, 15, 0, 0, time.UTC),
+		Duration: 95*time.Minute + 30*time.Second,
+		Project:  "wakapi",
+		Projects: []string{"wakapi", "anchr"},
+	}
+
+	assert.Equal(t, "2025-03-10", session.ExportValue("date", &User{ID: "user1"}))
+	assert.Equal(t, "2025-03-10T23:30:00+01:00", session.ExportValue("start", user))
+	assert.Equal(t, "1.59", session.ExportValue("hours", user))
+	assert.Equal(t, "wakapi, anchr", session.ExportValue("projects", user))
+
+	assert.Equal(t, "user1@example.org", session.ExportValue("Email", user))
+	assert.Equal(t, "2025-03-10", session.ExportValue("Start date", user))
+	assert.Equal(t, "23:30:00", session.ExportValue("Start time", user))
+	assert.Equal(t, "2025-03-11", session.ExportValue("End date", user))
+	assert.Equal(t, "01:15:00", session.ExportValue("End time", user))
+	assert.Equal(t, "01:35:30", session.ExportValue("Duration", user))
+	assert.Nil(t, session.ExportValue("unknown", user))
+}
+
+func TestIsValidWorkSessionFormat(t *testing.T) {
+	assert.True(t, IsValidWorkSessionFormat("csv"))
+	assert.True(t, IsValidWorkSessionFormat("toggl"))
+	assert.True(t, IsValidWorkSessionFormat("ics"))
+	assert.False(t, IsValidWorkSessionFormat("json"))
+}
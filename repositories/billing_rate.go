@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type BillingRateRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewBillingRateRepository(db *gorm.DB) *BillingRateRepository {
+	return &BillingRateRepository{config: config.Get(), db: db}
+}
+
+func (r *BillingRateRepository) GetById(id uint) (*models.BillingRate, error) {
+	rate := &models.BillingRate{}
+	if err := r.db.Where(&models.BillingRate{ID: id}).First(rate).Error; err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (r *BillingRateRepository) GetByUser(userId string) ([]*models.BillingRate, error) {
+	var rates []*models.BillingRate
+	if userId == "" {
+		return rates, nil
+	}
+	if err := r.db.
+		Where(&models.BillingRate{UserID: userId}).
+		Order("id asc").
+		Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *BillingRateRepository) Insert(rate *models.BillingRate) (*models.BillingRate, error) {
+	if err := r.db.Create(rate).Error; err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (r *BillingRateRepository) Delete(id uint) error {
+	return r.db.Where("id = ?", id).Delete(models.BillingRate{}).Error
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type DefaultLanguageMappingRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewDefaultLanguageMappingRepository(db *gorm.DB) *DefaultLanguageMappingRepository {
+	return &DefaultLanguageMappingRepository{config: config.Get(), db: db}
+}
+
+func (r *DefaultLanguageMappingRepository) GetAll() ([]*models.DefaultLanguageMapping, error) {
+	var mappings []*models.DefaultLanguageMapping
+	if err := r.db.Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+func (r *DefaultLanguageMappingRepository) Insert(mapping *models.DefaultLanguageMapping) (*models.DefaultLanguageMapping, error) {
+	if !mapping.IsValid() {
+		return nil, errors.New("invalid mapping")
+	}
+	result := r.db.Create(mapping)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+func (r *DefaultLanguageMappingRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.DefaultLanguageMapping{}).Error
+}
@@ -16,3 +16,14 @@ func NewDiagnosticsRepository(db *gorm.DB) *DiagnosticsRepository {
 func (r *DiagnosticsRepository) Insert(diagnostics *models.Diagnostics) (*models.Diagnostics, error) {
 	return diagnostics, r.db.Create(diagnostics).Error
 }
+
+func (r *DiagnosticsRepository) GetByUser(userId string) ([]*models.Diagnostics, error) {
+	var diagnostics []*models.Diagnostics
+	if err := r.db.
+		Where("submitted_by = ?", userId).
+		Order("id asc").
+		Find(&diagnostics).Error; err != nil {
+		return nil, err
+	}
+	return diagnostics, nil
+}
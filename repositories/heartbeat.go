@@ -100,6 +100,32 @@ func (r *HeartbeatRepository) GetAllWithin(from, to time.Time, user *models.User
 	return heartbeats, nil
 }
 
+// StreamAllByUser passes all of a user's heartbeats to the given callback, batch by batch, without ever loading all of them into memory at once
+func (r *HeartbeatRepository) StreamAllByUser(user *models.User, batchSize int, callback func([]*models.Heartbeat) error) error {
+	var heartbeats []*models.Heartbeat
+	return r.db.
+		Where(&models.Heartbeat{UserID: user.ID}).
+		FindInBatches(&heartbeats, batchSize, func(tx *gorm.DB, batch int) error {
+			return callback(heartbeats)
+		}).Error
+}
+
+// GetPageByUser returns up to limit of the user's heartbeats within the given interval, ordered by id and starting after the given one (keyset pagination)
+func (r *HeartbeatRepository) GetPageByUser(user *models.User, afterId uint64, from, to time.Time, limit int) ([]*models.Heartbeat, error) {
+	var heartbeats []*models.Heartbeat
+	if err := r.db.
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("id > ?", afterId).
+		Where("time >= ?", from.Local()).
+		Where("time < ?", to.Local()).
+		Order("id asc").
+		Limit(limit).
+		Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
 func (r *HeartbeatRepository) GetAllWithinByFilters(from, to time.Time, user *models.User, filterMap map[string][]string) ([]*models.Heartbeat, error) {
 	// https://stackoverflow.com/a/20765152/3112139
 	var heartbeats []*models.Heartbeat
@@ -151,6 +177,31 @@ func (r *HeartbeatRepository) GetLastByUsers() ([]*models.TimeByUser, error) {
 	return result, nil
 }
 
+// GetLatestTimesByUsers returns the time of each of the given users' latest heartbeat, omitting users without any
+func (r *HeartbeatRepository) GetLatestTimesByUsers(users []*models.User) ([]*models.TimeByUser, error) {
+	var result []*models.TimeByUser
+
+	userIds := make([]string, len(users))
+	for i, u := range users {
+		userIds[i] = u.ID
+	}
+
+	if len(userIds) == 0 {
+		return result, nil
+	}
+
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Select(utils.QuoteSql(r.db, "user_id as %s, max(time) as %s", "user", "time")).
+		Where("user_id in ?", userIds).
+		Group("user_id").
+		Scan(&result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (r *HeartbeatRepository) Count(approximate bool) (count int64, err error) {
 	if r.config.Db.IsMySQL() && approximate {
 		err = r.db.Table("information_schema.tables").
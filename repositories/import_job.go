@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type ImportJobRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewImportJobRepository(db *gorm.DB) *ImportJobRepository {
+	return &ImportJobRepository{config: config.Get(), db: db}
+}
+
+func (r *ImportJobRepository) GetById(id uint) (*models.ImportJob, error) {
+	job := &models.ImportJob{}
+	if err := r.db.Where(&models.ImportJob{ID: id}).First(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetLatestByUsers returns each of the given users' most recent job, omitting users who never imported anything
+func (r *ImportJobRepository) GetLatestByUsers(users []*models.User) ([]*models.ImportJob, error) {
+	var jobs []*models.ImportJob
+
+	userIds := make([]string, len(users))
+	for i, u := range users {
+		userIds[i] = u.ID
+	}
+
+	if len(userIds) == 0 {
+		return jobs, nil
+	}
+
+	latestIds := r.db.
+		Model(&models.ImportJob{}).
+		Select("max(id)").
+		Where("user_id in ?", userIds).
+		Group("user_id")
+
+	if err := r.db.
+		Where("id in (?)", latestIds).
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// GetByUser returns the given user's most recent jobs, newest first
+func (r *ImportJobRepository) GetByUser(userId string, limit int) ([]*models.ImportJob, error) {
+	var jobs []*models.ImportJob
+	if userId == "" {
+		return jobs, nil
+	}
+	if err := r.db.
+		Where(&models.ImportJob{UserID: userId}).
+		Order("created_at desc, id desc").
+		Limit(limit).
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// GetByState returns all jobs in the given state, with users preloaded
+func (r *ImportJobRepository) GetByState(state string) ([]*models.ImportJob, error) {
+	var jobs []*models.ImportJob
+	if err := r.db.
+		Preload("User").
+		Where(&models.ImportJob{State: state}).
+		Order("created_at asc").
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *ImportJobRepository) Insert(job *models.ImportJob) (*models.ImportJob, error) {
+	if err := r.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *ImportJobRepository) Update(job *models.ImportJob) (*models.ImportJob, error) {
+	updateMap := map[string]interface{}{
+		"state":         job.State,
+		"processed":     job.Processed,
+		"total":         job.Total,
+		"imported":      job.Imported,
+		"duplicates":    job.Duplicates,
+		"errors":        job.Errors,
+		"params":        job.Params,
+		"checkpoint_at": job.CheckpointAt,
+		"finished_at":   job.FinishedAt,
+	}
+	if err := r.db.Model(job).Where(&models.ImportJob{ID: job.ID}).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
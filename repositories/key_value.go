@@ -36,6 +36,19 @@ func (r *KeyValueRepository) GetString(key string) (*models.KeyStringValue, erro
 	return kv, nil
 }
 
+func (r *KeyValueRepository) GetStrings(keys []string) ([]*models.KeyStringValue, error) {
+	var keyValues []*models.KeyStringValue
+	if len(keys) == 0 {
+		return keyValues, nil
+	}
+	if err := r.db.
+		Where(utils.QuoteSql(r.db, "%s in ?", "key"), keys).
+		Find(&keyValues).Error; err != nil {
+		return nil, err
+	}
+	return keyValues, nil
+}
+
 func (r *KeyValueRepository) Search(like string) ([]*models.KeyStringValue, error) {
 	var keyValues []*models.KeyStringValue
 	if err := r.db.Table("key_string_values").
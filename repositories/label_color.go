@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LabelColorRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewLabelColorRepository(db *gorm.DB) *LabelColorRepository {
+	return &LabelColorRepository{config: config.Get(), db: db}
+}
+
+func (r *LabelColorRepository) GetByUser(userId string) ([]*models.LabelColor, error) {
+	var colors []*models.LabelColor
+	if userId == "" {
+		return colors, nil
+	}
+	if err := r.db.
+		Where(&models.LabelColor{UserID: userId}).
+		Find(&colors).Error; err != nil {
+		return colors, err
+	}
+	return colors, nil
+}
+
+func (r *LabelColorRepository) Upsert(color *models.LabelColor) (*models.LabelColor, error) {
+	if !color.IsValid() {
+		return nil, errors.New("invalid label color")
+	}
+	result := r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "label"}},
+			DoUpdates: clause.AssignmentColumns([]string{"color"}),
+		}).
+		Create(color)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return color, nil
+}
+
+func (r *LabelColorRepository) DeleteByUserAndLabel(userId, label string) error {
+	return r.db.
+		Where(&models.LabelColor{UserID: userId, Label: label}).
+		Delete(models.LabelColor{}).Error
+}
@@ -29,13 +29,26 @@ func (r *LeaderboardRepository) CountAllByUser(userId string) (int64, error) {
 	err := r.db.
 		Table("leaderboard_items").
 		Where("user_id = ?", userId).
+		Where("leaderboard_id is null").
 		Count(&count).Error
 	return count, err
 }
 
+// GetAllByUser returns all of a user's leaderboard items, including those of private leaderboards
+func (r *LeaderboardRepository) GetAllByUser(userId string) ([]*models.LeaderboardItem, error) {
+	var items []*models.LeaderboardItem
+	if err := r.db.
+		Where("user_id = ?", userId).
+		Order("id asc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 func (r *LeaderboardRepository) CountUsers(excludeZero bool) (int64, error) {
 	var count int64
-	q := r.db.Table("leaderboard_items").Distinct("user_id")
+	q := r.db.Table("leaderboard_items").Distinct("user_id").Where("leaderboard_id is null")
 	if excludeZero {
 		q = q.Where("total > 0")
 	}
@@ -43,7 +56,7 @@ func (r *LeaderboardRepository) CountUsers(excludeZero bool) (int64, error) {
 	return count, err
 }
 
-func (r *LeaderboardRepository) GetAllAggregatedByInterval(key *models.IntervalKey, by *uint8, limit, skip int) ([]*models.LeaderboardItemRanked, error) {
+func (r *LeaderboardRepository) GetAllAggregatedByInterval(key *models.IntervalKey, by *uint8, leaderboardId *uint, limit, skip int) ([]*models.LeaderboardItemRanked, error) {
 	// TODO: distinct by (user, key) to filter out potential duplicates ?
 
 	var items []*models.LeaderboardItemRanked
@@ -52,6 +65,7 @@ func (r *LeaderboardRepository) GetAllAggregatedByInterval(key *models.IntervalK
 		Select("*, rank() over (partition by \"key\" order by total desc) as \"rank\"").
 		Where("\"interval\" in ?", *key)
 	subq = utils.WhereNullable(subq, "\"by\"", by)
+	subq = utils.WhereNullable(subq, "leaderboard_id", leaderboardId)
 
 	q := r.db.Table("(?) as ranked", subq)
 	q = r.withPaging(q, limit, skip)
@@ -62,13 +76,14 @@ func (r *LeaderboardRepository) GetAllAggregatedByInterval(key *models.IntervalK
 	return items, nil
 }
 
-func (r *LeaderboardRepository) GetAggregatedByUserAndInterval(userId string, key *models.IntervalKey, by *uint8, limit, skip int) ([]*models.LeaderboardItemRanked, error) {
+func (r *LeaderboardRepository) GetAggregatedByUserAndInterval(userId string, key *models.IntervalKey, by *uint8, leaderboardId *uint, limit, skip int) ([]*models.LeaderboardItemRanked, error) {
 	var items []*models.LeaderboardItemRanked
 	subq := r.db.
 		Table("leaderboard_items").
 		Select("*, rank() over (partition by \"key\" order by total desc) as \"rank\"").
 		Where("\"interval\" in ?", *key)
 	subq = utils.WhereNullable(subq, "\"by\"", by)
+	subq = utils.WhereNullable(subq, "leaderboard_id", leaderboardId)
 
 	q := r.db.Table("(?) as ranked", subq).Where("user_id = ?", userId)
 	q = r.withPaging(q, limit, skip)
@@ -82,16 +97,27 @@ func (r *LeaderboardRepository) GetAggregatedByUserAndInterval(userId string, ke
 func (r *LeaderboardRepository) DeleteByUser(userId string) error {
 	if err := r.db.
 		Where("user_id = ?", userId).
+		Where("leaderboard_id is null").
 		Delete(models.LeaderboardItem{}).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *LeaderboardRepository) DeleteByUserAndInterval(userId string, key *models.IntervalKey) error {
-	if err := r.db.
+func (r *LeaderboardRepository) DeleteByUserAndInterval(userId string, key *models.IntervalKey, leaderboardId *uint) error {
+	q := r.db.
 		Where("user_id = ?", userId).
-		Where("\"interval\" in ?", *key).
+		Where("\"interval\" in ?", *key)
+	q = utils.WhereNullable(q, "leaderboard_id", leaderboardId)
+	if err := q.Delete(models.LeaderboardItem{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *LeaderboardRepository) DeleteByLeaderboard(leaderboardId uint) error {
+	if err := r.db.
+		Where("leaderboard_id = ?", leaderboardId).
 		Delete(models.LeaderboardItem{}).Error; err != nil {
 		return err
 	}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type NotificationChannelRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewNotificationChannelRepository(db *gorm.DB) *NotificationChannelRepository {
+	return &NotificationChannelRepository{config: config.Get(), db: db}
+}
+
+func (r *NotificationChannelRepository) GetById(id uint) (*models.NotificationChannel, error) {
+	channel := &models.NotificationChannel{}
+	if err := r.db.Where(&models.NotificationChannel{ID: id}).First(channel).Error; err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (r *NotificationChannelRepository) GetByUser(userId string) ([]*models.NotificationChannel, error) {
+	var channels []*models.NotificationChannel
+	if userId == "" {
+		return channels, nil
+	}
+	if err := r.db.
+		Where(&models.NotificationChannel{UserID: userId}).
+		Order("id asc").
+		Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func (r *NotificationChannelRepository) Insert(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	if err := r.db.Create(channel).Error; err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (r *NotificationChannelRepository) Update(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	updateMap := map[string]interface{}{
+		"name":    channel.Name,
+		"type":    channel.Type,
+		"url":     channel.Url,
+		"room_id": channel.RoomId,
+		"token":   channel.Token,
+		"kinds":   channel.Kinds,
+		"enabled": channel.Enabled,
+	}
+	if err := r.db.Model(channel).Where(&models.NotificationChannel{ID: channel.ID}).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// RecordResult stores the outcome of the latest attempt to post to the channel, an empty reason meaning success
+func (r *NotificationChannelRepository) RecordResult(id uint, reason string) error {
+	updateMap := map[string]interface{}{"last_error": reason}
+	if reason == "" {
+		updateMap["last_sent_at"] = models.CustomTime(time.Now())
+	}
+	return r.db.Model(&models.NotificationChannel{}).Where("id = ?", id).Updates(updateMap).Error
+}
+
+func (r *NotificationChannelRepository) Delete(id uint) error {
+	return r.db.Where("id = ?", id).Delete(models.NotificationChannel{}).Error
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type PrivateLeaderboardRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewPrivateLeaderboardRepository(db *gorm.DB) *PrivateLeaderboardRepository {
+	return &PrivateLeaderboardRepository{config: config.Get(), db: db}
+}
+
+func (r *PrivateLeaderboardRepository) GetAll() ([]*models.PrivateLeaderboard, error) {
+	var leaderboards []*models.PrivateLeaderboard
+	if err := r.db.Find(&leaderboards).Error; err != nil {
+		return nil, err
+	}
+	return leaderboards, nil
+}
+
+func (r *PrivateLeaderboardRepository) GetById(id uint) (*models.PrivateLeaderboard, error) {
+	leaderboard := &models.PrivateLeaderboard{}
+	if err := r.db.Where(&models.PrivateLeaderboard{ID: id}).First(leaderboard).Error; err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
+
+func (r *PrivateLeaderboardRepository) GetByInviteCode(code string) (*models.PrivateLeaderboard, error) {
+	leaderboard := &models.PrivateLeaderboard{}
+	if err := r.db.Where(&models.PrivateLeaderboard{InviteCode: code}).First(leaderboard).Error; err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
+
+// GetByMember returns all private leaderboards the given user is a member of
+func (r *PrivateLeaderboardRepository) GetByMember(userId string) ([]*models.PrivateLeaderboard, error) {
+	var leaderboards []*models.PrivateLeaderboard
+	if userId == "" {
+		return leaderboards, nil
+	}
+	if err := r.db.
+		Joins("inner join private_leaderboard_members on private_leaderboard_members.leaderboard_id = private_leaderboards.id").
+		Where("private_leaderboard_members.user_id = ?", userId).
+		Order("private_leaderboards.name asc").
+		Find(&leaderboards).Error; err != nil {
+		return nil, err
+	}
+	return leaderboards, nil
+}
+
+// GetMembers returns all members of the given leaderboard with users preloaded
+func (r *PrivateLeaderboardRepository) GetMembers(leaderboardId uint) ([]*models.PrivateLeaderboardMember, error) {
+	var members []*models.PrivateLeaderboardMember
+	if err := r.db.
+		Preload("User").
+		Where(&models.PrivateLeaderboardMember{LeaderboardID: leaderboardId}).
+		Order("user_id asc").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *PrivateLeaderboardRepository) GetMember(leaderboardId uint, userId string) (*models.PrivateLeaderboardMember, error) {
+	member := &models.PrivateLeaderboardMember{}
+	if err := r.db.
+		Where(&models.PrivateLeaderboardMember{LeaderboardID: leaderboardId, UserID: userId}).
+		First(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *PrivateLeaderboardRepository) Insert(leaderboard *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error) {
+	if err := r.db.Create(leaderboard).Error; err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
+
+func (r *PrivateLeaderboardRepository) Update(leaderboard *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error) {
+	updateMap := map[string]interface{}{
+		"name":        leaderboard.Name,
+		"invite_code": leaderboard.InviteCode,
+		"interval":    leaderboard.Interval,
+		"language":    leaderboard.Language,
+	}
+	if err := r.db.Model(leaderboard).Where(&models.PrivateLeaderboard{ID: leaderboard.ID}).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
+
+func (r *PrivateLeaderboardRepository) InsertMember(member *models.PrivateLeaderboardMember) (*models.PrivateLeaderboardMember, error) {
+	if err := r.db.Create(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *PrivateLeaderboardRepository) DeleteMember(leaderboardId uint, userId string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("leaderboard_id = ? and user_id = ?", leaderboardId, userId).Delete(models.LeaderboardItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("leaderboard_id = ? and user_id = ?", leaderboardId, userId).Delete(models.PrivateLeaderboardMember{}).Error
+	})
+}
+
+func (r *PrivateLeaderboardRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("leaderboard_id = ?", id).Delete(models.LeaderboardItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("leaderboard_id = ?", id).Delete(models.PrivateLeaderboardMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(models.PrivateLeaderboard{}).Error
+	})
+}
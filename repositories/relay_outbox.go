@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type RelayOutboxRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewRelayOutboxRepository(db *gorm.DB) *RelayOutboxRepository {
+	return &RelayOutboxRepository{config: config.Get(), db: db}
+}
+
+// InsertBatch enqueues the given items, silently skipping the ones, which are already in the outbox for the same user and target
+func (r *RelayOutboxRepository) InsertBatch(items []*models.RelayOutboxItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.
+		Clauses(clause.OnConflict{
+			DoNothing: true,
+		}).
+		Create(&items).Error
+}
+
+// GetDue returns undelivered items, whose next delivery attempt is due, in the order they were enqueued
+func (r *RelayOutboxRepository) GetDue(now time.Time, limit int) ([]*models.RelayOutboxItem, error) {
+	var items []*models.RelayOutboxItem
+	if err := r.db.
+		Where("delivered_at is null").
+		Where("attempts < ?", models.RelayOutboxMaxAttempts).
+		Where("next_attempt_at <= ?", now).
+		Order("id asc").
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CountUndeliveredByUsers counts the given users' outbox items, which are still waiting for delivery or finally failed
+func (r *RelayOutboxRepository) CountUndeliveredByUsers(users []*models.User) ([]*models.CountByUser, error) {
+	var counts []*models.CountByUser
+
+	userIds := make([]string, len(users))
+	for i, u := range users {
+		userIds[i] = u.ID
+	}
+
+	if len(userIds) == 0 {
+		return counts, nil
+	}
+
+	if err := r.db.
+		Model(&models.RelayOutboxItem{}).
+		Select(utils.QuoteSql(r.db, "user_id as %s, count(id) as %s", "user", "count")).
+		Where("user_id in ? and delivered_at is null", userIds).
+		Group("user_id").
+		Find(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (r *RelayOutboxRepository) GetBacklog(userId string) (*models.RelayBacklog, error) {
+	backlog := &models.RelayBacklog{}
+
+	if err := r.db.
+		Model(&models.RelayOutboxItem{}).
+		Where("user_id = ? and delivered_at is null and attempts < ?", userId, models.RelayOutboxMaxAttempts).
+		Count(&backlog.Pending).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.
+		Model(&models.RelayOutboxItem{}).
+		Where("user_id = ? and delivered_at is null and attempts >= ?", userId, models.RelayOutboxMaxAttempts).
+		Count(&backlog.Failed).Error; err != nil {
+		return nil, err
+	}
+
+	if backlog.Pending > 0 {
+		var oldest models.RelayOutboxItem
+		if err := r.db.
+			Where("user_id = ? and delivered_at is null and attempts < ?", userId, models.RelayOutboxMaxAttempts).
+			Order("id asc").
+			First(&oldest).Error; err != nil {
+			return nil, err
+		}
+		oldestAt := oldest.CreatedAt.T()
+		backlog.OldestPendingAt = &oldestAt
+	}
+
+	return backlog, nil
+}
+
+// MarkDelivered flags the given items as delivered, unless they already were, so it is safe to be called repeatedly
+func (r *RelayOutboxRepository) MarkDelivered(ids []uint64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.
+		Model(&models.RelayOutboxItem{}).
+		Where("id in ? and delivered_at is null", ids).
+		Update("delivered_at", models.CustomTime(at)).Error
+}
+
+// UpdateAttempts persists the retry state of the given (failed) items
+func (r *RelayOutboxRepository) UpdateAttempts(items []*models.RelayOutboxItem) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			if err := tx.Model(item).Where("id = ? and delivered_at is null", item.ID).Updates(map[string]interface{}{
+				"attempts":        item.Attempts,
+				"next_attempt_at": item.NextAttemptAt,
+				"last_error":      item.LastError,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Postpone defers the next delivery attempt of the given items without counting it as a failure
+func (r *RelayOutboxRepository) Postpone(ids []uint64, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.
+		Model(&models.RelayOutboxItem{}).
+		Where("id in ?", ids).
+		Update("next_attempt_at", models.CustomTime(until)).Error
+}
+
+func (r *RelayOutboxRepository) DeleteByIds(ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Where("id in ?", ids).Delete(models.RelayOutboxItem{}).Error
+}
+
+// DeleteDeliveredBefore removes delivered items as well as the ones given up on, which were enqueued before the given time
+func (r *RelayOutboxRepository) DeleteDeliveredBefore(t time.Time) (int64, error) {
+	result := r.db.
+		Where("created_at < ? and (delivered_at is not null or attempts >= ?)", t, models.RelayOutboxMaxAttempts).
+		Delete(models.RelayOutboxItem{})
+	return result.RowsAffected, result.Error
+}
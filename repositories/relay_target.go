@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type RelayTargetRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewRelayTargetRepository(db *gorm.DB) *RelayTargetRepository {
+	return &RelayTargetRepository{config: config.Get(), db: db}
+}
+
+func (r *RelayTargetRepository) GetById(id uint) (*models.RelayTarget, error) {
+	target := &models.RelayTarget{}
+	if err := r.db.Where(&models.RelayTarget{ID: id}).First(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *RelayTargetRepository) GetByUser(userId string) ([]*models.RelayTarget, error) {
+	var targets []*models.RelayTarget
+	if userId == "" {
+		return targets, nil
+	}
+	if err := r.db.
+		Where(&models.RelayTarget{UserID: userId}).
+		Order("id asc").
+		Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *RelayTargetRepository) GetByUsers(users []*models.User) ([]*models.RelayTarget, error) {
+	var targets []*models.RelayTarget
+
+	userIds := make([]string, len(users))
+	for i, u := range users {
+		userIds[i] = u.ID
+	}
+
+	if len(userIds) == 0 {
+		return targets, nil
+	}
+
+	if err := r.db.
+		Where("user_id in ?", userIds).
+		Order("id asc").
+		Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *RelayTargetRepository) Insert(target *models.RelayTarget) (*models.RelayTarget, error) {
+	if err := r.db.Create(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *RelayTargetRepository) Update(target *models.RelayTarget) (*models.RelayTarget, error) {
+	updateMap := map[string]interface{}{
+		"name":        target.Name,
+		"api_url":     target.ApiUrl,
+		"api_key":     target.ApiKey,
+		"enabled":     target.Enabled,
+		"filter_mode": target.FilterMode,
+		"projects":    target.Projects,
+		"labels":      target.Labels,
+	}
+	if err := r.db.Model(target).Where(&models.RelayTarget{ID: target.ID}).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// RecordSuccess increments the target's success counter in-place (without reading it first), because relays for the same target may run concurrently
+func (r *RelayTargetRepository) RecordSuccess(id uint) error {
+	return r.db.Model(&models.RelayTarget{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"success_count":        gorm.Expr("success_count + 1"),
+		"consecutive_failures": 0,
+		"last_success_at":      models.CustomTime(time.Now()),
+	}).Error
+}
+
+// RecordFailure increments the target's failure counters in-place and returns the resulting number of consecutive failures
+func (r *RelayTargetRepository) RecordFailure(id uint, reason string) (int, error) {
+	var consecutiveFailures int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RelayTarget{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"failure_count":        gorm.Expr("failure_count + 1"),
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			"last_error":           reason,
+			"last_failure_at":      models.CustomTime(time.Now()),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RelayTarget{}).Where("id = ?", id).Select("consecutive_failures").Scan(&consecutiveFailures).Error
+	})
+	return consecutiveFailures, err
+}
+
+func (r *RelayTargetRepository) Delete(id uint) error {
+	return r.db.Where("id = ?", id).Delete(models.RelayTarget{}).Error
+}
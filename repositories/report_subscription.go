@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ReportSubscriptionRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewReportSubscriptionRepository(db *gorm.DB) *ReportSubscriptionRepository {
+	return &ReportSubscriptionRepository{config: config.Get(), db: db}
+}
+
+func (r *ReportSubscriptionRepository) GetById(id uint) (*models.ReportSubscription, error) {
+	subscription := &models.ReportSubscription{}
+	if err := r.db.Preload("User").Where(&models.ReportSubscription{ID: id}).First(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *ReportSubscriptionRepository) GetByUser(userId string) ([]*models.ReportSubscription, error) {
+	var subscriptions []*models.ReportSubscription
+	if userId == "" {
+		return subscriptions, nil
+	}
+	if err := r.db.
+		Where(&models.ReportSubscription{UserID: userId}).
+		Order("id asc").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetDue returns enabled subscriptions, whose next report is due, including their users
+func (r *ReportSubscriptionRepository) GetDue(now time.Time) ([]*models.ReportSubscription, error) {
+	var subscriptions []*models.ReportSubscription
+	if err := r.db.
+		Preload("User").
+		Where("enabled = ?", true).
+		Where("next_run_at <= ?", now).
+		Order("next_run_at asc").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *ReportSubscriptionRepository) Insert(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	if err := r.db.Omit("User").Create(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *ReportSubscriptionRepository) Update(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	updateMap := map[string]interface{}{
+		"period":       subscription.Period,
+		"weekday":      subscription.Weekday,
+		"hour":         subscription.Hour,
+		"sections":     subscription.Sections,
+		"projects":     subscription.Projects,
+		"enabled":      subscription.Enabled,
+		"next_run_at":  subscription.NextRunAt,
+		"last_sent_at": subscription.LastSentAt,
+	}
+	if err := r.db.Model(subscription).Where(&models.ReportSubscription{ID: subscription.ID}).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *ReportSubscriptionRepository) Delete(id uint) error {
+	return r.db.Where("id = ?", id).Delete(models.ReportSubscription{}).Error
+}
@@ -21,9 +21,12 @@ type IHeartbeatRepository interface {
 	GetAll() ([]*models.Heartbeat, error)
 	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Heartbeat, error)
 	GetAllWithinByFilters(time.Time, time.Time, *models.User, map[string][]string) ([]*models.Heartbeat, error)
+	StreamAllByUser(*models.User, int, func([]*models.Heartbeat) error) error
+	GetPageByUser(*models.User, uint64, time.Time, time.Time, int) ([]*models.Heartbeat, error)
 	GetLatestByFilters(*models.User, map[string][]string) (*models.Heartbeat, error)
 	GetFirstByUsers() ([]*models.TimeByUser, error)
 	GetLastByUsers() ([]*models.TimeByUser, error)
+	GetLatestTimesByUsers([]*models.User) ([]*models.TimeByUser, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
 	Count(bool) (int64, error)
@@ -38,11 +41,13 @@ type IHeartbeatRepository interface {
 
 type IDiagnosticsRepository interface {
 	Insert(diagnostics *models.Diagnostics) (*models.Diagnostics, error)
+	GetByUser(string) ([]*models.Diagnostics, error)
 }
 
 type IKeyValueRepository interface {
 	GetAll() ([]*models.KeyStringValue, error)
 	GetString(string) (*models.KeyStringValue, error)
+	GetStrings([]string) ([]*models.KeyStringValue, error)
 	PutString(*models.KeyStringValue) error
 	DeleteString(string) error
 	Search(string) ([]*models.KeyStringValue, error)
@@ -56,6 +61,103 @@ type ILanguageMappingRepository interface {
 	Delete(uint) error
 }
 
+type IDefaultLanguageMappingRepository interface {
+	GetAll() ([]*models.DefaultLanguageMapping, error)
+	Insert(*models.DefaultLanguageMapping) (*models.DefaultLanguageMapping, error)
+	Delete(uint) error
+}
+
+type ISessionRepository interface {
+	GetById(string) (*models.Session, error)
+	GetByUser(string) ([]*models.Session, error)
+	Insert(*models.Session) (*models.Session, error)
+	Touch(*models.Session, time.Time) error
+	Delete(string) error
+	DeleteByUser(string, ...string) error
+	DeleteBefore(time.Time) error
+}
+
+type ITeamRepository interface {
+	GetById(uint) (*models.Team, error)
+	GetMemberships(string) ([]*models.TeamMember, error)
+	GetMembers(uint) ([]*models.TeamMember, error)
+	GetMember(uint, string) (*models.TeamMember, error)
+	Insert(*models.Team) (*models.Team, error)
+	Update(*models.Team) (*models.Team, error)
+	InsertMember(*models.TeamMember) (*models.TeamMember, error)
+	UpdateMember(*models.TeamMember) (*models.TeamMember, error)
+	DeleteMember(uint) error
+	Delete(uint) error
+}
+
+type IImportJobRepository interface {
+	GetById(uint) (*models.ImportJob, error)
+	GetByUser(string, int) ([]*models.ImportJob, error)
+	GetLatestByUsers([]*models.User) ([]*models.ImportJob, error)
+	GetByState(string) ([]*models.ImportJob, error)
+	Insert(*models.ImportJob) (*models.ImportJob, error)
+	Update(*models.ImportJob) (*models.ImportJob, error)
+}
+
+type IRelayTargetRepository interface {
+	GetById(uint) (*models.RelayTarget, error)
+	GetByUser(string) ([]*models.RelayTarget, error)
+	GetByUsers([]*models.User) ([]*models.RelayTarget, error)
+	Insert(*models.RelayTarget) (*models.RelayTarget, error)
+	Update(*models.RelayTarget) (*models.RelayTarget, error)
+	RecordSuccess(uint) error
+	RecordFailure(uint, string) (int, error)
+	Delete(uint) error
+}
+
+type IRelayOutboxRepository interface {
+	InsertBatch([]*models.RelayOutboxItem) error
+	GetDue(time.Time, int) ([]*models.RelayOutboxItem, error)
+	GetBacklog(string) (*models.RelayBacklog, error)
+	CountUndeliveredByUsers([]*models.User) ([]*models.CountByUser, error)
+	MarkDelivered([]uint64, time.Time) error
+	UpdateAttempts([]*models.RelayOutboxItem) error
+	Postpone([]uint64, time.Time) error
+	DeleteByIds([]uint64) error
+	DeleteDeliveredBefore(time.Time) (int64, error)
+}
+
+type IWebhookRepository interface {
+	GetById(uint) (*models.Webhook, error)
+	GetByUser(string) ([]*models.Webhook, error)
+	GetEnabled() ([]*models.Webhook, error)
+	Insert(*models.Webhook) (*models.Webhook, error)
+	Update(*models.Webhook) (*models.Webhook, error)
+	Delete(uint) error
+}
+
+type IWebhookDeliveryRepository interface {
+	InsertBatch([]*models.WebhookDelivery) error
+	GetDue(time.Time, int) ([]*models.WebhookDelivery, error)
+	GetByWebhook(uint, int) ([]*models.WebhookDelivery, error)
+	Update(*models.WebhookDelivery) error
+	Postpone([]uint64, time.Time) error
+	DeleteFinishedBefore(time.Time) (int64, error)
+}
+
+type INotificationChannelRepository interface {
+	GetById(uint) (*models.NotificationChannel, error)
+	GetByUser(string) ([]*models.NotificationChannel, error)
+	Insert(*models.NotificationChannel) (*models.NotificationChannel, error)
+	Update(*models.NotificationChannel) (*models.NotificationChannel, error)
+	RecordResult(uint, string) error
+	Delete(uint) error
+}
+
+type IReportSubscriptionRepository interface {
+	GetById(uint) (*models.ReportSubscription, error)
+	GetByUser(string) ([]*models.ReportSubscription, error)
+	GetDue(time.Time) ([]*models.ReportSubscription, error)
+	Insert(*models.ReportSubscription) (*models.ReportSubscription, error)
+	Update(*models.ReportSubscription) (*models.ReportSubscription, error)
+	Delete(uint) error
+}
+
 type IProjectLabelRepository interface {
 	GetAll() ([]*models.ProjectLabel, error)
 	GetById(uint) (*models.ProjectLabel, error)
@@ -64,6 +166,19 @@ type IProjectLabelRepository interface {
 	Delete(uint) error
 }
 
+type ILabelColorRepository interface {
+	GetByUser(string) ([]*models.LabelColor, error)
+	Upsert(*models.LabelColor) (*models.LabelColor, error)
+	DeleteByUserAndLabel(string, string) error
+}
+
+type IBillingRateRepository interface {
+	GetById(uint) (*models.BillingRate, error)
+	GetByUser(string) ([]*models.BillingRate, error)
+	Insert(*models.BillingRate) (*models.BillingRate, error)
+	Delete(uint) error
+}
+
 type ISummaryRepository interface {
 	Insert(*models.Summary) error
 	GetAll() ([]*models.Summary, error)
@@ -83,19 +198,37 @@ type IUserRepository interface {
 	GetByLoggedInBefore(time.Time) ([]*models.User, error)
 	GetByLoggedInAfter(time.Time) ([]*models.User, error)
 	GetByLastActiveAfter(time.Time) ([]*models.User, error)
+	Search(string, int, int) ([]*models.User, error)
 	Count() (int64, error)
 	InsertOrGet(*models.User) (*models.User, bool, error)
 	Update(*models.User) (*models.User, error)
 	UpdateField(*models.User, string, interface{}) (*models.User, error)
+	SetAdmin(*models.User, bool) (*models.User, error)
 	Delete(*models.User) error
 }
 
+type IPrivateLeaderboardRepository interface {
+	GetAll() ([]*models.PrivateLeaderboard, error)
+	GetById(uint) (*models.PrivateLeaderboard, error)
+	GetByInviteCode(string) (*models.PrivateLeaderboard, error)
+	GetByMember(string) ([]*models.PrivateLeaderboard, error)
+	GetMembers(uint) ([]*models.PrivateLeaderboardMember, error)
+	GetMember(uint, string) (*models.PrivateLeaderboardMember, error)
+	Insert(*models.PrivateLeaderboard) (*models.PrivateLeaderboard, error)
+	Update(*models.PrivateLeaderboard) (*models.PrivateLeaderboard, error)
+	InsertMember(*models.PrivateLeaderboardMember) (*models.PrivateLeaderboardMember, error)
+	DeleteMember(uint, string) error
+	Delete(uint) error
+}
+
 type ILeaderboardRepository interface {
 	InsertBatch([]*models.LeaderboardItem) error
 	CountAllByUser(string) (int64, error)
+	GetAllByUser(string) ([]*models.LeaderboardItem, error)
 	CountUsers(bool) (int64, error)
 	DeleteByUser(string) error
-	DeleteByUserAndInterval(string, *models.IntervalKey) error
-	GetAllAggregatedByInterval(*models.IntervalKey, *uint8, int, int) ([]*models.LeaderboardItemRanked, error)
-	GetAggregatedByUserAndInterval(string, *models.IntervalKey, *uint8, int, int) ([]*models.LeaderboardItemRanked, error)
+	DeleteByUserAndInterval(string, *models.IntervalKey, *uint) error
+	DeleteByLeaderboard(uint) error
+	GetAllAggregatedByInterval(*models.IntervalKey, *uint8, *uint, int, int) ([]*models.LeaderboardItemRanked, error)
+	GetAggregatedByUserAndInterval(string, *models.IntervalKey, *uint8, *uint, int, int) ([]*models.LeaderboardItemRanked, error)
 }
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SessionRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{config: config.Get(), db: db}
+}
+
+func (r *SessionRepository) GetById(id string) (*models.Session, error) {
+	session := &models.Session{}
+	if err := r.db.Where(&models.Session{ID: id}).First(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) GetByUser(userId string) ([]*models.Session, error) {
+	var sessions []*models.Session
+	if userId == "" {
+		return sessions, nil
+	}
+	if err := r.db.
+		Where(&models.Session{UserID: userId}).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepository) Insert(session *models.Session) (*models.Session, error) {
+	if err := r.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) Touch(session *models.Session, at time.Time) error {
+	return r.db.Model(session).
+		Where(&models.Session{ID: session.ID}).
+		UpdateColumn("last_seen_at", models.CustomTime(at)).Error
+}
+
+func (r *SessionRepository) Delete(id string) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.Session{}).Error
+}
+
+// DeleteByUser deletes all sessions of the given user, except for those explicitly excluded
+func (r *SessionRepository) DeleteByUser(userId string, exceptIds ...string) error {
+	q := r.db.Where("user_id = ?", userId)
+	if len(exceptIds) > 0 {
+		q = q.Where("id NOT IN ?", exceptIds)
+	}
+	return q.Delete(models.Session{}).Error
+}
+
+func (r *SessionRepository) DeleteBefore(t time.Time) error {
+	return r.db.
+		Where("created_at < ?", t.Local()).
+		Delete(models.Session{}).Error
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type TeamRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewTeamRepository(db *gorm.DB) *TeamRepository {
+	return &TeamRepository{config: config.Get(), db: db}
+}
+
+func (r *TeamRepository) GetById(id uint) (*models.Team, error) {
+	team := &models.Team{}
+	if err := r.db.Where(&models.Team{ID: id}).First(team).Error; err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// GetMemberships returns all team memberships of the given user, including pending invitations, with teams preloaded
+func (r *TeamRepository) GetMemberships(userId string) ([]*models.TeamMember, error) {
+	var members []*models.TeamMember
+	if userId == "" {
+		return members, nil
+	}
+	if err := r.db.
+		Preload("Team").
+		Where(&models.TeamMember{UserID: userId}).
+		Order("created_at asc").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetMembers returns all members of the given team, including pending invitations, with users preloaded
+func (r *TeamRepository) GetMembers(teamId uint) ([]*models.TeamMember, error) {
+	var members []*models.TeamMember
+	if err := r.db.
+		Preload("User").
+		Where(&models.TeamMember{TeamID: teamId}).
+		Order("user_id asc").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *TeamRepository) GetMember(teamId uint, userId string) (*models.TeamMember, error) {
+	member := &models.TeamMember{}
+	if err := r.db.
+		Where(&models.TeamMember{TeamID: teamId, UserID: userId}).
+		First(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *TeamRepository) Insert(team *models.Team) (*models.Team, error) {
+	if err := r.db.Create(team).Error; err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (r *TeamRepository) Update(team *models.Team) (*models.Team, error) {
+	if err := r.db.Model(team).Where(&models.Team{ID: team.ID}).Update("name", team.Name).Error; err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (r *TeamRepository) InsertMember(member *models.TeamMember) (*models.TeamMember, error) {
+	if err := r.db.Create(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *TeamRepository) UpdateMember(member *models.TeamMember) (*models.TeamMember, error) {
+	updateMap := map[string]interface{}{
+		"role":     member.Role,
+		"accepted": member.Accepted,
+	}
+	if err := r.db.Model(member).Where(&models.TeamMember{ID: member.ID}).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *TeamRepository) DeleteMember(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.TeamMember{}).Error
+}
+
+func (r *TeamRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", id).Delete(models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(models.Team{}).Error
+	})
+}
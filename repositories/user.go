@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/duke-git/lancet/v2/condition"
+	"strings"
 	"time"
 
 	"github.com/muety/wakapi/models"
@@ -102,6 +103,20 @@ func (r *UserRepository) GetByLastActiveAfter(t time.Time) ([]*models.User, erro
 	return r.GetByIds(userIds)
 }
 
+// Search returns users whose id or e-mail address contains the given query, ordered by id
+func (r *UserRepository) Search(query string, limit, skip int) ([]*models.User, error) {
+	var users []*models.User
+	q := r.db.Model(&models.User{}).Order("id asc")
+	if query != "" {
+		pattern := "%" + utils.EscapeLike(strings.ToLower(query)) + "%"
+		q = q.Where("lower(id) like ? escape ? or lower(email) like ? escape ?", pattern, `\`, pattern, `\`)
+	}
+	if err := utils.WithPaging(q, limit, skip).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) Count() (int64, error) {
 	var count int64
 	if err := r.db.
@@ -150,6 +165,17 @@ func (r *UserRepository) Update(user *models.User) (*models.User, error) {
 		"stripe_customer_id":       user.StripeCustomerId,
 		"invited_by":               user.InvitedBy,
 		"exclude_unknown_projects": user.ExcludeUnknownProjects,
+		"is_disabled":              user.IsDisabled,
+		"oidc_subject":             user.OidcSubject,
+		"totp_secret":              user.TotpSecret,
+		"totp_enabled":             user.TotpEnabled,
+		"totp_recovery_codes":      user.TotpRecoveryCodes,
+		"totp_last_step":           user.TotpLastStep,
+		"session_gap_minutes":      user.SessionGapMinutes,
+		"calendar_token":           user.CalendarToken,
+		"ticket_pattern":           user.TicketPattern,
+		"ticket_from_entity":       user.TicketFromEntity,
+		"daily_goal_minutes":       user.DailyGoalMinutes,
 	}
 
 	result := r.db.Model(user).Updates(updateMap)
@@ -173,6 +199,16 @@ func (r *UserRepository) UpdateField(user *models.User, key string, value interf
 	return user, nil
 }
 
+// SetAdmin persists the user's admin flag, which is deliberately left out of Update, so that privileges can't be
+// changed by accident through any of the code paths updating a user's settings
+func (r *UserRepository) SetAdmin(user *models.User, isAdmin bool) (*models.User, error) {
+	if err := r.db.Model(user).Update("is_admin", isAdmin).Error; err != nil {
+		return nil, err
+	}
+	user.IsAdmin = isAdmin
+	return user, nil
+}
+
 func (r *UserRepository) Delete(user *models.User) error {
 	return r.db.Delete(user).Error
 }
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{config: config.Get(), db: db}
+}
+
+func (r *WebhookRepository) GetById(id uint) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	if err := r.db.Where(&models.Webhook{ID: id}).First(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetByUser(userId string) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	if userId == "" {
+		return webhooks, nil
+	}
+	if err := r.db.
+		Where(&models.Webhook{UserID: userId}).
+		Order("id asc").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetEnabled returns all enabled webhooks, except for global ones, whose owner isn't an admin (anymore)
+func (r *WebhookRepository) GetEnabled() ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	if err := r.db.
+		Where("enabled = ?", true).
+		Where("is_global = ? or user_id in (?)", false, r.db.Model(&models.User{}).Select("id").Where("is_admin = ?", true)).
+		Order("id asc").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Insert(webhook *models.Webhook) (*models.Webhook, error) {
+	if err := r.db.Create(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) Update(webhook *models.Webhook) (*models.Webhook, error) {
+	updateMap := map[string]interface{}{
+		"name":      webhook.Name,
+		"url":       webhook.Url,
+		"secret":    webhook.Secret,
+		"events":    webhook.Events,
+		"enabled":   webhook.Enabled,
+		"is_global": webhook.IsGlobal,
+	}
+	if err := r.db.Model(webhook).Where(&models.Webhook{ID: webhook.ID}).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) Delete(id uint) error {
+	return r.db.Where("id = ?", id).Delete(models.Webhook{}).Error
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type WebhookDeliveryRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{config: config.Get(), db: db}
+}
+
+func (r *WebhookDeliveryRepository) InsertBatch(deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+// GetDue returns deliveries, whose next attempt is due, in the order they were enqueued
+func (r *WebhookDeliveryRepository) GetDue(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	if err := r.db.
+		Where("delivered_at is null").
+		Where("attempts < ?", models.WebhookDeliveryMaxAttempts).
+		Where("next_attempt_at <= ?", now).
+		Order("id asc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// GetByWebhook returns the webhook's latest deliveries, most recent first
+func (r *WebhookDeliveryRepository) GetByWebhook(webhookId uint, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	if err := r.db.
+		Where(&models.WebhookDelivery{WebhookID: webhookId}).
+		Order("id desc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Update persists the outcome of a delivery attempt
+func (r *WebhookDeliveryRepository) Update(delivery *models.WebhookDelivery) error {
+	return r.db.Model(delivery).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"status_code":     delivery.StatusCode,
+		"last_error":      delivery.LastError,
+		"delivered_at":    delivery.DeliveredAt,
+	}).Error
+}
+
+// Postpone defers the next attempt of the given deliveries without counting it as a failure
+func (r *WebhookDeliveryRepository) Postpone(ids []uint64, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.
+		Model(&models.WebhookDelivery{}).
+		Where("id in ?", ids).
+		Update("next_attempt_at", models.CustomTime(until)).Error
+}
+
+// DeleteFinishedBefore removes delivered deliveries as well as the ones given up on, which were enqueued before the given time
+func (r *WebhookDeliveryRepository) DeleteFinishedBefore(t time.Time) (int64, error) {
+	result := r.db.
+		Where("created_at < ? and (delivered_at is not null or attempts >= ?)", t, models.WebhookDeliveryMaxAttempts).
+		Delete(models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+	"net/http"
+)
+
+type AdminHandler struct {
+	config    *conf.Config
+	userSrvc  services.IUserService
+	adminSrvc services.IAdminService
+}
+
+func NewAdminHandler(userService services.IUserService, adminService services.IAdminService) *AdminHandler {
+	return &AdminHandler{
+		config:    conf.Get(),
+		userSrvc:  userService,
+		adminSrvc: adminService,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+	r.Post("/", h.PostIndex)
+
+	router.Mount("/admin", r)
+}
+
+func (h *AdminHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	if !h.checkAdmin(w, r) {
+		return
+	}
+	templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w))
+}
+
+func (h *AdminHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	if !h.checkAdmin(w, r) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing form values"))
+		return
+	}
+
+	admin := middlewares.GetPrincipal(r)
+	user, err := h.userSrvc.GetUserById(r.PostForm.Get("username"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError("user not found"))
+		return
+	}
+
+	var message string
+	var passwordReset *models.AdminPasswordResetResult
+
+	switch r.PostForm.Get("action") {
+	case "disable":
+		_, err = h.adminSrvc.SetDisabled(admin, user, true)
+		message = fmt.Sprintf("user '%s' was disabled", user.ID)
+	case "enable":
+		_, err = h.adminSrvc.SetDisabled(admin, user, false)
+		message = fmt.Sprintf("user '%s' was enabled", user.ID)
+	case "grant_admin":
+		_, err = h.adminSrvc.SetAdmin(admin, user, true)
+		message = fmt.Sprintf("user '%s' is now an admin", user.ID)
+	case "revoke_admin":
+		_, err = h.adminSrvc.SetAdmin(admin, user, false)
+		message = fmt.Sprintf("user '%s' is no longer an admin", user.ID)
+	case "reset_password":
+		var link string
+		link, err = h.adminSrvc.TriggerPasswordReset(user)
+		passwordReset = &models.AdminPasswordResetResult{Link: link, MailSent: err == nil}
+		if errors.Is(err, services.ErrAdminNoEmail) {
+			err = nil
+		}
+		message = fmt.Sprintf("password reset was triggered for user '%s'", user.ID)
+	case "reset_apikey":
+		_, err = h.adminSrvc.ResetApiKey(user)
+		message = fmt.Sprintf("api key of user '%s' was reset", user.ID)
+	case "delete":
+		err = h.adminSrvc.DeleteUser(admin, user)
+		message = fmt.Sprintf("user '%s' was deleted", user.ID)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError("unknown action requests"))
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, services.ErrAdminSelfModification) {
+			w.WriteHeader(http.StatusBadRequest)
+			templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError(err.Error()))
+			return
+		}
+		conf.Log().Request(r).Error("failed to perform admin operation on user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError(conf.ErrInternalServerError))
+		return
+	}
+
+	vm := h.buildViewModel(r, w).WithSuccess(message)
+	if passwordReset != nil {
+		vm.PasswordReset = passwordReset
+		vm.ResetUserId = user.ID
+	}
+	templates[conf.AdminTemplate].Execute(w, vm)
+}
+
+func (h *AdminHandler) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if user := middlewares.GetPrincipal(r); user == nil || !user.IsAdmin {
+		routeutils.SetError(r, w, "you need to be an admin to access this page")
+		http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
+		return false
+	}
+	return true
+}
+
+func (h *AdminHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.AdminViewModel {
+	user := middlewares.GetPrincipal(r)
+	query := r.URL.Query().Get("q")
+	pageParams := utils.ParsePageParamsWithDefault(r, 1, 50)
+
+	users, err := h.adminSrvc.SearchUsers(query, pageParams)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching users - %v", err)
+		return &view.AdminViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+			PageParams: pageParams,
+		}
+	}
+
+	vm := &view.AdminViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Users:      users,
+		Query:      query,
+		PageParams: pageParams,
+	}
+	return routeutils.WithSessionMessages(vm, r, w)
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+	"net/http"
+)
+
+type AdminApiHandler struct {
+	config    *conf.Config
+	userSrvc  services.IUserService
+	adminSrvc services.IAdminService
+}
+
+func NewAdminApiHandler(userService services.IUserService, adminService services.IAdminService) *AdminApiHandler {
+	return &AdminApiHandler{
+		config:    conf.Get(),
+		userSrvc:  userService,
+		adminSrvc: adminService,
+	}
+}
+
+func (h *AdminApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	r.Use(h.requireAdmin)
+	r.Get("/users", h.GetUsers)
+	r.Get("/users/{user}", h.GetUser)
+	r.Delete("/users/{user}", h.DeleteUser)
+	r.Post("/users/{user}/disable", h.PostDisable)
+	r.Post("/users/{user}/enable", h.PostEnable)
+	r.Post("/users/{user}/admin", h.PostAdmin)
+	r.Delete("/users/{user}/admin", h.DeleteAdmin)
+	r.Post("/users/{user}/password_reset", h.PostPasswordReset)
+	r.Post("/users/{user}/api_key_reset", h.PostApiKeyReset)
+
+	router.Mount("/admin", r)
+}
+
+// @Summary List and search users
+// @ID get-admin-users
+// @Tags admin
+// @Produce json
+// @Param q query string false "Search query, matched against user id and e-mail address"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.AdminUserInfo
+// @Router /admin/users [get]
+func (h *AdminApiHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.adminSrvc.SearchUsers(r.URL.Query().Get("q"), utils.ParsePageParamsWithDefault(r, 1, 50))
+	if err != nil {
+		conf.Log().Request(r).Error("failed to search users - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, users)
+}
+
+// @Summary Retrieve a single user
+// @ID get-admin-user
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AdminUserInfo
+// @Router /admin/users/{user} [get]
+func (h *AdminApiHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	info, err := h.adminSrvc.GetUser(chi.URLParam(r, "user"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, info)
+}
+
+// @Summary Delete a user and all their data
+// @ID delete-admin-user
+// @Tags admin
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /admin/users/{user} [delete]
+func (h *AdminApiHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	h.withTargetUser(w, r, func(admin, user *models.User) error {
+		if err := h.adminSrvc.DeleteUser(admin, user); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+// @Summary Disable a user account
+// @ID post-admin-user-disable
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AdminUserInfo
+// @Router /admin/users/{user}/disable [post]
+func (h *AdminApiHandler) PostDisable(w http.ResponseWriter, r *http.Request) {
+	h.withTargetUser(w, r, func(admin, user *models.User) error {
+		_, err := h.adminSrvc.SetDisabled(admin, user, true)
+		return h.respondUser(w, r, user, err)
+	})
+}
+
+// @Summary Re-enable a disabled user account
+// @ID post-admin-user-enable
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AdminUserInfo
+// @Router /admin/users/{user}/enable [post]
+func (h *AdminApiHandler) PostEnable(w http.ResponseWriter, r *http.Request) {
+	h.withTargetUser(w, r, func(admin, user *models.User) error {
+		_, err := h.adminSrvc.SetDisabled(admin, user, false)
+		return h.respondUser(w, r, user, err)
+	})
+}
+
+// @Summary Grant admin privileges to a user
+// @ID post-admin-user-admin
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AdminUserInfo
+// @Router /admin/users/{user}/admin [post]
+func (h *AdminApiHandler) PostAdmin(w http.ResponseWriter, r *http.Request) {
+	h.withTargetUser(w, r, func(admin, user *models.User) error {
+		_, err := h.adminSrvc.SetAdmin(admin, user, true)
+		return h.respondUser(w, r, user, err)
+	})
+}
+
+// @Summary Revoke admin privileges from a user
+// @ID delete-admin-user-admin
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AdminUserInfo
+// @Router /admin/users/{user}/admin [delete]
+func (h *AdminApiHandler) DeleteAdmin(w http.ResponseWriter, r *http.Request) {
+	h.withTargetUser(w, r, func(admin, user *models.User) error {
+		_, err := h.adminSrvc.SetAdmin(admin, user, false)
+		return h.respondUser(w, r, user, err)
+	})
+}
+
+// @Summary Trigger a password reset for a user
+// @Description Generates a password reset link and mails it to the user, if they have an e-mail address. The link is returned in either case.
+// @ID post-admin-user-password-reset
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AdminPasswordResetResult
+// @Router /admin/users/{user}/password_reset [post]
+func (h *AdminApiHandler) PostPasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.withTargetUser(w, r, func(admin, user *models.User) error {
+		link, err := h.adminSrvc.TriggerPasswordReset(user)
+		if err != nil && !errors.Is(err, services.ErrAdminNoEmail) {
+			return err
+		}
+		helpers.RespondJSON(w, r, http.StatusOK, &models.AdminPasswordResetResult{Link: link, MailSent: err == nil})
+		return nil
+	})
+}
+
+// @Summary Reset a user's api key
+// @ID post-admin-user-api-key-reset
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AdminUserInfo
+// @Router /admin/users/{user}/api_key_reset [post]
+func (h *AdminApiHandler) PostApiKeyReset(w http.ResponseWriter, r *http.Request) {
+	h.withTargetUser(w, r, func(admin, user *models.User) error {
+		_, err := h.adminSrvc.ResetApiKey(user)
+		return h.respondUser(w, r, user, err)
+	})
+}
+
+func (h *AdminApiHandler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := middlewares.GetPrincipal(r); user == nil || !user.IsAdmin {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(conf.ErrForbidden))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *AdminApiHandler) withTargetUser(w http.ResponseWriter, r *http.Request, f func(admin, user *models.User) error) {
+	admin := middlewares.GetPrincipal(r)
+
+	user, err := h.userSrvc.GetUserById(chi.URLParam(r, "user"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := f(admin, user); err != nil {
+		if errors.Is(err, services.ErrAdminSelfModification) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		conf.Log().Request(r).Error("failed to perform admin operation on user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+	}
+}
+
+func (h *AdminApiHandler) respondUser(w http.ResponseWriter, r *http.Request, user *models.User, err error) error {
+	if err != nil {
+		return err
+	}
+	info, err := h.adminSrvc.GetUser(user.ID)
+	if err != nil {
+		return err
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, info)
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/go-chi/chi/v5"
 	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
 	"net/http"
 
 	conf "github.com/muety/wakapi/config"
@@ -26,7 +27,10 @@ func NewDiagnosticsApiHandler(userService services.IUserService, diagnosticsServ
 }
 
 func (h *DiagnosticsApiHandler) RegisterRoutes(router chi.Router) {
-	router.Post("/plugins/errors", h.Post)
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor("/api/plugins/errors").Handler)
+		r.Post("/plugins/errors", h.Post)
+	})
 }
 
 // @Summary Push a new diagnostics object
@@ -46,6 +50,10 @@ func (h *DiagnosticsApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user := middlewares.GetPrincipal(r); user != nil {
+		diagnostics.SubmittedBy = &user.ID
+	}
+
 	if _, err := h.diagnosticsSrvc.Create(&diagnostics); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(conf.ErrInternalServerError))
@@ -0,0 +1,463 @@
+package api
+
+import (
+	"fmt"
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	su "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	exportPageSizeDefault = 1000
+	exportPageSizeMax     = 5000
+	calendarFeedDays      = 90 // how far back work sessions are included in the calendar feed
+)
+
+// ExportApiHandler serves a user's raw data in wakapi's own format, e.g. for migrating to another instance
+type ExportApiHandler struct {
+	config              *conf.Config
+	userSrvc            services.IUserService
+	heartbeatSrvc       services.IHeartbeatService
+	aliasSrvc           services.IAliasService
+	projectLabelSrvc    services.IProjectLabelService
+	languageMappingSrvc services.ILanguageMappingService
+	exportSrvc          services.IExportService
+	reportSrvc          services.IReportService
+	reportRenderer      services.IReportRenderer
+}
+
+func NewExportApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, aliasService services.IAliasService, projectLabelService services.IProjectLabelService, languageMappingService services.ILanguageMappingService, exportService services.IExportService, reportService services.IReportService, reportRenderer services.IReportRenderer) *ExportApiHandler {
+	return &ExportApiHandler{
+		config:              conf.Get(),
+		userSrvc:            userService,
+		heartbeatSrvc:       heartbeatService,
+		aliasSrvc:           aliasService,
+		projectLabelSrvc:    projectLabelService,
+		languageMappingSrvc: languageMappingService,
+		exportSrvc:          exportService,
+		reportSrvc:          reportService,
+		reportRenderer:      reportRenderer,
+	}
+}
+
+func (h *ExportApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Get("/calendar.ics", h.GetCalendar) // authenticated by calendar token, as calendar clients can't send api keys
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/heartbeats", h.GetHeartbeats)
+		r.Get("/heartbeats.{format}", h.ExportHeartbeats)
+		r.Get("/durations.{format}", h.ExportDurations)
+		r.Get("/summaries.{format}", h.ExportSummaries)
+		r.Get("/worklogs.{format}", h.ExportWorklogs)
+		r.Get("/sessions.{format}", h.ExportWorkSessions)
+		r.Get("/report.{format}", h.ExportReport)
+		r.Get("/settings", h.GetSettings)
+	})
+
+	router.Mount("/export", r)
+}
+
+// @Summary Retrieve the authenticated user's raw heartbeats page by page, ordered by id
+// @ID get-export-heartbeats
+// @Tags export
+// @Produce json
+// @Param after query int false "Cursor as returned with the previous page, omit for the first page"
+// @Param limit query int false "Page size (max. 5000)"
+// @Param from query string false "Only include heartbeats at or after this time (RFC 3339)"
+// @Param to query string false "Only include heartbeats before this time (RFC 3339)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.HeartbeatsExportPage
+// @Router /export/heartbeats [get]
+func (h *ExportApiHandler) GetHeartbeats(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	query := r.URL.Query()
+
+	var err error
+	var after uint64
+	if s := query.Get("after"); s != "" {
+		if after, err = strconv.ParseUint(s, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid cursor"))
+			return
+		}
+	}
+
+	limit := exportPageSizeDefault
+	if s := query.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid limit"))
+			return
+		}
+		if limit > exportPageSizeMax {
+			limit = exportPageSizeMax
+		}
+	}
+
+	from, to := time.Time{}, time.Now().Add(24*time.Hour)
+	if s := query.Get("from"); s != "" {
+		if from, err = time.Parse(time.RFC3339, s); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid 'from' parameter"))
+			return
+		}
+	}
+	if s := query.Get("to"); s != "" {
+		if to, err = time.Parse(time.RFC3339, s); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid 'to' parameter"))
+			return
+		}
+	}
+
+	heartbeats, err := h.heartbeatSrvc.GetPageByUser(user, after, from, to, limit)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch heartbeats for export of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	page := &models.HeartbeatsExportPage{
+		Data:    heartbeats,
+		Cursor:  after,
+		HasMore: len(heartbeats) == limit,
+	}
+	if len(heartbeats) > 0 {
+		page.Cursor = heartbeats[len(heartbeats)-1].ID
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, page)
+}
+
+// @Summary Stream the authenticated user's heartbeats within the given range as csv or ndjson
+// @ID get-export-heartbeats-rows
+// @Tags export
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format path string true "Output format" Enums(csv, ndjson)
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param columns query string false "Comma-separated list of columns to include, all by default"
+// @Param raw query bool false "Whether to leave aliases unresolved"
+// @Security ApiKeyAuth
+// @Success 200 {string} string
+// @Router /export/heartbeats.{format} [get]
+func (h *ExportApiHandler) ExportHeartbeats(w http.ResponseWriter, r *http.Request) {
+	h.exportRows(w, r, models.ExportHeartbeats)
+}
+
+// @Summary Stream the authenticated user's coding durations within the given range as csv or ndjson
+// @ID get-export-durations-rows
+// @Tags export
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format path string true "Output format" Enums(csv, ndjson)
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param columns query string false "Comma-separated list of columns to include, all by default"
+// @Param raw query bool false "Whether to leave aliases unresolved"
+// @Security ApiKeyAuth
+// @Success 200 {string} string
+// @Router /export/durations.{format} [get]
+func (h *ExportApiHandler) ExportDurations(w http.ResponseWriter, r *http.Request) {
+	h.exportRows(w, r, models.ExportDurations)
+}
+
+// @Summary Stream the authenticated user's daily summaries within the given range as csv or ndjson, one row per day and entity
+// @ID get-export-summaries-rows
+// @Tags export
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format path string true "Output format" Enums(csv, ndjson)
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param columns query string false "Comma-separated list of columns to include, all by default"
+// @Param raw query bool false "Whether to leave aliases unresolved"
+// @Security ApiKeyAuth
+// @Success 200 {string} string
+// @Router /export/summaries.{format} [get]
+func (h *ExportApiHandler) ExportSummaries(w http.ResponseWriter, r *http.Request) {
+	h.exportRows(w, r, models.ExportSummaries)
+}
+
+// @Summary Stream the time the authenticated user spent per ticket and day within the given range as csv or ndjson, e.g. for importing worklogs into jira
+// @Description Tickets are extracted from branch names using the pattern configured in the user's settings. Activity without a ticket is skipped.
+// @ID get-export-worklogs
+// @Tags export
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format path string true "Output format" Enums(csv, ndjson)
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param ticket query string false "Ticket to filter by"
+// @Param columns query string false "Comma-separated list of columns to include, all by default"
+// @Param raw query bool false "Whether to leave aliases unresolved"
+// @Security ApiKeyAuth
+// @Success 200 {string} string
+// @Router /export/worklogs.{format} [get]
+func (h *ExportApiHandler) ExportWorklogs(w http.ResponseWriter, r *http.Request) {
+	h.exportRows(w, r, models.ExportWorklogs)
+}
+
+// @Summary Download the authenticated user's work sessions within the given range as timesheet csv, as csv for importing into toggl track or clockify, or as icalendar file
+// @Description Work sessions are periods of contiguous coding activity without breaks longer than the user's configured session gap, attributed to the project most time was spent on.
+// @ID get-export-sessions
+// @Tags export
+// @Produce text/csv
+// @Produce text/calendar
+// @Param format path string true "Output format" Enums(csv, toggl, ics)
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Security ApiKeyAuth
+// @Success 200 {file} file
+// @Router /export/sessions.{format} [get]
+func (h *ExportApiHandler) ExportWorkSessions(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	format := chi.URLParam(r, "format")
+	if !models.IsValidWorkSessionFormat(format) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unsupported format, use 'csv', 'toggl' or 'ics'"))
+		return
+	}
+
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	sessions, err := h.exportSrvc.GetWorkSessions(user, &models.ExportParams{From: summaryParams.From, To: summaryParams.To, Filters: summaryParams.Filters})
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute work sessions for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	extension := format
+	if format == models.WorkSessionFormatToggl {
+		extension = models.WorkSessionFormatCsv
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_sessions_%s_%s.%s\"", summaryParams.From.Format(time.DateOnly), summaryParams.To.Add(-1*time.Second).Format(time.DateOnly), extension))
+	h.writeWorkSessions(w, r, format, user, sessions)
+}
+
+// @Summary Subscribe to a user's work sessions of the past 90 days as icalendar feed
+// @Description The feed is authenticated by a secret token instead of an api key, which users can generate in their settings and pass as query parameter.
+// @ID get-export-calendar
+// @Tags export
+// @Produce text/calendar
+// @Param token query string true "Calendar token"
+// @Success 200 {file} file
+// @Failure 401 {string} string
+// @Router /export/calendar.ics [get]
+func (h *ExportApiHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userSrvc.GetUserByCalendarToken(r.URL.Query().Get("token"))
+	if err != nil || user.IsDisabled {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(conf.ErrUnauthorized))
+		return
+	}
+
+	now := time.Now().In(user.TZ())
+	params := &models.ExportParams{
+		From: datetime.BeginOfDay(now.AddDate(0, 0, -calendarFeedDays)),
+		To:   now,
+	}
+
+	sessions, err := h.exportSrvc.GetWorkSessions(user, params)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute work sessions for calendar of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	h.writeWorkSessions(w, r, models.WorkSessionFormatIcs, user, sessions)
+}
+
+// @Summary Download a report of the authenticated user's coding activity within the given range as standalone html or pdf document, e.g. for invoicing
+// @ID get-export-report
+// @Tags export
+// @Produce text/html
+// @Produce application/pdf
+// @Param format path string true "Output format" Enums(html, pdf)
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param details query bool false "Whether to include a breakdown of every project by branch and file"
+// @Security ApiKeyAuth
+// @Success 200 {file} file
+// @Router /export/report.{format} [get]
+func (h *ExportApiHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
+	su.ServeReportDocument(w, r, h.reportSrvc, h.reportRenderer)
+}
+
+// @Summary Retrieve the authenticated user's aliases, project labels and language mappings
+// @ID get-export-settings
+// @Tags export
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SettingsExport
+// @Router /export/settings [get]
+func (h *ExportApiHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	settings, err := h.loadSettings(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch settings for export of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, settings)
+}
+
+func (h *ExportApiHandler) exportRows(w http.ResponseWriter, r *http.Request, kind string) {
+	user := middlewares.GetPrincipal(r)
+
+	format := chi.URLParam(r, "format")
+	if !models.IsValidExportFormat(format) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unsupported format, use 'csv' or 'ndjson'"))
+		return
+	}
+
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	raw, _ := strconv.ParseBool(r.URL.Query().Get("raw"))
+	params, err := (&models.ExportParams{
+		From:    summaryParams.From,
+		To:      summaryParams.To,
+		Filters: summaryParams.Filters,
+		Raw:     raw,
+	}).WithColumns(kind, r.URL.Query().Get("columns"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == models.ExportFormatNdjson {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_%s_%s_%s.%s\"", kind, params.From.Format(time.DateOnly), params.To.Format(time.DateOnly), format))
+
+	writer, err := helpers.NewRowWriter(w, format, params.Columns)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to start %s export for user '%s' - %v", kind, user.ID, err)
+		return
+	}
+
+	// rows are streamed right away, so errors can't be reported by status code anymore
+	if err := h.exportSrvc.Export(kind, user, params, writer.WriteRow); err != nil {
+		conf.Log().Request(r).Error("failed to export %s for user '%s' - %v", kind, user.ID, err)
+	}
+	if err := writer.Flush(); err != nil {
+		conf.Log().Request(r).Error("failed to write %s export for user '%s' - %v", kind, user.ID, err)
+	}
+}
+
+func (h *ExportApiHandler) writeWorkSessions(w http.ResponseWriter, r *http.Request, format string, user *models.User, sessions models.WorkSessions) {
+	if format == models.WorkSessionFormatIcs {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		if err := helpers.WriteCalendar(w, fmt.Sprintf("Wakapi – %s", user.ID), user.ID, sessions); err != nil {
+			conf.Log().Request(r).Error("failed to write work session calendar for user '%s' - %v", user.ID, err)
+		}
+		return
+	}
+
+	columns := models.WorkSessionColumns(format)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer, _ := helpers.NewRowWriter(w, models.ExportFormatCsv, columns)
+	for _, s := range sessions {
+		row := make([]interface{}, len(columns))
+		for i, c := range columns {
+			row[i] = s.ExportValue(c, user)
+		}
+		if err := writer.WriteRow(row); err != nil {
+			conf.Log().Request(r).Error("failed to write work sessions for user '%s' - %v", user.ID, err)
+			return
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		conf.Log().Request(r).Error("failed to write work sessions for user '%s' - %v", user.ID, err)
+	}
+}
+
+func (h *ExportApiHandler) loadSettings(user *models.User) (*models.SettingsExport, error) {
+	var (
+		settings models.SettingsExport
+		err      error
+	)
+	if settings.Aliases, err = h.aliasSrvc.GetByUser(user.ID); err != nil {
+		return nil, err
+	}
+	if settings.ProjectLabels, err = h.projectLabelSrvc.GetByUser(user.ID); err != nil {
+		return nil, err
+	}
+	if settings.LabelColors, err = h.projectLabelSrvc.GetColorsByUser(user.ID); err != nil {
+		return nil, err
+	}
+	if settings.LanguageMappings, err = h.languageMappingSrvc.GetByUser(user.ID); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
@@ -21,14 +21,20 @@ type HeartbeatApiHandler struct {
 	userSrvc            services.IUserService
 	heartbeatSrvc       services.IHeartbeatService
 	languageMappingSrvc services.ILanguageMappingService
+	relaySrvc           services.IRelayService
+	relayTargetSrvc     services.IRelayTargetService
+	projectLabelSrvc    services.IProjectLabelService
 }
 
-func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService) *HeartbeatApiHandler {
+func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService, relayService services.IRelayService, relayTargetService services.IRelayTargetService, projectLabelService services.IProjectLabelService) *HeartbeatApiHandler {
 	return &HeartbeatApiHandler{
 		config:              conf.Get(),
 		userSrvc:            userService,
 		heartbeatSrvc:       heartbeatService,
 		languageMappingSrvc: languageMappingService,
+		relaySrvc:           relayService,
+		relayTargetSrvc:     relayTargetService,
+		projectLabelSrvc:    projectLabelService,
 	}
 }
 
@@ -40,7 +46,7 @@ func (h *HeartbeatApiHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
 		r.Use(
 			middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
-			customMiddleware.NewWakatimeRelayMiddleware().Handler,
+			customMiddleware.NewWakatimeRelayMiddleware(h.relaySrvc, h.relayTargetSrvc, h.projectLabelSrvc).Handler,
 		)
 		// see https://github.com/muety/wakapi/issues/203
 		r.Post("/heartbeat", h.Post)
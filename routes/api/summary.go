@@ -47,6 +47,7 @@ func (h *SummaryApiHandler) RegisterRoutes(router chi.Router) {
 // @Param operating_system query string false "OS to filter by"
 // @Param machine query string false "Machine to filter by"
 // @Param label query string false "Project label to filter by"
+// @Param label_depth query int false "Depth at which to roll up nested labels (e.g. 1 to sum up 'client/acme' as 'client'), 0 for no roll-up"
 // @Security ApiKeyAuth
 // @Success 200 {object} models.Summary
 // @Router /summary [get]
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"net/http"
+	"strconv"
+)
+
+type TeamApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	teamSrvc services.ITeamService
+}
+
+func NewTeamApiHandler(userService services.IUserService, teamService services.ITeamService) *TeamApiHandler {
+	return &TeamApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+		teamSrvc: teamService,
+	}
+}
+
+func (h *TeamApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	r.Get("/", h.GetTeams)
+	r.Get("/{id}/summary", h.GetSummary)
+
+	router.Mount("/teams", r)
+}
+
+// @Summary List the authenticated user's team memberships, including pending invitations
+// @ID get-teams
+// @Tags teams
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.TeamMember
+// @Router /teams [get]
+func (h *TeamApiHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	memberships, err := h.teamSrvc.GetMemberships(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch teams of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, memberships)
+}
+
+// @Summary Retrieve a team's aggregated summary, including a per-member breakdown
+// @ID get-team-summary
+// @Tags teams
+// @Produce json
+// @Param id path int true "Team ID"
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.TeamSummary
+// @Router /teams/{id}/summary [get]
+func (h *TeamApiHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid team id"))
+		return
+	}
+
+	team, err := h.teamSrvc.GetById(uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	params, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	summary, err := h.teamSrvc.Summary(user, team, params.From, params.To, params.Filters)
+	if err != nil {
+		if errors.Is(err, services.ErrTeamForbidden) {
+			// don't reveal the existence of teams to non-members
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(conf.ErrNotFound))
+			return
+		}
+		conf.Log().Request(r).Error("failed to load summary of team %d - %v", team.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, summary)
+}
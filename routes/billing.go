@@ -0,0 +1,259 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type BillingHandler struct {
+	config           *conf.Config
+	userSrvc         services.IUserService
+	heartbeatSrvc    services.IHeartbeatService
+	projectLabelSrvc services.IProjectLabelService
+	billingSrvc      services.IBillingService
+}
+
+func NewBillingHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, projectLabelService services.IProjectLabelService, billingService services.IBillingService) *BillingHandler {
+	return &BillingHandler{
+		config:           conf.Get(),
+		userSrvc:         userService,
+		heartbeatSrvc:    heartbeatService,
+		projectLabelSrvc: projectLabelService,
+		billingSrvc:      billingService,
+	}
+}
+
+func (h *BillingHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+	r.Post("/", h.PostIndex)
+	r.Get("/export.csv", h.GetExport)
+
+	router.Mount("/billing", r)
+}
+
+func (h *BillingHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	setDefaultBillingInterval(r)
+	vm := h.buildViewModel(r, w)
+
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.BillingTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+
+	report, err := h.billingSrvc.Compute(vm.User, summaryParams.From, summaryParams.To, summaryParams.Filters)
+	if errors.Is(err, services.ErrBillingRangeTooLong) {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.BillingTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute billable amounts for user '%s' - %v", vm.User.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.BillingTemplate].Execute(w, vm.WithError(conf.ErrInternalServerError))
+		return
+	}
+	vm.Report = report
+
+	templates[conf.BillingTemplate].Execute(w, vm)
+}
+
+func (h *BillingHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
+	billingUrl := fmt.Sprintf("%s/billing", h.config.Server.BasePath)
+
+	if err := r.ParseForm(); err != nil {
+		routeutils.SetError(r, w, "missing form values")
+		http.Redirect(w, r, billingUrl, http.StatusFound)
+		return
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	var err error
+	var message string
+
+	switch r.PostForm.Get("action") {
+	case "add_rate":
+		var rate *models.BillingRate
+		if rate, err = parseBillingRate(r, user); err == nil {
+			_, err = h.billingSrvc.CreateRate(rate)
+			message = fmt.Sprintf("rate for %s '%s' was added", rate.TargetType, rate.TargetKey)
+		}
+	case "delete_rate":
+		var id uint64
+		var rate *models.BillingRate
+		if id, err = strconv.ParseUint(r.PostForm.Get("rate_id"), 10, 32); err != nil {
+			err = services.ErrBillingRateInvalid
+			break
+		}
+		if rate, err = h.billingSrvc.GetRateById(uint(id)); err != nil || rate.UserID != user.ID {
+			routeutils.SetError(r, w, "rate not found")
+			http.Redirect(w, r, billingUrl, http.StatusFound)
+			return
+		}
+		err = h.billingSrvc.DeleteRate(rate)
+		message = "rate was deleted"
+	default:
+		routeutils.SetError(r, w, "unknown action requests")
+		http.Redirect(w, r, billingUrl, http.StatusFound)
+		return
+	}
+
+	if errors.Is(err, services.ErrBillingRateInvalid) || errors.Is(err, services.ErrBillingRateInEffect) {
+		routeutils.SetError(r, w, err.Error())
+	} else if err != nil {
+		conf.Log().Request(r).Error("failed to update billing rates of user '%s' - %v", user.ID, err)
+		routeutils.SetError(r, w, conf.ErrInternalServerError)
+	} else {
+		routeutils.SetSuccess(r, w, message)
+	}
+	http.Redirect(w, r, billingUrl, http.StatusFound)
+}
+
+// GetExport serves the billable amounts for the requested range and filters as csv, one row per project and rate
+func (h *BillingHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	setDefaultBillingInterval(r)
+
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	report, err := h.billingSrvc.Compute(summaryParams.User, summaryParams.From, summaryParams.To, summaryParams.Filters)
+	if errors.Is(err, services.ErrBillingRangeTooLong) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute billable amounts for user '%s' - %v", summaryParams.User.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	columns := models.BillingExportColumns()
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_billing_%s_%s.csv\"", summaryParams.From.Format(time.DateOnly), summaryParams.To.Add(-1*time.Second).Format(time.DateOnly)))
+
+	writer, err := helpers.NewRowWriter(w, models.ExportFormatCsv, columns)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to start billing export for user '%s' - %v", summaryParams.User.ID, err)
+		return
+	}
+	for _, line := range report.Lines {
+		row := make([]interface{}, len(columns))
+		for i, c := range columns {
+			row[i] = line.ExportValue(c)
+		}
+		if err := writer.WriteRow(row); err != nil {
+			conf.Log().Request(r).Error("failed to write billing export for user '%s' - %v", summaryParams.User.ID, err)
+			return
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		conf.Log().Request(r).Error("failed to write billing export for user '%s' - %v", summaryParams.User.ID, err)
+	}
+}
+
+func (h *BillingHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.BillingViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	vm := &view.BillingViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Rates:    []models.BillingRates{},
+		Labels:   []string{},
+		Projects: []string{},
+		Query:    r.URL.Query(),
+		Today:    time.Now().In(user.TZ()),
+	}
+
+	rates, err := h.billingSrvc.GetRatesByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching billing rates of user '%s' - %v", user.ID, err)
+		vm.SetError(criticalError)
+		return vm
+	}
+	vm.Rates = rates.Versions()
+
+	if projects, err := h.heartbeatSrvc.GetEntitySetByUser(models.SummaryProject, user.ID); err == nil {
+		sort.Strings(projects)
+		vm.Projects = projects
+	}
+	if labels, err := h.projectLabelSrvc.GetByUserGroupedInverted(user.ID); err == nil {
+		for label := range labels {
+			vm.Labels = append(vm.Labels, label)
+		}
+		sort.Strings(vm.Labels)
+	}
+
+	return routeutils.WithSessionMessages(vm, r, w)
+}
+
+func parseBillingRate(r *http.Request, user *models.User) (*models.BillingRate, error) {
+	hourlyRate, err := strconv.ParseFloat(strings.TrimSpace(r.PostForm.Get("hourly_rate")), 64)
+	if err != nil {
+		return nil, services.ErrBillingRateInvalid
+	}
+	var roundingMinutes int
+	if s := strings.TrimSpace(r.PostForm.Get("rounding_minutes")); s != "" {
+		if roundingMinutes, err = strconv.Atoi(s); err != nil {
+			return nil, services.ErrBillingRateInvalid
+		}
+	}
+	validFrom, err := time.ParseInLocation(time.DateOnly, r.PostForm.Get("valid_from"), user.TZ())
+	if err != nil {
+		return nil, services.ErrBillingRateInvalid
+	}
+
+	return &models.BillingRate{
+		UserID:          user.ID,
+		TargetType:      r.PostForm.Get("target_type"),
+		TargetKey:       strings.TrimSpace(r.PostForm.Get("target")),
+		HourlyRate:      hourlyRate,
+		Currency:        strings.ToUpper(strings.TrimSpace(r.PostForm.Get("currency"))),
+		Billable:        r.PostForm.Get("billable") == "true",
+		RoundingMinutes: roundingMinutes,
+		RoundingMode:    r.PostForm.Get("rounding_mode"),
+		ValidFrom:       models.CustomTime(validFrom),
+	}, nil
+}
+
+func setDefaultBillingInterval(r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("interval") == "" && q.Get("from") == "" {
+		q.Set("interval", "month")
+		r.URL.RawQuery = q.Encode()
+	}
+}
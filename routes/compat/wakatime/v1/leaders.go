@@ -120,21 +120,11 @@ func (h *LeadersHandler) buildViewModel(globalLeaderboard, languageLeaderboard m
 	totalUsers, _ := h.leaderboardSrvc.CountUsers(true)
 	totalPages := int(totalUsers/int64(pageParams.PageSize) + 1)
 
-	_, from, to := helpers.ResolveIntervalTZ(interval, time.UTC)
-	numDays := len(utils.SplitRangeByDays(from, to))
-
 	vm := &v1.LeadersViewModel{
-		Data:       make([]*v1.LeadersEntry, 0, len(languageLeaderboard.UserIDs())),
+		Data:       newLeadersEntries(globalLeaderboard, languageLeaderboard, interval),
 		Page:       pageParams.Page,
 		TotalPages: totalPages,
-		Range: &v1.LeadersRange{
-			EndText:   helpers.FormatDateHuman(to),
-			EndDate:   to.Format(time.RFC3339),
-			StartText: helpers.FormatDateHuman(from),
-			StartDate: from.Format(time.RFC3339),
-			Name:      (*interval)[0],
-			Text:      interval.GetHumanReadable(),
-		},
+		Range:      newLeadersRange(interval),
 	}
 
 	if len(currentUserGlobal) > 0 {
@@ -145,10 +135,30 @@ func (h *LeadersHandler) buildViewModel(globalLeaderboard, languageLeaderboard m
 		}
 	}
 
-	for _, entry := range globalLeaderboard {
+	return vm
+}
+
+func newLeadersRange(interval *models.IntervalKey) *v1.LeadersRange {
+	_, from, to := helpers.ResolveIntervalTZ(interval, time.UTC)
+	return &v1.LeadersRange{
+		EndText:   helpers.FormatDateHuman(to),
+		EndDate:   to.Format(time.RFC3339),
+		StartText: helpers.FormatDateHuman(from),
+		StartDate: from.Format(time.RFC3339),
+		Name:      (*interval)[0],
+		Text:      interval.GetHumanReadable(),
+	}
+}
+
+func newLeadersEntries(leaderboard, languageLeaderboard models.Leaderboard, interval *models.IntervalKey) []*v1.LeadersEntry {
+	_, from, to := helpers.ResolveIntervalTZ(interval, time.UTC)
+	numDays := len(utils.SplitRangeByDays(from, to))
+
+	entries := make([]*v1.LeadersEntry, 0, len(leaderboard))
+	for _, entry := range leaderboard {
 		dailyAverage := entry.Total / time.Duration(numDays)
 
-		vm.Data = append(vm.Data, &v1.LeadersEntry{
+		entries = append(entries, &v1.LeadersEntry{
 			Rank: int(entry.Rank),
 			RunningTotal: &v1.LeadersRunningTotal{
 				TotalSeconds:              float64(entry.Total / time.Second),
@@ -165,6 +175,5 @@ func (h *LeadersHandler) buildViewModel(globalLeaderboard, languageLeaderboard m
 			User: v1.NewFromUser(entry.User),
 		})
 	}
-
-	return vm
+	return entries
 }
@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"net/http"
+	"strconv"
+
+	conf "github.com/muety/wakapi/config"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/muety/wakapi/services"
+)
+
+type PrivateLeaderboardsHandler struct {
+	config                 *conf.Config
+	userSrvc               services.IUserService
+	privateLeaderboardSrvc services.IPrivateLeaderboardService
+}
+
+func NewPrivateLeaderboardsHandler(userService services.IUserService, privateLeaderboardService services.IPrivateLeaderboardService) *PrivateLeaderboardsHandler {
+	return &PrivateLeaderboardsHandler{
+		userSrvc:               userService,
+		privateLeaderboardSrvc: privateLeaderboardService,
+		config:                 conf.Get(),
+	}
+}
+
+func (h *PrivateLeaderboardsHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/compat/wakatime/v1/users/current/leaderboards", h.GetAll)
+		r.Get("/compat/wakatime/v1/users/current/leaderboards/{id}", h.Get)
+	})
+}
+
+// @Summary List the private leaderboards the current user is a member of
+// @Description Mimics https://wakatime.com/developers#private_leaderboards
+// @ID get-wakatime-private-leaderboards
+// @Tags wakatime
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.PrivateLeaderboardsViewModel
+// @Router /compat/wakatime/v1/users/current/leaderboards [get]
+func (h *PrivateLeaderboardsHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	leaderboards, err := h.privateLeaderboardSrvc.GetByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching private leaderboards of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	vm := &v1.PrivateLeaderboardsViewModel{
+		Data:       make([]*v1.PrivateLeaderboardEntry, 0, len(leaderboards)),
+		Total:      len(leaderboards),
+		TotalPages: 1,
+	}
+
+	for _, l := range leaderboards {
+		members, err := h.privateLeaderboardSrvc.GetMembers(l)
+		if err != nil {
+			conf.Log().Request(r).Error("error while fetching members of private leaderboard %d - %v", l.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+
+		isOwner := l.OwnerID == user.ID
+		entry := &v1.PrivateLeaderboardEntry{
+			ID:           strconv.Itoa(int(l.ID)),
+			Name:         l.Name,
+			Language:     l.Language,
+			TimeRange:    (*l.Scope())[0],
+			MembersCount: len(members),
+			CanEdit:      isOwner,
+			CanDelete:    isOwner,
+			CreatedAt:    l.CreatedAt.T(),
+		}
+		if isOwner {
+			entry.Code = l.InviteCode
+		}
+		vm.Data = append(vm.Data, entry)
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
+
+// @Summary List of members of a private leaderboard ranked by coding activity in descending order.
+// @Description Mimics https://wakatime.com/developers#private_leaderboards_leaders
+// @ID get-wakatime-private-leaderboard
+// @Tags wakatime
+// @Produce json
+// @Param id path string true "Private leaderboard ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.LeadersViewModel
+// @Router /compat/wakatime/v1/users/current/leaderboards/{id} [get]
+func (h *PrivateLeaderboardsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	var leaderboard *models.PrivateLeaderboard
+	if id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32); err == nil {
+		leaderboard, _ = h.privateLeaderboardSrvc.GetById(uint(id))
+	}
+	if leaderboard == nil || !h.privateLeaderboardSrvc.IsMember(leaderboard, user) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	by := models.SummaryLanguage
+
+	languageLeaderboard, err := h.privateLeaderboardSrvc.GetLeaderboard(user, leaderboard, &by)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching language-specific items of private leaderboard %d - %v", leaderboard.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	var primaryLeaderboard models.Leaderboard
+	if leaderboard.HasLanguageFocus() {
+		primaryLeaderboard = languageLeaderboard.TopByKey(by, leaderboard.Language)
+	} else if primaryLeaderboard, err = h.privateLeaderboardSrvc.GetLeaderboard(user, leaderboard, nil); err != nil {
+		conf.Log().Request(r).Error("error while fetching items of private leaderboard %d - %v", leaderboard.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	primaryLeaderboard.FilterEmpty()
+
+	vm := &v1.LeadersViewModel{
+		Data:       newLeadersEntries(primaryLeaderboard, languageLeaderboard, leaderboard.Scope()),
+		Page:       1,
+		TotalPages: 1,
+		Language:   leaderboard.Language,
+		Range:      newLeadersRange(leaderboard.Scope()),
+	}
+
+	if currentUser := *primaryLeaderboard.GetByUser(user.ID); len(currentUser) > 0 {
+		vm.CurrentUser = &v1.LeadersCurrentUser{
+			Rank: int(currentUser[0].Rank),
+			Page: 1,
+			User: v1.NewFromUser(currentUser[0].User),
+		}
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
@@ -0,0 +1,338 @@
+package routes
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/services/imports"
+	fsutils "github.com/muety/wakapi/utils/fs"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	csvImportPreviewRows = 50
+	csvImportUploadTTL   = 24 * time.Hour
+)
+
+// common column names, used to pre-select the mapping
+var csvImportColumnGuesses = map[string][]string{
+	"time":     {"time", "timestamp", "date", "datetime", "start", "started_at", "start_time"},
+	"entity":   {"entity", "file", "path", "task", "description"},
+	"project":  {"project", "project_name"},
+	"language": {"language", "lang"},
+	"branch":   {"branch"},
+	"editor":   {"editor", "ide", "tool", "application"},
+	"machine":  {"machine", "host", "hostname", "computer"},
+	"duration": {"duration", "seconds", "time_spent"},
+}
+
+// CsvImportHandler guides users through importing heartbeats from csv files: uploading a file, mapping its columns to
+// heartbeat fields and previewing the result, before finally starting the import. Uploaded files are kept on disk
+// in between these steps.
+type CsvImportHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	importSrvc services.IImportService
+	uploadDir  string
+}
+
+func NewCsvImportHandler(userService services.IUserService, importService services.IImportService) *CsvImportHandler {
+	return &CsvImportHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		importSrvc: importService,
+		uploadDir:  filepath.Join(os.TempDir(), "wakapi_csv_imports"),
+	}
+}
+
+func (h *CsvImportHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+	r.Post("/", h.PostIndex)
+
+	router.Mount("/import/csv", r)
+}
+
+func (h *CsvImportHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	templates[conf.CsvImportTemplate].Execute(w, h.buildViewModel(r, w))
+}
+
+func (h *CsvImportHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.ImportEnabled {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.CsvImportTemplate].Execute(w, h.buildViewModel(r, w).WithError("imports are disabled on this server"))
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing form values"))
+		return
+	}
+
+	switch r.PostFormValue("action") {
+	case "upload":
+		h.upload(w, r)
+	case "preview":
+		h.preview(w, r)
+	case "import":
+		h.startImport(w, r)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, h.buildViewModel(r, w).WithError("unknown action requests"))
+	}
+}
+
+func (h *CsvImportHandler) upload(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	vm := h.buildViewModel(r, w)
+
+	upload, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError("missing csv file"))
+		return
+	}
+	defer upload.Close()
+
+	header, err := imports.ReadCsvHeader(upload)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+	if _, err := upload.Seek(0, io.SeekStart); err != nil {
+		conf.Log().Request(r).Error("failed to rewind uploaded csv file of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(conf.ErrInternalServerError))
+		return
+	}
+
+	token, err := h.storeUpload(user, upload)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to store uploaded csv file of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(conf.ErrInternalServerError))
+		return
+	}
+
+	vm.Token = token
+	vm.FileName = fileHeader.Filename
+	vm.Header = header
+	vm.Mapping = guessCsvMapping(header)
+	templates[conf.CsvImportTemplate].Execute(w, vm)
+}
+
+func (h *CsvImportHandler) preview(w http.ResponseWriter, r *http.Request) {
+	vm, mapping, file, ok := h.loadMappingStep(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	preview, err := imports.NewCsvImporter(file, mapping, services.HeartbeatDiffThreshold).Preview(csvImportPreviewRows)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+
+	vm.Preview = preview
+	templates[conf.CsvImportTemplate].Execute(w, vm)
+}
+
+func (h *CsvImportHandler) startImport(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	vm, mapping, file, ok := h.loadMappingStep(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.importSrvc.CheckRateLimit(user); err != nil {
+		file.Close()
+		w.WriteHeader(http.StatusTooManyRequests)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+
+	// move the upload out of the way of expiry, the import job removes it once done
+	path, err := fsutils.StashFile(file)
+	file.Close()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to store uploaded csv file of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(conf.ErrInternalServerError))
+		return
+	}
+	os.Remove(file.Name())
+
+	job := models.NewImportJob(user, models.ImportSourceCsv).WithParams(&models.ImportJobParams{
+		FilePath:   path,
+		DeleteFile: true,
+		CsvMapping: mapping,
+		Timezone:   vm.Timezone,
+	})
+	if err := h.importSrvc.Submit(job); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+
+	routeutils.SetSuccess(r, w, "Import started. This might take a few minutes. Please check back later.")
+	http.Redirect(w, r, fmt.Sprintf("%s/settings#integrations", h.config.Server.BasePath), http.StatusFound)
+}
+
+// loadMappingStep restores the state of the mapping step from the submitted form and opens the previously uploaded file
+func (h *CsvImportHandler) loadMappingStep(w http.ResponseWriter, r *http.Request) (*view.CsvImportViewModel, *models.CsvImportMapping, *os.File, bool) {
+	user := middlewares.GetPrincipal(r)
+	vm := h.buildViewModel(r, w)
+
+	vm.Token = r.PostFormValue("token")
+	vm.FileName = r.PostFormValue("file_name")
+	vm.Timezone = r.PostFormValue("timezone")
+	for _, field := range view.CsvImportFields {
+		vm.Mapping[field] = r.PostFormValue("column_" + field)
+	}
+
+	file, err := h.openUpload(user, vm.Token)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, h.buildViewModel(r, w).WithError("uploaded file not found or expired, please upload it again"))
+		return nil, nil, nil, false
+	}
+
+	if vm.Header, err = imports.ReadCsvHeader(file); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, h.buildViewModel(r, w).WithError(err.Error()))
+		return nil, nil, nil, false
+	}
+
+	location, err := time.LoadLocation(vm.Timezone)
+	if err != nil {
+		file.Close()
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(fmt.Sprintf("invalid time zone '%s'", vm.Timezone)))
+		return nil, nil, nil, false
+	}
+
+	mapping := &models.CsvImportMapping{
+		Time:     vm.Mapping["time"],
+		Entity:   vm.Mapping["entity"],
+		Project:  vm.Mapping["project"],
+		Language: vm.Mapping["language"],
+		Branch:   vm.Mapping["branch"],
+		Editor:   vm.Mapping["editor"],
+		Machine:  vm.Mapping["machine"],
+		Duration: vm.Mapping["duration"],
+		Location: location,
+	}
+	if !mapping.IsValid() {
+		file.Close()
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.CsvImportTemplate].Execute(w, vm.WithError(imports.ErrInvalidCsvMapping.Error()))
+		return nil, nil, nil, false
+	}
+
+	return vm, mapping, file, true
+}
+
+func (h *CsvImportHandler) storeUpload(user *models.User, upload io.Reader) (string, error) {
+	if err := os.MkdirAll(h.uploadDir, 0700); err != nil {
+		return "", err
+	}
+	h.deleteExpiredUploads()
+
+	token := uuid.NewV4().String()
+	file, err := os.OpenFile(h.uploadPath(user, token), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, upload); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return token, nil
+}
+
+func (h *CsvImportHandler) openUpload(user *models.User, token string) (*os.File, error) {
+	if _, err := uuid.FromString(token); err != nil {
+		return nil, err
+	}
+	return os.Open(h.uploadPath(user, token))
+}
+
+func (h *CsvImportHandler) uploadPath(user *models.User, token string) string {
+	return filepath.Join(h.uploadDir, fmt.Sprintf("%s_%s.csv", user.ID, token))
+}
+
+// deleteExpiredUploads removes files of abandoned imports
+func (h *CsvImportHandler) deleteExpiredUploads() {
+	entries, err := os.ReadDir(h.uploadDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > csvImportUploadTTL {
+			os.Remove(filepath.Join(h.uploadDir, entry.Name()))
+		}
+	}
+}
+
+func (h *CsvImportHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.CsvImportViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	return &view.CsvImportViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Mapping:  map[string]string{},
+		Timezone: user.TZ().String(),
+	}
+}
+
+func guessCsvMapping(header []string) map[string]string {
+	mapping := map[string]string{}
+	for field, guesses := range csvImportColumnGuesses {
+		for _, column := range header {
+			for _, guess := range guesses {
+				if _, taken := mapping[field]; !taken && strings.EqualFold(strings.TrimSpace(column), guess) {
+					mapping[field] = column
+				}
+			}
+		}
+	}
+	return mapping
+}
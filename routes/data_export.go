@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"net/http"
+	"os"
+	"time"
+)
+
+type DataExportHandler struct {
+	config         *conf.Config
+	dataExportSrvc services.IDataExportService
+}
+
+func NewDataExportHandler(dataExportService services.IDataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		config:         conf.Get(),
+		dataExportSrvc: dataExportService,
+	}
+}
+
+func (h *DataExportHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Get("/download", h.GetDownload)
+
+	router.Mount("/export", r)
+}
+
+// GetDownload serves a previously generated data export, authorized through the signed token of the download link sent by mail
+func (h *DataExportHandler) GetDownload(w http.ResponseWriter, r *http.Request) {
+	user, path, err := h.dataExportSrvc.ResolveDownload(r.URL.Query().Get("token"))
+	if err != nil {
+		routeutils.SetError(r, w, err.Error())
+		http.Redirect(w, r, defaultErrorRedirectTarget(), http.StatusFound)
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to open data export of user '%s' - %v", user.ID, err)
+		routeutils.SetError(r, w, conf.ErrInternalServerError)
+		http.Redirect(w, r, defaultErrorRedirectTarget(), http.StatusFound)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to stat data export of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_export_%s_%s.zip\"", user.ID, info.ModTime().Format(time.DateOnly)))
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
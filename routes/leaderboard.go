@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"github.com/duke-git/lancet/v2/slice"
 	"github.com/go-chi/chi/v5"
@@ -12,36 +13,52 @@ import (
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
 type LeaderboardHandler struct {
-	config             *conf.Config
-	userService        services.IUserService
-	leaderboardService services.ILeaderboardService
+	config                    *conf.Config
+	userService               services.IUserService
+	leaderboardService        services.ILeaderboardService
+	privateLeaderboardService services.IPrivateLeaderboardService
 }
 
 var allowedAggregations = map[string]uint8{
 	"language": models.SummaryLanguage,
 }
 
-func NewLeaderboardHandler(userService services.IUserService, leaderboardService services.ILeaderboardService) *LeaderboardHandler {
+func NewLeaderboardHandler(userService services.IUserService, leaderboardService services.ILeaderboardService, privateLeaderboardService services.IPrivateLeaderboardService) *LeaderboardHandler {
 	return &LeaderboardHandler{
-		config:             conf.Get(),
-		userService:        userService,
-		leaderboardService: leaderboardService,
+		config:                    conf.Get(),
+		userService:               userService,
+		leaderboardService:        leaderboardService,
+		privateLeaderboardService: privateLeaderboardService,
 	}
 }
 
 func (h *LeaderboardHandler) RegisterRoutes(router chi.Router) {
 	r := chi.NewRouter()
-	r.Use(
-		middlewares.NewAuthenticateMiddleware(h.userService).
-			WithRedirectTarget(defaultErrorRedirectTarget()).
-			WithRedirectErrorMessage("unauthorized").
-			WithOptionalFor("/").Handler,
-	)
-	r.Get("/", h.GetIndex)
+	r.Group(func(r chi.Router) {
+		r.Use(
+			middlewares.NewAuthenticateMiddleware(h.userService).
+				WithRedirectTarget(defaultErrorRedirectTarget()).
+				WithRedirectErrorMessage("unauthorized").
+				WithOptionalFor("/").Handler,
+		)
+		r.Get("/", h.GetIndex)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(
+			middlewares.NewAuthenticateMiddleware(h.userService).
+				WithRedirectTarget(defaultErrorRedirectTarget()).
+				WithRedirectErrorMessage("unauthorized").Handler,
+		)
+		r.Get("/private", h.GetPrivateIndex)
+		r.Post("/private", h.PostPrivateIndex)
+		r.Get("/private/{id}", h.GetPrivate)
+		r.Post("/private/{id}", h.PostPrivate)
+	})
 
 	router.Mount("/leaderboard", r)
 }
@@ -113,13 +130,7 @@ func (h *LeaderboardHandler) buildViewModel(r *http.Request, w http.ResponseWrit
 				}
 			}
 
-			userLeaderboards := slice.GroupWith[*models.LeaderboardItemRanked, string](leaderboard, func(item *models.LeaderboardItemRanked) string {
-				return item.UserID
-			})
-			userLanguages = map[string][]string{}
-			for u, items := range userLeaderboards {
-				userLanguages[u] = models.Leaderboard(items).TopKeysByUser(models.SummaryLanguage, u)
-			}
+			userLanguages = getUserLanguages(leaderboard)
 
 			topKeys = leaderboard.TopKeys(by)
 			if len(topKeys) > 0 {
@@ -161,3 +172,252 @@ func (h *LeaderboardHandler) buildViewModel(r *http.Request, w http.ResponseWrit
 	}
 	return routeutils.WithSessionMessages(vm, r, w)
 }
+
+func (h *LeaderboardHandler) GetPrivateIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	templates[conf.PrivateLeaderboardsTemplate].Execute(w, h.buildPrivateIndexViewModel(r, w))
+}
+
+func (h *LeaderboardHandler) PostPrivateIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.PrivateLeaderboardsTemplate].Execute(w, h.buildPrivateIndexViewModel(r, w).WithError("missing form values"))
+		return
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	var err error
+	var leaderboard *models.PrivateLeaderboard
+	var message string
+
+	switch r.PostForm.Get("action") {
+	case "create":
+		leaderboard, err = h.privateLeaderboardService.Create(user, r.PostForm.Get("name"), r.PostForm.Get("interval"), r.PostForm.Get("language"))
+		message = "leaderboard '%s' was created, it will be populated in a moment"
+	case "join":
+		leaderboard, err = h.privateLeaderboardService.Join(user, r.PostForm.Get("code"))
+		message = "you joined leaderboard '%s'"
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.PrivateLeaderboardsTemplate].Execute(w, h.buildPrivateIndexViewModel(r, w).WithError("unknown action requests"))
+		return
+	}
+
+	if err != nil {
+		if isPrivateLeaderboardClientError(err) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			conf.Log().Request(r).Error("failed to perform private leaderboard operation - %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		templates[conf.PrivateLeaderboardsTemplate].Execute(w, h.buildPrivateIndexViewModel(r, w).WithError(privateLeaderboardErrorMessage(err)))
+		return
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf(message, leaderboard.Name))
+	http.Redirect(w, r, fmt.Sprintf("%s/leaderboard/private/%d", h.config.Server.BasePath, leaderboard.ID), http.StatusFound)
+}
+
+func (h *LeaderboardHandler) GetPrivate(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	leaderboard, ok := h.checkPrivateMembership(w, r)
+	if !ok {
+		return
+	}
+	if err := templates[conf.LeaderboardTemplate].Execute(w, h.buildPrivateViewModel(r, w, leaderboard)); err != nil {
+		conf.Log().Request(r).Error("failed to get private leaderboard page - %v", err)
+	}
+}
+
+func (h *LeaderboardHandler) PostPrivate(w http.ResponseWriter, r *http.Request) {
+	leaderboard, ok := h.checkPrivateMembership(w, r)
+	if !ok {
+		return
+	}
+
+	leaderboardUrl := fmt.Sprintf("%s/leaderboard/private/%d", h.config.Server.BasePath, leaderboard.ID)
+	indexUrl := fmt.Sprintf("%s/leaderboard/private", h.config.Server.BasePath)
+
+	if err := r.ParseForm(); err != nil {
+		routeutils.SetError(r, w, "missing form values")
+		http.Redirect(w, r, leaderboardUrl, http.StatusFound)
+		return
+	}
+
+	user := middlewares.GetPrincipal(r)
+	username := r.PostForm.Get("username")
+
+	var err error
+	var message string
+
+	switch r.PostForm.Get("action") {
+	case "update":
+		leaderboard.Name = r.PostForm.Get("name")
+		leaderboard.Interval = r.PostForm.Get("interval")
+		leaderboard.Language = r.PostForm.Get("language")
+		_, err = h.privateLeaderboardService.Update(user, leaderboard)
+		message = "leaderboard was updated, it will be regenerated in a moment"
+	case "reset_code":
+		_, err = h.privateLeaderboardService.ResetInviteCode(user, leaderboard)
+		message = "invite code was reset"
+	case "remove":
+		err = h.privateLeaderboardService.RemoveMember(user, leaderboard, username)
+		message = fmt.Sprintf("user '%s' was removed from the leaderboard", username)
+	case "leave":
+		if err = h.privateLeaderboardService.Leave(user, leaderboard); err == nil {
+			routeutils.SetSuccess(r, w, fmt.Sprintf("you left leaderboard '%s'", leaderboard.Name))
+			http.Redirect(w, r, indexUrl, http.StatusFound)
+			return
+		}
+	case "delete":
+		if err = h.privateLeaderboardService.Delete(user, leaderboard); err == nil {
+			routeutils.SetSuccess(r, w, fmt.Sprintf("leaderboard '%s' was deleted", leaderboard.Name))
+			http.Redirect(w, r, indexUrl, http.StatusFound)
+			return
+		}
+	default:
+		routeutils.SetError(r, w, "unknown action requests")
+		http.Redirect(w, r, leaderboardUrl, http.StatusFound)
+		return
+	}
+
+	if err != nil {
+		routeutils.SetError(r, w, privateLeaderboardErrorMessage(err))
+		if !isPrivateLeaderboardClientError(err) {
+			conf.Log().Request(r).Error("failed to perform operation on private leaderboard %d - %v", leaderboard.ID, err)
+		}
+	} else {
+		routeutils.SetSuccess(r, w, message)
+	}
+	http.Redirect(w, r, leaderboardUrl, http.StatusFound)
+}
+
+func (h *LeaderboardHandler) checkPrivateMembership(w http.ResponseWriter, r *http.Request) (*models.PrivateLeaderboard, bool) {
+	user := middlewares.GetPrincipal(r)
+	if id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32); err == nil {
+		if leaderboard, err := h.privateLeaderboardService.GetById(uint(id)); err == nil && h.privateLeaderboardService.IsMember(leaderboard, user) {
+			return leaderboard, true
+		}
+	}
+	routeutils.SetError(r, w, "leaderboard not found")
+	http.Redirect(w, r, fmt.Sprintf("%s/leaderboard/private", h.config.Server.BasePath), http.StatusFound)
+	return nil, false
+}
+
+func (h *LeaderboardHandler) buildPrivateIndexViewModel(r *http.Request, w http.ResponseWriter) *view.PrivateLeaderboardsViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	vm := &view.PrivateLeaderboardsViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Leaderboards: []*models.PrivateLeaderboard{},
+	}
+
+	leaderboards, err := h.privateLeaderboardService.GetByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching private leaderboards of user '%s' - %v", user.ID, err)
+		vm.SetError(criticalError)
+		return vm
+	}
+	vm.Leaderboards = leaderboards
+
+	return routeutils.WithSessionMessages(vm, r, w)
+}
+
+func (h *LeaderboardHandler) buildPrivateViewModel(r *http.Request, w http.ResponseWriter, leaderboard *models.PrivateLeaderboard) *view.LeaderboardViewModel {
+	user := middlewares.GetPrincipal(r)
+	byParam := strings.ToLower(r.URL.Query().Get("by"))
+	keyParam := strings.ToLower(r.URL.Query().Get("key"))
+
+	vm := &view.LeaderboardViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Items:              []*models.LeaderboardItemRanked{},
+		IntervalLabel:      leaderboard.Scope().GetHumanReadable(),
+		PrivateLeaderboard: leaderboard,
+	}
+
+	members, err := h.privateLeaderboardService.GetMembers(leaderboard)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching members of private leaderboard %d - %v", leaderboard.ID, err)
+		return vm.WithError(criticalError)
+	}
+	vm.Members = members
+
+	// boards with a language focus only rank coding time in that language
+	if leaderboard.HasLanguageFocus() {
+		byParam, keyParam = "language", strings.ToLower(leaderboard.Language)
+	}
+
+	var items models.Leaderboard
+
+	if byParam == "" {
+		items, err = h.privateLeaderboardService.GetLeaderboard(user, leaderboard, nil)
+	} else if by, ok := allowedAggregations[byParam]; ok {
+		items, err = h.privateLeaderboardService.GetLeaderboard(user, leaderboard, &by)
+		if err == nil {
+			vm.UserLanguages = getUserLanguages(items)
+			vm.TopKeys = items.TopKeys(by)
+			if keyParam == "" && len(vm.TopKeys) > 0 {
+				keyParam = strings.ToLower(vm.TopKeys[0])
+			}
+			items = items.TopByKey(by, keyParam)
+		}
+	} else {
+		return vm.WithError(fmt.Sprintf("unsupported aggregation '%s'", byParam))
+	}
+
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching items of private leaderboard %d - %v", leaderboard.ID, err)
+		return vm.WithError(criticalError)
+	}
+
+	items.FilterEmpty()
+
+	vm.By = byParam
+	vm.Key = keyParam
+	vm.Items = items
+	return routeutils.WithSessionMessages(vm, r, w)
+}
+
+func getUserLanguages(leaderboard models.Leaderboard) map[string][]string {
+	userLeaderboards := slice.GroupWith[*models.LeaderboardItemRanked, string](leaderboard, func(item *models.LeaderboardItemRanked) string {
+		return item.UserID
+	})
+	userLanguages := map[string][]string{}
+	for u, items := range userLeaderboards {
+		userLanguages[u] = models.Leaderboard(items).TopKeysByUser(models.SummaryLanguage, u)
+	}
+	return userLanguages
+}
+
+func isPrivateLeaderboardClientError(err error) bool {
+	return errors.Is(err, services.ErrPrivateLeaderboardInvalid) ||
+		errors.Is(err, services.ErrPrivateLeaderboardNotFound) ||
+		errors.Is(err, services.ErrPrivateLeaderboardForbidden) ||
+		errors.Is(err, services.ErrPrivateLeaderboardAlreadyMember) ||
+		errors.Is(err, services.ErrPrivateLeaderboardOwner)
+}
+
+func privateLeaderboardErrorMessage(err error) string {
+	if isPrivateLeaderboardClientError(err) {
+		return err.Error()
+	}
+	return conf.ErrInternalServerError
+}
@@ -1,20 +1,24 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"github.com/dchest/captcha"
 	"github.com/emvi/logbuch"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httprate"
 	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/models/view"
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
+	uuid "github.com/satori/go.uuid"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -24,22 +28,38 @@ type LoginHandler struct {
 	userSrvc     services.IUserService
 	mailSrvc     services.IMailService
 	keyValueSrvc services.IKeyValueService
+	oidcSrvc     services.IOidcService
+	totpSrvc     services.ITotpService
+	sessionSrvc  services.ISessionService
 }
 
-func NewLoginHandler(userService services.IUserService, mailService services.IMailService, keyValueService services.IKeyValueService) *LoginHandler {
+const totpLoginMaxAge = 5 * time.Minute
+
+func NewLoginHandler(userService services.IUserService, mailService services.IMailService, keyValueService services.IKeyValueService, oidcService services.IOidcService, totpService services.ITotpService, sessionService services.ISessionService) *LoginHandler {
 	return &LoginHandler{
 		config:       conf.Get(),
 		userSrvc:     userService,
 		mailSrvc:     mailService,
 		keyValueSrvc: keyValueService,
+		oidcSrvc:     oidcService,
+		totpSrvc:     totpService,
+		sessionSrvc:  sessionService,
 	}
 }
 
 func (h *LoginHandler) RegisterRoutes(router chi.Router) {
+	loginMaxRate, loginMaxRateWindow := h.config.Security.GetLoginMaxRate()
+
 	router.Get("/login", h.GetIndex)
 	router.
 		With(httprate.LimitByRealIP(h.config.Security.GetLoginMaxRate())).
 		Post("/login", h.PostLogin)
+	router.
+		With(
+			httprate.LimitByRealIP(loginMaxRate, loginMaxRateWindow),
+			httprate.Limit(loginMaxRate, loginMaxRateWindow, httprate.WithKeyFuncs(h.keyByTotpPendingUser)),
+		).
+		Post("/login/totp", h.PostLoginTotp)
 	router.
 		With(httprate.LimitByRealIP(h.config.Security.GetSignupMaxRate())).
 		Get("/signup", h.GetSignup)
@@ -50,6 +70,10 @@ func (h *LoginHandler) RegisterRoutes(router chi.Router) {
 	router.
 		With(httprate.LimitByRealIP(h.config.Security.GetPasswordResetMaxRate())).
 		Post("/reset-password", h.PostResetPassword)
+	router.
+		With(httprate.LimitByRealIP(h.config.Security.GetLoginMaxRate())).
+		Get("/login/oidc", h.GetOidcLogin)
+	router.Get("/login/oidc/callback", h.GetOidcCallback)
 
 	authMiddleware := middlewares.NewAuthenticateMiddleware(h.userSrvc).
 		WithRedirectTarget(defaultErrorRedirectTarget()).
@@ -85,6 +109,12 @@ func (h *LoginHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.config.Oidc.IsPasswordLoginEnabled() {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("password login is disabled, please use single sign-on"))
+		return
+	}
+
 	var login models.Login
 	if err := r.ParseForm(); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -110,10 +140,162 @@ func (h *LoginHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, login.Username)
+	if user.IsDisabled {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("account is disabled"))
+		return
+	}
+
+	if user.HasTotp() {
+		h.beginTotpLogin(w, r, user)
+		return
+	}
+
+	cookie, err := createSessionCookie(r, h.sessionSrvc, user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to create session - %v", err)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("internal server error"))
+		return
+	}
+
+	user.LastLoggedInAt = models.CustomTime(time.Now())
+	h.userSrvc.Update(user)
+
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
+}
+
+func (h *LoginHandler) GetOidcLogin(w http.ResponseWriter, r *http.Request) {
+	if !h.config.Oidc.Enabled {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	state, nonce := uuid.NewV4().String(), uuid.NewV4().String()
+	authUrl, err := h.oidcSrvc.AuthCodeURL(state, nonce)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to initialize oidc provider - %v", err)
+		routeutils.SetError(r, w, "single sign-on is currently unavailable")
+		http.Redirect(w, r, fmt.Sprintf("%s/login", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	encoded, err := h.config.Security.SecureCookie.Encode(models.OidcStateCookieKey, fmt.Sprintf("%s,%s", state, nonce))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		conf.Log().Request(r).Error("failed to encode secure cookie - %v", err)
+		return
+	}
+
+	stateCookie := h.config.CreateCookie(models.OidcStateCookieKey, encoded)
+	stateCookie.MaxAge = int((10 * time.Minute).Seconds())
+	http.SetCookie(w, stateCookie)
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
+
+func (h *LoginHandler) GetOidcCallback(w http.ResponseWriter, r *http.Request) {
+	if !h.config.Oidc.Enabled {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	loginUrl := fmt.Sprintf("%s/login", h.config.Server.BasePath)
+	http.SetCookie(w, h.config.GetClearCookie(models.OidcStateCookieKey))
+
+	var stateValue string
+	cookie, err := r.Cookie(models.OidcStateCookieKey)
+	if err == nil {
+		err = h.config.Security.SecureCookie.Decode(models.OidcStateCookieKey, cookie.Value, &stateValue)
+	}
+	stateParts := strings.Split(stateValue, ",")
+	if err != nil || len(stateParts) != 2 || stateParts[0] != r.URL.Query().Get("state") {
+		routeutils.SetError(r, w, "invalid or expired sign-in attempt, please try again")
+		http.Redirect(w, r, loginUrl, http.StatusFound)
+		return
+	}
+
+	if errMessage := r.URL.Query().Get("error"); errMessage != "" {
+		conf.Log().Request(r).Warn("oidc provider returned error '%s'", errMessage)
+		routeutils.SetError(r, w, "single sign-on failed")
+		http.Redirect(w, r, loginUrl, http.StatusFound)
+		return
+	}
+
+	identity, err := h.oidcSrvc.Exchange(r.Context(), r.URL.Query().Get("code"), stateParts[1])
+	if err != nil {
+		conf.Log().Request(r).Error("failed to exchange oidc authorization code - %v", err)
+		routeutils.SetError(r, w, "single sign-on failed")
+		http.Redirect(w, r, loginUrl, http.StatusFound)
+		return
+	}
+
+	user, err := h.oidcSrvc.Login(identity)
+	if err != nil {
+		conf.Log().Request(r).Warn("failed to sign in oidc subject '%s' - %v", identity.Subject, err)
+		message := "single sign-on failed"
+		if errors.Is(err, services.ErrOidcSignupDisabled) || errors.Is(err, services.ErrOidcUsernameTaken) || errors.Is(err, services.ErrOidcAccountDisabled) {
+			message = err.Error()
+		}
+		routeutils.SetError(r, w, message)
+		http.Redirect(w, r, loginUrl, http.StatusFound)
+		return
+	}
+
+	authCookie, err := createSessionCookie(r, h.sessionSrvc, user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to create session - %v", err)
+		return
+	}
+
+	http.SetCookie(w, authCookie)
+	http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
+}
+
+func (h *LoginHandler) PostLoginTotp(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	userId, err := h.decodeTotpPendingCookie(r)
+	if err != nil {
+		routeutils.SetError(r, w, "login expired, please try again")
+		http.Redirect(w, r, fmt.Sprintf("%s/login", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	user, err := h.userSrvc.GetUserById(userId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("resource not found"))
+		return
+	}
+
+	valid, err := h.totpSrvc.Verify(user, r.PostFormValue("code"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to verify one-time password for user %s - %v", user.ID, err)
+		templates[conf.LoginTotpTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("internal server error"))
+		return
+	}
+	if !valid {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates[conf.LoginTotpTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("invalid code"))
+		return
+	}
+
+	if user.IsDisabled {
+		http.SetCookie(w, h.config.GetClearCookie(models.TotpPendingCookieKey))
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("account is disabled"))
+		return
+	}
+
+	cookie, err := createSessionCookie(r, h.sessionSrvc, user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to create session - %v", err)
 		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("internal server error"))
 		return
 	}
@@ -121,7 +303,8 @@ func (h *LoginHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 	user.LastLoggedInAt = models.CustomTime(time.Now())
 	h.userSrvc.Update(user)
 
-	http.SetCookie(w, h.config.CreateCookie(models.AuthCookieKey, encoded))
+	http.SetCookie(w, h.config.GetClearCookie(models.TotpPendingCookieKey))
+	http.SetCookie(w, cookie)
 	http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
 }
 
@@ -133,6 +316,11 @@ func (h *LoginHandler) PostLogout(w http.ResponseWriter, r *http.Request) {
 	if user := middlewares.GetPrincipal(r); user != nil {
 		h.userSrvc.FlushUserCache(user.ID)
 	}
+	if sessionToken, err := helpers.ExtractCookieAuth(r, h.config); err == nil {
+		if err := h.sessionSrvc.DeleteByToken(*sessionToken); err != nil {
+			conf.Log().Request(r).Error("failed to delete session - %v", err)
+		}
+	}
 	http.SetCookie(w, h.config.GetClearCookie(models.AuthCookieKey))
 	http.Redirect(w, r, fmt.Sprintf("%s/", h.config.Server.BasePath), http.StatusFound)
 }
@@ -167,6 +355,12 @@ func (h *LoginHandler) PostSignup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.config.Oidc.IsPasswordLoginEnabled() {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w, h.config.Security.SignupCaptcha).WithError("registration is only possible via single sign-on"))
+		return
+	}
+
 	if !h.config.IsDev() && !h.config.Security.AllowSignup && (!h.config.Security.InviteCodes || signup.InviteCode == "") {
 		w.WriteHeader(http.StatusForbidden)
 		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w, h.config.Security.SignupCaptcha).WithError("registration is disabled on this server"))
@@ -302,6 +496,11 @@ func (h *LoginHandler) PostSetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// password was reset, so existing sessions must not outlive it
+	if err := h.sessionSrvc.DeleteByUser(user); err != nil {
+		conf.Log().Request(r).Error("failed to revoke sessions for user '%s' - %v", user.ID, err)
+	}
+
 	routeutils.SetSuccess(r, w, "password updated successfully")
 	http.Redirect(w, r, fmt.Sprintf("%s/login", h.config.Server.BasePath), http.StatusFound)
 }
@@ -311,6 +510,12 @@ func (h *LoginHandler) PostResetPassword(w http.ResponseWriter, r *http.Request)
 		loadTemplates()
 	}
 
+	if !h.config.Oidc.IsPasswordLoginEnabled() {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.ResetPasswordTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("password login is disabled, please use single sign-on"))
+		return
+	}
+
 	if !h.config.Mail.Enabled {
 		w.WriteHeader(http.StatusNotImplemented)
 		templates[conf.ResetPasswordTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("mailing is disabled on this server"))
@@ -353,14 +558,67 @@ func (h *LoginHandler) PostResetPassword(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
 }
 
+// beginTotpLogin remembers the user as having passed the first factor and prompts for the second one, without yet issuing an actual session
+func (h *LoginHandler) beginTotpLogin(w http.ResponseWriter, r *http.Request, user *models.User) {
+	encoded, err := h.config.Security.SecureCookie.Encode(models.TotpPendingCookieKey, fmt.Sprintf("%s,%d", user.ID, time.Now().Unix()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to encode secure cookie - %v", err)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("internal server error"))
+		return
+	}
+
+	cookie := h.config.CreateCookie(models.TotpPendingCookieKey, encoded)
+	cookie.MaxAge = int(totpLoginMaxAge.Seconds())
+	http.SetCookie(w, cookie)
+	templates[conf.LoginTotpTemplate].Execute(w, h.buildViewModel(r, w, false))
+}
+
+func (h *LoginHandler) decodeTotpPendingCookie(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(models.TotpPendingCookieKey)
+	if err != nil {
+		return "", err
+	}
+
+	var value string
+	if err := h.config.Security.SecureCookie.Decode(models.TotpPendingCookieKey, cookie.Value, &value); err != nil {
+		return "", err
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return "", errors.New("malformed cookie")
+	}
+	if issuedAt, err := strconv.ParseInt(parts[1], 10, 64); err != nil || time.Since(time.Unix(issuedAt, 0)) > totpLoginMaxAge {
+		return "", errors.New("cookie expired")
+	}
+	return parts[0], nil
+}
+
+// rate-limit second factor attempts per user in addition to per ip, falling back to the ip for requests without a
+// valid cookie, so these don't all share (and exhaust) the same bucket
+func (h *LoginHandler) keyByTotpPendingUser(r *http.Request) (string, error) {
+	if userId, err := h.decodeTotpPendingCookie(r); err == nil && userId != "" {
+		return "user:" + userId, nil
+	}
+	ip, err := httprate.KeyByRealIP(r)
+	if err != nil {
+		return "", err
+	}
+	return "ip:" + ip, nil
+}
+
 func (h *LoginHandler) buildViewModel(r *http.Request, w http.ResponseWriter, withCaptcha bool) *view.LoginViewModel {
 	numUsers, _ := h.userSrvc.Count()
 
 	vm := &view.LoginViewModel{
 		SharedViewModel: view.NewSharedViewModel(h.config, nil),
 		TotalUsers:      int(numUsers),
-		AllowSignup:     h.config.IsDev() || h.config.Security.AllowSignup,
+		AllowSignup:     (h.config.IsDev() || h.config.Security.AllowSignup) && h.config.Oidc.IsPasswordLoginEnabled(),
 		InviteCode:      r.URL.Query().Get("invite"),
+		OidcEnabled:     h.config.Oidc.Enabled,
+		OidcName:        h.config.Oidc.DisplayName,
+		PasswordLogin:   h.config.Oidc.IsPasswordLoginEnabled(),
 	}
 
 	if withCaptcha {
@@ -369,3 +627,20 @@ func (h *LoginHandler) buildViewModel(r *http.Request, w http.ResponseWriter, wi
 
 	return routeutils.WithSessionMessages(vm, r, w)
 }
+
+// createSessionCookie starts a new server-side session for the given user and returns an auth cookie referencing it
+func createSessionCookie(r *http.Request, sessionService services.ISessionService, user *models.User) (*http.Cookie, error) {
+	cfg := conf.Get()
+
+	token, _, err := sessionService.Create(user, r.UserAgent(), middlewares.ReadUserIP(r))
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := cfg.Security.SecureCookie.Encode(models.AuthCookieKey, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.CreateCookie(models.AuthCookieKey, encoded), nil
+}
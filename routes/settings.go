@@ -2,10 +2,12 @@ package routes
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/duke-git/lancet/v2/condition"
 	"github.com/go-chi/chi/v5"
 	uuid "github.com/satori/go.uuid"
+	"html/template"
 	"net/http"
 	"sort"
 	"strconv"
@@ -16,6 +18,7 @@ import (
 	"github.com/emvi/logbuch"
 	"github.com/gorilla/schema"
 	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/models/view"
@@ -23,6 +26,7 @@ import (
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/services/imports"
 	"github.com/muety/wakapi/utils"
+	fsutils "github.com/muety/wakapi/utils/fs"
 )
 
 const criticalError = "a critical error has occurred, sorry"
@@ -38,6 +42,16 @@ type SettingsHandler struct {
 	projectLabelSrvc    services.IProjectLabelService
 	keyValueSrvc        services.IKeyValueService
 	mailSrvc            services.IMailService
+	totpSrvc            services.ITotpService
+	sessionSrvc         services.ISessionService
+	dataExportSrvc      services.IDataExportService
+	importSrvc          services.IImportService
+	wakapiMigrationSrvc services.IWakapiMigrationService
+	relayTargetSrvc     services.IRelayTargetService
+	relaySrvc           services.IRelayService
+	webhookSrvc         services.IWebhookService
+	notificationSrvc    services.INotificationService
+	reportSrvc          services.IReportService
 	httpClient          *http.Client
 	aggregationLocks    map[string]bool
 }
@@ -51,7 +65,12 @@ type actionResult struct {
 	values  *map[string]interface{}
 }
 
-const valueInviteCode = "invite_code"
+const (
+	valueInviteCode        = "invite_code"
+	valueTotpSecret        = "totp_secret"
+	valueTotpQrCode        = "totp_qr_code"
+	valueTotpRecoveryCodes = "totp_recovery_codes"
+)
 
 var credentialsDecoder = schema.NewDecoder()
 
@@ -65,6 +84,16 @@ func NewSettingsHandler(
 	projectLabelService services.IProjectLabelService,
 	keyValueService services.IKeyValueService,
 	mailService services.IMailService,
+	totpService services.ITotpService,
+	sessionService services.ISessionService,
+	dataExportService services.IDataExportService,
+	importService services.IImportService,
+	wakapiMigrationService services.IWakapiMigrationService,
+	relayTargetService services.IRelayTargetService,
+	relayService services.IRelayService,
+	webhookService services.IWebhookService,
+	notificationService services.INotificationService,
+	reportService services.IReportService,
 ) *SettingsHandler {
 	return &SettingsHandler{
 		config:              conf.Get(),
@@ -77,6 +106,16 @@ func NewSettingsHandler(
 		heartbeatSrvc:       heartbeatService,
 		keyValueSrvc:        keyValueService,
 		mailSrvc:            mailService,
+		totpSrvc:            totpService,
+		sessionSrvc:         sessionService,
+		dataExportSrvc:      dataExportService,
+		importSrvc:          importService,
+		wakapiMigrationSrvc: wakapiMigrationService,
+		relayTargetSrvc:     relayTargetService,
+		relaySrvc:           relayService,
+		webhookSrvc:         webhookService,
+		notificationSrvc:    notificationService,
+		reportSrvc:          reportService,
 		httpClient:          &http.Client{Timeout: 10 * time.Second},
 		aggregationLocks:    make(map[string]bool),
 	}
@@ -107,7 +146,7 @@ func (h *SettingsHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
 		loadTemplates()
 	}
 
-	if err := r.ParseForm(); err != nil {
+	if err := h.parseForm(r); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		templates[conf.SettingsTemplate].Execute(w, h.buildViewModel(r, w, nil).WithError("missing form values"))
 		return
@@ -150,8 +189,20 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionChangePassword
 	case "update_user":
 		return h.actionUpdateUser
+	case "setup_totp":
+		return h.actionSetupTotp
+	case "enable_totp":
+		return h.actionEnableTotp
+	case "disable_totp":
+		return h.actionDisableTotp
+	case "reset_user_totp":
+		return h.actionResetUserTotp
 	case "reset_apikey":
 		return h.actionResetApiKey
+	case "revoke_session":
+		return h.actionRevokeSession
+	case "revoke_sessions":
+		return h.actionRevokeSessions
 	case "delete_alias":
 		return h.actionDeleteAlias
 	case "add_alias":
@@ -160,20 +211,66 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionAddLabel
 	case "delete_label":
 		return h.actionDeleteLabel
+	case "update_label_color":
+		return h.actionUpdateLabelColor
+	case "delete_label_color":
+		return h.actionDeleteLabelColor
 	case "delete_mapping":
 		return h.actionDeleteLanguageMapping
 	case "add_mapping":
 		return h.actionAddLanguageMapping
+	case "delete_default_mapping":
+		return h.actionDeleteDefaultLanguageMapping
+	case "add_default_mapping":
+		return h.actionAddDefaultLanguageMapping
 	case "update_sharing":
 		return h.actionUpdateSharing
 	case "update_leaderboard":
 		return h.actionUpdateLeaderboard
 	case "toggle_wakatime":
 		return h.actionSetWakatimeApiKey
+	case "add_relay_target":
+		return h.actionAddRelayTarget
+	case "update_relay_target":
+		return h.actionUpdateRelayTarget
+	case "toggle_relay_target":
+		return h.actionToggleRelayTarget
+	case "delete_relay_target":
+		return h.actionDeleteRelayTarget
+	case "add_webhook":
+		return h.actionAddWebhook
+	case "toggle_webhook":
+		return h.actionToggleWebhook
+	case "delete_webhook":
+		return h.actionDeleteWebhook
+	case "add_notification_channel":
+		return h.actionAddNotificationChannel
+	case "toggle_notification_channel":
+		return h.actionToggleNotificationChannel
+	case "test_notification_channel":
+		return h.actionTestNotificationChannel
+	case "delete_notification_channel":
+		return h.actionDeleteNotificationChannel
+	case "add_report_subscription":
+		return h.actionAddReportSubscription
+	case "toggle_report_subscription":
+		return h.actionToggleReportSubscription
+	case "send_report_subscription":
+		return h.actionSendReportSubscription
+	case "delete_report_subscription":
+		return h.actionDeleteReportSubscription
 	case "import_wakatime":
 		return h.actionImportWakatime
+	case "import_wakatime_file":
+		return h.actionImportWakatimeFile
+	case "import_activitywatch":
+		return h.actionImportActivityWatch
+	case "import_wakapi":
+		return h.actionImportWakapi
 	case "regenerate_summaries":
 		return h.actionRegenerateSummaries
+	case "export_data":
+		return h.actionExportData
 	case "clear_data":
 		return h.actionClearData
 	case "delete_account":
@@ -182,6 +279,16 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionGenerateInvite
 	case "update_unknown_projects":
 		return h.actionUpdateExcludeUnknownProjects
+	case "update_tickets":
+		return h.actionUpdateTickets
+	case "update_work_sessions":
+		return h.actionUpdateWorkSessions
+	case "update_daily_goal":
+		return h.actionUpdateDailyGoal
+	case "reset_calendar_token":
+		return h.actionResetCalendarToken
+	case "disable_calendar_token":
+		return h.actionDisableCalendarToken
 	}
 	return nil
 }
@@ -209,6 +316,8 @@ func (h *SettingsHandler) actionUpdateUser(w http.ResponseWriter, r *http.Reques
 		return actionResult{http.StatusBadRequest, "", "cannot unset email while subscription is active", nil}
 	}
 
+	locationChanged := user.Location != payload.Location
+
 	user.Email = payload.Email
 	user.Location = payload.Location
 	user.ReportsWeekly = payload.ReportsWeekly
@@ -218,6 +327,10 @@ func (h *SettingsHandler) actionUpdateUser(w http.ResponseWriter, r *http.Reques
 		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
 	}
 
+	if locationChanged {
+		h.rescheduleReportSubscriptions(r, user)
+	}
+
 	return actionResult{http.StatusOK, "user updated successfully", "", nil}
 }
 
@@ -255,17 +368,94 @@ func (h *SettingsHandler) actionChangePassword(w http.ResponseWriter, r *http.Re
 		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
 	}
 
-	login := &models.Login{
-		Username: user.ID,
-		Password: user.Password,
+	// log out all other devices, but keep the current one
+	if err := h.sessionSrvc.DeleteByUser(user, h.currentSessionId(r)); err != nil {
+		conf.Log().Request(r).Error("failed to revoke sessions for user '%s' - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	return actionResult{http.StatusOK, "password was updated successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionSetupTotp(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	secret, qrCode, err := h.totpSrvc.Setup(user)
+	if err != nil {
+		if errors.Is(err, services.ErrTotpAlreadyInUse) {
+			return actionResult{http.StatusConflict, "", err.Error(), nil}
+		}
+		conf.Log().Request(r).Error("failed to set up totp for user %s - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	return actionResult{http.StatusOK, "", "", &map[string]interface{}{
+		valueTotpSecret: secret,
+		valueTotpQrCode: qrCode,
+	}}
+}
+
+func (h *SettingsHandler) actionEnableTotp(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
 	}
-	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, login.Username)
+
+	user := middlewares.GetPrincipal(r)
+	recoveryCodes, err := h.totpSrvc.Enable(user, r.PostFormValue("code"))
 	if err != nil {
+		if errors.Is(err, services.ErrTotpInvalidCode) || errors.Is(err, services.ErrTotpNotSetUp) || errors.Is(err, services.ErrTotpAlreadyInUse) {
+			return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+		}
+		conf.Log().Request(r).Error("failed to enable totp for user %s - %v", user.ID, err)
 		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
 	}
 
-	http.SetCookie(w, h.config.CreateCookie(models.AuthCookieKey, encoded))
-	return actionResult{http.StatusOK, "password was updated successfully", "", nil}
+	return actionResult{http.StatusOK, "two-factor authentication enabled successfully", "", &map[string]interface{}{
+		valueTotpRecoveryCodes: recoveryCodes,
+	}}
+}
+
+func (h *SettingsHandler) actionDisableTotp(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user.HasTotp() {
+		if valid, err := h.totpSrvc.Verify(user, r.PostFormValue("code")); err != nil || !valid {
+			return actionResult{http.StatusUnauthorized, "", "invalid code", nil}
+		}
+	}
+
+	if err := h.totpSrvc.Disable(user); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "two-factor authentication disabled", "", nil}
+}
+
+func (h *SettingsHandler) actionResetUserTotp(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if user := middlewares.GetPrincipal(r); !user.IsAdmin {
+		return actionResult{http.StatusForbidden, "", "not allowed to reset two-factor authentication", nil}
+	}
+
+	targetUser, err := h.userSrvc.GetUserById(r.PostFormValue("username"))
+	if err != nil {
+		return actionResult{http.StatusNotFound, "", "user not found", nil}
+	}
+
+	if err := h.totpSrvc.Disable(targetUser); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	conf.Log().Request(r).Info("admin reset two-factor authentication for user %s", targetUser.ID)
+	return actionResult{http.StatusOK, fmt.Sprintf("two-factor authentication reset for user '%s'", targetUser.ID), "", nil}
 }
 
 func (h *SettingsHandler) actionResetApiKey(w http.ResponseWriter, r *http.Request) actionResult {
@@ -278,10 +468,53 @@ func (h *SettingsHandler) actionResetApiKey(w http.ResponseWriter, r *http.Reque
 		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
 	}
 
+	if err := h.sessionSrvc.DeleteByUser(user, h.currentSessionId(r)); err != nil {
+		conf.Log().Request(r).Error("failed to revoke sessions for user '%s' - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
 	msg := fmt.Sprintf("your new api key is: %s", user.ApiKey)
 	return actionResult{http.StatusOK, msg, "", nil}
 }
 
+func (h *SettingsHandler) actionRevokeSession(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	sessionId := r.PostFormValue("session_id")
+	if sessionId == "" {
+		return actionResult{http.StatusBadRequest, "", "missing parameters", nil}
+	}
+
+	if err := h.sessionSrvc.Delete(user, sessionId); err != nil {
+		return actionResult{http.StatusNotFound, "", "session not found", nil}
+	}
+
+	if sessionId == h.currentSessionId(r) {
+		http.SetCookie(w, h.config.GetClearCookie(models.AuthCookieKey))
+		http.Redirect(w, r, fmt.Sprintf("%s/login", h.config.Server.BasePath), http.StatusFound)
+		return actionResult{-1, "", "", nil}
+	}
+
+	return actionResult{http.StatusOK, "session was revoked", "", nil}
+}
+
+func (h *SettingsHandler) actionRevokeSessions(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if err := h.sessionSrvc.DeleteByUser(user, h.currentSessionId(r)); err != nil {
+		conf.Log().Request(r).Error("failed to revoke sessions for user '%s' - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	return actionResult{http.StatusOK, "all other sessions were revoked", "", nil}
+}
+
 func (h *SettingsHandler) actionUpdateLeaderboard(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -335,6 +568,113 @@ func (h *SettingsHandler) actionUpdateExcludeUnknownProjects(w http.ResponseWrit
 	return actionResult{http.StatusOK, "regenerating summaries, this might take a while", "", nil}
 }
 
+func (h *SettingsHandler) actionUpdateTickets(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	defer h.userSrvc.FlushCache()
+
+	if h.isAggregationLocked(user.ID) {
+		return actionResult{http.StatusConflict, "", "summary regeneration already in progress, please wait", nil}
+	}
+
+	pattern := strings.TrimSpace(r.PostFormValue("ticket_pattern"))
+	fromEntity := r.PostFormValue("ticket_from_entity") == "true"
+	if pattern != "" && !models.ValidateTicketPattern(pattern) {
+		return actionResult{http.StatusBadRequest, "", "invalid ticket pattern", nil}
+	}
+	if pattern == user.TicketPattern && fromEntity == user.TicketFromEntity {
+		return actionResult{http.StatusOK, "settings updated", "", nil}
+	}
+
+	user.TicketPattern = pattern
+	user.TicketFromEntity = fromEntity
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	// tickets are persisted as part of summaries
+	go func(user *models.User) {
+		h.toggleAggregationLock(user.ID, true)
+		defer h.toggleAggregationLock(user.ID, false)
+		if err := h.regenerateSummaries(user); err != nil {
+			conf.Log().Request(r).Error("failed to regenerate summaries for user '%s' - %v", user.ID, err)
+		}
+	}(user)
+
+	return actionResult{http.StatusOK, "regenerating summaries, this might take a while", "", nil}
+}
+
+func (h *SettingsHandler) actionUpdateDailyGoal(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	defer h.userSrvc.FlushCache()
+
+	goal, err := strconv.Atoi(r.PostFormValue("daily_goal_minutes"))
+	if err != nil || goal < 0 || goal > models.MaxDailyGoalMinutes {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("daily goal must be between 0 and %d minutes", models.MaxDailyGoalMinutes), nil}
+	}
+
+	user.DailyGoalMinutes = goal
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "settings updated", "", nil}
+}
+
+func (h *SettingsHandler) actionUpdateWorkSessions(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	defer h.userSrvc.FlushCache()
+
+	gap, err := strconv.Atoi(r.PostFormValue("session_gap_minutes"))
+	if err != nil || gap < 1 || gap > models.MaxSessionGapMinutes {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("session gap must be between 1 and %d minutes", models.MaxSessionGapMinutes), nil}
+	}
+
+	user.SessionGapMinutes = gap
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "settings updated", "", nil}
+}
+
+func (h *SettingsHandler) actionResetCalendarToken(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if _, err := h.userSrvc.ResetCalendarToken(user); err != nil {
+		conf.Log().Request(r).Error("failed to reset calendar token for user '%s' - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "a new calendar url was generated, previous ones stopped working", "", nil}
+}
+
+func (h *SettingsHandler) actionDisableCalendarToken(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	defer h.userSrvc.FlushCache()
+
+	user.CalendarToken = ""
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "calendar subscription disabled", "", nil}
+}
+
 func (h *SettingsHandler) actionUpdateSharing(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -424,7 +764,7 @@ func (h *SettingsHandler) actionAddLabel(w http.ResponseWriter, r *http.Request)
 		label := &models.ProjectLabel{
 			UserID:     user.ID,
 			ProjectKey: key,
-			Label:      r.PostFormValue("value"),
+			Label:      models.NormalizeLabel(r.PostFormValue("value")),
 		}
 		labels = append(labels, label)
 	}
@@ -467,6 +807,39 @@ func (h *SettingsHandler) actionDeleteLabel(w http.ResponseWriter, r *http.Reque
 	return actionResult{http.StatusNotFound, "", "label not found", nil}
 }
 
+func (h *SettingsHandler) actionUpdateLabelColor(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	color := &models.LabelColor{
+		UserID: user.ID,
+		Label:  models.NormalizeLabel(r.PostFormValue("key")),
+		Color:  r.PostFormValue("color"),
+	}
+
+	if !color.IsValid() {
+		return actionResult{http.StatusBadRequest, "", "invalid label color", nil}
+	}
+	if _, err := h.projectLabelSrvc.SetColor(color); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not update label color", nil}
+	}
+	return actionResult{http.StatusOK, "label color updated successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteLabelColor(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if err := h.projectLabelSrvc.DeleteColor(user.ID, r.PostFormValue("key")); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not reset label color", nil}
+	}
+	return actionResult{http.StatusOK, "label color reset successfully", "", nil}
+}
+
 func (h *SettingsHandler) actionDeleteLanguageMapping(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -497,17 +870,17 @@ func (h *SettingsHandler) actionAddLanguageMapping(w http.ResponseWriter, r *htt
 		loadTemplates()
 	}
 	user := middlewares.GetPrincipal(r)
-	extension := r.PostFormValue("extension")
-	language := r.PostFormValue("language")
-
-	if extension[0] == '.' {
-		extension = extension[1:]
-	}
+	mappingType, pattern := parseLanguageMappingPattern(r.PostFormValue("type"), r.PostFormValue("extension"))
 
 	mapping := &models.LanguageMapping{
 		UserID:    user.ID,
-		Extension: extension,
-		Language:  language,
+		Extension: pattern,
+		Type:      mappingType,
+		Language:  r.PostFormValue("language"),
+	}
+
+	if !mapping.IsValid() {
+		return actionResult{http.StatusBadRequest, "", "invalid mapping", nil}
 	}
 
 	if _, err := h.languageMappingSrvc.Create(mapping); err != nil {
@@ -517,6 +890,56 @@ func (h *SettingsHandler) actionAddLanguageMapping(w http.ResponseWriter, r *htt
 	return actionResult{http.StatusOK, "mapping added successfully", "", nil}
 }
 
+func (h *SettingsHandler) actionDeleteDefaultLanguageMapping(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsAdmin {
+		return actionResult{http.StatusForbidden, "", "not allowed to delete default mapping", nil}
+	}
+
+	id, err := strconv.Atoi(r.PostFormValue("mapping_id"))
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "could not delete mapping", nil}
+	}
+
+	if err := h.languageMappingSrvc.DeleteDefault(uint(id)); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete mapping", nil}
+	}
+
+	return actionResult{http.StatusOK, "default mapping deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionAddDefaultLanguageMapping(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsAdmin {
+		return actionResult{http.StatusForbidden, "", "not allowed to add default mapping", nil}
+	}
+
+	mappingType, pattern := parseLanguageMappingPattern(r.PostFormValue("type"), r.PostFormValue("extension"))
+	mapping := &models.DefaultLanguageMapping{
+		Extension: pattern,
+		Type:      mappingType,
+		Language:  r.PostFormValue("language"),
+	}
+
+	if !mapping.IsValid() {
+		return actionResult{http.StatusBadRequest, "", "invalid mapping", nil}
+	}
+
+	if _, err := h.languageMappingSrvc.CreateDefault(mapping); err != nil {
+		return actionResult{http.StatusConflict, "", "mapping already exists", nil}
+	}
+
+	return actionResult{http.StatusOK, "default mapping added successfully", "", nil}
+}
+
 func (h *SettingsHandler) actionSetWakatimeApiKey(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -541,124 +964,448 @@ func (h *SettingsHandler) actionSetWakatimeApiKey(w http.ResponseWriter, r *http
 	return actionResult{http.StatusOK, "Wakatime API Key updated successfully", "", nil}
 }
 
-func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Request) actionResult {
+func (h *SettingsHandler) actionAddRelayTarget(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
 	}
 
-	if !h.config.App.ImportEnabled {
-		return actionResult{http.StatusForbidden, "", "imports are disabled on this server", nil}
-	}
-
 	user := middlewares.GetPrincipal(r)
-	if user.WakatimeApiKey == "" {
-		return actionResult{http.StatusForbidden, "", "not connected to wakatime", nil}
+	target := &models.RelayTarget{
+		UserID:     user.ID,
+		Name:       strings.TrimSpace(r.PostFormValue("name")),
+		ApiUrl:     strings.TrimSpace(r.PostFormValue("api_url")),
+		ApiKey:     strings.TrimSpace(r.PostFormValue("api_key")),
+		Enabled:    true,
+		FilterMode: r.PostFormValue("filter_mode"),
+		Projects:   r.PostFormValue("projects"),
+		Labels:     r.PostFormValue("labels"),
+	}
+	if target.ApiUrl == "" {
+		target.ApiUrl = conf.WakatimeApiUrl
 	}
 
-	useLegacyImporter, _ := strconv.ParseBool(r.PostFormValue("use_legacy_importer"))
-	kvKeyLastImport := fmt.Sprintf("%s_%s", conf.KeyLastImport, user.ID)
-	kvKeyLastImportSuccess := fmt.Sprintf("%s_%s", conf.KeyLastImportSuccess, user.ID)
-
-	if !h.config.IsDev() {
-		lastImport, _ := time.Parse(time.RFC822, h.keyValueSrvc.MustGetString(kvKeyLastImport).Value)
-		if time.Now().Sub(lastImport) < time.Duration(h.config.App.ImportBackoffMin)*time.Minute {
-			return actionResult{
-				http.StatusTooManyRequests,
-				"",
-				fmt.Sprintf("Too many data imports - you are only allowed to request an import every %d minutes.", h.config.App.ImportBackoffMin),
-				nil,
-			}
-		}
+	if !target.IsValid() {
+		return actionResult{http.StatusBadRequest, "", "invalid relay target", nil}
+	}
+	if !h.validateWakatimeKey(target.ApiKey, target.BaseUrl()) {
+		return actionResult{http.StatusBadRequest, "", "failed to connect to relay target, API key or endpoint URL invalid?", nil}
+	}
 
-		lastImportSuccess, _ := time.Parse(time.RFC822, h.keyValueSrvc.MustGetString(kvKeyLastImportSuccess).Value)
-		if time.Now().Sub(lastImportSuccess) < time.Duration(h.config.App.ImportMaxRate)*time.Hour {
-			return actionResult{
-				http.StatusTooManyRequests,
-				"",
-				fmt.Sprintf("Too many data imports - last import ran less than %d hours ago, please wait.", h.config.App.ImportMaxRate),
-				nil,
-			}
-		}
+	if _, err := h.relayTargetSrvc.Create(target); err != nil {
+		conf.Log().Request(r).Error("failed to create relay target for user %s - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
 	}
+	return actionResult{http.StatusOK, "relay target added successfully", "", nil}
+}
 
-	go func(user *models.User) {
-		start := time.Now()
-		importer := imports.NewWakatimeImporter(user.WakatimeApiKey, useLegacyImporter)
+func (h *SettingsHandler) actionUpdateRelayTarget(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
 
-		countBefore, _ := h.heartbeatSrvc.CountByUser(user)
+	target, result := h.getOwnRelayTarget(r)
+	if target == nil {
+		return *result
+	}
 
-		var (
-			stream      <-chan *models.Heartbeat
-			importError error
-		)
-		if latest, err := h.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginWakatime, user); latest == nil || err != nil {
-			stream, importError = importer.ImportAll(user)
-		} else {
-			// if an import has happened before, only import heartbeats newer than the latest of the last import
-			stream, importError = importer.Import(user, latest.Time.T(), time.Now())
-		}
-		if importError != nil {
-			conf.Log().Error("wakatime import for user '%s' failed - %v", user.ID, importError)
-			return
-		}
+	target.FilterMode = r.PostFormValue("filter_mode")
+	target.Projects = r.PostFormValue("projects")
+	target.Labels = r.PostFormValue("labels")
 
-		// import successful
-		h.keyValueSrvc.PutString(&models.KeyStringValue{
-			Key:   kvKeyLastImportSuccess,
-			Value: time.Now().Format(time.RFC822),
-		})
+	if !target.IsValid() {
+		return actionResult{http.StatusBadRequest, "", "invalid relay target", nil}
+	}
+	if _, err := h.relayTargetSrvc.Update(target); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "relay target updated successfully", "", nil}
+}
 
-		count := 0
-		batch := make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
+func (h *SettingsHandler) actionToggleRelayTarget(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
 
-		insert := func(batch []*models.Heartbeat) {
-			if err := h.heartbeatSrvc.InsertBatch(batch); err != nil {
-				logbuch.Warn("failed to insert imported heartbeat, already existing? - %v", err)
-			}
-		}
+	target, result := h.getOwnRelayTarget(r)
+	if target == nil {
+		return *result
+	}
 
-		for hb := range stream {
-			count++
-			batch = append(batch, hb)
+	target.Enabled = !target.Enabled
+	if _, err := h.relayTargetSrvc.Update(target); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, condition.TernaryOperator[bool, string](target.Enabled, "relay target enabled", "relay target disabled"), "", nil}
+}
 
-			if len(batch) == h.config.App.ImportBatchSize {
-				insert(batch)
-				batch = make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
-			}
-		}
-		if len(batch) > 0 {
-			insert(batch)
-		}
+func (h *SettingsHandler) actionDeleteRelayTarget(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
 
-		countAfter, _ := h.heartbeatSrvc.CountByUser(user)
-		logbuch.Info("downloaded %d heartbeats for user '%s' (%d actually imported)", count, user.ID, countAfter-countBefore)
+	target, result := h.getOwnRelayTarget(r)
+	if target == nil {
+		return *result
+	}
 
-		h.regenerateSummaries(user)
+	if err := h.relayTargetSrvc.Delete(target); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete relay target", nil}
+	}
+	return actionResult{http.StatusOK, "relay target deleted successfully", "", nil}
+}
 
-		if !user.HasData {
-			user.HasData = true
-			if _, err := h.userSrvc.Update(user); err != nil {
-				conf.Log().Request(r).Error("failed to set 'has_data' flag for user %s - %v", user.ID, err)
-			}
-		}
+func (h *SettingsHandler) actionAddWebhook(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
 
-		if user.Email != "" {
-			if err := h.mailSrvc.SendImportNotification(user, time.Now().Sub(start), int(countAfter-countBefore)); err != nil {
-				conf.Log().Request(r).Error("failed to send import notification mail to %s - %v", user.ID, err)
-			} else {
-				logbuch.Info("sent import notification mail to %s", user.ID)
-			}
+	user := middlewares.GetPrincipal(r)
+	webhook := &models.Webhook{
+		UserID:   user.ID,
+		Name:     strings.TrimSpace(r.PostFormValue("name")),
+		Url:      strings.TrimSpace(r.PostFormValue("url")),
+		Events:   strings.Join(r.PostForm["events"], ","),
+		Enabled:  true,
+		IsGlobal: user.IsAdmin && r.PostFormValue("is_global") == "true",
+	}
+
+	if _, err := h.webhookSrvc.Create(webhook); err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid webhook, please specify a valid url and at least one event", nil}
+	}
+	return actionResult{http.StatusOK, "webhook added successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionToggleWebhook(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	webhook, result := h.getOwnWebhook(r)
+	if webhook == nil {
+		return *result
+	}
+
+	webhook.Enabled = !webhook.Enabled
+	if _, err := h.webhookSrvc.Update(webhook); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, condition.TernaryOperator[bool, string](webhook.Enabled, "webhook enabled", "webhook disabled"), "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteWebhook(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	webhook, result := h.getOwnWebhook(r)
+	if webhook == nil {
+		return *result
+	}
+
+	if err := h.webhookSrvc.Delete(webhook); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete webhook", nil}
+	}
+	return actionResult{http.StatusOK, "webhook deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionAddNotificationChannel(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	channel := &models.NotificationChannel{
+		UserID:  user.ID,
+		Name:    strings.TrimSpace(r.PostFormValue("name")),
+		Type:    r.PostFormValue("type"),
+		Url:     strings.TrimSpace(r.PostFormValue("url")),
+		RoomId:  strings.TrimSpace(r.PostFormValue("room_id")),
+		Token:   strings.TrimSpace(r.PostFormValue("token")),
+		Kinds:   strings.Join(r.PostForm["kinds"], ","),
+		Enabled: true,
+	}
+
+	if _, err := h.notificationSrvc.Create(channel); err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid notification channel, please specify a valid url and at least one kind of notification (matrix also requires room id and access token)", nil}
+	}
+	return actionResult{http.StatusOK, "notification channel added successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionToggleNotificationChannel(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	channel, result := h.getOwnNotificationChannel(r)
+	if channel == nil {
+		return *result
+	}
+
+	channel.Enabled = !channel.Enabled
+	if _, err := h.notificationSrvc.Update(channel); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, condition.TernaryOperator[bool, string](channel.Enabled, "notification channel enabled", "notification channel disabled"), "", nil}
+}
+
+func (h *SettingsHandler) actionTestNotificationChannel(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	channel, result := h.getOwnNotificationChannel(r)
+	if channel == nil {
+		return *result
+	}
+
+	if err := h.notificationSrvc.SendTest(channel); err != nil {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("failed to send test notification: %v", err), nil}
+	}
+	return actionResult{http.StatusOK, "test notification sent successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteNotificationChannel(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	channel, result := h.getOwnNotificationChannel(r)
+	if channel == nil {
+		return *result
+	}
+
+	if err := h.notificationSrvc.Delete(channel); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete notification channel", nil}
+	}
+	return actionResult{http.StatusOK, "notification channel deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionAddReportSubscription(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	weekday, _ := strconv.Atoi(r.PostFormValue("weekday"))
+	hour, err := strconv.Atoi(r.PostFormValue("hour"))
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid delivery time", nil}
+	}
+
+	subscription := &models.ReportSubscription{
+		User:     user,
+		UserID:   user.ID,
+		Period:   r.PostFormValue("period"),
+		Weekday:  weekday,
+		Hour:     hour,
+		Sections: strings.Join(r.PostForm["sections"], ","),
+		Projects: strings.Join(r.PostForm["projects"], ","),
+		Enabled:  true,
+	}
+
+	if _, err := h.reportSrvc.CreateSubscription(subscription); err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid report subscription, please specify a period, delivery time and at least one section", nil}
+	}
+	return actionResult{http.StatusOK, "report subscription added successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionToggleReportSubscription(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	subscription, result := h.getOwnReportSubscription(r)
+	if subscription == nil {
+		return *result
+	}
+
+	subscription.Enabled = !subscription.Enabled
+	if _, err := h.reportSrvc.UpdateSubscription(subscription); err != nil {
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, condition.TernaryOperator[bool, string](subscription.Enabled, "report subscription enabled", "report subscription disabled"), "", nil}
+}
+
+func (h *SettingsHandler) actionSendReportSubscription(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	subscription, result := h.getOwnReportSubscription(r)
+	if subscription == nil {
+		return *result
+	}
+
+	if err := h.reportSrvc.QueueSubscriptionReport(subscription); err != nil {
+		if errors.Is(err, services.ErrReportRateLimited) {
+			return actionResult{http.StatusTooManyRequests, "", err.Error(), nil}
 		}
-	}(user)
+		conf.Log().Request(r).Error("failed to queue report for user '%s' - %v", subscription.UserID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
 
-	h.keyValueSrvc.PutString(&models.KeyStringValue{
-		Key:   kvKeyLastImport,
-		Value: time.Now().Format(time.RFC822),
-	})
+	return actionResult{http.StatusAccepted, "report is being generated and will be sent to you shortly", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteReportSubscription(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	subscription, result := h.getOwnReportSubscription(r)
+	if subscription == nil {
+		return *result
+	}
+
+	if err := h.reportSrvc.DeleteSubscription(subscription); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete report subscription", nil}
+	}
+	return actionResult{http.StatusOK, "report subscription deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.ImportEnabled {
+		return actionResult{http.StatusForbidden, "", "imports are disabled on this server", nil}
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user.WakatimeApiKey == "" {
+		return actionResult{http.StatusForbidden, "", "not connected to wakatime", nil}
+	}
+
+	useLegacyImporter, _ := strconv.ParseBool(r.PostFormValue("use_legacy_importer"))
+
+	if err := h.importSrvc.CheckRateLimit(user); err != nil {
+		return actionResult{http.StatusTooManyRequests, "", err.Error(), nil}
+	}
+
+	job := models.NewImportJob(user, models.ImportSourceWakatime).WithParams(&models.ImportJobParams{ForceLegacy: useLegacyImporter})
+	if latest, err := h.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginWakatime, user); latest != nil && err == nil {
+		// if an import has happened before, only import heartbeats newer than the latest of the last import
+		job.WithRange(latest.Time.T(), time.Now())
+	}
+
+	if err := h.importSrvc.Submit(job); err != nil {
+		conf.Log().Request(r).Error("wakatime import for user '%s' failed - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
 
 	return actionResult{http.StatusAccepted, "Import started. This will take several minutes. Please check back later.", "", nil}
 }
 
+func (h *SettingsHandler) actionImportWakatimeFile(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.ImportEnabled {
+		return actionResult{http.StatusForbidden, "", "imports are disabled on this server", nil}
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	upload, _, err := r.FormFile("file")
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "missing export file", nil}
+	}
+	defer upload.Close()
+
+	if err := h.importSrvc.CheckRateLimit(user); err != nil {
+		return actionResult{http.StatusTooManyRequests, "", err.Error(), nil}
+	}
+
+	// uploaded files are cleaned up once the request is finished, so keep a copy for the background import
+	path, err := fsutils.StashFile(upload)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to store uploaded wakatime export of user '%s' - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	job := models.NewImportJob(user, models.ImportSourceWakatimeFile).WithParams(&models.ImportJobParams{FilePath: path, DeleteFile: true})
+	if err := h.importSrvc.Submit(job); err != nil {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("failed to read export file - %v", err), nil}
+	}
+
+	return actionResult{http.StatusAccepted, "Import started. This might take a few minutes. Please check back later.", "", nil}
+}
+
+func (h *SettingsHandler) actionImportActivityWatch(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.ImportEnabled {
+		return actionResult{http.StatusForbidden, "", "imports are disabled on this server", nil}
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	upload, _, err := r.FormFile("file")
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "missing export file", nil}
+	}
+	defer upload.Close()
+
+	// buckets can be selected either by kind (editor, window, web) or by their id
+	buckets := r.PostForm["bucket_kinds"]
+	for _, id := range strings.Split(r.PostFormValue("bucket_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			buckets = append(buckets, id)
+		}
+	}
+	if len(buckets) == 0 {
+		return actionResult{http.StatusBadRequest, "", "no buckets selected", nil}
+	}
+
+	if err := h.importSrvc.CheckRateLimit(user); err != nil {
+		return actionResult{http.StatusTooManyRequests, "", err.Error(), nil}
+	}
+
+	path, err := fsutils.StashFile(upload)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to store uploaded activitywatch export of user '%s' - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	job := models.NewImportJob(user, models.ImportSourceActivityWatch).WithParams(&models.ImportJobParams{FilePath: path, DeleteFile: true, Buckets: buckets})
+	if err := h.importSrvc.Submit(job); err != nil {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("failed to read export file - %v", err), nil}
+	}
+
+	return actionResult{http.StatusAccepted, "Import started. This might take a few minutes. Please check back later.", "", nil}
+}
+
+func (h *SettingsHandler) actionImportWakapi(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.ImportEnabled {
+		return actionResult{http.StatusForbidden, "", "imports are disabled on this server", nil}
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	baseUrl, apiKey := strings.TrimSpace(r.PostFormValue("wakapi_url")), strings.TrimSpace(r.PostFormValue("wakapi_api_key"))
+	if baseUrl == "" || apiKey == "" {
+		return actionResult{http.StatusBadRequest, "", "missing url or api key", nil}
+	}
+
+	if err := h.importSrvc.CheckRateLimit(user); err != nil {
+		return actionResult{http.StatusTooManyRequests, "", err.Error(), nil}
+	}
+
+	if err := h.wakapiMigrationSrvc.MigrateAsync(user, baseUrl, apiKey); err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+
+	return actionResult{http.StatusAccepted, "Migration started. This might take a while, depending on how much data you have. Please check back later.", "", nil}
+}
+
 func (h *SettingsHandler) actionRegenerateSummaries(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -681,6 +1428,26 @@ func (h *SettingsHandler) actionRegenerateSummaries(w http.ResponseWriter, r *ht
 	return actionResult{http.StatusAccepted, "summaries are being regenerated - this may take a up to a couple of minutes, please come back later", "", nil}
 }
 
+func (h *SettingsHandler) actionExportData(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if err := h.dataExportSrvc.RequestExport(user); err != nil {
+		if errors.Is(err, services.ErrDataExportNoMail) {
+			return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+		}
+		if errors.Is(err, services.ErrDataExportTooFrequent) {
+			return actionResult{http.StatusTooManyRequests, "", err.Error(), nil}
+		}
+		conf.Log().Request(r).Error("failed to request data export for user '%s' - %v", user.ID, err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	return actionResult{http.StatusAccepted, "Your data export is being prepared, you will receive an e-mail with a download link once it's ready.", "", nil}
+}
+
 func (h *SettingsHandler) actionClearData(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -781,6 +1548,90 @@ func (h *SettingsHandler) validateWakatimeKey(apiKey string, baseUrl string) boo
 	return true
 }
 
+func (h *SettingsHandler) getOwnRelayTarget(r *http.Request) (*models.RelayTarget, *actionResult) {
+	user := middlewares.GetPrincipal(r)
+
+	id, err := strconv.ParseUint(r.PostFormValue("target_id"), 10, 32)
+	if err != nil {
+		return nil, &actionResult{http.StatusBadRequest, "", "invalid relay target id", nil}
+	}
+
+	target, err := h.relayTargetSrvc.GetById(uint(id))
+	if err != nil || target.UserID != user.ID {
+		return nil, &actionResult{http.StatusNotFound, "", "relay target not found", nil}
+	}
+	return target, nil
+}
+
+func (h *SettingsHandler) getOwnWebhook(r *http.Request) (*models.Webhook, *actionResult) {
+	user := middlewares.GetPrincipal(r)
+
+	id, err := strconv.ParseUint(r.PostFormValue("webhook_id"), 10, 32)
+	if err != nil {
+		return nil, &actionResult{http.StatusBadRequest, "", "invalid webhook id", nil}
+	}
+
+	webhook, err := h.webhookSrvc.GetById(uint(id))
+	if err != nil || webhook.UserID != user.ID {
+		return nil, &actionResult{http.StatusNotFound, "", "webhook not found", nil}
+	}
+	return webhook, nil
+}
+
+func (h *SettingsHandler) getOwnNotificationChannel(r *http.Request) (*models.NotificationChannel, *actionResult) {
+	user := middlewares.GetPrincipal(r)
+
+	id, err := strconv.ParseUint(r.PostFormValue("channel_id"), 10, 32)
+	if err != nil {
+		return nil, &actionResult{http.StatusBadRequest, "", "invalid notification channel id", nil}
+	}
+
+	channel, err := h.notificationSrvc.GetById(uint(id))
+	if err != nil || channel.UserID != user.ID {
+		return nil, &actionResult{http.StatusNotFound, "", "notification channel not found", nil}
+	}
+	return channel, nil
+}
+
+// rescheduleReportSubscriptions updates the next delivery time of the user's report subscriptions, which depends on their time zone
+func (h *SettingsHandler) rescheduleReportSubscriptions(r *http.Request, user *models.User) {
+	subscriptions, err := h.reportSrvc.GetSubscriptionsByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch report subscriptions for user '%s' - %v", user.ID, err)
+		return
+	}
+	for _, subscription := range subscriptions {
+		subscription.User = user
+		if _, err := h.reportSrvc.UpdateSubscription(subscription); err != nil {
+			conf.Log().Request(r).Error("failed to reschedule report subscription %d - %v", subscription.ID, err)
+		}
+	}
+}
+
+func (h *SettingsHandler) getOwnReportSubscription(r *http.Request) (*models.ReportSubscription, *actionResult) {
+	user := middlewares.GetPrincipal(r)
+
+	id, err := strconv.ParseUint(r.PostFormValue("subscription_id"), 10, 32)
+	if err != nil {
+		return nil, &actionResult{http.StatusBadRequest, "", "invalid report subscription id", nil}
+	}
+
+	subscription, err := h.reportSrvc.GetSubscriptionById(uint(id))
+	if err != nil || subscription.UserID != user.ID {
+		return nil, &actionResult{http.StatusNotFound, "", "report subscription not found", nil}
+	}
+	subscription.User = user
+	return subscription, nil
+}
+
+// parseForm additionally supports multipart forms, as required for file uploads
+func (h *SettingsHandler) parseForm(r *http.Request) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.ParseMultipartForm(32 << 20)
+	}
+	return r.ParseForm()
+}
+
 func (h *SettingsHandler) regenerateSummaries(user *models.User) error {
 	logbuch.Info("clearing summaries for user '%s'", user.ID)
 	if err := h.summarySrvc.DeleteByUser(user.ID); err != nil {
@@ -802,6 +1653,11 @@ func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter,
 	// mappings
 	mappings, _ := h.languageMappingSrvc.GetByUser(user.ID)
 
+	var defaultMappings []*models.DefaultLanguageMapping
+	if user.IsAdmin {
+		defaultMappings, _ = h.languageMappingSrvc.GetDefaults()
+	}
+
 	// aliases
 	aliases, err := h.aliasSrvc.GetByUser(user.ID)
 	if err != nil {
@@ -850,10 +1706,17 @@ func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter,
 		}
 	}
 
+	labelColors, err := h.projectLabelSrvc.GetColorsByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching label colors - %v", err)
+		labelColors = map[string]string{}
+	}
+
 	combinedLabels := make([]*view.SettingsVMCombinedLabel, 0)
 	for _, l := range labelMap {
 		cl := &view.SettingsVMCombinedLabel{
 			Key:    l[0].Label,
+			Color:  labelColors[l[0].Label],
 			Values: make([]string, len(l)),
 		}
 		for i, l1 := range l {
@@ -895,25 +1758,114 @@ func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter,
 	inviteCode := getVal[string](args, valueInviteCode, "")
 	inviteLink := condition.TernaryOperator[bool, string](inviteCode == "", "", fmt.Sprintf("%s/signup?invite=%s", h.config.Server.GetPublicUrl(), inviteCode))
 
+	// two-factor authentication
+	totpRecoveryCodes := getVal[[]string](args, valueTotpRecoveryCodes, nil)
+	totpQrCode := template.URL(getVal[string](args, valueTotpQrCode, "")) // always a data url of a locally generated png image
+	totpSecret := getVal[string](args, valueTotpSecret, "")
+
+	// sessions
+	sessions, err := h.sessionSrvc.GetByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching sessions - %v", err)
+	}
+
+	// import history
+	importJobs, err := h.importSrvc.GetJobsByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching import jobs - %v", err)
+	}
+
+	// relay targets
+	relayTargets, err := h.relayTargetSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching relay targets - %v", err)
+	}
+	relayBacklog, err := h.relaySrvc.GetBacklog(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching relay backlog - %v", err)
+	}
+
+	// webhooks
+	var webhooks []*view.SettingsVMWebhook
+	if userWebhooks, err := h.webhookSrvc.GetByUser(user.ID); err == nil {
+		webhooks = make([]*view.SettingsVMWebhook, len(userWebhooks))
+		for i, webhook := range userWebhooks {
+			deliveries, err := h.webhookSrvc.GetDeliveries(webhook)
+			if err != nil {
+				conf.Log().Request(r).Error("error while fetching deliveries of webhook %d - %v", webhook.ID, err)
+			}
+			webhooks[i] = &view.SettingsVMWebhook{Webhook: webhook, Deliveries: deliveries}
+		}
+	} else {
+		conf.Log().Request(r).Error("error while fetching webhooks - %v", err)
+	}
+
+	// notification channels
+	notificationChannels, err := h.notificationSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching notification channels - %v", err)
+	}
+
+	// report subscriptions
+	reportSubscriptions, err := h.reportSrvc.GetSubscriptionsByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching report subscriptions - %v", err)
+	}
+
+	// calendar subscription
+	var calendarUrl string
+	if user.CalendarToken != "" {
+		calendarUrl = fmt.Sprintf("%s%s/api/export/calendar.ics?token=%s", h.config.Server.GetPublicUrl(), h.config.Server.BasePath, user.CalendarToken)
+	}
+
 	vm := &view.SettingsViewModel{
 		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
 			SharedViewModel: view.NewSharedViewModel(h.config, nil),
 			User:            user,
 			ApiKey:          user.ApiKey,
 		},
-		LanguageMappings:    mappings,
-		Aliases:             combinedAliases,
-		Labels:              combinedLabels,
-		Projects:            projects,
-		UserFirstData:       firstData,
-		SubscriptionPrice:   subscriptionPrice,
-		SupportContact:      h.config.App.SupportContact,
-		DataRetentionMonths: h.config.App.DataRetentionMonths,
-		InviteLink:          inviteLink,
+		LanguageMappings:         mappings,
+		DefaultMappings:          defaultMappings,
+		Aliases:                  combinedAliases,
+		Labels:                   combinedLabels,
+		Projects:                 projects,
+		UserFirstData:            firstData,
+		SubscriptionPrice:        subscriptionPrice,
+		SupportContact:           h.config.App.SupportContact,
+		DataRetentionMonths:      h.config.App.DataRetentionMonths,
+		InviteLink:               inviteLink,
+		TotpSecret:               totpSecret,
+		TotpQrCode:               totpQrCode,
+		TotpRecoveryCodes:        totpRecoveryCodes,
+		Sessions:                 sessions,
+		CurrentSessionId:         h.currentSessionId(r),
+		ImportJobs:               importJobs,
+		RelayTargets:             relayTargets,
+		RelayBacklog:             relayBacklog,
+		Webhooks:                 webhooks,
+		WebhookEvents:            models.WebhookEvents,
+		NotificationChannels:     notificationChannels,
+		NotificationChannelTypes: models.NotificationChannelTypes,
+		NotificationKinds:        models.NotificationKinds,
+		ReportSubscriptions:      reportSubscriptions,
+		ReportPeriods:            models.ReportPeriods,
+		ReportSections:           models.ReportSections,
+		CalendarUrl:              calendarUrl,
+		MaxSessionGapMinutes:     models.MaxSessionGapMinutes,
+		MaxDailyGoalMinutes:      models.MaxDailyGoalMinutes,
+		DefaultTicketPattern:     models.DefaultTicketPattern,
 	}
 	return routeutils.WithSessionMessages(vm, r, w)
 }
 
+func (h *SettingsHandler) currentSessionId(r *http.Request) string {
+	sessionToken, err := helpers.ExtractCookieAuth(r, h.config)
+	if err != nil {
+		return ""
+	}
+	return models.HashSessionToken(*sessionToken)
+}
+
 func (h *SettingsHandler) toggleAggregationLock(userId string, locked bool) {
 	h.aggregationLocks[userId] = locked
 }
@@ -934,3 +1886,14 @@ func getVal[T any](values *map[string]interface{}, key string, fallback T) T {
 	}
 	return val.(T)
 }
+
+func parseLanguageMappingPattern(mappingType, pattern string) (string, string) {
+	if mappingType == "" {
+		mappingType = models.LanguageMappingTypeExtension
+	}
+	pattern = strings.TrimSpace(pattern)
+	if mappingType == models.LanguageMappingTypeExtension {
+		pattern = strings.TrimPrefix(pattern, ".")
+	}
+	return mappingType, pattern
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"fmt"
+	"github.com/duke-git/lancet/v2/mathutil"
 	"github.com/go-chi/chi/v5"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/helpers"
@@ -15,18 +16,24 @@ import (
 )
 
 type SummaryHandler struct {
-	config       *conf.Config
-	userSrvc     services.IUserService
-	summarySrvc  services.ISummaryService
-	keyValueSrvc services.IKeyValueService
+	config           *conf.Config
+	userSrvc         services.IUserService
+	summarySrvc      services.ISummaryService
+	keyValueSrvc     services.IKeyValueService
+	projectLabelSrvc services.IProjectLabelService
+	reportSrvc       services.IReportService
+	reportRenderer   services.IReportRenderer
 }
 
-func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService, keyValueService services.IKeyValueService) *SummaryHandler {
+func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService, keyValueService services.IKeyValueService, projectLabelService services.IProjectLabelService, reportService services.IReportService, reportRenderer services.IReportRenderer) *SummaryHandler {
 	return &SummaryHandler{
-		summarySrvc:  summaryService,
-		userSrvc:     userService,
-		keyValueSrvc: keyValueService,
-		config:       conf.Get(),
+		summarySrvc:      summaryService,
+		userSrvc:         userService,
+		keyValueSrvc:     keyValueService,
+		projectLabelSrvc: projectLabelService,
+		reportSrvc:       reportService,
+		reportRenderer:   reportRenderer,
+		config:           conf.Get(),
 	}
 }
 
@@ -37,6 +44,7 @@ func (h *SummaryHandler) RegisterRoutes(router chi.Router) {
 		WithRedirectErrorMessage("unauthorized").Handler,
 	)
 	r.Get("/", h.GetIndex)
+	r.Get("/report.{format}", h.GetReport)
 
 	router.Mount("/summary", r)
 }
@@ -86,6 +94,26 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 		firstData, _ = time.Parse(time.RFC822Z, firstDataKv.Value)
 	}
 
+	// label colors and hierarchy depth
+	labelColors, err := h.projectLabelSrvc.GetColorsByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to load label colors - %v", err)
+		labelColors = map[string]string{}
+	}
+
+	var maxLabelDepth int
+	if labels, err := h.projectLabelSrvc.GetByUser(user.ID); err == nil {
+		for _, l := range labels {
+			maxLabelDepth = mathutil.Max(maxLabelDepth, models.LabelDepth(l.Label))
+		}
+	}
+	labelDepthOptions := make([]int, 0, maxLabelDepth)
+	if maxLabelDepth > 1 {
+		for i := 1; i <= maxLabelDepth; i++ {
+			labelDepthOptions = append(labelDepthOptions, i)
+		}
+	}
+
 	vm := view.SummaryViewModel{
 		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
 			SharedViewModel: view.NewSharedViewModel(h.config, nil),
@@ -97,6 +125,9 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 		EditorColors:        su.FilterColors(h.config.App.GetEditorColors(), summary.Editors),
 		LanguageColors:      su.FilterColors(h.config.App.GetLanguageColors(), summary.Languages),
 		OSColors:            su.FilterColors(h.config.App.GetOSColors(), summary.OperatingSystems),
+		LabelColors:         labelColors,
+		LabelDepth:          summaryParams.Filters.LabelDepth,
+		LabelDepthOptions:   labelDepthOptions,
 		RawQuery:            rawQuery,
 		UserFirstData:       firstData,
 		DataRetentionMonths: h.config.App.DataRetentionMonths,
@@ -105,6 +136,16 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 	templates[conf.SummaryTemplate].Execute(w, vm)
 }
 
+// GetReport serves the currently selected summary as a downloadable html or pdf report document
+func (h *SummaryHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("interval") == "" && q.Get("from") == "" {
+		q.Set("interval", "today")
+		r.URL.RawQuery = q.Encode()
+	}
+	su.ServeReportDocument(w, r, h.reportSrvc, h.reportRenderer)
+}
+
 func (h *SummaryHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.SummaryViewModel {
 	return su.WithSessionMessages(&view.SummaryViewModel{
 		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
@@ -0,0 +1,312 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"net/http"
+	"strconv"
+)
+
+type TeamsHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	teamSrvc services.ITeamService
+}
+
+func NewTeamsHandler(userService services.IUserService, teamService services.ITeamService) *TeamsHandler {
+	return &TeamsHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+		teamSrvc: teamService,
+	}
+}
+
+func (h *TeamsHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+	r.Post("/", h.PostIndex)
+	r.Get("/{id}", h.GetTeam)
+	r.Post("/{id}", h.PostTeam)
+
+	router.Mount("/teams", r)
+}
+
+func (h *TeamsHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w))
+}
+
+func (h *TeamsHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w).WithError("missing form values"))
+		return
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	if r.PostForm.Get("action") == "create" {
+		team, err := h.teamSrvc.Create(user, r.PostForm.Get("name"))
+		if err != nil {
+			h.handleIndexError(w, r, err)
+			return
+		}
+		routeutils.SetSuccess(r, w, fmt.Sprintf("team '%s' was created", team.Name))
+		http.Redirect(w, r, fmt.Sprintf("%s/teams/%d", h.config.Server.BasePath, team.ID), http.StatusFound)
+		return
+	}
+
+	team, err := h.loadTeam(r.PostForm.Get("team_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w).WithError("team not found"))
+		return
+	}
+
+	var message string
+	switch r.PostForm.Get("action") {
+	case "accept":
+		_, err = h.teamSrvc.Accept(user, team)
+		message = fmt.Sprintf("you joined team '%s'", team.Name)
+	case "decline":
+		err = h.teamSrvc.Leave(user, team)
+		message = fmt.Sprintf("invitation to team '%s' was declined", team.Name)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w).WithError("unknown action requests"))
+		return
+	}
+
+	if err != nil {
+		h.handleIndexError(w, r, err)
+		return
+	}
+	templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w).WithSuccess(message))
+}
+
+func (h *TeamsHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	team, membership, ok := h.checkMembership(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("interval") == "" && q.Get("from") == "" {
+		q.Set("interval", "last_7_days")
+		r.URL.RawQuery = q.Encode()
+	}
+
+	vm := h.buildTeamViewModel(r, w, team, membership)
+
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+
+	teamSummary, err := h.teamSrvc.Summary(vm.User, team, summaryParams.From, summaryParams.To, summaryParams.Filters)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to load summary of team %d - %v", team.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.TeamTemplate].Execute(w, vm.WithError(conf.ErrInternalServerError))
+		return
+	}
+	vm.TeamSummary = teamSummary
+
+	templates[conf.TeamTemplate].Execute(w, vm)
+}
+
+func (h *TeamsHandler) PostTeam(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	team, _, ok := h.checkMembership(w, r)
+	if !ok {
+		return
+	}
+
+	teamUrl := fmt.Sprintf("%s/teams/%d", h.config.Server.BasePath, team.ID)
+
+	if err := r.ParseForm(); err != nil {
+		routeutils.SetError(r, w, "missing form values")
+		http.Redirect(w, r, teamUrl, http.StatusFound)
+		return
+	}
+
+	user := middlewares.GetPrincipal(r)
+	username := r.PostForm.Get("username")
+
+	var err error
+	var message string
+
+	switch r.PostForm.Get("action") {
+	case "rename":
+		_, err = h.teamSrvc.Rename(user, team, r.PostForm.Get("name"))
+		message = "team was renamed"
+	case "invite":
+		_, err = h.teamSrvc.Invite(user, team, username)
+		message = fmt.Sprintf("user '%s' was invited", username)
+	case "remove":
+		err = h.teamSrvc.RemoveMember(user, team, username)
+		message = fmt.Sprintf("user '%s' was removed from the team", username)
+	case "make_owner":
+		_, err = h.teamSrvc.SetRole(user, team, username, models.TeamRoleOwner)
+		message = fmt.Sprintf("user '%s' is now an owner", username)
+	case "make_member":
+		_, err = h.teamSrvc.SetRole(user, team, username, models.TeamRoleMember)
+		message = fmt.Sprintf("user '%s' is no longer an owner", username)
+	case "leave":
+		if err = h.teamSrvc.Leave(user, team); err == nil {
+			routeutils.SetSuccess(r, w, fmt.Sprintf("you left team '%s'", team.Name))
+			http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+			return
+		}
+	case "delete":
+		if err = h.teamSrvc.Delete(user, team); err == nil {
+			routeutils.SetSuccess(r, w, fmt.Sprintf("team '%s' was deleted", team.Name))
+			http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+			return
+		}
+	default:
+		routeutils.SetError(r, w, "unknown action requests")
+		http.Redirect(w, r, teamUrl, http.StatusFound)
+		return
+	}
+
+	if err != nil {
+		routeutils.SetError(r, w, teamErrorMessage(err))
+		if !isTeamClientError(err) {
+			conf.Log().Request(r).Error("failed to perform operation on team %d - %v", team.ID, err)
+		}
+	} else {
+		routeutils.SetSuccess(r, w, message)
+	}
+	http.Redirect(w, r, teamUrl, http.StatusFound)
+}
+
+func (h *TeamsHandler) handleIndexError(w http.ResponseWriter, r *http.Request, err error) {
+	if isTeamClientError(err) {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		conf.Log().Request(r).Error("failed to perform team operation - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w).WithError(teamErrorMessage(err)))
+}
+
+func (h *TeamsHandler) checkMembership(w http.ResponseWriter, r *http.Request) (*models.Team, *models.TeamMember, bool) {
+	user := middlewares.GetPrincipal(r)
+	team, err := h.loadTeam(chi.URLParam(r, "id"))
+	if err == nil {
+		if membership, err := h.teamSrvc.GetMembership(team, user); err == nil {
+			return team, membership, true
+		}
+	}
+	routeutils.SetError(r, w, "team not found")
+	http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+	return nil, nil, false
+}
+
+func (h *TeamsHandler) loadTeam(rawId string) (*models.Team, error) {
+	id, err := strconv.ParseUint(rawId, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return h.teamSrvc.GetById(uint(id))
+}
+
+func (h *TeamsHandler) buildIndexViewModel(r *http.Request, w http.ResponseWriter) *view.TeamsViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	vm := &view.TeamsViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Memberships: []*models.TeamMember{},
+		Invitations: []*models.TeamMember{},
+	}
+
+	memberships, err := h.teamSrvc.GetMemberships(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching teams of user '%s' - %v", user.ID, err)
+		vm.SetError(criticalError)
+		return vm
+	}
+	for _, m := range memberships {
+		if m.Accepted {
+			vm.Memberships = append(vm.Memberships, m)
+		} else {
+			vm.Invitations = append(vm.Invitations, m)
+		}
+	}
+
+	return routeutils.WithSessionMessages(vm, r, w)
+}
+
+func (h *TeamsHandler) buildTeamViewModel(r *http.Request, w http.ResponseWriter, team *models.Team, membership *models.TeamMember) *view.TeamViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	vm := &view.TeamViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Team:       team,
+		Membership: membership,
+		Members:    []*models.TeamMember{},
+		Query:      r.URL.Query(),
+	}
+
+	members, err := h.teamSrvc.GetMembers(team)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching members of team %d - %v", team.ID, err)
+		vm.SetError(criticalError)
+		return vm
+	}
+	vm.Members = members
+
+	return routeutils.WithSessionMessages(vm, r, w)
+}
+
+func isTeamClientError(err error) bool {
+	return errors.Is(err, services.ErrTeamForbidden) ||
+		errors.Is(err, services.ErrTeamInvalid) ||
+		errors.Is(err, services.ErrTeamUserNotFound) ||
+		errors.Is(err, services.ErrTeamAlreadyMember) ||
+		errors.Is(err, services.ErrTeamLastOwner)
+}
+
+func teamErrorMessage(err error) string {
+	if isTeamClientError(err) {
+		return err.Error()
+	}
+	return conf.ErrInternalServerError
+}
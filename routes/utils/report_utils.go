@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+// ServeReportDocument generates a report for the requested range and filters and responds with it as a downloadable html or pdf document
+func ServeReportDocument(w http.ResponseWriter, r *http.Request, reportSrvc services.IReportService, renderer services.IReportRenderer) {
+	format := chi.URLParam(r, "format")
+	if !models.IsValidReportFormat(format) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unsupported format, use 'html' or 'pdf'"))
+		return
+	}
+
+	params, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	withDetails, _ := strconv.ParseBool(r.URL.Query().Get("details"))
+	report, err := reportSrvc.GenerateDocument(params.User, params, withDetails)
+	if errors.Is(err, services.ErrReportRangeTooLong) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to generate report document for user '%s' - %v", params.User.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	// render to buffer first to still be able to respond with an error status
+	var buf bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if format == models.ReportFormatPdf {
+		contentType = "application/pdf"
+		err = renderer.RenderPdf(&buf, report)
+	} else {
+		err = renderer.RenderHtml(&buf, report)
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to render %s report document for user '%s' - %v", format, params.User.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_report_%s_%s.%s\"", params.From.Format(time.DateOnly), params.To.Add(-1*time.Second).Format(time.DateOnly), format))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/duke-git/lancet/v2/condition"
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"time"
+)
+
+var (
+	ErrAdminSelfModification = errors.New("admins cannot disable, demote or delete themselves")
+	ErrAdminNoEmail          = errors.New("user has no e-mail address")
+)
+
+type AdminService struct {
+	config          *config.Config
+	userSrvc        IUserService
+	heartbeatSrvc   IHeartbeatService
+	keyValueSrvc    IKeyValueService
+	mailSrvc        IMailService
+	sessionSrvc     ISessionService
+	relayTargetSrvc IRelayTargetService
+	relaySrvc       IRelayService
+	importSrvc      IImportService
+}
+
+func NewAdminService(userService IUserService, heartbeatService IHeartbeatService, keyValueService IKeyValueService, mailService IMailService, sessionService ISessionService, relayTargetService IRelayTargetService, relayService IRelayService, importService IImportService) *AdminService {
+	return &AdminService{
+		config:          config.Get(),
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatService,
+		keyValueSrvc:    keyValueService,
+		mailSrvc:        mailService,
+		sessionSrvc:     sessionService,
+		relayTargetSrvc: relayTargetService,
+		relaySrvc:       relayService,
+		importSrvc:      importService,
+	}
+}
+
+func (srv *AdminService) SearchUsers(query string, pageParams *utils.PageParams) ([]*models.AdminUserInfo, error) {
+	users, err := srv.userSrvc.Search(query, pageParams)
+	if err != nil {
+		return nil, err
+	}
+	return srv.getInfos(users)
+}
+
+func (srv *AdminService) GetUser(userId string) (*models.AdminUserInfo, error) {
+	user, err := srv.userSrvc.GetUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := srv.getInfos([]*models.User{user})
+	if err != nil {
+		return nil, err
+	}
+	return infos[0], nil
+}
+
+func (srv *AdminService) SetDisabled(admin, user *models.User, disabled bool) (*models.User, error) {
+	if admin.ID == user.ID {
+		return nil, ErrAdminSelfModification
+	}
+
+	user.IsDisabled = disabled
+	u, err := srv.userSrvc.Update(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		if err := srv.sessionSrvc.DeleteByUser(user); err != nil {
+			return nil, err
+		}
+	}
+
+	logbuch.Info("user '%s' was %s by admin '%s'", user.ID, condition.TernaryOperator[bool, string](disabled, "disabled", "enabled"), admin.ID)
+	return u, nil
+}
+
+func (srv *AdminService) SetAdmin(admin, user *models.User, isAdmin bool) (*models.User, error) {
+	if admin.ID == user.ID && !isAdmin {
+		return nil, ErrAdminSelfModification
+	}
+
+	logbuch.Info("admin privileges of user '%s' were %s by admin '%s'", user.ID, condition.TernaryOperator[bool, string](isAdmin, "granted", "revoked"), admin.ID)
+	return srv.userSrvc.SetAdmin(user, isAdmin)
+}
+
+// TriggerPasswordReset generates a new password reset link for the given user and mails it to them, if possible.
+// The link is returned either way, so that admins can hand it out manually to users without an e-mail address.
+func (srv *AdminService) TriggerPasswordReset(user *models.User) (string, error) {
+	u, err := srv.userSrvc.GenerateResetToken(user)
+	if err != nil {
+		return "", err
+	}
+
+	link := fmt.Sprintf("%s/set-password?token=%s", srv.config.Server.GetPublicUrl(), u.ResetToken)
+
+	if user.Email == "" || !srv.config.Mail.Enabled {
+		return link, ErrAdminNoEmail
+	}
+
+	go func(user *models.User) {
+		if err := srv.mailSrvc.SendPasswordReset(user, link); err != nil {
+			config.Log().Error("failed to send password reset mail to %s - %v", user.ID, err)
+		} else {
+			logbuch.Info("sent admin-triggered password reset mail to %s", user.ID)
+		}
+	}(u)
+
+	return link, nil
+}
+
+func (srv *AdminService) ResetApiKey(user *models.User) (*models.User, error) {
+	return srv.userSrvc.ResetApiKey(user)
+}
+
+func (srv *AdminService) DeleteUser(admin, user *models.User) error {
+	if admin.ID == user.ID {
+		return ErrAdminSelfModification
+	}
+	logbuch.Warn("deleting user '%s' on behalf of admin '%s'", user.ID, admin.ID)
+	return srv.userSrvc.Delete(user)
+}
+
+func (srv *AdminService) getInfos(users []*models.User) ([]*models.AdminUserInfo, error) {
+	counts, err := srv.heartbeatSrvc.CountByUsers(users)
+	if err != nil {
+		return nil, err
+	}
+	countsByUser := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		countsByUser[c.User] = c.Count
+	}
+
+	latest, err := srv.heartbeatSrvc.GetLatestTimesByUsers(users)
+	if err != nil {
+		return nil, err
+	}
+	latestByUser := make(map[string]time.Time, len(latest))
+	for _, l := range latest {
+		latestByUser[l.User] = l.Time.T()
+	}
+
+	keys := make([]string, 0, len(users)*2)
+	for _, u := range users {
+		keys = append(keys, fmt.Sprintf("%s_%s", config.KeyLastImport, u.ID), fmt.Sprintf("%s_%s", config.KeyLastImportSuccess, u.ID))
+	}
+	kvs, err := srv.keyValueSrvc.GetStrings(keys)
+	if err != nil {
+		return nil, err
+	}
+	valuesByKey := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		valuesByKey[kv.Key] = kv.Value
+	}
+
+	targets, err := srv.relayTargetSrvc.GetByUsers(users)
+	if err != nil {
+		return nil, err
+	}
+	targetsByUser := make(map[string][]*models.RelayTarget, len(users))
+	for _, t := range targets {
+		targetsByUser[t.UserID] = append(targetsByUser[t.UserID], t)
+	}
+
+	backlogs, err := srv.relaySrvc.CountBacklogByUsers(users)
+	if err != nil {
+		return nil, err
+	}
+	backlogsByUser := make(map[string]int64, len(backlogs))
+	for _, b := range backlogs {
+		backlogsByUser[b.User] = b.Count
+	}
+
+	jobs, err := srv.importSrvc.GetLatestJobsByUsers(users)
+	if err != nil {
+		return nil, err
+	}
+	jobsByUser := make(map[string]*models.ImportJob, len(jobs))
+	for _, j := range jobs {
+		jobsByUser[j.UserID] = j
+	}
+
+	infos := make([]*models.AdminUserInfo, len(users))
+	for i, u := range users {
+		info := models.NewAdminUserInfo(u)
+		info.Heartbeats = countsByUser[u.ID]
+
+		if t, ok := latestByUser[u.ID]; ok {
+			info.LastHeartbeatAt = &t
+		}
+
+		info.LastImportAt = srv.parseTime(valuesByKey[fmt.Sprintf("%s_%s", config.KeyLastImport, u.ID)])
+		info.LastImportSuccessAt = srv.parseTime(valuesByKey[fmt.Sprintf("%s_%s", config.KeyLastImportSuccess, u.ID)])
+		info.WithRelayTargets(targetsByUser[u.ID], backlogsByUser[u.ID]).WithImportJob(jobsByUser[u.ID])
+
+		infos[i] = info
+	}
+
+	return infos, nil
+}
+
+func (srv *AdminService) parseTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC822, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type AdminServiceTestSuite struct {
+	suite.Suite
+	Admin              *models.User
+	TestUser           *models.User
+	UserService        *mocks.UserServiceMock
+	HeartbeatService   *mocks.HeartbeatServiceMock
+	KeyValueService    *mocks.KeyValueServiceMock
+	SessionService     *mocks.SessionServiceMock
+	RelayTargetService *mocks.RelayTargetServiceMock
+	RelayService       *mocks.RelayServiceMock
+	ImportService      *mocks.ImportServiceMock
+}
+
+func (suite *AdminServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Mail.Enabled = false
+	config.Set(cfg)
+
+	suite.Admin = &models.User{ID: "admin", IsAdmin: true}
+	suite.TestUser = &models.User{ID: TestUserId, Email: "john@example.org"}
+
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+	suite.SessionService = new(mocks.SessionServiceMock)
+	suite.RelayTargetService = new(mocks.RelayTargetServiceMock)
+	suite.RelayService = new(mocks.RelayServiceMock)
+	suite.ImportService = new(mocks.ImportServiceMock)
+
+	suite.UserService.On("Update", mock.Anything).Return(suite.TestUser, nil)
+}
+
+func TestAdminServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AdminServiceTestSuite))
+}
+
+func (suite *AdminServiceTestSuite) TestAdminService_SearchUsers() {
+	sut := NewAdminService(suite.UserService, suite.HeartbeatService, suite.KeyValueService, nil, suite.SessionService, suite.RelayTargetService, suite.RelayService, suite.ImportService)
+
+	lastHeartbeat := time.Now().Add(-1 * time.Hour)
+	lastImport := time.Now().Add(-24 * time.Hour).Truncate(time.Minute)
+	otherUser := &models.User{ID: "janedoe", WakatimeApiKey: "secret"}
+	pageParams := &utils.PageParams{Page: 1, PageSize: 10}
+
+	suite.UserService.On("Search", "doe", pageParams).Return([]*models.User{suite.TestUser, otherUser}, nil)
+	suite.HeartbeatService.On("CountByUsers", mock.Anything).Return([]*models.CountByUser{{User: TestUserId, Count: 42}}, nil)
+	suite.HeartbeatService.On("GetLatestTimesByUsers", mock.Anything).Return([]*models.TimeByUser{{User: TestUserId, Time: models.CustomTime(lastHeartbeat)}}, nil)
+	suite.KeyValueService.On("GetStrings", mock.Anything).Return([]*models.KeyStringValue{{Key: "last_import_" + TestUserId, Value: lastImport.Format(time.RFC822)}}, nil)
+	suite.RelayTargetService.On("GetByUsers", mock.Anything).Return([]*models.RelayTarget{
+		{ID: 1, UserID: TestUserId, Enabled: true},
+		{ID: 2, UserID: TestUserId, Enabled: false},
+	}, nil)
+	suite.RelayService.On("CountBacklogByUsers", mock.Anything).Return([]*models.CountByUser{{User: TestUserId, Count: 5}}, nil)
+	suite.ImportService.On("GetLatestJobsByUsers", mock.Anything).Return([]*models.ImportJob{
+		{UserID: "janedoe", State: models.ImportJobCompleted, CreatedAt: models.CustomTime(lastImport), FinishedAt: (*models.CustomTime)(&lastImport)},
+	}, nil)
+
+	result, err := sut.SearchUsers("doe", pageParams)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+
+	assert.Equal(suite.T(), TestUserId, result[0].ID)
+	assert.Equal(suite.T(), int64(42), result[0].Heartbeats)
+	assert.Equal(suite.T(), lastHeartbeat.Unix(), result[0].LastHeartbeatAt.Unix())
+	assert.Equal(suite.T(), lastImport.Unix(), result[0].LastImportAt.Unix())
+	assert.Nil(suite.T(), result[0].LastImportSuccessAt)
+	assert.True(suite.T(), result[0].RelayEnabled)
+	assert.False(suite.T(), result[0].RelayWakatime)
+	assert.Equal(suite.T(), 1, result[0].RelayTargets)
+	assert.Equal(suite.T(), int64(5), result[0].RelayBacklog)
+
+	assert.Equal(suite.T(), int64(0), result[1].Heartbeats)
+	assert.Nil(suite.T(), result[1].LastHeartbeatAt)
+	assert.True(suite.T(), result[1].RelayEnabled)
+	assert.Equal(suite.T(), 0, result[1].RelayTargets)
+	assert.Equal(suite.T(), models.ImportJobCompleted, result[1].LastImportState)
+	assert.Equal(suite.T(), lastImport.Unix(), result[1].LastImportSuccessAt.Unix())
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "GetLatestTimesByUsers", 1)
+	suite.KeyValueService.AssertNumberOfCalls(suite.T(), "GetStrings", 1)
+	suite.KeyValueService.AssertCalled(suite.T(), "GetStrings", []string{"last_import_" + TestUserId, "last_successful_import_" + TestUserId, "last_import_janedoe", "last_successful_import_janedoe"})
+}
+
+func (suite *AdminServiceTestSuite) TestAdminService_SetDisabled() {
+	sut := NewAdminService(suite.UserService, suite.HeartbeatService, suite.KeyValueService, nil, suite.SessionService, suite.RelayTargetService, suite.RelayService, suite.ImportService)
+
+	suite.SessionService.On("DeleteByUser", suite.TestUser, mock.Anything).Return(nil)
+
+	_, err := sut.SetDisabled(suite.Admin, suite.TestUser, true)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.TestUser.IsDisabled)
+	suite.SessionService.AssertCalled(suite.T(), "DeleteByUser", suite.TestUser, mock.Anything)
+
+	_, err = sut.SetDisabled(suite.Admin, suite.TestUser, false)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), suite.TestUser.IsDisabled)
+	suite.SessionService.AssertNumberOfCalls(suite.T(), "DeleteByUser", 1)
+
+	_, err = sut.SetDisabled(suite.Admin, suite.Admin, true)
+	assert.ErrorIs(suite.T(), err, ErrAdminSelfModification)
+}
+
+func (suite *AdminServiceTestSuite) TestAdminService_SetAdmin() {
+	sut := NewAdminService(suite.UserService, suite.HeartbeatService, suite.KeyValueService, nil, suite.SessionService, suite.RelayTargetService, suite.RelayService, suite.ImportService)
+
+	suite.UserService.On("SetAdmin", suite.TestUser, true).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).IsAdmin = args.Bool(1)
+	}).Return(suite.TestUser, nil)
+
+	_, err := sut.SetAdmin(suite.Admin, suite.TestUser, true)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.TestUser.IsAdmin)
+	suite.UserService.AssertCalled(suite.T(), "SetAdmin", suite.TestUser, true)
+	suite.UserService.AssertNotCalled(suite.T(), "Update", mock.Anything)
+
+	_, err = sut.SetAdmin(suite.Admin, suite.Admin, false)
+	assert.ErrorIs(suite.T(), err, ErrAdminSelfModification)
+	assert.True(suite.T(), suite.Admin.IsAdmin)
+
+	assert.ErrorIs(suite.T(), sut.DeleteUser(suite.Admin, suite.Admin), ErrAdminSelfModification)
+}
+
+func (suite *AdminServiceTestSuite) TestAdminService_TriggerPasswordReset_MailDisabled() {
+	sut := NewAdminService(suite.UserService, suite.HeartbeatService, suite.KeyValueService, nil, suite.SessionService, suite.RelayTargetService, suite.RelayService, suite.ImportService)
+
+	suite.UserService.On("GenerateResetToken", suite.TestUser).Return(&models.User{ID: TestUserId, ResetToken: "foo"}, nil)
+
+	link, err := sut.TriggerPasswordReset(suite.TestUser)
+	assert.ErrorIs(suite.T(), err, ErrAdminNoEmail)
+	assert.Contains(suite.T(), link, "/set-password?token=foo")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
 	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/artifex/v2"
 	"github.com/muety/wakapi/config"
 	"sync"
@@ -20,6 +21,7 @@ var aggregationLock = sync.Mutex{}
 
 type AggregationService struct {
 	config           *config.Config
+	eventBus         *hub.Hub
 	userService      IUserService
 	summaryService   ISummaryService
 	heartbeatService IHeartbeatService
@@ -31,6 +33,7 @@ type AggregationService struct {
 func NewAggregationService(userService IUserService, summaryService ISummaryService, heartbeatService IHeartbeatService) *AggregationService {
 	return &AggregationService{
 		config:           config.Get(),
+		eventBus:         config.EventBus(),
 		userService:      userService,
 		summaryService:   summaryService,
 		heartbeatService: heartbeatService,
@@ -143,7 +146,12 @@ func (srv *AggregationService) process(job AggregationJob) {
 		logbuch.Info("successfully generated summary (%v, %v, %s)", job.From, job.To, job.User.ID)
 		if err := srv.summaryService.Insert(summary); err != nil {
 			config.Log().Error("failed to save summary (%v, %v, %s) - %v", summary.UserID, summary.FromTime, summary.ToTime, err)
+			return
 		}
+		srv.eventBus.Publish(hub.Message{
+			Name:   config.EventSummaryCreate,
+			Fields: map[string]interface{}{config.FieldPayload: summary, config.FieldUser: job.User},
+		})
 	}
 }
 
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+// maximum number of days billable amounts can be computed for at once, as rounding requires a summary per day
+const billingMaxDays = 366
+
+var (
+	ErrBillingRateInvalid  = errors.New("invalid rate")
+	ErrBillingRateInEffect = errors.New("rates that already took effect can't be deleted, please add a new version instead")
+	ErrBillingRangeTooLong = fmt.Errorf("billable amounts can be computed for at most %d days at once", billingMaxDays)
+)
+
+// BillingService manages users' hourly rates and computes billable amounts from their summaries. Rounding is applied to
+// every project's coding time per day, before summing up, just like time is typically billed.
+type BillingService struct {
+	config              *config.Config
+	cache               *cache.Cache
+	repository          repositories.IBillingRateRepository
+	summaryService      ISummaryService
+	projectLabelService IProjectLabelService
+}
+
+func NewBillingService(billingRateRepository repositories.IBillingRateRepository, summaryService ISummaryService, projectLabelService IProjectLabelService) *BillingService {
+	return &BillingService{
+		config:              config.Get(),
+		cache:               cache.New(1*time.Hour, 1*time.Hour),
+		repository:          billingRateRepository,
+		summaryService:      summaryService,
+		projectLabelService: projectLabelService,
+	}
+}
+
+func (srv *BillingService) GetRateById(id uint) (*models.BillingRate, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *BillingService) GetRatesByUser(userId string) (models.BillingRates, error) {
+	if rates, found := srv.cache.Get(userId); found {
+		return rates.(models.BillingRates), nil
+	}
+
+	rates, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, models.BillingRates(rates), cache.DefaultExpiration)
+	return rates, nil
+}
+
+// CreateRate adds a new rate, or a new version of an existing one, if there already is a rate for the same target
+func (srv *BillingService) CreateRate(rate *models.BillingRate) (*models.BillingRate, error) {
+	if rate.TargetType == models.BillingTargetLabel {
+		rate.TargetKey = models.NormalizeLabel(rate.TargetKey)
+	}
+	if !rate.IsValid() {
+		return nil, ErrBillingRateInvalid
+	}
+	result, err := srv.repository.Insert(rate)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(rate.UserID)
+	return result, nil
+}
+
+// DeleteRate deletes a rate, that didn't take effect, yet. Past versions are kept, so amounts of periods already billed don't change.
+func (srv *BillingService) DeleteRate(rate *models.BillingRate) error {
+	if rate.UserID == "" {
+		return errors.New("no user id specified")
+	}
+	if !rate.IsUpcoming() {
+		return ErrBillingRateInEffect
+	}
+	err := srv.repository.Delete(rate.ID)
+	srv.cache.Delete(rate.UserID)
+	return err
+}
+
+// Compute calculates the billable amounts for the given user's coding activity within the given range, optionally
+// restricted by filters. Every project's time is rounded per day according to the rate applying on that day, and
+// results in one line per project and rate.
+func (srv *BillingService) Compute(user *models.User, from, to time.Time, filters *models.Filters) (*models.BillingReport, error) {
+	days := utils.SplitRangeByDays(from, to)
+	if len(days) > billingMaxDays {
+		return nil, ErrBillingRangeTooLong
+	}
+
+	rates, err := srv.GetRatesByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	projectLabels, err := srv.projectLabelService.GetByUserGrouped(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[string]*models.BillingLine)
+	for _, day := range days {
+		dayFrom, dayTo := datetime.BeginOfDay(day[0]), day[1]
+		summary, err := srv.summaryService.Aliased(day[0], dayTo, user, srv.summaryService.Retrieve, filters, false)
+		if err != nil {
+			config.Log().Error("failed to generate day summary (%v to %v) for billing of '%s' - %v", day[0], dayTo, user.ID, err)
+			return nil, err
+		}
+
+		for _, item := range summary.Projects {
+			duration := item.TotalFixed()
+			if duration == 0 {
+				continue
+			}
+
+			labels := make([]string, len(projectLabels[item.Key]))
+			for i, l := range projectLabels[item.Key] {
+				labels[i] = l.Label
+			}
+			rate := rates.Resolve(item.Key, labels, dayFrom)
+
+			key := item.Key
+			if rate != nil {
+				key = fmt.Sprintf("%s--%d", item.Key, rate.ID)
+			}
+			line, ok := lines[key]
+			if !ok {
+				line = &models.BillingLine{Project: item.Key, Rate: rate}
+				lines[key] = line
+			}
+
+			billed := duration
+			if rate != nil {
+				billed = rate.Round(duration)
+				line.Amount += rate.Amount(billed)
+			}
+			line.Days++
+			line.Duration += duration
+			line.BilledDuration += billed
+		}
+	}
+
+	return newBillingReport(from, to, lines), nil
+}
+
+func newBillingReport(from, to time.Time, lines map[string]*models.BillingLine) *models.BillingReport {
+	report := &models.BillingReport{
+		From:   from,
+		To:     to,
+		Lines:  make([]*models.BillingLine, 0, len(lines)),
+		Totals: []*models.BillingTotal{},
+	}
+
+	totals := make(map[string]*models.BillingTotal)
+	for _, line := range lines {
+		line.Amount = math.Round(line.Amount*100) / 100 // sum of per-day amounts
+		report.Lines = append(report.Lines, line)
+		if !line.IsBillable() {
+			continue
+		}
+		if _, ok := totals[line.Rate.Currency]; !ok {
+			totals[line.Rate.Currency] = &models.BillingTotal{Currency: line.Rate.Currency}
+			report.Totals = append(report.Totals, totals[line.Rate.Currency])
+		}
+		totals[line.Rate.Currency].BilledDuration += line.BilledDuration
+		totals[line.Rate.Currency].Amount += line.Amount
+	}
+	for _, total := range totals {
+		total.Amount = math.Round(total.Amount*100) / 100
+	}
+
+	sort.Slice(report.Lines, func(i, j int) bool {
+		if report.Lines[i].Project != report.Lines[j].Project {
+			return report.Lines[i].Project < report.Lines[j].Project
+		}
+		if report.Lines[i].Rate == nil || report.Lines[j].Rate == nil {
+			return report.Lines[i].Rate == nil
+		}
+		return report.Lines[i].Rate.ValidFrom.T().Before(report.Lines[j].Rate.ValidFrom.T())
+	})
+	sort.Slice(report.Totals, func(i, j int) bool {
+		return report.Totals[i].Currency < report.Totals[j].Currency
+	})
+
+	return report
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type BillingServiceTestSuite struct {
+	suite.Suite
+	TestUser              *models.User
+	BillingRateRepository *mocks.BillingRateRepositoryMock
+	SummaryService        *mocks.SummaryServiceMock
+	ProjectLabelService   *mocks.ProjectLabelServiceMock
+}
+
+func (suite *BillingServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.BillingRateRepository = new(mocks.BillingRateRepositoryMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+}
+
+func TestBillingServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(BillingServiceTestSuite))
+}
+
+func (suite *BillingServiceTestSuite) TestBillingService_Compute() {
+	sut := NewBillingService(suite.BillingRateRepository, suite.SummaryService, suite.ProjectLabelService)
+
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2, day3 := day1.AddDate(0, 0, 1), day1.AddDate(0, 0, 2)
+
+	rates := []*models.BillingRate{
+		{ID: 1, UserID: TestUserId, TargetType: models.BillingTargetLabel, TargetKey: "client", HourlyRate: 60, Currency: "EUR", Billable: true, RoundingMinutes: 15, RoundingMode: models.BillingRoundingUp, ValidFrom: models.CustomTime(day1)},
+		{ID: 2, UserID: TestUserId, TargetType: models.BillingTargetLabel, TargetKey: "client", HourlyRate: 80, Currency: "EUR", Billable: true, RoundingMinutes: 15, RoundingMode: models.BillingRoundingUp, ValidFrom: models.CustomTime(day2)},
+		{ID: 3, UserID: TestUserId, TargetType: models.BillingTargetProject, TargetKey: "internal", HourlyRate: 50, Currency: "EUR", Billable: false, ValidFrom: models.CustomTime(day1)},
+	}
+	labels := map[string][]*models.ProjectLabel{
+		"wakapi": {{ProjectKey: "wakapi", Label: "client/acme"}},
+	}
+
+	summary := func(items ...*models.SummaryItem) *models.Summary {
+		return &models.Summary{Projects: items}
+	}
+
+	suite.BillingRateRepository.On("GetByUser", TestUserId).Return(rates, nil)
+	suite.ProjectLabelService.On("GetByUserGrouped", TestUserId).Return(labels, nil)
+	suite.SummaryService.On("Aliased", day1, day2, suite.TestUser, mock.Anything, (*models.Filters)(nil)).Return(summary(
+		&models.SummaryItem{Type: models.SummaryProject, Key: "wakapi", Total: 50 * 60}, // 50 min. -> 1 h at 60
+		&models.SummaryItem{Type: models.SummaryProject, Key: "internal", Total: 3600},
+		&models.SummaryItem{Type: models.SummaryProject, Key: "anchr", Total: 600},
+	), nil)
+	suite.SummaryService.On("Aliased", day2, day3, suite.TestUser, mock.Anything, (*models.Filters)(nil)).Return(summary(
+		&models.SummaryItem{Type: models.SummaryProject, Key: "wakapi", Total: 20 * 60}, // 20 min. -> 30 min. at 80
+	), nil)
+
+	report, err := sut.Compute(suite.TestUser, day1, day3, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), report.Lines, 4)
+
+	assert.Equal(suite.T(), "anchr", report.Lines[0].Project)
+	assert.Nil(suite.T(), report.Lines[0].Rate)
+	assert.Equal(suite.T(), 10*time.Minute, report.Lines[0].BilledDuration)
+
+	assert.Equal(suite.T(), "internal", report.Lines[1].Project)
+	assert.False(suite.T(), report.Lines[1].IsBillable())
+	assert.Zero(suite.T(), report.Lines[1].Amount)
+
+	assert.Equal(suite.T(), "wakapi", report.Lines[2].Project)
+	assert.Equal(suite.T(), uint(1), report.Lines[2].Rate.ID)
+	assert.Equal(suite.T(), 50*time.Minute, report.Lines[2].Duration)
+	assert.Equal(suite.T(), 1*time.Hour, report.Lines[2].BilledDuration)
+	assert.Equal(suite.T(), 60.0, report.Lines[2].Amount)
+
+	assert.Equal(suite.T(), uint(2), report.Lines[3].Rate.ID)
+	assert.Equal(suite.T(), 30*time.Minute, report.Lines[3].BilledDuration)
+	assert.Equal(suite.T(), 40.0, report.Lines[3].Amount)
+
+	assert.Len(suite.T(), report.Totals, 1)
+	assert.Equal(suite.T(), "EUR", report.Totals[0].Currency)
+	assert.Equal(suite.T(), 100.0, report.Totals[0].Amount)
+	assert.Equal(suite.T(), 90*time.Minute, report.Totals[0].BilledDuration)
+
+	_, err = sut.Compute(suite.TestUser, day1.AddDate(-2, 0, 0), day3, nil)
+	assert.ErrorIs(suite.T(), err, ErrBillingRangeTooLong)
+}
+
+func (suite *BillingServiceTestSuite) TestBillingService_CreateRate() {
+	sut := NewBillingService(suite.BillingRateRepository, suite.SummaryService, suite.ProjectLabelService)
+
+	rate := &models.BillingRate{UserID: TestUserId, TargetType: models.BillingTargetLabel, TargetKey: " client / acme ", HourlyRate: 80, Currency: "EUR", RoundingMode: models.BillingRoundingUp, ValidFrom: models.CustomTime(time.Now())}
+	suite.BillingRateRepository.On("Insert", rate).Return(rate, nil)
+
+	_, err := sut.CreateRate(rate)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "client/acme", rate.TargetKey)
+
+	_, err = sut.CreateRate(&models.BillingRate{UserID: TestUserId, TargetType: "customer", TargetKey: "acme", Currency: "EUR", RoundingMode: models.BillingRoundingUp, ValidFrom: models.CustomTime(time.Now())})
+	assert.ErrorIs(suite.T(), err, ErrBillingRateInvalid)
+	suite.BillingRateRepository.AssertNumberOfCalls(suite.T(), "Insert", 1)
+}
+
+func (suite *BillingServiceTestSuite) TestBillingService_DeleteRate() {
+	sut := NewBillingService(suite.BillingRateRepository, suite.SummaryService, suite.ProjectLabelService)
+
+	past := &models.BillingRate{ID: 1, UserID: TestUserId, ValidFrom: models.CustomTime(time.Now().Add(-24 * time.Hour))}
+	upcoming := &models.BillingRate{ID: 2, UserID: TestUserId, ValidFrom: models.CustomTime(time.Now().Add(24 * time.Hour))}
+	suite.BillingRateRepository.On("Delete", uint(2)).Return(nil)
+
+	assert.ErrorIs(suite.T(), sut.DeleteRate(past), ErrBillingRateInEffect)
+	assert.Nil(suite.T(), sut.DeleteRate(upcoming))
+	suite.BillingRateRepository.AssertNumberOfCalls(suite.T(), "Delete", 1)
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	uuid "github.com/satori/go.uuid"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	DataExportTTL                 = 24 * time.Hour
+	dataExportMinInterval         = 1 * time.Hour
+	dataExportHeartbeatsBatchSize = 1000
+	dataExportTokenName           = "data_export"
+)
+
+var (
+	ErrDataExportNoMail       = errors.New("a valid e-mail address is required to request a data export")
+	ErrDataExportTooFrequent  = errors.New("too many data exports - please wait before requesting another one")
+	ErrDataExportInvalidToken = errors.New("invalid or expired download link")
+)
+
+// DataExportService assembles everything Wakapi stores about a user into a zip archive. Archives are generated in the
+// background, kept on disk for a limited amount of time and handed out through signed download links sent by mail.
+type DataExportService struct {
+	config                 *config.Config
+	userService            IUserService
+	heartbeatService       IHeartbeatService
+	summaryService         ISummaryService
+	aliasService           IAliasService
+	projectLabelService    IProjectLabelService
+	languageMappingService ILanguageMappingService
+	leaderboardService     ILeaderboardService // optional, nil if leaderboards are disabled
+	diagnosticsService     IDiagnosticsService
+	keyValueService        IKeyValueService
+	mailService            IMailService
+	queueDefault           *artifex.Dispatcher
+	queueImports           *artifex.Dispatcher
+	exportDir              string
+}
+
+func NewDataExportService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, aliasService IAliasService, projectLabelService IProjectLabelService, languageMappingService ILanguageMappingService, leaderboardService ILeaderboardService, diagnosticsService IDiagnosticsService, keyValueService IKeyValueService, mailService IMailService) *DataExportService {
+	return &DataExportService{
+		config:                 config.Get(),
+		userService:            userService,
+		heartbeatService:       heartbeatService,
+		summaryService:         summaryService,
+		aliasService:           aliasService,
+		projectLabelService:    projectLabelService,
+		languageMappingService: languageMappingService,
+		leaderboardService:     leaderboardService,
+		diagnosticsService:     diagnosticsService,
+		keyValueService:        keyValueService,
+		mailService:            mailService,
+		queueDefault:           config.GetDefaultQueue(),
+		queueImports:           config.GetQueue(config.QueueImports),
+		exportDir:              filepath.Join(os.TempDir(), "wakapi_exports"),
+	}
+}
+
+func (srv *DataExportService) Schedule() {
+	logbuch.Info("scheduling expired data exports cleanup")
+	if _, err := srv.queueDefault.DispatchEvery(srv.DeleteExpired, 1*time.Hour); err != nil {
+		config.Log().Error("failed to schedule expired data exports cleanup, %v", err)
+	}
+}
+
+// RequestExport schedules the generation of a data export for the given user, who will receive a download link via e-mail once done
+func (srv *DataExportService) RequestExport(user *models.User) error {
+	if !srv.config.Mail.Enabled || user.Email == "" {
+		return ErrDataExportNoMail
+	}
+
+	kvKey := fmt.Sprintf("%s_%s", config.KeyLastDataExport, user.ID)
+	lastExport, _ := time.Parse(time.RFC822, srv.keyValueService.MustGetString(kvKey).Value)
+	if time.Since(lastExport) < dataExportMinInterval {
+		return ErrDataExportTooFrequent
+	}
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{Key: kvKey, Value: time.Now().Format(time.RFC822)}); err != nil {
+		return err
+	}
+
+	return srv.queueImports.Dispatch(func() {
+		if err := srv.exportAndNotify(user); err != nil {
+			config.Log().Error("failed to export data of user '%s' - %v", user.ID, err)
+		}
+	})
+}
+
+// Export writes a zip archive of all the user's data to the given writer. Heartbeats and summaries are written as
+// newline-delimited json and fetched in chunks, so huge accounts don't have to be kept in memory entirely.
+func (srv *DataExportService) Export(user *models.User, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	if err := srv.writeJson(archive, "profile.json", models.NewDataExportProfile(user)); err != nil {
+		return err
+	}
+	if err := srv.writeHeartbeats(archive, user); err != nil {
+		return err
+	}
+	if err := srv.writeSummaries(archive, user); err != nil {
+		return err
+	}
+
+	aliases, err := srv.aliasService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := srv.writeJson(archive, "aliases.json", aliases); err != nil {
+		return err
+	}
+
+	labels, err := srv.projectLabelService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := srv.writeJson(archive, "project_labels.json", labels); err != nil {
+		return err
+	}
+
+	labelColors, err := srv.projectLabelService.GetColorsByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := srv.writeJson(archive, "label_colors.json", labelColors); err != nil {
+		return err
+	}
+
+	mappings, err := srv.languageMappingService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := srv.writeJson(archive, "language_mappings.json", mappings); err != nil {
+		return err
+	}
+
+	leaderboardItems := []*models.LeaderboardItem{}
+	if srv.leaderboardService != nil {
+		if leaderboardItems, err = srv.leaderboardService.GetAllByUser(user.ID); err != nil {
+			return err
+		}
+	}
+	if err := srv.writeJson(archive, "leaderboard_items.json", leaderboardItems); err != nil {
+		return err
+	}
+
+	diagnostics, err := srv.diagnosticsService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := srv.writeJson(archive, "diagnostics.json", diagnostics); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// ResolveDownload validates the token of a download link and returns the path of the corresponding export archive
+func (srv *DataExportService) ResolveDownload(token string) (*models.User, string, error) {
+	var payload models.DataExportToken
+	if err := srv.config.Security.SecureCookie.Decode(dataExportTokenName, token, &payload); err != nil || payload.IsExpired() {
+		return nil, "", ErrDataExportInvalidToken
+	}
+
+	user, err := srv.userService.GetUserById(payload.UserID)
+	if err != nil {
+		return nil, "", ErrDataExportInvalidToken
+	}
+
+	path := filepath.Join(srv.exportDir, filepath.Base(payload.FileName))
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", ErrDataExportInvalidToken
+	}
+	return user, path, nil
+}
+
+// DeleteExpired removes all export archives that can't be downloaded anymore
+func (srv *DataExportService) DeleteExpired() {
+	entries, err := os.ReadDir(srv.exportDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < DataExportTTL {
+			continue
+		}
+		if err := os.Remove(filepath.Join(srv.exportDir, entry.Name())); err != nil {
+			config.Log().Error("failed to delete expired data export '%s' - %v", entry.Name(), err)
+		}
+	}
+}
+
+func (srv *DataExportService) exportAndNotify(user *models.User) error {
+	start := time.Now()
+
+	if err := os.MkdirAll(srv.exportDir, 0700); err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("%s.zip", uuid.NewV4().String())
+	tmpPath := filepath.Join(srv.exportDir, fileName+".tmp")
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := srv.Export(user, file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(srv.exportDir, fileName)); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(DataExportTTL)
+	token, err := srv.config.Security.SecureCookie.Encode(dataExportTokenName, &models.DataExportToken{
+		UserID:    user.ID,
+		FileName:  fileName,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	logbuch.Info("exported data of user '%s' in %v", user.ID, time.Since(start))
+
+	link := fmt.Sprintf("%s/export/download?token=%s", srv.config.Server.GetPublicUrl(), url.QueryEscape(token))
+	return srv.mailService.SendDataExport(user, link, expiresAt)
+}
+
+func (srv *DataExportService) writeHeartbeats(archive *zip.Writer, user *models.User) error {
+	w, err := archive.Create("heartbeats.ndjson")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	return srv.heartbeatService.StreamAllByUser(user, dataExportHeartbeatsBatchSize, func(heartbeats []*models.Heartbeat) error {
+		for _, h := range heartbeats {
+			if err := encoder.Encode(h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (srv *DataExportService) writeSummaries(archive *zip.Writer, user *models.User) error {
+	w, err := archive.Create("summaries.ndjson")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+
+	// fetch summaries year by year to limit memory usage
+	// windows overlap a bit to also catch summaries crossing year boundaries (e.g. due to time zones), but every summary is only written once
+	for from := config.BeginningOfWakatime(); from.Before(time.Now()); from = from.AddDate(1, 0, 0) {
+		to := from.AddDate(1, 0, 0)
+		summaries, err := srv.summaryService.GetPersisted(from, to.AddDate(0, 0, 2), user)
+		if err != nil {
+			return err
+		}
+		for _, s := range summaries {
+			if s.FromTime.T().Before(from) || !s.FromTime.T().Before(to) {
+				continue
+			}
+			if err := encoder.Encode(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (srv *DataExportService) writeJson(archive *zip.Writer, name string, data interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(data)
+}
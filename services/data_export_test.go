@@ -0,0 +1,150 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/securecookie"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type DataExportServiceTestSuite struct {
+	suite.Suite
+	TestUser               *models.User
+	UserService            *mocks.UserServiceMock
+	HeartbeatService       *mocks.HeartbeatServiceMock
+	SummaryService         *mocks.SummaryServiceMock
+	AliasService           *mocks.AliasServiceMock
+	ProjectLabelService    *mocks.ProjectLabelServiceMock
+	LanguageMappingService *mocks.LanguageMappingServiceMock
+	DiagnosticsService     *mocks.DiagnosticsServiceMock
+}
+
+func (suite *DataExportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Security.SecureCookie = securecookie.New(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32))
+	config.Set(cfg)
+
+	suite.TestUser = &models.User{ID: TestUserId, Email: "user@example.org", WakatimeApiKey: "secret"}
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.LanguageMappingService = new(mocks.LanguageMappingServiceMock)
+	suite.DiagnosticsService = new(mocks.DiagnosticsServiceMock)
+}
+
+func TestDataExportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(DataExportServiceTestSuite))
+}
+
+func (suite *DataExportServiceTestSuite) TestDataExportService_Export() {
+	sut := suite.newService()
+
+	heartbeats := []*models.Heartbeat{
+		{ID: 1, UserID: TestUserId, Project: TestProject1},
+		{ID: 2, UserID: TestUserId, Project: TestProject2},
+	}
+	summary := &models.Summary{ID: 1, UserID: TestUserId, FromTime: models.CustomTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)), ToTime: models.CustomTime(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC))}
+	summaryPreviousYear := &models.Summary{ID: 2, UserID: TestUserId, FromTime: models.CustomTime(time.Date(2020, 12, 31, 23, 0, 0, 0, time.UTC)), ToTime: models.CustomTime(time.Date(2021, 1, 1, 23, 0, 0, 0, time.UTC))}
+
+	suite.HeartbeatService.On("StreamAllByUser", suite.TestUser, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		callback := args.Get(2).(func([]*models.Heartbeat) error)
+		callback(heartbeats[:1])
+		callback(heartbeats[1:])
+	}).Return(nil)
+	// overlapping windows will return the same summary twice
+	suite.SummaryService.On("GetPersisted", mock.Anything, mock.Anything, suite.TestUser).Return([]*models.Summary{summaryPreviousYear, summary}, nil)
+	suite.AliasService.On("GetByUser", TestUserId).Return([]*models.Alias{{Key: TestProject1, Value: TestProject2}}, nil)
+	suite.ProjectLabelService.On("GetByUser", TestUserId).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("GetColorsByUser", TestUserId).Return(map[string]string{}, nil)
+	suite.LanguageMappingService.On("GetByUser", TestUserId).Return([]*models.LanguageMapping{}, nil)
+	suite.DiagnosticsService.On("GetByUser", TestUserId).Return([]*models.Diagnostics{{ID: 1, Plugin: "vscode"}}, nil)
+
+	var buf bytes.Buffer
+	err := sut.Export(suite.TestUser, &buf)
+	assert.Nil(suite.T(), err)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(suite.T(), err)
+
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, _ := f.Open()
+		var content bytes.Buffer
+		content.ReadFrom(r)
+		files[f.Name] = content.Bytes()
+	}
+
+	assert.Len(suite.T(), files, 9)
+	assert.Contains(suite.T(), files, "leaderboard_items.json")
+	assert.Equal(suite.T(), 2, countLines(files["heartbeats.ndjson"]))
+	assert.Equal(suite.T(), 2, countLines(files["summaries.ndjson"]))
+
+	var profile map[string]interface{}
+	assert.Nil(suite.T(), json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(suite.T(), TestUserId, profile["id"])
+	assert.Equal(suite.T(), true, profile["has_wakatime_api_key"])
+	assert.NotContains(suite.T(), string(files["profile.json"]), "secret")
+}
+
+func (suite *DataExportServiceTestSuite) TestDataExportService_Export_Error() {
+	sut := suite.newService()
+
+	suite.HeartbeatService.On("StreamAllByUser", suite.TestUser, mock.Anything, mock.Anything).Return(errors.New(""))
+
+	var buf bytes.Buffer
+	assert.Error(suite.T(), sut.Export(suite.TestUser, &buf))
+}
+
+func (suite *DataExportServiceTestSuite) TestDataExportService_ResolveDownload() {
+	sut := suite.newService()
+	assert.Nil(suite.T(), os.WriteFile(filepath.Join(sut.exportDir, "export.zip"), []byte{}, 0600))
+
+	suite.UserService.On("GetUserById", TestUserId).Return(suite.TestUser, nil)
+
+	validToken, _ := sut.config.Security.SecureCookie.Encode(dataExportTokenName, &models.DataExportToken{UserID: TestUserId, FileName: "export.zip", ExpiresAt: time.Now().Add(time.Hour)})
+	expiredToken, _ := sut.config.Security.SecureCookie.Encode(dataExportTokenName, &models.DataExportToken{UserID: TestUserId, FileName: "export.zip", ExpiresAt: time.Now().Add(-time.Hour)})
+	missingToken, _ := sut.config.Security.SecureCookie.Encode(dataExportTokenName, &models.DataExportToken{UserID: TestUserId, FileName: "../missing.zip", ExpiresAt: time.Now().Add(time.Hour)})
+
+	user, path, err := sut.ResolveDownload(validToken)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.TestUser, user)
+	assert.Equal(suite.T(), filepath.Join(sut.exportDir, "export.zip"), path)
+
+	_, _, err = sut.ResolveDownload(expiredToken)
+	assert.ErrorIs(suite.T(), err, ErrDataExportInvalidToken)
+
+	_, _, err = sut.ResolveDownload(missingToken)
+	assert.ErrorIs(suite.T(), err, ErrDataExportInvalidToken)
+
+	_, _, err = sut.ResolveDownload(validToken + "x")
+	assert.ErrorIs(suite.T(), err, ErrDataExportInvalidToken)
+}
+
+func (suite *DataExportServiceTestSuite) newService() *DataExportService {
+	sut := NewDataExportService(suite.UserService, suite.HeartbeatService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService, suite.LanguageMappingService, nil, suite.DiagnosticsService, nil, nil)
+	sut.exportDir = suite.T().TempDir()
+	return sut
+}
+
+func countLines(data []byte) int {
+	var count int
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		count++
+	}
+	return count
+}
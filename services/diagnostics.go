@@ -22,3 +22,7 @@ func (srv *DiagnosticsService) Create(diagnostics *models.Diagnostics) (*models.
 	diagnostics.ID = 0
 	return srv.repository.Insert(diagnostics)
 }
+
+func (srv *DiagnosticsService) GetByUser(userId string) ([]*models.Diagnostics, error) {
+	return srv.repository.GetByUser(userId)
+}
@@ -0,0 +1,181 @@
+package document
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/routes"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+	"github.com/muety/wakapi/views/report"
+)
+
+const (
+	tplNameReport       = "report.tpl.html"
+	maxDetailRows       = 50 // per project and table, remaining entities are summed up as "other"
+	reportDocumentTitle = "Coding Activity Report"
+)
+
+// ReportRenderer renders reports as standalone documents, i.e. as html with inline styles or as pdf
+type ReportRenderer struct {
+	templates utils.TemplateMap
+}
+
+// reportDocument is the layout-agnostic representation of a report shared by html and pdf rendering
+type reportDocument struct {
+	Title       string
+	User        string
+	From        time.Time
+	To          time.Time
+	Filters     []string
+	Total       time.Duration
+	Tables      []*documentTable
+	Details     []*documentDetail
+	GeneratedAt time.Time
+}
+
+type documentTable struct {
+	Title string
+	Rows  []*documentRow
+}
+
+type documentRow struct {
+	Key   string
+	Total time.Duration
+	Share float64 // in percent of the table's reference total
+}
+
+type documentDetail struct {
+	Project string
+	Total   time.Duration
+	Tables  []*documentTable
+}
+
+func NewReportRenderer() services.IReportRenderer {
+	funcs := routes.DefaultTemplateFuncs()
+	funcs["hours"] = fmtHours
+	funcs["percent"] = fmtPercent
+
+	// Use local file system when in 'dev' environment, go embed file system otherwise
+	templateFs := conf.ChooseFS("views/report", report.TemplateFiles)
+	templates, err := utils.LoadTemplates(templateFs, funcs)
+	if err != nil {
+		panic(err)
+	}
+
+	return &ReportRenderer{templates: templates}
+}
+
+func (r *ReportRenderer) RenderHtml(w io.Writer, report *models.Report) error {
+	return r.templates[tplNameReport].Execute(w, newReportDocument(report))
+}
+
+func (r *ReportRenderer) RenderPdf(w io.Writer, report *models.Report) error {
+	_, err := layoutPdf(newReportDocument(report)).WriteTo(w)
+	return err
+}
+
+func newReportDocument(report *models.Report) *reportDocument {
+	summary := report.Summary.Sorted()
+	total := summary.TotalTime()
+
+	doc := &reportDocument{
+		Title:       reportDocumentTitle,
+		From:        report.From,
+		To:          report.To.Add(-1 * time.Second), // exclusive upper bound
+		Filters:     report.FilterList(),
+		Total:       total,
+		Tables:      []*documentTable{},
+		Details:     []*documentDetail{},
+		GeneratedAt: time.Now(),
+	}
+	if report.User != nil {
+		doc.User = report.User.ID
+		doc.GeneratedAt = doc.GeneratedAt.In(report.User.TZ())
+	}
+
+	doc.addTable("Projects", summary.Projects, total)
+
+	days := &documentTable{Title: "Days", Rows: []*documentRow{}}
+	for _, day := range report.DailySummaries {
+		if day == nil || day.TotalTime() == 0 {
+			continue
+		}
+		days.Rows = append(days.Rows, &documentRow{
+			Key:   day.FromTime.T().Format("Mon, ") + helpers.FormatDate(day.FromTime.T()),
+			Total: day.TotalTime(),
+			Share: share(day.TotalTime(), total),
+		})
+	}
+	if len(days.Rows) > 0 {
+		doc.Tables = append(doc.Tables, days)
+	}
+
+	doc.addTable("Labels", summary.Labels, total)
+	doc.addTable("Languages", summary.Languages, total)
+	doc.addTable("Editors", summary.Editors, total)
+	doc.addTable("Operating Systems", summary.OperatingSystems, total)
+	doc.addTable("Machines", summary.Machines, total)
+
+	for _, d := range report.ProjectDetails {
+		detail := &documentDetail{Project: d.Project, Total: d.Total, Tables: []*documentTable{}}
+		if table := newDocumentTable("Branches", d.Branches, d.Total, maxDetailRows); table != nil {
+			detail.Tables = append(detail.Tables, table)
+		}
+		if table := newDocumentTable("Files", d.Entities, d.Total, maxDetailRows); table != nil {
+			detail.Tables = append(detail.Tables, table)
+		}
+		doc.Details = append(doc.Details, detail)
+	}
+
+	return doc
+}
+
+func (d *reportDocument) addTable(title string, items models.SummaryItems, total time.Duration) {
+	if table := newDocumentTable(title, items, total, 0); table != nil {
+		d.Tables = append(d.Tables, table)
+	}
+}
+
+// newDocumentTable converts the given summary items into table rows, omitting entries without any time, or returns nil if there are none left
+func newDocumentTable(title string, items models.SummaryItems, total time.Duration, maxRows int) *documentTable {
+	table := &documentTable{Title: title, Rows: []*documentRow{}}
+	var other time.Duration
+	for _, item := range items {
+		if item.TotalFixed() == 0 {
+			continue
+		}
+		if maxRows > 0 && len(table.Rows) >= maxRows {
+			other += item.TotalFixed()
+			continue
+		}
+		table.Rows = append(table.Rows, &documentRow{Key: item.Key, Total: item.TotalFixed(), Share: share(item.TotalFixed(), total)})
+	}
+	if other > 0 {
+		table.Rows = append(table.Rows, &documentRow{Key: "other", Total: other, Share: share(other, total)})
+	}
+	if len(table.Rows) == 0 {
+		return nil
+	}
+	return table
+}
+
+func share(value, total time.Duration) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) / float64(total) * 100
+}
+
+// fmtHours formats the duration as decimal hours, e.g. "1.75" for 1 hour and 45 minutes, as typically used for invoicing
+func fmtHours(d time.Duration) string {
+	return fmt.Sprintf("%.2f", d.Hours())
+}
+
+func fmtPercent(p float64) string {
+	return fmt.Sprintf("%.1f %%", p)
+}
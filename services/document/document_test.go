@@ -0,0 +1,120 @@
+package document
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport() *models.Report {
+	from := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC) // monday
+	day := func(offset int, total time.Duration) *models.Summary {
+		return &models.Summary{
+			FromTime: models.CustomTime(from.AddDate(0, 0, offset)),
+			Projects: models.SummaryItems{{Type: models.SummaryProject, Key: "wakapi", Total: total / time.Second}},
+		}
+	}
+
+	return &models.Report{
+		From:    from,
+		To:      from.AddDate(0, 0, 3),
+		User:    &models.User{ID: "user1"},
+		Filters: models.NewFiltersWith(models.SummaryLanguage, "Go"),
+		Summary: &models.Summary{
+			Projects: models.SummaryItems{
+				{Type: models.SummaryProject, Key: "anchr", Total: 1800},
+				{Type: models.SummaryProject, Key: "wakapi", Total: 9000},
+			},
+			Languages: models.SummaryItems{{Type: models.SummaryLanguage, Key: "Go", Total: 10800}},
+			Labels:    models.SummaryItems{{Type: models.SummaryLabel, Key: "customer-a", Total: 9000}},
+		},
+		DailySummaries: []*models.Summary{day(0, time.Hour), day(1, 0), day(2, 2*time.Hour)},
+		ProjectDetails: []*models.ReportProjectDetail{{
+			Project:  "wakapi",
+			Total:    150 * time.Minute,
+			Entities: models.SummaryItems{{Type: models.SummaryEntity, Key: "/home/user/dev/wakapi/main.go", Total: 9000}},
+		}},
+	}
+}
+
+func TestNewReportDocument(t *testing.T) {
+	doc := newReportDocument(testReport())
+
+	assert.Equal(t, "user1", doc.User)
+	assert.Equal(t, time.Date(2023, 1, 4, 23, 59, 59, 0, time.UTC), doc.To)
+	assert.Equal(t, []string{"language: Go"}, doc.Filters)
+	assert.Equal(t, 3*time.Hour, doc.Total)
+
+	var titles []string
+	for _, table := range doc.Tables {
+		titles = append(titles, table.Title)
+	}
+	assert.Equal(t, []string{"Projects", "Days", "Labels", "Languages"}, titles)
+
+	projects := doc.Tables[0].Rows
+	assert.Equal(t, "wakapi", projects[0].Key)
+	assert.InDelta(t, 83.3, projects[0].Share, 0.1)
+
+	days := doc.Tables[1].Rows
+	assert.Len(t, days, 2) // days without activity are omitted
+	assert.Equal(t, "Mon, 2023-01-02", days[0].Key)
+
+	assert.Len(t, doc.Details, 1)
+	assert.Len(t, doc.Details[0].Tables, 1)
+	assert.Equal(t, "Files", doc.Details[0].Tables[0].Title)
+}
+
+func TestNewDocumentTable_MaxRows(t *testing.T) {
+	items := models.SummaryItems{
+		{Key: "a", Total: 300},
+		{Key: "b", Total: 200},
+		{Key: "c", Total: 100},
+		{Key: "d", Total: 0},
+	}
+	table := newDocumentTable("Files", items, 600*time.Second, 1)
+	assert.Len(t, table.Rows, 2)
+	assert.Equal(t, "other", table.Rows[1].Key)
+	assert.Equal(t, 300*time.Second, table.Rows[1].Total)
+
+	assert.Nil(t, newDocumentTable("Files", models.SummaryItems{}, 0, 0))
+}
+
+func TestReportRenderer_RenderHtml(t *testing.T) {
+	conf.Set(conf.Empty())
+
+	var buf bytes.Buffer
+	err := NewReportRenderer().RenderHtml(&buf, testReport())
+
+	assert.Nil(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "<title>Coding Activity Report – 2023-01-02 to 2023-01-04</title>")
+	assert.Contains(t, out, "language: Go")
+	assert.Contains(t, out, "Total: 3 hrs 0 mins (3.00 h)")
+	assert.Contains(t, out, "<td>wakapi</td>")
+	assert.Contains(t, out, "<td>83.3 %</td>")
+	assert.Contains(t, out, "<td>customer-a</td>")
+	assert.Contains(t, out, "/home/user/dev/wakapi/main.go")
+}
+
+func TestReportRenderer_RenderPdf(t *testing.T) {
+	conf.Set(conf.Empty())
+
+	var buf bytes.Buffer
+	err := NewReportRenderer().RenderPdf(&buf, testReport())
+
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+	assert.Contains(t, buf.String(), "/Count 1")
+}
+
+func TestLayoutPdf_PageBreaks(t *testing.T) {
+	report := testReport()
+	for i := 0; i < 100; i++ {
+		report.Summary.Languages = append(report.Summary.Languages, &models.SummaryItem{Type: models.SummaryLanguage, Key: "lang", Total: 60})
+	}
+	assert.Greater(t, layoutPdf(newReportDocument(report)).NumPages(), 2)
+}
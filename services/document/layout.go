@@ -0,0 +1,134 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/muety/wakapi/helpers"
+)
+
+const (
+	marginLeft    = 50.0
+	marginRight   = pdfPageWidth - 50.0
+	marginTop     = 60.0
+	contentBottom = pdfPageHeight - 60.0
+	footerY       = pdfPageHeight - 35.0
+
+	rowHeight   = 16.0
+	fontSize    = 9.5
+	colShare    = marginRight - 160 // right-aligned columns, given by their right edge
+	colTime     = marginRight - 70
+	colHours    = marginRight
+	colKeyWidth = colShare - 60 - marginLeft
+)
+
+// pdfLayout lays out a report document top to bottom, starting a new page whenever the current one is full
+type pdfLayout struct {
+	pdf  *pdfDocument
+	page int
+	y    float64
+}
+
+func layoutPdf(doc *reportDocument) *pdfDocument {
+	l := &pdfLayout{pdf: newPdfDocument()}
+	l.newPage()
+
+	l.pdf.Text(l.page, marginLeft, l.y+14, 20, true, doc.Title)
+	l.y += 36
+	l.keyValue("User", doc.User)
+	l.keyValue("Period", fmt.Sprintf("%s – %s", helpers.FormatDate(doc.From), helpers.FormatDate(doc.To)))
+	if len(doc.Filters) > 0 {
+		l.keyValue("Filters", strings.Join(doc.Filters, "; "))
+	}
+	l.y += 6
+	l.pdf.Text(l.page, marginLeft, l.y+12, 13, true, fmt.Sprintf("Total: %s (%s h)", helpers.FmtWakatimeDuration(doc.Total), fmtHours(doc.Total)))
+	l.y += 24
+
+	if len(doc.Tables) == 0 {
+		l.pdf.Text(l.page, marginLeft, l.y+12, 11, false, "No coding activity in this period.")
+	}
+
+	for _, table := range doc.Tables {
+		l.table(table, 14)
+	}
+
+	if len(doc.Details) > 0 {
+		l.ensure(3 * rowHeight)
+		l.y += 12
+		l.pdf.Text(l.page, marginLeft, l.y+14, 16, true, "Project Details")
+		l.y += 26
+	}
+	for _, detail := range doc.Details {
+		l.ensure(4 * rowHeight)
+		l.pdf.Text(l.page, marginLeft, l.y+12, 13, true, truncateText(detail.Project, colTime-marginLeft, 13, true, false))
+		l.pdf.TextRight(l.page, colHours, l.y+12, 13, true, fmt.Sprintf("%s (%s h)", helpers.FmtWakatimeDuration(detail.Total), fmtHours(detail.Total)))
+		l.y += 20
+		for _, table := range detail.Tables {
+			l.table(table, 11)
+		}
+	}
+
+	// footers can only be written once the number of pages is known
+	generated := "Generated by Wakapi on " + helpers.FormatDateTime(doc.GeneratedAt)
+	for i := 0; i < l.pdf.NumPages(); i++ {
+		l.pdf.Line(i, marginLeft, footerY-12, marginRight, footerY-12, 0.5, 0.8)
+		l.pdf.Text(i, marginLeft, footerY, 8, false, generated)
+		l.pdf.TextRight(i, marginRight, footerY, 8, false, fmt.Sprintf("Page %d of %d", i+1, l.pdf.NumPages()))
+	}
+
+	return l.pdf
+}
+
+func (l *pdfLayout) newPage() {
+	l.pdf.AddPage()
+	l.page = l.pdf.NumPages() - 1
+	l.y = marginTop
+}
+
+// ensure starts a new page, unless there is at least the given vertical space left on the current one
+func (l *pdfLayout) ensure(height float64) bool {
+	if l.y+height <= contentBottom {
+		return false
+	}
+	l.newPage()
+	return true
+}
+
+func (l *pdfLayout) keyValue(key, value string) {
+	l.pdf.Text(l.page, marginLeft, l.y+10, 10, true, key+":")
+	l.pdf.Text(l.page, marginLeft+60, l.y+10, 10, false, truncateText(value, marginRight-marginLeft-60, 10, false, false))
+	l.y += 15
+}
+
+func (l *pdfLayout) table(table *documentTable, titleSize float64) {
+	// keep title and header together with at least two rows
+	l.ensure(titleSize + 3*rowHeight + 10)
+	l.y += 8
+	l.pdf.Text(l.page, marginLeft, l.y+titleSize, titleSize, true, table.Title)
+	l.y += titleSize + 8
+	l.tableHeader()
+
+	for _, row := range table.Rows {
+		if l.ensure(rowHeight) {
+			l.tableHeader()
+		}
+		baseline := l.y + rowHeight - 4.5
+		l.pdf.Text(l.page, marginLeft+4, baseline, fontSize, false, truncateText(row.Key, colKeyWidth, fontSize, false, true))
+		l.pdf.TextRight(l.page, colShare, baseline, fontSize, false, fmtPercent(row.Share))
+		l.pdf.TextRight(l.page, colTime, baseline, fontSize, false, helpers.FmtWakatimeDuration(row.Total))
+		l.pdf.TextRight(l.page, colHours-4, baseline, fontSize, false, fmtHours(row.Total))
+		l.y += rowHeight
+		l.pdf.Line(l.page, marginLeft, l.y, marginRight, l.y, 0.3, 0.85)
+	}
+	l.y += 6
+}
+
+func (l *pdfLayout) tableHeader() {
+	l.pdf.Rect(l.page, marginLeft, l.y, marginRight-marginLeft, rowHeight, 0.937, 0.945, 0.961)
+	baseline := l.y + rowHeight - 4.5
+	l.pdf.Text(l.page, marginLeft+4, baseline, fontSize, true, "Name")
+	l.pdf.TextRight(l.page, colShare, baseline, fontSize, true, "Share")
+	l.pdf.TextRight(l.page, colTime, baseline, fontSize, true, "Time")
+	l.pdf.TextRight(l.page, colHours-4, baseline, fontSize, true, "Hours")
+	l.y += rowHeight
+}
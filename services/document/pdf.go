@@ -0,0 +1,202 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 in points
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+)
+
+// widths of ascii characters 32 to 126 of the standard helvetica fonts in 1/1000 em, see their adobe font metrics
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// characters of windows-1252 outside latin-1, which the standard fonts' WinAnsiEncoding supports
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfDocument is a minimal pdf writer for text-based documents on a4 pages. It only supports the standard helvetica fonts,
+// which every pdf reader provides, as well as lines and filled rectangles, which is all it takes to lay out a report,
+// without pulling in any external dependencies. Coordinates are given in points from the top left corner of a page.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+func newPdfDocument() *pdfDocument {
+	return &pdfDocument{}
+}
+
+func (d *pdfDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDocument) NumPages() int {
+	return len(d.pages)
+}
+
+// Text writes a single line of text on the given page, with y being the text's baseline
+func (d *pdfDocument) Text(page int, x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.pages[page], "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, pdfPageHeight-y, escapePdfString(text))
+}
+
+// TextRight writes a single line of text, which ends at x
+func (d *pdfDocument) TextRight(page int, x, y, size float64, bold bool, text string) {
+	d.Text(page, x-textWidth(text, size, bold), y, size, bold, text)
+}
+
+func (d *pdfDocument) Line(page int, x1, y1, x2, y2, width float64, gray float64) {
+	fmt.Fprintf(d.pages[page], "q %.2f G %.2f w %.2f %.2f m %.2f %.2f l S Q\n", gray, width, x1, pdfPageHeight-y1, x2, pdfPageHeight-y2)
+}
+
+// Rect draws a filled rectangle, whose top left corner is at x, y, in the given rgb color (components from 0 to 1)
+func (d *pdfDocument) Rect(page int, x, y, w, h float64, r, g, b float64) {
+	fmt.Fprintf(d.pages[page], "q %.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f Q\n", r, g, b, x, pdfPageHeight-y-h, w, h)
+}
+
+// WriteTo serializes the document, compressing all page contents
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	beginObject := func() int {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+		return len(offsets)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// objects 1 to 4: catalog, page tree, fonts; pages and their contents follow
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	beginObject()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	beginObject()
+	fmt.Fprintf(&buf, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(d.pages))
+	beginObject()
+	buf.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+	beginObject()
+	buf.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	for _, page := range d.pages {
+		id := beginObject()
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n", pdfPageWidth, pdfPageHeight, id+1)
+
+		var content bytes.Buffer
+		zw := zlib.NewWriter(&content)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+
+		beginObject()
+		fmt.Fprintf(&buf, "<< /Length %d /Filter /FlateDecode >>\nstream\n", content.Len())
+		buf.Write(content.Bytes())
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+func textWidth(text string, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	var width int
+	for _, r := range text {
+		if r >= 32 && r <= 126 {
+			width += widths[r-32]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// truncateText shortens the text to fit the given width, replacing its end by an ellipsis, or its beginning, if fromLeft is set (e.g. for file paths)
+func truncateText(text string, maxWidth, size float64, bold, fromLeft bool) string {
+	if textWidth(text, size, bold) <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		if fromLeft {
+			runes = runes[1:]
+			if candidate := "…" + string(runes); textWidth(candidate, size, bold) <= maxWidth {
+				return candidate
+			}
+		} else {
+			runes = runes[:len(runes)-1]
+			if candidate := string(runes) + "…"; textWidth(candidate, size, bold) <= maxWidth {
+				return candidate
+			}
+		}
+	}
+	return "…"
+}
+
+// escapePdfString encodes the text as windows-1252, replacing unsupported characters, and escapes it for use in a pdf string literal
+func escapePdfString(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		var c byte
+		if b, ok := winAnsiExtra[r]; ok {
+			c = b
+		} else if r < 256 && (r < 0x80 || r > 0x9f) {
+			c = byte(r)
+		} else {
+			c = '?'
+		}
+		switch c {
+		case '\\', '(', ')':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n', '\r', '\t':
+			sb.WriteByte(' ')
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
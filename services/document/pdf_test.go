@@ -0,0 +1,53 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPdfDocument_WriteTo(t *testing.T) {
+	pdf := newPdfDocument()
+	pdf.AddPage()
+	pdf.Text(0, 50, 50, 12, true, "Hello (world)")
+	pdf.AddPage()
+	pdf.Rect(1, 50, 50, 100, 20, 0.9, 0.9, 0.9)
+	pdf.Line(1, 50, 80, 150, 80, 0.5, 0)
+
+	var buf bytes.Buffer
+	_, err := pdf.WriteTo(&buf)
+	assert.Nil(t, err)
+
+	out := buf.String()
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("%%EOF\n")))
+	assert.Contains(t, out, "/Type /Pages /Kids [5 0 R 7 0 R] /Count 2")
+
+	// startxref must point to the xref table, whose entries must point to the respective objects
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	assert.Len(t, startxref, 2)
+	xref, _ := strconv.Atoi(startxref[1])
+	assert.True(t, bytes.HasPrefix(buf.Bytes()[xref:], []byte("xref\n0 9\n")))
+
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out, -1)
+	assert.Len(t, offsets, 8)
+	for i, match := range offsets {
+		offset, _ := strconv.Atoi(match[1])
+		assert.True(t, bytes.HasPrefix(buf.Bytes()[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))))
+	}
+}
+
+func TestEscapePdfString(t *testing.T) {
+	assert.Equal(t, `a \(b\) \\ c`, escapePdfString(`a (b) \ c`))
+	assert.Equal(t, "\xe4 \x80 \x96 ?", escapePdfString("ä € – 日"))
+}
+
+func TestTruncateText(t *testing.T) {
+	assert.Equal(t, "short", truncateText("short", 100, 10, false, false))
+	assert.Equal(t, "…/main.go", truncateText("src/github.com/muety/wakapi/main.go", textWidth("…/main.go", 10, false), 10, false, true))
+	assert.Equal(t, "src/…", truncateText("src/github.com/muety/wakapi/main.go", textWidth("src/…", 10, false), 10, false, false))
+}
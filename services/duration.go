@@ -34,11 +34,12 @@ func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *
 	// but unfortunately we cannot use it, as it features mysql-specific functions (lag(), timediff(), ...)
 	var count int
 	var latest *models.Duration
+	tickets := user.TicketExtractor()
 
 	mapping := make(map[string][]*models.Duration)
 
 	for _, h := range heartbeats {
-		d1 := models.NewDurationFromHeartbeat(h).WithEntityIgnored().Hashed()
+		d1 := models.NewDurationFromHeartbeat(h).WithEntityIgnored().WithTicket(tickets.Extract(h.Branch, h.Entity)).Hashed()
 
 		if list, ok := mapping[d1.GroupHash]; !ok || len(list) < 1 {
 			mapping[d1.GroupHash] = []*models.Duration{d1}
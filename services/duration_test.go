@@ -198,6 +198,32 @@ func (suite *DurationServiceTestSuite) TestDurationService_Get_Filtered() {
 	}
 }
 
+func (suite *DurationServiceTestSuite) TestDurationService_Get_Tickets() {
+	sut := NewDurationService(suite.HeartbeatService)
+
+	user := &models.User{ID: TestUserId, TicketPattern: models.DefaultTicketPattern}
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+	heartbeats := []*models.Heartbeat{
+		{UserID: TestUserId, Project: TestProject1, Branch: "feature/PROJ-12-login", Time: models.CustomTime(from)},
+		{UserID: TestUserId, Project: TestProject1, Branch: "feature/PROJ-12-login", Time: models.CustomTime(from.Add(30 * time.Second))},
+		{UserID: TestUserId, Project: TestProject1, Branch: "fix/PROJ-7", Time: models.CustomTime(from.Add(60 * time.Second))},
+		{UserID: TestUserId, Project: TestProject1, Branch: TestBranchMaster, Time: models.CustomTime(from.Add(90 * time.Second))},
+	}
+	suite.HeartbeatService.On("GetAllWithin", from, to, user).Return(heartbeats, nil)
+
+	durations, err := sut.Get(from, to, user, nil)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 3)
+	assert.Equal(suite.T(), "PROJ-12", durations[0].Ticket)
+	assert.Equal(suite.T(), 60*time.Second, durations[0].Duration)
+	assert.Equal(suite.T(), "PROJ-7", durations[1].Ticket)
+	assert.Empty(suite.T(), durations[2].Ticket)
+
+	durations, err = sut.Get(from, to, user, models.NewFiltersWith(models.SummaryTicket, "PROJ-7"))
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 1)
+}
+
 func filterHeartbeats(from, to time.Time, heartbeats []*models.Heartbeat) []*models.Heartbeat {
 	filtered := make([]*models.Heartbeat, 0, len(heartbeats))
 	for _, h := range heartbeats {
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const exportHeartbeatsPageSize = 1000
+
+// ExportService streams a user's heartbeats, durations, daily summaries or per-ticket worklogs row by row, e.g. to be written as csv for
+// spreadsheets or as ndjson for data warehouses. Unless raw data is requested, aliases are resolved and filters are
+// matched against the aliased values, just like in summaries.
+type ExportService struct {
+	config              *config.Config
+	heartbeatService    IHeartbeatService
+	durationService     IDurationService
+	summaryService      ISummaryService
+	aliasService        IAliasService
+	projectLabelService IProjectLabelService
+}
+
+func NewExportService(heartbeatService IHeartbeatService, durationService IDurationService, summaryService ISummaryService, aliasService IAliasService, projectLabelService IProjectLabelService) *ExportService {
+	return &ExportService{
+		config:              config.Get(),
+		heartbeatService:    heartbeatService,
+		durationService:     durationService,
+		summaryService:      summaryService,
+		aliasService:        aliasService,
+		projectLabelService: projectLabelService,
+	}
+}
+
+// Export passes every row of the given kind of export to yield, with values in the order of params.Columns. Streaming
+// stops at the first error returned by yield.
+func (srv *ExportService) Export(kind string, user *models.User, params *models.ExportParams, yield func([]interface{}) error) error {
+	switch kind {
+	case models.ExportHeartbeats:
+		return srv.exportHeartbeats(user, params, yield)
+	case models.ExportDurations:
+		return srv.exportDurations(user, params, yield)
+	case models.ExportSummaries:
+		return srv.exportSummaries(user, params, yield)
+	case models.ExportWorklogs:
+		return srv.exportWorklogs(user, params, yield)
+	}
+	return fmt.Errorf("unsupported export '%s'", kind)
+}
+
+func (srv *ExportService) exportHeartbeats(user *models.User, params *models.ExportParams, yield func([]interface{}) error) error {
+	filters, ok := srv.resolveFilters(user, params.Filters)
+	if !ok {
+		return nil
+	}
+	if !params.Raw {
+		if err := srv.aliasService.InitializeUser(user.ID); err != nil {
+			return err
+		}
+	}
+
+	// paged by id rather than loading the entire range at once
+	for after := uint64(0); ; {
+		heartbeats, err := srv.heartbeatService.GetPageByUser(user, after, params.From, params.To, exportHeartbeatsPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range heartbeats {
+			if !params.Raw {
+				h = srv.aliasHeartbeat(user, h)
+			}
+			if filters != nil && !filters.MatchHeartbeat(h) {
+				continue
+			}
+			if err := yield(exportRow(params.Columns, h.ExportValue)); err != nil {
+				return err
+			}
+		}
+
+		if len(heartbeats) < exportHeartbeatsPageSize {
+			return nil
+		}
+		after = heartbeats[len(heartbeats)-1].ID
+	}
+}
+
+// GetWorkSessions derives work sessions from the user's (aliased, unless raw data is requested) durations within the given range.
+// Sessions are formed from matching durations only, i.e. when filtering by project, breaks spent on other projects count as gaps.
+func (srv *ExportService) GetWorkSessions(user *models.User, params *models.ExportParams) (models.WorkSessions, error) {
+	durations := make(models.Durations, 0)
+	if err := srv.eachDuration(user, params, func(d *models.Duration) error {
+		durations = append(durations, d)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return models.NewWorkSessions(durations, user.SessionGap()), nil
+}
+
+func (srv *ExportService) exportDurations(user *models.User, params *models.ExportParams, yield func([]interface{}) error) error {
+	return srv.eachDuration(user, params, func(d *models.Duration) error {
+		return yield(exportRow(params.Columns, d.ExportValue))
+	})
+}
+
+// eachDuration passes every duration within the requested range, which matches the requested filters, to yield
+func (srv *ExportService) eachDuration(user *models.User, params *models.ExportParams, yield func(*models.Duration) error) error {
+	filters, ok := srv.resolveFilters(user, params.Filters)
+	if !ok {
+		return nil
+	}
+	if !params.Raw {
+		if err := srv.aliasService.InitializeUser(user.ID); err != nil {
+			return err
+		}
+	}
+
+	// computed day by day to keep memory usage bounded for long ranges
+	for _, day := range utils.SplitRangeByDays(params.From, params.To) {
+		durations, err := srv.durationService.Get(day[0], day[1], user, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range durations {
+			if !params.Raw {
+				d = srv.aliasDuration(user, d)
+			}
+			if filters != nil && !filters.MatchDuration(d) {
+				continue
+			}
+			if err := yield(d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (srv *ExportService) exportSummaries(user *models.User, params *models.ExportParams, yield func([]interface{}) error) error {
+	for _, day := range utils.SplitRangeByDays(params.From, params.To) {
+		var (
+			summary *models.Summary
+			err     error
+		)
+		if params.Raw {
+			summary, err = srv.summaryService.Retrieve(day[0], day[1], user, params.Filters)
+		} else {
+			summary, err = srv.summaryService.Aliased(day[0], day[1], user, srv.summaryService.Retrieve, params.Filters, false)
+		}
+		if err != nil {
+			return err
+		}
+
+		date := day[0].Format(time.DateOnly)
+		for _, t := range models.SummaryTypes() {
+			for _, item := range *summary.GetByType(t) {
+				row := exportRow(params.Columns, func(column string) interface{} {
+					switch column {
+					case "date":
+						return date
+					case "type":
+						return summaryTypeName(t)
+					case "key":
+						return item.Key
+					case "total":
+						return item.TotalFixed().Seconds()
+					}
+					return nil
+				})
+				if err := yield(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// exportWorklogs sums up the time spent per ticket and day, skipping all activity without a ticket
+func (srv *ExportService) exportWorklogs(user *models.User, params *models.ExportParams, yield func([]interface{}) error) error {
+	worklogs := make([]*models.Worklog, 0)
+	indices := make(map[string]int) // date + ticket -> index in worklogs
+
+	if err := srv.eachDuration(user, params, func(d *models.Duration) error {
+		if d.Ticket == "" {
+			return nil
+		}
+		key := d.Time.T().In(user.TZ()).Format(time.DateOnly) + "__" + d.Ticket
+		i, ok := indices[key]
+		if !ok {
+			i = len(worklogs)
+			indices[key] = i
+			worklogs = append(worklogs, &models.Worklog{Ticket: d.Ticket, Started: d.Time.T(), Projects: []string{}})
+		}
+		if d.Time.T().Before(worklogs[i].Started) {
+			worklogs[i].Started = d.Time.T()
+		}
+		worklogs[i].Duration += d.Duration
+		if !slices.Contains(worklogs[i].Projects, d.Project) {
+			worklogs[i].Projects = append(worklogs[i].Projects, d.Project)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, w := range worklogs {
+		if err := yield(exportRow(params.Columns, func(column string) interface{} {
+			return w.ExportValue(column, user)
+		})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveFilters expands label filters to the projects carrying them and returns false if nothing can match at all
+func (srv *ExportService) resolveFilters(user *models.User, filters *models.Filters) (*models.Filters, bool) {
+	if filters == nil || filters.IsEmpty() {
+		return nil, true
+	}
+	if filters.Label == nil || !filters.Label.Exists() {
+		return filters, true
+	}
+
+	filters = filters.WithProjectLabels(newProjectLabelsReverseResolver(srv.projectLabelService, user))
+	return filters, filters.Project != nil && filters.Project.Exists()
+}
+
+func (srv *ExportService) aliasHeartbeat(user *models.User, h *models.Heartbeat) *models.Heartbeat {
+	aliased := *h
+	aliased.Project = srv.resolveAlias(user, models.SummaryProject, h.Project)
+	aliased.Language = srv.resolveAlias(user, models.SummaryLanguage, h.Language)
+	aliased.Editor = srv.resolveAlias(user, models.SummaryEditor, h.Editor)
+	aliased.OperatingSystem = srv.resolveAlias(user, models.SummaryOS, h.OperatingSystem)
+	aliased.Machine = srv.resolveAlias(user, models.SummaryMachine, h.Machine)
+	aliased.Branch = srv.resolveAlias(user, models.SummaryBranch, h.Branch)
+	return &aliased
+}
+
+func (srv *ExportService) aliasDuration(user *models.User, d *models.Duration) *models.Duration {
+	aliased := *d
+	aliased.Project = srv.resolveAlias(user, models.SummaryProject, d.Project)
+	aliased.Language = srv.resolveAlias(user, models.SummaryLanguage, d.Language)
+	aliased.Editor = srv.resolveAlias(user, models.SummaryEditor, d.Editor)
+	aliased.OperatingSystem = srv.resolveAlias(user, models.SummaryOS, d.OperatingSystem)
+	aliased.Machine = srv.resolveAlias(user, models.SummaryMachine, d.Machine)
+	aliased.Branch = srv.resolveAlias(user, models.SummaryBranch, d.Branch)
+	return &aliased
+}
+
+func (srv *ExportService) resolveAlias(user *models.User, entityType uint8, key string) string {
+	if alias, err := srv.aliasService.GetAliasOrDefault(user.ID, entityType, key); err == nil {
+		return alias
+	}
+	return key
+}
+
+func exportRow(columns []string, value func(string) interface{}) []interface{} {
+	row := make([]interface{}, len(columns))
+	for i, c := range columns {
+		row[i] = value(c)
+	}
+	return row
+}
+
+func summaryTypeName(t uint8) string {
+	if t == models.SummaryEntity {
+		return "entity"
+	}
+	return models.GetEntityColumn(t)
+}
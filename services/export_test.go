@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ExportServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	TestHeartbeats      []*models.Heartbeat
+	HeartbeatService    *mocks.HeartbeatServiceMock
+	DurationService     *mocks.DurationServiceMock
+	SummaryService      *mocks.SummaryServiceMock
+	AliasService        *mocks.AliasServiceMock
+	ProjectLabelService *mocks.ProjectLabelServiceMock
+}
+
+func (suite *ExportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.TestHeartbeats = []*models.Heartbeat{
+		{ID: 1, UserID: TestUserId, Entity: "main.go", Project: TestProject1, Language: TestLanguageGo, Editor: TestEditorGoland, Time: models.CustomTime(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC))},
+		{ID: 2, UserID: TestUserId, Entity: "app.py", Project: TestProject2, Language: TestLanguagePython, Editor: TestEditorVscode, Time: models.CustomTime(time.Date(2023, 1, 1, 10, 1, 0, 0, time.UTC))},
+	}
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+}
+
+func TestExportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportServiceTestSuite))
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Export_Heartbeats() {
+	sut := NewExportService(suite.HeartbeatService, suite.DurationService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService)
+
+	from, to := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), from, to, exportHeartbeatsPageSize).Return(suite.TestHeartbeats, nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, models.SummaryProject, TestProject1).Return("wakapi", nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", errors.New("no alias"))
+
+	params, _ := (&models.ExportParams{From: from, To: to, Filters: models.NewFiltersWith(models.SummaryProject, "wakapi")}).WithColumns(models.ExportHeartbeats, "time,project,language")
+
+	var rows [][]interface{}
+	err := sut.Export(models.ExportHeartbeats, suite.TestUser, params, func(row []interface{}) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), rows, 1) // filter matched against aliased project
+	assert.Equal(suite.T(), []interface{}{suite.TestHeartbeats[0].Time.T(), "wakapi", TestLanguageGo}, rows[0])
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Export_Durations_Raw() {
+	sut := NewExportService(suite.HeartbeatService, suite.DurationService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService)
+
+	from, to := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)
+	day := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	suite.DurationService.On("Get", from, day, suite.TestUser, (*models.Filters)(nil)).Return(models.Durations{
+		{Project: TestProject1, Language: TestLanguageGo, Time: models.CustomTime(from.Add(time.Hour)), Duration: 90 * time.Second},
+		{Project: TestProject2, Language: TestLanguagePython, Time: models.CustomTime(from.Add(2 * time.Hour)), Duration: 30 * time.Second},
+	}, nil)
+	suite.DurationService.On("Get", day, to, suite.TestUser, (*models.Filters)(nil)).Return(models.Durations{}, nil)
+
+	params, _ := (&models.ExportParams{From: from, To: to, Raw: true, Filters: models.NewFiltersWith(models.SummaryLanguage, TestLanguageGo)}).WithColumns(models.ExportDurations, "")
+
+	var rows [][]interface{}
+	err := sut.Export(models.ExportDurations, suite.TestUser, params, func(row []interface{}) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), rows, 1)
+	assert.Equal(suite.T(), 90.0, rows[0][1])
+	assert.Equal(suite.T(), TestProject1, rows[0][2])
+	suite.DurationService.AssertNumberOfCalls(suite.T(), "Get", 2)
+	suite.AliasService.AssertNotCalled(suite.T(), "InitializeUser", mock.Anything)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_GetWorkSessions() {
+	sut := NewExportService(suite.HeartbeatService, suite.DurationService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService)
+
+	from, to := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	suite.TestUser.SessionGapMinutes = 30
+
+	suite.DurationService.On("Get", from, to, suite.TestUser, (*models.Filters)(nil)).Return(models.Durations{
+		{Project: TestProject1, Time: models.CustomTime(from.Add(9 * time.Hour)), Duration: 20 * time.Minute},
+		{Project: TestProject2, Time: models.CustomTime(from.Add(9*time.Hour + 40*time.Minute)), Duration: 5 * time.Minute},
+		{Project: TestProject2, Time: models.CustomTime(from.Add(14 * time.Hour)), Duration: 10 * time.Minute},
+	}, nil)
+
+	sessions, err := sut.GetWorkSessions(suite.TestUser, &models.ExportParams{From: from, To: to, Raw: true})
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), sessions, 2)
+	assert.Equal(suite.T(), TestProject1, sessions[0].Project)
+	assert.Equal(suite.T(), 25*time.Minute, sessions[0].Duration)
+	assert.Equal(suite.T(), from.Add(9*time.Hour+45*time.Minute), sessions[0].End)
+	assert.Equal(suite.T(), TestProject2, sessions[1].Project)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Export_Worklogs() {
+	sut := NewExportService(suite.HeartbeatService, suite.DurationService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService)
+
+	from, to := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	suite.DurationService.On("Get", from, to, suite.TestUser, (*models.Filters)(nil)).Return(models.Durations{
+		{Project: TestProject2, Ticket: "PROJ-1", Time: models.CustomTime(from.Add(11 * time.Hour)), Duration: 70 * time.Minute},
+		{Project: TestProject1, Ticket: "PROJ-1", Time: models.CustomTime(from.Add(9 * time.Hour)), Duration: 20 * time.Minute},
+		{Project: TestProject1, Time: models.CustomTime(from.Add(10 * time.Hour)), Duration: 5 * time.Minute},
+		{Project: TestProject2, Ticket: "PROJ-2", Time: models.CustomTime(from.Add(14 * time.Hour)), Duration: 10 * time.Minute},
+	}, nil)
+
+	params, _ := (&models.ExportParams{From: from, To: to, Raw: true}).WithColumns(models.ExportWorklogs, "")
+
+	var rows [][]interface{}
+	err := sut.Export(models.ExportWorklogs, suite.TestUser, params, func(row []interface{}) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), rows, 2)
+	assert.Equal(suite.T(), []interface{}{"PROJ-1", "2023-01-01", "2023-01-01T09:00:00Z", 5400, "1h 30m", "Coding activity in test-project-2, test-project-1"}, rows[0])
+	assert.Equal(suite.T(), "PROJ-2", rows[1][0])
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Export_Summaries() {
+	sut := NewExportService(suite.HeartbeatService, suite.DurationService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService)
+
+	from, to := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	suite.SummaryService.On("Aliased", from, to, suite.TestUser, mock.Anything, mock.Anything).Return(&models.Summary{
+		Projects:  []*models.SummaryItem{{Type: models.SummaryProject, Key: TestProject1, Total: 120}},
+		Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: TestLanguageGo, Total: 120}},
+	}, nil)
+
+	params, _ := (&models.ExportParams{From: from, To: to}).WithColumns(models.ExportSummaries, "")
+
+	var rows [][]interface{}
+	err := sut.Export(models.ExportSummaries, suite.TestUser, params, func(row []interface{}) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), [][]interface{}{
+		{"2023-01-01", "project", TestProject1, 120.0},
+		{"2023-01-01", "language", TestLanguageGo, 120.0},
+	}, rows)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Export_UnknownLabel() {
+	sut := NewExportService(suite.HeartbeatService, suite.DurationService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService)
+
+	suite.ProjectLabelService.On("GetByUserGroupedInverted", TestUserId).Return(map[string][]*models.ProjectLabel{}, nil)
+
+	params, _ := (&models.ExportParams{From: time.Now().Add(-time.Hour), To: time.Now(), Filters: models.NewFiltersWith(models.SummaryLabel, "unknown")}).WithColumns(models.ExportHeartbeats, "")
+
+	err := sut.Export(models.ExportHeartbeats, suite.TestUser, params, func(row []interface{}) error {
+		suite.Fail("expected no rows")
+		return nil
+	})
+
+	assert.Nil(suite.T(), err)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "GetPageByUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"sync"
+	"time"
+)
+
+// interval at which users, who were active since the last check, are checked for having reached their daily goal
+const goalCheckInterval = 5 * time.Minute
+
+var goalCheckLock = sync.Mutex{}
+
+// GoalService checks whether users reached their daily coding time goal and publishes an event the first time they
+// did on any day. Only users who sent heartbeats since the previous check are looked at.
+type GoalService struct {
+	config          *config.Config
+	eventBus        *hub.Hub
+	userService     IUserService
+	summaryService  ISummaryService
+	keyValueService IKeyValueService
+	queueDefault    *artifex.Dispatcher
+	active          map[string]bool
+	activeLock      sync.Mutex
+}
+
+// GoalReachedData is the payload of goal reached events
+type GoalReachedData struct {
+	Date         string  `json:"date"`
+	GoalSeconds  float64 `json:"goal_seconds"`
+	TotalSeconds float64 `json:"total_seconds"`
+}
+
+func NewGoalService(userService IUserService, summaryService ISummaryService, keyValueService IKeyValueService) *GoalService {
+	return &GoalService{
+		config:          config.Get(),
+		eventBus:        config.EventBus(),
+		userService:     userService,
+		summaryService:  summaryService,
+		keyValueService: keyValueService,
+		queueDefault:    config.GetDefaultQueue(),
+		active:          make(map[string]bool),
+	}
+}
+
+func (srv *GoalService) Schedule() {
+	logbuch.Info("scheduling daily goal checks")
+
+	sub := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
+			srv.activeLock.Lock()
+			srv.active[heartbeat.UserID] = true
+			srv.activeLock.Unlock()
+		}
+	}(&sub)
+
+	if _, err := srv.queueDefault.DispatchEvery(srv.CheckGoals, goalCheckInterval); err != nil {
+		config.Log().Error("failed to schedule daily goal checks, %v", err)
+	}
+}
+
+// CheckGoals checks the daily goals of all users who were active since the last run
+func (srv *GoalService) CheckGoals() {
+	if ok := goalCheckLock.TryLock(); !ok {
+		return // previous run still in progress
+	}
+	defer goalCheckLock.Unlock()
+
+	srv.activeLock.Lock()
+	active := srv.active
+	srv.active = make(map[string]bool)
+	srv.activeLock.Unlock()
+
+	for userId := range active {
+		user, err := srv.userService.GetUserById(userId)
+		if err != nil || user.DailyGoalMinutes <= 0 {
+			continue
+		}
+		if err := srv.check(user, time.Now()); err != nil {
+			config.Log().Error("failed to check daily goal of user %s - %v", userId, err)
+		}
+	}
+}
+
+func (srv *GoalService) check(user *models.User, now time.Time) error {
+	now = now.In(user.TZ())
+	date := now.Format(time.DateOnly)
+	key := fmt.Sprintf("%s_%s", config.KeyGoalReached, user.ID)
+	if srv.keyValueService.MustGetString(key).Value == date {
+		return nil // already reached today
+	}
+
+	summary, err := srv.summaryService.Aliased(datetime.BeginOfDay(now), now, user, srv.summaryService.Retrieve, nil, false)
+	if err != nil {
+		return err
+	}
+
+	goal := time.Duration(user.DailyGoalMinutes) * time.Minute
+	if summary.TotalTime() < goal {
+		return nil
+	}
+
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{Key: key, Value: date}); err != nil {
+		return err
+	}
+
+	srv.eventBus.Publish(hub.Message{
+		Name: config.EventGoalReached,
+		Fields: map[string]interface{}{
+			config.FieldUserId:  user.ID,
+			config.FieldPayload: &GoalReachedData{Date: date, GoalSeconds: goal.Seconds(), TotalSeconds: summary.TotalTime().Seconds()},
+		},
+	})
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type GoalServiceTestSuite struct {
+	suite.Suite
+	TestUser        *models.User
+	UserService     *mocks.UserServiceMock
+	SummaryService  *mocks.SummaryServiceMock
+	KeyValueService *mocks.KeyValueServiceMock
+}
+
+func (suite *GoalServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+
+	suite.TestUser = &models.User{ID: TestUserId, Location: "UTC", DailyGoalMinutes: 60}
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestGoalServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(GoalServiceTestSuite))
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_Check() {
+	sut := NewGoalService(suite.UserService, suite.SummaryService, suite.KeyValueService)
+	sub := config.EventBus().Subscribe(1, config.EventGoalReached)
+	defer config.EventBus().Unsubscribe(sub)
+
+	now := time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)
+	key := config.KeyGoalReached + "_" + TestUserId
+	summary := func(minutes time.Duration) *models.Summary {
+		return &models.Summary{Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: minutes * 60}}}
+	}
+
+	// not reached yet
+	suite.KeyValueService.On("MustGetString", key).Return(&models.KeyStringValue{Key: key}).Twice()
+	suite.SummaryService.On("Aliased", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), now, suite.TestUser, mock.Anything, (*models.Filters)(nil)).Return(summary(59), nil).Once()
+	assert.Nil(suite.T(), sut.check(suite.TestUser, now))
+	suite.KeyValueService.AssertNotCalled(suite.T(), "PutString", mock.Anything)
+
+	// reached
+	suite.SummaryService.On("Aliased", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), now, suite.TestUser, mock.Anything, (*models.Filters)(nil)).Return(summary(61), nil).Once()
+	suite.KeyValueService.On("PutString", &models.KeyStringValue{Key: key, Value: "2024-01-01"}).Return(nil).Once()
+	assert.Nil(suite.T(), sut.check(suite.TestUser, now))
+
+	select {
+	case m := <-sub.Receiver:
+		assert.Equal(suite.T(), TestUserId, m.Fields[config.FieldUserId])
+		data := m.Fields[config.FieldPayload].(*GoalReachedData)
+		assert.Equal(suite.T(), "2024-01-01", data.Date)
+		assert.Equal(suite.T(), float64(3600), data.GoalSeconds)
+		assert.Equal(suite.T(), float64(61*60), data.TotalSeconds)
+	case <-time.After(time.Second):
+		suite.T().Fatal("expected goal reached event")
+	}
+
+	// already reached today
+	suite.KeyValueService.On("MustGetString", key).Return(&models.KeyStringValue{Key: key, Value: "2024-01-01"}).Once()
+	assert.Nil(suite.T(), sut.check(suite.TestUser, now))
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 2)
+}
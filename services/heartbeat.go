@@ -107,6 +107,12 @@ func (srv *HeartbeatService) CountByUser(user *models.User) (int64, error) {
 	return count, err
 }
 
+// CountByUserUncached bypasses the cache, which is only updated asynchronously after inserting heartbeats, e.g. to
+// tell exactly how many heartbeats got added by an import
+func (srv *HeartbeatService) CountByUserUncached(user *models.User) (int64, error) {
+	return srv.repository.CountByUser(user)
+}
+
 func (srv *HeartbeatService) CountByUsers(users []*models.User) ([]*models.CountByUser, error) {
 	missingUsers := make([]*models.User, 0, len(users))
 	userCounts := make([]*models.CountByUser, 0, len(users))
@@ -143,6 +149,16 @@ func (srv *HeartbeatService) GetAllWithin(from, to time.Time, user *models.User)
 	return srv.augmented(heartbeats, user.ID)
 }
 
+// StreamAllByUser passes all of a user's heartbeats in batches to the given callback, as they are persisted (i.e. without augmentation)
+func (srv *HeartbeatService) StreamAllByUser(user *models.User, batchSize int, callback func([]*models.Heartbeat) error) error {
+	return srv.repository.StreamAllByUser(user, batchSize, callback)
+}
+
+// GetPageByUser returns a chunk of the user's heartbeats as persisted (i.e. without augmentation), ordered by id
+func (srv *HeartbeatService) GetPageByUser(user *models.User, afterId uint64, from, to time.Time, limit int) ([]*models.Heartbeat, error) {
+	return srv.repository.GetPageByUser(user, afterId, from, to, limit)
+}
+
 func (srv *HeartbeatService) GetAllWithinByFilters(from, to time.Time, user *models.User, filters *models.Filters) ([]*models.Heartbeat, error) {
 	heartbeats, err := srv.repository.GetAllWithinByFilters(from, to, user, srv.filtersToColumnMap(filters))
 	if err != nil {
@@ -167,6 +183,10 @@ func (srv *HeartbeatService) GetFirstByUsers() ([]*models.TimeByUser, error) {
 	return srv.repository.GetFirstByUsers()
 }
 
+func (srv *HeartbeatService) GetLatestTimesByUsers(users []*models.User) ([]*models.TimeByUser, error) {
+	return srv.repository.GetLatestTimesByUsers(users)
+}
+
 func (srv *HeartbeatService) GetEntitySetByUser(entityType uint8, userId string) ([]string, error) {
 	cacheKey := srv.getEntityUserCacheKey(entityType, userId)
 	if results, found := srv.cache.Get(cacheKey); found {
@@ -241,13 +261,17 @@ func (srv *HeartbeatService) GetUserProjectStats(user *models.User, from, to tim
 }
 
 func (srv *HeartbeatService) augmented(heartbeats []*models.Heartbeat, userId string) ([]*models.Heartbeat, error) {
-	languageMapping, err := srv.languageMappingSrvc.ResolveByUser(userId)
+	languageMappings, err := srv.languageMappingSrvc.ResolveByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	defaultMappings, err := srv.languageMappingSrvc.ResolveDefaults()
 	if err != nil {
 		return nil, err
 	}
 
 	for i := range heartbeats {
-		heartbeats[i].Augment(languageMapping)
+		heartbeats[i].Augment(languageMappings, defaultMappings)
 	}
 
 	return heartbeats, nil
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/services/imports"
+)
+
+const (
+	importProgressInterval = 10_000
+	importJobSaveInterval  = 5 * time.Second
+	importJobHistoryLimit  = 10
+)
+
+// ImportService consumes the heartbeats yielded by an importer (see services/imports) and persists them in batches.
+// Every import is tracked as an ImportJob, whose progress is saved while the stream is consumed, so that jobs
+// interrupted by a shutdown can be resumed. Once the stream is drained, the user's summaries are regenerated and the
+// user gets notified via mail.
+type ImportService struct {
+	config             *config.Config
+	eventBus           *hub.Hub
+	repository         repositories.IImportJobRepository
+	userService        IUserService
+	heartbeatService   IHeartbeatService
+	summaryService     ISummaryService
+	aggregationService IAggregationService
+	keyValueService    IKeyValueService
+	mailService        IMailService
+	notifications      INotificationService
+}
+
+func NewImportService(importJobRepository repositories.IImportJobRepository, userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, aggregationService IAggregationService, keyValueService IKeyValueService, mailService IMailService, notificationService INotificationService) *ImportService {
+	return &ImportService{
+		config:             config.Get(),
+		eventBus:           config.EventBus(),
+		repository:         importJobRepository,
+		userService:        userService,
+		heartbeatService:   heartbeatService,
+		summaryService:     summaryService,
+		aggregationService: aggregationService,
+		keyValueService:    keyValueService,
+		mailService:        mailService,
+		notifications:      notificationService,
+	}
+}
+
+// CheckRateLimit returns an error in case the user isn't allowed to start another import yet
+func (srv *ImportService) CheckRateLimit(user *models.User) error {
+	if srv.config.IsDev() {
+		return nil
+	}
+
+	lastImport, _ := time.Parse(time.RFC822, srv.keyValueService.MustGetString(fmt.Sprintf("%s_%s", config.KeyLastImport, user.ID)).Value)
+	if time.Since(lastImport) < time.Duration(srv.config.App.ImportBackoffMin)*time.Minute {
+		return fmt.Errorf("Too many data imports - you are only allowed to request an import every %d minutes.", srv.config.App.ImportBackoffMin)
+	}
+
+	lastImportSuccess, _ := time.Parse(time.RFC822, srv.keyValueService.MustGetString(fmt.Sprintf("%s_%s", config.KeyLastImportSuccess, user.ID)).Value)
+	if time.Since(lastImportSuccess) < time.Duration(srv.config.App.ImportMaxRate)*time.Hour {
+		return fmt.Errorf("Too many data imports - last import ran less than %d hours ago, please wait.", srv.config.App.ImportMaxRate)
+	}
+
+	return nil
+}
+
+// GetJobsByUser returns the user's most recent import jobs, newest first
+func (srv *ImportService) GetJobsByUser(user *models.User) ([]*models.ImportJob, error) {
+	return srv.repository.GetByUser(user.ID, importJobHistoryLimit)
+}
+
+// GetLatestJobsByUsers returns the most recent job of each of the given users, who ever imported anything
+func (srv *ImportService) GetLatestJobsByUsers(users []*models.User) ([]*models.ImportJob, error) {
+	return srv.repository.GetLatestByUsers(users)
+}
+
+// GetRunningJobs returns all jobs of the given source that are not finished yet. Right after startup, these are the
+// ones that got interrupted by the last shutdown.
+func (srv *ImportService) GetRunningJobs(source string) ([]*models.ImportJob, error) {
+	jobs, err := srv.repository.GetByState(models.ImportJobRunning)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]*models.ImportJob, 0, len(jobs))
+	for _, j := range jobs {
+		if j.Source == source {
+			filtered = append(filtered, j)
+		}
+	}
+	return filtered, nil
+}
+
+func (srv *ImportService) CreateJob(job *models.ImportJob) (*models.ImportJob, error) {
+	return srv.repository.Insert(job)
+}
+
+func (srv *ImportService) UpdateJob(job *models.ImportJob) (*models.ImportJob, error) {
+	return srv.repository.Update(job)
+}
+
+// FailJob marks the given job as failed for a reason outside the import itself, e.g. an unreachable source
+func (srv *ImportService) FailJob(job *models.ImportJob, err error) {
+	job.AddError(err.Error())
+	srv.finishJob(job, models.ImportJobFailed)
+}
+
+// Submit persists the given job and runs it in the background, while keeping track of when imports were started and
+// succeeded for rate limiting. Errors that occur before any data is read (e.g. an invalid file) are returned directly.
+func (srv *ImportService) Submit(job *models.ImportJob) error {
+	importer, err := srv.newImporter(job)
+	if err != nil {
+		srv.deleteFile(job)
+		return err
+	}
+
+	if _, err := srv.repository.Insert(job); err != nil {
+		srv.deleteFile(job)
+		return err
+	}
+
+	stream, err := srv.startImport(job, importer)
+	if err != nil {
+		srv.FailJob(job, err)
+		return err
+	}
+
+	srv.keyValueService.PutString(&models.KeyStringValue{
+		Key:   fmt.Sprintf("%s_%s", config.KeyLastImport, job.UserID),
+		Value: time.Now().Format(time.RFC822),
+	})
+
+	go srv.runAsync(job, importer, stream)
+	return nil
+}
+
+// ResumeInterrupted continues all jobs that were still running when wakapi was last shut down. Migrations from other
+// wakapi instances are excluded, as the WakapiMigrationService resumes those from its own checkpoints.
+func (srv *ImportService) ResumeInterrupted() {
+	jobs, err := srv.repository.GetByState(models.ImportJobRunning)
+	if err != nil {
+		config.Log().Error("failed to fetch interrupted import jobs - %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if job.Source == models.ImportSourceWakapi {
+			continue
+		}
+
+		if !job.IsReplayable() {
+			// fetched from scratch again, heartbeats imported before are recognized as already existing
+			job.Processed, job.Duplicates = 0, 0
+		}
+
+		importer, err := srv.newImporter(job)
+		if err != nil {
+			srv.FailJob(job, err)
+			continue
+		}
+
+		stream, err := srv.startImport(job, importer)
+		if err != nil {
+			srv.FailJob(job, err)
+			continue
+		}
+
+		logbuch.Info("resuming %s import job %d for user '%s' after %d processed heartbeats", job.Source, job.ID, job.UserID, job.Processed)
+		go srv.runAsync(job, importer, stream)
+	}
+}
+
+// RunJob runs the given, previously created job using the given importer and blocks until it is finished. The job's
+// state tells whether the importer ran through or stopped early. Optionally, the last heartbeat of every batch is
+// passed to checkpoint once the batch is persisted, so that importers can keep track of where to resume from.
+func (srv *ImportService) RunJob(job *models.ImportJob, importer imports.DataImporter, checkpoint func(*models.Heartbeat)) (*models.ImportResult, error) {
+	stream, err := srv.startImport(job, importer)
+	if err != nil {
+		srv.FailJob(job, err)
+		return nil, err
+	}
+	return srv.consume(job, importer, stream, checkpoint)
+}
+
+func (srv *ImportService) runAsync(job *models.ImportJob, importer imports.DataImporter, stream <-chan *models.Heartbeat) {
+	if _, err := srv.consume(job, importer, stream, nil); err != nil {
+		config.Log().Error("%s import for user '%s' failed - %v", job.Source, job.UserID, err)
+		return
+	}
+	if job.State == models.ImportJobCompleted {
+		srv.keyValueService.PutString(&models.KeyStringValue{
+			Key:   fmt.Sprintf("%s_%s", config.KeyLastImportSuccess, job.UserID),
+			Value: time.Now().Format(time.RFC822),
+		})
+	}
+}
+
+// consume blocks until the given stream is closed
+func (srv *ImportService) consume(job *models.ImportJob, importer imports.DataImporter, stream <-chan *models.Heartbeat, checkpoint func(*models.Heartbeat)) (*models.ImportResult, error) {
+	start := time.Now()
+	user := job.User
+	result := &models.ImportResult{}
+	processedBefore, duplicatesBefore, importedBefore := job.Processed, job.Duplicates, job.Imported
+
+	countBefore, err := srv.heartbeatService.CountByUserUncached(user)
+	if err != nil {
+		srv.FailJob(job, err)
+		return nil, err
+	}
+
+	// heartbeats of replayable sources that were already processed before the job got interrupted
+	var skip int
+	if job.IsReplayable() {
+		skip = processedBefore
+	}
+
+	batchSize := srv.config.App.ImportBatchSize
+	batch := make([]*models.Heartbeat, 0, batchSize)
+	lastSaved := time.Now()
+
+	// duplicates are not tracked in memory, but skipped by the heartbeats' unique hash index upon insert, so every
+	// heartbeat that was submitted, but didn't end up as a new row is counted as one
+	var submitted int
+	duplicates := func(countNow int64) int {
+		return max(submitted-int(countNow-countBefore), 0)
+	}
+
+	insert := func(batch []*models.Heartbeat) {
+		if err := srv.heartbeatService.InsertBatch(batch); err != nil {
+			logbuch.Warn("failed to insert imported heartbeat, already existing? - %v", err)
+			return
+		}
+		submitted += len(batch)
+		if checkpoint != nil {
+			checkpoint(batch[len(batch)-1])
+		}
+		if time.Since(lastSaved) >= importJobSaveInterval {
+			countNow, _ := srv.heartbeatService.CountByUserUncached(user)
+			job.Processed, job.Duplicates = processedBefore+result.Processed, duplicatesBefore+duplicates(countNow)
+			job.Imported = importedBefore + int(countNow-countBefore)
+			srv.saveCheckpoint(job)
+			lastSaved = time.Now()
+		}
+	}
+
+	for hb := range stream {
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		result.Processed++
+		if result.Processed%importProgressInterval == 0 {
+			logbuch.Info("processed %d heartbeats so far while importing data for user '%s'", result.Processed, user.ID)
+		}
+
+		batch = append(batch, hb)
+		if len(batch) == batchSize {
+			insert(batch)
+			batch = make([]*models.Heartbeat, 0, batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		insert(batch)
+	}
+
+	countAfter, _ := srv.heartbeatService.CountByUserUncached(user)
+	result.Imported = int(countAfter - countBefore)
+	result.Duplicates = duplicates(countAfter)
+	job.Processed, job.Duplicates, job.Imported = processedBefore+result.Processed, duplicatesBefore+result.Duplicates, importedBefore+result.Imported
+	logbuch.Info("processed %d heartbeats for user '%s' (%d actually imported, %d duplicates)", result.Processed, user.ID, result.Imported, result.Duplicates)
+
+	state := models.ImportJobCompleted
+	if reporter, ok := importer.(imports.FailureReporter); ok {
+		for _, f := range reporter.Failures() {
+			job.AddError(f)
+		}
+		if reporter.Aborted() {
+			state = models.ImportJobFailed
+		}
+	}
+
+	if err := srv.regenerateSummaries(user); err != nil {
+		srv.FailJob(job, err)
+		return nil, err
+	}
+	srv.finishJob(job, state)
+
+	if !user.HasData && countAfter > 0 {
+		user.HasData = true
+		if _, err := srv.userService.Update(user); err != nil {
+			config.Log().Error("failed to set 'has_data' flag for user %s - %v", user.ID, err)
+		}
+	}
+
+	result.Duration = time.Since(start)
+
+	if state == models.ImportJobFailed {
+		srv.notifyFailure(job, result.Imported)
+		return result, nil
+	}
+
+	if user.Email != "" {
+		if err := srv.mailService.SendImportNotification(user, result.Duration, result.Imported); err != nil {
+			config.Log().Error("failed to send import notification mail to %s - %v", user.ID, err)
+		} else {
+			logbuch.Info("sent import notification mail to %s", user.ID)
+		}
+	}
+	if err := srv.notifications.SendImportNotification(user, result.Duration, result.Imported); err != nil {
+		config.Log().Error("failed to post import notification for %s - %v", user.ID, err)
+	}
+
+	return result, nil
+}
+
+// notifyFailure tells the user that the given job stopped early, along with the errors that occurred
+func (srv *ImportService) notifyFailure(job *models.ImportJob, numImported int) {
+	user := job.User
+	if user.Email != "" {
+		if err := srv.mailService.SendImportFailureNotification(user, numImported, job.ErrorList()); err != nil {
+			config.Log().Error("failed to send import failure mail to %s - %v", user.ID, err)
+		} else {
+			logbuch.Info("sent import failure mail to %s", user.ID)
+		}
+	}
+	if err := srv.notifications.SendImportFailureNotification(user, numImported, job.ErrorList()); err != nil {
+		config.Log().Error("failed to post import failure notification for %s - %v", user.ID, err)
+	}
+}
+
+// newImporter (re-)creates the importer for the given job from its source and parameters
+func (srv *ImportService) newImporter(job *models.ImportJob) (imports.DataImporter, error) {
+	params := job.GetParams()
+
+	switch job.Source {
+	case models.ImportSourceWakatime:
+		return imports.NewWakatimeImporter(job.User.WakatimeApiKey, params.ForceLegacy), nil
+	case models.ImportSourceWakatimeFile:
+		file, err := os.Open(params.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		return imports.NewWakatimeFileImporter(file, job.User.WakatimeApiKey), nil
+	case models.ImportSourceActivityWatch:
+		file, err := os.Open(params.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		return imports.NewActivityWatchImporter(file, params.Buckets, HeartbeatDiffThreshold), nil
+	case models.ImportSourceCsv:
+		if params.CsvMapping == nil {
+			return nil, imports.ErrInvalidCsvMapping
+		}
+		location, err := time.LoadLocation(params.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		file, err := os.Open(params.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		mapping := *params.CsvMapping
+		mapping.Location = location
+		return imports.NewCsvImporter(file, &mapping, HeartbeatDiffThreshold), nil
+	}
+
+	return nil, fmt.Errorf("unsupported import source '%s'", job.Source)
+}
+
+func (srv *ImportService) startImport(job *models.ImportJob, importer imports.DataImporter) (<-chan *models.Heartbeat, error) {
+	if job.From == nil || job.To == nil {
+		return importer.ImportAll(job.User)
+	}
+	return importer.Import(job.User, job.From.T(), job.To.T())
+}
+
+func (srv *ImportService) saveCheckpoint(job *models.ImportJob) {
+	now := models.CustomTime(time.Now())
+	job.CheckpointAt = &now
+	if _, err := srv.repository.Update(job); err != nil {
+		config.Log().Error("failed to save progress of import job %d - %v", job.ID, err)
+	}
+}
+
+// finishJob sets the job's final state, removes the data that is no longer needed and persists it
+func (srv *ImportService) finishJob(job *models.ImportJob, state string) {
+	now := models.CustomTime(time.Now())
+	job.State = state
+	job.FinishedAt = &now
+	if state == models.ImportJobCompleted {
+		job.Total = job.Processed
+	}
+
+	srv.deleteFile(job)
+	params := job.GetParams()
+	params.ApiKey = ""
+	job.WithParams(params)
+
+	if _, err := srv.repository.Update(job); err != nil {
+		config.Log().Error("failed to update import job %d - %v", job.ID, err)
+	}
+	logbuch.Info("%s import job %d for user '%s' finished as %s", job.Source, job.ID, job.UserID, job.State)
+
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventImportFinish,
+		Fields: map[string]interface{}{config.FieldPayload: job, config.FieldUserId: job.UserID},
+	})
+}
+
+// deleteFile removes the job's file, if it was uploaded only for the sake of this import
+func (srv *ImportService) deleteFile(job *models.ImportJob) {
+	if params := job.GetParams(); params.DeleteFile && params.FilePath != "" {
+		if err := os.Remove(params.FilePath); err != nil && !os.IsNotExist(err) {
+			config.Log().Error("failed to delete import file '%s' - %v", params.FilePath, err)
+		}
+	}
+}
+
+func (srv *ImportService) regenerateSummaries(user *models.User) error {
+	logbuch.Info("clearing summaries for user '%s'", user.ID)
+	if err := srv.summaryService.DeleteByUser(user.ID); err != nil {
+		config.Log().Error("failed to clear summaries: %v", err)
+		return err
+	}
+
+	if err := srv.aggregationService.AggregateSummaries(datastructure.New(user.ID)); err != nil {
+		config.Log().Error("failed to regenerate summaries: %v", err)
+		return err
+	}
+
+	return nil
+}
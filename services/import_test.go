@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ImportServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	ImportJobRepository *mocks.ImportJobRepositoryMock
+	UserService         *mocks.UserServiceMock
+	HeartbeatService    *mocks.HeartbeatServiceMock
+	SummaryService      *mocks.SummaryServiceMock
+	AggregationService  *mocks.AggregationServiceMock
+	MailService         *mocks.MailServiceMock
+	NotificationService *mocks.NotificationServiceMock
+}
+
+func (suite *ImportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.App.ImportBatchSize = 2
+	config.Set(cfg)
+
+	suite.TestUser = &models.User{ID: TestUserId, Email: "user@example.org"}
+	suite.ImportJobRepository = new(mocks.ImportJobRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+	suite.MailService = new(mocks.MailServiceMock)
+	suite.NotificationService = new(mocks.NotificationServiceMock)
+	suite.NotificationService.On("SendImportNotification", suite.TestUser, mock.Anything, mock.Anything).Return(nil)
+}
+
+func TestImportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ImportServiceTestSuite))
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_RunJob() {
+	sut := NewImportService(suite.ImportJobRepository, suite.UserService, suite.HeartbeatService, suite.SummaryService, suite.AggregationService, nil, suite.MailService, suite.NotificationService)
+
+	heartbeats := suite.newHeartbeats()
+	importer := &testImporter{heartbeats: append(heartbeats, heartbeats[0])} // incl. duplicate
+	job := models.NewImportJob(suite.TestUser, models.ImportSourceWakatime)
+
+	suite.HeartbeatService.On("CountByUserUncached", suite.TestUser).Return(int64(0), nil).Once()
+	suite.HeartbeatService.On("CountByUserUncached", suite.TestUser).Return(int64(3), nil).Once()
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+	suite.SummaryService.On("DeleteByUser", TestUserId).Return(nil)
+	suite.AggregationService.On("AggregateSummaries", mock.Anything).Return(nil)
+	suite.UserService.On("Update", suite.TestUser).Return(suite.TestUser, nil)
+	suite.MailService.On("SendImportNotification", suite.TestUser, mock.Anything, 3).Return(nil)
+	suite.ImportJobRepository.On("Update", job).Return(job, nil)
+
+	result, err := sut.RunJob(job, importer, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 4, result.Processed)
+	assert.Equal(suite.T(), 1, result.Duplicates)
+	assert.Equal(suite.T(), 3, result.Imported)
+	assert.True(suite.T(), suite.TestUser.HasData)
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "InsertBatch", 2)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendImportNotification", 1)
+	suite.NotificationService.AssertNumberOfCalls(suite.T(), "SendImportNotification", 1)
+
+	assert.Equal(suite.T(), models.ImportJobCompleted, job.State)
+	assert.NotNil(suite.T(), job.FinishedAt)
+	assert.Equal(suite.T(), 4, job.Processed)
+	assert.Equal(suite.T(), 4, job.Total)
+	assert.Equal(suite.T(), 3, job.Imported)
+	assert.Equal(suite.T(), 1, job.Duplicates)
+	suite.ImportJobRepository.AssertCalled(suite.T(), "Update", job)
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_RunJob_Resume() {
+	sut := NewImportService(suite.ImportJobRepository, suite.UserService, suite.HeartbeatService, suite.SummaryService, suite.AggregationService, nil, suite.MailService, suite.NotificationService)
+
+	heartbeats := suite.newHeartbeats()
+	importer := &testImporter{heartbeats: heartbeats}
+	job := models.NewImportJob(suite.TestUser, models.ImportSourceCsv)
+	job.Processed, job.Imported = 2, 2 // interrupted after the first batch
+	suite.TestUser.HasData = true
+
+	suite.HeartbeatService.On("CountByUserUncached", suite.TestUser).Return(int64(2), nil).Once()
+	suite.HeartbeatService.On("CountByUserUncached", suite.TestUser).Return(int64(3), nil).Once()
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+	suite.SummaryService.On("DeleteByUser", TestUserId).Return(nil)
+	suite.AggregationService.On("AggregateSummaries", mock.Anything).Return(nil)
+	suite.MailService.On("SendImportNotification", suite.TestUser, mock.Anything, 1).Return(nil)
+	suite.ImportJobRepository.On("Update", job).Return(job, nil)
+
+	result, err := sut.RunJob(job, importer, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, result.Processed)
+	assert.Equal(suite.T(), 3, job.Processed)
+	assert.Equal(suite.T(), 3, job.Imported)
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "InsertBatch", 1)
+	suite.HeartbeatService.AssertCalled(suite.T(), "InsertBatch", heartbeats[2:])
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_RunJob_Aborted() {
+	sut := NewImportService(suite.ImportJobRepository, suite.UserService, suite.HeartbeatService, suite.SummaryService, suite.AggregationService, nil, suite.MailService, suite.NotificationService)
+
+	importer := &testImporter{heartbeats: suite.newHeartbeats()[:1], failures: []string{"got status 502 from source instance"}}
+	job := models.NewImportJob(suite.TestUser, models.ImportSourceWakapi).WithParams(&models.ImportJobParams{BaseUrl: "https://wakapi.dev", ApiKey: "secret"})
+	suite.TestUser.HasData = true
+
+	suite.HeartbeatService.On("CountByUserUncached", suite.TestUser).Return(int64(1), nil)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+	suite.SummaryService.On("DeleteByUser", TestUserId).Return(nil)
+	suite.AggregationService.On("AggregateSummaries", mock.Anything).Return(nil)
+	suite.MailService.On("SendImportFailureNotification", suite.TestUser, 0, []string{"got status 502 from source instance"}).Return(nil)
+	suite.NotificationService.On("SendImportFailureNotification", suite.TestUser, 0, mock.Anything).Return(nil)
+	suite.ImportJobRepository.On("Update", job).Return(job, nil)
+
+	_, err := sut.RunJob(job, importer, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.ImportJobFailed, job.State)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendImportFailureNotification", 1)
+	suite.MailService.AssertNotCalled(suite.T(), "SendImportNotification", mock.Anything, mock.Anything, mock.Anything)
+	suite.NotificationService.AssertNotCalled(suite.T(), "SendImportNotification", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(suite.T(), []string{"got status 502 from source instance"}, job.ErrorList())
+	assert.Equal(suite.T(), "https://wakapi.dev", job.GetParams().BaseUrl)
+	assert.Empty(suite.T(), job.GetParams().ApiKey)
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_ResumeInterrupted() {
+	sut := NewImportService(suite.ImportJobRepository, suite.UserService, suite.HeartbeatService, suite.SummaryService, suite.AggregationService, nil, suite.MailService, suite.NotificationService)
+
+	job1 := models.NewImportJob(suite.TestUser, models.ImportSourceActivityWatch).WithParams(&models.ImportJobParams{FilePath: "/nonexistent/export.json"})
+	job2 := models.NewImportJob(suite.TestUser, models.ImportSourceWakapi)
+
+	suite.ImportJobRepository.On("GetByState", models.ImportJobRunning).Return([]*models.ImportJob{job1, job2}, nil)
+	suite.ImportJobRepository.On("Update", job1).Return(job1, nil)
+
+	sut.ResumeInterrupted()
+
+	// file got lost, so job can't be resumed
+	assert.Equal(suite.T(), models.ImportJobFailed, job1.State)
+	assert.Len(suite.T(), job1.ErrorList(), 1)
+
+	// migrations are resumed separately
+	assert.True(suite.T(), job2.IsRunning())
+	suite.ImportJobRepository.AssertNotCalled(suite.T(), "Update", job2)
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_Submit_InvalidParams() {
+	sut := NewImportService(suite.ImportJobRepository, suite.UserService, suite.HeartbeatService, suite.SummaryService, suite.AggregationService, nil, suite.MailService, suite.NotificationService)
+
+	job := models.NewImportJob(suite.TestUser, models.ImportSourceCsv).WithParams(&models.ImportJobParams{Timezone: "UTC"})
+
+	err := sut.Submit(job)
+
+	assert.True(suite.T(), errors.Is(err, imports.ErrInvalidCsvMapping))
+	suite.ImportJobRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
+
+func (suite *ImportServiceTestSuite) newHeartbeats() []*models.Heartbeat {
+	heartbeats := []*models.Heartbeat{
+		{UserID: TestUserId, Project: TestProject1, Time: models.CustomTime(time.Now().Add(-3 * time.Minute))},
+		{UserID: TestUserId, Project: TestProject1, Time: models.CustomTime(time.Now().Add(-2 * time.Minute))},
+		{UserID: TestUserId, Project: TestProject2, Time: models.CustomTime(time.Now().Add(-1 * time.Minute))},
+	}
+	for _, h := range heartbeats {
+		h.Hashed()
+	}
+	return heartbeats
+}
+
+// testImporter yields the given heartbeats and reports the given failures, if any, as having aborted the import
+type testImporter struct {
+	heartbeats []*models.Heartbeat
+	failures   []string
+}
+
+func (i *testImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	out := make(chan *models.Heartbeat)
+	go func() {
+		defer close(out)
+		for _, h := range i.heartbeats {
+			out <- h
+		}
+	}()
+	return out, nil
+}
+
+func (i *testImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return i.Import(user, time.Time{}, time.Now())
+}
+
+func (i *testImporter) Failures() []string {
+	return i.failures
+}
+
+func (i *testImporter) Aborted() bool {
+	return len(i.failures) > 0
+}
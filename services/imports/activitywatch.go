@@ -0,0 +1,357 @@
+package imports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// data example: https://docs.activitywatch.net/en/latest/buckets-and-events.html
+
+const OriginActivityWatch = "activitywatch"
+
+const (
+	ActivityWatchKindEditor = "editor"
+	ActivityWatchKindWindow = "window"
+	ActivityWatchKindWeb    = "web"
+)
+
+var activityWatchLanguages = map[string]string{
+	"c":               "C",
+	"cpp":             "C++",
+	"csharp":          "C#",
+	"css":             "CSS",
+	"dart":            "Dart",
+	"dockerfile":      "Docker",
+	"go":              "Go",
+	"html":            "HTML",
+	"java":            "Java",
+	"javascript":      "JavaScript",
+	"javascriptreact": "JavaScript",
+	"json":            "JSON",
+	"kotlin":          "Kotlin",
+	"markdown":        "Markdown",
+	"php":             "PHP",
+	"plaintext":       "Text",
+	"python":          "Python",
+	"ruby":            "Ruby",
+	"rust":            "Rust",
+	"scss":            "SCSS",
+	"shellscript":     "Bash",
+	"sql":             "SQL",
+	"swift":           "Swift",
+	"typescript":      "TypeScript",
+	"typescriptreact": "TSX",
+	"vue":             "Vue",
+	"xml":             "XML",
+	"yaml":            "YAML",
+}
+
+type activityWatchBucket struct {
+	Id       string `json:"id"`
+	Type     string `json:"type"`
+	Client   string `json:"client"`
+	Hostname string `json:"hostname"`
+}
+
+type activityWatchEvent struct {
+	Id        int64                  `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Duration  float64                `json:"duration"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// ActivityWatchImporter reads events from an activitywatch bucket export file and converts them to heartbeats. Events
+// of editor-, window- and web watchers are supported, others (e.g. afk) are skipped. As heartbeats are instantaneous,
+// while events carry a duration, every event is split into multiple heartbeats, each at most splitInterval apart.
+type ActivityWatchImporter struct {
+	file          io.ReadCloser
+	buckets       []string // bucket ids or kinds to import, all supported ones if empty
+	splitInterval time.Duration
+	queue         *artifex.Dispatcher
+	err           error
+	errLock       sync.RWMutex
+}
+
+func NewActivityWatchImporter(file io.ReadCloser, buckets []string, splitInterval time.Duration) *ActivityWatchImporter {
+	return &ActivityWatchImporter{
+		file:          file,
+		buckets:       buckets,
+		splitInterval: splitInterval,
+		queue:         config.GetQueue(config.QueueImports),
+	}
+}
+
+func (a *ActivityWatchImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	if a.splitInterval <= 0 {
+		a.file.Close()
+		return nil, errors.New("invalid split interval")
+	}
+
+	out := make(chan *models.Heartbeat)
+
+	process := func(user *models.User, minFrom time.Time, maxTo time.Time, out chan *models.Heartbeat) {
+		defer close(out)
+		defer a.file.Close()
+
+		logbuch.Info("running activitywatch import for user '%s'", user.ID)
+
+		err := streamActivityWatchBuckets(a.file, a.includes, func(bucket *activityWatchBucket, event *activityWatchEvent) {
+			for _, hb := range a.mapEvent(bucket, event, user) {
+				if hb.Time.T().Before(minFrom) || hb.Time.T().After(maxTo) {
+					continue
+				}
+				out <- hb
+			}
+		})
+		if err != nil {
+			config.Log().Error("failed to parse activitywatch export file for user '%s' - %v", user.ID, err)
+			a.setErr(err)
+		}
+	}
+
+	logbuch.Info("scheduling activitywatch import for user '%s' (interval [%v, %v])", user.ID, minFrom, maxTo)
+	if err := a.queue.Dispatch(func() {
+		process(user, minFrom, maxTo, out)
+	}); err != nil {
+		a.file.Close()
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (a *ActivityWatchImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return a.Import(user, config.BeginningOfWakatime(), time.Now())
+}
+
+// Err returns the error that caused the import to stop early, if any. Only meaningful after the stream was closed.
+func (a *ActivityWatchImporter) Err() error {
+	a.errLock.RLock()
+	defer a.errLock.RUnlock()
+	return a.err
+}
+
+func (a *ActivityWatchImporter) Failures() []string {
+	if err := a.Err(); err != nil {
+		return []string{err.Error()}
+	}
+	return []string{}
+}
+
+func (a *ActivityWatchImporter) Aborted() bool {
+	return a.Err() != nil
+}
+
+func (a *ActivityWatchImporter) setErr(err error) {
+	a.errLock.Lock()
+	defer a.errLock.Unlock()
+	a.err = err
+}
+
+func (a *ActivityWatchImporter) includes(bucket *activityWatchBucket) bool {
+	kind := activityWatchBucketKind(bucket)
+	if kind == "" {
+		return false
+	}
+	if len(a.buckets) == 0 {
+		return true
+	}
+	for _, b := range a.buckets {
+		if b == bucket.Id || b == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *ActivityWatchImporter) mapEvent(bucket *activityWatchBucket, event *activityWatchEvent, user *models.User) []*models.Heartbeat {
+	template := &models.Heartbeat{
+		User:            user,
+		UserID:          user.ID,
+		Machine:         bucket.Hostname,
+		OperatingSystem: "unknown",
+		Origin:          OriginActivityWatch,
+	}
+
+	switch activityWatchBucketKind(bucket) {
+	case ActivityWatchKindEditor:
+		template.Type = "file"
+		template.Category = "coding"
+		template.Entity = eventString(event, "file")
+		template.Project = filepath.Base(eventString(event, "project"))
+		template.Language = activityWatchLanguage(eventString(event, "language"))
+		template.Editor = strings.TrimPrefix(bucket.Client, "aw-watcher-")
+	case ActivityWatchKindWindow:
+		template.Type = "app"
+		template.Entity = eventString(event, "app")
+		template.Editor = OriginActivityWatch
+	case ActivityWatchKindWeb:
+		template.Type = "domain"
+		template.Category = "browsing"
+		if u, err := url.Parse(eventString(event, "url")); err == nil {
+			template.Entity = u.Hostname()
+		}
+		template.Editor = activityWatchBrowser(bucket)
+	}
+
+	if template.Project == "." {
+		template.Project = ""
+	}
+	if template.Entity == "" {
+		return []*models.Heartbeat{}
+	}
+
+	duration := time.Duration(event.Duration * float64(time.Second))
+	return expandHeartbeat(template, event.Timestamp, duration, a.splitInterval, fmt.Sprintf("%s/%d", bucket.Id, event.Id))
+}
+
+// streamActivityWatchBuckets walks the export file token by token and decodes events one after another. Events of a
+// bucket can only be streamed if the bucket's metadata precede them in the file, otherwise they're buffered.
+func streamActivityWatchBuckets(reader io.Reader, filter func(*activityWatchBucket) bool, callback func(*activityWatchBucket, *activityWatchEvent)) error {
+	decoder := json.NewDecoder(reader)
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return errors.New("invalid export file, expected json object")
+	}
+
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if key, _ := t.(string); key != "buckets" {
+			if err := skipValue(decoder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '{'); err != nil {
+			return errors.New("invalid export file, expected buckets to be an object")
+		}
+		for decoder.More() {
+			t, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			bucketId, _ := t.(string)
+			if err := streamActivityWatchBucket(decoder, bucketId, filter, callback); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func streamActivityWatchBucket(decoder *json.Decoder, bucketId string, filter func(*activityWatchBucket) bool, callback func(*activityWatchBucket, *activityWatchEvent)) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return errors.New("invalid export file, expected bucket to be an object")
+	}
+
+	bucket := &activityWatchBucket{Id: bucketId}
+	var buffered []*activityWatchEvent
+
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		key, _ := t.(string)
+		switch key {
+		case "id":
+			err = decoder.Decode(&bucket.Id)
+		case "type":
+			err = decoder.Decode(&bucket.Type)
+		case "client":
+			err = decoder.Decode(&bucket.Client)
+		case "hostname":
+			err = decoder.Decode(&bucket.Hostname)
+		case "events":
+			known := bucket.Type != ""
+			if known && !filter(bucket) {
+				err = skipValue(decoder)
+				break
+			}
+			if err := expectDelim(decoder, '['); err != nil {
+				return errors.New("invalid export file, expected events to be an array")
+			}
+			for decoder.More() {
+				var event activityWatchEvent
+				if err := decoder.Decode(&event); err != nil {
+					return fmt.Errorf("failed to decode event - %v", err)
+				}
+				if known {
+					callback(bucket, &event)
+				} else {
+					buffered = append(buffered, &event)
+				}
+			}
+			_, err = decoder.Token()
+		default:
+			err = skipValue(decoder)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(buffered) > 0 && filter(bucket) {
+		for _, event := range buffered {
+			callback(bucket, event)
+		}
+	}
+
+	_, err := decoder.Token()
+	return err
+}
+
+func activityWatchBucketKind(bucket *activityWatchBucket) string {
+	switch {
+	case bucket.Type == "app.editor.activity":
+		return ActivityWatchKindEditor
+	case bucket.Type == "currentwindow":
+		return ActivityWatchKindWindow
+	case bucket.Type == "web.tab.current":
+		return ActivityWatchKindWeb
+	}
+	return ""
+}
+
+func activityWatchBrowser(bucket *activityWatchBucket) string {
+	// bucket ids look like "aw-watcher-web-chrome" or "aw-watcher-web-firefox_<hostname>"
+	if browser, ok := strings.CutPrefix(strings.Split(bucket.Id, "_")[0], "aw-watcher-web-"); ok && browser != "" {
+		return browser
+	}
+	return "browser"
+}
+
+func activityWatchLanguage(languageId string) string {
+	if language, ok := activityWatchLanguages[strings.ToLower(languageId)]; ok {
+		return language
+	}
+	return languageId
+}
+
+func eventString(event *activityWatchEvent, key string) string {
+	if value, ok := event.Data[key].(string); ok {
+		return value
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package imports
+
+import (
+	"bytes"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+const testActivityWatchExport = `{
+  "buckets": {
+    "aw-watcher-vscode_host1": {
+      "id": "aw-watcher-vscode_host1",
+      "type": "app.editor.activity",
+      "client": "aw-watcher-vscode",
+      "hostname": "host1",
+      "events": [
+        { "id": 1, "timestamp": "2021-01-01T10:00:00Z", "duration": 300, "data": { "file": "/home/foo/wakapi/main.go", "project": "/home/foo/wakapi", "language": "go" } }
+      ]
+    },
+    "aw-watcher-window_host1": {
+      "events": [
+        { "id": 2, "timestamp": "2021-01-01T11:00:00Z", "duration": 0, "data": { "app": "Firefox", "title": "Wakapi" } }
+      ],
+      "id": "aw-watcher-window_host1",
+      "type": "currentwindow",
+      "client": "aw-watcher-window",
+      "hostname": "host1"
+    },
+    "aw-watcher-web-chrome": {
+      "id": "aw-watcher-web-chrome",
+      "type": "web.tab.current",
+      "client": "aw-client-web",
+      "hostname": "host1",
+      "events": [
+        { "id": 3, "timestamp": "2021-01-01T12:00:00Z", "duration": 60, "data": { "url": "https://wakapi.dev/summary", "title": "Wakapi" } }
+      ]
+    },
+    "aw-watcher-afk_host1": {
+      "id": "aw-watcher-afk_host1",
+      "type": "afkstatus",
+      "client": "aw-watcher-afk",
+      "hostname": "host1",
+      "events": [
+        { "id": 4, "timestamp": "2021-01-01T12:00:00Z", "duration": 60, "data": { "status": "afk" } }
+      ]
+    }
+  }
+}`
+
+func TestActivityWatchImporter_Import(t *testing.T) {
+	user := &models.User{ID: "foo"}
+
+	heartbeats, err := drain(NewActivityWatchImporter(io.NopCloser(bytes.NewBufferString(testActivityWatchExport)), []string{}, 2*time.Minute).ImportAll(user))
+	assert.Nil(t, err)
+
+	// 300 sec. editor event -> 0, 2, 4, 5 min.
+	assert.Len(t, heartbeats, 4+1+2)
+
+	assert.Equal(t, "/home/foo/wakapi/main.go", heartbeats[0].Entity)
+	assert.Equal(t, "wakapi", heartbeats[0].Project)
+	assert.Equal(t, "Go", heartbeats[0].Language)
+	assert.Equal(t, "vscode", heartbeats[0].Editor)
+	assert.Equal(t, "host1", heartbeats[0].Machine)
+	assert.Equal(t, OriginActivityWatch, heartbeats[0].Origin)
+	assert.Equal(t, time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), heartbeats[0].Time.T().UTC())
+	assert.Equal(t, time.Date(2021, 1, 1, 10, 5, 0, 0, time.UTC), heartbeats[3].Time.T().UTC())
+	assert.NotEqual(t, heartbeats[0].Hash, heartbeats[1].Hash)
+
+	assert.Equal(t, "app", heartbeats[4].Type)
+	assert.Equal(t, "Firefox", heartbeats[4].Entity)
+
+	assert.Equal(t, "domain", heartbeats[5].Type)
+	assert.Equal(t, "wakapi.dev", heartbeats[5].Entity)
+	assert.Equal(t, "chrome", heartbeats[5].Editor)
+}
+
+func TestActivityWatchImporter_Import_SelectedBuckets(t *testing.T) {
+	user := &models.User{ID: "foo"}
+
+	heartbeats, err := drain(NewActivityWatchImporter(io.NopCloser(bytes.NewBufferString(testActivityWatchExport)), []string{ActivityWatchKindWindow, "aw-watcher-web-chrome"}, 2*time.Minute).ImportAll(user))
+	assert.Nil(t, err)
+
+	assert.Len(t, heartbeats, 3)
+	assert.Equal(t, "app", heartbeats[0].Type)
+	assert.Equal(t, "domain", heartbeats[1].Type)
+}
+
+func TestActivityWatchImporter_Import_Invalid(t *testing.T) {
+	user := &models.User{ID: "foo"}
+
+	sut := NewActivityWatchImporter(io.NopCloser(bytes.NewBufferString(`[1, 2, 3]`)), []string{}, 2*time.Minute)
+	heartbeats, err := drain(sut.ImportAll(user))
+	assert.Nil(t, err)
+	assert.Empty(t, heartbeats)
+	assert.True(t, sut.Aborted())
+	assert.Len(t, sut.Failures(), 1)
+
+	sut = NewActivityWatchImporter(io.NopCloser(bytes.NewBufferString(testActivityWatchExport)), []string{}, 2*time.Minute)
+	_, _ = drain(sut.ImportAll(user))
+	assert.False(t, sut.Aborted())
+}
@@ -0,0 +1,383 @@
+package imports
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const OriginCsv = "csv"
+
+var ErrInvalidCsvMapping = errors.New("invalid column mapping, time and either entity or project are required")
+
+var csvTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+	"20060102",
+}
+
+// csvEpochMinDigits is the minimum number of digits for a plain number to be read as a unix timestamp (i.e. anything
+// after early 1973), unless the column is explicitly named as such, so that compact dates like '20240101' aren't
+const csvEpochMinDigits = 9
+
+// CsvImporter reads heartbeats from arbitrary csv files, given a mapping of columns to heartbeat fields. Rows carrying
+// a duration are expanded into multiple synthetic heartbeats, each at most splitInterval apart (in the future, these
+// could be stored as durations directly instead).
+type CsvImporter struct {
+	file          io.ReadCloser
+	mapping       *models.CsvImportMapping
+	splitInterval time.Duration
+	queue         *artifex.Dispatcher
+	errors        []*models.CsvImportRowError
+	errorsLock    sync.RWMutex
+}
+
+func NewCsvImporter(file io.ReadCloser, mapping *models.CsvImportMapping, splitInterval time.Duration) *CsvImporter {
+	return &CsvImporter{
+		file:          file,
+		mapping:       mapping,
+		splitInterval: splitInterval,
+		queue:         config.GetQueue(config.QueueImports),
+		errors:        []*models.CsvImportRowError{},
+	}
+}
+
+func (c *CsvImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	if !c.mapping.IsValid() {
+		c.file.Close()
+		return nil, ErrInvalidCsvMapping
+	}
+	if c.splitInterval <= 0 {
+		c.file.Close()
+		return nil, errors.New("invalid split interval")
+	}
+
+	reader, header, err := newCsvReader(c.file)
+	if err != nil {
+		c.file.Close()
+		return nil, err
+	}
+	columns, err := c.resolveColumns(header)
+	if err != nil {
+		c.file.Close()
+		return nil, err
+	}
+
+	out := make(chan *models.Heartbeat)
+
+	process := func(user *models.User, minFrom time.Time, maxTo time.Time, out chan *models.Heartbeat) {
+		defer close(out)
+		defer c.file.Close()
+
+		logbuch.Info("running csv import for user '%s'", user.ID)
+
+		c.readRecords(reader, columns, -1, func(record *models.CsvImportRecord) {
+			for _, hb := range c.toHeartbeats(record, user) {
+				if hb.Time.T().Before(minFrom) || hb.Time.T().After(maxTo) {
+					continue
+				}
+				out <- hb
+			}
+		})
+
+		if errs := c.Errors(); len(errs) > 0 {
+			logbuch.Warn("skipped %d invalid rows while importing csv for user '%s'", len(errs), user.ID)
+		}
+	}
+
+	logbuch.Info("scheduling csv import for user '%s' (interval [%v, %v])", user.ID, minFrom, maxTo)
+	if err := c.queue.Dispatch(func() {
+		process(user, minFrom, maxTo, out)
+	}); err != nil {
+		c.file.Close()
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (c *CsvImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return c.Import(user, config.BeginningOfWakatime(), time.Now())
+}
+
+// Preview maps the first n rows without importing anything, e.g. to let users verify their column mapping
+func (c *CsvImporter) Preview(n int) (*models.CsvImportPreview, error) {
+	if !c.mapping.IsValid() {
+		return nil, ErrInvalidCsvMapping
+	}
+
+	reader, header, err := newCsvReader(c.file)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := c.resolveColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.CsvImportPreview{Records: make([]*models.CsvImportRecord, 0, n)}
+	c.readRecords(reader, columns, n, func(record *models.CsvImportRecord) {
+		preview.Records = append(preview.Records, record)
+	})
+	preview.Errors = c.Errors()
+	return preview, nil
+}
+
+// Errors returns all rows that failed to be parsed so far
+func (c *CsvImporter) Errors() []*models.CsvImportRowError {
+	c.errorsLock.RLock()
+	defer c.errorsLock.RUnlock()
+	return append([]*models.CsvImportRowError{}, c.errors...)
+}
+
+func (c *CsvImporter) Failures() []string {
+	errs := c.Errors()
+	failures := make([]string, len(errs))
+	for i, e := range errs {
+		failures[i] = fmt.Sprintf("Line %d: %s", e.Line, e.Error)
+	}
+	return failures
+}
+
+func (c *CsvImporter) Aborted() bool {
+	return false
+}
+
+// readRecords reads up to limit rows (all, if negative) and passes the successfully mapped ones to the callback
+func (c *CsvImporter) readRecords(reader *csv.Reader, columns map[string]int, limit int, callback func(*models.CsvImportRecord)) {
+	for i := 0; limit < 0 || i < limit; i++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				c.addError(parseErr.Line, parseErr.Err)
+				continue
+			}
+			c.addError(0, err)
+			return
+		}
+		line, _ := reader.FieldPos(0)
+
+		record, err := c.mapRecord(row, columns)
+		if err != nil {
+			c.addError(line, err)
+			continue
+		}
+		record.Line = line
+		callback(record)
+	}
+}
+
+func (c *CsvImporter) mapRecord(row []string, columns map[string]int) (*models.CsvImportRecord, error) {
+	get := func(field string) string {
+		if idx, ok := columns[field]; ok && idx < len(row) {
+			return strings.TrimSpace(row[idx])
+		}
+		return ""
+	}
+
+	t, err := parseCsvTime(get("time"), c.mapping.Location, isCsvEpochColumn(c.mapping.Time))
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.CsvImportRecord{
+		Time:     t,
+		Entity:   get("entity"),
+		Project:  get("project"),
+		Language: get("language"),
+		Branch:   get("branch"),
+		Editor:   get("editor"),
+		Machine:  get("machine"),
+	}
+
+	if record.Entity == "" && record.Project == "" {
+		return nil, errors.New("neither entity nor project given")
+	}
+
+	if value := get("duration"); value != "" {
+		if record.Duration, err = parseCsvDuration(value); err != nil {
+			return nil, err
+		}
+		if record.Duration > MaxActivityDuration {
+			return nil, fmt.Errorf("duration '%s' exceeds the maximum of %v", value, MaxActivityDuration)
+		}
+	}
+
+	return record, nil
+}
+
+func (c *CsvImporter) toHeartbeats(record *models.CsvImportRecord, user *models.User) []*models.Heartbeat {
+	template := &models.Heartbeat{
+		User:     user,
+		UserID:   user.ID,
+		Entity:   record.Entity,
+		Type:     "file",
+		Category: "coding",
+		Project:  record.Project,
+		Language: record.Language,
+		Branch:   record.Branch,
+		Editor:   record.Editor,
+		Machine:  record.Machine,
+		Origin:   OriginCsv,
+	}
+	if template.Entity == "" {
+		template.Entity = record.Project
+		template.Type = "app"
+	}
+	return expandHeartbeat(template, record.Time, record.Duration, c.splitInterval, strconv.Itoa(record.Line))
+}
+
+func (c *CsvImporter) resolveColumns(header []string) (map[string]int, error) {
+	indices := make(map[string]int, len(header))
+	for i, h := range header {
+		indices[strings.TrimSpace(h)] = i
+	}
+
+	columns := map[string]int{}
+	for field, column := range map[string]string{
+		"time":     c.mapping.Time,
+		"entity":   c.mapping.Entity,
+		"project":  c.mapping.Project,
+		"language": c.mapping.Language,
+		"branch":   c.mapping.Branch,
+		"editor":   c.mapping.Editor,
+		"machine":  c.mapping.Machine,
+		"duration": c.mapping.Duration,
+	} {
+		if column == "" {
+			continue
+		}
+		idx, ok := indices[column]
+		if !ok {
+			return nil, fmt.Errorf("column '%s' not found", column)
+		}
+		columns[field] = idx
+	}
+	return columns, nil
+}
+
+func (c *CsvImporter) addError(line int, err error) {
+	c.errorsLock.Lock()
+	defer c.errorsLock.Unlock()
+	c.errors = append(c.errors, &models.CsvImportRowError{Line: line, Error: err.Error()})
+}
+
+// ReadCsvHeader returns the column names of the given csv file
+func ReadCsvHeader(file io.Reader) ([]string, error) {
+	_, header, err := newCsvReader(file)
+	return header, err
+}
+
+// newCsvReader guesses the file's delimiter from its first line and returns a reader positioned after the header
+func newCsvReader(file io.Reader) (*csv.Reader, []string, error) {
+	buffered := bufio.NewReader(file)
+	firstLine, err := buffered.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	firstLine = strings.TrimPrefix(firstLine, "\ufeff") // byte order mark
+	if strings.TrimSpace(firstLine) == "" {
+		return nil, nil, errors.New("csv file is empty")
+	}
+
+	delimiter, maxCount := ',', 0
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		if count := strings.Count(firstLine, string(d)); count > maxCount {
+			delimiter, maxCount = d, count
+		}
+	}
+
+	reader := csv.NewReader(io.MultiReader(strings.NewReader(firstLine), buffered))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = false
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read csv header - %v", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	return reader, header, nil
+}
+
+func parseCsvTime(value string, location *time.Location, epoch bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing time")
+	}
+	if unix, err := strconv.ParseFloat(value, 64); err == nil && (epoch || countIntegerDigits(value) >= csvEpochMinDigits) {
+		if unix > 1e11 { // milliseconds
+			unix /= 1000
+		}
+		return time.Unix(0, int64(unix*1e9)), nil
+	}
+	for _, format := range csvTimeFormats {
+		if t, err := time.ParseInLocation(format, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format '%s'", value)
+}
+
+func isCsvEpochColumn(column string) bool {
+	column = strings.ToLower(column)
+	return strings.Contains(column, "unix") || strings.Contains(column, "epoch")
+}
+
+func countIntegerDigits(value string) int {
+	integer, _, _ := strings.Cut(strings.TrimLeft(value, "+-"), ".")
+	return len(integer)
+}
+
+// parseCsvDuration accepts plain seconds, clock times (hh:mm or hh:mm:ss) and go durations (e.g. 1h30m)
+func parseCsvDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 && !math.IsInf(seconds, 0) {
+		if seconds > math.MaxInt64/float64(time.Second) {
+			return 0, fmt.Errorf("duration '%s' out of range", value)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	if parts := strings.Split(value, ":"); len(parts) == 2 || len(parts) == 3 {
+		var total time.Duration
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration '%s'", value)
+			}
+			total += time.Duration(n) * units[i]
+		}
+		return total, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid duration '%s'", value)
+}
@@ -0,0 +1,132 @@
+package imports
+
+import (
+	"bytes"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+const testCsv = "\ufeffDate;Task;Project;Lang;Seconds\n" +
+	"2021-01-01 10:00;main.go;wakapi;Go;300\n" +
+	"2021-01-01 11:00;;wakapi;;\n" +
+	"yesterday;main.go;wakapi;Go;60\n" +
+	"2021-01-01 12:00;;;Go;60\n" +
+	"2021-01-01 13:00;README.md;wakapi;Markdown;00:01:00\n"
+
+func TestCsvImporter_Preview(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Berlin")
+	mapping := &models.CsvImportMapping{Time: "Date", Entity: "Task", Project: "Project", Language: "Lang", Duration: "Seconds", Location: location}
+
+	preview, err := NewCsvImporter(io.NopCloser(bytes.NewBufferString(testCsv)), mapping, 2*time.Minute).Preview(3)
+	assert.Nil(t, err)
+
+	assert.Len(t, preview.Records, 2)
+	assert.Len(t, preview.Errors, 1)
+
+	assert.Equal(t, 2, preview.Records[0].Line)
+	assert.Equal(t, time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC), preview.Records[0].Time.UTC())
+	assert.Equal(t, "main.go", preview.Records[0].Entity)
+	assert.Equal(t, "Go", preview.Records[0].Language)
+	assert.Equal(t, 5*time.Minute, preview.Records[0].Duration)
+
+	assert.Equal(t, 4, preview.Errors[0].Line)
+	assert.Contains(t, preview.Errors[0].Error, "yesterday")
+}
+
+func TestCsvImporter_Import(t *testing.T) {
+	user := &models.User{ID: "foo"}
+	mapping := &models.CsvImportMapping{Time: "Date", Entity: "Task", Project: "Project", Language: "Lang", Duration: "Seconds", Location: time.UTC}
+
+	sut := NewCsvImporter(io.NopCloser(bytes.NewBufferString(testCsv)), mapping, 2*time.Minute)
+	heartbeats, err := drain(sut.ImportAll(user))
+	assert.Nil(t, err)
+
+	// 300 sec. -> 0, 2, 4, 5 min.; no duration -> single heartbeat; 1 min. -> 0, 1 min.
+	assert.Len(t, heartbeats, 4+1+2)
+	assert.Len(t, sut.Errors(), 2)
+
+	assert.Equal(t, "file", heartbeats[0].Type)
+	assert.Equal(t, OriginCsv, heartbeats[0].Origin)
+	assert.Equal(t, "app", heartbeats[4].Type)
+	assert.Equal(t, "wakapi", heartbeats[4].Entity)
+	assert.Equal(t, time.Date(2021, 1, 1, 13, 1, 0, 0, time.UTC), heartbeats[6].Time.T().UTC())
+}
+
+func TestCsvImporter_Import_DurationTooLong(t *testing.T) {
+	user := &models.User{ID: "foo"}
+	mapping := &models.CsvImportMapping{Time: "Date", Project: "Project", Duration: "Seconds", Location: time.UTC}
+	data := "Date,Project,Seconds\n2021-01-01 10:00,wakapi,1e15\n2021-01-01 11:00,wakapi,25:00:00\n2021-01-01 12:00,wakapi,60\n"
+
+	sut := NewCsvImporter(io.NopCloser(bytes.NewBufferString(data)), mapping, 2*time.Minute)
+	heartbeats, err := drain(sut.ImportAll(user))
+	assert.Nil(t, err)
+
+	assert.Len(t, heartbeats, 2)
+	assert.Len(t, sut.Failures(), 2)
+	assert.Contains(t, sut.Failures()[0], "Line 2")
+	assert.Contains(t, sut.Failures()[1], "Line 3")
+}
+
+func TestExpandHeartbeat_Bounded(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	heartbeats := expandHeartbeat(&models.Heartbeat{}, start, 1000*time.Hour, time.Hour, "1")
+	assert.Len(t, heartbeats, 25)
+	assert.Equal(t, start.Add(MaxActivityDuration), heartbeats[24].Time.T())
+
+	assert.Len(t, expandHeartbeat(&models.Heartbeat{}, start, time.Hour, 0, "1"), 2) // start and end only
+}
+
+func TestCsvImporter_Import_InvalidMapping(t *testing.T) {
+	user := &models.User{ID: "foo"}
+
+	_, err := NewCsvImporter(io.NopCloser(bytes.NewBufferString(testCsv)), &models.CsvImportMapping{Time: "Date", Location: time.UTC}, 2*time.Minute).ImportAll(user)
+	assert.ErrorIs(t, err, ErrInvalidCsvMapping)
+
+	_, err = NewCsvImporter(io.NopCloser(bytes.NewBufferString(testCsv)), &models.CsvImportMapping{Time: "Start", Project: "Project", Location: time.UTC}, 2*time.Minute).ImportAll(user)
+	assert.Error(t, err)
+}
+
+func TestParseCsvDuration(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"90":       90 * time.Second,
+		"1.5":      1500 * time.Millisecond,
+		"01:30":    90 * time.Minute,
+		"01:30:15": 90*time.Minute + 15*time.Second,
+		"1h30m":    90 * time.Minute,
+	} {
+		d, err := parseCsvDuration(value)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, d, value)
+	}
+
+	_, err := parseCsvDuration("-5")
+	assert.Error(t, err)
+	_, err = parseCsvDuration("abc")
+	assert.Error(t, err)
+}
+
+func TestParseCsvTime(t *testing.T) {
+	for value, expected := range map[string]time.Time{
+		"1704103200":          time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		"1704103200000":       time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		"20240101":            time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2024-01-01 10:00:00": time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+	} {
+		ts, err := parseCsvTime(value, time.UTC, false)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, ts.UTC(), value)
+	}
+
+	ts, err := parseCsvTime("20240101", time.UTC, true)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(20240101, 0), ts)
+
+	_, err = parseCsvTime("12345", time.UTC, false)
+	assert.Error(t, err)
+	assert.True(t, isCsvEpochColumn("Unix Time"))
+	assert.False(t, isCsvEpochColumn("date"))
+}
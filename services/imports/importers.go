@@ -1,6 +1,8 @@
 package imports
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/muety/wakapi/models"
 	"time"
 )
@@ -9,3 +11,59 @@ type DataImporter interface {
 	Import(*models.User, time.Time, time.Time) (<-chan *models.Heartbeat, error)
 	ImportAll(*models.User) (<-chan *models.Heartbeat, error)
 }
+
+// FailureReporter is implemented by importers, which skip over invalid data or stop early instead of failing entirely.
+// Failures are only complete once the importer's stream was closed.
+type FailureReporter interface {
+	Failures() []string
+	Aborted() bool // whether the import stopped early, i.e. data is missing
+}
+
+// MaxActivityDuration is the longest duration accepted for a single imported activity, longer ones are considered invalid
+const MaxActivityDuration = 24 * time.Hour
+
+// expandHeartbeat turns an activity with a duration into a series of heartbeats, each at most interval apart, so that
+// their sum amounts to the original duration when aggregated
+func expandHeartbeat(template *models.Heartbeat, start time.Time, duration, interval time.Duration, originId string) []*models.Heartbeat {
+	if duration < 0 {
+		duration = 0
+	}
+	if duration > MaxActivityDuration {
+		duration = MaxActivityDuration
+	}
+	if interval <= 0 {
+		interval = duration + 1 // start and end only
+	}
+	end := start.Add(duration)
+
+	heartbeats := make([]*models.Heartbeat, 0, int(duration/interval)+2)
+	for t, i := start, 0; ; t, i = t.Add(interval), i+1 {
+		if t.After(end) {
+			t = end
+		}
+		hb := *template
+		hb.Time = models.CustomTime(t)
+		hb.OriginId = fmt.Sprintf("%s/%d", originId, i)
+		heartbeats = append(heartbeats, (&hb).Hashed())
+		if !t.Before(end) {
+			break
+		}
+	}
+	return heartbeats
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	t, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("expected '%v', got '%v'", delim, t)
+	}
+	return nil
+}
+
+func skipValue(decoder *json.Decoder) error {
+	var skip json.RawMessage
+	return decoder.Decode(&skip)
+}
@@ -0,0 +1,221 @@
+package imports
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const OriginWakapi = "wakapi"
+
+const (
+	wakapiExportPageSize = 2500
+	wakapiMaxAttempts    = 3
+	wakapiRetryDelay     = 5 * time.Second
+)
+
+var (
+	ErrWakapiExportUnsupported = errors.New("the source instance does not support data exports, please ask its operator to update wakapi")
+	ErrWakapiUnreachable       = errors.New("the source instance could not be reached")
+)
+
+// WakapiImporter migrates a user's heartbeats from another wakapi instance, using its native export api. Heartbeats
+// are fetched page by page in the order they were created on the source instance, so an interrupted import can be
+// resumed from the last page persisted (see WithCursor).
+type WakapiImporter struct {
+	baseUrl    string
+	apiKey     string
+	after      uint64
+	httpClient *http.Client
+	queue      *artifex.Dispatcher
+	err        error
+	errLock    sync.RWMutex
+}
+
+func NewWakapiImporter(baseUrl, apiKey string) *WakapiImporter {
+	return &WakapiImporter{
+		baseUrl:    NormalizeWakapiUrl(baseUrl),
+		apiKey:     apiKey,
+		httpClient: utils.NewPublicHttpClient(30*time.Second, config.Get().Security.AllowPrivateNetworks),
+		queue:      config.GetQueue(config.QueueImports),
+	}
+}
+
+// WithCursor makes the import continue after the heartbeat with the given id (as known to the source instance)
+func (w *WakapiImporter) WithCursor(after uint64) *WakapiImporter {
+	w.after = after
+	return w
+}
+
+func (w *WakapiImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	out := make(chan *models.Heartbeat)
+
+	process := func(user *models.User, minFrom time.Time, maxTo time.Time, out chan *models.Heartbeat) {
+		defer close(out)
+		logbuch.Info("running wakapi import for user '%s' from '%s' (after heartbeat %d)", user.ID, w.baseUrl, w.after)
+
+		for after := w.after; ; {
+			page, err := w.fetchPage(after, minFrom, maxTo)
+			if err != nil {
+				config.Log().Error("failed to fetch heartbeats after %d while importing from '%s' for user '%s' - %v", after, w.baseUrl, user.ID, err)
+				w.setErr(err)
+				return
+			}
+
+			for _, h := range page.Data {
+				out <- mapWakapiHeartbeat(h, user)
+			}
+
+			if !page.HasMore || page.Cursor <= after {
+				return
+			}
+			after = page.Cursor
+		}
+	}
+
+	logbuch.Info("scheduling wakapi import for user '%s' (interval [%v, %v])", user.ID, minFrom, maxTo)
+	if err := w.queue.Dispatch(func() {
+		process(user, minFrom, maxTo, out)
+	}); err != nil {
+		config.Log().Error("failed to dispatch wakapi import job for user '%s', %v", user.ID, err)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (w *WakapiImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return w.Import(user, time.Time{}, time.Now().Add(24*time.Hour))
+}
+
+// Err returns the error that caused the import to stop early, if any. Only meaningful after the stream was closed.
+func (w *WakapiImporter) Err() error {
+	w.errLock.RLock()
+	defer w.errLock.RUnlock()
+	return w.err
+}
+
+func (w *WakapiImporter) Failures() []string {
+	if err := w.Err(); err != nil {
+		return []string{err.Error()}
+	}
+	return []string{}
+}
+
+func (w *WakapiImporter) Aborted() bool {
+	return w.Err() != nil
+}
+
+// FetchSettings retrieves the user's aliases, project labels and language mappings from the source instance
+func (w *WakapiImporter) FetchSettings() (*models.SettingsExport, error) {
+	var settings models.SettingsExport
+	if err := w.get("/api/export/settings", nil, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// FetchSummary retrieves the user's summary for the 'all_time' interval from the source instance, e.g. to compare totals after the import
+func (w *WakapiImporter) FetchSummary() (*models.Summary, error) {
+	var summary models.Summary
+	if err := w.get("/api/summary", url.Values{"interval": []string{"all_time"}}, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (w *WakapiImporter) fetchPage(after uint64, minFrom, maxTo time.Time) (page *models.HeartbeatsExportPage, err error) {
+	query := url.Values{
+		"after": []string{strconv.FormatUint(after, 10)},
+		"limit": []string{strconv.Itoa(wakapiExportPageSize)},
+		"to":    []string{maxTo.Format(time.RFC3339)},
+	}
+	if !minFrom.IsZero() {
+		query.Set("from", minFrom.Format(time.RFC3339))
+	}
+
+	for attempt := 1; attempt <= wakapiMaxAttempts; attempt++ {
+		page = &models.HeartbeatsExportPage{}
+		if err = w.get("/api/export/heartbeats", query, page); err == nil || errors.Is(err, ErrWakapiExportUnsupported) {
+			return page, err
+		}
+		if attempt < wakapiMaxAttempts {
+			logbuch.Warn("failed to fetch heartbeats from '%s' (attempt %d of %d), retrying - %v", w.baseUrl, attempt, wakapiMaxAttempts, err)
+			time.Sleep(wakapiRetryDelay)
+		}
+	}
+	return nil, err
+}
+
+func (w *WakapiImporter) get(path string, query url.Values, target interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, w.baseUrl+path, nil)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(w.apiKey))))
+	req.Header.Set("Accept", "application/json")
+
+	res, err := w.httpClient.Do(req)
+	if err != nil {
+		// details of connection errors are not passed on, as they would allow to probe the server's network
+		logbuch.Warn("failed to request '%s' - %v", w.baseUrl+path, err)
+		if errors.Is(err, utils.ErrNonPublicAddress) {
+			return utils.ErrNonPublicAddress
+		}
+		return ErrWakapiUnreachable
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return ErrWakapiExportUnsupported
+	case res.StatusCode == http.StatusUnauthorized:
+		return errors.New("invalid api key")
+	case res.StatusCode >= 400:
+		return fmt.Errorf("got status %d from source instance", res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(target)
+}
+
+func (w *WakapiImporter) setErr(err error) {
+	w.errLock.Lock()
+	defer w.errLock.Unlock()
+	w.err = err
+}
+
+// NormalizeWakapiUrl turns user input like 'wakapi.dev/api/' into a base url like 'https://wakapi.dev'
+func NormalizeWakapiUrl(baseUrl string) string {
+	baseUrl = strings.TrimSpace(baseUrl)
+	if !strings.HasPrefix(baseUrl, "http://") && !strings.HasPrefix(baseUrl, "https://") {
+		baseUrl = "https://" + baseUrl
+	}
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	baseUrl = strings.TrimSuffix(baseUrl, "/api")
+	return baseUrl
+}
+
+func mapWakapiHeartbeat(h *models.Heartbeat, user *models.User) *models.Heartbeat {
+	hb := *h
+	hb.ID = 0
+	hb.User = user
+	hb.UserID = user.ID
+	hb.Hash = ""
+	hb.Origin = OriginWakapi
+	hb.OriginId = strconv.FormatUint(h.ID, 10)
+	return (&hb).Hashed()
+}
@@ -0,0 +1,134 @@
+package imports
+
+import (
+	"encoding/json"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newWakapiTestServer(t *testing.T) *httptest.Server {
+	allowPrivateNetworks(true) // stub server listens on loopback
+
+	heartbeats := []*models.Heartbeat{
+		{ID: 4, Entity: "main.go", Project: "wakapi", Language: "Go", Time: models.CustomTime(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC))},
+		{ID: 7, Entity: "README.md", Project: "wakapi", Language: "Markdown", Time: models.CustomTime(time.Date(2023, 1, 1, 10, 1, 0, 0, time.UTC))},
+		{ID: 9, Entity: "main.py", Project: "anchr", Language: "Python", Time: models.CustomTime(time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC))},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/export/heartbeats", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Basic YXBpLWtleQ==", r.Header.Get("Authorization"))
+
+		// serve two heartbeats per page
+		after, _ := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+		page := &models.HeartbeatsExportPage{Data: []*models.Heartbeat{}}
+		for _, h := range heartbeats {
+			if h.ID > after {
+				page.Data = append(page.Data, h)
+			}
+		}
+		if len(page.Data) > 2 {
+			page.Data = page.Data[:2]
+		}
+		page.Cursor = page.Data[len(page.Data)-1].ID
+		page.HasMore = page.Cursor != 9
+		json.NewEncoder(w).Encode(page)
+	})
+	mux.HandleFunc("/api/export/settings", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&models.SettingsExport{
+			Aliases:     []*models.Alias{{ID: 1, Type: models.SummaryProject, UserID: "bar", Key: "wakapi", Value: "wakapi-mobile"}},
+			LabelColors: map[string]string{"work": "#ff0000"},
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestWakapiImporter_Import(t *testing.T) {
+	server := newWakapiTestServer(t)
+	defer server.Close()
+
+	user := &models.User{ID: "foo"}
+	sut := NewWakapiImporter(server.URL+"/api/", "api-key")
+
+	heartbeats, err := drain(sut.ImportAll(user))
+	assert.Nil(t, err)
+	assert.Nil(t, sut.Err())
+
+	assert.Len(t, heartbeats, 3)
+	assert.Equal(t, "main.go", heartbeats[0].Entity)
+	assert.Equal(t, time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), heartbeats[0].Time.T().UTC())
+	assert.Equal(t, uint64(0), heartbeats[0].ID)
+	assert.Equal(t, "foo", heartbeats[0].UserID)
+	assert.Equal(t, OriginWakapi, heartbeats[0].Origin)
+	assert.Equal(t, "4", heartbeats[0].OriginId)
+	assert.NotEmpty(t, heartbeats[0].Hash)
+	assert.Equal(t, "9", heartbeats[2].OriginId)
+}
+
+func TestWakapiImporter_Import_Resume(t *testing.T) {
+	server := newWakapiTestServer(t)
+	defer server.Close()
+
+	user := &models.User{ID: "foo"}
+
+	heartbeats, err := drain(NewWakapiImporter(server.URL, "api-key").WithCursor(7).ImportAll(user))
+	assert.Nil(t, err)
+
+	assert.Len(t, heartbeats, 1)
+	assert.Equal(t, "main.py", heartbeats[0].Entity)
+}
+
+func TestWakapiImporter_Import_Unsupported(t *testing.T) {
+	allowPrivateNetworks(true)
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	sut := NewWakapiImporter(server.URL, "api-key")
+
+	heartbeats, err := drain(sut.ImportAll(&models.User{ID: "foo"}))
+	assert.Nil(t, err)
+	assert.Empty(t, heartbeats)
+	assert.ErrorIs(t, sut.Err(), ErrWakapiExportUnsupported)
+}
+
+func TestWakapiImporter_FetchSettings(t *testing.T) {
+	server := newWakapiTestServer(t)
+	defer server.Close()
+
+	settings, err := NewWakapiImporter(server.URL, "api-key").FetchSettings()
+	assert.Nil(t, err)
+	assert.Len(t, settings.Aliases, 1)
+	assert.Equal(t, "wakapi-mobile", settings.Aliases[0].Value)
+	assert.Equal(t, "#ff0000", settings.LabelColors["work"])
+}
+
+func TestWakapiImporter_FetchSummary_PrivateAddress(t *testing.T) {
+	server := newWakapiTestServer(t)
+	defer server.Close()
+	allowPrivateNetworks(false)
+
+	_, err := NewWakapiImporter(server.URL, "api-key").FetchSummary()
+	assert.Equal(t, utils.ErrNonPublicAddress, err)
+
+	_, err = NewWakapiImporter("http://wakapi.invalid", "api-key").FetchSummary()
+	assert.Equal(t, ErrWakapiUnreachable, err)
+}
+
+func TestNormalizeWakapiUrl(t *testing.T) {
+	assert.Equal(t, "https://wakapi.dev", NormalizeWakapiUrl("wakapi.dev"))
+	assert.Equal(t, "https://wakapi.dev", NormalizeWakapiUrl(" https://wakapi.dev/api/ "))
+	assert.Equal(t, "http://localhost:3000/wakapi", NormalizeWakapiUrl("http://localhost:3000/wakapi/"))
+}
+
+func allowPrivateNetworks(allow bool) {
+	cfg := config.Empty()
+	cfg.Security.AllowPrivateNetworks = allow
+	config.Set(cfg)
+}
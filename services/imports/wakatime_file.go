@@ -0,0 +1,177 @@
+package imports
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
+)
+
+// WakatimeFileImporter reads heartbeats from a json export file (as downloadable from https://wakatime.com/settings/account)
+// instead of fetching them from the api. The file may optionally be gzip-compressed and is parsed day by day, so it
+// doesn't have to be kept in memory entirely.
+type WakatimeFileImporter struct {
+	file    io.ReadCloser
+	apiKey  string // optional, only used to resolve user agent- and machine name ids
+	queue   *artifex.Dispatcher
+	err     error
+	errLock sync.RWMutex
+}
+
+func NewWakatimeFileImporter(file io.ReadCloser, apiKey string) *WakatimeFileImporter {
+	return &WakatimeFileImporter{
+		file:   file,
+		apiKey: apiKey,
+		queue:  config.GetQueue(config.QueueImports),
+	}
+}
+
+func (w *WakatimeFileImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	reader, err := w.open()
+	if err != nil {
+		w.file.Close()
+		return nil, err
+	}
+
+	out := make(chan *models.Heartbeat)
+
+	process := func(user *models.User, minFrom time.Time, maxTo time.Time, out chan *models.Heartbeat) {
+		defer close(out)
+		defer w.file.Close()
+		defer reader.Close()
+
+		logbuch.Info("running wakatime file import for user '%s'", user.ID)
+
+		userAgents := map[string]*wakatime.UserAgentEntry{}
+		machineNames := map[string]*wakatime.MachineEntry{}
+		if w.apiKey != "" {
+			baseUrl := user.WakaTimeURL(config.WakatimeApiUrl)
+			if data, err := fetchUserAgents(baseUrl, w.apiKey); err == nil {
+				userAgents = data
+			} else {
+				logbuch.Warn("failed to fetch user agents for wakatime file import of user '%s', falling back to unknown - %v", user.ID, err)
+			}
+			if data, err := fetchMachineNames(baseUrl, w.apiKey); err == nil {
+				machineNames = data
+			} else {
+				logbuch.Warn("failed to fetch machine names for wakatime file import of user '%s', falling back to ids - %v", user.ID, err)
+			}
+		}
+
+		err := streamJsonExportDays(reader, func(day *wakatime.JsonExportDay) {
+			for _, h := range day.Heartbeats {
+				hb := mapHeartbeat(h, userAgents, machineNames, user)
+				if hb.Time.T().Before(minFrom) || hb.Time.T().After(maxTo) {
+					continue
+				}
+				out <- hb
+			}
+		})
+		if err != nil {
+			config.Log().Error("failed to parse wakatime export file for user '%s' - %v", user.ID, err)
+			w.setErr(err)
+		}
+	}
+
+	logbuch.Info("scheduling wakatime file import for user '%s' (interval [%v, %v])", user.ID, minFrom, maxTo)
+	if err := w.queue.Dispatch(func() {
+		process(user, minFrom, maxTo, out)
+	}); err != nil {
+		reader.Close()
+		w.file.Close()
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (w *WakatimeFileImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return w.Import(user, config.BeginningOfWakatime(), time.Now())
+}
+
+// Err returns the error that caused the import to stop early, if any. Only meaningful after the stream was closed.
+func (w *WakatimeFileImporter) Err() error {
+	w.errLock.RLock()
+	defer w.errLock.RUnlock()
+	return w.err
+}
+
+func (w *WakatimeFileImporter) Failures() []string {
+	if err := w.Err(); err != nil {
+		return []string{err.Error()}
+	}
+	return []string{}
+}
+
+func (w *WakatimeFileImporter) Aborted() bool {
+	return w.Err() != nil
+}
+
+func (w *WakatimeFileImporter) setErr(err error) {
+	w.errLock.Lock()
+	defer w.errLock.Unlock()
+	w.err = err
+}
+
+// open transparently decompresses gzip-compressed files, detected by their magic number. The returned reader has to be
+// closed in addition to the underlying file.
+func (w *WakatimeFileImporter) open() (io.ReadCloser, error) {
+	reader := bufio.NewReader(w.file)
+	magic, err := reader.Peek(2)
+	if err != nil {
+		return nil, errors.New("export file is empty or unreadable")
+	}
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(reader)
+	}
+	return io.NopCloser(reader), nil
+}
+
+// streamJsonExportDays walks the export's top-level object token by token and decodes the days one after another
+func streamJsonExportDays(reader io.Reader, callback func(*wakatime.JsonExportDay)) error {
+	decoder := json.NewDecoder(reader)
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return errors.New("invalid export file, expected json object")
+	}
+
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		if key, _ := t.(string); key != "days" {
+			if err := skipValue(decoder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '['); err != nil {
+			return errors.New("invalid export file, expected days to be an array")
+		}
+		for decoder.More() {
+			var day wakatime.JsonExportDay
+			if err := decoder.Decode(&day); err != nil {
+				return fmt.Errorf("failed to decode day - %v", err)
+			}
+			callback(&day)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
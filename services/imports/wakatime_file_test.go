@@ -0,0 +1,84 @@
+package imports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+const testExport = `{
+  "user": { "id": "foo", "username": "foo" },
+  "range": { "start": 1609459200, "end": 1609718399 },
+  "days": [
+    { "date": "2021-01-01", "heartbeats": [
+      { "id": "1", "entity": "main.go", "type": "file", "project": "wakapi", "language": "Go", "time": 1609491600.5, "user_agent_id": "wakatime/v1.35.4 (linux-5.10.0) go1.17 vscode/1.62.0 vscode-wakatime/17.1.0", "machine_name_id": "m1" },
+      { "id": "1", "entity": "main.go", "type": "file", "project": "wakapi", "language": "Go", "time": 1609491600.5, "user_agent_id": "wakatime/v1.35.4 (linux-5.10.0) go1.17 vscode/1.62.0 vscode-wakatime/17.1.0", "machine_name_id": "m1" }
+    ] },
+    { "date": "2021-01-02", "heartbeats": [] },
+    { "date": "2021-01-03", "heartbeats": [
+      { "id": "2", "entity": "README.md", "type": "file", "project": "wakapi", "language": "Markdown", "time": 1609664400, "user_agent_id": "ua2", "machine_name_id": "m1" }
+    ] }
+  ]
+}`
+
+func TestWakatimeFileImporter_Import(t *testing.T) {
+	user := &models.User{ID: "foo"}
+
+	heartbeats, err := drain(NewWakatimeFileImporter(io.NopCloser(bytes.NewBufferString(testExport)), "").ImportAll(user))
+	assert.Nil(t, err)
+
+	assert.Len(t, heartbeats, 3) // duplicates are skipped upon insert
+	assert.Equal(t, heartbeats[0].Hash, heartbeats[1].Hash)
+	assert.Equal(t, "main.go", heartbeats[0].Entity)
+	assert.Equal(t, "vscode", heartbeats[0].Editor)
+	assert.Equal(t, "Linux", heartbeats[0].OperatingSystem)
+	assert.Equal(t, "m1", heartbeats[0].Machine)
+	assert.Equal(t, OriginWakatime, heartbeats[0].Origin)
+	assert.NotEmpty(t, heartbeats[0].Hash)
+	assert.Equal(t, "unknown", heartbeats[2].Editor)
+}
+
+func TestWakatimeFileImporter_Import_Gzip(t *testing.T) {
+	user := &models.User{ID: "foo"}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(testExport))
+	gz.Close()
+
+	from := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	heartbeats, err := drain(NewWakatimeFileImporter(io.NopCloser(&buf), "").Import(user, from, time.Now()))
+	assert.Nil(t, err)
+
+	assert.Len(t, heartbeats, 1)
+	assert.Equal(t, "README.md", heartbeats[0].Entity)
+}
+
+func TestWakatimeFileImporter_Import_Invalid(t *testing.T) {
+	user := &models.User{ID: "foo"}
+
+	_, err := NewWakatimeFileImporter(io.NopCloser(bytes.NewBufferString("")), "").ImportAll(user)
+	assert.Error(t, err)
+
+	sut := NewWakatimeFileImporter(io.NopCloser(bytes.NewBufferString(`[1, 2, 3]`)), "")
+	heartbeats, err := drain(sut.ImportAll(user))
+	assert.Nil(t, err)
+	assert.Empty(t, heartbeats)
+	assert.True(t, sut.Aborted())
+	assert.Len(t, sut.Failures(), 1)
+}
+
+func drain(stream <-chan *models.Heartbeat, err error) ([]*models.Heartbeat, error) {
+	if err != nil {
+		return nil, err
+	}
+	heartbeats := make([]*models.Heartbeat, 0)
+	for hb := range stream {
+		heartbeats = append(heartbeats, hb)
+	}
+	return heartbeats, nil
+}
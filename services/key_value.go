@@ -22,6 +22,10 @@ func (srv *KeyValueService) GetString(key string) (*models.KeyStringValue, error
 	return srv.repository.GetString(key)
 }
 
+func (srv *KeyValueService) GetStrings(keys []string) ([]*models.KeyStringValue, error) {
+	return srv.repository.GetStrings(keys)
+}
+
 func (srv *KeyValueService) GetByPrefix(prefix string) ([]*models.KeyStringValue, error) {
 	return srv.repository.Search(prefix + "%")
 }
@@ -9,17 +9,21 @@ import (
 	"time"
 )
 
+const cacheKeyDefaultMappings = "--defaults"
+
 type LanguageMappingService struct {
-	config     *config.Config
-	cache      *cache.Cache
-	repository repositories.ILanguageMappingRepository
+	config            *config.Config
+	cache             *cache.Cache
+	repository        repositories.ILanguageMappingRepository
+	defaultRepository repositories.IDefaultLanguageMappingRepository
 }
 
-func NewLanguageMappingService(languageMappingsRepo repositories.ILanguageMappingRepository) *LanguageMappingService {
+func NewLanguageMappingService(languageMappingsRepo repositories.ILanguageMappingRepository, defaultLanguageMappingsRepo repositories.IDefaultLanguageMappingRepository) *LanguageMappingService {
 	return &LanguageMappingService{
-		config:     config.Get(),
-		repository: languageMappingsRepo,
-		cache:      cache.New(24*time.Hour, 24*time.Hour),
+		config:            config.Get(),
+		repository:        languageMappingsRepo,
+		defaultRepository: defaultLanguageMappingsRepo,
+		cache:             cache.New(24*time.Hour, 24*time.Hour),
 	}
 }
 
@@ -40,15 +44,25 @@ func (srv *LanguageMappingService) GetByUser(userId string) ([]*models.LanguageM
 	return mappings, nil
 }
 
-func (srv *LanguageMappingService) ResolveByUser(userId string) (map[string]string, error) {
-	mappings := srv.getServerMappings()
+func (srv *LanguageMappingService) ResolveByUser(userId string) ([]*models.LanguageMapping, error) {
 	userMappings, err := srv.GetByUser(userId)
 	if err != nil {
 		return nil, err
 	}
 
+	// user mappings take precedence over server mappings for the same pattern
+	mappings := make([]*models.LanguageMapping, 0, len(userMappings))
+	overridden := make(map[string]bool, len(userMappings))
 	for _, m := range userMappings {
-		mappings[m.Extension] = m.Language
+		mappings = append(mappings, m)
+		if m.GetType() == models.LanguageMappingTypeExtension {
+			overridden[m.Extension] = true
+		}
+	}
+	for ext, lang := range srv.getServerMappings() {
+		if !overridden[ext] {
+			mappings = append(mappings, &models.LanguageMapping{Extension: ext, Type: models.LanguageMappingTypeExtension, Language: lang})
+		}
 	}
 	return mappings, nil
 }
@@ -72,6 +86,48 @@ func (srv *LanguageMappingService) Delete(mapping *models.LanguageMapping) error
 	return err
 }
 
+func (srv *LanguageMappingService) GetDefaults() ([]*models.DefaultLanguageMapping, error) {
+	if mappings, found := srv.cache.Get(cacheKeyDefaultMappings); found {
+		return mappings.([]*models.DefaultLanguageMapping), nil
+	}
+
+	mappings, err := srv.defaultRepository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(cacheKeyDefaultMappings, mappings, cache.DefaultExpiration)
+	return mappings, nil
+}
+
+func (srv *LanguageMappingService) ResolveDefaults() ([]*models.LanguageMapping, error) {
+	defaultMappings, err := srv.GetDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := make([]*models.LanguageMapping, len(defaultMappings))
+	for i, m := range defaultMappings {
+		mappings[i] = m.AsLanguageMapping()
+	}
+	return mappings, nil
+}
+
+func (srv *LanguageMappingService) CreateDefault(mapping *models.DefaultLanguageMapping) (*models.DefaultLanguageMapping, error) {
+	result, err := srv.defaultRepository.Insert(mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.cache.Delete(cacheKeyDefaultMappings)
+	return result, nil
+}
+
+func (srv *LanguageMappingService) DeleteDefault(id uint) error {
+	err := srv.defaultRepository.Delete(id)
+	srv.cache.Delete(cacheKeyDefaultMappings)
+	return err
+}
+
 func (srv *LanguageMappingService) getServerMappings() map[string]string {
 	// https://dave.cheney.net/2017/04/30/if-a-map-isnt-a-reference-variable-what-is-it
 	return srv.config.App.GetCustomLanguages()
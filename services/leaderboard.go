@@ -99,10 +99,26 @@ func (srv *LeaderboardService) Schedule() {
 }
 
 func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval *models.IntervalKey, by []uint8) error {
+	return srv.computeLeaderboard(users, interval, by, nil)
+}
+
+// ComputePrivateLeaderboard regenerates the given private leaderboard from scratch for the given members
+func (srv *LeaderboardService) ComputePrivateLeaderboard(leaderboard *models.PrivateLeaderboard, users []*models.User) error {
+	scope := leaderboard.Scope()
+	if scope == nil {
+		return fmt.Errorf("invalid scope '%s' of private leaderboard %d", leaderboard.Interval, leaderboard.ID)
+	}
+	if err := srv.repository.DeleteByLeaderboard(leaderboard.ID); err != nil {
+		return err
+	}
+	return srv.computeLeaderboard(users, scope, []uint8{models.SummaryLanguage}, &leaderboard.ID)
+}
+
+func (srv *LeaderboardService) computeLeaderboard(users []*models.User, interval *models.IntervalKey, by []uint8, leaderboardId *uint) error {
 	logbuch.Info("generating leaderboard (%s) for %d users (%d aggregations)", (*interval)[0], len(users), len(by))
 
 	for _, user := range users {
-		if err := srv.repository.DeleteByUserAndInterval(user.ID, interval); err != nil {
+		if err := srv.repository.DeleteByUserAndInterval(user.ID, interval, leaderboardId); err != nil {
 			config.Log().Error("failed to delete leaderboard items for user %s (interval %s) - %v", user.ID, (*interval)[0], err)
 			continue
 		}
@@ -112,6 +128,7 @@ func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval
 			config.Log().Error("failed to generate general leaderboard for user %s - %v", user.ID, err)
 			continue
 		}
+		item.LeaderboardID = leaderboardId
 
 		if err := srv.repository.InsertBatch([]*models.LeaderboardItem{item}); err != nil {
 			config.Log().Error("failed to persist general leaderboard for user %s - %v", user.ID, err)
@@ -128,6 +145,9 @@ func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval
 			if len(items) == 0 {
 				continue
 			}
+			for _, item := range items {
+				item.LeaderboardID = leaderboardId
+			}
 
 			if err := srv.repository.InsertBatch(items); err != nil {
 				config.Log().Error("failed to persist aggregated (by %s) leaderboard for user %s - %v", models.GetEntityColumn(by), user.ID, err)
@@ -146,6 +166,10 @@ func (srv *LeaderboardService) ExistsAnyByUser(userId string) (bool, error) {
 	return count > 0, err
 }
 
+func (srv *LeaderboardService) GetAllByUser(userId string) ([]*models.LeaderboardItem, error) {
+	return srv.repository.GetAllByUser(userId)
+}
+
 func (srv *LeaderboardService) CountUsers(excludeZero bool) (int64, error) {
 	// check cache
 	cacheKey := fmt.Sprintf("count_total_%v", excludeZero)
@@ -175,22 +199,39 @@ func (srv *LeaderboardService) GetAggregatedByInterval(interval *models.Interval
 		return cacheResult.([]*models.LeaderboardItemRanked), nil
 	}
 
-	items, err := srv.repository.GetAllAggregatedByInterval(interval, by, pageParams.Limit(), pageParams.Offset())
+	items, err := srv.repository.GetAllAggregatedByInterval(interval, by, nil, pageParams.Limit(), pageParams.Offset())
 	if err != nil {
 		return nil, err
 	}
 
 	if resolveUsers {
-		users, err := srv.userService.GetManyMapped(models.Leaderboard(items).UserIDs())
-		if err != nil {
-			config.Log().Error("failed to resolve users for leaderboard item - %v", err)
-		} else {
-			for _, item := range items {
-				if u, ok := users[item.UserID]; ok {
-					item.User = u
-				}
-			}
-		}
+		srv.resolveUsers(items)
+	}
+
+	srv.cache.SetDefault(cacheKey, items)
+	return items, nil
+}
+
+// GetPrivateLeaderboard returns all items of the given private leaderboard, which is never paginated
+func (srv *LeaderboardService) GetPrivateLeaderboard(leaderboard *models.PrivateLeaderboard, by *uint8, resolveUsers bool) (models.Leaderboard, error) {
+	scope := leaderboard.Scope()
+	if scope == nil {
+		return nil, fmt.Errorf("invalid scope '%s' of private leaderboard %d", leaderboard.Interval, leaderboard.ID)
+	}
+
+	// check cache
+	cacheKey := srv.getHash(scope, by, "", nil) + "__private__" + strconv.Itoa(int(leaderboard.ID))
+	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
+		return cacheResult.([]*models.LeaderboardItemRanked), nil
+	}
+
+	items, err := srv.repository.GetAllAggregatedByInterval(scope, by, &leaderboard.ID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolveUsers {
+		srv.resolveUsers(items)
 	}
 
 	srv.cache.SetDefault(cacheKey, items)
@@ -204,7 +245,7 @@ func (srv *LeaderboardService) GetAggregatedByIntervalAndUser(interval *models.I
 		return cacheResult.([]*models.LeaderboardItemRanked), nil
 	}
 
-	items, err := srv.repository.GetAggregatedByUserAndInterval(userId, interval, by, 0, 0)
+	items, err := srv.repository.GetAggregatedByUserAndInterval(userId, interval, by, nil, 0, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -278,6 +319,19 @@ func (srv *LeaderboardService) GenerateAggregatedByUser(user *models.User, inter
 	return items, nil
 }
 
+func (srv *LeaderboardService) resolveUsers(items []*models.LeaderboardItemRanked) {
+	users, err := srv.userService.GetManyMapped(models.Leaderboard(items).UserIDs())
+	if err != nil {
+		config.Log().Error("failed to resolve users for leaderboard item - %v", err)
+		return
+	}
+	for _, item := range items {
+		if u, ok := users[item.UserID]; ok {
+			item.User = u
+		}
+	}
+}
+
 func (srv *LeaderboardService) getHash(interval *models.IntervalKey, by *uint8, user string, pageParams *utils.PageParams) string {
 	k := strings.Join(*interval, "__") + "__" + user
 	if by != nil && !reflect.ValueOf(by).IsNil() {
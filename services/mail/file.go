@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"fmt"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	uuid "github.com/satori/go.uuid"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileSendingService doesn't send any mails, but writes them to a local directory instead, either as .eml files or
+// following the maildir format (https://cr.yp.to/proto/maildir.html), so they can be read with any regular mail client
+type FileSendingService struct {
+	config conf.FileMailConfig
+}
+
+func NewFileSendingService(config conf.FileMailConfig) *FileSendingService {
+	return &FileSendingService{config: config}
+}
+
+func (s *FileSendingService) Send(mail *models.Mail) error {
+	mail = mail.Sanitized()
+
+	if s.config.Format == conf.MailFileFormatMaildir {
+		return s.deliverMaildir(mail)
+	}
+	return s.deliverEml(mail)
+}
+
+func (s *FileSendingService) deliverEml(mail *models.Mail) error {
+	if err := os.MkdirAll(s.config.Path, 0750); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.eml", mail.Date.Format("20060102-150405"), uuid.NewV4().String()[:8])
+	return writeFileAtomic(s.config.Path, filepath.Join(s.config.Path, name), mail.String())
+}
+
+// deliverMaildir writes the mail to tmp/ first and then moves it to new/, so that readers never see partially written files
+func (s *FileSendingService) deliverMaildir(mail *models.Mail) error {
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(s.config.Path, dir), 0750); err != nil {
+			return err
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	hostname = strings.NewReplacer("/", "\\057", ":", "\\072").Replace(hostname)
+	name := fmt.Sprintf("%d.%s.%s", mail.Date.UnixNano(), uuid.NewV4().String(), hostname)
+
+	return writeFileAtomic(filepath.Join(s.config.Path, "tmp"), filepath.Join(s.config.Path, "new", name), mail.String())
+}
+
+func writeFileAtomic(tmpDir, dst, content string) error {
+	f, err := os.CreateTemp(tmpDir, ".mail-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), dst)
+}
@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSendingService_Send_Eml(t *testing.T) {
+	dir := t.TempDir()
+	sut := NewFileSendingService(conf.FileMailConfig{Path: dir, Format: conf.MailFileFormatEml})
+
+	assert.Nil(t, sut.Send(testMail()))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Len(t, files, 1)
+
+	content, _ := os.ReadFile(files[0])
+	assert.Contains(t, string(content), "Subject: Wakapi - Test\r\n")
+	assert.Contains(t, string(content), "To: john@example.org\r\n")
+	assert.Contains(t, string(content), "<p>Hello</p>")
+}
+
+func TestFileSendingService_Send_Maildir(t *testing.T) {
+	dir := t.TempDir()
+	sut := NewFileSendingService(conf.FileMailConfig{Path: dir, Format: conf.MailFileFormatMaildir})
+
+	assert.Nil(t, sut.Send(testMail()))
+	assert.Nil(t, sut.Send(testMail()))
+
+	newFiles, _ := os.ReadDir(filepath.Join(dir, "new"))
+	tmpFiles, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	assert.Len(t, newFiles, 2)
+	assert.Empty(t, tmpFiles)
+	assert.DirExists(t, filepath.Join(dir, "cur"))
+}
+
+func testMail() *models.Mail {
+	mail := &models.Mail{
+		From:    "Wakapi <noreply@wakapi.dev>",
+		To:      models.MailAddresses{"john@example.org"},
+		Subject: "Wakapi - Test",
+	}
+	return mail.WithHTML("<p>Hello</p>")
+}
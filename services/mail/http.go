@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"net/http"
+	"time"
+)
+
+// HttpSendingService posts mails as json to a configurable url, e.g. a transactional mail api or a small custom adapter in front of one
+type HttpSendingService struct {
+	config     conf.HttpMailConfig
+	httpClient *http.Client
+}
+
+// httpMailPayload is the request body sent for every mail. Raw holds the complete mime message for apis, which accept those.
+type httpMailPayload struct {
+	From        string    `json:"from"`
+	To          []string  `json:"to"`
+	Subject     string    `json:"subject"`
+	ContentType string    `json:"content_type"`
+	Body        string    `json:"body"`
+	MessageID   string    `json:"message_id"`
+	Date        time.Time `json:"date"`
+	Raw         string    `json:"raw"`
+}
+
+func NewHttpSendingService(config conf.HttpMailConfig) *HttpSendingService {
+	return &HttpSendingService{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *HttpSendingService) Send(mail *models.Mail) error {
+	mail = mail.Sanitized()
+
+	payload, err := json.Marshal(&httpMailPayload{
+		From:        mail.From.String(),
+		To:          mail.To.Strings(),
+		Subject:     mail.Subject,
+		ContentType: mail.Type,
+		Body:        mail.Body,
+		MessageID:   mail.MessageID,
+		Date:        mail.Date,
+		Raw:         mail.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.config.Url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	if s.config.Authorization != "" {
+		request.Header.Set("Authorization", s.config.Authorization)
+	}
+
+	response, err := utils.RaiseForStatus(s.httpClient.Do(request))
+	if response != nil {
+		defer response.Body.Close()
+	}
+	return err
+}
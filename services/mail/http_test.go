@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpSendingService_Send(t *testing.T) {
+	var (
+		payload       httpMailPayload
+		authorization string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sut := NewHttpSendingService(conf.HttpMailConfig{Url: server.URL, Authorization: "Bearer secret"})
+	assert.Nil(t, sut.Send(testMail()))
+
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.Equal(t, []string{"john@example.org"}, payload.To)
+	assert.Equal(t, "Wakapi - Test", payload.Subject)
+	assert.Equal(t, "<p>Hello</p>", payload.Body)
+	assert.NotEmpty(t, payload.MessageID)
+	assert.Contains(t, payload.Raw, "Subject: Wakapi - Test\r\n")
+}
+
+func TestHttpSendingService_Send_Fail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	sut := NewHttpSendingService(conf.HttpMailConfig{Url: server.URL})
+	assert.NotNil(t, sut.Send(testMail()))
+}
@@ -17,14 +17,18 @@ import (
 const (
 	tplNamePasswordReset               = "reset_password"
 	tplNameImportNotification          = "import_finished"
+	tplNameImportFailureNotification   = "import_failed"
 	tplNameWakatimeFailureNotification = "wakatime_connection_failure"
 	tplNameReport                      = "report"
 	tplNameSubscriptionNotification    = "subscription_expiring"
+	tplNameDataExport                  = "data_export"
 	subjectPasswordReset               = "Wakapi - Password Reset"
 	subjectImportNotification          = "Wakapi - Data Import Finished"
+	subjectImportFailureNotification   = "Wakapi - Data Import Failed"
 	subjectWakatimeFailureNotification = "Wakapi - WakaTime Connection Failure"
 	subjectReport                      = "Wakapi - Report from %s"
 	subjectSubscriptionNotification    = "Wakapi - Subscription expiring / expired"
+	subjectDataExport                  = "Wakapi - Your Data Export is Ready"
 )
 
 type SendingService interface {
@@ -44,8 +48,15 @@ func NewMailService() services.IMailService {
 	sendingService = &NoopSendingService{}
 
 	if config.Mail.Enabled {
-		if config.Mail.Provider == conf.MailProviderSmtp {
+		switch config.Mail.Provider {
+		case conf.MailProviderSmtp:
 			sendingService = NewSMTPSendingService(config.Mail.Smtp)
+		case conf.MailProviderFile:
+			sendingService = NewFileSendingService(config.Mail.File)
+		case conf.MailProviderSendmail:
+			sendingService = NewSendmailSendingService(config.Mail.Sendmail)
+		case conf.MailProviderHttp:
+			sendingService = NewHttpSendingService(config.Mail.Http)
 		}
 	}
 
@@ -108,6 +119,24 @@ func (m *MailService) SendImportNotification(recipient *models.User, duration ti
 	return m.sendingService.Send(mail)
 }
 
+func (m *MailService) SendImportFailureNotification(recipient *models.User, numHeartbeats int, errors []string) error {
+	tpl, err := m.getImportFailureNotificationTemplate(ImportFailureNotificationTplData{
+		PublicUrl:     m.config.Server.PublicUrl,
+		NumHeartbeats: numHeartbeats,
+		Errors:        errors,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectImportFailureNotification,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
 func (m *MailService) SendReport(recipient *models.User, report *models.Report) error {
 	tpl, err := m.getReportTemplate(ReportTplData{report})
 	if err != nil {
@@ -140,6 +169,23 @@ func (m *MailService) SendSubscriptionNotification(recipient *models.User, hasEx
 	return m.sendingService.Send(mail)
 }
 
+func (m *MailService) SendDataExport(recipient *models.User, downloadLink string, expiresAt time.Time) error {
+	tpl, err := m.getDataExportTemplate(DataExportTplData{
+		DownloadLink: downloadLink,
+		ExpiresAt:    expiresAt.In(recipient.TZ()).Format(m.config.App.DateTimeFormat),
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectDataExport,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
 func (m *MailService) getPasswordResetTemplate(data PasswordResetTplData) (*bytes.Buffer, error) {
 	var rendered bytes.Buffer
 	if err := m.templates[m.fmtName(tplNamePasswordReset)].Execute(&rendered, data); err != nil {
@@ -164,6 +210,14 @@ func (m *MailService) getImportNotificationTemplate(data ImportNotificationTplDa
 	return &rendered, nil
 }
 
+func (m *MailService) getImportFailureNotificationTemplate(data ImportFailureNotificationTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameImportFailureNotification)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
 func (m *MailService) getReportTemplate(data ReportTplData) (*bytes.Buffer, error) {
 	var rendered bytes.Buffer
 	if err := m.templates[m.fmtName(tplNameReport)].Execute(&rendered, data); err != nil {
@@ -180,6 +234,14 @@ func (m *MailService) getSubscriptionNotificationTemplate(data SubscriptionNotif
 	return &rendered, nil
 }
 
+func (m *MailService) getDataExportTemplate(data DataExportTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameDataExport)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
 func (m *MailService) fmtName(name string) string {
 	return fmt.Sprintf("%s.tpl.html", name)
 }
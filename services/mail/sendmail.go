@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// SendmailSendingService hands mails to the local mta by piping them to a sendmail-compatible binary (e.g. the one shipped with postfix, exim or msmtp)
+type SendmailSendingService struct {
+	config conf.SendmailMailConfig
+}
+
+func NewSendmailSendingService(config conf.SendmailMailConfig) *SendmailSendingService {
+	return &SendmailSendingService{config: config}
+}
+
+func (s *SendmailSendingService) Send(mail *models.Mail) error {
+	mail = mail.Sanitized()
+
+	args := strings.Fields(s.config.Args)
+	if !slices.Contains(args, "-t") {
+		// recipients are not read from the message's headers, so pass them explicitly, after "--" to keep addresses
+		// starting with a dash from being interpreted as options
+		args = append(append(args, "--"), mail.To.RawStrings()...)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.config.Path, args...)
+	cmd.Stdin = mail.Reader()
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sendmail: %v - %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendmailSendingService_Send(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	bin := filepath.Join(dir, "sendmail")
+
+	// fake sendmail binary, which records its arguments and the piped message
+	script := "#!/bin/sh\necho \"$@\" > " + out + ".args\ncat > " + out + "\n"
+	assert.Nil(t, os.WriteFile(bin, []byte(script), 0700))
+
+	sut := NewSendmailSendingService(conf.SendmailMailConfig{Path: bin, Args: "-i"})
+	assert.Nil(t, sut.Send(testMail()))
+
+	args, _ := os.ReadFile(out + ".args")
+	message, _ := os.ReadFile(out)
+	assert.Equal(t, "-i -- john@example.org\n", string(args))
+	assert.Contains(t, string(message), "Subject: Wakapi - Test\r\n")
+}
+
+func TestSendmailSendingService_Send_Fail(t *testing.T) {
+	sut := NewSendmailSendingService(conf.SendmailMailConfig{Path: "/bin/false", Args: "-t -i"})
+	assert.NotNil(t, sut.Send(testMail()))
+}
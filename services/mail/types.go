@@ -12,6 +12,17 @@ type ImportNotificationTplData struct {
 	NumHeartbeats int
 }
 
+type ImportFailureNotificationTplData struct {
+	PublicUrl     string
+	NumHeartbeats int
+	Errors        []string
+}
+
+type DataExportTplData struct {
+	DownloadLink string
+	ExpiresAt    string
+}
+
 type WakatimeFailureNotificationNotificationTplData struct {
 	PublicUrl   string
 	NumFailures int
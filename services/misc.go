@@ -36,12 +36,13 @@ type MiscService struct {
 	summaryService   ISummaryService
 	keyValueService  IKeyValueService
 	mailService      IMailService
+	notifications    INotificationService
 	queueDefault     *artifex.Dispatcher
 	queueWorkers     *artifex.Dispatcher
 	queueMails       *artifex.Dispatcher
 }
 
-func NewMiscService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, keyValueService IKeyValueService, mailService IMailService) *MiscService {
+func NewMiscService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, keyValueService IKeyValueService, mailService IMailService, notificationService INotificationService) *MiscService {
 	return &MiscService{
 		config:           config.Get(),
 		userService:      userService,
@@ -49,6 +50,7 @@ func NewMiscService(userService IUserService, heartbeatService IHeartbeatService
 		summaryService:   summaryService,
 		keyValueService:  keyValueService,
 		mailService:      mailService,
+		notifications:    notificationService,
 		queueDefault:     config.GetDefaultQueue(),
 		queueWorkers:     config.GetQueue(config.QueueProcessing),
 		queueMails:       config.GetQueue(config.QueueMails),
@@ -223,11 +225,14 @@ func (srv *MiscService) NotifyExpiringSubscription() {
 			}
 		}
 
-		// skip users without e-mail address
 		// skip users who already received a notification before
 		// skip users who either never had a subscription before or intentionally deleted it
 		// skip users who have upcoming auto-renewal (everyone except users who chose to cancel subscription at later date)
-		if alreadySent || u.Email == "" || u.SubscribedUntil == nil || (u.SubscriptionRenewal != nil && u.SubscriptionRenewal.T().After(now)) {
+		// skip users without e-mail address and without chat channels for subscription notifications
+		if alreadySent || u.SubscribedUntil == nil || (u.SubscriptionRenewal != nil && u.SubscriptionRenewal.T().After(now)) {
+			continue
+		}
+		if u.Email == "" && !srv.notifications.HasChannels(u, models.NotificationKindSubscription) {
 			continue
 		}
 
@@ -253,9 +258,14 @@ func (srv *MiscService) sendSubscriptionNotificationScheduled(user *models.User,
 		logbuch.Info("sending subscription expiry notification mail to %s (expired: %v)", u.ID, hasExpired)
 		defer time.Sleep(10 * time.Second)
 
-		if err := srv.mailService.SendSubscriptionNotification(&u, hasExpired); err != nil {
-			config.Log().Error("failed to send subscription notification mail to user '%s', %v", u.ID, err)
-			return
+		if u.Email != "" {
+			if err := srv.mailService.SendSubscriptionNotification(&u, hasExpired); err != nil {
+				config.Log().Error("failed to send subscription notification mail to user '%s', %v", u.ID, err)
+				return
+			}
+		}
+		if err := srv.notifications.SendSubscriptionNotification(&u, hasExpired); err != nil {
+			config.Log().Error("failed to post subscription notification for user '%s', %v", u.ID, err)
 		}
 
 		if err := srv.keyValueService.PutString(&models.KeyStringValue{
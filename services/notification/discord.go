@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"encoding/json"
+	"github.com/muety/wakapi/models"
+	"net/http"
+)
+
+// max. length of a discord message
+const discordMaxContentLength = 2000
+
+// DiscordSender posts to discord webhooks
+type DiscordSender struct {
+	httpClient *http.Client
+}
+
+type discordPayload struct {
+	Content string `json:"content"`
+}
+
+func NewDiscordSender(httpClient *http.Client) *DiscordSender {
+	return &DiscordSender{httpClient: httpClient}
+}
+
+func (s *DiscordSender) Send(channel *models.NotificationChannel, notification *models.Notification) error {
+	content := []rune(renderMarkdown(notification))
+	if len(content) > discordMaxContentLength {
+		content = append(content[:discordMaxContentLength-1], '…')
+	}
+
+	body, err := json.Marshal(&discordPayload{Content: string(content)})
+	if err != nil {
+		return err
+	}
+	return post(s.httpClient, http.MethodPost, channel.Url, "application/json", body, nil)
+}
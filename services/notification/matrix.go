@@ -0,0 +1,45 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/muety/wakapi/models"
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MatrixSender sends messages to a matrix room via the client-server api, authenticated with the access token of a (bot) user, who joined the room
+type MatrixSender struct {
+	httpClient *http.Client
+}
+
+type matrixPayload struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func NewMatrixSender(httpClient *http.Client) *MatrixSender {
+	return &MatrixSender{httpClient: httpClient}
+}
+
+func (s *MatrixSender) Send(channel *models.NotificationChannel, notification *models.Notification) error {
+	body, err := json.Marshal(&matrixPayload{
+		MsgType:       "m.notice",
+		Body:          renderPlain(notification),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: renderHtml(notification),
+	})
+	if err != nil {
+		return err
+	}
+
+	// transaction id makes the request idempotent
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", strings.TrimSuffix(channel.Url, "/"), url.PathEscape(channel.RoomId), uuid.NewV4().String())
+	return post(s.httpClient, http.MethodPut, endpoint, "application/json", body, map[string]string{
+		"Authorization": "Bearer " + channel.Token,
+	})
+}
@@ -0,0 +1,235 @@
+package notification
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/emvi/logbuch"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sender posts a notification to a channel of a certain type
+type Sender interface {
+	Send(*models.NotificationChannel, *models.Notification) error
+}
+
+// NotificationService posts reports and other notifications, which are otherwise sent by mail, to a user's chat channels
+type NotificationService struct {
+	config     *conf.Config
+	repository repositories.INotificationChannelRepository
+	senders    map[string]Sender
+}
+
+func NewNotificationService(notificationChannelRepository repositories.INotificationChannelRepository) services.INotificationService {
+	config := conf.Get()
+	httpClient := utils.NewPublicHttpClient(10*time.Second, config.Security.AllowPrivateNetworks)
+
+	return &NotificationService{
+		config:     config,
+		repository: notificationChannelRepository,
+		senders: map[string]Sender{
+			models.NotificationChannelSlack:      NewSlackSender(httpClient),
+			models.NotificationChannelMattermost: NewMattermostSender(httpClient),
+			models.NotificationChannelDiscord:    NewDiscordSender(httpClient),
+			models.NotificationChannelMatrix:     NewMatrixSender(httpClient),
+			models.NotificationChannelNtfy:       NewNtfySender(httpClient),
+		},
+	}
+}
+
+func (srv *NotificationService) GetById(id uint) (*models.NotificationChannel, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *NotificationService) GetByUser(userId string) ([]*models.NotificationChannel, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+func (srv *NotificationService) Create(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	if !channel.IsValid() {
+		return nil, errors.New("invalid notification channel")
+	}
+	return srv.repository.Insert(channel)
+}
+
+func (srv *NotificationService) Update(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	if !channel.IsValid() {
+		return nil, errors.New("invalid notification channel")
+	}
+	return srv.repository.Update(channel)
+}
+
+func (srv *NotificationService) Delete(channel *models.NotificationChannel) error {
+	return srv.repository.Delete(channel.ID)
+}
+
+// HasChannels returns whether the user has any enabled channel, which notifications of the given kind are routed to
+func (srv *NotificationService) HasChannels(user *models.User, kind string) bool {
+	return len(srv.getChannels(user, kind)) > 0
+}
+
+// SendTest posts a test message to the given channel, regardless of whether it's enabled or which kinds of notifications it receives
+func (srv *NotificationService) SendTest(channel *models.NotificationChannel) error {
+	return srv.send(channel, &models.Notification{
+		Kind:  models.NotificationKindTest,
+		Title: "Wakapi test notification",
+		Lines: []string{"If you can read this, your notification channel is set up correctly."},
+		Link:  srv.settingsLink("integrations"),
+	})
+}
+
+func (srv *NotificationService) SendWakatimeFailureNotification(recipient *models.User, numFailures int) error {
+	return srv.notify(recipient, &models.Notification{
+		Kind:  models.NotificationKindRelayFailure,
+		Title: "Wakapi: WakaTime connection failure",
+		Lines: []string{
+			fmt.Sprintf("Relaying heartbeats to WakaTime failed %d times within the last 24 hours, so the connection was removed.", numFailures),
+			"Please check your API key and reconnect in the settings.",
+		},
+		Link: srv.settingsLink("integrations"),
+	})
+}
+
+func (srv *NotificationService) SendRelayTargetFailureNotification(recipient *models.User, target *models.RelayTarget) error {
+	name := target.Name
+	if name == "" {
+		name = target.ApiUrl
+	}
+	return srv.notify(recipient, &models.Notification{
+		Kind:  models.NotificationKindRelayFailure,
+		Title: "Wakapi: relay target disabled",
+		Lines: []string{
+			fmt.Sprintf("Relaying heartbeats to '%s' failed %d times in a row, so the target was disabled.", name, target.ConsecutiveFailures),
+			fmt.Sprintf("Last error: %s", target.LastError),
+			"Please check its API URL and key and re-enable it in the settings.",
+		},
+		Link: srv.settingsLink("integrations"),
+	})
+}
+
+func (srv *NotificationService) SendImportNotification(recipient *models.User, duration time.Duration, numHeartbeats int) error {
+	return srv.notify(recipient, &models.Notification{
+		Kind:  models.NotificationKindImport,
+		Title: "Wakapi: data import finished",
+		Lines: []string{fmt.Sprintf("Imported %d heartbeats in %.0f seconds.", numHeartbeats, duration.Seconds())},
+		Link:  srv.config.Server.PublicUrl,
+	})
+}
+
+func (srv *NotificationService) SendImportFailureNotification(recipient *models.User, numHeartbeats int, errors []string) error {
+	lines := []string{fmt.Sprintf("Your data import stopped early, only %d heartbeats were imported.", numHeartbeats)}
+	return srv.notify(recipient, &models.Notification{
+		Kind:  models.NotificationKindImport,
+		Title: "Wakapi: data import failed",
+		Lines: append(lines, errors...),
+		Link:  srv.settingsLink("data"),
+	})
+}
+
+func (srv *NotificationService) SendReport(recipient *models.User, report *models.Report) error {
+	return srv.notify(recipient, newReportNotification(report, srv.config.Server.PublicUrl))
+}
+
+func (srv *NotificationService) SendSubscriptionNotification(recipient *models.User, hasExpired bool) error {
+	notification := &models.Notification{
+		Kind:  models.NotificationKindSubscription,
+		Title: "Wakapi: subscription expiring",
+		Lines: []string{"Your Wakapi subscription is about to expire. Renew it to keep access to your full history."},
+		Link:  srv.settingsLink("subscription"),
+	}
+	if hasExpired {
+		notification.Title = "Wakapi: subscription expired"
+		notification.Lines = []string{fmt.Sprintf("Your Wakapi subscription has expired. Data older than %d months will be deleted, unless you renew it.", srv.config.App.DataRetentionMonths)}
+	}
+	return srv.notify(recipient, notification)
+}
+
+// notify posts the notification to all of the user's enabled channels receiving its kind and returns the errors of all failed attempts
+func (srv *NotificationService) notify(user *models.User, notification *models.Notification) error {
+	var errs []error
+	for _, channel := range srv.getChannels(user, notification.Kind) {
+		if err := srv.send(channel, notification); err != nil {
+			errs = append(errs, fmt.Errorf("channel %d: %w", channel.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (srv *NotificationService) send(channel *models.NotificationChannel, notification *models.Notification) error {
+	sender, ok := srv.senders[channel.Type]
+	if !ok {
+		return fmt.Errorf("unsupported channel type '%s'", channel.Type)
+	}
+
+	err := sender.Send(channel, notification)
+
+	var reason string
+	if err != nil {
+		reason = err.Error()
+	}
+	if updateErr := srv.repository.RecordResult(channel.ID, reason); updateErr != nil {
+		conf.Log().Error("failed to update notification channel %d - %v", channel.ID, updateErr)
+	}
+	if err == nil {
+		logbuch.Info("sent %s notification to %s channel %d of user %s", notification.Kind, channel.Type, channel.ID, channel.UserID)
+	}
+	return err
+}
+
+func (srv *NotificationService) getChannels(user *models.User, kind string) []*models.NotificationChannel {
+	channels, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Error("failed to fetch notification channels for user %s - %v", user.ID, err)
+		return nil
+	}
+
+	result := make([]*models.NotificationChannel, 0, len(channels))
+	for _, c := range channels {
+		if c.Enabled && c.Subscribes(kind) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (srv *NotificationService) settingsLink(tab string) string {
+	return fmt.Sprintf("%s/settings#%s", srv.config.Server.PublicUrl, tab)
+}
+
+// post sends the given body to the channel's endpoint and fails for non-successful responses.
+// Errors never contain the url, as webhook urls are secrets themselves.
+func post(client *http.Client, method, endpoint, contentType string, body []byte, headers map[string]string) error {
+	request, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid url")
+	}
+	request.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 256))
+		return fmt.Errorf("got status %d - %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package notification
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type stubRequest struct {
+	Method  string
+	Path    string
+	Headers http.Header
+	Body    string
+}
+
+func newStubServer(requests *[]*stubRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*requests = append(*requests, &stubRequest{Method: r.Method, Path: r.URL.EscapedPath(), Headers: r.Header, Body: string(body)})
+		if strings.HasSuffix(r.URL.Path, "/broken") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no_service"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestNotificationService_SendImportNotification(t *testing.T) {
+	cfg := conf.Empty()
+	cfg.Security.AllowPrivateNetworks = true // stub server listens on loopback
+	conf.Set(cfg)
+
+	var requests []*stubRequest
+	server := newStubServer(&requests)
+	defer server.Close()
+
+	user := &models.User{ID: "user1"}
+	channels := []*models.NotificationChannel{
+		{ID: 1, UserID: "user1", Type: models.NotificationChannelSlack, Url: server.URL + "/slack", Kinds: "import,report", Enabled: true},
+		{ID: 2, UserID: "user1", Type: models.NotificationChannelDiscord, Url: server.URL + "/discord", Kinds: "report", Enabled: true},
+		{ID: 3, UserID: "user1", Type: models.NotificationChannelNtfy, Url: server.URL + "/ntfy", Kinds: "import", Enabled: false},
+		{ID: 4, UserID: "user1", Type: models.NotificationChannelMattermost, Url: server.URL + "/secret-token/broken", Kinds: "import", Enabled: true},
+	}
+
+	repository := new(mocks.NotificationChannelRepositoryMock)
+	repository.On("GetByUser", "user1").Return(channels, nil)
+	repository.On("RecordResult", mock.Anything, mock.Anything).Return(nil)
+
+	sut := NewNotificationService(repository)
+	err := sut.SendImportNotification(user, 3*time.Second, 42)
+
+	assert.ErrorContains(t, err, "channel 4: got status 404 - no_service")
+	assert.NotContains(t, err.Error(), "secret-token")
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "/slack", requests[0].Path)
+	assert.Contains(t, requests[0].Body, "Imported 42 heartbeats in 3 seconds.")
+	assert.Equal(t, "/secret-token/broken", requests[1].Path)
+
+	repository.AssertCalled(t, "RecordResult", uint(1), "")
+	repository.AssertCalled(t, "RecordResult", uint(4), "got status 404 - no_service")
+	assert.True(t, sut.HasChannels(user, models.NotificationKindReport))
+	assert.False(t, sut.HasChannels(user, models.NotificationKindSubscription))
+}
+
+func TestNotificationService_SendTest_PrivateAddress(t *testing.T) {
+	conf.Set(conf.Empty())
+
+	var requests []*stubRequest
+	server := newStubServer(&requests)
+	defer server.Close()
+
+	channel := &models.NotificationChannel{ID: 1, UserID: "user1", Type: models.NotificationChannelSlack, Url: server.URL + "/slack", Enabled: true}
+
+	repository := new(mocks.NotificationChannelRepositoryMock)
+	repository.On("RecordResult", mock.Anything, mock.Anything).Return(nil)
+
+	err := NewNotificationService(repository).SendTest(channel)
+
+	assert.ErrorContains(t, err, "non-public address")
+	assert.Empty(t, requests)
+}
+
+func TestSenders_Send(t *testing.T) {
+	var requests []*stubRequest
+	server := newStubServer(&requests)
+	defer server.Close()
+
+	notification := &models.Notification{
+		Kind:  models.NotificationKindReport,
+		Title: "Wakapi report",
+		Lines: []string{"Total: 1 hrs 2 mins", "Projects: <wakapi> (1h 2m)"},
+		Link:  "https://wakapi.dev/summary",
+	}
+
+	assert.Nil(t, NewSlackSender(server.Client()).Send(&models.NotificationChannel{Url: server.URL + "/slack"}, notification))
+	assert.Nil(t, NewMattermostSender(server.Client()).Send(&models.NotificationChannel{Url: server.URL + "/mattermost"}, notification))
+	assert.Nil(t, NewDiscordSender(server.Client()).Send(&models.NotificationChannel{Url: server.URL + "/discord"}, notification))
+	assert.Nil(t, NewMatrixSender(server.Client()).Send(&models.NotificationChannel{Url: server.URL + "/", RoomId: "!room:example.org", Token: "matrix-token"}, notification))
+	assert.Nil(t, NewNtfySender(server.Client()).Send(&models.NotificationChannel{Url: server.URL + "/wakapi-topic", Token: "ntfy-token"}, notification))
+	assert.Len(t, requests, 5)
+
+	var slack, mattermost slackPayload
+	json.Unmarshal([]byte(requests[0].Body), &slack)
+	json.Unmarshal([]byte(requests[1].Body), &mattermost)
+	assert.Equal(t, "*Wakapi report*\nTotal: 1 hrs 2 mins\nProjects: &lt;wakapi&gt; (1h 2m)\n<https://wakapi.dev/summary|Open Wakapi>", slack.Text)
+	assert.Equal(t, "**Wakapi report**\nTotal: 1 hrs 2 mins\nProjects: <wakapi> (1h 2m)\n[Open Wakapi](https://wakapi.dev/summary)", mattermost.Text)
+
+	var discord discordPayload
+	json.Unmarshal([]byte(requests[2].Body), &discord)
+	assert.Equal(t, mattermost.Text, discord.Content)
+
+	var matrix matrixPayload
+	json.Unmarshal([]byte(requests[3].Body), &matrix)
+	assert.Equal(t, http.MethodPut, requests[3].Method)
+	assert.True(t, strings.HasPrefix(requests[3].Path, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/"))
+	assert.Equal(t, "Bearer matrix-token", requests[3].Headers.Get("Authorization"))
+	assert.Equal(t, "m.notice", matrix.MsgType)
+	assert.Contains(t, matrix.FormattedBody, "Projects: &lt;wakapi&gt; (1h 2m)")
+
+	assert.Equal(t, "/wakapi-topic", requests[4].Path)
+	assert.Equal(t, "Wakapi report", requests[4].Headers.Get("Title"))
+	assert.Equal(t, "https://wakapi.dev/summary", requests[4].Headers.Get("Click"))
+	assert.Equal(t, "Bearer ntfy-token", requests[4].Headers.Get("Authorization"))
+	assert.Equal(t, "Total: 1 hrs 2 mins\nProjects: <wakapi> (1h 2m)", requests[4].Body)
+}
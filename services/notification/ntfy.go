@@ -0,0 +1,31 @@
+package notification
+
+import (
+	"github.com/muety/wakapi/models"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// NtfySender publishes to an ntfy topic, see https://docs.ntfy.sh/publish
+type NtfySender struct {
+	httpClient *http.Client
+}
+
+func NewNtfySender(httpClient *http.Client) *NtfySender {
+	return &NtfySender{httpClient: httpClient}
+}
+
+func (s *NtfySender) Send(channel *models.NotificationChannel, notification *models.Notification) error {
+	headers := map[string]string{
+		"Title": mime.BEncoding.Encode("utf-8", notification.Title), // non-ascii headers need to be rfc 2047-encoded
+		"Tags":  "wakapi," + notification.Kind,
+	}
+	if notification.Link != "" {
+		headers["Click"] = notification.Link
+	}
+	if channel.Token != "" {
+		headers["Authorization"] = "Bearer " + channel.Token
+	}
+	return post(s.httpClient, http.MethodPost, channel.Url, "text/plain; charset=utf-8", []byte(strings.Join(notification.Lines, "\n")), headers)
+}
@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"fmt"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"html"
+	"strings"
+	"time"
+)
+
+// number of top projects and languages listed in a report
+const reportTopItems = 3
+
+// newReportNotification renders a compact version of the report, as sent by mail, i.e. total time, top projects, languages and labels and the busiest day, as far as included in the report
+func newReportNotification(report *models.Report, publicUrl string) *models.Notification {
+	notification := &models.Notification{
+		Kind:  models.NotificationKindReport,
+		Title: fmt.Sprintf("Wakapi report: %s – %s", helpers.FormatDateHuman(report.From.In(report.User.TZ())), helpers.FormatDateHuman(report.To.In(report.User.TZ()))),
+		Link:  fmt.Sprintf("%s/summary?interval=%s", publicUrl, report.SummaryInterval()),
+	}
+
+	if report.Summary == nil || report.Summary.TotalTime() == 0 {
+		notification.Lines = []string{"No coding activity in this period."}
+		return notification
+	}
+
+	summary := report.Summary.Sorted()
+	notification.Lines = append(notification.Lines, fmt.Sprintf("Total: %s", helpers.FmtWakatimeDuration(summary.TotalTime())))
+	if report.Includes(models.ReportSectionComparison) && report.Previous.TotalTime() > 0 {
+		notification.Lines = append(notification.Lines, fmt.Sprintf("Previous period: %s (%+d %%)", helpers.FmtWakatimeDuration(report.Previous.TotalTime()), report.Change()))
+	} else if report.Includes(models.ReportSectionComparison) {
+		notification.Lines = append(notification.Lines, "Previous period: no coding activity")
+	}
+	if projects := formatTopItems(summary.Projects); projects != "" && report.Includes(models.ReportSectionProjects) {
+		notification.Lines = append(notification.Lines, fmt.Sprintf("Projects: %s", projects))
+	}
+	if languages := formatTopItems(summary.Languages); languages != "" && report.Includes(models.ReportSectionLanguages) {
+		notification.Lines = append(notification.Lines, fmt.Sprintf("Languages: %s", languages))
+	}
+	if labels := formatTopItems(summary.Labels); labels != "" && report.Includes(models.ReportSectionLabels) {
+		notification.Lines = append(notification.Lines, fmt.Sprintf("Labels: %s", labels))
+	}
+
+	var busiest *models.Summary
+	for _, s := range report.DailySummaries {
+		if s != nil && (busiest == nil || s.TotalTime() > busiest.TotalTime()) {
+			busiest = s
+		}
+	}
+	if busiest != nil && busiest.TotalTime() > 0 && report.Includes(models.ReportSectionDays) {
+		notification.Lines = append(notification.Lines, fmt.Sprintf("Busiest day: %s (%s)", busiest.FromTime.T().Format("Monday"), helpers.FmtWakatimeDuration(busiest.TotalTime())))
+	}
+
+	return notification
+}
+
+func formatTopItems(items models.SummaryItems) string {
+	parts := make([]string, 0, reportTopItems)
+	for i := 0; i < len(items) && i < reportTopItems; i++ {
+		parts = append(parts, fmt.Sprintf("%s (%s)", items[i].Key, formatShortDuration(items[i].TotalFixed())))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatShortDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if h := d / time.Hour; h > 0 {
+		return fmt.Sprintf("%dh %dm", h, (d-h*time.Hour)/time.Minute)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+func renderPlain(n *models.Notification) string {
+	var sb strings.Builder
+	sb.WriteString(n.Title)
+	for _, l := range n.Lines {
+		sb.WriteString("\n" + l)
+	}
+	if n.Link != "" {
+		sb.WriteString("\n" + n.Link)
+	}
+	return sb.String()
+}
+
+// renderMarkdown renders common markdown, as understood by discord and mattermost
+func renderMarkdown(n *models.Notification) string {
+	var sb strings.Builder
+	sb.WriteString("**" + n.Title + "**")
+	for _, l := range n.Lines {
+		sb.WriteString("\n" + l)
+	}
+	if n.Link != "" {
+		sb.WriteString(fmt.Sprintf("\n[Open Wakapi](%s)", n.Link))
+	}
+	return sb.String()
+}
+
+// renderSlack renders slack's mrkdwn flavor, see https://api.slack.com/reference/surfaces/formatting
+func renderSlack(n *models.Notification) string {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	var sb strings.Builder
+	sb.WriteString("*" + escape.Replace(n.Title) + "*")
+	for _, l := range n.Lines {
+		sb.WriteString("\n" + escape.Replace(l))
+	}
+	if n.Link != "" {
+		sb.WriteString(fmt.Sprintf("\n<%s|Open Wakapi>", n.Link))
+	}
+	return sb.String()
+}
+
+func renderHtml(n *models.Notification) string {
+	var sb strings.Builder
+	sb.WriteString("<strong>" + html.EscapeString(n.Title) + "</strong>")
+	for _, l := range n.Lines {
+		sb.WriteString("<br>" + html.EscapeString(l))
+	}
+	if n.Link != "" {
+		sb.WriteString(fmt.Sprintf("<br><a href=\"%s\">Open Wakapi</a>", html.EscapeString(n.Link)))
+	}
+	return sb.String()
+}
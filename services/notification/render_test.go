@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReportNotification(t *testing.T) {
+	conf.Set(conf.Empty())
+
+	from := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC) // monday
+	day := func(offset int, total time.Duration) *models.Summary {
+		return &models.Summary{
+			FromTime: models.CustomTime(from.AddDate(0, 0, offset)),
+			Projects: models.SummaryItems{{Type: models.SummaryProject, Key: "wakapi", Total: total / time.Second}},
+		}
+	}
+
+	report := &models.Report{
+		From: from,
+		To:   from.AddDate(0, 0, 7),
+		User: &models.User{ID: "user1"},
+		Summary: &models.Summary{
+			Projects: models.SummaryItems{
+				{Type: models.SummaryProject, Key: "anchr", Total: 1800},
+				{Type: models.SummaryProject, Key: "wakapi", Total: 9000},
+			},
+			Languages: models.SummaryItems{
+				{Type: models.SummaryLanguage, Key: "Go", Total: 10800},
+			},
+		},
+		DailySummaries: []*models.Summary{day(0, time.Hour), day(1, 2*time.Hour), nil},
+	}
+
+	notification := newReportNotification(report, "https://wakapi.dev")
+	assert.Equal(t, models.NotificationKindReport, notification.Kind)
+	assert.Equal(t, "https://wakapi.dev/summary?interval=last_7_days", notification.Link)
+	assert.Equal(t, []string{
+		"Total: 3 hrs 0 mins",
+		"Projects: wakapi (2h 30m), anchr (30m)",
+		"Languages: Go (3h 0m)",
+		"Busiest day: Tuesday (2 hrs 0 mins)",
+	}, notification.Lines)
+
+	report.Period = models.ReportPeriodMonthly
+	report.Sections = []string{models.ReportSectionLanguages, models.ReportSectionComparison}
+	report.Previous = &models.Summary{Languages: models.SummaryItems{{Type: models.SummaryLanguage, Key: "Go", Total: 7200}}}
+	notification = newReportNotification(report, "https://wakapi.dev")
+	assert.Equal(t, "https://wakapi.dev/summary?interval=last_month", notification.Link)
+	assert.Equal(t, []string{
+		"Total: 3 hrs 0 mins",
+		"Previous period: 2 hrs 0 mins (+50 %)",
+		"Languages: Go (3h 0m)",
+	}, notification.Lines)
+
+	report.Summary = &models.Summary{}
+	assert.Equal(t, []string{"No coding activity in this period."}, newReportNotification(report, "").Lines)
+}
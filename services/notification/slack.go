@@ -0,0 +1,45 @@
+package notification
+
+import (
+	"encoding/json"
+	"github.com/muety/wakapi/models"
+	"net/http"
+)
+
+// SlackSender posts to slack incoming webhooks
+type SlackSender struct {
+	httpClient *http.Client
+}
+
+// MattermostSender posts to mattermost incoming webhooks, which are slack-compatible, but expect common markdown
+type MattermostSender struct {
+	httpClient *http.Client
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func NewSlackSender(httpClient *http.Client) *SlackSender {
+	return &SlackSender{httpClient: httpClient}
+}
+
+func NewMattermostSender(httpClient *http.Client) *MattermostSender {
+	return &MattermostSender{httpClient: httpClient}
+}
+
+func (s *SlackSender) Send(channel *models.NotificationChannel, notification *models.Notification) error {
+	body, err := json.Marshal(&slackPayload{Text: renderSlack(notification)})
+	if err != nil {
+		return err
+	}
+	return post(s.httpClient, http.MethodPost, channel.Url, "application/json", body, nil)
+}
+
+func (s *MattermostSender) Send(channel *models.NotificationChannel, notification *models.Notification) error {
+	body, err := json.Marshal(&slackPayload{Text: renderMarkdown(notification)})
+	if err != nil {
+		return err
+	}
+	return post(s.httpClient, http.MethodPost, channel.Url, "application/json", body, nil)
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/oauth2"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOidcDisabled        = errors.New("single sign-on is not enabled")
+	ErrOidcInvalidToken    = errors.New("invalid id token")
+	ErrOidcSignupDisabled  = errors.New("no account exists for this user and signup is disabled")
+	ErrOidcUsernameTaken   = errors.New("username is already taken by another account")
+	ErrOidcAccountDisabled = errors.New("account is disabled")
+)
+
+type OidcService struct {
+	config       *config.Config
+	userService  IUserService
+	provider     *oidc.Provider
+	verifier     *oidc.IDTokenVerifier
+	oauth2Config *oauth2.Config
+	initLock     sync.Mutex
+}
+
+func NewOidcService(userService IUserService) *OidcService {
+	return &OidcService{
+		config:      config.Get(),
+		userService: userService,
+	}
+}
+
+// AuthCodeURL returns the provider's url to redirect the user to for signing in
+func (srv *OidcService) AuthCodeURL(state, nonce string) (string, error) {
+	if err := srv.init(); err != nil {
+		return "", err
+	}
+	return srv.oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce)), nil
+}
+
+// Exchange redeems the given authorization code and returns the user's identity as stated by the verified id token
+func (srv *OidcService) Exchange(ctx context.Context, code, nonce string) (*models.OidcIdentity, error) {
+	if err := srv.init(); err != nil {
+		return nil, err
+	}
+
+	token, err := srv.oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrOidcInvalidToken
+	}
+
+	idToken, err := srv.verifier.Verify(ctx, rawIdToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrOidcInvalidToken
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	identity := models.OidcIdentityFromClaims(claims, srv.config.Oidc.UsernameClaim, srv.config.Oidc.GroupsClaim)
+	if !identity.IsValid() {
+		return nil, fmt.Errorf("id token lacks subject or valid '%s' claim", srv.config.Oidc.UsernameClaim)
+	}
+	return identity, nil
+}
+
+// Login resolves the given identity to a user, possibly linking an existing account or provisioning a new one
+func (srv *OidcService) Login(identity *models.OidcIdentity) (*models.User, error) {
+	user, err := srv.userService.GetUserByOidcSubject(identity.Subject)
+	if err != nil || user == nil || user.ID == "" {
+		if user, err = srv.linkOrCreate(identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.IsDisabled {
+		return nil, ErrOidcAccountDisabled
+	}
+
+	if srv.config.Oidc.AdminGroup != "" {
+		if isAdmin := identity.IsMemberOf(srv.config.Oidc.AdminGroup); isAdmin != user.IsAdmin {
+			if _, err := srv.userService.SetAdmin(user, isAdmin); err != nil {
+				return nil, err
+			}
+		}
+	}
+	user.LastLoggedInAt = models.CustomTime(time.Now())
+
+	return srv.userService.Update(user)
+}
+
+func (srv *OidcService) linkOrCreate(identity *models.OidcIdentity) (*models.User, error) {
+	if srv.config.Oidc.LinkByEmail && identity.Email != "" && identity.EmailVerified {
+		if user, err := srv.userService.GetUserByEmail(identity.Email); err == nil && user != nil && user.ID != "" && user.OidcSubject == "" {
+			logbuch.Info("linking existing user '%s' to oidc subject '%s' by e-mail", user.ID, identity.Subject)
+			user.OidcSubject = identity.Subject
+			return user, nil
+		}
+	}
+
+	if existing, err := srv.userService.GetUserById(identity.Username); err == nil && existing != nil && existing.ID != "" {
+		return nil, ErrOidcUsernameTaken
+	}
+
+	if !srv.config.Oidc.AllowSignup {
+		return nil, ErrOidcSignupDisabled
+	}
+
+	signup := &models.Signup{
+		Username: identity.Username,
+		Email:    identity.Email,
+		Password: uuid.NewV4().String(), // random password, as users are supposed to sign in via sso
+	}
+
+	user, created, err := srv.userService.CreateOrGet(signup, false)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrOidcUsernameTaken
+	}
+
+	logbuch.Info("created new user '%s' for oidc subject '%s'", user.ID, identity.Subject)
+	user.OidcSubject = identity.Subject
+	return user, nil
+}
+
+func (srv *OidcService) init() error {
+	if !srv.config.Oidc.Enabled {
+		return ErrOidcDisabled
+	}
+
+	srv.initLock.Lock()
+	defer srv.initLock.Unlock()
+
+	if srv.provider != nil {
+		return nil
+	}
+
+	// discovery is done lazily on first use, so that a temporarily unavailable provider doesn't prevent wakapi from starting up
+	provider, err := oidc.NewProvider(context.Background(), srv.config.Oidc.Issuer)
+	if err != nil {
+		return err
+	}
+
+	srv.provider = provider
+	srv.verifier = provider.Verifier(&oidc.Config{ClientID: srv.config.Oidc.ClientId})
+	srv.oauth2Config = &oauth2.Config{
+		ClientID:     srv.config.Oidc.ClientId,
+		ClientSecret: srv.config.Oidc.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  srv.config.Oidc.GetCallbackUrl(srv.config.Server.GetPublicUrl()),
+		Scopes:       srv.config.Oidc.GetScopes(),
+	}
+	return nil
+}
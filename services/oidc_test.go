@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	TestOidcClientId = "wakapi"
+	TestOidcSubject  = "3b241101-e2bb-4255-8caf-4136c566a962"
+)
+
+type OidcServiceTestSuite struct {
+	suite.Suite
+	Provider    *mockOidcProvider
+	UserService *mocks.UserServiceMock
+}
+
+func (suite *OidcServiceTestSuite) SetupSuite() {
+	suite.Provider = newMockOidcProvider(suite.T())
+}
+
+func (suite *OidcServiceTestSuite) TearDownSuite() {
+	suite.Provider.server.Close()
+}
+
+func (suite *OidcServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Server.PublicUrl = "http://localhost:3000"
+	cfg.Oidc.Enabled = true
+	cfg.Oidc.Issuer = suite.Provider.server.URL
+	cfg.Oidc.ClientId = TestOidcClientId
+	cfg.Oidc.Scopes = "openid,profile,email"
+	cfg.Oidc.UsernameClaim = "preferred_username"
+	cfg.Oidc.GroupsClaim = "groups"
+	cfg.Oidc.AdminGroup = "wakapi-admins"
+	cfg.Oidc.AllowSignup = true
+	config.Set(cfg)
+
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.Provider.claims = map[string]interface{}{
+		"sub":                TestOidcSubject,
+		"preferred_username": "johndoe",
+		"email":              "john@example.org",
+		"email_verified":     true,
+		"groups":             []string{"developers", "wakapi-admins"},
+	}
+}
+
+func TestOidcServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(OidcServiceTestSuite))
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Exchange() {
+	sut := NewOidcService(suite.UserService)
+
+	authUrl, err := sut.AuthCodeURL("some-state", "some-nonce")
+	assert.Nil(suite.T(), err)
+
+	parsedUrl, _ := url.Parse(authUrl)
+	assert.Equal(suite.T(), suite.Provider.server.URL+"/auth", parsedUrl.Scheme+"://"+parsedUrl.Host+parsedUrl.Path)
+	assert.Equal(suite.T(), "some-state", parsedUrl.Query().Get("state"))
+	assert.Equal(suite.T(), "some-nonce", parsedUrl.Query().Get("nonce"))
+	assert.Equal(suite.T(), "http://localhost:3000/login/oidc/callback", parsedUrl.Query().Get("redirect_uri"))
+
+	suite.Provider.claims["nonce"] = "some-nonce"
+
+	identity, err := sut.Exchange(context.Background(), "some-code", "some-nonce")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), TestOidcSubject, identity.Subject)
+	assert.Equal(suite.T(), "johndoe", identity.Username)
+	assert.Equal(suite.T(), "john@example.org", identity.Email)
+	assert.True(suite.T(), identity.EmailVerified)
+	assert.True(suite.T(), identity.IsMemberOf("wakapi-admins"))
+
+	_, err = sut.Exchange(context.Background(), "some-code", "other-nonce")
+	assert.ErrorIs(suite.T(), err, ErrOidcInvalidToken)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Exchange_CustomUsernameClaim() {
+	config.Get().Oidc.UsernameClaim = "email"
+	sut := NewOidcService(suite.UserService)
+	suite.Provider.claims["nonce"] = "some-nonce"
+
+	identity, err := sut.Exchange(context.Background(), "some-code", "some-nonce")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "john@example.org", identity.Username)
+
+	config.Get().Oidc.UsernameClaim = "nickname" // not present in token
+	sut = NewOidcService(suite.UserService)
+
+	_, err = sut.Exchange(context.Background(), "some-code", "some-nonce")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Login_Existing() {
+	sut := NewOidcService(suite.UserService)
+
+	existing := &models.User{ID: "john", OidcSubject: TestOidcSubject}
+	suite.UserService.On("GetUserByOidcSubject", TestOidcSubject).Return(existing, nil)
+	suite.UserService.On("Update", existing).Return(existing, nil)
+	suite.UserService.On("SetAdmin", existing, true).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).IsAdmin = args.Bool(1)
+	}).Return(existing, nil)
+
+	user, err := sut.Login(suite.testIdentity())
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "john", user.ID)
+	assert.True(suite.T(), user.IsAdmin)
+	suite.UserService.AssertNotCalled(suite.T(), "CreateOrGet", mock.Anything, mock.Anything)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Login_LinkByEmail() {
+	config.Get().Oidc.LinkByEmail = true
+	sut := NewOidcService(suite.UserService)
+
+	existing := &models.User{ID: "john", Email: "john@example.org", IsAdmin: true}
+	identity := suite.testIdentity()
+	identity.Groups = []string{}
+
+	suite.UserService.On("GetUserByOidcSubject", TestOidcSubject).Return((*models.User)(nil), errors.New("record not found"))
+	suite.UserService.On("GetUserByEmail", "john@example.org").Return(existing, nil)
+	suite.UserService.On("Update", existing).Return(existing, nil)
+	suite.UserService.On("SetAdmin", existing, false).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).IsAdmin = args.Bool(1)
+	}).Return(existing, nil)
+
+	user, err := sut.Login(identity)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "john", user.ID)
+	assert.Equal(suite.T(), TestOidcSubject, user.OidcSubject)
+	assert.False(suite.T(), user.IsAdmin)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Login_Provision() {
+	sut := NewOidcService(suite.UserService)
+
+	created := &models.User{ID: "johndoe", Email: "john@example.org"}
+	suite.UserService.On("GetUserByOidcSubject", TestOidcSubject).Return((*models.User)(nil), errors.New("record not found"))
+	suite.UserService.On("GetUserById", "johndoe").Return((*models.User)(nil), errors.New("record not found"))
+	suite.UserService.On("CreateOrGet", mock.MatchedBy(func(s *models.Signup) bool {
+		return s.Username == "johndoe" && s.Email == "john@example.org" && s.Password != ""
+	}), false).Return(created, true, nil)
+	suite.UserService.On("Update", created).Return(created, nil)
+	suite.UserService.On("SetAdmin", created, true).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).IsAdmin = args.Bool(1)
+	}).Return(created, nil)
+
+	user, err := sut.Login(suite.testIdentity())
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), TestOidcSubject, user.OidcSubject)
+	assert.True(suite.T(), user.IsAdmin)
+	suite.UserService.AssertNotCalled(suite.T(), "GetUserByEmail", mock.Anything)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Login_Rejected() {
+	sut := NewOidcService(suite.UserService)
+
+	suite.UserService.On("GetUserByOidcSubject", TestOidcSubject).Return((*models.User)(nil), errors.New("record not found"))
+	suite.UserService.On("GetUserById", "johndoe").Return(&models.User{ID: "johndoe"}, nil).Once()
+
+	_, err := sut.Login(suite.testIdentity())
+	assert.ErrorIs(suite.T(), err, ErrOidcUsernameTaken)
+
+	config.Get().Oidc.AllowSignup = false
+	suite.UserService.On("GetUserById", "johndoe").Return((*models.User)(nil), errors.New("record not found"))
+
+	_, err = sut.Login(suite.testIdentity())
+	assert.ErrorIs(suite.T(), err, ErrOidcSignupDisabled)
+	suite.UserService.AssertNotCalled(suite.T(), "CreateOrGet", mock.Anything, mock.Anything)
+}
+
+func (suite *OidcServiceTestSuite) testIdentity() *models.OidcIdentity {
+	return models.OidcIdentityFromClaims(suite.Provider.claims, "preferred_username", "groups")
+}
+
+// mockOidcProvider is a minimal openid connect provider, issuing id tokens with arbitrary claims for any authorization code
+type mockOidcProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]interface{}
+}
+
+func newMockOidcProvider(t *testing.T) *mockOidcProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &mockOidcProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *mockOidcProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/auth",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *mockOidcProvider) keys(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &p.key.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"},
+	}})
+}
+
+func (p *mockOidcProvider) token(w http.ResponseWriter, r *http.Request) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key}, (&jose.SignerOptions{}).WithHeader("kid", "test"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rawIdToken, err := jwt.Signed(signer).
+		Claims(jwt.Claims{
+			Issuer:   p.server.URL,
+			Audience: jwt.Audience{TestOidcClientId},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}).
+		Claims(p.claims).
+		CompactSerialize()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "some-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     rawIdToken,
+	})
+}
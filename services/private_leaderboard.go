@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	uuid "github.com/satori/go.uuid"
+	"strings"
+)
+
+var (
+	ErrPrivateLeaderboardInvalid       = errors.New("invalid leaderboard")
+	ErrPrivateLeaderboardNotFound      = errors.New("leaderboard not found")
+	ErrPrivateLeaderboardForbidden     = errors.New("not permitted to perform this operation on the leaderboard")
+	ErrPrivateLeaderboardAlreadyMember = errors.New("already a member of this leaderboard")
+	ErrPrivateLeaderboardOwner         = errors.New("the owner cannot leave a leaderboard, delete it instead")
+)
+
+type PrivateLeaderboardService struct {
+	config          *config.Config
+	repository      repositories.IPrivateLeaderboardRepository
+	leaderboardSrvc ILeaderboardService
+	queueDefault    *artifex.Dispatcher
+	queueWorkers    *artifex.Dispatcher
+}
+
+func NewPrivateLeaderboardService(privateLeaderboardRepository repositories.IPrivateLeaderboardRepository, leaderboardService ILeaderboardService) *PrivateLeaderboardService {
+	return &PrivateLeaderboardService{
+		config:          config.Get(),
+		repository:      privateLeaderboardRepository,
+		leaderboardSrvc: leaderboardService,
+		queueDefault:    config.GetDefaultQueue(),
+		queueWorkers:    config.GetQueue(config.QueueProcessing),
+	}
+}
+
+func (srv *PrivateLeaderboardService) Schedule() {
+	logbuch.Info("scheduling private leaderboard generation")
+
+	for _, cronExp := range srv.config.App.GetLeaderboardGenerationTimeCron() {
+		if _, err := srv.queueDefault.DispatchCron(srv.ComputeAll, cronExp); err != nil {
+			config.Log().Error("failed to schedule private leaderboard generation (%s), %v", cronExp, err)
+		}
+	}
+}
+
+func (srv *PrivateLeaderboardService) ComputeAll() {
+	leaderboards, err := srv.repository.GetAll()
+	if err != nil {
+		config.Log().Error("failed to get private leaderboards for generation - %v", err)
+		return
+	}
+	for _, l := range leaderboards {
+		if err := srv.Compute(l); err != nil {
+			config.Log().Error("failed to generate private leaderboard %d - %v", l.ID, err)
+		}
+	}
+}
+
+func (srv *PrivateLeaderboardService) Compute(leaderboard *models.PrivateLeaderboard) error {
+	members, err := srv.repository.GetMembers(leaderboard.ID)
+	if err != nil {
+		return err
+	}
+	users := make([]*models.User, 0, len(members))
+	for _, m := range members {
+		if m.User != nil {
+			users = append(users, m.User)
+		}
+	}
+	return srv.leaderboardSrvc.ComputePrivateLeaderboard(leaderboard, users)
+}
+
+func (srv *PrivateLeaderboardService) GetById(id uint) (*models.PrivateLeaderboard, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *PrivateLeaderboardService) GetByUser(user *models.User) ([]*models.PrivateLeaderboard, error) {
+	return srv.repository.GetByMember(user.ID)
+}
+
+func (srv *PrivateLeaderboardService) GetMembers(leaderboard *models.PrivateLeaderboard) ([]*models.PrivateLeaderboardMember, error) {
+	return srv.repository.GetMembers(leaderboard.ID)
+}
+
+func (srv *PrivateLeaderboardService) IsMember(leaderboard *models.PrivateLeaderboard, user *models.User) bool {
+	member, err := srv.repository.GetMember(leaderboard.ID, user.ID)
+	return err == nil && member != nil
+}
+
+// GetLeaderboard returns the ranked items of a private leaderboard, optionally aggregated by the given type, given the user is a member of it
+func (srv *PrivateLeaderboardService) GetLeaderboard(user *models.User, leaderboard *models.PrivateLeaderboard, by *uint8) (models.Leaderboard, error) {
+	if !srv.IsMember(leaderboard, user) {
+		return nil, ErrPrivateLeaderboardForbidden
+	}
+	return srv.leaderboardSrvc.GetPrivateLeaderboard(leaderboard, by, true)
+}
+
+func (srv *PrivateLeaderboardService) Create(owner *models.User, name, interval, language string) (*models.PrivateLeaderboard, error) {
+	leaderboard := &models.PrivateLeaderboard{
+		Name:       strings.TrimSpace(name),
+		OwnerID:    owner.ID,
+		InviteCode: generateLeaderboardInviteCode(),
+		Interval:   interval,
+		Language:   strings.TrimSpace(language),
+	}
+	if !leaderboard.IsValid() {
+		return nil, ErrPrivateLeaderboardInvalid
+	}
+	leaderboard.Interval = (*leaderboard.Scope())[0]
+
+	leaderboard, err := srv.repository.Insert(leaderboard)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := srv.repository.InsertMember(&models.PrivateLeaderboardMember{LeaderboardID: leaderboard.ID, UserID: owner.ID}); err != nil {
+		return nil, err
+	}
+
+	srv.computeAsync(leaderboard)
+	return leaderboard, nil
+}
+
+func (srv *PrivateLeaderboardService) Update(actor *models.User, leaderboard *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error) {
+	if leaderboard.OwnerID != actor.ID {
+		return nil, ErrPrivateLeaderboardForbidden
+	}
+	leaderboard.Name = strings.TrimSpace(leaderboard.Name)
+	leaderboard.Language = strings.TrimSpace(leaderboard.Language)
+	if !leaderboard.IsValid() {
+		return nil, ErrPrivateLeaderboardInvalid
+	}
+	leaderboard.Interval = (*leaderboard.Scope())[0]
+
+	leaderboard, err := srv.repository.Update(leaderboard)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.computeAsync(leaderboard)
+	return leaderboard, nil
+}
+
+func (srv *PrivateLeaderboardService) ResetInviteCode(actor *models.User, leaderboard *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error) {
+	if leaderboard.OwnerID != actor.ID {
+		return nil, ErrPrivateLeaderboardForbidden
+	}
+	leaderboard.InviteCode = generateLeaderboardInviteCode()
+	return srv.repository.Update(leaderboard)
+}
+
+func (srv *PrivateLeaderboardService) Join(user *models.User, inviteCode string) (*models.PrivateLeaderboard, error) {
+	leaderboard, err := srv.repository.GetByInviteCode(strings.TrimSpace(inviteCode))
+	if err != nil {
+		return nil, ErrPrivateLeaderboardNotFound
+	}
+	if srv.IsMember(leaderboard, user) {
+		return nil, ErrPrivateLeaderboardAlreadyMember
+	}
+
+	if _, err := srv.repository.InsertMember(&models.PrivateLeaderboardMember{LeaderboardID: leaderboard.ID, UserID: user.ID}); err != nil {
+		return nil, err
+	}
+
+	srv.computeAsync(leaderboard)
+	return leaderboard, nil
+}
+
+func (srv *PrivateLeaderboardService) Leave(user *models.User, leaderboard *models.PrivateLeaderboard) error {
+	if leaderboard.OwnerID == user.ID {
+		return ErrPrivateLeaderboardOwner
+	}
+	if !srv.IsMember(leaderboard, user) {
+		return ErrPrivateLeaderboardForbidden
+	}
+	return srv.deleteMember(leaderboard, user.ID)
+}
+
+func (srv *PrivateLeaderboardService) RemoveMember(actor *models.User, leaderboard *models.PrivateLeaderboard, userId string) error {
+	if leaderboard.OwnerID != actor.ID || userId == actor.ID {
+		return ErrPrivateLeaderboardForbidden
+	}
+	return srv.deleteMember(leaderboard, userId)
+}
+
+func (srv *PrivateLeaderboardService) Delete(actor *models.User, leaderboard *models.PrivateLeaderboard) error {
+	if leaderboard.OwnerID != actor.ID {
+		return ErrPrivateLeaderboardForbidden
+	}
+	return srv.repository.Delete(leaderboard.ID)
+}
+
+func (srv *PrivateLeaderboardService) deleteMember(leaderboard *models.PrivateLeaderboard, userId string) error {
+	if err := srv.repository.DeleteMember(leaderboard.ID, userId); err != nil {
+		return err
+	}
+	srv.computeAsync(leaderboard)
+	return nil
+}
+
+func (srv *PrivateLeaderboardService) computeAsync(leaderboard *models.PrivateLeaderboard) {
+	if err := srv.queueWorkers.Dispatch(func() {
+		if err := srv.Compute(leaderboard); err != nil {
+			config.Log().Error("failed to generate private leaderboard %d - %v", leaderboard.ID, err)
+		}
+	}); err != nil {
+		config.Log().Error("failed to dispatch private leaderboard generation - %v", err)
+	}
+}
+
+func generateLeaderboardInviteCode() string {
+	return strings.ReplaceAll(uuid.NewV4().String(), "-", "")[0:12]
+}
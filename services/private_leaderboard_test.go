@@ -0,0 +1,108 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type PrivateLeaderboardServiceTestSuite struct {
+	suite.Suite
+	Owner                        *models.User
+	Member                       *models.User
+	TestLeaderboard              *models.PrivateLeaderboard
+	PrivateLeaderboardRepository *mocks.PrivateLeaderboardRepositoryMock
+	LeaderboardService           *mocks.LeaderboardServiceMock
+}
+
+func (suite *PrivateLeaderboardServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+
+	suite.Owner = &models.User{ID: "owner"}
+	suite.Member = &models.User{ID: TestUserId}
+	suite.TestLeaderboard = &models.PrivateLeaderboard{ID: 1, Name: "Friends", OwnerID: suite.Owner.ID, InviteCode: "abcdef", Interval: models.IntervalPast7Days.GetHumanReadable()}
+
+	suite.PrivateLeaderboardRepository = new(mocks.PrivateLeaderboardRepositoryMock)
+	suite.LeaderboardService = new(mocks.LeaderboardServiceMock)
+
+	// leaderboards are (re-)computed asynchronously after membership changes
+	suite.PrivateLeaderboardRepository.On("GetMembers", mock.Anything).Return([]*models.PrivateLeaderboardMember{}, nil).Maybe()
+	suite.LeaderboardService.On("ComputePrivateLeaderboard", mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+func TestPrivateLeaderboardServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(PrivateLeaderboardServiceTestSuite))
+}
+
+func (suite *PrivateLeaderboardServiceTestSuite) TestPrivateLeaderboardService_Create() {
+	sut := NewPrivateLeaderboardService(suite.PrivateLeaderboardRepository, suite.LeaderboardService)
+
+	suite.PrivateLeaderboardRepository.On("Insert", mock.Anything).Return(nil, nil)
+	suite.PrivateLeaderboardRepository.On("InsertMember", mock.Anything).Return(nil, nil)
+
+	result, err := sut.Create(suite.Owner, " Friends ", "30_days", "Go")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Friends", result.Name)
+	assert.Equal(suite.T(), suite.Owner.ID, result.OwnerID)
+	assert.Len(suite.T(), result.InviteCode, 12)
+	assert.Equal(suite.T(), models.IntervalPast30Days, result.Scope())
+
+	member := suite.PrivateLeaderboardRepository.Calls[1].Arguments.Get(0).(*models.PrivateLeaderboardMember)
+	assert.Equal(suite.T(), suite.Owner.ID, member.UserID)
+
+	_, err = sut.Create(suite.Owner, "Friends", "all_time", "")
+	assert.ErrorIs(suite.T(), err, ErrPrivateLeaderboardInvalid)
+}
+
+func (suite *PrivateLeaderboardServiceTestSuite) TestPrivateLeaderboardService_Join() {
+	sut := NewPrivateLeaderboardService(suite.PrivateLeaderboardRepository, suite.LeaderboardService)
+
+	suite.PrivateLeaderboardRepository.On("GetByInviteCode", "abcdef").Return(suite.TestLeaderboard, nil)
+	suite.PrivateLeaderboardRepository.On("GetByInviteCode", mock.Anything).Return(nil, assert.AnError)
+	suite.PrivateLeaderboardRepository.On("GetMember", suite.TestLeaderboard.ID, suite.Owner.ID).Return(&models.PrivateLeaderboardMember{}, nil)
+	suite.PrivateLeaderboardRepository.On("GetMember", suite.TestLeaderboard.ID, suite.Member.ID).Return(nil, assert.AnError)
+	suite.PrivateLeaderboardRepository.On("InsertMember", mock.Anything).Return(nil, nil)
+
+	result, err := sut.Join(suite.Member, " abcdef")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.TestLeaderboard.ID, result.ID)
+
+	_, err = sut.Join(suite.Owner, "abcdef")
+	assert.ErrorIs(suite.T(), err, ErrPrivateLeaderboardAlreadyMember)
+
+	_, err = sut.Join(suite.Member, "invalid")
+	assert.ErrorIs(suite.T(), err, ErrPrivateLeaderboardNotFound)
+}
+
+func (suite *PrivateLeaderboardServiceTestSuite) TestPrivateLeaderboardService_GetLeaderboard() {
+	sut := NewPrivateLeaderboardService(suite.PrivateLeaderboardRepository, suite.LeaderboardService)
+
+	items := models.Leaderboard{{LeaderboardItem: models.LeaderboardItem{UserID: suite.Owner.ID}, Rank: 1}}
+
+	suite.PrivateLeaderboardRepository.On("GetMember", suite.TestLeaderboard.ID, suite.Owner.ID).Return(&models.PrivateLeaderboardMember{}, nil)
+	suite.PrivateLeaderboardRepository.On("GetMember", suite.TestLeaderboard.ID, suite.Member.ID).Return(nil, assert.AnError)
+	suite.LeaderboardService.On("GetPrivateLeaderboard", suite.TestLeaderboard, (*uint8)(nil), true).Return(items, nil)
+
+	result, err := sut.GetLeaderboard(suite.Owner, suite.TestLeaderboard, nil)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), items, result)
+
+	_, err = sut.GetLeaderboard(suite.Member, suite.TestLeaderboard, nil)
+	assert.ErrorIs(suite.T(), err, ErrPrivateLeaderboardForbidden)
+}
+
+func (suite *PrivateLeaderboardServiceTestSuite) TestPrivateLeaderboardService_Leave() {
+	sut := NewPrivateLeaderboardService(suite.PrivateLeaderboardRepository, suite.LeaderboardService)
+
+	suite.PrivateLeaderboardRepository.On("GetMember", suite.TestLeaderboard.ID, suite.Member.ID).Return(&models.PrivateLeaderboardMember{}, nil)
+	suite.PrivateLeaderboardRepository.On("DeleteMember", suite.TestLeaderboard.ID, suite.Member.ID).Return(nil)
+
+	assert.ErrorIs(suite.T(), sut.Leave(suite.Owner, suite.TestLeaderboard), ErrPrivateLeaderboardOwner)
+	assert.Nil(suite.T(), sut.Leave(suite.Member, suite.TestLeaderboard))
+	assert.ErrorIs(suite.T(), sut.RemoveMember(suite.Member, suite.TestLeaderboard, suite.Owner.ID), ErrPrivateLeaderboardForbidden)
+	suite.PrivateLeaderboardRepository.AssertNumberOfCalls(suite.T(), "DeleteMember", 1)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"github.com/duke-git/lancet/v2/slice"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
@@ -12,18 +13,20 @@ import (
 )
 
 type ProjectLabelService struct {
-	config     *config.Config
-	cache      *cache.Cache
-	eventBus   *hub.Hub
-	repository repositories.IProjectLabelRepository
+	config          *config.Config
+	cache           *cache.Cache
+	eventBus        *hub.Hub
+	repository      repositories.IProjectLabelRepository
+	colorRepository repositories.ILabelColorRepository
 }
 
-func NewProjectLabelService(projectLabelRepository repositories.IProjectLabelRepository) *ProjectLabelService {
+func NewProjectLabelService(projectLabelRepository repositories.IProjectLabelRepository, labelColorRepository repositories.ILabelColorRepository) *ProjectLabelService {
 	return &ProjectLabelService{
-		config:     config.Get(),
-		eventBus:   config.EventBus(),
-		repository: projectLabelRepository,
-		cache:      cache.New(24*time.Hour, 24*time.Hour),
+		config:          config.Get(),
+		eventBus:        config.EventBus(),
+		repository:      projectLabelRepository,
+		colorRepository: labelColorRepository,
+		cache:           cache.New(24*time.Hour, 24*time.Hour),
 	}
 }
 
@@ -68,7 +71,28 @@ func (srv *ProjectLabelService) GetByUserGroupedInverted(userId string) (map[str
 	return mappedLabels, nil
 }
 
+// GetColorsByUser returns the user's custom label colors, mapped by label key
+func (srv *ProjectLabelService) GetColorsByUser(userId string) (map[string]string, error) {
+	cacheKey := fmt.Sprintf("%s--colors", userId)
+	if colors, found := srv.cache.Get(cacheKey); found {
+		return colors.(map[string]string), nil
+	}
+
+	labelColors, err := srv.colorRepository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	colors := make(map[string]string, len(labelColors))
+	for _, c := range labelColors {
+		colors[c.Label] = c.Color
+	}
+	srv.cache.Set(cacheKey, colors, cache.DefaultExpiration)
+	return colors, nil
+}
+
 func (srv *ProjectLabelService) Create(label *models.ProjectLabel) (*models.ProjectLabel, error) {
+	label.Label = models.NormalizeLabel(label.Label)
 	result, err := srv.repository.Insert(label)
 	if err != nil {
 		return nil, err
@@ -89,6 +113,25 @@ func (srv *ProjectLabelService) Delete(label *models.ProjectLabel) error {
 	return err
 }
 
+func (srv *ProjectLabelService) SetColor(color *models.LabelColor) (*models.LabelColor, error) {
+	color.Label = models.NormalizeLabel(color.Label)
+	result, err := srv.colorRepository.Upsert(color)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(fmt.Sprintf("%s--colors", color.UserID))
+	return result, nil
+}
+
+func (srv *ProjectLabelService) DeleteColor(userId, label string) error {
+	if userId == "" {
+		return errors.New("no user id specified")
+	}
+	err := srv.colorRepository.DeleteByUserAndLabel(userId, models.NormalizeLabel(label))
+	srv.cache.Delete(fmt.Sprintf("%s--colors", userId))
+	return err
+}
+
 func (srv *ProjectLabelService) notifyUpdate(label *models.ProjectLabel, isDelete bool) {
 	name := config.EventProjectLabelCreate
 	if isDelete {
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	relayOutboxPollEvery     = 10 * time.Second
+	relayOutboxFetchSize     = 500
+	relayOutboxBatchSize     = 25 // max. number of heartbeats accepted by wakatime's bulk endpoint
+	relayOutboxRetention     = 7 * 24 * time.Hour
+	relayDisabledTargetDelay = 1 * time.Hour
+	maxRelayFailuresPerDay   = 100
+)
+
+var relayOutboxLock = sync.Mutex{}
+
+// RelayService delivers heartbeats to a user's WakaTime connection and relay targets. Heartbeats are persisted to an outbox first
+// and picked up by a background worker, which sends them in batches and retries failed deliveries with exponential backoff.
+type RelayService struct {
+	config          *config.Config
+	eventBus        *hub.Hub
+	httpClient      *http.Client
+	failureCache    *cache.Cache
+	queueDefault    *artifex.Dispatcher
+	queueRelay      *artifex.Dispatcher
+	repository      repositories.IRelayOutboxRepository
+	userSrvc        IUserService
+	relayTargetSrvc IRelayTargetService
+}
+
+func NewRelayService(relayOutboxRepository repositories.IRelayOutboxRepository, userService IUserService, relayTargetService IRelayTargetService) *RelayService {
+	return &RelayService{
+		config:          config.Get(),
+		eventBus:        config.EventBus(),
+		httpClient:      utils.NewPublicHttpClient(10*time.Second, config.Get().Security.AllowPrivateNetworks),
+		failureCache:    cache.New(24*time.Hour, 1*time.Hour),
+		queueDefault:    config.GetDefaultQueue(),
+		queueRelay:      config.GetQueue(config.QueueRelay),
+		repository:      relayOutboxRepository,
+		userSrvc:        userService,
+		relayTargetSrvc: relayTargetService,
+	}
+}
+
+func (srv *RelayService) Schedule() {
+	logbuch.Info("scheduling relay outbox delivery")
+	if _, err := srv.queueRelay.DispatchEvery(srv.ProcessOutbox, relayOutboxPollEvery); err != nil {
+		config.Log().Error("failed to schedule relay outbox delivery, %v", err)
+	}
+	if _, err := srv.queueDefault.DispatchEvery(srv.runCleanup, 6*time.Hour); err != nil {
+		config.Log().Error("failed to schedule relay outbox cleanup, %v", err)
+	}
+}
+
+// Enqueue persists the given heartbeats to the outbox, from where they will be delivered asynchronously
+func (srv *RelayService) Enqueue(items []*models.RelayOutboxItem) error {
+	now := models.CustomTime(time.Now())
+	for _, item := range items {
+		item.NextAttemptAt = now
+	}
+	return srv.repository.InsertBatch(items)
+}
+
+func (srv *RelayService) GetBacklog(user *models.User) (*models.RelayBacklog, error) {
+	return srv.repository.GetBacklog(user.ID)
+}
+
+func (srv *RelayService) CountBacklogByUsers(users []*models.User) ([]*models.CountByUser, error) {
+	return srv.repository.CountUndeliveredByUsers(users)
+}
+
+// ProcessOutbox delivers all due outbox items, grouped into bulk requests per user, target and client
+func (srv *RelayService) ProcessOutbox() {
+	if ok := relayOutboxLock.TryLock(); !ok {
+		return // previous run still in progress
+	}
+	defer relayOutboxLock.Unlock()
+
+	for {
+		items, err := srv.repository.GetDue(time.Now(), relayOutboxFetchSize)
+		if err != nil {
+			config.Log().Error("failed to fetch relay outbox items - %v", err)
+			return
+		}
+
+		for _, batch := range batchOutboxItems(items, relayOutboxBatchSize) {
+			srv.deliver(batch)
+		}
+
+		// delivered, failed and postponed items are not due anymore, so there is no risk of picking up the same ones again
+		if len(items) < relayOutboxFetchSize {
+			return
+		}
+	}
+}
+
+func (srv *RelayService) deliver(batch []*models.RelayOutboxItem) {
+	first := batch[0]
+	ids := make([]uint64, len(batch))
+	for i, item := range batch {
+		ids[i] = item.ID
+	}
+
+	var (
+		url    string
+		apiKey string
+		target *models.RelayTarget
+		user   *models.User
+		err    error
+	)
+
+	if first.IsPrimary() {
+		if user, err = srv.userSrvc.GetUserById(first.UserID); err != nil {
+			config.Log().Error("failed to fetch user %s for relaying - %v", first.UserID, err)
+			srv.postpone(ids)
+			return
+		}
+		if user.WakatimeApiKey == "" {
+			// wakatime connection was removed in the meantime
+			srv.drop(ids)
+			return
+		}
+		url = user.WakaTimeURL(config.WakatimeApiUrl) + config.WakatimeApiHeartbeatsBulkUrl
+		apiKey = user.WakatimeApiKey
+	} else {
+		if target, err = srv.relayTargetSrvc.GetById(first.TargetID); err != nil || target.UserID != first.UserID {
+			// relay target was deleted in the meantime
+			srv.drop(ids)
+			return
+		}
+		if !target.Enabled {
+			srv.postpone(ids)
+			return
+		}
+		url = target.BaseUrl() + config.WakatimeApiHeartbeatsBulkUrl
+		apiKey = target.ApiKey
+	}
+
+	if reason := srv.send(url, apiKey, batch); reason != "" {
+		logbuch.Warn("failed to relay %d heartbeats for user %s to %s - %s", len(batch), first.UserID, url, reason)
+
+		now := time.Now()
+		for _, item := range batch {
+			item.ScheduleRetry(reason, now)
+		}
+		if err := srv.repository.UpdateAttempts(batch); err != nil {
+			config.Log().Error("failed to update relay outbox items - %v", err)
+		}
+
+		if target != nil {
+			if err := srv.relayTargetSrvc.RecordFailure(target, reason); err != nil {
+				config.Log().Error("failed to update relay target %d - %v", target.ID, err)
+			}
+		} else {
+			srv.recordPrimaryFailure(user)
+		}
+		return
+	}
+
+	if err := srv.repository.MarkDelivered(ids, time.Now()); err != nil {
+		config.Log().Error("failed to mark relay outbox items as delivered - %v", err)
+	}
+	if target != nil {
+		if err := srv.relayTargetSrvc.RecordSuccess(target); err != nil {
+			config.Log().Error("failed to update relay target %d - %v", target.ID, err)
+		}
+	}
+}
+
+// send posts the batch's heartbeats to the given bulk endpoint and returns a failure reason or an empty string on success
+func (srv *RelayService) send(url, apiKey string, batch []*models.RelayOutboxItem) string {
+	payloads := make([]string, len(batch))
+	for i, item := range batch {
+		payloads[i] = item.Payload
+	}
+	body := "[" + strings.Join(payloads, ",") + "]"
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	if err != nil {
+		return err.Error()
+	}
+
+	first := batch[0]
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Origin", fmt.Sprintf("wakapi v%s", srv.config.Version))
+	request.Header.Set("X-Origin-Instance", first.OriginInstance)
+	request.Header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(apiKey))))
+	if first.MachineName != "" {
+		request.Header.Set("X-Machine-Name", first.MachineName)
+	}
+	if first.UserAgent != "" {
+		request.Header.Set("User-Agent", first.UserAgent)
+	}
+
+	response, err := srv.httpClient.Do(request)
+	if err != nil {
+		// the reason is shown to the user, so details of connection errors are not passed on
+		logbuch.Warn("failed to send relay request to %s - %v", url, err)
+		if errors.Is(err, utils.ErrNonPublicAddress) {
+			return utils.ErrNonPublicAddress.Error()
+		}
+		return "failed to connect"
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Sprintf("got status %d", response.StatusCode)
+	}
+	return ""
+}
+
+func (srv *RelayService) recordPrimaryFailure(user *models.User) {
+	// TODO: use leaky bucket instead of expiring cache?
+	if _, found := srv.failureCache.Get(user.ID); !found {
+		srv.failureCache.SetDefault(user.ID, 0)
+	}
+	if n, _ := srv.failureCache.IncrementInt(user.ID, 1); n == maxRelayFailuresPerDay {
+		srv.eventBus.Publish(hub.Message{
+			Name:   config.EventWakatimeFailure,
+			Fields: map[string]interface{}{config.FieldUser: user, config.FieldPayload: n},
+		})
+	} else if n%10 == 0 {
+		logbuch.Warn("%d / %d failed wakatime heartbeat relaying attempts for user %s within last 24 hours", n, maxRelayFailuresPerDay, user.ID)
+	}
+}
+
+func (srv *RelayService) postpone(ids []uint64) {
+	if err := srv.repository.Postpone(ids, time.Now().Add(relayDisabledTargetDelay)); err != nil {
+		config.Log().Error("failed to postpone relay outbox items - %v", err)
+	}
+}
+
+func (srv *RelayService) drop(ids []uint64) {
+	if err := srv.repository.DeleteByIds(ids); err != nil {
+		config.Log().Error("failed to delete relay outbox items - %v", err)
+	}
+}
+
+func (srv *RelayService) runCleanup() {
+	n, err := srv.repository.DeleteDeliveredBefore(time.Now().Add(-relayOutboxRetention))
+	if err != nil {
+		config.Log().Error("failed to clean up relay outbox - %v", err)
+		return
+	}
+	if n > 0 {
+		logbuch.Info("deleted %d delivered or abandoned relay outbox items", n)
+	}
+}
+
+// batchOutboxItems groups the given items by their batch key, preserving their order, and splits each group into chunks of at most the given size
+func batchOutboxItems(items []*models.RelayOutboxItem, size int) [][]*models.RelayOutboxItem {
+	batches := make([][]*models.RelayOutboxItem, 0)
+	open := make(map[string]int) // batch key -> index of the batch currently being filled
+
+	for _, item := range items {
+		key := item.BatchKey()
+		if i, ok := open[key]; ok && len(batches[i]) < size {
+			batches[i] = append(batches[i], item)
+			continue
+		}
+		open[key] = len(batches)
+		batches = append(batches, []*models.RelayOutboxItem{item})
+	}
+
+	return batches
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+	"time"
+)
+
+// maxRelayTargetFailures is the number of consecutive failed relays after which a target gets disabled automatically
+const maxRelayTargetFailures = 100
+
+type RelayTargetService struct {
+	config     *config.Config
+	eventBus   *hub.Hub
+	cache      *cache.Cache
+	repository repositories.IRelayTargetRepository
+}
+
+func NewRelayTargetService(relayTargetRepository repositories.IRelayTargetRepository) *RelayTargetService {
+	return &RelayTargetService{
+		config:     config.Get(),
+		eventBus:   config.EventBus(),
+		cache:      cache.New(1*time.Hour, 2*time.Hour),
+		repository: relayTargetRepository,
+	}
+}
+
+func (srv *RelayTargetService) GetById(id uint) (*models.RelayTarget, error) {
+	return srv.repository.GetById(id)
+}
+
+// GetByUser returns all of the user's relay targets including their current failure counters, bypassing the cache
+func (srv *RelayTargetService) GetByUser(userId string) ([]*models.RelayTarget, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+// GetByUsers returns the relay targets of all given users, bypassing the cache
+func (srv *RelayTargetService) GetByUsers(users []*models.User) ([]*models.RelayTarget, error) {
+	return srv.repository.GetByUsers(users)
+}
+
+// GetEnabledByUser returns the user's active relay targets, as used for relaying every incoming heartbeat
+func (srv *RelayTargetService) GetEnabledByUser(userId string) ([]*models.RelayTarget, error) {
+	if targets, found := srv.cache.Get(userId); found {
+		return targets.([]*models.RelayTarget), nil
+	}
+
+	targets, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make([]*models.RelayTarget, 0, len(targets))
+	for _, t := range targets {
+		if t.Enabled {
+			enabled = append(enabled, t)
+		}
+	}
+
+	srv.cache.SetDefault(userId, enabled)
+	return enabled, nil
+}
+
+func (srv *RelayTargetService) Create(target *models.RelayTarget) (*models.RelayTarget, error) {
+	if !target.IsValid() {
+		return nil, errors.New("invalid relay target")
+	}
+	srv.cache.Delete(target.UserID)
+	return srv.repository.Insert(target)
+}
+
+func (srv *RelayTargetService) Update(target *models.RelayTarget) (*models.RelayTarget, error) {
+	if !target.IsValid() {
+		return nil, errors.New("invalid relay target")
+	}
+	srv.cache.Delete(target.UserID)
+	return srv.repository.Update(target)
+}
+
+func (srv *RelayTargetService) Delete(target *models.RelayTarget) error {
+	srv.cache.Delete(target.UserID)
+	return srv.repository.Delete(target.ID)
+}
+
+func (srv *RelayTargetService) RecordSuccess(target *models.RelayTarget) error {
+	return srv.repository.RecordSuccess(target.ID)
+}
+
+// RecordFailure counts a failed relay attempt and disables the target once it failed too many times in a row
+func (srv *RelayTargetService) RecordFailure(target *models.RelayTarget, reason string) error {
+	n, err := srv.repository.RecordFailure(target.ID, reason)
+	if err != nil {
+		return err
+	}
+
+	if n < maxRelayTargetFailures {
+		if n%10 == 0 {
+			logbuch.Warn("%d / %d consecutive failed heartbeat relaying attempts to target %d of user %s", n, maxRelayTargetFailures, target.ID, target.UserID)
+		}
+		return nil
+	}
+
+	current, err := srv.repository.GetById(target.ID)
+	if err != nil {
+		return err
+	}
+	if !current.Enabled {
+		return nil
+	}
+
+	logbuch.Warn("disabling relay target %d of user %s, because of too many consecutive failures (%d)", target.ID, target.UserID, n)
+	current.Enabled = false
+	if _, err := srv.Update(current); err != nil {
+		return err
+	}
+
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventRelayTargetDisable,
+		Fields: map[string]interface{}{config.FieldUserId: current.UserID, config.FieldPayload: current},
+	})
+	return nil
+}
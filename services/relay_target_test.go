@@ -0,0 +1,84 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type RelayTargetServiceTestSuite struct {
+	suite.Suite
+	TestTargets           []*models.RelayTarget
+	RelayTargetRepository *mocks.RelayTargetRepositoryMock
+}
+
+func (suite *RelayTargetServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+
+	suite.TestTargets = []*models.RelayTarget{
+		{ID: 1, UserID: TestUserId, ApiUrl: "https://api.wakatime.com/api/v1", ApiKey: "key1", Enabled: true},
+		{ID: 2, UserID: TestUserId, ApiUrl: "https://wakapi.example.org/api/compat/wakatime/v1", ApiKey: "key2", Enabled: false},
+	}
+	suite.RelayTargetRepository = new(mocks.RelayTargetRepositoryMock)
+}
+
+func TestRelayTargetServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RelayTargetServiceTestSuite))
+}
+
+func (suite *RelayTargetServiceTestSuite) TestRelayTargetService_GetEnabledByUser() {
+	sut := NewRelayTargetService(suite.RelayTargetRepository)
+
+	suite.RelayTargetRepository.On("GetByUser", TestUserId).Return(suite.TestTargets, nil).Once()
+
+	result, err := sut.GetEnabledByUser(TestUserId)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	assert.Equal(suite.T(), uint(1), result[0].ID)
+
+	// served from cache
+	result, err = sut.GetEnabledByUser(TestUserId)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	suite.RelayTargetRepository.AssertNumberOfCalls(suite.T(), "GetByUser", 1)
+}
+
+func (suite *RelayTargetServiceTestSuite) TestRelayTargetService_RecordFailure() {
+	sut := NewRelayTargetService(suite.RelayTargetRepository)
+
+	suite.RelayTargetRepository.On("RecordFailure", uint(1), "got status 500").Return(maxRelayTargetFailures-1, nil).Once()
+
+	err := sut.RecordFailure(suite.TestTargets[0], "got status 500")
+	assert.Nil(suite.T(), err)
+	suite.RelayTargetRepository.AssertNotCalled(suite.T(), "Update", mock.Anything)
+}
+
+func (suite *RelayTargetServiceTestSuite) TestRelayTargetService_RecordFailure_Disable() {
+	sut := NewRelayTargetService(suite.RelayTargetRepository)
+	sub := config.EventBus().Subscribe(1, config.EventRelayTargetDisable)
+	defer config.EventBus().Unsubscribe(sub)
+
+	current := *suite.TestTargets[0]
+
+	suite.RelayTargetRepository.On("RecordFailure", uint(1), "got status 500").Return(maxRelayTargetFailures, nil)
+	suite.RelayTargetRepository.On("GetById", uint(1)).Return(&current, nil)
+	suite.RelayTargetRepository.On("Update", mock.Anything).Return(&current, nil)
+
+	err := sut.RecordFailure(suite.TestTargets[0], "got status 500")
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), current.Enabled)
+	suite.RelayTargetRepository.AssertNumberOfCalls(suite.T(), "Update", 1)
+
+	select {
+	case m := <-sub.Receiver:
+		assert.Equal(suite.T(), TestUserId, m.Fields[config.FieldUserId])
+		assert.Equal(suite.T(), &current, m.Fields[config.FieldPayload])
+	case <-time.After(time.Second):
+		suite.T().Fatal("expected relay target disable event")
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type relayStubRequest struct {
+	Path          string
+	Authorization string
+	MachineName   string
+	Heartbeats    []map[string]interface{}
+}
+
+type RelayServiceTestSuite struct {
+	suite.Suite
+	TestUser              *models.User
+	StubServer            *httptest.Server
+	StubStatus            int
+	StubRequests          []*relayStubRequest
+	stubLock              sync.Mutex
+	RelayOutboxRepository *mocks.RelayOutboxRepositoryMock
+	UserService           *mocks.UserServiceMock
+	RelayTargetService    *mocks.RelayTargetServiceMock
+}
+
+func (suite *RelayServiceTestSuite) SetupSuite() {
+	suite.StubServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.stubLock.Lock()
+		defer suite.stubLock.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		req := &relayStubRequest{
+			Path:          r.URL.Path,
+			Authorization: r.Header.Get("Authorization"),
+			MachineName:   r.Header.Get("X-Machine-Name"),
+		}
+		json.Unmarshal(body, &req.Heartbeats)
+		suite.StubRequests = append(suite.StubRequests, req)
+
+		w.WriteHeader(suite.StubStatus)
+	}))
+}
+
+func (suite *RelayServiceTestSuite) TearDownSuite() {
+	suite.StubServer.Close()
+}
+
+func (suite *RelayServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Security.AllowPrivateNetworks = true // stub server listens on loopback
+	config.Set(cfg)
+
+	suite.TestUser = &models.User{ID: TestUserId, WakatimeApiKey: "primary-key", WakatimeApiUrl: suite.StubServer.URL + "/primary"}
+	suite.StubStatus = http.StatusCreated
+	suite.StubRequests = []*relayStubRequest{}
+	suite.RelayOutboxRepository = new(mocks.RelayOutboxRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.RelayTargetService = new(mocks.RelayTargetServiceMock)
+}
+
+func TestRelayServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RelayServiceTestSuite))
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_ProcessOutbox() {
+	sut := NewRelayService(suite.RelayOutboxRepository, suite.UserService, suite.RelayTargetService)
+
+	target := &models.RelayTarget{ID: 7, UserID: TestUserId, ApiUrl: suite.StubServer.URL + "/target/", ApiKey: "target-key", Enabled: true}
+	items := []*models.RelayOutboxItem{
+		{ID: 1, UserID: TestUserId, TargetID: 0, Payload: `{"entity":"a.go"}`, MachineName: "laptop"},
+		{ID: 2, UserID: TestUserId, TargetID: 7, Payload: `{"entity":"a.go"}`, MachineName: "laptop"},
+		{ID: 3, UserID: TestUserId, TargetID: 0, Payload: `{"entity":"b.go"}`, MachineName: "laptop"},
+	}
+
+	suite.RelayOutboxRepository.On("GetDue", mock.Anything, relayOutboxFetchSize).Return(items, nil)
+	suite.RelayOutboxRepository.On("MarkDelivered", mock.Anything, mock.Anything).Return(nil)
+	suite.UserService.On("GetUserById", TestUserId).Return(suite.TestUser, nil)
+	suite.RelayTargetService.On("GetById", uint(7)).Return(target, nil)
+	suite.RelayTargetService.On("RecordSuccess", target).Return(nil)
+
+	sut.ProcessOutbox()
+
+	assert.Len(suite.T(), suite.StubRequests, 2) // one bulk request per target
+
+	assert.Equal(suite.T(), "/primary"+config.WakatimeApiHeartbeatsBulkUrl, suite.StubRequests[0].Path)
+	assert.Equal(suite.T(), "Basic "+base64.StdEncoding.EncodeToString([]byte("primary-key")), suite.StubRequests[0].Authorization)
+	assert.Equal(suite.T(), "laptop", suite.StubRequests[0].MachineName)
+	assert.Len(suite.T(), suite.StubRequests[0].Heartbeats, 2)
+	assert.Equal(suite.T(), "b.go", suite.StubRequests[0].Heartbeats[1]["entity"])
+
+	assert.Equal(suite.T(), "/target"+config.WakatimeApiHeartbeatsBulkUrl, suite.StubRequests[1].Path)
+	assert.Equal(suite.T(), "Basic "+base64.StdEncoding.EncodeToString([]byte("target-key")), suite.StubRequests[1].Authorization)
+	assert.Len(suite.T(), suite.StubRequests[1].Heartbeats, 1)
+
+	suite.RelayOutboxRepository.AssertCalled(suite.T(), "MarkDelivered", []uint64{1, 3}, mock.Anything)
+	suite.RelayOutboxRepository.AssertCalled(suite.T(), "MarkDelivered", []uint64{2}, mock.Anything)
+	suite.RelayTargetService.AssertCalled(suite.T(), "RecordSuccess", target)
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_ProcessOutbox_Retry() {
+	sut := NewRelayService(suite.RelayOutboxRepository, suite.UserService, suite.RelayTargetService)
+
+	target := &models.RelayTarget{ID: 7, UserID: TestUserId, ApiUrl: suite.StubServer.URL, ApiKey: "target-key", Enabled: true}
+	items := []*models.RelayOutboxItem{
+		{ID: 1, UserID: TestUserId, TargetID: 7, Payload: `{"entity":"a.go"}`, Attempts: 2},
+	}
+
+	suite.StubStatus = http.StatusServiceUnavailable
+	suite.RelayOutboxRepository.On("GetDue", mock.Anything, relayOutboxFetchSize).Return(items, nil)
+	suite.RelayOutboxRepository.On("UpdateAttempts", items).Return(nil)
+	suite.RelayTargetService.On("GetById", uint(7)).Return(target, nil)
+	suite.RelayTargetService.On("RecordFailure", target, "got status 503").Return(nil)
+
+	before := time.Now()
+	sut.ProcessOutbox()
+
+	assert.Len(suite.T(), suite.StubRequests, 1)
+	assert.Equal(suite.T(), 3, items[0].Attempts)
+	assert.Equal(suite.T(), "got status 503", items[0].LastError)
+	assert.True(suite.T(), items[0].NextAttemptAt.T().After(before.Add(models.RelayOutboxBackoff(3)-time.Second)))
+	suite.RelayOutboxRepository.AssertNotCalled(suite.T(), "MarkDelivered", mock.Anything, mock.Anything)
+	suite.RelayTargetService.AssertCalled(suite.T(), "RecordFailure", target, "got status 503")
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_ProcessOutbox_PrivateAddress() {
+	config.Get().Security.AllowPrivateNetworks = false
+	sut := NewRelayService(suite.RelayOutboxRepository, suite.UserService, suite.RelayTargetService)
+
+	target := &models.RelayTarget{ID: 7, UserID: TestUserId, ApiUrl: suite.StubServer.URL, ApiKey: "target-key", Enabled: true}
+	items := []*models.RelayOutboxItem{
+		{ID: 1, UserID: TestUserId, TargetID: 7, Payload: `{"entity":"a.go"}`},
+	}
+
+	suite.RelayOutboxRepository.On("GetDue", mock.Anything, relayOutboxFetchSize).Return(items, nil)
+	suite.RelayOutboxRepository.On("UpdateAttempts", items).Return(nil)
+	suite.RelayTargetService.On("GetById", uint(7)).Return(target, nil)
+	suite.RelayTargetService.On("RecordFailure", target, utils.ErrNonPublicAddress.Error()).Return(nil)
+
+	sut.ProcessOutbox()
+
+	assert.Empty(suite.T(), suite.StubRequests)
+	assert.Equal(suite.T(), utils.ErrNonPublicAddress.Error(), items[0].LastError)
+	suite.RelayOutboxRepository.AssertNotCalled(suite.T(), "MarkDelivered", mock.Anything, mock.Anything)
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_ProcessOutbox_DeletedTarget() {
+	sut := NewRelayService(suite.RelayOutboxRepository, suite.UserService, suite.RelayTargetService)
+
+	items := []*models.RelayOutboxItem{
+		{ID: 1, UserID: TestUserId, TargetID: 7, Payload: `{"entity":"a.go"}`},
+		{ID: 2, UserID: TestUserId, TargetID: 7, Payload: `{"entity":"b.go"}`},
+	}
+
+	suite.RelayOutboxRepository.On("GetDue", mock.Anything, relayOutboxFetchSize).Return(items, nil)
+	suite.RelayOutboxRepository.On("DeleteByIds", []uint64{1, 2}).Return(nil)
+	suite.RelayTargetService.On("GetById", uint(7)).Return(nil, errors.New("record not found"))
+
+	sut.ProcessOutbox()
+
+	assert.Empty(suite.T(), suite.StubRequests)
+	suite.RelayOutboxRepository.AssertCalled(suite.T(), "DeleteByIds", []uint64{1, 2})
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_BatchOutboxItems() {
+	items := make([]*models.RelayOutboxItem, 0)
+	for i := 0; i < 30; i++ {
+		items = append(items, &models.RelayOutboxItem{ID: uint64(i), UserID: TestUserId, MachineName: "laptop"})
+	}
+	items = append(items, &models.RelayOutboxItem{ID: 30, UserID: TestUserId, MachineName: "desktop"})
+
+	batches := batchOutboxItems(items, relayOutboxBatchSize)
+	assert.Len(suite.T(), batches, 3)
+	assert.Len(suite.T(), batches[0], 25)
+	assert.Len(suite.T(), batches[1], 5)
+	assert.Equal(suite.T(), uint64(25), batches[1][0].ID)
+	assert.Equal(suite.T(), "desktop", batches[2][0].MachineName)
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"github.com/duke-git/lancet/v2/datetime"
 	"github.com/duke-git/lancet/v2/slice"
 	"github.com/emvi/logbuch"
@@ -8,8 +10,12 @@ import (
 	"github.com/muety/artifex/v2"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
 	"math/rand"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -19,25 +25,45 @@ const reportDelay = 10 * time.Second
 // past time range to cover in the report
 const reportRange = 7 * 24 * time.Hour
 
+// interval at which report subscriptions are checked for due reports
+const reportSubscriptionInterval = 1 * time.Minute
+
+// minimum time between two manually requested deliveries of the same report subscription
+const reportSubscriptionManualInterval = 1 * time.Minute
+
+// maximum number of days a report document can span, as it includes a per-day breakdown
+const reportDocumentMaxDays = 366
+
+var reportSubscriptionLock = sync.Mutex{}
+
+var ErrReportRangeTooLong = fmt.Errorf("reports can span at most %d days", reportDocumentMaxDays)
+var ErrReportRateLimited = errors.New("a report for this subscription was requested just now, please try again in a minute")
+
 type ReportService struct {
 	config         *config.Config
 	eventBus       *hub.Hub
+	repository     repositories.IReportSubscriptionRepository
 	summaryService ISummaryService
 	userService    IUserService
 	mailService    IMailService
+	notifications  INotificationService
 	rand           *rand.Rand
+	manualRequests *cache.Cache // subscriptions, whose reports were requested manually recently
 	queueDefault   *artifex.Dispatcher
 	queueWorkers   *artifex.Dispatcher
 }
 
-func NewReportService(summaryService ISummaryService, userService IUserService, mailService IMailService) *ReportService {
+func NewReportService(reportSubscriptionRepository repositories.IReportSubscriptionRepository, summaryService ISummaryService, userService IUserService, mailService IMailService, notificationService INotificationService) *ReportService {
 	srv := &ReportService{
 		config:         config.Get(),
 		eventBus:       config.EventBus(),
+		repository:     reportSubscriptionRepository,
 		summaryService: summaryService,
 		userService:    userService,
 		mailService:    mailService,
+		notifications:  notificationService,
 		rand:           rand.New(rand.NewSource(time.Now().Unix())),
+		manualRequests: cache.New(reportSubscriptionManualInterval, 2*reportSubscriptionManualInterval),
 		queueDefault:   config.GetDefaultQueue(),
 		queueWorkers:   config.GetQueue(config.QueueReports),
 	}
@@ -49,21 +75,9 @@ func (srv *ReportService) Schedule() {
 	logbuch.Info("scheduling report generation")
 
 	scheduleUserReport := func(u *models.User) {
-		if err := srv.queueWorkers.Dispatch(func() {
-			t0 := time.Now()
-
-			if err := srv.SendReport(u, reportRange); err != nil {
-				config.Log().Error("failed to generate report for '%s', %v", u.ID, err)
-			}
-
-			// make the job take at least reportDelay seconds
-			if diff := reportDelay - time.Now().Sub(t0); diff > 0 {
-				logbuch.Debug("waiting for %v before sending next report", diff)
-				time.Sleep(diff)
-			}
-		}); err != nil {
-			config.Log().Error("failed to dispatch report generation job for user '%s', %v", u.ID, err)
-		}
+		srv.dispatchThrottled(u, func() error {
+			return srv.SendReport(u, reportRange)
+		})
 	}
 
 	_, err := srv.queueDefault.DispatchCron(func() {
@@ -74,9 +88,9 @@ func (srv *ReportService) Schedule() {
 			return
 		}
 
-		// filter users who have their email set
+		// filter users who have their email set or any chat channel to receive reports
 		users = slice.Filter[*models.User](users, func(i int, u *models.User) bool {
-			return u.Email != ""
+			return u.Email != "" || srv.notifications.HasChannels(u, models.NotificationKindReport)
 		})
 
 		// schedule jobs, throttled by one job per x seconds
@@ -89,11 +103,146 @@ func (srv *ReportService) Schedule() {
 	if err != nil {
 		config.Log().Error("failed to dispatch report generation jobs, %v", err)
 	}
+
+	if _, err := srv.queueDefault.DispatchEvery(srv.ProcessSubscriptions, reportSubscriptionInterval); err != nil {
+		config.Log().Error("failed to dispatch report subscription processing, %v", err)
+	}
+}
+
+// ProcessSubscriptions schedules the reports of all due subscriptions and advances them to their next delivery time.
+// Deliveries missed while the server was down are sent once, but not repeated for every missed period.
+func (srv *ReportService) ProcessSubscriptions() {
+	if ok := reportSubscriptionLock.TryLock(); !ok {
+		config.Log().Warn("couldn't acquire lock for processing report subscriptions, job is still pending")
+		return
+	}
+	defer reportSubscriptionLock.Unlock()
+
+	now := time.Now()
+	subscriptions, err := srv.repository.GetDue(now)
+	if err != nil {
+		config.Log().Error("failed to fetch due report subscriptions, %v", err)
+		return
+	}
+
+	for _, s := range subscriptions {
+		sub := s
+		scheduledAt := sub.NextRunAt.T()
+		sub.NextRunAt = models.CustomTime(sub.NextRun(now, sub.User.TZ()))
+		if _, err := srv.repository.Update(sub); err != nil {
+			config.Log().Error("failed to update report subscription %d, %v", sub.ID, err)
+			continue
+		}
+
+		srv.dispatchThrottled(sub.User, func() error {
+			if err := srv.sendSubscriptionReport(sub, scheduledAt); err != nil {
+				return err
+			}
+			sentAt := models.CustomTime(time.Now())
+			sub.LastSentAt = &sentAt
+			_, err := srv.repository.Update(sub)
+			return err
+		})
+	}
+}
+
+func (srv *ReportService) GetSubscriptionById(id uint) (*models.ReportSubscription, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *ReportService) GetSubscriptionsByUser(userId string) ([]*models.ReportSubscription, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+// CreateSubscription validates and stores the given subscription, which is expected to have its user set, and schedules its first delivery
+func (srv *ReportService) CreateSubscription(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	if !subscription.IsValid() || subscription.User == nil {
+		return nil, errors.New("invalid report subscription")
+	}
+	subscription.NextRunAt = models.CustomTime(subscription.NextRun(time.Now(), subscription.User.TZ()))
+	return srv.repository.Insert(subscription)
+}
+
+// UpdateSubscription stores the given subscription and re-schedules its next delivery, e.g. after it was re-enabled or the user's time zone changed
+func (srv *ReportService) UpdateSubscription(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	if !subscription.IsValid() || subscription.User == nil {
+		return nil, errors.New("invalid report subscription")
+	}
+	subscription.NextRunAt = models.CustomTime(subscription.NextRun(time.Now(), subscription.User.TZ()))
+	return srv.repository.Update(subscription)
+}
+
+func (srv *ReportService) DeleteSubscription(subscription *models.ReportSubscription) error {
+	return srv.repository.Delete(subscription.ID)
+}
+
+// SendSubscriptionReport immediately sends the report for the past period of the given subscription, regardless of its schedule
+func (srv *ReportService) SendSubscriptionReport(subscription *models.ReportSubscription) error {
+	return srv.sendSubscriptionReport(subscription, time.Now())
+}
+
+// QueueSubscriptionReport schedules an immediate delivery of the subscription's report, which is expected to have its user set,
+// on the (throttled) report queue. Manual deliveries of the same subscription are limited to one per minute.
+func (srv *ReportService) QueueSubscriptionReport(subscription *models.ReportSubscription) error {
+	if err := srv.manualRequests.Add(strconv.FormatUint(uint64(subscription.ID), 10), true, cache.DefaultExpiration); err != nil {
+		return ErrReportRateLimited
+	}
+	srv.dispatchThrottled(subscription.User, func() error {
+		return srv.SendSubscriptionReport(subscription)
+	})
+	return nil
+}
+
+// GenerateDocument builds a report for an arbitrary range and filters, e.g. to be exported as a document for invoicing.
+// The report includes all sections, a per-day breakdown and, optionally, a breakdown of every project by entity and branch.
+func (srv *ReportService) GenerateDocument(user *models.User, params *models.SummaryParams, withDetails bool) (*models.Report, error) {
+	if len(utils.SplitRangeByDays(params.From, params.To)) > reportDocumentMaxDays {
+		return nil, ErrReportRangeTooLong
+	}
+
+	filters := params.Filters
+	if filters != nil && filters.IsEmpty() {
+		filters = nil
+	}
+
+	report, err := srv.generateReport(user, params.From, params.To, filters, true)
+	if err != nil {
+		return nil, err
+	}
+	report.Filters = filters
+
+	if !withDetails {
+		return report, nil
+	}
+
+	for _, project := range report.Summary.Sorted().Projects {
+		// keep other filters, but narrow down to the single project
+		projectFilters := models.NewFiltersWith(models.SummaryProject, project.Key)
+		if filters != nil {
+			copied := *filters
+			copied.Project = models.OrFilter{project.Key}
+			projectFilters = &copied
+		}
+		summary, err := srv.summaryService.Aliased(params.From, params.To, user, srv.summaryService.Retrieve, projectFilters, false)
+		if err != nil {
+			config.Log().Error("failed to generate project summary of '%s' for report for '%s' - %v", project.Key, user.ID, err)
+			return nil, err
+		}
+		summary = summary.Sorted()
+		report.ProjectDetails = append(report.ProjectDetails, &models.ReportProjectDetail{
+			Project:  project.Key,
+			Total:    project.TotalFixed(),
+			Entities: summary.Entities,
+			Branches: summary.Branches,
+		})
+	}
+
+	return report, nil
 }
 
 func (srv *ReportService) SendReport(user *models.User, duration time.Duration) error {
-	if user.Email == "" {
-		logbuch.Warn("not generating report for '%s' as no e-mail address is set")
+	if user.Email == "" && !srv.notifications.HasChannels(user, models.NotificationKindReport) {
+		logbuch.Warn("not generating report for '%s' as neither e-mail address nor notification channels are set", user.ID)
 		return nil
 	}
 
@@ -102,11 +251,72 @@ func (srv *ReportService) SendReport(user *models.User, duration time.Duration)
 	end := time.Now().In(user.TZ())
 	start := time.Now().Add(-1 * duration)
 
-	fullSummary, err := srv.summaryService.Aliased(start, end, user, srv.summaryService.Retrieve, nil, false)
+	report, err := srv.generateReport(user, start, end, nil, true)
 	if err != nil {
-		config.Log().Error("failed to generate report for '%s' - %v", user.ID, err)
 		return err
 	}
+	report.Period = models.ReportPeriodWeekly
+
+	return srv.deliverReport(user, report)
+}
+
+func (srv *ReportService) sendSubscriptionReport(subscription *models.ReportSubscription, at time.Time) error {
+	user := subscription.User
+	if user.Email == "" && !srv.notifications.HasChannels(user, models.NotificationKindReport) {
+		logbuch.Warn("not generating %s report for '%s' as neither e-mail address nor notification channels are set", subscription.Period, user.ID)
+		return nil
+	}
+
+	logbuch.Info("generating %s report for '%s'", subscription.Period, user.ID)
+
+	var filters *models.Filters
+	if projects := subscription.ProjectList(); len(projects) > 0 {
+		filters = models.NewFilterWithMultiple(models.SummaryProject, projects)
+	}
+
+	from, to := subscription.Range(at, user.TZ())
+	withDays := subscription.Period != models.ReportPeriodDaily && subscription.Includes(models.ReportSectionDays)
+
+	report, err := srv.generateReport(user, from, to, filters, withDays)
+	if err != nil {
+		return err
+	}
+	report.To = to.Add(-1 * time.Second) // display last day of the period as its end
+	report.Period = subscription.Period
+	report.Sections = subscription.SectionList()
+	report.Projects = subscription.ProjectList()
+
+	if subscription.Includes(models.ReportSectionComparison) {
+		// range of a report delivered at the beginning of this one's period is the period right before
+		previousFrom, previousTo := subscription.Range(from, user.TZ())
+		previous, err := srv.summaryService.Aliased(previousFrom, previousTo, user, srv.summaryService.Retrieve, filters, false)
+		if err != nil {
+			config.Log().Error("failed to generate previous period summary for report for '%s' - %v", user.ID, err)
+			return err
+		}
+		report.Previous = previous
+	}
+
+	return srv.deliverReport(user, report)
+}
+
+func (srv *ReportService) generateReport(user *models.User, start, end time.Time, filters *models.Filters, withDays bool) (*models.Report, error) {
+	fullSummary, err := srv.summaryService.Aliased(start, end, user, srv.summaryService.Retrieve, filters, false)
+	if err != nil {
+		config.Log().Error("failed to generate report for '%s' - %v", user.ID, err)
+		return nil, err
+	}
+
+	report := &models.Report{
+		From:    start,
+		To:      end,
+		User:    user,
+		Summary: fullSummary,
+	}
+
+	if !withDays {
+		return report, nil
+	}
 
 	// generate per-day summaries
 	dayIntervals := utils.SplitRangeByDays(start, end)
@@ -114,7 +324,7 @@ func (srv *ReportService) SendReport(user *models.User, duration time.Duration)
 
 	for i, interval := range dayIntervals {
 		from, to := datetime.BeginOfDay(interval[0]), interval[1]
-		summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, nil, false)
+		summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, filters, false)
 		if err != nil {
 			config.Log().Error("failed to generate day summary (%v to %v) for report for '%s' - %v", from, to, user.ID, err)
 			break
@@ -124,19 +334,42 @@ func (srv *ReportService) SendReport(user *models.User, duration time.Duration)
 		dailySummaries[i] = summary
 	}
 
-	report := &models.Report{
-		From:           start,
-		To:             end,
-		User:           user,
-		Summary:        fullSummary,
-		DailySummaries: dailySummaries,
+	report.DailySummaries = dailySummaries
+	return report, nil
+}
+
+// deliverReport sends the report by mail, if the user has an e-mail address, and posts it to their notification channels
+func (srv *ReportService) deliverReport(user *models.User, report *models.Report) error {
+	if user.Email != "" {
+		if err := srv.mailService.SendReport(user, report); err != nil {
+			config.Log().Error("failed to send report for '%s', %v", user.ID, err)
+			return err
+		}
 	}
 
-	if err := srv.mailService.SendReport(user, report); err != nil {
-		config.Log().Error("failed to send report for '%s', %v", user.ID, err)
-		return err
+	if err := srv.notifications.SendReport(user, report); err != nil {
+		config.Log().Error("failed to post report for '%s' to notification channels, %v", user.ID, err)
 	}
 
 	logbuch.Info("sent report to user '%s'", user.ID)
 	return nil
 }
+
+// dispatchThrottled runs the given report job on the reports queue, making every job take at least reportDelay to throttle sending
+func (srv *ReportService) dispatchThrottled(u *models.User, job func() error) {
+	if err := srv.queueWorkers.Dispatch(func() {
+		t0 := time.Now()
+
+		if err := job(); err != nil {
+			config.Log().Error("failed to generate report for '%s', %v", u.ID, err)
+		}
+
+		// make the job take at least reportDelay seconds
+		if diff := reportDelay - time.Now().Sub(t0); diff > 0 {
+			logbuch.Debug("waiting for %v before sending next report", diff)
+			time.Sleep(diff)
+		}
+	}); err != nil {
+		config.Log().Error("failed to dispatch report generation job for user '%s', %v", u.ID, err)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReportServiceTestSuite struct {
+	suite.Suite
+	TestUser                     *models.User
+	ReportSubscriptionRepository *mocks.ReportSubscriptionRepositoryMock
+	SummaryService               *mocks.SummaryServiceMock
+	UserService                  *mocks.UserServiceMock
+	MailService                  *mocks.MailServiceMock
+	NotificationService          *mocks.NotificationServiceMock
+}
+
+func (suite *ReportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+
+	suite.TestUser = &models.User{ID: TestUserId, Email: "user@example.org", Location: "Europe/Berlin"}
+	suite.ReportSubscriptionRepository = new(mocks.ReportSubscriptionRepositoryMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.MailService = new(mocks.MailServiceMock)
+	suite.NotificationService = new(mocks.NotificationServiceMock)
+}
+
+func TestReportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ReportServiceTestSuite))
+}
+
+func (suite *ReportServiceTestSuite) TestReportService_SendSubscriptionReport() {
+	sut := NewReportService(suite.ReportSubscriptionRepository, suite.SummaryService, suite.UserService, suite.MailService, suite.NotificationService)
+
+	subscription := &models.ReportSubscription{
+		User:     suite.TestUser,
+		UserID:   suite.TestUser.ID,
+		Period:   models.ReportPeriodDaily,
+		Hour:     8,
+		Sections: "projects,comparison",
+		Projects: "wakapi,anchr",
+	}
+
+	tz := suite.TestUser.TZ()
+	today := time.Now().In(tz)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, tz)
+	from, previousFrom := to.AddDate(0, 0, -1), to.AddDate(0, 0, -2)
+	filters := models.NewFilterWithMultiple(models.SummaryProject, []string{"wakapi", "anchr"})
+
+	current := &models.Summary{Projects: models.SummaryItems{{Type: models.SummaryProject, Key: "wakapi", Total: 5400}}}
+	previous := &models.Summary{Projects: models.SummaryItems{{Type: models.SummaryProject, Key: "wakapi", Total: 3600}}}
+
+	suite.NotificationService.On("HasChannels", suite.TestUser, models.NotificationKindReport).Return(false)
+	suite.SummaryService.On("Aliased", from, to, suite.TestUser, mock.Anything, filters).Return(current, nil)
+	suite.SummaryService.On("Aliased", previousFrom, from, suite.TestUser, mock.Anything, filters).Return(previous, nil)
+	suite.MailService.On("SendReport", suite.TestUser, mock.Anything).Return(nil)
+	suite.NotificationService.On("SendReport", suite.TestUser, mock.Anything).Return(nil)
+
+	err := sut.SendSubscriptionReport(subscription)
+
+	assert.Nil(suite.T(), err)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 2) // no per-day summaries for daily reports
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendReport", 1)
+	suite.NotificationService.AssertNumberOfCalls(suite.T(), "SendReport", 1)
+
+	report := suite.MailService.Calls[0].Arguments.Get(1).(*models.Report)
+	assert.Equal(suite.T(), from, report.From)
+	assert.Equal(suite.T(), to.Add(-1*time.Second), report.To)
+	assert.Equal(suite.T(), []string{"wakapi", "anchr"}, report.Projects)
+	assert.Same(suite.T(), previous, report.Previous)
+	assert.Equal(suite.T(), 50, report.Change())
+	assert.True(suite.T(), report.Includes(models.ReportSectionProjects))
+	assert.False(suite.T(), report.Includes(models.ReportSectionLanguages))
+	assert.Empty(suite.T(), report.DailySummaries)
+}
+
+func (suite *ReportServiceTestSuite) TestReportService_SendSubscriptionReport_NoRecipient() {
+	sut := NewReportService(suite.ReportSubscriptionRepository, suite.SummaryService, suite.UserService, suite.MailService, suite.NotificationService)
+
+	suite.TestUser.Email = ""
+	subscription := &models.ReportSubscription{User: suite.TestUser, UserID: suite.TestUser.ID, Period: models.ReportPeriodWeekly, Sections: "projects"}
+
+	suite.NotificationService.On("HasChannels", suite.TestUser, models.NotificationKindReport).Return(false)
+
+	assert.Nil(suite.T(), sut.SendSubscriptionReport(subscription))
+	suite.SummaryService.AssertNotCalled(suite.T(), "Aliased", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.MailService.AssertNotCalled(suite.T(), "SendReport", mock.Anything, mock.Anything)
+}
+
+func (suite *ReportServiceTestSuite) TestReportService_QueueSubscriptionReport() {
+	sut := NewReportService(suite.ReportSubscriptionRepository, suite.SummaryService, suite.UserService, suite.MailService, suite.NotificationService)
+
+	suite.TestUser.Email = ""
+	subscription1 := &models.ReportSubscription{ID: 1, User: suite.TestUser, UserID: suite.TestUser.ID, Period: models.ReportPeriodWeekly, Sections: "projects"}
+	subscription2 := &models.ReportSubscription{ID: 2, User: suite.TestUser, UserID: suite.TestUser.ID, Period: models.ReportPeriodDaily, Sections: "projects"}
+
+	suite.NotificationService.On("HasChannels", suite.TestUser, models.NotificationKindReport).Return(false)
+
+	assert.Nil(suite.T(), sut.QueueSubscriptionReport(subscription1))
+	assert.ErrorIs(suite.T(), sut.QueueSubscriptionReport(subscription1), ErrReportRateLimited)
+	assert.Nil(suite.T(), sut.QueueSubscriptionReport(subscription2))
+}
+
+func (suite *ReportServiceTestSuite) TestReportService_CreateSubscription() {
+	sut := NewReportService(suite.ReportSubscriptionRepository, suite.SummaryService, suite.UserService, suite.MailService, suite.NotificationService)
+
+	suite.ReportSubscriptionRepository.On("Insert", mock.Anything).Return(&models.ReportSubscription{}, nil)
+
+	subscription := &models.ReportSubscription{User: suite.TestUser, UserID: suite.TestUser.ID, Period: models.ReportPeriodMonthly, Hour: 9, Sections: "projects"}
+	_, err := sut.CreateSubscription(subscription)
+
+	assert.Nil(suite.T(), err)
+	nextRun := subscription.NextRunAt.T().In(suite.TestUser.TZ())
+	assert.True(suite.T(), nextRun.After(time.Now()))
+	assert.Equal(suite.T(), 1, nextRun.Day())
+	assert.Equal(suite.T(), 9, nextRun.Hour())
+
+	_, err = sut.CreateSubscription(&models.ReportSubscription{User: suite.TestUser, UserID: suite.TestUser.ID, Period: "yearly", Sections: "projects"})
+	assert.Error(suite.T(), err)
+	suite.ReportSubscriptionRepository.AssertNumberOfCalls(suite.T(), "Insert", 1)
+}
+
+func (suite *ReportServiceTestSuite) TestReportService_GenerateDocument() {
+	sut := NewReportService(suite.ReportSubscriptionRepository, suite.SummaryService, suite.UserService, suite.MailService, suite.NotificationService)
+
+	from := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+	filters := models.NewFiltersWith(models.SummaryLanguage, "Go")
+
+	summary := &models.Summary{Projects: models.SummaryItems{
+		{Type: models.SummaryProject, Key: "anchr", Total: 1800},
+		{Type: models.SummaryProject, Key: "wakapi", Total: 5400},
+	}}
+	projectSummary := &models.Summary{Entities: models.SummaryItems{{Type: models.SummaryEntity, Key: "main.go", Total: 5400}}}
+
+	isProjectFilter := func(project string) interface{} {
+		return mock.MatchedBy(func(f *models.Filters) bool {
+			return f != nil && f.Project.Exists() && f.Project[0] == project && f.Language[0] == "Go"
+		})
+	}
+	suite.SummaryService.On("Aliased", from, to, suite.TestUser, mock.Anything, filters).Return(summary, nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, filters).Return(&models.Summary{}, nil)
+	suite.SummaryService.On("Aliased", from, to, suite.TestUser, mock.Anything, isProjectFilter("wakapi")).Return(projectSummary, nil)
+	suite.SummaryService.On("Aliased", from, to, suite.TestUser, mock.Anything, isProjectFilter("anchr")).Return(&models.Summary{}, nil)
+
+	report, err := sut.GenerateDocument(suite.TestUser, &models.SummaryParams{From: from, To: to, Filters: filters}, true)
+
+	assert.Nil(suite.T(), err)
+	assert.Same(suite.T(), filters, report.Filters)
+	assert.Len(suite.T(), report.DailySummaries, 2)
+	assert.Len(suite.T(), report.ProjectDetails, 2)
+	assert.Equal(suite.T(), "wakapi", report.ProjectDetails[0].Project)
+	assert.Equal(suite.T(), 90*time.Minute, report.ProjectDetails[0].Total)
+	assert.Same(suite.T(), projectSummary.Entities[0], report.ProjectDetails[0].Entities[0])
+
+	_, err = sut.GenerateDocument(suite.TestUser, &models.SummaryParams{From: from.AddDate(-2, 0, 0), To: to}, false)
+	assert.ErrorIs(suite.T(), err, ErrReportRangeTooLong)
+}
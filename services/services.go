@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/models/types"
+	"github.com/muety/wakapi/services/imports"
 	"github.com/muety/wakapi/utils"
+	"io"
 	"time"
 )
 
@@ -35,10 +38,14 @@ type IHeartbeatService interface {
 	InsertBatch([]*models.Heartbeat) error
 	Count(bool) (int64, error)
 	CountByUser(*models.User) (int64, error)
+	CountByUserUncached(*models.User) (int64, error)
 	CountByUsers([]*models.User) ([]*models.CountByUser, error)
 	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Heartbeat, error)
 	GetAllWithinByFilters(time.Time, time.Time, *models.User, *models.Filters) ([]*models.Heartbeat, error)
+	StreamAllByUser(*models.User, int, func([]*models.Heartbeat) error) error
+	GetPageByUser(*models.User, uint64, time.Time, time.Time, int) ([]*models.Heartbeat, error)
 	GetFirstByUsers() ([]*models.TimeByUser, error)
+	GetLatestTimesByUsers([]*models.User) ([]*models.TimeByUser, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
 	GetLatestByFilters(*models.User, *models.Filters) (*models.Heartbeat, error)
@@ -51,22 +58,85 @@ type IHeartbeatService interface {
 
 type IDiagnosticsService interface {
 	Create(*models.Diagnostics) (*models.Diagnostics, error)
+	GetByUser(string) ([]*models.Diagnostics, error)
+}
+
+type IDataExportService interface {
+	Schedule()
+	RequestExport(*models.User) error
+	Export(*models.User, io.Writer) error
+	ResolveDownload(string) (*models.User, string, error)
+	DeleteExpired()
 }
 
 type IKeyValueService interface {
 	GetString(string) (*models.KeyStringValue, error)
 	MustGetString(string) *models.KeyStringValue
+	GetStrings([]string) ([]*models.KeyStringValue, error)
 	GetByPrefix(string) ([]*models.KeyStringValue, error)
 	PutString(*models.KeyStringValue) error
 	DeleteString(string) error
 }
 
+type IOidcService interface {
+	AuthCodeURL(string, string) (string, error)
+	Exchange(context.Context, string, string) (*models.OidcIdentity, error)
+	Login(*models.OidcIdentity) (*models.User, error)
+}
+
+type IAdminService interface {
+	SearchUsers(string, *utils.PageParams) ([]*models.AdminUserInfo, error)
+	GetUser(string) (*models.AdminUserInfo, error)
+	SetDisabled(*models.User, *models.User, bool) (*models.User, error)
+	SetAdmin(*models.User, *models.User, bool) (*models.User, error)
+	TriggerPasswordReset(*models.User) (string, error)
+	ResetApiKey(*models.User) (*models.User, error)
+	DeleteUser(*models.User, *models.User) error
+}
+
+type ITeamService interface {
+	GetById(uint) (*models.Team, error)
+	GetMemberships(*models.User) ([]*models.TeamMember, error)
+	GetMembership(*models.Team, *models.User) (*models.TeamMember, error)
+	GetMembers(*models.Team) ([]*models.TeamMember, error)
+	Create(*models.User, string) (*models.Team, error)
+	Rename(*models.User, *models.Team, string) (*models.Team, error)
+	Delete(*models.User, *models.Team) error
+	Invite(*models.User, *models.Team, string) (*models.TeamMember, error)
+	Accept(*models.User, *models.Team) (*models.TeamMember, error)
+	Leave(*models.User, *models.Team) error
+	RemoveMember(*models.User, *models.Team, string) error
+	SetRole(*models.User, *models.Team, string, string) (*models.TeamMember, error)
+	Summary(*models.User, *models.Team, time.Time, time.Time, *models.Filters) (*models.TeamSummary, error)
+}
+
+type ISessionService interface {
+	Schedule()
+	Create(*models.User, string, string) (string, *models.Session, error)
+	GetByToken(string) (*models.Session, error)
+	GetByUser(*models.User) ([]*models.Session, error)
+	Delete(*models.User, string) error
+	DeleteByToken(string) error
+	DeleteByUser(*models.User, ...string) error
+}
+
+type ITotpService interface {
+	Setup(*models.User) (string, string, error)
+	Enable(*models.User, string) ([]string, error)
+	Disable(*models.User) error
+	Verify(*models.User, string) (bool, error)
+}
+
 type ILanguageMappingService interface {
 	GetById(uint) (*models.LanguageMapping, error)
 	GetByUser(string) ([]*models.LanguageMapping, error)
-	ResolveByUser(string) (map[string]string, error)
+	ResolveByUser(string) ([]*models.LanguageMapping, error)
 	Create(*models.LanguageMapping) (*models.LanguageMapping, error)
 	Delete(mapping *models.LanguageMapping) error
+	GetDefaults() ([]*models.DefaultLanguageMapping, error)
+	ResolveDefaults() ([]*models.LanguageMapping, error)
+	CreateDefault(*models.DefaultLanguageMapping) (*models.DefaultLanguageMapping, error)
+	DeleteDefault(uint) error
 }
 
 type IProjectLabelService interface {
@@ -74,16 +144,98 @@ type IProjectLabelService interface {
 	GetByUser(string) ([]*models.ProjectLabel, error)
 	GetByUserGrouped(string) (map[string][]*models.ProjectLabel, error)
 	GetByUserGroupedInverted(string) (map[string][]*models.ProjectLabel, error)
+	GetColorsByUser(string) (map[string]string, error)
 	Create(*models.ProjectLabel) (*models.ProjectLabel, error)
 	Delete(*models.ProjectLabel) error
+	SetColor(*models.LabelColor) (*models.LabelColor, error)
+	DeleteColor(string, string) error
 }
 
 type IMailService interface {
 	SendPasswordReset(*models.User, string) error
 	SendWakatimeFailureNotification(*models.User, int) error
 	SendImportNotification(*models.User, time.Duration, int) error
+	SendImportFailureNotification(*models.User, int, []string) error
 	SendReport(*models.User, *models.Report) error
 	SendSubscriptionNotification(*models.User, bool) error
+	SendDataExport(*models.User, string, time.Time) error
+}
+
+type INotificationService interface {
+	GetById(uint) (*models.NotificationChannel, error)
+	GetByUser(string) ([]*models.NotificationChannel, error)
+	Create(*models.NotificationChannel) (*models.NotificationChannel, error)
+	Update(*models.NotificationChannel) (*models.NotificationChannel, error)
+	Delete(*models.NotificationChannel) error
+	HasChannels(*models.User, string) bool
+	SendTest(*models.NotificationChannel) error
+	SendWakatimeFailureNotification(*models.User, int) error
+	SendRelayTargetFailureNotification(*models.User, *models.RelayTarget) error
+	SendImportNotification(*models.User, time.Duration, int) error
+	SendImportFailureNotification(*models.User, int, []string) error
+	SendReport(*models.User, *models.Report) error
+	SendSubscriptionNotification(*models.User, bool) error
+}
+
+type IRelayTargetService interface {
+	GetById(uint) (*models.RelayTarget, error)
+	GetByUser(string) ([]*models.RelayTarget, error)
+	GetByUsers([]*models.User) ([]*models.RelayTarget, error)
+	GetEnabledByUser(string) ([]*models.RelayTarget, error)
+	Create(*models.RelayTarget) (*models.RelayTarget, error)
+	Update(*models.RelayTarget) (*models.RelayTarget, error)
+	Delete(*models.RelayTarget) error
+	RecordSuccess(*models.RelayTarget) error
+	RecordFailure(*models.RelayTarget, string) error
+}
+
+type IRelayService interface {
+	Schedule()
+	Enqueue([]*models.RelayOutboxItem) error
+	GetBacklog(*models.User) (*models.RelayBacklog, error)
+	CountBacklogByUsers([]*models.User) ([]*models.CountByUser, error)
+	ProcessOutbox()
+}
+
+type IGoalService interface {
+	Schedule()
+	CheckGoals()
+}
+
+type IWebhookService interface {
+	Schedule()
+	GetById(uint) (*models.Webhook, error)
+	GetByUser(string) ([]*models.Webhook, error)
+	GetDeliveries(*models.Webhook) ([]*models.WebhookDelivery, error)
+	Create(*models.Webhook) (*models.Webhook, error)
+	Update(*models.Webhook) (*models.Webhook, error)
+	Delete(*models.Webhook) error
+	Emit(string, string, interface{}) error
+	ProcessDeliveries()
+}
+
+type IImportService interface {
+	CheckRateLimit(*models.User) error
+	GetJobsByUser(*models.User) ([]*models.ImportJob, error)
+	GetLatestJobsByUsers([]*models.User) ([]*models.ImportJob, error)
+	GetRunningJobs(string) ([]*models.ImportJob, error)
+	CreateJob(*models.ImportJob) (*models.ImportJob, error)
+	UpdateJob(*models.ImportJob) (*models.ImportJob, error)
+	FailJob(*models.ImportJob, error)
+	Submit(*models.ImportJob) error
+	RunJob(*models.ImportJob, imports.DataImporter, func(*models.Heartbeat)) (*models.ImportResult, error)
+	ResumeInterrupted()
+}
+
+type IExportService interface {
+	Export(string, *models.User, *models.ExportParams, func([]interface{}) error) error
+	GetWorkSessions(*models.User, *models.ExportParams) (models.WorkSessions, error)
+}
+
+type IWakapiMigrationService interface {
+	MigrateAsync(*models.User, string, string) error
+	ResumeInterrupted()
+	Migrate(*models.User, string, string) (*models.WakapiMigrationResult, error)
 }
 
 type IDurationService interface {
@@ -94,6 +246,7 @@ type ISummaryService interface {
 	Aliased(time.Time, time.Time, *models.User, types.SummaryRetriever, *models.Filters, bool) (*models.Summary, error)
 	Retrieve(time.Time, time.Time, *models.User, *models.Filters) (*models.Summary, error)
 	Summarize(time.Time, time.Time, *models.User, *models.Filters) (*models.Summary, error)
+	GetPersisted(time.Time, time.Time, *models.User) ([]*models.Summary, error)
 	GetLatestByUser() ([]*models.TimeByUser, error)
 	DeleteByUser(string) error
 	DeleteByUserBefore(string, time.Time) error
@@ -107,6 +260,27 @@ type IActivityService interface {
 type IReportService interface {
 	Schedule()
 	SendReport(*models.User, time.Duration) error
+	GetSubscriptionById(uint) (*models.ReportSubscription, error)
+	GetSubscriptionsByUser(string) ([]*models.ReportSubscription, error)
+	CreateSubscription(*models.ReportSubscription) (*models.ReportSubscription, error)
+	UpdateSubscription(*models.ReportSubscription) (*models.ReportSubscription, error)
+	DeleteSubscription(*models.ReportSubscription) error
+	SendSubscriptionReport(*models.ReportSubscription) error
+	QueueSubscriptionReport(*models.ReportSubscription) error
+	GenerateDocument(*models.User, *models.SummaryParams, bool) (*models.Report, error)
+}
+
+type IReportRenderer interface {
+	RenderHtml(io.Writer, *models.Report) error
+	RenderPdf(io.Writer, *models.Report) error
+}
+
+type IBillingService interface {
+	GetRateById(uint) (*models.BillingRate, error)
+	GetRatesByUser(string) (models.BillingRates, error)
+	CreateRate(*models.BillingRate) (*models.BillingRate, error)
+	DeleteRate(*models.BillingRate) error
+	Compute(*models.User, time.Time, time.Time, *models.Filters) (*models.BillingReport, error)
 }
 
 type IHousekeepingService interface {
@@ -118,21 +292,45 @@ type ILeaderboardService interface {
 	GetDefaultScope() *models.IntervalKey
 	Schedule()
 	ComputeLeaderboard([]*models.User, *models.IntervalKey, []uint8) error
+	ComputePrivateLeaderboard(*models.PrivateLeaderboard, []*models.User) error
 	ExistsAnyByUser(string) (bool, error)
+	GetAllByUser(string) ([]*models.LeaderboardItem, error)
 	CountUsers(bool) (int64, error)
 	GetByInterval(*models.IntervalKey, *utils.PageParams, bool) (models.Leaderboard, error)
 	GetByIntervalAndUser(*models.IntervalKey, string, bool) (models.Leaderboard, error)
 	GetAggregatedByInterval(*models.IntervalKey, *uint8, *utils.PageParams, bool) (models.Leaderboard, error)
 	GetAggregatedByIntervalAndUser(*models.IntervalKey, string, *uint8, bool) (models.Leaderboard, error)
+	GetPrivateLeaderboard(*models.PrivateLeaderboard, *uint8, bool) (models.Leaderboard, error)
 	GenerateByUser(*models.User, *models.IntervalKey) (*models.LeaderboardItem, error)
 	GenerateAggregatedByUser(*models.User, *models.IntervalKey, uint8) ([]*models.LeaderboardItem, error)
 }
 
+type IPrivateLeaderboardService interface {
+	Schedule()
+	ComputeAll()
+	Compute(*models.PrivateLeaderboard) error
+	GetById(uint) (*models.PrivateLeaderboard, error)
+	GetByUser(*models.User) ([]*models.PrivateLeaderboard, error)
+	GetMembers(*models.PrivateLeaderboard) ([]*models.PrivateLeaderboardMember, error)
+	IsMember(*models.PrivateLeaderboard, *models.User) bool
+	GetLeaderboard(*models.User, *models.PrivateLeaderboard, *uint8) (models.Leaderboard, error)
+	Create(*models.User, string, string, string) (*models.PrivateLeaderboard, error)
+	Update(*models.User, *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error)
+	ResetInviteCode(*models.User, *models.PrivateLeaderboard) (*models.PrivateLeaderboard, error)
+	Join(*models.User, string) (*models.PrivateLeaderboard, error)
+	Leave(*models.User, *models.PrivateLeaderboard) error
+	RemoveMember(*models.User, *models.PrivateLeaderboard, string) error
+	Delete(*models.User, *models.PrivateLeaderboard) error
+}
+
 type IUserService interface {
 	GetUserById(string) (*models.User, error)
 	GetUserByKey(string) (*models.User, error)
+	GetUserBySession(string) (*models.User, error)
 	GetUserByEmail(string) (*models.User, error)
+	GetUserByOidcSubject(string) (*models.User, error)
 	GetUserByResetToken(string) (*models.User, error)
+	GetUserByCalendarToken(string) (*models.User, error)
 	GetUserByStripeCustomerId(string) (*models.User, error)
 	GetAll() ([]*models.User, error)
 	GetAllMapped() (map[string]*models.User, error)
@@ -141,11 +339,14 @@ type IUserService interface {
 	GetAllByReports(bool) ([]*models.User, error)
 	GetAllByLeaderboard(bool) ([]*models.User, error)
 	GetActive(bool) ([]*models.User, error)
+	Search(string, *utils.PageParams) ([]*models.User, error)
 	Count() (int64, error)
 	CreateOrGet(*models.Signup, bool) (*models.User, bool, error)
 	Update(*models.User) (*models.User, error)
+	SetAdmin(*models.User, bool) (*models.User, error)
 	Delete(*models.User) error
 	ResetApiKey(*models.User) (*models.User, error)
+	ResetCalendarToken(*models.User) (*models.User, error)
 	SetWakatimeApiCredentials(*models.User, string, string) (*models.User, error)
 	GenerateResetToken(*models.User) (*models.User, error)
 	FlushCache()
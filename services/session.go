@@ -0,0 +1,170 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+	"time"
+)
+
+const (
+	// last seen timestamps are only persisted at this granularity to avoid a database write on every single request
+	sessionTouchInterval = 1 * time.Minute
+	cleanSessionsEvery   = 6 * time.Hour
+)
+
+var ErrSessionExpired = errors.New("session expired")
+
+type SessionService struct {
+	config       *config.Config
+	cache        *cache.Cache
+	repository   repositories.ISessionRepository
+	queueDefault *artifex.Dispatcher
+}
+
+func NewSessionService(sessionRepo repositories.ISessionRepository) *SessionService {
+	return &SessionService{
+		config:       config.Get(),
+		cache:        cache.New(10*time.Minute, 20*time.Minute),
+		repository:   sessionRepo,
+		queueDefault: config.GetDefaultQueue(),
+	}
+}
+
+func (srv *SessionService) Schedule() {
+	logbuch.Info("scheduling expired sessions cleanup")
+	if _, err := srv.queueDefault.DispatchEvery(srv.runCleanExpired, cleanSessionsEvery); err != nil {
+		config.Log().Error("failed to schedule expired sessions cleanup, %v", err)
+	}
+}
+
+// Create starts a new session for the given user and returns the (secret) token to be handed out to the client
+func (srv *SessionService) Create(user *models.User, userAgent, ip string) (string, *models.Session, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(b)
+
+	now := models.CustomTime(time.Now())
+	session, err := srv.repository.Insert(&models.Session{
+		ID:         models.HashSessionToken(token),
+		UserID:     user.ID,
+		UserAgent:  truncateString(userAgent, 255),
+		Ip:         truncateString(ip, 64),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, session, nil
+}
+
+// GetByToken resolves a valid session from its token and updates its last seen timestamp
+func (srv *SessionService) GetByToken(token string) (*models.Session, error) {
+	if token == "" {
+		return nil, errors.New("token must not be empty")
+	}
+
+	id := models.HashSessionToken(token)
+
+	var session *models.Session
+	if s, ok := srv.cache.Get(id); ok {
+		session = s.(*models.Session)
+	} else {
+		s, err := srv.repository.GetById(id)
+		if err != nil {
+			return nil, err
+		}
+		session = s
+		srv.cache.SetDefault(id, session)
+	}
+
+	if session.IsExpired(srv.maxAge()) {
+		srv.cache.Delete(id)
+		return nil, ErrSessionExpired
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt.T()) > sessionTouchInterval {
+		if err := srv.repository.Touch(session, now); err != nil {
+			config.Log().Error("failed to update last seen timestamp of session for user '%s', %v", session.UserID, err)
+		} else {
+			touched := *session
+			touched.LastSeenAt = models.CustomTime(now)
+			srv.cache.SetDefault(id, &touched)
+		}
+	}
+
+	return session, nil
+}
+
+func (srv *SessionService) GetByUser(user *models.User) ([]*models.Session, error) {
+	sessions, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if !s.IsExpired(srv.maxAge()) {
+			results = append(results, s)
+		}
+	}
+	return results, nil
+}
+
+// Delete revokes the session of the given id, if it belongs to the given user
+func (srv *SessionService) Delete(user *models.User, id string) error {
+	session, err := srv.repository.GetById(id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != user.ID {
+		return errors.New("not allowed to delete session")
+	}
+	srv.cache.Delete(id)
+	return srv.repository.Delete(id)
+}
+
+func (srv *SessionService) DeleteByToken(token string) error {
+	id := models.HashSessionToken(token)
+	srv.cache.Delete(id)
+	return srv.repository.Delete(id)
+}
+
+// DeleteByUser revokes all sessions of the given user, except for the explicitly excluded ones
+func (srv *SessionService) DeleteByUser(user *models.User, exceptIds ...string) error {
+	sessions, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		srv.cache.Delete(s.ID)
+	}
+	return srv.repository.DeleteByUser(user.ID, exceptIds...)
+}
+
+func (srv *SessionService) runCleanExpired() {
+	if err := srv.repository.DeleteBefore(time.Now().Add(-srv.maxAge())); err != nil {
+		config.Log().Error("failed to clean up expired sessions, %v", err)
+	}
+}
+
+func (srv *SessionService) maxAge() time.Duration {
+	return time.Duration(srv.config.Security.CookieMaxAgeSec) * time.Second
+}
+
+func truncateString(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
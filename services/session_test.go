@@ -0,0 +1,107 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type SessionServiceTestSuite struct {
+	suite.Suite
+	TestUser          *models.User
+	SessionRepository *mocks.SessionRepositoryMock
+}
+
+func (suite *SessionServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: TestUserId}
+}
+
+func (suite *SessionServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Security.CookieMaxAgeSec = 3600
+	config.Set(cfg)
+
+	suite.SessionRepository = new(mocks.SessionRepositoryMock)
+	suite.SessionRepository.On("Insert", mock.Anything).Return(nil, nil)
+}
+
+func TestSessionServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionServiceTestSuite))
+}
+
+func (suite *SessionServiceTestSuite) TestSessionService_Create() {
+	sut := NewSessionService(suite.SessionRepository)
+
+	token, session, err := sut.Create(suite.TestUser, "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", "127.0.0.1")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), token, 64)
+	assert.Equal(suite.T(), models.HashSessionToken(token), session.ID)
+	assert.NotEqual(suite.T(), token, session.ID)
+	assert.Equal(suite.T(), TestUserId, session.UserID)
+	assert.Equal(suite.T(), "Firefox on Linux", session.Device())
+}
+
+func (suite *SessionServiceTestSuite) TestSessionService_GetByToken() {
+	sut := NewSessionService(suite.SessionRepository)
+
+	validToken, expiredToken, staleToken := "valid", "expired", "stale"
+	now := time.Now()
+
+	suite.SessionRepository.On("GetById", models.HashSessionToken(validToken)).Return(&models.Session{
+		ID:         models.HashSessionToken(validToken),
+		UserID:     TestUserId,
+		CreatedAt:  models.CustomTime(now.Add(-10 * time.Minute)),
+		LastSeenAt: models.CustomTime(now),
+	}, nil)
+	suite.SessionRepository.On("GetById", models.HashSessionToken(expiredToken)).Return(&models.Session{
+		ID:         models.HashSessionToken(expiredToken),
+		UserID:     TestUserId,
+		CreatedAt:  models.CustomTime(now.Add(-2 * time.Hour)),
+		LastSeenAt: models.CustomTime(now.Add(-2 * time.Hour)),
+	}, nil)
+	suite.SessionRepository.On("GetById", models.HashSessionToken(staleToken)).Return(&models.Session{
+		ID:         models.HashSessionToken(staleToken),
+		UserID:     TestUserId,
+		CreatedAt:  models.CustomTime(now.Add(-30 * time.Minute)),
+		LastSeenAt: models.CustomTime(now.Add(-20 * time.Minute)),
+	}, nil)
+	suite.SessionRepository.On("GetById", mock.Anything).Return(nil, assert.AnError)
+	suite.SessionRepository.On("Touch", mock.Anything, mock.Anything).Return(nil)
+
+	result, err := sut.GetByToken(validToken)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), TestUserId, result.UserID)
+	suite.SessionRepository.AssertNotCalled(suite.T(), "Touch", mock.Anything, mock.Anything)
+
+	_, err = sut.GetByToken(expiredToken)
+	assert.ErrorIs(suite.T(), err, ErrSessionExpired)
+
+	_, err = sut.GetByToken("unknown")
+	assert.NotNil(suite.T(), err)
+
+	_, err = sut.GetByToken(staleToken)
+	assert.Nil(suite.T(), err)
+	suite.SessionRepository.AssertNumberOfCalls(suite.T(), "Touch", 1)
+
+	// last seen timestamp is updated in cache, too
+	_, err = sut.GetByToken(staleToken)
+	assert.Nil(suite.T(), err)
+	suite.SessionRepository.AssertNumberOfCalls(suite.T(), "Touch", 1)
+}
+
+func (suite *SessionServiceTestSuite) TestSessionService_Delete() {
+	sut := NewSessionService(suite.SessionRepository)
+
+	suite.SessionRepository.On("GetById", "own").Return(&models.Session{ID: "own", UserID: TestUserId}, nil)
+	suite.SessionRepository.On("GetById", "foreign").Return(&models.Session{ID: "foreign", UserID: "janedoe"}, nil)
+	suite.SessionRepository.On("Delete", "own").Return(nil)
+
+	assert.Nil(suite.T(), sut.Delete(suite.TestUser, "own"))
+	assert.NotNil(suite.T(), sut.Delete(suite.TestUser, "foreign"))
+	suite.SessionRepository.AssertNotCalled(suite.T(), "Delete", "foreign")
+}
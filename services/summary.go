@@ -63,8 +63,11 @@ func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f type
 	resolveAliasesReverse := srv.getAliasReverseResolver(user)
 	resolveProjectLabelsReverse := srv.getProjectLabelsReverseResolver(user)
 
+	var labelDepth int
+
 	// Post-process filters
 	if filters != nil {
+		labelDepth = filters.LabelDepth
 		filters = filters.WithAliases(resolveAliasesReverse)
 		filters = filters.WithProjectLabels(resolveProjectLabelsReverse)
 	}
@@ -82,9 +85,10 @@ func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f type
 
 	// Post-process summary and cache it
 	summary := s.WithResolvedAliases(resolveAliases)
-	summary = srv.withProjectLabels(summary)
-	summary.FillBy(models.SummaryProject, models.SummaryLabel) // first fill up labels from projects
-	summary.FillMissing()                                      // then, full up types which are entirely missing
+	summary = srv.withProjectLabels(summary, labelDepth)
+	summary.FillBy(models.SummaryProject, models.SummaryLabel)  // first fill up labels from projects
+	summary.FillBy(models.SummaryProject, models.SummaryTicket) // time without ticket, incl. summaries from before a pattern was set, counts as unknown
+	summary.FillMissing()                                       // then, full up types which are entirely missing
 
 	if withDetails := filters != nil && filters.IsProjectDetails(); !withDetails {
 		summary.Branches = nil
@@ -154,6 +158,9 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 		types = append(types, models.SummaryBranch)
 		types = append(types, models.SummaryEntity)
 	}
+	if user.TicketPattern != "" {
+		types = append(types, models.SummaryTicket)
+	}
 
 	typedAggregations := make(chan models.SummaryItemContainer)
 	defer close(typedAggregations)
@@ -169,6 +176,7 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 	var machineItems []*models.SummaryItem
 	var branchItems []*models.SummaryItem
 	var entityItems []*models.SummaryItem
+	var ticketItems []*models.SummaryItem
 
 	for i := 0; i < len(types); i++ {
 		item := <-typedAggregations
@@ -187,6 +195,8 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 			branchItems = item.Items
 		case models.SummaryEntity:
 			entityItems = item.Items
+		case models.SummaryTicket:
+			ticketItems = item.Items
 		}
 	}
 
@@ -206,6 +216,7 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 		Machines:         machineItems,
 		Branches:         branchItems,
 		Entities:         entityItems,
+		Tickets:          ticketItems,
 		NumHeartbeats:    durations.TotalNumHeartbeats(),
 	}
 
@@ -228,6 +239,11 @@ func (srv *SummaryService) DeleteByUserBefore(userId string, t time.Time) error
 	return srv.repository.DeleteByUserBefore(userId, t)
 }
 
+// GetPersisted returns the raw, pre-computed summaries of a user within the given interval, without aliases being resolved
+func (srv *SummaryService) GetPersisted(from, to time.Time, user *models.User) ([]*models.Summary, error) {
+	return srv.repository.GetByUserWithin(user, from, to)
+}
+
 func (srv *SummaryService) Insert(summary *models.Summary) error {
 	srv.invalidateUserCache(summary.UserID)
 	return srv.repository.Insert(summary)
@@ -258,7 +274,9 @@ func (srv *SummaryService) aggregateBy(durations []*models.Duration, summaryType
 	c <- models.SummaryItemContainer{Type: summaryType, Items: items}
 }
 
-func (srv *SummaryService) withProjectLabels(summary *models.Summary) *models.Summary {
+// withProjectLabels computes label totals from the summary's projects. Hierarchical labels (e.g. "client/acme/backend") are truncated to the given depth
+// (e.g. to "client" for depth 1), whereby every project only counts once towards each resulting label.
+func (srv *SummaryService) withProjectLabels(summary *models.Summary, depth int) *models.Summary {
 	newEntry := func(key string, total time.Duration) *models.SummaryItem {
 		return &models.SummaryItem{
 			Type:  models.SummaryLabel,
@@ -278,18 +296,21 @@ func (srv *SummaryService) withProjectLabels(summary *models.Summary) *models.Su
 		mappedProjects[p.Key] = p
 	}
 
-	var totalLabelTime time.Duration
 	labelMap := make(map[string]*models.SummaryItem, 0)
+	countedLabels := make(map[string]bool) // project key + label key
 	for _, l := range allLabels {
 		if p, ok := mappedProjects[l.ProjectKey]; ok {
-			if _, ok2 := labelMap[l.Label]; !ok2 {
-				labelMap[l.Label] = newEntry(l.Label, 0)
+			key := models.TruncateLabel(l.Label, depth)
+			if _, counted := countedLabels[p.Key+"__"+key]; counted {
+				continue
+			}
+			if _, ok2 := labelMap[key]; !ok2 {
+				labelMap[key] = newEntry(key, 0)
 			}
-			labelMap[l.Label].Total += p.Total
-			totalLabelTime += p.Total
+			labelMap[key].Total += p.Total
+			countedLabels[p.Key+"__"+key] = true
 		}
 	}
-	//labelMap[models.DefaultProjectLabel] = newEntry(models.DefaultProjectLabel, summary.TotalTimeBy(models.SummaryProject) / time.Second-totalLabelTime)
 
 	labels := make([]*models.SummaryItem, 0, len(labelMap))
 	for _, v := range labelMap {
@@ -323,6 +344,7 @@ func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.
 		Labels:           make([]*models.SummaryItem, 0),
 		Branches:         make([]*models.SummaryItem, 0),
 		Entities:         make([]*models.SummaryItem, 0),
+		Tickets:          make([]*models.SummaryItem, 0),
 	}
 
 	var processed = map[time.Time]bool{}
@@ -360,6 +382,7 @@ func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.
 		finalSummary.Labels = srv.mergeSummaryItems(finalSummary.Labels, s.Labels)
 		finalSummary.Branches = srv.mergeSummaryItems(finalSummary.Branches, s.Branches)
 		finalSummary.Entities = srv.mergeSummaryItems(finalSummary.Entities, s.Entities)
+		finalSummary.Tickets = srv.mergeSummaryItems(finalSummary.Tickets, s.Tickets)
 		finalSummary.NumHeartbeats += s.NumHeartbeats
 
 		processed[hash] = true
@@ -486,17 +509,24 @@ func (srv *SummaryService) getAliasReverseResolver(user *models.User) models.Ali
 }
 
 func (srv *SummaryService) getProjectLabelsReverseResolver(user *models.User) models.ProjectLabelReverseResolver {
+	return newProjectLabelsReverseResolver(srv.projectLabelService, user)
+}
+
+func newProjectLabelsReverseResolver(projectLabelService IProjectLabelService, user *models.User) models.ProjectLabelReverseResolver {
+	// filtering by a label also matches all projects labeled with any of its nested labels, e.g. "client" matches "client/acme"
 	return func(k string) []string {
-		var labels []*models.ProjectLabel
-		allLabels, err := srv.projectLabelService.GetByUserGroupedInverted(user.ID)
-		if err == nil {
-			if l, ok := allLabels[k]; ok {
-				labels = l
-			}
+		projectStrings := make([]string, 0)
+		allLabels, err := projectLabelService.GetByUserGroupedInverted(user.ID)
+		if err != nil {
+			return projectStrings
 		}
-		projectStrings := make([]string, 0, len(labels))
-		for _, l := range labels {
-			projectStrings = append(projectStrings, l.ProjectKey)
+		for label, labels := range allLabels {
+			if !models.IsLabelDescendant(label, k) {
+				continue
+			}
+			for _, l := range labels {
+				projectStrings = append(projectStrings, l.ProjectKey)
+			}
 		}
 		return projectStrings
 	}
@@ -456,6 +456,53 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectLabels()
 	assert.Equal(suite.T(), 6, result.NumHeartbeats)
 }
 
+func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_NestedProjectLabels() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+
+	durations := filterDurations(from, to, suite.TestDurations)
+	durations = append(durations, &models.Duration{
+		UserID:          TestUserId,
+		Project:         TestProject2,
+		Language:        TestLanguageGo,
+		Editor:          TestEditorGoland,
+		OperatingSystem: TestOsLinux,
+		Machine:         TestMachine1,
+		Time:            models.CustomTime(durations[len(durations)-1].Time.T().Add(10 * time.Second)),
+		Duration:        10 * time.Second,
+	})
+
+	labels := []*models.ProjectLabel{
+		{UserID: TestUserId, ProjectKey: TestProject1, Label: "client/acme/backend"},
+		{UserID: TestUserId, ProjectKey: TestProject1, Label: "client/acme/frontend"},
+		{UserID: TestUserId, ProjectKey: TestProject2, Label: "client/other"},
+	}
+
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(labels, nil)
+	suite.DurationService.On("Get", from, to, suite.TestUser, mock.Anything).Return(models.Durations(durations), nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject1).Return(TestProject1, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject2).Return(TestProject2, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", nil)
+
+	result, err := sut.Aliased(from, to, suite.TestUser, sut.Summarize, nil, false)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 185*time.Second, result.TotalTimeByKey(models.SummaryLabel, "client/acme/backend"))
+	assert.Equal(suite.T(), 185*time.Second, result.TotalTimeByKey(models.SummaryLabel, "client/acme/frontend"))
+	assert.Equal(suite.T(), 10*time.Second, result.TotalTimeByKey(models.SummaryLabel, "client/other"))
+
+	result, err = sut.Aliased(from, to, suite.TestUser, sut.Summarize, (&models.Filters{}).WithLabelDepth(2), false)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 185*time.Second, result.TotalTimeByKey(models.SummaryLabel, "client/acme")) // project counted only once
+	assert.Equal(suite.T(), 10*time.Second, result.TotalTimeByKey(models.SummaryLabel, "client/other"))
+
+	result, err = sut.Aliased(from, to, suite.TestUser, sut.Summarize, (&models.Filters{}).WithLabelDepth(1), false)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Labels, 1)
+	assert.Equal(suite.T(), 195*time.Second, result.TotalTimeByKey(models.SummaryLabel, "client"))
+}
+
 func (suite *SummaryServiceTestSuite) TestSummaryService_Filters() {
 	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
 
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTeamForbidden     = errors.New("not permitted to perform this operation on the team")
+	ErrTeamInvalid       = errors.New("invalid team")
+	ErrTeamUserNotFound  = errors.New("user not found")
+	ErrTeamAlreadyMember = errors.New("user is already a member of the team or was invited before")
+	ErrTeamLastOwner     = errors.New("a team needs at least one owner")
+)
+
+type TeamService struct {
+	config      *config.Config
+	repository  repositories.ITeamRepository
+	userSrvc    IUserService
+	summarySrvc ISummaryService
+}
+
+func NewTeamService(teamRepository repositories.ITeamRepository, userService IUserService, summaryService ISummaryService) *TeamService {
+	return &TeamService{
+		config:      config.Get(),
+		repository:  teamRepository,
+		userSrvc:    userService,
+		summarySrvc: summaryService,
+	}
+}
+
+func (srv *TeamService) GetById(id uint) (*models.Team, error) {
+	return srv.repository.GetById(id)
+}
+
+// GetMemberships returns all teams the user is part of as well as all pending invitations
+func (srv *TeamService) GetMemberships(user *models.User) ([]*models.TeamMember, error) {
+	return srv.repository.GetMemberships(user.ID)
+}
+
+// GetMembership returns the user's accepted membership in the given team or ErrTeamForbidden if there is none
+func (srv *TeamService) GetMembership(team *models.Team, user *models.User) (*models.TeamMember, error) {
+	member, err := srv.repository.GetMember(team.ID, user.ID)
+	if err != nil || !member.Accepted {
+		return nil, ErrTeamForbidden
+	}
+	return member, nil
+}
+
+func (srv *TeamService) GetMembers(team *models.Team) ([]*models.TeamMember, error) {
+	return srv.repository.GetMembers(team.ID)
+}
+
+func (srv *TeamService) Create(owner *models.User, name string) (*models.Team, error) {
+	team := &models.Team{Name: strings.TrimSpace(name)}
+	if !team.IsValid() {
+		return nil, ErrTeamInvalid
+	}
+
+	team, err := srv.repository.Insert(team)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := srv.repository.InsertMember(&models.TeamMember{
+		TeamID:    team.ID,
+		UserID:    owner.ID,
+		Role:      models.TeamRoleOwner,
+		Accepted:  true,
+		InvitedBy: owner.ID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (srv *TeamService) Rename(actor *models.User, team *models.Team, name string) (*models.Team, error) {
+	if _, err := srv.requireOwner(team, actor); err != nil {
+		return nil, err
+	}
+	team.Name = strings.TrimSpace(name)
+	if !team.IsValid() {
+		return nil, ErrTeamInvalid
+	}
+	return srv.repository.Update(team)
+}
+
+func (srv *TeamService) Delete(actor *models.User, team *models.Team) error {
+	if _, err := srv.requireOwner(team, actor); err != nil {
+		return err
+	}
+	return srv.repository.Delete(team.ID)
+}
+
+// Invite adds a pending membership for the given user, which only becomes effective once accepted by them
+func (srv *TeamService) Invite(actor *models.User, team *models.Team, userId string) (*models.TeamMember, error) {
+	if _, err := srv.requireOwner(team, actor); err != nil {
+		return nil, err
+	}
+
+	user, err := srv.userSrvc.GetUserById(strings.TrimSpace(userId))
+	if err != nil {
+		return nil, ErrTeamUserNotFound
+	}
+
+	if existing, _ := srv.repository.GetMember(team.ID, user.ID); existing != nil {
+		return nil, ErrTeamAlreadyMember
+	}
+
+	return srv.repository.InsertMember(&models.TeamMember{
+		TeamID:    team.ID,
+		UserID:    user.ID,
+		Role:      models.TeamRoleMember,
+		Accepted:  false,
+		InvitedBy: actor.ID,
+	})
+}
+
+func (srv *TeamService) Accept(user *models.User, team *models.Team) (*models.TeamMember, error) {
+	member, err := srv.repository.GetMember(team.ID, user.ID)
+	if err != nil {
+		return nil, ErrTeamForbidden
+	}
+	member.Accepted = true
+	return srv.repository.UpdateMember(member)
+}
+
+// Leave removes the user from the team, which also serves for declining a pending invitation
+func (srv *TeamService) Leave(user *models.User, team *models.Team) error {
+	member, err := srv.repository.GetMember(team.ID, user.ID)
+	if err != nil {
+		return ErrTeamForbidden
+	}
+	if err := srv.checkRemainingOwners(member); err != nil {
+		return err
+	}
+	return srv.repository.DeleteMember(member.ID)
+}
+
+func (srv *TeamService) RemoveMember(actor *models.User, team *models.Team, userId string) error {
+	if _, err := srv.requireOwner(team, actor); err != nil {
+		return err
+	}
+	member, err := srv.repository.GetMember(team.ID, userId)
+	if err != nil {
+		return ErrTeamUserNotFound
+	}
+	if err := srv.checkRemainingOwners(member); err != nil {
+		return err
+	}
+	return srv.repository.DeleteMember(member.ID)
+}
+
+func (srv *TeamService) SetRole(actor *models.User, team *models.Team, userId string, role string) (*models.TeamMember, error) {
+	if _, err := srv.requireOwner(team, actor); err != nil {
+		return nil, err
+	}
+	member, err := srv.repository.GetMember(team.ID, userId)
+	if err != nil {
+		return nil, ErrTeamUserNotFound
+	}
+	if !member.Accepted {
+		return nil, ErrTeamForbidden
+	}
+	if role != models.TeamRoleOwner && role != models.TeamRoleMember {
+		return nil, ErrTeamInvalid
+	}
+	if role == models.TeamRoleMember {
+		if err := srv.checkRemainingOwners(member); err != nil {
+			return nil, err
+		}
+	}
+	member.Role = role
+	return srv.repository.UpdateMember(member)
+}
+
+// Summary aggregates the summaries of all accepted team members in the given interval. Every member only contributes
+// the types of data they chose to share (see models.SharedTeamSummaryTypes) and only within the number of past days
+// they share data for, while members who don't share any data at all are listed without coding time. If filters target
+// a type a member doesn't share, that member is excluded altogether, as otherwise their activity on the filtered entity
+// could be inferred.
+func (srv *TeamService) Summary(actor *models.User, team *models.Team, from, to time.Time, filters *models.Filters) (*models.TeamSummary, error) {
+	if _, err := srv.GetMembership(team, actor); err != nil {
+		return nil, err
+	}
+
+	if filters == nil {
+		filters = &models.Filters{}
+	}
+
+	members, err := srv.repository.GetMembers(team.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	teamSummary := &models.TeamSummary{
+		Team:     team,
+		From:     from,
+		To:       to,
+		Summary:  models.NewEmptySummary(),
+		Members:  make([]*models.TeamMemberSummary, 0, len(members)),
+		Filtered: !filters.IsEmpty(),
+	}
+	teamSummary.Summary.FromTime = models.CustomTime(from)
+	teamSummary.Summary.ToTime = models.CustomTime(to)
+
+	for _, m := range members {
+		if !m.Accepted || m.User == nil {
+			continue
+		}
+
+		shared := models.SharedTeamSummaryTypes(m.User)
+		memberSummary := &models.TeamMemberSummary{
+			UserID:  m.UserID,
+			Shared:  make([]uint8, 0),
+			Summary: models.NewEmptySummary(),
+		}
+		for _, t := range models.SummaryTypes() {
+			if shared[t] {
+				memberSummary.Shared = append(memberSummary.Shared, t)
+			}
+		}
+		teamSummary.Members = append(teamSummary.Members, memberSummary)
+
+		memberFrom, memberTo, ok := sharedRange(m.User, from, to)
+		if !ok {
+			memberSummary.Private = m.User.ShareDataMaxDays == 0
+			continue
+		}
+
+		if !filtersPermitted(filters, shared) {
+			memberSummary.Excluded = true
+			continue
+		}
+
+		summary, err := srv.summarySrvc.Aliased(memberFrom, memberTo, m.User, srv.summarySrvc.Retrieve, filters, false)
+		if err != nil {
+			return nil, err
+		}
+
+		memberSummary.Total = summary.TotalTime()
+		memberSummary.Summary = sharedSummaryCopy(summary, shared)
+		mergeTeamSummary(teamSummary.Summary, memberSummary.Summary)
+	}
+
+	teamSummary.Summary.Sorted()
+	return teamSummary, nil
+}
+
+// sharedRange clamps the given interval to the past days a user shares data for, analogous to badges and public stats,
+// and returns false if nothing of it is shared
+func sharedRange(user *models.User, from, to time.Time) (time.Time, time.Time, bool) {
+	if user.ShareDataMaxDays == 0 {
+		return from, to, false
+	}
+	// negative value means no limit
+	if user.ShareDataMaxDays > 0 {
+		if minStart := time.Now().AddDate(0, 0, -user.ShareDataMaxDays); from.Before(minStart) {
+			from = minStart
+		}
+	}
+	return from, to, from.Before(to)
+}
+
+func (srv *TeamService) requireOwner(team *models.Team, user *models.User) (*models.TeamMember, error) {
+	member, err := srv.GetMembership(team, user)
+	if err != nil || !member.IsOwner() {
+		return nil, ErrTeamForbidden
+	}
+	return member, nil
+}
+
+func (srv *TeamService) checkRemainingOwners(member *models.TeamMember) error {
+	if !member.IsOwner() {
+		return nil
+	}
+	members, err := srv.repository.GetMembers(member.TeamID)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.ID != member.ID && m.IsOwner() && m.Accepted {
+			return nil
+		}
+	}
+	return ErrTeamLastOwner
+}
+
+func filtersPermitted(filters *models.Filters, shared map[uint8]bool) bool {
+	for _, t := range models.SummaryTypes() {
+		if filters.CountByType(t) > 0 && !shared[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// sharedSummaryCopy returns a deep copy of the given summary, limited to the given types, such that cached summaries aren't modified
+func sharedSummaryCopy(summary *models.Summary, shared map[uint8]bool) *models.Summary {
+	result := models.NewEmptySummary()
+	result.UserID = summary.UserID
+	result.FromTime = summary.FromTime
+	result.ToTime = summary.ToTime
+	for _, t := range models.SummaryTypes() {
+		if !shared[t] {
+			continue
+		}
+		items := make(models.SummaryItems, 0, len(*summary.GetByType(t)))
+		for _, item := range *summary.GetByType(t) {
+			items = append(items, &models.SummaryItem{Type: item.Type, Key: item.Key, Total: item.Total})
+		}
+		result.SetByType(t, &items)
+	}
+	return result
+}
+
+func mergeTeamSummary(target, summary *models.Summary) {
+	for _, t := range models.SummaryTypes() {
+		targetItems := target.GetByType(t)
+		for _, item := range *summary.GetByType(t) {
+			var found bool
+			for _, existing := range *targetItems {
+				if existing.Key == item.Key {
+					existing.Total += item.Total
+					found = true
+					break
+				}
+			}
+			if !found {
+				*targetItems = append(*targetItems, &models.SummaryItem{Type: item.Type, Key: item.Key, Total: item.Total})
+			}
+		}
+	}
+}
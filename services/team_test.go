@@ -0,0 +1,179 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type TeamServiceTestSuite struct {
+	suite.Suite
+	TestTeam       *models.Team
+	Owner          *models.User
+	Member         *models.User
+	Private        *models.User
+	TeamRepository *mocks.TeamRepositoryMock
+	UserService    *mocks.UserServiceMock
+	SummaryService *mocks.SummaryServiceMock
+}
+
+func (suite *TeamServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+
+	suite.TestTeam = &models.Team{ID: 1, Name: "Backend"}
+	suite.Owner = &models.User{ID: "owner", ShareDataMaxDays: -1, ShareProjects: true, ShareLanguages: true}
+	suite.Member = &models.User{ID: TestUserId, ShareDataMaxDays: -1, ShareLanguages: true}
+	suite.Private = &models.User{ID: "private"}
+
+	suite.TeamRepository = new(mocks.TeamRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+}
+
+func TestTeamServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TeamServiceTestSuite))
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_Create() {
+	sut := NewTeamService(suite.TeamRepository, suite.UserService, suite.SummaryService)
+
+	suite.TeamRepository.On("Insert", mock.Anything).Return(nil, nil)
+	suite.TeamRepository.On("InsertMember", mock.Anything).Return(nil, nil)
+
+	team, err := sut.Create(suite.Owner, "  Backend ")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Backend", team.Name)
+
+	member := suite.TeamRepository.Calls[1].Arguments.Get(0).(*models.TeamMember)
+	assert.Equal(suite.T(), suite.Owner.ID, member.UserID)
+	assert.Equal(suite.T(), models.TeamRoleOwner, member.Role)
+	assert.True(suite.T(), member.Accepted)
+
+	_, err = sut.Create(suite.Owner, " ")
+	assert.ErrorIs(suite.T(), err, ErrTeamInvalid)
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_Invite() {
+	sut := NewTeamService(suite.TeamRepository, suite.UserService, suite.SummaryService)
+
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.Owner.ID).Return(&models.TeamMember{ID: 1, TeamID: 1, UserID: suite.Owner.ID, Role: models.TeamRoleOwner, Accepted: true}, nil)
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.Member.ID).Return(&models.TeamMember{ID: 2, TeamID: 1, UserID: suite.Member.ID, Role: models.TeamRoleMember, Accepted: true}, nil)
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.Private.ID).Return(nil, assert.AnError)
+	suite.TeamRepository.On("InsertMember", mock.Anything).Return(nil, nil)
+	suite.UserService.On("GetUserById", suite.Member.ID).Return(suite.Member, nil)
+	suite.UserService.On("GetUserById", suite.Private.ID).Return(suite.Private, nil)
+
+	member, err := sut.Invite(suite.Owner, suite.TestTeam, suite.Private.ID)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), member.Accepted)
+	assert.Equal(suite.T(), models.TeamRoleMember, member.Role)
+	assert.Equal(suite.T(), suite.Owner.ID, member.InvitedBy)
+
+	_, err = sut.Invite(suite.Owner, suite.TestTeam, suite.Member.ID)
+	assert.ErrorIs(suite.T(), err, ErrTeamAlreadyMember)
+
+	// only owners may invite
+	_, err = sut.Invite(suite.Member, suite.TestTeam, suite.Private.ID)
+	assert.ErrorIs(suite.T(), err, ErrTeamForbidden)
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_Leave_LastOwner() {
+	sut := NewTeamService(suite.TeamRepository, suite.UserService, suite.SummaryService)
+
+	owner := &models.TeamMember{ID: 1, TeamID: 1, UserID: suite.Owner.ID, Role: models.TeamRoleOwner, Accepted: true}
+	member := &models.TeamMember{ID: 2, TeamID: 1, UserID: suite.Member.ID, Role: models.TeamRoleMember, Accepted: true}
+
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.Owner.ID).Return(owner, nil)
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.Member.ID).Return(member, nil)
+	suite.TeamRepository.On("GetMembers", suite.TestTeam.ID).Return([]*models.TeamMember{owner, member}, nil)
+	suite.TeamRepository.On("DeleteMember", member.ID).Return(nil)
+
+	assert.ErrorIs(suite.T(), sut.Leave(suite.Owner, suite.TestTeam), ErrTeamLastOwner)
+	assert.Nil(suite.T(), sut.Leave(suite.Member, suite.TestTeam))
+	suite.TeamRepository.AssertNotCalled(suite.T(), "DeleteMember", owner.ID)
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_Summary() {
+	sut := NewTeamService(suite.TeamRepository, suite.UserService, suite.SummaryService)
+
+	from, to := time.Now().Add(-24*time.Hour), time.Now()
+
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.Owner.ID).Return(&models.TeamMember{ID: 1, TeamID: 1, UserID: suite.Owner.ID, Role: models.TeamRoleOwner, Accepted: true}, nil)
+	suite.TeamRepository.On("GetMembers", suite.TestTeam.ID).Return([]*models.TeamMember{
+		{ID: 1, TeamID: 1, UserID: suite.Owner.ID, User: suite.Owner, Role: models.TeamRoleOwner, Accepted: true},
+		{ID: 2, TeamID: 1, UserID: suite.Member.ID, User: suite.Member, Role: models.TeamRoleMember, Accepted: true},
+		{ID: 3, TeamID: 1, UserID: suite.Private.ID, User: suite.Private, Role: models.TeamRoleMember, Accepted: false},
+	}, nil)
+
+	ownerSummary := &models.Summary{
+		UserID:    suite.Owner.ID,
+		Projects:  []*models.SummaryItem{{Type: models.SummaryProject, Key: TestProject1, Total: 3600}},
+		Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: TestLanguageGo, Total: 3600}},
+		Editors:   []*models.SummaryItem{{Type: models.SummaryEditor, Key: TestEditorGoland, Total: 3600}},
+	}
+	memberSummary := &models.Summary{
+		UserID:    suite.Member.ID,
+		Projects:  []*models.SummaryItem{{Type: models.SummaryProject, Key: TestProject2, Total: 1800}},
+		Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: TestLanguageGo, Total: 1800}},
+	}
+
+	suite.SummaryService.On("Aliased", from, to, suite.Owner, mock.Anything, mock.Anything).Return(ownerSummary, nil)
+	suite.SummaryService.On("Aliased", from, to, suite.Member, mock.Anything, mock.Anything).Return(memberSummary, nil)
+
+	result, err := sut.Summary(suite.Owner, suite.TestTeam, from, to, nil)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Members, 2) // pending invitation is not included
+	assert.Equal(suite.T(), 90*time.Minute, result.TotalTime())
+
+	// languages are shared by both members
+	assert.Len(suite.T(), result.Summary.Languages, 1)
+	assert.Equal(suite.T(), time.Duration(5400), result.Summary.Languages[0].Total) // summary items are in seconds
+
+	// projects are only shared by the owner, editors by nobody
+	assert.Len(suite.T(), result.Summary.Projects, 1)
+	assert.Equal(suite.T(), TestProject1, result.Summary.Projects[0].Key)
+	assert.Empty(suite.T(), result.Summary.Editors)
+	assert.Empty(suite.T(), result.Members[1].Summary.Projects)
+
+	// cached summaries must not be touched
+	assert.Len(suite.T(), memberSummary.Projects, 1)
+	assert.Equal(suite.T(), time.Duration(3600), ownerSummary.Languages[0].Total)
+
+	// filtering by project excludes members who don't share their projects
+	filters := models.NewFiltersWith(models.SummaryProject, TestProject1)
+	result, err = sut.Summary(suite.Owner, suite.TestTeam, from, to, filters)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), result.Filtered)
+	assert.False(suite.T(), result.Members[0].Excluded)
+	assert.True(suite.T(), result.Members[1].Excluded)
+	assert.Equal(suite.T(), 60*time.Minute, result.TotalTime())
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 3)
+
+	// members only contribute within the days they share, members sharing nothing not at all
+	suite.Member.ShareDataMaxDays = 0
+	result, err = sut.Summary(suite.Owner, suite.TestTeam, from, to, nil)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), result.Members[1].Private)
+	assert.Zero(suite.T(), result.Members[1].Total)
+	assert.Equal(suite.T(), 60*time.Minute, result.TotalTime())
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 4)
+
+	suite.Owner.ShareDataMaxDays = 7
+	longFrom := to.AddDate(0, 0, -30)
+	suite.SummaryService.On("Aliased", mock.MatchedBy(func(t time.Time) bool {
+		return t.After(to.AddDate(0, 0, -8)) && t.Before(to.AddDate(0, 0, -6))
+	}), to, suite.Owner, mock.Anything, mock.Anything).Return(ownerSummary, nil).Once()
+	result, err = sut.Summary(suite.Owner, suite.TestTeam, longFrom, to, nil)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 60*time.Minute, result.TotalTime())
+
+	// non-members can't view the summary
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.Private.ID).Return(&models.TeamMember{ID: 3, Accepted: false}, nil)
+	_, err = sut.Summary(suite.Private, suite.TestTeam, from, to, nil)
+	assert.ErrorIs(suite.T(), err, ErrTeamForbidden)
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+	"image/png"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	totpIssuer            = "Wakapi"
+	totpQrCodeSize        = 256
+	totpNumRecoveryCodes  = 8
+	totpRecoveryCodeBytes = 5
+	totpPeriod            = 30 // seconds
+	totpSkew              = 1  // periods before or after the current one to accept
+)
+
+var (
+	ErrTotpNotSetUp     = errors.New("two-factor authentication was not set up")
+	ErrTotpInvalidCode  = errors.New("invalid one-time password")
+	ErrTotpAlreadyInUse = errors.New("two-factor authentication is already enabled")
+)
+
+type TotpService struct {
+	config      *config.Config
+	userService IUserService
+	lock        sync.Mutex // to prevent concurrent requests from using the same code twice
+}
+
+func NewTotpService(userService IUserService) *TotpService {
+	return &TotpService{
+		config:      config.Get(),
+		userService: userService,
+	}
+}
+
+// Setup generates a new, not yet activated totp secret for the given user and returns it, along with a qr code image as data url
+func (srv *TotpService) Setup(user *models.User) (string, string, error) {
+	if user.HasTotp() {
+		return "", "", ErrTotpAlreadyInUse
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.ID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	img, err := key.Image(totpQrCodeSize, totpQrCodeSize)
+	if err != nil {
+		return "", "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", "", err
+	}
+
+	user.TotpSecret = key.Secret()
+	user.TotpEnabled = false
+	user.TotpLastStep = 0
+	user.TotpRecoveryCodes = ""
+	if _, err := srv.userService.Update(user); err != nil {
+		return "", "", err
+	}
+
+	return key.Secret(), fmt.Sprintf("data:image/png;base64,%s", base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+// Enable activates two-factor authentication, given a valid code for the previously set up secret, and returns a fresh set of recovery codes
+func (srv *TotpService) Enable(user *models.User, code string) ([]string, error) {
+	if user.TotpSecret == "" {
+		return nil, ErrTotpNotSetUp
+	}
+	if user.HasTotp() {
+		return nil, ErrTotpAlreadyInUse
+	}
+	step, ok := validateTotpCode(normalizeTotpCode(code), user.TotpSecret, time.Now())
+	if !ok {
+		return nil, ErrTotpInvalidCode
+	}
+
+	recoveryCodes, hashedCodes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TotpEnabled = true
+	user.TotpLastStep = step
+	user.TotpRecoveryCodes = strings.Join(hashedCodes, ",")
+	if _, err := srv.userService.Update(user); err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+func (srv *TotpService) Disable(user *models.User) error {
+	user.TotpSecret = ""
+	user.TotpEnabled = false
+	user.TotpRecoveryCodes = ""
+	_, err := srv.userService.Update(user)
+	return err
+}
+
+// Verify checks the given code to either be a currently valid one-time password or one of the user's unused recovery codes, which is invalidated then.
+// One-time passwords are only accepted once, i.e. codes of the same or an earlier time step than the last accepted one are rejected.
+func (srv *TotpService) Verify(user *models.User, code string) (bool, error) {
+	if !user.HasTotp() {
+		return false, ErrTotpNotSetUp
+	}
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	code = normalizeTotpCode(code)
+	if step, ok := validateTotpCode(code, user.TotpSecret, time.Now()); ok {
+		if step <= user.TotpLastStep {
+			return false, nil // replayed
+		}
+		user.TotpLastStep = step
+		if _, err := srv.userService.Update(user); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	hashedCode := hashRecoveryCode(code)
+	remainingCodes := make([]string, 0, totpNumRecoveryCodes)
+	var found bool
+	for _, c := range strings.Split(user.TotpRecoveryCodes, ",") {
+		if c == "" {
+			continue
+		}
+		if !found && subtle.ConstantTimeCompare([]byte(c), []byte(hashedCode)) == 1 {
+			found = true
+			continue
+		}
+		remainingCodes = append(remainingCodes, c)
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	user.TotpRecoveryCodes = strings.Join(remainingCodes, ",")
+	if _, err := srv.userService.Update(user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// validateTotpCode checks the code against the time steps around t and returns the one it matches
+func validateTotpCode(code, secret string, t time.Time) (int64, bool) {
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := hotp.GenerateCodeCustom(secret, uint64(step), hotp.ValidateOpts{Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, hashedCodes := make([]string, totpNumRecoveryCodes), make([]string, totpNumRecoveryCodes)
+	for i := range codes {
+		b := make([]byte, totpRecoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		codes[i] = hex.EncodeToString(b)
+		hashedCodes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashedCodes, nil
+}
+
+// recovery codes are random and of sufficient entropy, so a simple hash (as opposed to a password hash) is fine
+func hashRecoveryCode(code string) string {
+	h := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(h[:])
+}
+
+func normalizeTotpCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
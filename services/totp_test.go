@@ -0,0 +1,91 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TotpServiceTestSuite struct {
+	suite.Suite
+	UserService *mocks.UserServiceMock
+}
+
+func (suite *TotpServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.UserService.On("Update", mock.Anything).Return(&models.User{}, nil)
+}
+
+func TestTotpServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TotpServiceTestSuite))
+}
+
+func (suite *TotpServiceTestSuite) TestTotpService_SetupAndEnable() {
+	sut := NewTotpService(suite.UserService)
+	user := &models.User{ID: TestUserId}
+
+	secret, qrCode, err := sut.Setup(user)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), secret)
+	assert.True(suite.T(), strings.HasPrefix(qrCode, "data:image/png;base64,"))
+	assert.Equal(suite.T(), secret, user.TotpSecret)
+	assert.False(suite.T(), user.HasTotp())
+
+	_, err = sut.Enable(user, "000000")
+	assert.ErrorIs(suite.T(), err, ErrTotpInvalidCode)
+	assert.False(suite.T(), user.HasTotp())
+
+	code, _ := totp.GenerateCode(secret, time.Now())
+	recoveryCodes, err := sut.Enable(user, code)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), recoveryCodes, totpNumRecoveryCodes)
+	assert.True(suite.T(), user.HasTotp())
+	assert.NotContains(suite.T(), user.TotpRecoveryCodes, recoveryCodes[0]) // stored hashed only
+
+	_, _, err = sut.Setup(user)
+	assert.ErrorIs(suite.T(), err, ErrTotpAlreadyInUse)
+}
+
+func (suite *TotpServiceTestSuite) TestTotpService_Verify() {
+	sut := NewTotpService(suite.UserService)
+	user := &models.User{ID: TestUserId}
+
+	secret, _, _ := sut.Setup(user)
+	code, _ := totp.GenerateCode(secret, time.Now())
+	recoveryCodes, _ := sut.Enable(user, code)
+
+	// code used for enabling must not be accepted again
+	valid, err := sut.Verify(user, code)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), valid)
+
+	code, _ = totp.GenerateCode(secret, time.Now().Add(totpPeriod*time.Second))
+	valid, err = sut.Verify(user, code)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), valid)
+	valid, _ = sut.Verify(user, code)
+	assert.False(suite.T(), valid)
+
+	valid, _ = sut.Verify(user, "abcdef")
+	assert.False(suite.T(), valid)
+
+	// recovery codes are single-use
+	valid, _ = sut.Verify(user, " "+strings.ToUpper(recoveryCodes[3])+" ")
+	assert.True(suite.T(), valid)
+	assert.Len(suite.T(), strings.Split(user.TotpRecoveryCodes, ","), totpNumRecoveryCodes-1)
+	valid, _ = sut.Verify(user, recoveryCodes[3])
+	assert.False(suite.T(), valid)
+
+	assert.Nil(suite.T(), sut.Disable(user))
+	assert.False(suite.T(), user.HasTotp())
+	_, err = sut.Verify(user, code)
+	assert.ErrorIs(suite.T(), err, ErrTotpNotSetUp)
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/duke-git/lancet/v2/convertor"
@@ -17,20 +19,24 @@ import (
 )
 
 type UserService struct {
-	config      *config.Config
-	cache       *cache.Cache
-	eventBus    *hub.Hub
-	mailService IMailService
-	repository  repositories.IUserRepository
+	config         *config.Config
+	cache          *cache.Cache
+	eventBus       *hub.Hub
+	mailService    IMailService
+	notifications  INotificationService
+	sessionService ISessionService
+	repository     repositories.IUserRepository
 }
 
-func NewUserService(mailService IMailService, userRepo repositories.IUserRepository) *UserService {
+func NewUserService(mailService IMailService, notificationService INotificationService, sessionService ISessionService, userRepo repositories.IUserRepository) *UserService {
 	srv := &UserService{
-		config:      config.Get(),
-		eventBus:    config.EventBus(),
-		cache:       cache.New(1*time.Hour, 2*time.Hour),
-		mailService: mailService,
-		repository:  userRepo,
+		config:         config.Get(),
+		eventBus:       config.EventBus(),
+		cache:          cache.New(1*time.Hour, 2*time.Hour),
+		mailService:    mailService,
+		notifications:  notificationService,
+		sessionService: sessionService,
+		repository:     userRepo,
 	}
 
 	sub1 := srv.eventBus.Subscribe(0, config.EventWakatimeFailure)
@@ -52,9 +58,26 @@ func NewUserService(mailService IMailService, userRepo repositories.IUserReposit
 					logbuch.Info("sent wakatime connection failure mail to %s", user.ID)
 				}
 			}
+			if err := notificationService.SendWakatimeFailureNotification(user, n); err != nil {
+				config.Log().Error("failed to post wakatime failure notification for user %s - %v", user.ID, err)
+			}
 		}
 	}(&sub1)
 
+	sub2 := srv.eventBus.Subscribe(0, config.EventRelayTargetDisable)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			target := m.Fields[config.FieldPayload].(*models.RelayTarget)
+			user, err := srv.GetUserById(m.Fields[config.FieldUserId].(string))
+			if err != nil {
+				continue
+			}
+			if err := notificationService.SendRelayTargetFailureNotification(user, target); err != nil {
+				config.Log().Error("failed to post relay target failure notification for user %s - %v", user.ID, err)
+			}
+		}
+	}(&sub2)
+
 	return srv
 }
 
@@ -94,6 +117,14 @@ func (srv *UserService) GetUserByKey(key string) (*models.User, error) {
 	return u, nil
 }
 
+func (srv *UserService) GetUserBySession(token string) (*models.User, error) {
+	session, err := srv.sessionService.GetByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return srv.GetUserById(session.UserID)
+}
+
 func (srv *UserService) GetUserByEmail(email string) (*models.User, error) {
 	if email == "" {
 		return nil, errors.New("email must not be empty")
@@ -101,6 +132,13 @@ func (srv *UserService) GetUserByEmail(email string) (*models.User, error) {
 	return srv.repository.FindOne(models.User{Email: email})
 }
 
+func (srv *UserService) GetUserByOidcSubject(subject string) (*models.User, error) {
+	if subject == "" {
+		return nil, errors.New("subject must not be empty")
+	}
+	return srv.repository.FindOne(models.User{OidcSubject: subject})
+}
+
 func (srv *UserService) GetUserByResetToken(resetToken string) (*models.User, error) {
 	if resetToken == "" {
 		return nil, errors.New("reset token must not be empty")
@@ -108,6 +146,13 @@ func (srv *UserService) GetUserByResetToken(resetToken string) (*models.User, er
 	return srv.repository.FindOne(models.User{ResetToken: resetToken})
 }
 
+func (srv *UserService) GetUserByCalendarToken(token string) (*models.User, error) {
+	if token == "" {
+		return nil, errors.New("calendar token must not be empty")
+	}
+	return srv.repository.FindOne(models.User{CalendarToken: token})
+}
+
 func (srv *UserService) GetUserByStripeCustomerId(customerId string) (*models.User, error) {
 	if customerId == "" {
 		return nil, errors.New("customer id must not be empty")
@@ -167,6 +212,13 @@ func (srv *UserService) GetActive(exact bool) ([]*models.User, error) {
 	return results, nil
 }
 
+func (srv *UserService) Search(query string, pageParams *utils.PageParams) ([]*models.User, error) {
+	if pageParams == nil || pageParams.Limit() == 0 {
+		return srv.repository.Search(query, -1, -1)
+	}
+	return srv.repository.Search(query, pageParams.Limit(), pageParams.Offset())
+}
+
 func (srv *UserService) Count() (int64, error) {
 	return srv.repository.Count()
 }
@@ -197,12 +249,34 @@ func (srv *UserService) Update(user *models.User) (*models.User, error) {
 	return srv.repository.Update(user)
 }
 
+// SetAdmin grants or revokes the user's admin privileges, which can't be changed through Update
+func (srv *UserService) SetAdmin(user *models.User, isAdmin bool) (*models.User, error) {
+	srv.FlushUserCache(user.ID)
+	u, err := srv.repository.SetAdmin(user, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	srv.notifyUpdate(u)
+	return u, nil
+}
+
 func (srv *UserService) ResetApiKey(user *models.User) (*models.User, error) {
 	srv.FlushUserCache(user.ID)
 	user.ApiKey = uuid.NewV4().String()
 	return srv.Update(user)
 }
 
+// ResetCalendarToken generates a new secret for the user's work session calendar feed, invalidating the previous one
+func (srv *UserService) ResetCalendarToken(user *models.User) (*models.User, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	srv.FlushUserCache(user.ID)
+	user.CalendarToken = hex.EncodeToString(b)
+	return srv.Update(user)
+}
+
 func (srv *UserService) SetWakatimeApiCredentials(user *models.User, apiKey string, apiUrl string) (*models.User, error) {
 	srv.FlushUserCache(user.ID)
 
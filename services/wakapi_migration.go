@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+)
+
+const wakapiMigrationCheckpointInterval = 10 * time.Second
+
+// WakapiMigrationService moves a user's data over from another wakapi instance. Aliases, project labels and language
+// mappings are copied first, followed by all heartbeats. The progress of the latter is checkpointed, so that an
+// interrupted migration continues where it left off when being started again. Finally, total coding time on both
+// instances is compared to verify that nothing got lost. Every migration is tracked as an import job.
+type WakapiMigrationService struct {
+	config                 *config.Config
+	importService          IImportService
+	summaryService         ISummaryService
+	aliasService           IAliasService
+	projectLabelService    IProjectLabelService
+	languageMappingService ILanguageMappingService
+	keyValueService        IKeyValueService
+}
+
+func NewWakapiMigrationService(importService IImportService, summaryService ISummaryService, aliasService IAliasService, projectLabelService IProjectLabelService, languageMappingService ILanguageMappingService, keyValueService IKeyValueService) *WakapiMigrationService {
+	return &WakapiMigrationService{
+		config:                 config.Get(),
+		importService:          importService,
+		summaryService:         summaryService,
+		aliasService:           aliasService,
+		projectLabelService:    projectLabelService,
+		languageMappingService: languageMappingService,
+		keyValueService:        keyValueService,
+	}
+}
+
+// MigrateAsync checks whether the source instance is reachable with the given credentials and, if so, runs the
+// migration in the background
+func (srv *WakapiMigrationService) MigrateAsync(user *models.User, baseUrl, apiKey string) error {
+	if _, err := imports.NewWakapiImporter(baseUrl, apiKey).FetchSummary(); err != nil {
+		return fmt.Errorf("failed to connect to '%s' - %v", imports.NormalizeWakapiUrl(baseUrl), err)
+	}
+
+	job, err := srv.createJob(user, baseUrl, apiKey)
+	if err != nil {
+		return err
+	}
+
+	go srv.runAsync(job)
+
+	return srv.keyValueService.PutString(&models.KeyStringValue{
+		Key:   fmt.Sprintf("%s_%s", config.KeyLastImport, user.ID),
+		Value: time.Now().Format(time.RFC822),
+	})
+}
+
+// Migrate blocks until the migration is finished or failed
+func (srv *WakapiMigrationService) Migrate(user *models.User, baseUrl, apiKey string) (*models.WakapiMigrationResult, error) {
+	job, err := srv.createJob(user, baseUrl, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return srv.migrate(job)
+}
+
+// ResumeInterrupted continues all migrations that were still running when wakapi was last shut down
+func (srv *WakapiMigrationService) ResumeInterrupted() {
+	jobs, err := srv.importService.GetRunningJobs(models.ImportSourceWakapi)
+	if err != nil {
+		config.Log().Error("failed to fetch interrupted wakapi migrations - %v", err)
+		return
+	}
+	for _, job := range jobs {
+		logbuch.Info("resuming wakapi migration job %d for user '%s'", job.ID, job.UserID)
+		go srv.runAsync(job)
+	}
+}
+
+func (srv *WakapiMigrationService) createJob(user *models.User, baseUrl, apiKey string) (*models.ImportJob, error) {
+	return srv.importService.CreateJob(models.NewImportJob(user, models.ImportSourceWakapi).WithParams(&models.ImportJobParams{
+		BaseUrl: imports.NormalizeWakapiUrl(baseUrl),
+		ApiKey:  apiKey,
+	}))
+}
+
+func (srv *WakapiMigrationService) runAsync(job *models.ImportJob) {
+	if _, err := srv.migrate(job); err != nil {
+		config.Log().Error("wakapi migration for user '%s' failed - %v", job.UserID, err)
+		return
+	}
+	srv.keyValueService.PutString(&models.KeyStringValue{
+		Key:   fmt.Sprintf("%s_%s", config.KeyLastImportSuccess, job.UserID),
+		Value: time.Now().Format(time.RFC822),
+	})
+}
+
+func (srv *WakapiMigrationService) migrate(job *models.ImportJob) (*models.WakapiMigrationResult, error) {
+	user, params := job.User, job.GetParams()
+	importer := imports.NewWakapiImporter(params.BaseUrl, params.ApiKey)
+
+	settings, err := importer.FetchSettings()
+	if err != nil {
+		srv.importService.FailJob(job, err)
+		return nil, err
+	}
+	srv.importSettings(user, settings)
+
+	cursor := srv.loadCheckpoint(user, params.BaseUrl)
+	if cursor > 0 {
+		logbuch.Info("resuming wakapi migration for user '%s' after heartbeat %d", user.ID, cursor)
+	}
+
+	var lastSaved time.Time
+	result, err := srv.importService.RunJob(job, importer.WithCursor(cursor), func(hb *models.Heartbeat) {
+		if time.Since(lastSaved) < wakapiMigrationCheckpointInterval {
+			return
+		}
+		if cursor, err := strconv.ParseUint(hb.OriginId, 10, 64); err == nil {
+			srv.saveCheckpoint(user, params.BaseUrl, cursor)
+			lastSaved = time.Now()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := importer.Err(); err != nil {
+		return nil, fmt.Errorf("migration interrupted, will resume from last checkpoint when started again - %v", err)
+	}
+	srv.keyValueService.DeleteString(srv.checkpointKey(user))
+
+	migrationResult := &models.WakapiMigrationResult{ImportResult: result}
+	if err := srv.verify(user, importer, migrationResult); err != nil {
+		config.Log().Error("failed to verify wakapi migration for user '%s' - %v", user.ID, err)
+		return migrationResult, nil
+	}
+
+	if migrationResult.Verified() {
+		logbuch.Info("verified wakapi migration for user '%s' (%v on source, %v locally)", user.ID, migrationResult.SourceTotal, migrationResult.TargetTotal)
+	} else {
+		logbuch.Warn("coding time of user '%s' after wakapi migration does not match source (%v on source, %v locally)", user.ID, migrationResult.SourceTotal, migrationResult.TargetTotal)
+		job.AddError(fmt.Sprintf("coding time does not match source (%v on source, %v locally)", migrationResult.SourceTotal, migrationResult.TargetTotal))
+		srv.importService.UpdateJob(job)
+	}
+	return migrationResult, nil
+}
+
+// importSettings copies all aliases, project labels and language mappings not yet existing locally, failures are only logged
+func (srv *WakapiMigrationService) importSettings(user *models.User, settings *models.SettingsExport) {
+	existingAliases, _ := srv.aliasService.GetByUser(user.ID)
+	for _, a := range settings.Aliases {
+		if containsWakapiAlias(existingAliases, a) {
+			continue
+		}
+		if _, err := srv.aliasService.Create(&models.Alias{UserID: user.ID, Type: a.Type, Key: a.Key, Value: a.Value}); err != nil {
+			logbuch.Warn("failed to migrate alias '%s' of user '%s' - %v", a.Key, user.ID, err)
+		}
+	}
+
+	existingLabels, _ := srv.projectLabelService.GetByUser(user.ID)
+	for _, l := range settings.ProjectLabels {
+		if containsWakapiProjectLabel(existingLabels, l) {
+			continue
+		}
+		if _, err := srv.projectLabelService.Create(&models.ProjectLabel{UserID: user.ID, ProjectKey: l.ProjectKey, Label: l.Label}); err != nil {
+			logbuch.Warn("failed to migrate project label '%s' of user '%s' - %v", l.Label, user.ID, err)
+		}
+	}
+	for label, color := range settings.LabelColors {
+		if _, err := srv.projectLabelService.SetColor(&models.LabelColor{UserID: user.ID, Label: label, Color: color}); err != nil {
+			logbuch.Warn("failed to migrate color of label '%s' of user '%s' - %v", label, user.ID, err)
+		}
+	}
+
+	existingMappings, _ := srv.languageMappingService.GetByUser(user.ID)
+	for _, m := range settings.LanguageMappings {
+		if containsWakapiLanguageMapping(existingMappings, m) {
+			continue
+		}
+		if _, err := srv.languageMappingService.Create(&models.LanguageMapping{UserID: user.ID, Extension: m.Extension, Type: m.Type, Language: m.Language}); err != nil {
+			logbuch.Warn("failed to migrate language mapping '%s' of user '%s' - %v", m.Extension, user.ID, err)
+		}
+	}
+}
+
+func (srv *WakapiMigrationService) verify(user *models.User, importer *imports.WakapiImporter, result *models.WakapiMigrationResult) error {
+	source, err := importer.FetchSummary()
+	if err != nil {
+		return err
+	}
+	// same range as the source's 'all_time' interval, the summary's own from and to are cropped to where data is available
+	target, err := srv.summaryService.Aliased(time.Time{}, time.Now(), user, srv.summaryService.Retrieve, nil, false)
+	if err != nil {
+		return err
+	}
+	result.SourceTotal = source.TotalTime()
+	result.TargetTotal = target.TotalTime()
+	return nil
+}
+
+// loadCheckpoint returns the cursor to resume the migration from, or 0 if it has to start from the beginning
+func (srv *WakapiMigrationService) loadCheckpoint(user *models.User, baseUrl string) uint64 {
+	var checkpoint models.WakapiMigrationCheckpoint
+	if err := json.Unmarshal([]byte(srv.keyValueService.MustGetString(srv.checkpointKey(user)).Value), &checkpoint); err != nil {
+		return 0
+	}
+	if checkpoint.BaseUrl != baseUrl {
+		return 0 // checkpoint refers to a different source instance
+	}
+	return checkpoint.Cursor
+}
+
+func (srv *WakapiMigrationService) saveCheckpoint(user *models.User, baseUrl string, cursor uint64) {
+	data, _ := json.Marshal(&models.WakapiMigrationCheckpoint{BaseUrl: baseUrl, Cursor: cursor, UpdatedAt: time.Now()})
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{Key: srv.checkpointKey(user), Value: string(data)}); err != nil {
+		config.Log().Error("failed to save wakapi migration checkpoint for user '%s' - %v", user.ID, err)
+	}
+}
+
+func (srv *WakapiMigrationService) checkpointKey(user *models.User) string {
+	return fmt.Sprintf("%s_%s", config.KeyWakapiMigrationCheckpoint, user.ID)
+}
+
+func containsWakapiAlias(aliases []*models.Alias, alias *models.Alias) bool {
+	for _, a := range aliases {
+		if a.Type == alias.Type && a.Key == alias.Key && a.Value == alias.Value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWakapiProjectLabel(labels []*models.ProjectLabel, label *models.ProjectLabel) bool {
+	for _, l := range labels {
+		if l.ProjectKey == label.ProjectKey && l.Label == label.Label {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWakapiLanguageMapping(mappings []*models.LanguageMapping, mapping *models.LanguageMapping) bool {
+	for _, m := range mappings {
+		if m.Extension == mapping.Extension && m.GetType() == mapping.GetType() {
+			return true // patterns are unique per user and type
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type WakapiMigrationServiceTestSuite struct {
+	suite.Suite
+	TestUser               *models.User
+	Source                 *httptest.Server
+	ImportService          *mocks.ImportServiceMock
+	SummaryService         *mocks.SummaryServiceMock
+	AliasService           *mocks.AliasServiceMock
+	ProjectLabelService    *mocks.ProjectLabelServiceMock
+	LanguageMappingService *mocks.LanguageMappingServiceMock
+	KeyValueService        *mocks.KeyValueServiceMock
+}
+
+func (suite *WakapiMigrationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Security.AllowPrivateNetworks = true // stub server listens on loopback
+	config.Set(cfg)
+
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.ImportService = new(mocks.ImportServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.LanguageMappingService = new(mocks.LanguageMappingServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+
+	heartbeats := []*models.Heartbeat{
+		{ID: 1, Project: TestProject1, Time: models.CustomTime(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC))},
+		{ID: 2, Project: TestProject1, Time: models.CustomTime(time.Date(2023, 1, 1, 10, 1, 0, 0, time.UTC))},
+		{ID: 3, Project: TestProject2, Time: models.CustomTime(time.Date(2023, 1, 1, 10, 2, 0, 0, time.UTC))},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/export/heartbeats", func(w http.ResponseWriter, r *http.Request) {
+		after, _ := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+		page := &models.HeartbeatsExportPage{Data: []*models.Heartbeat{}, Cursor: after}
+		for _, h := range heartbeats {
+			if h.ID > after {
+				page.Data = append(page.Data, h)
+				page.Cursor = h.ID
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+	mux.HandleFunc("/api/export/settings", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&models.SettingsExport{
+			Aliases: []*models.Alias{
+				{ID: 1, Type: models.SummaryProject, UserID: "someone", Key: TestProject1, Value: "wakapi-mobile"},
+				{ID: 2, Type: models.SummaryProject, UserID: "someone", Key: TestProject1, Value: "wakapi-web"},
+			},
+			ProjectLabels:    []*models.ProjectLabel{{ID: 1, UserID: "someone", ProjectKey: TestProject1, Label: "oss"}},
+			LabelColors:      map[string]string{"oss": "#00ff00"},
+			LanguageMappings: []*models.LanguageMapping{{ID: 1, UserID: "someone", Extension: ".vue", Language: "Vue"}},
+		})
+	})
+	mux.HandleFunc("/api/summary", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(suite.newSummary(time.Hour))
+	})
+	suite.Source = httptest.NewServer(mux)
+}
+
+func (suite *WakapiMigrationServiceTestSuite) AfterTest(suiteName, testName string) {
+	suite.Source.Close()
+}
+
+func TestWakapiMigrationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(WakapiMigrationServiceTestSuite))
+}
+
+func (suite *WakapiMigrationServiceTestSuite) TestWakapiMigrationService_Migrate() {
+	sut := NewWakapiMigrationService(suite.ImportService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService, suite.LanguageMappingService, suite.KeyValueService)
+
+	checkpointKey := config.KeyWakapiMigrationCheckpoint + "_" + TestUserId
+	checkpoint, _ := json.Marshal(&models.WakapiMigrationCheckpoint{BaseUrl: suite.Source.URL, Cursor: 1})
+
+	var imported []*models.Heartbeat
+	job := models.NewImportJob(suite.TestUser, models.ImportSourceWakapi).WithParams(&models.ImportJobParams{BaseUrl: suite.Source.URL, ApiKey: "api-key"})
+
+	suite.AliasService.On("GetByUser", TestUserId).Return([]*models.Alias{{Type: models.SummaryProject, UserID: TestUserId, Key: TestProject1, Value: "wakapi-web"}}, nil)
+	suite.AliasService.On("Create", mock.Anything).Return(&models.Alias{}, nil)
+	suite.ProjectLabelService.On("GetByUser", TestUserId).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("SetColor", mock.Anything).Return(&models.LabelColor{}, nil)
+	suite.LanguageMappingService.On("GetByUser", TestUserId).Return([]*models.LanguageMapping{}, nil)
+	suite.LanguageMappingService.On("Create", mock.Anything).Return(&models.LanguageMapping{}, nil)
+	suite.KeyValueService.On("MustGetString", checkpointKey).Return(&models.KeyStringValue{Key: checkpointKey, Value: string(checkpoint)})
+	suite.KeyValueService.On("PutString", mock.Anything).Return(nil)
+	suite.KeyValueService.On("DeleteString", checkpointKey).Return(nil)
+	suite.ImportService.On("CreateJob", mock.Anything).Return(job, nil)
+	suite.ImportService.On("RunJob", job, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		importer, checkpoint := args.Get(1).(imports.DataImporter), args.Get(2).(func(*models.Heartbeat))
+		stream, _ := importer.ImportAll(suite.TestUser)
+		for hb := range stream {
+			imported = append(imported, hb)
+		}
+		checkpoint(imported[len(imported)-1])
+	}).Return(&models.ImportResult{Processed: 2, Imported: 2}, nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything).Return(suite.newSummary(time.Hour), nil)
+
+	result, err := sut.Migrate(suite.TestUser, suite.Source.URL+"/", "api-key")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, result.Imported)
+	assert.Equal(suite.T(), time.Hour, result.SourceTotal)
+	assert.True(suite.T(), result.Verified())
+
+	// resumed after checkpoint
+	assert.Len(suite.T(), imported, 2)
+	assert.Equal(suite.T(), "2", imported[0].OriginId)
+	assert.Equal(suite.T(), TestUserId, imported[0].UserID)
+
+	// only missing alias created
+	suite.AliasService.AssertNumberOfCalls(suite.T(), "Create", 1)
+	suite.AliasService.AssertCalled(suite.T(), "Create", &models.Alias{UserID: TestUserId, Type: models.SummaryProject, Key: TestProject1, Value: "wakapi-mobile"})
+	suite.ProjectLabelService.AssertCalled(suite.T(), "SetColor", &models.LabelColor{UserID: TestUserId, Label: "oss", Color: "#00ff00"})
+	suite.LanguageMappingService.AssertNumberOfCalls(suite.T(), "Create", 1)
+
+	suite.KeyValueService.AssertCalled(suite.T(), "PutString", mock.MatchedBy(func(kv *models.KeyStringValue) bool {
+		return kv.Key == checkpointKey && strings.Contains(kv.Value, `"cursor":3`)
+	}))
+	suite.KeyValueService.AssertCalled(suite.T(), "DeleteString", checkpointKey)
+	suite.ImportService.AssertCalled(suite.T(), "CreateJob", mock.MatchedBy(func(j *models.ImportJob) bool {
+		return j.Source == models.ImportSourceWakapi && j.GetParams().BaseUrl == suite.Source.URL
+	}))
+}
+
+func (suite *WakapiMigrationServiceTestSuite) TestWakapiMigrationService_Migrate_Unverified() {
+	sut := NewWakapiMigrationService(suite.ImportService, suite.SummaryService, suite.AliasService, suite.ProjectLabelService, suite.LanguageMappingService, suite.KeyValueService)
+
+	job := models.NewImportJob(suite.TestUser, models.ImportSourceWakapi).WithParams(&models.ImportJobParams{BaseUrl: suite.Source.URL, ApiKey: "api-key"})
+
+	suite.AliasService.On("GetByUser", TestUserId).Return([]*models.Alias{}, nil)
+	suite.AliasService.On("Create", mock.Anything).Return(&models.Alias{}, nil)
+	suite.ProjectLabelService.On("GetByUser", TestUserId).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("SetColor", mock.Anything).Return(&models.LabelColor{}, nil)
+	suite.LanguageMappingService.On("GetByUser", TestUserId).Return([]*models.LanguageMapping{}, nil)
+	suite.LanguageMappingService.On("Create", mock.Anything).Return(&models.LanguageMapping{}, nil)
+	suite.KeyValueService.On("MustGetString", mock.Anything).Return(&models.KeyStringValue{})
+	suite.KeyValueService.On("DeleteString", mock.Anything).Return(nil)
+	suite.ImportService.On("CreateJob", mock.Anything).Return(job, nil)
+	suite.ImportService.On("UpdateJob", job).Return(job, nil)
+	suite.ImportService.On("RunJob", job, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stream, _ := args.Get(1).(imports.DataImporter).ImportAll(suite.TestUser)
+		for range stream {
+		}
+	}).Return(&models.ImportResult{Processed: 3, Imported: 3}, nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything).Return(suite.newSummary(30*time.Minute), nil)
+
+	result, err := sut.Migrate(suite.TestUser, suite.Source.URL, "api-key")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 30*time.Minute, result.TargetTotal)
+	assert.False(suite.T(), result.Verified())
+	assert.Len(suite.T(), job.ErrorList(), 1)
+	suite.ImportService.AssertCalled(suite.T(), "UpdateJob", job)
+}
+
+func (suite *WakapiMigrationServiceTestSuite) newSummary(total time.Duration) *models.Summary {
+	return &models.Summary{
+		FromTime: models.CustomTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		ToTime:   models.CustomTime(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)),
+		Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: TestProject1, Total: total / time.Second}},
+	}
+}
+
+func TestContainsWakapiLanguageMapping(t *testing.T) {
+	existing := []*models.LanguageMapping{{Extension: "Dockerfile", Language: "Docker"}}
+
+	assert.True(t, containsWakapiLanguageMapping(existing, &models.LanguageMapping{Extension: "Dockerfile", Type: models.LanguageMappingTypeExtension}))
+	assert.False(t, containsWakapiLanguageMapping(existing, &models.LanguageMapping{Extension: "Dockerfile", Type: models.LanguageMappingTypeFilename}))
+	assert.False(t, containsWakapiLanguageMapping(existing, &models.LanguageMapping{Extension: "vue"}))
+}
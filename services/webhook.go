@@ -0,0 +1,394 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	webhookPollEvery            = 10 * time.Second
+	webhookFetchSize            = 100
+	webhookHeartbeatFlushEvery  = 1 * time.Minute
+	webhookHeartbeatBatchSize   = 1000
+	webhookDeliveryRetention    = 30 * 24 * time.Hour
+	webhookDisabledDelay        = 1 * time.Hour
+	webhookDeliveryLogSize      = 10
+	webhookCacheKeyEnabled      = "enabled"
+	WebhookHeaderEvent          = "X-Wakapi-Event"
+	WebhookHeaderDelivery       = "X-Wakapi-Delivery"
+	WebhookHeaderTimestamp      = "X-Wakapi-Timestamp"
+	WebhookHeaderSignature      = "X-Wakapi-Signature"
+	webhookSignaturePrefix      = "sha256="
+	webhookHeartbeatsDataField  = "heartbeats"
+	webhookWakatimeFailureField = "failures"
+)
+
+var webhookDeliveryLock = sync.Mutex{}
+
+// WebhookService notifies users' (and admins' global) webhooks about events from the event bus. Events are persisted as deliveries first,
+// which are sent by a background worker on a dedicated queue, signed with the webhook's secret and retried with exponential backoff.
+// Heartbeats are not sent one by one, but collected per user and flushed in batches periodically.
+type WebhookService struct {
+	config             *config.Config
+	eventBus           *hub.Hub
+	httpClient         *http.Client
+	cache              *cache.Cache
+	queueDefault       *artifex.Dispatcher
+	queueWebhooks      *artifex.Dispatcher
+	repository         repositories.IWebhookRepository
+	deliveryRepository repositories.IWebhookDeliveryRepository
+	heartbeatBuffer    map[string][]*models.Heartbeat
+	heartbeatLock      sync.Mutex
+}
+
+type webhookUserData struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Location string `json:"location"`
+}
+
+type webhookSummaryData struct {
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	TotalSeconds float64            `json:"total_seconds"`
+	Projects     map[string]float64 `json:"projects"`
+	Languages    map[string]float64 `json:"languages"`
+}
+
+func NewWebhookService(webhookRepository repositories.IWebhookRepository, webhookDeliveryRepository repositories.IWebhookDeliveryRepository) *WebhookService {
+	return &WebhookService{
+		config:             config.Get(),
+		eventBus:           config.EventBus(),
+		httpClient:         utils.NewPublicHttpClient(10*time.Second, config.Get().Security.AllowPrivateNetworks),
+		cache:              cache.New(10*time.Minute, 20*time.Minute),
+		queueDefault:       config.GetDefaultQueue(),
+		queueWebhooks:      config.GetQueue(config.QueueWebhooks),
+		repository:         webhookRepository,
+		deliveryRepository: webhookDeliveryRepository,
+		heartbeatBuffer:    make(map[string][]*models.Heartbeat),
+	}
+}
+
+// Schedule starts listening for events and periodically sends due deliveries
+func (srv *WebhookService) Schedule() {
+	logbuch.Info("scheduling webhook delivery")
+
+	sub := srv.eventBus.Subscribe(0, models.WebhookEvents...)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.handleMessage(m)
+		}
+	}(&sub)
+
+	if _, err := srv.queueWebhooks.DispatchEvery(srv.ProcessDeliveries, webhookPollEvery); err != nil {
+		config.Log().Error("failed to schedule webhook delivery, %v", err)
+	}
+	if _, err := srv.queueWebhooks.DispatchEvery(srv.flushHeartbeats, webhookHeartbeatFlushEvery); err != nil {
+		config.Log().Error("failed to schedule webhook heartbeat batching, %v", err)
+	}
+	if _, err := srv.queueDefault.DispatchEvery(srv.runCleanup, 6*time.Hour); err != nil {
+		config.Log().Error("failed to schedule webhook delivery cleanup, %v", err)
+	}
+}
+
+func (srv *WebhookService) GetById(id uint) (*models.Webhook, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *WebhookService) GetByUser(userId string) ([]*models.Webhook, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+// GetDeliveries returns the webhook's most recent deliveries
+func (srv *WebhookService) GetDeliveries(webhook *models.Webhook) ([]*models.WebhookDelivery, error) {
+	return srv.deliveryRepository.GetByWebhook(webhook.ID, webhookDeliveryLogSize)
+}
+
+// Create registers the given webhook and generates a random signing secret for it, unless one was given
+func (srv *WebhookService) Create(webhook *models.Webhook) (*models.Webhook, error) {
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+	if !webhook.IsValid() {
+		return nil, errors.New("invalid webhook")
+	}
+	srv.cache.Delete(webhookCacheKeyEnabled)
+	return srv.repository.Insert(webhook)
+}
+
+func (srv *WebhookService) Update(webhook *models.Webhook) (*models.Webhook, error) {
+	if !webhook.IsValid() {
+		return nil, errors.New("invalid webhook")
+	}
+	srv.cache.Delete(webhookCacheKeyEnabled)
+	return srv.repository.Update(webhook)
+}
+
+func (srv *WebhookService) Delete(webhook *models.Webhook) error {
+	srv.cache.Delete(webhookCacheKeyEnabled)
+	return srv.repository.Delete(webhook.ID)
+}
+
+// Emit enqueues a delivery of the given event to every webhook subscribed to it, which is either owned by the given user or global
+func (srv *WebhookService) Emit(event, userId string, data interface{}) error {
+	webhooks, err := srv.getMatching(event, userId)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	eventId := uuid.NewV4().String()
+	payload, err := json.Marshal(&models.WebhookPayload{
+		Id:        eventId,
+		Event:     event,
+		UserId:    userId,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := models.CustomTime(time.Now())
+	deliveries := make([]*models.WebhookDelivery, len(webhooks))
+	for i, w := range webhooks {
+		deliveries[i] = &models.WebhookDelivery{
+			WebhookID:     w.ID,
+			EventId:       eventId,
+			Event:         event,
+			Payload:       string(payload),
+			NextAttemptAt: now,
+		}
+	}
+	return srv.deliveryRepository.InsertBatch(deliveries)
+}
+
+// ProcessDeliveries sends all due deliveries
+func (srv *WebhookService) ProcessDeliveries() {
+	if ok := webhookDeliveryLock.TryLock(); !ok {
+		return // previous run still in progress
+	}
+	defer webhookDeliveryLock.Unlock()
+
+	webhooks := make(map[uint]*models.Webhook) // webhooks looked up during this run, nil if not existing anymore
+
+	for {
+		deliveries, err := srv.deliveryRepository.GetDue(time.Now(), webhookFetchSize)
+		if err != nil {
+			config.Log().Error("failed to fetch webhook deliveries - %v", err)
+			return
+		}
+
+		for _, d := range deliveries {
+			if _, ok := webhooks[d.WebhookID]; !ok {
+				webhook, _ := srv.repository.GetById(d.WebhookID)
+				webhooks[d.WebhookID] = webhook
+			}
+			srv.deliver(d, webhooks[d.WebhookID])
+		}
+
+		// delivered, failed and postponed deliveries are not due anymore, so there is no risk of picking up the same ones again
+		if len(deliveries) < webhookFetchSize {
+			return
+		}
+	}
+}
+
+func (srv *WebhookService) deliver(delivery *models.WebhookDelivery, webhook *models.Webhook) {
+	now := time.Now()
+
+	if webhook == nil {
+		// webhook was deleted in the meantime, give up on the delivery
+		delivery.Attempts = models.WebhookDeliveryMaxAttempts
+		delivery.LastError = "webhook not found"
+	} else if !webhook.Enabled {
+		if err := srv.deliveryRepository.Postpone([]uint64{delivery.ID}, now.Add(webhookDisabledDelay)); err != nil {
+			config.Log().Error("failed to postpone webhook delivery %d - %v", delivery.ID, err)
+		}
+		return
+	} else if status, reason := srv.send(webhook, delivery, now); reason != "" {
+		logbuch.Warn("failed to deliver %s event to webhook %d of user %s - %s", delivery.Event, webhook.ID, webhook.UserID, reason)
+		delivery.ScheduleRetry(status, reason, now)
+	} else {
+		delivery.MarkDelivered(status, now)
+	}
+
+	if err := srv.deliveryRepository.Update(delivery); err != nil {
+		config.Log().Error("failed to update webhook delivery %d - %v", delivery.ID, err)
+	}
+}
+
+// send posts the delivery's payload to the webhook and returns the response status and a failure reason or an empty string on success
+func (srv *WebhookService) send(webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, string) {
+	body := []byte(delivery.Payload)
+
+	request, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	timestamp := now.Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", fmt.Sprintf("wakapi/%s", srv.config.Version))
+	request.Header.Set(WebhookHeaderEvent, delivery.Event)
+	request.Header.Set(WebhookHeaderDelivery, delivery.EventId)
+	request.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(WebhookHeaderSignature, webhookSignaturePrefix+webhook.Sign(body, timestamp))
+
+	response, err := srv.httpClient.Do(request)
+	if err != nil {
+		return 0, err.Error()
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Sprintf("got status %d", response.StatusCode)
+	}
+	return response.StatusCode, ""
+}
+
+func (srv *WebhookService) handleMessage(m hub.Message) {
+	var (
+		userId string
+		data   interface{}
+	)
+
+	switch m.Name {
+	case config.EventHeartbeatCreate:
+		srv.bufferHeartbeat(m.Fields[config.FieldPayload].(*models.Heartbeat))
+		return
+	case config.EventUserUpdate:
+		user := m.Fields[config.FieldPayload].(*models.User)
+		userId, data = user.ID, &webhookUserData{ID: user.ID, Email: user.Email, Location: user.Location}
+	case config.EventWakatimeFailure:
+		userId = m.Fields[config.FieldUser].(*models.User).ID
+		data = map[string]interface{}{webhookWakatimeFailureField: m.Fields[config.FieldPayload]}
+	case config.EventSummaryCreate:
+		summary := m.Fields[config.FieldPayload].(*models.Summary)
+		userId, data = summary.UserID, newWebhookSummaryData(summary)
+	case config.EventImportFinish, config.EventGoalReached:
+		userId, data = m.Fields[config.FieldUserId].(string), m.Fields[config.FieldPayload]
+	default:
+		return
+	}
+
+	if err := srv.Emit(m.Name, userId, data); err != nil {
+		config.Log().Error("failed to enqueue %s webhook deliveries for user %s - %v", m.Name, userId, err)
+	}
+}
+
+// bufferHeartbeat collects the heartbeat for the next batch of the user's heartbeat webhooks, if there are any
+func (srv *WebhookService) bufferHeartbeat(heartbeat *models.Heartbeat) {
+	if webhooks, err := srv.getMatching(config.EventHeartbeatCreate, heartbeat.UserID); err != nil || len(webhooks) == 0 {
+		return
+	}
+
+	srv.heartbeatLock.Lock()
+	srv.heartbeatBuffer[heartbeat.UserID] = append(srv.heartbeatBuffer[heartbeat.UserID], heartbeat)
+	var batch []*models.Heartbeat
+	if len(srv.heartbeatBuffer[heartbeat.UserID]) >= webhookHeartbeatBatchSize {
+		batch = srv.heartbeatBuffer[heartbeat.UserID]
+		delete(srv.heartbeatBuffer, heartbeat.UserID)
+	}
+	srv.heartbeatLock.Unlock()
+
+	if batch != nil {
+		srv.emitHeartbeats(heartbeat.UserID, batch)
+	}
+}
+
+func (srv *WebhookService) flushHeartbeats() {
+	srv.heartbeatLock.Lock()
+	buffer := srv.heartbeatBuffer
+	srv.heartbeatBuffer = make(map[string][]*models.Heartbeat)
+	srv.heartbeatLock.Unlock()
+
+	for userId, heartbeats := range buffer {
+		srv.emitHeartbeats(userId, heartbeats)
+	}
+}
+
+func (srv *WebhookService) emitHeartbeats(userId string, heartbeats []*models.Heartbeat) {
+	data := map[string]interface{}{webhookHeartbeatsDataField: heartbeats}
+	if err := srv.Emit(config.EventHeartbeatCreate, userId, data); err != nil {
+		config.Log().Error("failed to enqueue heartbeat webhook deliveries for user %s - %v", userId, err)
+	}
+}
+
+func (srv *WebhookService) getMatching(event, userId string) ([]*models.Webhook, error) {
+	var enabled []*models.Webhook
+	if cached, found := srv.cache.Get(webhookCacheKeyEnabled); found {
+		enabled = cached.([]*models.Webhook)
+	} else {
+		webhooks, err := srv.repository.GetEnabled()
+		if err != nil {
+			return nil, err
+		}
+		srv.cache.SetDefault(webhookCacheKeyEnabled, webhooks)
+		enabled = webhooks
+	}
+
+	matching := make([]*models.Webhook, 0)
+	for _, w := range enabled {
+		if w.Matches(event, userId) {
+			matching = append(matching, w)
+		}
+	}
+	return matching, nil
+}
+
+func (srv *WebhookService) runCleanup() {
+	n, err := srv.deliveryRepository.DeleteFinishedBefore(time.Now().Add(-webhookDeliveryRetention))
+	if err != nil {
+		config.Log().Error("failed to clean up webhook deliveries - %v", err)
+		return
+	}
+	if n > 0 {
+		logbuch.Info("deleted %d finished webhook deliveries", n)
+	}
+}
+
+func newWebhookSummaryData(summary *models.Summary) *webhookSummaryData {
+	data := &webhookSummaryData{
+		From:         summary.FromTime.T(),
+		To:           summary.ToTime.T(),
+		TotalSeconds: summary.TotalTime().Seconds(),
+		Projects:     make(map[string]float64),
+		Languages:    make(map[string]float64),
+	}
+	for _, item := range summary.Projects {
+		data.Projects[item.Key] = item.TotalFixed().Seconds()
+	}
+	for _, item := range summary.Languages {
+		data.Languages[item.Key] = item.TotalFixed().Seconds()
+	}
+	return data
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
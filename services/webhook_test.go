@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type webhookStubRequest struct {
+	Path      string
+	Event     string
+	Signature string
+	Timestamp int64
+	Body      []byte
+}
+
+type WebhookServiceTestSuite struct {
+	suite.Suite
+	StubServer                *httptest.Server
+	StubRequests              []*webhookStubRequest
+	stubLock                  sync.Mutex
+	WebhookRepository         *mocks.WebhookRepositoryMock
+	WebhookDeliveryRepository *mocks.WebhookDeliveryRepositoryMock
+}
+
+func (suite *WebhookServiceTestSuite) SetupSuite() {
+	suite.StubServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.stubLock.Lock()
+		defer suite.stubLock.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(WebhookHeaderTimestamp), 10, 64)
+		suite.StubRequests = append(suite.StubRequests, &webhookStubRequest{
+			Path:      r.URL.Path,
+			Event:     r.Header.Get(WebhookHeaderEvent),
+			Signature: r.Header.Get(WebhookHeaderSignature),
+			Timestamp: timestamp,
+			Body:      body,
+		})
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func (suite *WebhookServiceTestSuite) TearDownSuite() {
+	suite.StubServer.Close()
+}
+
+func (suite *WebhookServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Security.AllowPrivateNetworks = true // stub server listens on loopback
+	config.Set(cfg)
+
+	suite.StubRequests = []*webhookStubRequest{}
+	suite.WebhookRepository = new(mocks.WebhookRepositoryMock)
+	suite.WebhookDeliveryRepository = new(mocks.WebhookDeliveryRepositoryMock)
+}
+
+func TestWebhookServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookServiceTestSuite))
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_Emit() {
+	sut := NewWebhookService(suite.WebhookRepository, suite.WebhookDeliveryRepository)
+
+	webhooks := []*models.Webhook{
+		{ID: 1, UserID: TestUserId, Enabled: true, Events: config.EventImportFinish},
+		{ID: 2, UserID: "otheruser", Enabled: true, Events: config.EventImportFinish},
+		{ID: 3, UserID: "admin", Enabled: true, IsGlobal: true, Events: config.EventImportFinish + "," + config.EventUserUpdate},
+		{ID: 4, UserID: TestUserId, Enabled: true, Events: config.EventSummaryCreate},
+	}
+
+	var inserted []*models.WebhookDelivery
+	suite.WebhookRepository.On("GetEnabled").Return(webhooks, nil).Once()
+	suite.WebhookDeliveryRepository.On("InsertBatch", mock.Anything).Run(func(args mock.Arguments) {
+		inserted = args.Get(0).([]*models.WebhookDelivery)
+	}).Return(nil)
+
+	sut.handleMessage(hub.Message{
+		Name:   config.EventImportFinish,
+		Fields: map[string]interface{}{config.FieldPayload: &models.ImportJob{ID: 5, State: models.ImportJobCompleted}, config.FieldUserId: TestUserId},
+	})
+
+	assert.Len(suite.T(), inserted, 2)
+	assert.Equal(suite.T(), uint(1), inserted[0].WebhookID)
+	assert.Equal(suite.T(), uint(3), inserted[1].WebhookID)
+	assert.Equal(suite.T(), inserted[0].EventId, inserted[1].EventId)
+
+	var payload map[string]interface{}
+	assert.Nil(suite.T(), json.Unmarshal([]byte(inserted[0].Payload), &payload))
+	assert.Equal(suite.T(), config.EventImportFinish, payload["event"])
+	assert.Equal(suite.T(), TestUserId, payload["user_id"])
+	assert.Equal(suite.T(), float64(5), payload["data"].(map[string]interface{})["id"])
+
+	// no webhooks for this user and event, enabled webhooks are served from cache
+	assert.Nil(suite.T(), sut.Emit(config.EventUserUpdate, "otheruser", nil))
+	suite.WebhookDeliveryRepository.AssertNumberOfCalls(suite.T(), "InsertBatch", 2)
+	suite.WebhookRepository.AssertNumberOfCalls(suite.T(), "GetEnabled", 1)
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_BatchHeartbeats() {
+	sut := NewWebhookService(suite.WebhookRepository, suite.WebhookDeliveryRepository)
+
+	suite.WebhookRepository.On("GetEnabled").Return([]*models.Webhook{
+		{ID: 1, UserID: TestUserId, Enabled: true, Events: config.EventHeartbeatCreate},
+	}, nil)
+
+	var inserted []*models.WebhookDelivery
+	suite.WebhookDeliveryRepository.On("InsertBatch", mock.Anything).Run(func(args mock.Arguments) {
+		inserted = append(inserted, args.Get(0).([]*models.WebhookDelivery)...)
+	}).Return(nil)
+
+	for _, hb := range []*models.Heartbeat{
+		{UserID: TestUserId, Project: "wakapi"},
+		{UserID: "otheruser", Project: "other"},
+		{UserID: TestUserId, Project: "anchr"},
+	} {
+		sut.handleMessage(hub.Message{Name: config.EventHeartbeatCreate, Fields: map[string]interface{}{config.FieldPayload: hb}})
+	}
+
+	assert.Empty(suite.T(), inserted)
+	sut.flushHeartbeats()
+
+	assert.Len(suite.T(), inserted, 1)
+	var payload struct {
+		Data struct {
+			Heartbeats []*models.Heartbeat `json:"heartbeats"`
+		} `json:"data"`
+	}
+	assert.Nil(suite.T(), json.Unmarshal([]byte(inserted[0].Payload), &payload))
+	assert.Len(suite.T(), payload.Data.Heartbeats, 2)
+	assert.Equal(suite.T(), "anchr", payload.Data.Heartbeats[1].Project)
+
+	sut.flushHeartbeats()
+	assert.Len(suite.T(), inserted, 1)
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_ProcessDeliveries() {
+	sut := NewWebhookService(suite.WebhookRepository, suite.WebhookDeliveryRepository)
+
+	healthy := &models.Webhook{ID: 1, UserID: TestUserId, Url: suite.StubServer.URL + "/healthy", Secret: "secret", Enabled: true}
+	broken := &models.Webhook{ID: 2, UserID: TestUserId, Url: suite.StubServer.URL + "/broken", Secret: "secret", Enabled: true}
+	disabled := &models.Webhook{ID: 3, UserID: TestUserId, Url: suite.StubServer.URL + "/disabled", Secret: "secret", Enabled: false}
+
+	deliveries := []*models.WebhookDelivery{
+		{ID: 10, WebhookID: 1, EventId: "a", Event: config.EventUserUpdate, Payload: `{"event":"user.update"}`},
+		{ID: 11, WebhookID: 2, EventId: "a", Event: config.EventUserUpdate, Payload: `{"event":"user.update"}`},
+		{ID: 12, WebhookID: 3, EventId: "a", Event: config.EventUserUpdate, Payload: `{"event":"user.update"}`},
+		{ID: 13, WebhookID: 4, EventId: "a", Event: config.EventUserUpdate, Payload: `{"event":"user.update"}`},
+		{ID: 14, WebhookID: 1, EventId: "b", Event: config.EventSummaryCreate, Payload: `{"event":"summary.create"}`},
+	}
+
+	suite.WebhookDeliveryRepository.On("GetDue", mock.Anything, webhookFetchSize).Return(deliveries, nil)
+	suite.WebhookRepository.On("GetById", uint(1)).Return(healthy, nil).Once()
+	suite.WebhookRepository.On("GetById", uint(2)).Return(broken, nil).Once()
+	suite.WebhookRepository.On("GetById", uint(3)).Return(disabled, nil).Once()
+	suite.WebhookRepository.On("GetById", uint(4)).Return(nil, assert.AnError).Once()
+	suite.WebhookDeliveryRepository.On("Update", mock.Anything).Return(nil)
+	suite.WebhookDeliveryRepository.On("Postpone", []uint64{12}, mock.Anything).Return(nil)
+
+	sut.ProcessDeliveries()
+
+	assert.Len(suite.T(), suite.StubRequests, 3)
+	for _, r := range suite.StubRequests {
+		assert.Equal(suite.T(), webhookSignaturePrefix+healthy.Sign(r.Body, r.Timestamp), r.Signature)
+	}
+	assert.Equal(suite.T(), "/healthy", suite.StubRequests[0].Path)
+	assert.Equal(suite.T(), config.EventSummaryCreate, suite.StubRequests[2].Event)
+
+	assert.True(suite.T(), deliveries[0].IsDelivered())
+	assert.Equal(suite.T(), http.StatusNoContent, deliveries[0].StatusCode)
+	assert.False(suite.T(), deliveries[1].IsDelivered())
+	assert.Equal(suite.T(), 1, deliveries[1].Attempts)
+	assert.Equal(suite.T(), http.StatusBadGateway, deliveries[1].StatusCode)
+	assert.Equal(suite.T(), 0, deliveries[2].Attempts)
+	assert.True(suite.T(), deliveries[3].IsExhausted())
+	assert.True(suite.T(), deliveries[4].IsDelivered())
+
+	suite.WebhookDeliveryRepository.AssertNumberOfCalls(suite.T(), "Update", 4)
+	suite.WebhookDeliveryRepository.AssertCalled(suite.T(), "Postpone", []uint64{12}, mock.Anything)
+	suite.WebhookRepository.AssertNumberOfCalls(suite.T(), "GetById", 4)
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_ProcessDeliveries_PrivateAddress() {
+	config.Get().Security.AllowPrivateNetworks = false
+	sut := NewWebhookService(suite.WebhookRepository, suite.WebhookDeliveryRepository)
+
+	webhook := &models.Webhook{ID: 1, UserID: TestUserId, Url: suite.StubServer.URL + "/healthy", Secret: "secret", Enabled: true}
+	delivery := &models.WebhookDelivery{ID: 10, WebhookID: 1, EventId: "a", Event: config.EventUserUpdate, Payload: `{"event":"user.update"}`}
+
+	suite.WebhookDeliveryRepository.On("GetDue", mock.Anything, webhookFetchSize).Return([]*models.WebhookDelivery{delivery}, nil)
+	suite.WebhookRepository.On("GetById", uint(1)).Return(webhook, nil)
+	suite.WebhookDeliveryRepository.On("Update", mock.Anything).Return(nil)
+
+	sut.ProcessDeliveries()
+
+	assert.Empty(suite.T(), suite.StubRequests)
+	assert.False(suite.T(), delivery.IsDelivered())
+	assert.Contains(suite.T(), delivery.LastError, "non-public address")
+}
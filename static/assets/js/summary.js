@@ -14,6 +14,7 @@ const machinesCanvas = document.getElementById('chart-machine')
 const labelsCanvas = document.getElementById('chart-label')
 const branchesCanvas = document.getElementById('chart-branches')
 const entitiesCanvas = document.getElementById('chart-entities')
+const ticketsCanvas = document.getElementById('chart-tickets')
 
 const projectContainer = document.getElementById('project-container')
 const osContainer = document.getElementById('os-container')
@@ -23,10 +24,11 @@ const machineContainer = document.getElementById('machine-container')
 const labelContainer = document.getElementById('label-container')
 const branchContainer = document.getElementById('branch-container')
 const entityContainer = document.getElementById('entity-container')
+const ticketContainer = document.getElementById('ticket-container')
 
-const containers = [projectContainer, osContainer, editorContainer, languageContainer, machineContainer, labelContainer, branchContainer, entityContainer]
-const canvases = [projectsCanvas, osCanvas, editorsCanvas, languagesCanvas, machinesCanvas, labelsCanvas, branchesCanvas, entitiesCanvas]
-const data = [wakapiData.projects, wakapiData.operatingSystems, wakapiData.editors, wakapiData.languages, wakapiData.machines, wakapiData.labels, wakapiData.branches, wakapiData.entities]
+const containers = [projectContainer, osContainer, editorContainer, languageContainer, machineContainer, labelContainer, branchContainer, entityContainer, ticketContainer]
+const canvases = [projectsCanvas, osCanvas, editorsCanvas, languagesCanvas, machinesCanvas, labelsCanvas, branchesCanvas, entitiesCanvas, ticketsCanvas]
+const data = [wakapiData.projects, wakapiData.operatingSystems, wakapiData.editors, wakapiData.languages, wakapiData.machines, wakapiData.labels, wakapiData.branches, wakapiData.entities, wakapiData.tickets]
 
 let topNPickers = [...document.getElementsByClassName('top-picker')]
 topNPickers.sort(((a, b) => parseInt(a.attributes['data-entity'].value) - parseInt(b.attributes['data-entity'].value)))
@@ -306,11 +308,11 @@ function draw(subselection) {
                         .slice(0, Math.min(showTopN[5], wakapiData.labels.length))
                         .map(p => parseInt(p.total)),
                     backgroundColor: wakapiData.labels.map((p, i) => {
-                        const c = hexToRgb(vibrantColors ? getRandomColor(p.key) : getColor(p.key, i))
+                        const c = hexToRgb(labelColors[p.key] || (vibrantColors ? getRandomColor(p.key) : getColor(p.key, i)))
                         return `rgba(${c.r}, ${c.g}, ${c.b}, 1)`
                     }),
                     hoverBackgroundColor: wakapiData.labels.map((p, i) => {
-                        const c = hexToRgb(vibrantColors ? getRandomColor(p.key) : getColor(p.key, i))
+                        const c = hexToRgb(labelColors[p.key] || (vibrantColors ? getRandomColor(p.key) : getColor(p.key, i)))
                         return `rgba(${c.r}, ${c.g}, ${c.b}, 0.8)`
                     }),
                     borderWidth: 0
@@ -425,6 +427,51 @@ function draw(subselection) {
         })
         : null
 
+    let ticketChart = ticketsCanvas && !ticketsCanvas.classList.contains('hidden') && shouldUpdate(8)
+        ? new Chart(ticketsCanvas.getContext('2d'), {
+            type: "bar",
+            data: {
+                datasets: [{
+                    data: wakapiData.tickets
+                        .slice(0, Math.min(showTopN[8], wakapiData.tickets.length))
+                        .map(p => parseInt(p.total)),
+                    backgroundColor: wakapiData.tickets.map((p, i) => {
+                        const c = hexToRgb(vibrantColors ? getRandomColor(p.key) : getColor(p.key, i % baseColors.length))
+                        return `rgba(${c.r}, ${c.g}, ${c.b}, 1)`
+                    }),
+                    hoverBackgroundColor: wakapiData.tickets.map((p, i) => {
+                        const c = hexToRgb(vibrantColors ? getRandomColor(p.key) : getColor(p.key, i % baseColors.length))
+                        return `rgba(${c.r}, ${c.g}, ${c.b}, 0.8)`
+                    }),
+                }],
+                labels: wakapiData.tickets
+                    .slice(0, Math.min(showTopN[8], wakapiData.tickets.length))
+                    .map(p => p.key)
+            },
+            options: {
+                indexAxis: 'y',
+                scales: {
+                    xAxes: {
+                        title: {
+                            display: true,
+                            text: 'Duration (hh:mm:ss)',
+                        },
+                        ticks: {
+                            callback: (label) => label.toString().toHHMMSS(),
+                        }
+                    }
+                },
+                plugins: {
+                    legend: {
+                        display: false,
+                    },
+                    tooltip: getTooltipOptions('tickets'),
+                },
+                maintainAspectRatio: false,
+            }
+        })
+        : null
+
     charts[0] = projectChart ? projectChart : charts[0]
     charts[1] = osChart ? osChart : charts[1]
     charts[2] = editorChart ? editorChart : charts[2]
@@ -433,6 +480,7 @@ function draw(subselection) {
     charts[5] = labelChart ? labelChart : charts[5]
     charts[6] = branchChart ? branchChart : charts[6]
     charts[7] = entityChart ? entityChart : charts[7]
+    charts[8] = ticketChart ? ticketChart : charts[8]
 }
 
 function parseTopN() {
@@ -500,6 +548,13 @@ function extractFile(filePath) {
     return filePath.split(delimiter).at(-1)
 }
 
+function setLabelDepth(depth) {
+    const url = new URL(window.location.href)
+    if (depth > 0) url.searchParams.set('label_depth', depth)
+    else url.searchParams.delete('label_depth')
+    window.location.href = url.toString()
+}
+
 function updateNumTotal() {
     for (let i = 0; i < data.length; i++) {
         document.querySelector(`span[data-entity='${i}']`).innerText = data[i].length.toString()
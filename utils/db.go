@@ -52,6 +52,11 @@ func WithPaging(query *gorm.DB, limit, skip int) *gorm.DB {
 	return query
 }
 
+// EscapeLike escapes wildcards in a string to be used as part of a like pattern with '\\' as escape character
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 type stringWriter struct {
 	*strings.Builder
 }
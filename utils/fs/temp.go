@@ -0,0 +1,22 @@
+package fs
+
+import (
+	"io"
+	"os"
+)
+
+// StashFile copies the given reader's contents to a new temporary file and returns its path, e.g. to keep an uploaded
+// file beyond the lifetime of the request that carried it. The caller is responsible for removing the file again.
+func StashFile(src io.Reader) (string, error) {
+	file, err := os.CreateTemp("", "wakapi_upload_*")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, src); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
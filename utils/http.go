@@ -7,10 +7,12 @@ import (
 	"github.com/duke-git/lancet/v2/strutil"
 	"github.com/mileusna/useragent"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -20,10 +22,19 @@ const (
 
 var (
 	cacheMaxAgeRe *regexp.Regexp
+	reservedNets  []*net.IPNet
 )
 
+var ErrNonPublicAddress = errors.New("refusing to connect to non-public address")
+
 func init() {
 	cacheMaxAgeRe = regexp.MustCompile(cacheMaxAgePattern)
+
+	// special-purpose ranges not covered by net.IP's own checks
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		reservedNets = append(reservedNets, n)
+	}
 }
 
 type PageParams struct {
@@ -103,6 +114,49 @@ func ParseUserAgent(ua string) (string, string, error) { // os, editor, err
 	return "", "", errors.New("failed to parse user agent string")
 }
 
+// IsPublicIP tells whether the given address is publicly routable, i.e. not loopback, private, link-local, multicast or otherwise reserved
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewPublicHttpClient returns an http client, which refuses to connect to non-public addresses, unless allowPrivate is
+// set. Addresses are checked right before connecting, i.e. after dns resolution, so that host names resolving to
+// internal addresses are rejected as well. Proxies are bypassed, as requests sent through them couldn't be checked.
+func NewPublicHttpClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{Timeout: timeout}
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("%w '%s'", ErrNonPublicAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
 func RaiseForStatus(res *http.Response, err error) (*http.Response, error) {
 	if err != nil {
 		return res, err
@@ -0,0 +1,6 @@
+package report
+
+import "embed"
+
+//go:embed *.html
+var TemplateFiles embed.FS